@@ -25,7 +25,7 @@ _sym_db = _symbol_database.Default()
 from google.protobuf import timestamp_pb2 as google_dot_protobuf_dot_timestamp__pb2
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x08ws.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x92\x01\n\x14\x44\x61tabaseBranchUpdate\x12\x15\n\rdatabase_name\x18\x01 \x01(\t\x12\x1a\n\x12previous_branch_id\x18\x02 \x01(\t\x12\x15\n\rnew_branch_id\x18\x03 \x01(\t\x12\x16\n\x0e\x62ranch_created\x18\x04 \x01(\x08\x12\x18\n\x10parent_branch_id\x18\x05 \x01(\t\"\xd6\x01\n\x0bPushMessage\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12\x12\n\nbatch_file\x18\x02 \x01(\x0c\x12\x11\n\tcode_diff\x18\x03 \x01(\t\x12\x1a\n\x12\x63hange_description\x18\x04 \x01(\t\x12\x15\n\rfiles_changed\x18\x05 \x01(\x05\x12\x11\n\tadditions\x18\x06 \x01(\x05\x12\x11\n\tdeletions\x18\x07 \x01(\x05\x12\x36\n\x17\x64\x61tabase_branch_updates\x18\x08 \x03(\x0b\x32\x15.DatabaseBranchUpdate\"\xb4\x01\n\x0cPushResponse\x12(\n\x06status\x18\x01 \x01(\x0e\x32\x18.PushResponse.PushStatus\x12\x15\n\rerror_message\x18\x02 \x01(\t\x12\x0f\n\x07push_id\x18\x03 \x01(\t\"R\n\nPushStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0f\n\x0bIN_PROGRESS\x10\x02\x12\n\n\x06\x46\x41ILED\x10\x03\x12\r\n\tCOMPLETED\x10\x04\"\xce\x01\n\x11ResponseAssertion\x12.\n\x04type\x18\x01 \x01(\x0e\x32 .ResponseAssertion.AssertionType\x12\x10\n\x08\x65xpected\x18\x02 \x01(\t\x12\x11\n\x04path\x18\x03 \x01(\tH\x00\x88\x01\x01\"[\n\rAssertionType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0f\n\x0bSTATUS_CODE\x10\x01\x12\r\n\tJSON_PATH\x10\x02\x12\n\n\x06HEADER\x10\x03\x12\x11\n\rBODY_CONTAINS\x10\x04\x42\x07\n\x05_path\"\xb0\x01\n\x12VariableExtraction\x12\x0c\n\x04name\x18\x01 \x01(\t\x12.\n\x06source\x18\x02 \x01(\x0e\x32\x1e.VariableExtraction.SourceType\x12\x11\n\x04path\x18\x03 \x01(\tH\x00\x88\x01\x01\"@\n\nSourceType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x08\n\x04JSON\x10\x01\x12\n\n\x06HEADER\x10\x02\x12\x0f\n\x0bSTATUS_CODE\x10\x03\x42\x07\n\x05_path\"\xbf\x03\n\x0fHTTPRequestStep\x12\x11\n\tstep_name\x18\x01 \x01(\t\x12+\n\x06method\x18\x02 \x01(\x0e\x32\x1b.HTTPRequestStep.HttpMethod\x12\x0c\n\x04path\x18\x03 \x01(\t\x12.\n\x07headers\x18\x04 \x03(\x0b\x32\x1d.HTTPRequestStep.HeadersEntry\x12\x11\n\x04\x62ody\x18\x05 \x01(\tH\x00\x88\x01\x01\x12.\n\x11\x65xtract_variables\x18\x06 \x03(\x0b\x32\x13.VariableExtraction\x12&\n\nassertions\x18\x07 \x03(\x0b\x32\x12.ResponseAssertion\x12\x12\n\ndepends_on\x18\x08 \x03(\t\x12\x1b\n\x13\x63ontinue_on_failure\x18\t \x01(\x08\x1a.\n\x0cHeadersEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"Y\n\nHttpMethod\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x07\n\x03GET\x10\x01\x12\x08\n\x04POST\x10\x02\x12\x07\n\x03PUT\x10\x03\x12\n\n\x06\x44\x45LETE\x10\x04\x12\t\n\x05PATCH\x10\x05\x12\x0b\n\x07OPTIONS\x10\x06\x42\x07\n\x05_body\"\xbf\x01\n\x08HttpTest\x12\x1f\n\x05steps\x18\x01 \x03(\x0b\x32\x10.HTTPRequestStep\x12:\n\x11initial_variables\x18\x02 \x03(\x0b\x32\x1f.HttpTest.InitialVariablesEntry\x12\x1d\n\x15stop_on_first_failure\x18\x03 \x01(\x08\x1a\x37\n\x15InitialVariablesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"%\n\x0b\x42rowserTest\x12\x16\n\x0eworkflow_steps\x18\x01 \x03(\t\"\x88\x02\n\nTestResult\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12&\n\x06status\x18\x02 \x01(\x0e\x32\x16.TestResult.TestStatus\x12\x18\n\x0b\x64\x65scription\x18\x03 \x01(\tH\x00\x88\x01\x01\x12\x14\n\x0c\x64\x65tails_json\x18\x04 \x01(\t\x12-\n\ttimestamp\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"R\n\nTestStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0b\n\x07RUNNING\x10\x02\x12\x08\n\x04PASS\x10\x03\x12\x08\n\x04\x46\x41IL\x10\x04\x12\t\n\x05\x45RROR\x10\x05\x42\x0e\n\x0c_description\"w\n\x0e\x43laudeMetadata\x12\x10\n\x08\x63ost_usd\x18\x01 \x01(\x01\x12\x13\n\x0b\x64uration_ms\x18\x02 \x01(\x03\x12\x17\n\x0f\x64uration_api_ms\x18\x03 \x01(\x03\x12\x11\n\tnum_turns\x18\x04 \x01(\x05\x12\x12\n\nsession_id\x18\x05 \x01(\t\"q\n\x07TestLog\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12-\n\ttimestamp\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x10\n\x08log_type\x18\x03 \x01(\t\x12\x14\n\x0c\x64\x65tails_json\x18\x04 \x01(\t\"~\n\x08TestInfo\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\x1e\n\thttp_test\x18\x03 \x01(\x0b\x32\t.HttpTestH\x00\x12$\n\x0c\x62rowser_test\x18\x04 \x01(\x0b\x32\x0c.BrowserTestH\x00\x42\x06\n\x04test\"\xb3\x05\n\x1bVerificationProgressMessage\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12=\n\x05stage\x18\x02 \x01(\x0e\x32..VerificationProgressMessage.VerificationStage\x12\x18\n\x05tests\x18\x03 \x03(\x0b\x32\t.TestInfo\x12!\n\x0ctest_results\x18\x04 \x03(\x0b\x32\x0b.TestResult\x12\x1a\n\rerror_message\x18\x05 \x01(\tH\x00\x88\x01\x01\x12\x33\n\nstarted_at\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.TimestampH\x01\x88\x01\x01\x12\x35\n\x0c\x63ompleted_at\x18\x07 \x01(\x0b\x32\x1a.google.protobuf.TimestampH\x02\x88\x01\x01\x12-\n\x0f\x63laude_metadata\x18\x08 \x01(\x0b\x32\x0f.ClaudeMetadataH\x03\x88\x01\x01\x12\x1b\n\ttest_logs\x18\t \x03(\x0b\x32\x08.TestLog\"\xec\x01\n\x11VerificationStage\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x10\n\x0cINITIALIZING\x10\x01\x12\x12\n\x0e\x44IFF_GENERATED\x10\x02\x12\x14\n\x10GENERATING_TESTS\x10\x03\x12\x13\n\x0fTESTS_GENERATED\x10\x04\x12\x11\n\rRUNNING_TESTS\x10\x05\x12\x19\n\x15LOCAL_TESTS_COMPLETED\x10\x06\x12\x17\n\x13VERIFYING_TELEMETRY\x10\x07\x12\x15\n\x11GENERATING_REPORT\x10\x08\x12\x10\n\x0cREPORT_READY\x10\t\x12\t\n\x05\x45RROR\x10\nB\x10\n\x0e_error_messageB\r\n\x0b_started_atB\x0f\n\r_completed_atB\x12\n\x10_claude_metadata\"\xdc\x02\n\x1cVerificationProgressResponse\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12@\n\x06status\x18\x02 \x01(\x0e\x32\x30.VerificationProgressResponse.VerificationStatus\x12\x1a\n\rerror_message\x18\x03 \x01(\tH\x00\x88\x01\x01\x12\x19\n\x0c\x61gent_report\x18\x04 \x01(\tH\x01\x88\x01\x01\x12\x19\n\x0chuman_report\x18\x05 \x01(\tH\x02\x88\x01\x01\"c\n\x12VerificationStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0c\n\x08\x41\x43\x43\x45PTED\x10\x01\x12\t\n\x05\x45RROR\x10\x02\x12\x15\n\x11GENERATING_REPORT\x10\x03\x12\x10\n\x0cREPORT_READY\x10\x04\x42\x10\n\x0e_error_messageB\x0f\n\r_agent_reportB\x0f\n\r_human_report\"$\n\x0b\x41uthMessage\x12\x15\n\rsession_token\x18\x01 \x01(\t\"\xa6\x01\n\x0c\x41uthResponse\x12(\n\x06status\x18\x01 \x01(\x0e\x32\x18.AuthResponse.AuthStatus\x12\x1a\n\rerror_message\x18\x02 \x01(\tH\x00\x88\x01\x01\">\n\nAuthStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x11\n\rAUTHENTICATED\x10\x01\x12\x10\n\x0cUNAUTHORIZED\x10\x02\x42\x10\n\x0e_error_message\"\x9c\x04\n\x10WebsocketMessage\x12\x33\n\x0cmessage_type\x18\x01 \x01(\x0e\x32\x1d.WebsocketMessage.MessageType\x12$\n\x0cpush_message\x18\x02 \x01(\x0b\x32\x0c.PushMessageH\x00\x12&\n\rpush_response\x18\x03 \x01(\x0b\x32\r.PushResponseH\x00\x12=\n\x15verification_progress\x18\x04 \x01(\x0b\x32\x1c.VerificationProgressMessageH\x00\x12G\n\x1everification_progress_response\x18\x05 \x01(\x0b\x32\x1d.VerificationProgressResponseH\x00\x12$\n\x0c\x61uth_message\x18\x06 \x01(\x0b\x32\x0c.AuthMessageH\x00\x12&\n\rauth_response\x18\x07 \x01(\x0b\x32\r.AuthResponseH\x00\"\xa3\x01\n\x0bMessageType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x10\n\x0cPUSH_REQUEST\x10\x01\x12\x11\n\rPUSH_RESPONSE\x10\x02\x12\x19\n\x15VERIFICATION_PROGRESS\x10\x03\x12\"\n\x1eVERIFICATION_PROGRESS_RESPONSE\x10\x04\x12\x10\n\x0c\x41UTH_REQUEST\x10\x05\x12\x11\n\rAUTH_RESPONSE\x10\x06\x42\t\n\x07messageB:Z8github.com/bifrostinc/code-sync-mcp/code-sync-sidecar/pbb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x08ws.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x92\x01\n\x14\x44\x61tabaseBranchUpdate\x12\x15\n\rdatabase_name\x18\x01 \x01(\t\x12\x1a\n\x12previous_branch_id\x18\x02 \x01(\t\x12\x15\n\rnew_branch_id\x18\x03 \x01(\t\x12\x16\n\x0e\x62ranch_created\x18\x04 \x01(\x08\x12\x18\n\x10parent_branch_id\x18\x05 \x01(\t\"\xc3\x05\n\x0bPushMessage\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12\x12\n\nbatch_file\x18\x02 \x01(\x0c\x12\x11\n\tcode_diff\x18\x03 \x01(\t\x12\x1a\n\x12\x63hange_description\x18\x04 \x01(\t\x12\x15\n\rfiles_changed\x18\x05 \x01(\x05\x12\x11\n\tadditions\x18\x06 \x01(\x05\x12\x11\n\tdeletions\x18\x07 \x01(\x05\x12\x36\n\x17\x64\x61tabase_branch_updates\x18\x08 \x03(\x0b\x32\x15.DatabaseBranchUpdate\x12\r\n\x05\x66orce\x18\t \x01(\x08\x12\x13\n\x0brsync_flags\x18\n \x03(\t\x12&\n\x05\x66iles\x18\x0b \x03(\x0b\x32\x17.PushMessage.FilesEntry\x12\x15\n\rdeleted_paths\x18\x0c \x03(\t\x12\x1d\n\x15\x64\x65leted_paths_dry_run\x18\r \x01(\x08\x12\x14\n\x0crequired_env\x18\x0e \x03(\t\x12\x1b\n\x13streamed_batch_size\x18\x0f \x01(\x03\x12\x14\n\x0ctriggered_by\x18\x10 \x01(\t\x12\x0e\n\x06mirror\x18\x11 \x01(\x08\x12\r\n\x05paths\x18\x12 \x03(\t\x12\x12\n\nbatch_hash\x18\x13 \x01(\t\x12.\n\nnot_before\x18\x14 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1b\n\x13\x62ypass_push_windows\x18\x15 \x01(\x08\x12)\n\x0f\x61\x63tivation_plan\x18\x16 \x03(\x0b\x32\x10.ActivationStage\x12\x10\n\x08sequence\x18\x17 \x01(\x03\x12&\n\x0fsubtree_batches\x18\x18 \x03(\x0b\x32\r.SubtreeBatch\x1a;\n\nFilesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1c\n\x05value\x18\x02 \x01(\x0b\x32\r.InjectedFile:\x02\x38\x01\"0\n\x0cSubtreeBatch\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x12\n\nbatch_file\x18\x02 \x01(\x0c\"$\n\x0f\x41\x63tivationStage\x12\x11\n\tlaunchers\x18\x01 \x03(\t\"?\n\x0cInjectedFile\x12\x0f\n\x07\x63ontent\x18\x01 \x01(\x0c\x12\x0c\n\x04mode\x18\x02 \x01(\r\x12\x10\n\x08template\x18\x03 \x01(\x08\"J\n\x12InjectedFileResult\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x0f\n\x07success\x18\x02 \x01(\x08\x12\x15\n\rerror_message\x18\x03 \x01(\t\"\xb4\x01\n\x11\x44\x65letedPathResult\x12\x0c\n\x04path\x18\x01 \x01(\t\x12)\n\x06status\x18\x02 \x01(\x0e\x32\x19.DeletedPathResult.Status\x12\x15\n\rerror_message\x18\x03 \x01(\t\"O\n\x06Status\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07REMOVED\x10\x01\x12\r\n\tNOT_FOUND\x10\x02\x12\x10\n\x0cWOULD_REMOVE\x10\x03\x12\n\n\x06\x46\x41ILED\x10\x04\"R\n\nHookResult\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x11\n\texit_code\x18\x02 \x01(\x05\x12\x0e\n\x06output\x18\x03 \x01(\t\x12\x13\n\x0b\x64uration_ms\x18\x04 \x01(\x03\"\x91\x0b\n\x0cPushResponse\x12(\n\x06status\x18\x01 \x01(\x0e\x32\x18.PushResponse.PushStatus\x12\x15\n\rerror_message\x18\x02 \x01(\t\x12\x0f\n\x07push_id\x18\x03 \x01(\t\x12!\n\x0chook_results\x18\x04 \x03(\x0b\x32\x0b.HookResult\x12\x17\n\x0f\x61lready_applied\x18\x05 \x01(\x08\x12\x19\n\x11\x63onflicting_files\x18\x06 \x03(\t\x12\x15\n\rcreated_files\x18\x07 \x03(\t\x12\x16\n\x0emodified_files\x18\x08 \x03(\t\x12\x15\n\rdeleted_files\x18\t \x03(\t\x12\x1e\n\x16\x66ile_changes_truncated\x18\n \x01(\x08\x12\x10\n\x08replayed\x18\x0b \x01(\x08\x12\x15\n\rsuperseded_by\x18\x0c \x01(\t\x12+\n\x0einjected_files\x18\r \x03(\x0b\x32\x13.InjectedFileResult\x12)\n\rdeleted_paths\x18\x0e \x03(\x0b\x32\x12.DeletedPathResult\x12\x19\n\x03pod\x18\x0f \x01(\x0b\x32\x0c.PodMetadata\x12\'\n\x0freplica_results\x18\x10 \x03(\x0b\x32\x0e.ReplicaResult\x12\x1b\n\x06timing\x18\x11 \x01(\x0b\x32\x0b.PushTiming\x12\r\n\x05no_op\x18\x12 \x01(\x08\x12\x13\n\x0bmissing_env\x18\x13 \x03(\t\x12\x16\n\x0ersync_attempts\x18\x14 \x01(\x05\x12\x17\n\x0fsignal_attempts\x18\x15 \x01(\x05\x12\x18\n\x10mirror_deletions\x18\x16 \x03(\t\x12(\n\x0fworkspace_usage\x18\x17 \x01(\x0b\x32\x0f.WorkspaceUsage\x12\x1a\n\x12out_of_scope_paths\x18\x18 \x03(\t\x12/\n\x10launcher_results\x18\x19 \x03(\x0b\x32\x15.LauncherSignalResult\x12.\n\nheld_until\x18\x1a \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x13\n\x0b\x61pproved_by\x18\x1b \x01(\t\x12\x1f\n\x17\x66\x61iled_activation_stage\x18\x1c \x01(\x05\x12\x15\n\rlast_sequence\x18\x1d \x01(\x03\x12\"\n\x0csequence_gap\x18\x1e \x01(\x0b\x32\x0c.SequenceGap\x12\'\n\x0fsubtree_results\x18\x1f \x03(\x0b\x32\x0e.SubtreeResult\x12)\n\x10security_denials\x18  \x03(\x0b\x32\x0f.SecurityDenial\"\xb4\x03\n\nPushStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0f\n\x0bIN_PROGRESS\x10\x02\x12\n\n\x06\x46\x41ILED\x10\x03\x12\r\n\tCOMPLETED\x10\x04\x12\r\n\tCANCELLED\x10\x05\x12\x0c\n\x08\x43ONFLICT\x10\x06\x12\x15\n\x11INSUFFICIENT_DISK\x10\x07\x12\x11\n\rRELOAD_FAILED\x10\x08\x12\x0c\n\x08RECEIVED\x10\t\x12\x0c\n\x08\x41PPLYING\x10\n\x12\r\n\tRELOADING\x10\x0b\x12\x0b\n\x07HEALTHY\x10\x0c\x12\x0f\n\x0bROLLED_BACK\x10\r\x12\x0e\n\nSUPERSEDED\x10\x0e\x12\x0b\n\x07PARTIAL\x10\x0f\x12\x0f\n\x0bMISSING_ENV\x10\x10\x12\x0b\n\x07STALLED\x10\x11\x12\x16\n\x12TOO_MANY_DELETIONS\x10\x12\x12\x12\n\x0eQUOTA_EXCEEDED\x10\x13\x12\x10\n\x0cOUT_OF_SCOPE\x10\x14\x12\x14\n\x10\x42\x41TCH_NOT_CACHED\x10\x15\x12\x18\n\x14LAUNCHER_NOT_RUNNING\x10\x16\x12\x15\n\x11\x41WAITING_APPROVAL\x10\x17\x12\x10\n\x0cOUT_OF_ORDER\x10\x18\"H\n\x0eSecurityDenial\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x11\n\toperation\x18\x02 \x01(\t\x12\x15\n\rerror_message\x18\x03 \x01(\t\";\n\x0eSecurityModule\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0c\n\x04mode\x18\x02 \x01(\t\x12\r\n\x05label\x18\x03 \x01(\t\"q\n\rSubtreeResult\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x0f\n\x07success\x18\x02 \x01(\x08\x12\x15\n\rerror_message\x18\x03 \x01(\t\x12\x15\n\rfiles_changed\x18\x04 \x01(\x05\x12\x13\n\x0b\x64uration_ms\x18\x05 \x01(\x03\"*\n\x0bSequenceGap\x12\r\n\x05\x66irst\x18\x01 \x01(\x03\x12\x0c\n\x04last\x18\x02 \x01(\x03\"\x92\x01\n\x14LauncherSignalResult\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0b\n\x03pid\x18\x02 \x01(\x05\x12\x0e\n\x06signal\x18\x03 \x01(\t\x12\x11\n\tsignalled\x18\x04 \x01(\x08\x12\x13\n\x0bnot_running\x18\x05 \x01(\x08\x12\x15\n\rerror_message\x18\x06 \x01(\t\x12\x10\n\x08\x61ttempts\x18\x07 \x01(\x05\"\x92\x01\n\x0eWorkspaceUsage\x12\r\n\x05\x62ytes\x18\x01 \x01(\x03\x12\x0e\n\x06inodes\x18\x02 \x01(\x03\x12\x12\n\nsoft_bytes\x18\x03 \x01(\x03\x12\x12\n\nhard_bytes\x18\x04 \x01(\x03\x12\x13\n\x0bsoft_inodes\x18\x05 \x01(\x03\x12\x13\n\x0bhard_inodes\x18\x06 \x01(\x03\x12\x0f\n\x07warning\x18\x07 \x01(\t\"\xc8\x01\n\nPushTiming\x12\x13\n\x0b\x64ownload_ms\x18\x01 \x01(\x03\x12\x16\n\x0e\x62\x61tch_write_ms\x18\x02 \x01(\x03\x12\x10\n\x08rsync_ms\x18\x03 \x01(\x03\x12\x14\n\x0c\x65nv_write_ms\x18\x04 \x01(\x03\x12\x1c\n\x14signal_to_healthy_ms\x18\x05 \x01(\x03\x12\x10\n\x08total_ms\x18\x06 \x01(\x03\x12\x10\n\x08\x62uild_ms\x18\x07 \x01(\x03\x12\x12\n\ninstall_ms\x18\x08 \x01(\x03\x12\x0f\n\x07warm_ms\x18\t \x01(\x03\"t\n\rReplicaResult\x12\x12\n\nreplica_id\x18\x01 \x01(\t\x12(\n\x06status\x18\x02 \x01(\x0e\x32\x18.PushResponse.PushStatus\x12\x15\n\rerror_message\x18\x03 \x01(\t\x12\x0e\n\x06leader\x18\x04 \x01(\x08\"\xf5\x01\n\x0cPushProgress\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12\"\n\x05stage\x18\x02 \x01(\x0e\x32\x13.PushProgress.Stage\x12\x0f\n\x07percent\x18\x03 \x01(\x05\x12\x12\n\nbytes_done\x18\x04 \x01(\x03\x12\x13\n\x0b\x62ytes_total\x18\x05 \x01(\x03\x12\x14\n\x0coutput_lines\x18\x06 \x03(\t\"`\n\x05Stage\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0f\n\x0b\x44OWNLOADING\x10\x01\x12\x0c\n\x08\x41PPLYING\x10\x02\x12\r\n\tRELOADING\x10\x03\x12\x0c\n\x08\x42UILDING\x10\x04\x12\x0e\n\nINSTALLING\x10\x05\"\x1d\n\nPushCancel\x12\x0f\n\x07push_id\x18\x01 \x01(\t\"7\n\x0f\x41\x63tivateRequest\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12\x13\n\x0b\x61pproved_by\x18\x02 \x01(\t\"\xce\x01\n\x11ResponseAssertion\x12.\n\x04type\x18\x01 \x01(\x0e\x32 .ResponseAssertion.AssertionType\x12\x10\n\x08\x65xpected\x18\x02 \x01(\t\x12\x11\n\x04path\x18\x03 \x01(\tH\x00\x88\x01\x01\"[\n\rAssertionType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0f\n\x0bSTATUS_CODE\x10\x01\x12\r\n\tJSON_PATH\x10\x02\x12\n\n\x06HEADER\x10\x03\x12\x11\n\rBODY_CONTAINS\x10\x04\x42\x07\n\x05_path\"\xb0\x01\n\x12VariableExtraction\x12\x0c\n\x04name\x18\x01 \x01(\t\x12.\n\x06source\x18\x02 \x01(\x0e\x32\x1e.VariableExtraction.SourceType\x12\x11\n\x04path\x18\x03 \x01(\tH\x00\x88\x01\x01\"@\n\nSourceType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x08\n\x04JSON\x10\x01\x12\n\n\x06HEADER\x10\x02\x12\x0f\n\x0bSTATUS_CODE\x10\x03\x42\x07\n\x05_path\"\xbf\x03\n\x0fHTTPRequestStep\x12\x11\n\tstep_name\x18\x01 \x01(\t\x12+\n\x06method\x18\x02 \x01(\x0e\x32\x1b.HTTPRequestStep.HttpMethod\x12\x0c\n\x04path\x18\x03 \x01(\t\x12.\n\x07headers\x18\x04 \x03(\x0b\x32\x1d.HTTPRequestStep.HeadersEntry\x12\x11\n\x04\x62ody\x18\x05 \x01(\tH\x00\x88\x01\x01\x12.\n\x11\x65xtract_variables\x18\x06 \x03(\x0b\x32\x13.VariableExtraction\x12&\n\nassertions\x18\x07 \x03(\x0b\x32\x12.ResponseAssertion\x12\x12\n\ndepends_on\x18\x08 \x03(\t\x12\x1b\n\x13\x63ontinue_on_failure\x18\t \x01(\x08\x1a.\n\x0cHeadersEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"Y\n\nHttpMethod\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x07\n\x03GET\x10\x01\x12\x08\n\x04POST\x10\x02\x12\x07\n\x03PUT\x10\x03\x12\n\n\x06\x44\x45LETE\x10\x04\x12\t\n\x05PATCH\x10\x05\x12\x0b\n\x07OPTIONS\x10\x06\x42\x07\n\x05_body\"\xbf\x01\n\x08HttpTest\x12\x1f\n\x05steps\x18\x01 \x03(\x0b\x32\x10.HTTPRequestStep\x12:\n\x11initial_variables\x18\x02 \x03(\x0b\x32\x1f.HttpTest.InitialVariablesEntry\x12\x1d\n\x15stop_on_first_failure\x18\x03 \x01(\x08\x1a\x37\n\x15InitialVariablesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"%\n\x0b\x42rowserTest\x12\x16\n\x0eworkflow_steps\x18\x01 \x03(\t\"\x88\x02\n\nTestResult\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12&\n\x06status\x18\x02 \x01(\x0e\x32\x16.TestResult.TestStatus\x12\x18\n\x0b\x64\x65scription\x18\x03 \x01(\tH\x00\x88\x01\x01\x12\x14\n\x0c\x64\x65tails_json\x18\x04 \x01(\t\x12-\n\ttimestamp\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"R\n\nTestStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0b\n\x07RUNNING\x10\x02\x12\x08\n\x04PASS\x10\x03\x12\x08\n\x04\x46\x41IL\x10\x04\x12\t\n\x05\x45RROR\x10\x05\x42\x0e\n\x0c_description\"w\n\x0e\x43laudeMetadata\x12\x10\n\x08\x63ost_usd\x18\x01 \x01(\x01\x12\x13\n\x0b\x64uration_ms\x18\x02 \x01(\x03\x12\x17\n\x0f\x64uration_api_ms\x18\x03 \x01(\x03\x12\x11\n\tnum_turns\x18\x04 \x01(\x05\x12\x12\n\nsession_id\x18\x05 \x01(\t\"q\n\x07TestLog\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12-\n\ttimestamp\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x10\n\x08log_type\x18\x03 \x01(\t\x12\x14\n\x0c\x64\x65tails_json\x18\x04 \x01(\t\"~\n\x08TestInfo\x12\x0f\n\x07test_id\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\x1e\n\thttp_test\x18\x03 \x01(\x0b\x32\t.HttpTestH\x00\x12$\n\x0c\x62rowser_test\x18\x04 \x01(\x0b\x32\x0c.BrowserTestH\x00\x42\x06\n\x04test\"\xb3\x05\n\x1bVerificationProgressMessage\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12=\n\x05stage\x18\x02 \x01(\x0e\x32..VerificationProgressMessage.VerificationStage\x12\x18\n\x05tests\x18\x03 \x03(\x0b\x32\t.TestInfo\x12!\n\x0ctest_results\x18\x04 \x03(\x0b\x32\x0b.TestResult\x12\x1a\n\rerror_message\x18\x05 \x01(\tH\x00\x88\x01\x01\x12\x33\n\nstarted_at\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.TimestampH\x01\x88\x01\x01\x12\x35\n\x0c\x63ompleted_at\x18\x07 \x01(\x0b\x32\x1a.google.protobuf.TimestampH\x02\x88\x01\x01\x12-\n\x0f\x63laude_metadata\x18\x08 \x01(\x0b\x32\x0f.ClaudeMetadataH\x03\x88\x01\x01\x12\x1b\n\ttest_logs\x18\t \x03(\x0b\x32\x08.TestLog\"\xec\x01\n\x11VerificationStage\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x10\n\x0cINITIALIZING\x10\x01\x12\x12\n\x0e\x44IFF_GENERATED\x10\x02\x12\x14\n\x10GENERATING_TESTS\x10\x03\x12\x13\n\x0fTESTS_GENERATED\x10\x04\x12\x11\n\rRUNNING_TESTS\x10\x05\x12\x19\n\x15LOCAL_TESTS_COMPLETED\x10\x06\x12\x17\n\x13VERIFYING_TELEMETRY\x10\x07\x12\x15\n\x11GENERATING_REPORT\x10\x08\x12\x10\n\x0cREPORT_READY\x10\t\x12\t\n\x05\x45RROR\x10\nB\x10\n\x0e_error_messageB\r\n\x0b_started_atB\x0f\n\r_completed_atB\x12\n\x10_claude_metadata\"\xdc\x02\n\x1cVerificationProgressResponse\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12@\n\x06status\x18\x02 \x01(\x0e\x32\x30.VerificationProgressResponse.VerificationStatus\x12\x1a\n\rerror_message\x18\x03 \x01(\tH\x00\x88\x01\x01\x12\x19\n\x0c\x61gent_report\x18\x04 \x01(\tH\x01\x88\x01\x01\x12\x19\n\x0chuman_report\x18\x05 \x01(\tH\x02\x88\x01\x01\"c\n\x12VerificationStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0c\n\x08\x41\x43\x43\x45PTED\x10\x01\x12\t\n\x05\x45RROR\x10\x02\x12\x15\n\x11GENERATING_REPORT\x10\x03\x12\x10\n\x0cREPORT_READY\x10\x04\x42\x10\n\x0e_error_messageB\x0f\n\r_agent_reportB\x0f\n\r_human_report\"$\n\x0b\x41uthMessage\x12\x15\n\rsession_token\x18\x01 \x01(\t\"\xa6\x01\n\x0c\x41uthResponse\x12(\n\x06status\x18\x01 \x01(\x0e\x32\x18.AuthResponse.AuthStatus\x12\x1a\n\rerror_message\x18\x02 \x01(\tH\x00\x88\x01\x01\">\n\nAuthStatus\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x11\n\rAUTHENTICATED\x10\x01\x12\x10\n\x0cUNAUTHORIZED\x10\x02\x42\x10\n\x0e_error_message\"\xdf\x02\n\x0f\x43onnectionStats\x12\x33\n\x0f\x63onnected_since\x18\x01 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x17\n\x0freconnect_count\x18\x02 \x01(\x05\x12\x15\n\rmessages_sent\x18\x03 \x01(\x03\x12\x19\n\x11messages_received\x18\x04 \x01(\x03\x12\x0e\n\x06rtt_ms\x18\x05 \x01(\x03\x12\x17\n\x0frtt_variance_ms\x18\x06 \x01(\x03\x12\x1c\n\x14reconnects_last_hour\x18\x07 \x01(\x05\x12\x1a\n\x12message_latency_ms\x18\x08 \x01(\x03\x12#\n\x1bthroughput_bytes_per_second\x18\t \x01(\x03\x12\x18\n\x10read_deadline_ms\x18\n \x01(\x03\x12\x18\n\x10\x63hunk_size_bytes\x18\x0b \x01(\x05\x12\x10\n\x08\x64\x65graded\x18\x0c \x01(\x08\"\xf2\x03\n\x0cStatusReport\x12-\n\ttimestamp\x18\x01 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0euptime_seconds\x18\x02 \x01(\x03\x12\x14\n\x0clast_push_id\x18\x03 \x01(\t\x12\x33\n\x0elauncher_state\x18\x04 \x01(\x0e\x32\x1b.StatusReport.LauncherState\x12\x14\n\x0clauncher_pid\x18\x05 \x01(\x05\x12\x16\n\x0esync_dir_bytes\x18\x06 \x01(\x03\x12\x1b\n\x13sync_dir_free_bytes\x18\x07 \x01(\x03\x12*\n\x10\x63onnection_stats\x18\x08 \x01(\x0b\x32\x10.ConnectionStats\x12\x19\n\x03pod\x18\t \x01(\x0b\x32\x0c.PodMetadata\x12(\n\x0fworkspace_usage\x18\n \x01(\x0b\x32\x0f.WorkspaceUsage\x12!\n\tresources\x18\x0b \x01(\x0b\x32\x0e.ResourceUsage\x12$\n\rapp_readiness\x18\x0c \x01(\x0b\x32\r.AppReadiness\"K\n\rLauncherState\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07RUNNING\x10\x01\x12\x0f\n\x0bNOT_RUNNING\x10\x02\x12\x0f\n\x0bNO_PID_FILE\x10\x03\"_\n\x0c\x41ppReadiness\x12\r\n\x05ready\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12/\n\x0breported_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"\xe8\x01\n\rResourceUsage\x12\x11\n\trss_bytes\x18\x01 \x01(\x03\x12\x12\n\ncpu_millis\x18\x02 \x01(\x03\x12\x12\n\ngoroutines\x18\x03 \x01(\x05\x12\x1c\n\x14\x62uffered_batch_bytes\x18\x04 \x01(\x03\x12\"\n\x1a\x62uffered_batch_limit_bytes\x18\x05 \x01(\x03\x12\x1a\n\x12memory_limit_bytes\x18\x06 \x01(\x03\x12\x1b\n\x13\x63group_memory_bytes\x18\x07 \x01(\x03\x12!\n\x19\x63group_memory_limit_bytes\x18\x08 \x01(\x03\"E\n\x0bPodMetadata\x12\x10\n\x08pod_name\x18\x01 \x01(\t\x12\x11\n\tnamespace\x18\x02 \x01(\t\x12\x11\n\tnode_name\x18\x03 \x01(\t\"y\n\x08LogEntry\x12-\n\ttimestamp\x18\x01 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x0e\n\x06source\x18\x02 \x01(\t\x12\x0c\n\x04line\x18\x03 \x01(\t\x12\x11\n\ttruncated\x18\x04 \x01(\x08\x12\r\n\x05level\x18\x05 \x01(\t\"&\n\x08LogBatch\x12\x1a\n\x07\x65ntries\x18\x01 \x03(\x0b\x32\t.LogEntry\"L\n\tShellOpen\x12\x12\n\nsession_id\x18\x01 \x01(\t\x12\x0c\n\x04rows\x18\x02 \x01(\r\x12\x0c\n\x04\x63ols\x18\x03 \x01(\r\x12\x0f\n\x07\x63ommand\x18\x04 \x01(\t\"-\n\tShellData\x12\x12\n\nsession_id\x18\x01 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x02 \x01(\x0c\"=\n\x0bShellResize\x12\x12\n\nsession_id\x18\x01 \x01(\t\x12\x0c\n\x04rows\x18\x02 \x01(\r\x12\x0c\n\x04\x63ols\x18\x03 \x01(\r\" \n\nShellClose\x12\x12\n\nsession_id\x18\x01 \x01(\t\"I\n\tShellExit\x12\x12\n\nsession_id\x18\x01 \x01(\t\x12\x11\n\texit_code\x18\x02 \x01(\x05\x12\x15\n\rerror_message\x18\x03 \x01(\t\"\x87\x03\n\x05Hello\x12\x14\n\x0clast_push_id\x18\x01 \x01(\t\x12\x16\n\x0elast_push_hash\x18\x02 \x01(\t\x12\x33\n\x0flast_applied_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x17\n\x0fsidecar_version\x18\x04 \x01(\t\x12\x18\n\x10protocol_version\x18\x05 \x01(\x05\x12\x10\n\x08\x66\x65\x61tures\x18\x06 \x03(\t\x12\x38\n\x11\x61\x63\x63\x65pted_messages\x18\x07 \x03(\x0e\x32\x1d.WebsocketMessage.MessageType\x12\x14\n\x0cresume_token\x18\x08 \x01(\t\x12\x15\n\rrsync_version\x18\t \x01(\t\x12\x16\n\x0ersync_protocol\x18\n \x01(\x05\x12\x16\n\x0e\x63\x61\x63hed_batches\x18\x0b \x03(\t\x12\x15\n\rlast_sequence\x18\x0c \x01(\x03\x12(\n\x0fsecurity_module\x18\r \x01(\x0b\x32\x0f.SecurityModule\"\x85\x01\n\x08HelloAck\x12\x18\n\x10protocol_version\x18\x01 \x01(\x05\x12\x16\n\x0eserver_version\x18\x02 \x01(\t\x12\x10\n\x08\x66\x65\x61tures\x18\x03 \x03(\t\x12\x14\n\x0cresume_token\x18\x04 \x01(\t\x12\x1f\n\x17unacknowledged_push_ids\x18\x05 \x03(\t\"\x1f\n\x0fSnapshotRequest\x12\x0c\n\x04name\x18\x01 \x01(\t\"`\n\x0cSnapshotInfo\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x12\n\nsize_bytes\x18\x02 \x01(\x03\x12.\n\ncreated_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"\xd6\x01\n\x10SnapshotResponse\x12\x0c\n\x04name\x18\x01 \x01(\t\x12(\n\x06status\x18\x02 \x01(\x0e\x32\x18.SnapshotResponse.Status\x12\x15\n\rerror_message\x18\x03 \x01(\t\x12\x1f\n\x08snapshot\x18\x04 \x01(\x0b\x32\r.SnapshotInfo\x12 \n\tsnapshots\x18\x05 \x03(\x0b\x32\r.SnapshotInfo\"0\n\x06Status\x12\x0b\n\x07UNKNOWN\x10\x00\x12\r\n\tCOMPLETED\x10\x01\x12\n\n\x06\x46\x41ILED\x10\x02\"%\n\x0fManifestRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\"n\n\tFileEntry\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x12\n\nsize_bytes\x18\x02 \x01(\x03\x12/\n\x0bmodified_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x0e\n\x06sha256\x18\x04 \x01(\t\"X\n\x10ManifestResponse\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x19\n\x05\x66iles\x18\x02 \x03(\x0b\x32\n.FileEntry\x12\x15\n\rerror_message\x18\x03 \x01(\t\">\n\x11SyncStatusRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x15\n\raudit_entries\x18\x02 \x01(\x05\"\xe5\x01\n\nAuditEntry\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12(\n\x04time\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x15\n\rfiles_changed\x18\x03 \x01(\x05\x12\x18\n\x10\x65nv_keys_changed\x18\x04 \x03(\t\x12)\n\x07outcome\x18\x05 \x01(\x0e\x32\x18.PushResponse.PushStatus\x12\x15\n\rerror_message\x18\x06 \x01(\t\x12\x14\n\x0ctriggered_by\x18\x07 \x01(\t\x12\x13\n\x0b\x61pproved_by\x18\x08 \x01(\t\"/\n\x0e\x45nvFileVersion\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x0f\n\x07version\x18\x02 \x01(\t\"\x95\x02\n\x10\x45nvVarProvenance\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\r\n\x05scope\x18\x02 \x01(\t\x12(\n\x06source\x18\x03 \x01(\x0e\x32\x18.EnvVarProvenance.Source\x12\x10\n\x08provider\x18\x04 \x01(\t\x12\x12\n\nsecret_ref\x18\x05 \x01(\t\x12\x0f\n\x07push_id\x18\x06 \x01(\t\x12\x0f\n\x07version\x18\x07 \x01(\t\x12.\n\nupdated_at\x18\x08 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"B\n\x06Source\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0c\n\x08\x44\x41TABASE\x10\x01\x12\x08\n\x04PUSH\x10\x02\x12\x13\n\x0fSECRET_PROVIDER\x10\x03\"\xa3\x03\n\x12SyncStatusResponse\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x14\n\x0clast_push_id\x18\x02 \x01(\t\x12\x16\n\x0elast_push_hash\x18\x03 \x01(\t\x12\x33\n\x0flast_applied_at\x18\x04 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0eworkspace_hash\x18\x05 \x01(\t\x12\"\n\tenv_files\x18\x06 \x03(\x0b\x32\x0f.EnvFileVersion\x12\x33\n\x0elauncher_state\x18\x07 \x01(\x0e\x32\x1b.StatusReport.LauncherState\x12\x14\n\x0clauncher_pid\x18\x08 \x01(\x05\x12\x16\n\x0e\x61\x63tive_push_id\x18\t \x01(\t\x12\x15\n\rqueued_pushes\x18\n \x01(\x05\x12\x15\n\rerror_message\x18\x0b \x01(\t\x12\x1e\n\taudit_log\x18\x0c \x03(\x0b\x32\x0b.AuditEntry\x12)\n\x0e\x65nv_provenance\x18\r \x03(\x0b\x32\x11.EnvVarProvenance\"E\n\x0e\x46ileGetRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0c\n\x04path\x18\x02 \x01(\t\x12\x11\n\tmax_bytes\x18\x03 \x01(\x03\"\xae\x01\n\x0f\x46ileGetResponse\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0c\n\x04path\x18\x02 \x01(\t\x12\x0f\n\x07\x63ontent\x18\x03 \x01(\x0c\x12\x12\n\nsize_bytes\x18\x04 \x01(\x03\x12\x0c\n\x04mode\x18\x05 \x01(\r\x12/\n\x0bmodified_at\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x15\n\rerror_message\x18\x07 \x01(\t\"2\n\x0e\x44irListRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0c\n\x04path\x18\x02 \x01(\t\"\xcf\x01\n\x08\x44irEntry\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x1c\n\x04type\x18\x02 \x01(\x0e\x32\x0e.DirEntry.Type\x12\x12\n\nsize_bytes\x18\x03 \x01(\x03\x12\x0c\n\x04mode\x18\x04 \x01(\r\x12/\n\x0bmodified_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"D\n\x04Type\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x08\n\x04\x46ILE\x10\x01\x12\r\n\tDIRECTORY\x10\x02\x12\x0b\n\x07SYMLINK\x10\x03\x12\t\n\x05OTHER\x10\x04\"y\n\x0f\x44irListResponse\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0c\n\x04path\x18\x02 \x01(\t\x12\x1a\n\x07\x65ntries\x18\x03 \x03(\x0b\x32\t.DirEntry\x12\x11\n\ttruncated\x18\x04 \x01(\x08\x12\x15\n\rerror_message\x18\x05 \x01(\t\"X\n\x0eLogTailRequest\x12\x0f\n\x07tail_id\x18\x01 \x01(\t\x12\x0c\n\x04path\x18\x02 \x01(\t\x12\r\n\x05lines\x18\x03 \x01(\x05\x12\x18\n\x10\x64uration_seconds\x18\x04 \x01(\x05\"\x1e\n\x0bLogTailStop\x12\x0f\n\x07tail_id\x18\x01 \x01(\t\"D\n\x0bLogTailData\x12\x0f\n\x07tail_id\x18\x01 \x01(\t\x12\r\n\x05lines\x18\x02 \x03(\t\x12\x15\n\rdropped_lines\x18\x03 \x01(\x03\"\x95\x01\n\nLogTailEnd\x12\x0f\n\x07tail_id\x18\x01 \x01(\t\x12\"\n\x06reason\x18\x02 \x01(\x0e\x32\x12.LogTailEnd.Reason\x12\x15\n\rerror_message\x18\x03 \x01(\t\";\n\x06Reason\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x0b\n\x07STOPPED\x10\x01\x12\x0b\n\x07\x45XPIRED\x10\x02\x12\n\n\x06\x46\x41ILED\x10\x03\"H\n\nBatchChunk\x12\x0f\n\x07push_id\x18\x01 \x01(\t\x12\r\n\x05index\x18\x02 \x01(\x05\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\x0c\x12\x0c\n\x04last\x18\x04 \x01(\x08\"\xe3\x01\n\rResyncRequest\x12%\n\x06reason\x18\x01 \x01(\x0e\x32\x15.ResyncRequest.Reason\x12\x0e\n\x06\x64\x65tail\x18\x02 \x01(\t\x12\x16\n\x0eworkspace_hash\x18\x03 \x01(\t\x12\x14\n\x0clast_push_id\x18\x04 \x01(\t\x12\x15\n\rdrifted_files\x18\x05 \x03(\t\"V\n\x06Reason\x12\x0b\n\x07UNKNOWN\x10\x00\x12\t\n\x05\x44RIFT\x10\x01\x12\x0e\n\nCORRUPTION\x10\x02\x12\x11\n\rMISSING_STATE\x10\x03\x12\x11\n\rAPP_REQUESTED\x10\x04\"9\n\x12\x45nvRollbackRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0f\n\x07version\x18\x02 \x01(\t\"h\n\nEnvVersion\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0f\n\x07push_id\x18\x02 \x01(\t\x12.\n\ncreated_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\r\n\x05\x66iles\x18\x04 \x03(\t\"\xf5\x01\n\x13\x45nvRollbackResponse\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12+\n\x06status\x18\x02 \x01(\x0e\x32\x1b.EnvRollbackResponse.Status\x12\x15\n\rerror_message\x18\x03 \x01(\t\x12\x1c\n\x07version\x18\x04 \x01(\x0b\x32\x0b.EnvVersion\x12\x1d\n\x08versions\x18\x05 \x03(\x0b\x32\x0b.EnvVersion\x12\x17\n\x0f\x63urrent_version\x18\x06 \x01(\t\"0\n\x06Status\x12\x0b\n\x07UNKNOWN\x10\x00\x12\r\n\tCOMPLETED\x10\x01\x12\n\n\x06\x46\x41ILED\x10\x02\"\x88\x01\n\x0eLauncherExited\x12\x0b\n\x03pid\x18\x01 \x01(\x05\x12/\n\x0b\x64\x65tected_at\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x0e\n\x06reason\x18\x03 \x01(\t\x12\x12\n\napp_status\x18\x04 \x01(\t\x12\x14\n\x0clast_push_id\x18\x05 \x01(\t\"(\n\x12\x44iagnosticsRequest\x12\x12\n\nrequest_id\x18\x01 \x01(\t\"h\n\x10\x44iagnosticsChunk\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\r\n\x05index\x18\x02 \x01(\x05\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\x0c\x12\x0c\n\x04last\x18\x04 \x01(\x08\x12\x15\n\rerror_message\x18\x05 \x01(\t\"\xd3\x14\n\x10WebsocketMessage\x12\x33\n\x0cmessage_type\x18\x01 \x01(\x0e\x32\x1d.WebsocketMessage.MessageType\x12$\n\x0cpush_message\x18\x02 \x01(\x0b\x32\x0c.PushMessageH\x00\x12&\n\rpush_response\x18\x03 \x01(\x0b\x32\r.PushResponseH\x00\x12=\n\x15verification_progress\x18\x04 \x01(\x0b\x32\x1c.VerificationProgressMessageH\x00\x12G\n\x1everification_progress_response\x18\x05 \x01(\x0b\x32\x1d.VerificationProgressResponseH\x00\x12$\n\x0c\x61uth_message\x18\x06 \x01(\x0b\x32\x0c.AuthMessageH\x00\x12&\n\rauth_response\x18\x07 \x01(\x0b\x32\r.AuthResponseH\x00\x12&\n\rstatus_report\x18\x08 \x01(\x0b\x32\r.StatusReportH\x00\x12\x1e\n\tlog_batch\x18\t \x01(\x0b\x32\t.LogBatchH\x00\x12 \n\nshell_open\x18\n \x01(\x0b\x32\n.ShellOpenH\x00\x12 \n\nshell_data\x18\x0b \x01(\x0b\x32\n.ShellDataH\x00\x12$\n\x0cshell_resize\x18\x0c \x01(\x0b\x32\x0c.ShellResizeH\x00\x12\"\n\x0bshell_close\x18\r \x01(\x0b\x32\x0b.ShellCloseH\x00\x12 \n\nshell_exit\x18\x0e \x01(\x0b\x32\n.ShellExitH\x00\x12\"\n\x0bpush_cancel\x18\x0f \x01(\x0b\x32\x0b.PushCancelH\x00\x12&\n\rpush_progress\x18\x10 \x01(\x0b\x32\r.PushProgressH\x00\x12\x17\n\x05hello\x18\x11 \x01(\x0b\x32\x06.HelloH\x00\x12,\n\x10snapshot_request\x18\x12 \x01(\x0b\x32\x10.SnapshotRequestH\x00\x12.\n\x11snapshot_response\x18\x13 \x01(\x0b\x32\x11.SnapshotResponseH\x00\x12,\n\x10manifest_request\x18\x14 \x01(\x0b\x32\x10.ManifestRequestH\x00\x12.\n\x11manifest_response\x18\x15 \x01(\x0b\x32\x11.ManifestResponseH\x00\x12*\n\x0flauncher_exited\x18\x16 \x01(\x0b\x32\x0f.LauncherExitedH\x00\x12\x1e\n\thello_ack\x18\x17 \x01(\x0b\x32\t.HelloAckH\x00\x12\x32\n\x13\x64iagnostics_request\x18\x18 \x01(\x0b\x32\x13.DiagnosticsRequestH\x00\x12.\n\x11\x64iagnostics_chunk\x18\x19 \x01(\x0b\x32\x11.DiagnosticsChunkH\x00\x12\x31\n\x13sync_status_request\x18\x1a \x01(\x0b\x32\x12.SyncStatusRequestH\x00\x12\x33\n\x14sync_status_response\x18\x1b \x01(\x0b\x32\x13.SyncStatusResponseH\x00\x12+\n\x10\x66ile_get_request\x18\x1c \x01(\x0b\x32\x0f.FileGetRequestH\x00\x12-\n\x11\x66ile_get_response\x18\x1d \x01(\x0b\x32\x10.FileGetResponseH\x00\x12+\n\x10\x64ir_list_request\x18\x1e \x01(\x0b\x32\x0f.DirListRequestH\x00\x12-\n\x11\x64ir_list_response\x18\x1f \x01(\x0b\x32\x10.DirListResponseH\x00\x12+\n\x10log_tail_request\x18  \x01(\x0b\x32\x0f.LogTailRequestH\x00\x12%\n\rlog_tail_stop\x18! \x01(\x0b\x32\x0c.LogTailStopH\x00\x12%\n\rlog_tail_data\x18\" \x01(\x0b\x32\x0c.LogTailDataH\x00\x12#\n\x0clog_tail_end\x18# \x01(\x0b\x32\x0b.LogTailEndH\x00\x12\"\n\x0b\x62\x61tch_chunk\x18$ \x01(\x0b\x32\x0b.BatchChunkH\x00\x12(\n\x0eresync_request\x18% \x01(\x0b\x32\x0e.ResyncRequestH\x00\x12\x33\n\x14\x65nv_rollback_request\x18& \x01(\x0b\x32\x13.EnvRollbackRequestH\x00\x12\x35\n\x15\x65nv_rollback_response\x18\' \x01(\x0b\x32\x14.EnvRollbackResponseH\x00\x12,\n\x10\x61\x63tivate_request\x18( \x01(\x0b\x32\x10.ActivateRequestH\x00\"\xe9\x06\n\x0bMessageType\x12\x0b\n\x07UNKNOWN\x10\x00\x12\x10\n\x0cPUSH_REQUEST\x10\x01\x12\x11\n\rPUSH_RESPONSE\x10\x02\x12\x19\n\x15VERIFICATION_PROGRESS\x10\x03\x12\"\n\x1eVERIFICATION_PROGRESS_RESPONSE\x10\x04\x12\x10\n\x0c\x41UTH_REQUEST\x10\x05\x12\x11\n\rAUTH_RESPONSE\x10\x06\x12\x11\n\rSTATUS_REPORT\x10\x07\x12\r\n\tLOG_ENTRY\x10\x08\x12\x0e\n\nSHELL_OPEN\x10\t\x12\x0f\n\x0bSHELL_STDIN\x10\n\x12\x10\n\x0cSHELL_STDOUT\x10\x0b\x12\x10\n\x0cSHELL_RESIZE\x10\x0c\x12\x0f\n\x0bSHELL_CLOSE\x10\r\x12\x0e\n\nSHELL_EXIT\x10\x0e\x12\x0f\n\x0bPUSH_CANCEL\x10\x0f\x12\x11\n\rPUSH_PROGRESS\x10\x10\x12\x0f\n\x0bSIDECAR_LOG\x10\x11\x12\t\n\x05HELLO\x10\x12\x12\x13\n\x0fSNAPSHOT_CREATE\x10\x13\x12\x14\n\x10SNAPSHOT_RESTORE\x10\x14\x12\x15\n\x11SNAPSHOT_RESPONSE\x10\x15\x12\x14\n\x10MANIFEST_REQUEST\x10\x16\x12\x15\n\x11MANIFEST_RESPONSE\x10\x17\x12\x13\n\x0fLAUNCHER_EXITED\x10\x18\x12\r\n\tHELLO_ACK\x10\x19\x12\x17\n\x13\x44IAGNOSTICS_REQUEST\x10\x1a\x12\x15\n\x11\x44IAGNOSTICS_CHUNK\x10\x1b\x12\x17\n\x13SYNC_STATUS_REQUEST\x10\x1c\x12\x18\n\x14SYNC_STATUS_RESPONSE\x10\x1d\x12\x14\n\x10\x46ILE_GET_REQUEST\x10\x1e\x12\x15\n\x11\x46ILE_GET_RESPONSE\x10\x1f\x12\x14\n\x10\x44IR_LIST_REQUEST\x10 \x12\x15\n\x11\x44IR_LIST_RESPONSE\x10!\x12\x14\n\x10LOG_TAIL_REQUEST\x10\"\x12\x11\n\rLOG_TAIL_STOP\x10#\x12\x11\n\rLOG_TAIL_DATA\x10$\x12\x10\n\x0cLOG_TAIL_END\x10%\x12\x0f\n\x0b\x42\x41TCH_CHUNK\x10&\x12\x12\n\x0eRESYNC_REQUEST\x10\'\x12\x10\n\x0c\x45NV_ROLLBACK\x10(\x12\x19\n\x15\x45NV_ROLLBACK_RESPONSE\x10)\x12\x0c\n\x08\x41\x43TIVATE\x10*B\t\n\x07message\"3\n\x0cMessageBatch\x12#\n\x08messages\x18\x01 \x03(\x0b\x32\x11.WebsocketMessageB:Z8github.com/bifrostinc/code-sync-mcp/code-sync-sidecar/pbb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -33,6 +33,8 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'ws_pb2', _globals)
 if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z8github.com/bifrostinc/code-sync-mcp/code-sync-sidecar/pb'
+  _globals['_PUSHMESSAGE_FILESENTRY']._loaded_options = None
+  _globals['_PUSHMESSAGE_FILESENTRY']._serialized_options = b'8\001'
   _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._loaded_options = None
   _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._serialized_options = b'8\001'
   _globals['_HTTPTEST_INITIALVARIABLESENTRY']._loaded_options = None
@@ -40,57 +42,197 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['_DATABASEBRANCHUPDATE']._serialized_start=46
   _globals['_DATABASEBRANCHUPDATE']._serialized_end=192
   _globals['_PUSHMESSAGE']._serialized_start=195
-  _globals['_PUSHMESSAGE']._serialized_end=409
-  _globals['_PUSHRESPONSE']._serialized_start=412
-  _globals['_PUSHRESPONSE']._serialized_end=592
-  _globals['_PUSHRESPONSE_PUSHSTATUS']._serialized_start=510
-  _globals['_PUSHRESPONSE_PUSHSTATUS']._serialized_end=592
-  _globals['_RESPONSEASSERTION']._serialized_start=595
-  _globals['_RESPONSEASSERTION']._serialized_end=801
-  _globals['_RESPONSEASSERTION_ASSERTIONTYPE']._serialized_start=701
-  _globals['_RESPONSEASSERTION_ASSERTIONTYPE']._serialized_end=792
-  _globals['_VARIABLEEXTRACTION']._serialized_start=804
-  _globals['_VARIABLEEXTRACTION']._serialized_end=980
-  _globals['_VARIABLEEXTRACTION_SOURCETYPE']._serialized_start=907
-  _globals['_VARIABLEEXTRACTION_SOURCETYPE']._serialized_end=971
-  _globals['_HTTPREQUESTSTEP']._serialized_start=983
-  _globals['_HTTPREQUESTSTEP']._serialized_end=1430
-  _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._serialized_start=1284
-  _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._serialized_end=1330
-  _globals['_HTTPREQUESTSTEP_HTTPMETHOD']._serialized_start=1332
-  _globals['_HTTPREQUESTSTEP_HTTPMETHOD']._serialized_end=1421
-  _globals['_HTTPTEST']._serialized_start=1433
-  _globals['_HTTPTEST']._serialized_end=1624
-  _globals['_HTTPTEST_INITIALVARIABLESENTRY']._serialized_start=1569
-  _globals['_HTTPTEST_INITIALVARIABLESENTRY']._serialized_end=1624
-  _globals['_BROWSERTEST']._serialized_start=1626
-  _globals['_BROWSERTEST']._serialized_end=1663
-  _globals['_TESTRESULT']._serialized_start=1666
-  _globals['_TESTRESULT']._serialized_end=1930
-  _globals['_TESTRESULT_TESTSTATUS']._serialized_start=1832
-  _globals['_TESTRESULT_TESTSTATUS']._serialized_end=1914
-  _globals['_CLAUDEMETADATA']._serialized_start=1932
-  _globals['_CLAUDEMETADATA']._serialized_end=2051
-  _globals['_TESTLOG']._serialized_start=2053
-  _globals['_TESTLOG']._serialized_end=2166
-  _globals['_TESTINFO']._serialized_start=2168
-  _globals['_TESTINFO']._serialized_end=2294
-  _globals['_VERIFICATIONPROGRESSMESSAGE']._serialized_start=2297
-  _globals['_VERIFICATIONPROGRESSMESSAGE']._serialized_end=2988
-  _globals['_VERIFICATIONPROGRESSMESSAGE_VERIFICATIONSTAGE']._serialized_start=2682
-  _globals['_VERIFICATIONPROGRESSMESSAGE_VERIFICATIONSTAGE']._serialized_end=2918
-  _globals['_VERIFICATIONPROGRESSRESPONSE']._serialized_start=2991
-  _globals['_VERIFICATIONPROGRESSRESPONSE']._serialized_end=3339
-  _globals['_VERIFICATIONPROGRESSRESPONSE_VERIFICATIONSTATUS']._serialized_start=3188
-  _globals['_VERIFICATIONPROGRESSRESPONSE_VERIFICATIONSTATUS']._serialized_end=3287
-  _globals['_AUTHMESSAGE']._serialized_start=3341
-  _globals['_AUTHMESSAGE']._serialized_end=3377
-  _globals['_AUTHRESPONSE']._serialized_start=3380
-  _globals['_AUTHRESPONSE']._serialized_end=3546
-  _globals['_AUTHRESPONSE_AUTHSTATUS']._serialized_start=3466
-  _globals['_AUTHRESPONSE_AUTHSTATUS']._serialized_end=3528
-  _globals['_WEBSOCKETMESSAGE']._serialized_start=3549
-  _globals['_WEBSOCKETMESSAGE']._serialized_end=4089
-  _globals['_WEBSOCKETMESSAGE_MESSAGETYPE']._serialized_start=3915
-  _globals['_WEBSOCKETMESSAGE_MESSAGETYPE']._serialized_end=4078
+  _globals['_PUSHMESSAGE']._serialized_end=902
+  _globals['_PUSHMESSAGE_FILESENTRY']._serialized_start=843
+  _globals['_PUSHMESSAGE_FILESENTRY']._serialized_end=902
+  _globals['_SUBTREEBATCH']._serialized_start=904
+  _globals['_SUBTREEBATCH']._serialized_end=952
+  _globals['_ACTIVATIONSTAGE']._serialized_start=954
+  _globals['_ACTIVATIONSTAGE']._serialized_end=990
+  _globals['_INJECTEDFILE']._serialized_start=992
+  _globals['_INJECTEDFILE']._serialized_end=1055
+  _globals['_INJECTEDFILERESULT']._serialized_start=1057
+  _globals['_INJECTEDFILERESULT']._serialized_end=1131
+  _globals['_DELETEDPATHRESULT']._serialized_start=1134
+  _globals['_DELETEDPATHRESULT']._serialized_end=1314
+  _globals['_DELETEDPATHRESULT_STATUS']._serialized_start=1235
+  _globals['_DELETEDPATHRESULT_STATUS']._serialized_end=1314
+  _globals['_HOOKRESULT']._serialized_start=1316
+  _globals['_HOOKRESULT']._serialized_end=1398
+  _globals['_PUSHRESPONSE']._serialized_start=1401
+  _globals['_PUSHRESPONSE']._serialized_end=2826
+  _globals['_PUSHRESPONSE_PUSHSTATUS']._serialized_start=2390
+  _globals['_PUSHRESPONSE_PUSHSTATUS']._serialized_end=2826
+  _globals['_SECURITYDENIAL']._serialized_start=2828
+  _globals['_SECURITYDENIAL']._serialized_end=2900
+  _globals['_SECURITYMODULE']._serialized_start=2902
+  _globals['_SECURITYMODULE']._serialized_end=2961
+  _globals['_SUBTREERESULT']._serialized_start=2963
+  _globals['_SUBTREERESULT']._serialized_end=3076
+  _globals['_SEQUENCEGAP']._serialized_start=3078
+  _globals['_SEQUENCEGAP']._serialized_end=3120
+  _globals['_LAUNCHERSIGNALRESULT']._serialized_start=3123
+  _globals['_LAUNCHERSIGNALRESULT']._serialized_end=3269
+  _globals['_WORKSPACEUSAGE']._serialized_start=3272
+  _globals['_WORKSPACEUSAGE']._serialized_end=3418
+  _globals['_PUSHTIMING']._serialized_start=3421
+  _globals['_PUSHTIMING']._serialized_end=3621
+  _globals['_REPLICARESULT']._serialized_start=3623
+  _globals['_REPLICARESULT']._serialized_end=3739
+  _globals['_PUSHPROGRESS']._serialized_start=3742
+  _globals['_PUSHPROGRESS']._serialized_end=3987
+  _globals['_PUSHPROGRESS_STAGE']._serialized_start=3891
+  _globals['_PUSHPROGRESS_STAGE']._serialized_end=3987
+  _globals['_PUSHCANCEL']._serialized_start=3989
+  _globals['_PUSHCANCEL']._serialized_end=4018
+  _globals['_ACTIVATEREQUEST']._serialized_start=4020
+  _globals['_ACTIVATEREQUEST']._serialized_end=4075
+  _globals['_RESPONSEASSERTION']._serialized_start=4078
+  _globals['_RESPONSEASSERTION']._serialized_end=4284
+  _globals['_RESPONSEASSERTION_ASSERTIONTYPE']._serialized_start=4184
+  _globals['_RESPONSEASSERTION_ASSERTIONTYPE']._serialized_end=4275
+  _globals['_VARIABLEEXTRACTION']._serialized_start=4287
+  _globals['_VARIABLEEXTRACTION']._serialized_end=4463
+  _globals['_VARIABLEEXTRACTION_SOURCETYPE']._serialized_start=4390
+  _globals['_VARIABLEEXTRACTION_SOURCETYPE']._serialized_end=4454
+  _globals['_HTTPREQUESTSTEP']._serialized_start=4466
+  _globals['_HTTPREQUESTSTEP']._serialized_end=4913
+  _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._serialized_start=4767
+  _globals['_HTTPREQUESTSTEP_HEADERSENTRY']._serialized_end=4813
+  _globals['_HTTPREQUESTSTEP_HTTPMETHOD']._serialized_start=4815
+  _globals['_HTTPREQUESTSTEP_HTTPMETHOD']._serialized_end=4904
+  _globals['_HTTPTEST']._serialized_start=4916
+  _globals['_HTTPTEST']._serialized_end=5107
+  _globals['_HTTPTEST_INITIALVARIABLESENTRY']._serialized_start=5052
+  _globals['_HTTPTEST_INITIALVARIABLESENTRY']._serialized_end=5107
+  _globals['_BROWSERTEST']._serialized_start=5109
+  _globals['_BROWSERTEST']._serialized_end=5146
+  _globals['_TESTRESULT']._serialized_start=5149
+  _globals['_TESTRESULT']._serialized_end=5413
+  _globals['_TESTRESULT_TESTSTATUS']._serialized_start=5315
+  _globals['_TESTRESULT_TESTSTATUS']._serialized_end=5397
+  _globals['_CLAUDEMETADATA']._serialized_start=5415
+  _globals['_CLAUDEMETADATA']._serialized_end=5534
+  _globals['_TESTLOG']._serialized_start=5536
+  _globals['_TESTLOG']._serialized_end=5649
+  _globals['_TESTINFO']._serialized_start=5651
+  _globals['_TESTINFO']._serialized_end=5777
+  _globals['_VERIFICATIONPROGRESSMESSAGE']._serialized_start=5780
+  _globals['_VERIFICATIONPROGRESSMESSAGE']._serialized_end=6471
+  _globals['_VERIFICATIONPROGRESSMESSAGE_VERIFICATIONSTAGE']._serialized_start=6165
+  _globals['_VERIFICATIONPROGRESSMESSAGE_VERIFICATIONSTAGE']._serialized_end=6401
+  _globals['_VERIFICATIONPROGRESSRESPONSE']._serialized_start=6474
+  _globals['_VERIFICATIONPROGRESSRESPONSE']._serialized_end=6822
+  _globals['_VERIFICATIONPROGRESSRESPONSE_VERIFICATIONSTATUS']._serialized_start=6671
+  _globals['_VERIFICATIONPROGRESSRESPONSE_VERIFICATIONSTATUS']._serialized_end=6770
+  _globals['_AUTHMESSAGE']._serialized_start=6824
+  _globals['_AUTHMESSAGE']._serialized_end=6860
+  _globals['_AUTHRESPONSE']._serialized_start=6863
+  _globals['_AUTHRESPONSE']._serialized_end=7029
+  _globals['_AUTHRESPONSE_AUTHSTATUS']._serialized_start=6949
+  _globals['_AUTHRESPONSE_AUTHSTATUS']._serialized_end=7011
+  _globals['_CONNECTIONSTATS']._serialized_start=7032
+  _globals['_CONNECTIONSTATS']._serialized_end=7383
+  _globals['_STATUSREPORT']._serialized_start=7386
+  _globals['_STATUSREPORT']._serialized_end=7884
+  _globals['_STATUSREPORT_LAUNCHERSTATE']._serialized_start=7809
+  _globals['_STATUSREPORT_LAUNCHERSTATE']._serialized_end=7884
+  _globals['_APPREADINESS']._serialized_start=7886
+  _globals['_APPREADINESS']._serialized_end=7981
+  _globals['_RESOURCEUSAGE']._serialized_start=7984
+  _globals['_RESOURCEUSAGE']._serialized_end=8216
+  _globals['_PODMETADATA']._serialized_start=8218
+  _globals['_PODMETADATA']._serialized_end=8287
+  _globals['_LOGENTRY']._serialized_start=8289
+  _globals['_LOGENTRY']._serialized_end=8410
+  _globals['_LOGBATCH']._serialized_start=8412
+  _globals['_LOGBATCH']._serialized_end=8450
+  _globals['_SHELLOPEN']._serialized_start=8452
+  _globals['_SHELLOPEN']._serialized_end=8528
+  _globals['_SHELLDATA']._serialized_start=8530
+  _globals['_SHELLDATA']._serialized_end=8575
+  _globals['_SHELLRESIZE']._serialized_start=8577
+  _globals['_SHELLRESIZE']._serialized_end=8638
+  _globals['_SHELLCLOSE']._serialized_start=8640
+  _globals['_SHELLCLOSE']._serialized_end=8672
+  _globals['_SHELLEXIT']._serialized_start=8674
+  _globals['_SHELLEXIT']._serialized_end=8747
+  _globals['_HELLO']._serialized_start=8750
+  _globals['_HELLO']._serialized_end=9141
+  _globals['_HELLOACK']._serialized_start=9144
+  _globals['_HELLOACK']._serialized_end=9277
+  _globals['_SNAPSHOTREQUEST']._serialized_start=9279
+  _globals['_SNAPSHOTREQUEST']._serialized_end=9310
+  _globals['_SNAPSHOTINFO']._serialized_start=9312
+  _globals['_SNAPSHOTINFO']._serialized_end=9408
+  _globals['_SNAPSHOTRESPONSE']._serialized_start=9411
+  _globals['_SNAPSHOTRESPONSE']._serialized_end=9625
+  _globals['_SNAPSHOTRESPONSE_STATUS']._serialized_start=9577
+  _globals['_SNAPSHOTRESPONSE_STATUS']._serialized_end=9625
+  _globals['_MANIFESTREQUEST']._serialized_start=9627
+  _globals['_MANIFESTREQUEST']._serialized_end=9664
+  _globals['_FILEENTRY']._serialized_start=9666
+  _globals['_FILEENTRY']._serialized_end=9776
+  _globals['_MANIFESTRESPONSE']._serialized_start=9778
+  _globals['_MANIFESTRESPONSE']._serialized_end=9866
+  _globals['_SYNCSTATUSREQUEST']._serialized_start=9868
+  _globals['_SYNCSTATUSREQUEST']._serialized_end=9930
+  _globals['_AUDITENTRY']._serialized_start=9933
+  _globals['_AUDITENTRY']._serialized_end=10162
+  _globals['_ENVFILEVERSION']._serialized_start=10164
+  _globals['_ENVFILEVERSION']._serialized_end=10211
+  _globals['_ENVVARPROVENANCE']._serialized_start=10214
+  _globals['_ENVVARPROVENANCE']._serialized_end=10491
+  _globals['_ENVVARPROVENANCE_SOURCE']._serialized_start=10425
+  _globals['_ENVVARPROVENANCE_SOURCE']._serialized_end=10491
+  _globals['_SYNCSTATUSRESPONSE']._serialized_start=10494
+  _globals['_SYNCSTATUSRESPONSE']._serialized_end=10913
+  _globals['_FILEGETREQUEST']._serialized_start=10915
+  _globals['_FILEGETREQUEST']._serialized_end=10984
+  _globals['_FILEGETRESPONSE']._serialized_start=10987
+  _globals['_FILEGETRESPONSE']._serialized_end=11161
+  _globals['_DIRLISTREQUEST']._serialized_start=11163
+  _globals['_DIRLISTREQUEST']._serialized_end=11213
+  _globals['_DIRENTRY']._serialized_start=11216
+  _globals['_DIRENTRY']._serialized_end=11423
+  _globals['_DIRENTRY_TYPE']._serialized_start=11355
+  _globals['_DIRENTRY_TYPE']._serialized_end=11423
+  _globals['_DIRLISTRESPONSE']._serialized_start=11425
+  _globals['_DIRLISTRESPONSE']._serialized_end=11546
+  _globals['_LOGTAILREQUEST']._serialized_start=11548
+  _globals['_LOGTAILREQUEST']._serialized_end=11636
+  _globals['_LOGTAILSTOP']._serialized_start=11638
+  _globals['_LOGTAILSTOP']._serialized_end=11668
+  _globals['_LOGTAILDATA']._serialized_start=11670
+  _globals['_LOGTAILDATA']._serialized_end=11738
+  _globals['_LOGTAILEND']._serialized_start=11741
+  _globals['_LOGTAILEND']._serialized_end=11890
+  _globals['_LOGTAILEND_REASON']._serialized_start=11831
+  _globals['_LOGTAILEND_REASON']._serialized_end=11890
+  _globals['_BATCHCHUNK']._serialized_start=11892
+  _globals['_BATCHCHUNK']._serialized_end=11964
+  _globals['_RESYNCREQUEST']._serialized_start=11967
+  _globals['_RESYNCREQUEST']._serialized_end=12194
+  _globals['_RESYNCREQUEST_REASON']._serialized_start=12108
+  _globals['_RESYNCREQUEST_REASON']._serialized_end=12194
+  _globals['_ENVROLLBACKREQUEST']._serialized_start=12196
+  _globals['_ENVROLLBACKREQUEST']._serialized_end=12253
+  _globals['_ENVVERSION']._serialized_start=12255
+  _globals['_ENVVERSION']._serialized_end=12359
+  _globals['_ENVROLLBACKRESPONSE']._serialized_start=12362
+  _globals['_ENVROLLBACKRESPONSE']._serialized_end=12607
+  _globals['_ENVROLLBACKRESPONSE_STATUS']._serialized_start=9577
+  _globals['_ENVROLLBACKRESPONSE_STATUS']._serialized_end=9625
+  _globals['_LAUNCHEREXITED']._serialized_start=12610
+  _globals['_LAUNCHEREXITED']._serialized_end=12746
+  _globals['_DIAGNOSTICSREQUEST']._serialized_start=12748
+  _globals['_DIAGNOSTICSREQUEST']._serialized_end=12788
+  _globals['_DIAGNOSTICSCHUNK']._serialized_start=12790
+  _globals['_DIAGNOSTICSCHUNK']._serialized_end=12894
+  _globals['_WEBSOCKETMESSAGE']._serialized_start=12897
+  _globals['_WEBSOCKETMESSAGE']._serialized_end=15540
+  _globals['_WEBSOCKETMESSAGE_MESSAGETYPE']._serialized_start=14656
+  _globals['_WEBSOCKETMESSAGE_MESSAGETYPE']._serialized_end=15529
+  _globals['_MESSAGEBATCH']._serialized_start=15542
+  _globals['_MESSAGEBATCH']._serialized_end=15593
 # @@protoc_insertion_point(module_scope)
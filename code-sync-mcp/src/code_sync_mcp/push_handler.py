@@ -15,6 +15,15 @@ from code_sync_mcp.pb import ws_pb2
 log = logging.getLogger(__name__)
 
 PushStatusPb = ws_pb2.PushResponse.PushStatus
+PushProgressStagePb = ws_pb2.PushProgress.Stage
+
+# Push responses with these statuses are followed by the push's final response.
+INTERMEDIATE_PUSH_STATUSES = {
+    PushStatusPb.RECEIVED,
+    PushStatusPb.APPLYING,
+    PushStatusPb.RELOADING,
+    PushStatusPb.HEALTHY,
+}
 
 
 class PushFuture(Future):
@@ -70,9 +79,32 @@ class PushHandler:
         await websocket.send(ws_msg.SerializeToString())
 
         log.info("Batch data sent. Waiting for potential ACK/response...")
-        response_bytes = await asyncio.wait_for(websocket.recv(), timeout=10.0)
-        response_msg = ws_pb2.WebsocketMessage()
-        response_msg.ParseFromString(response_bytes)
+        while True:
+            response_bytes = await asyncio.wait_for(websocket.recv(), timeout=10.0)
+            response_msg = ws_pb2.WebsocketMessage()
+            response_msg.ParseFromString(response_bytes)
+            if (
+                response_msg.message_type
+                == ws_pb2.WebsocketMessage.MessageType.PUSH_RESPONSE
+                and response_msg.push_response.status in INTERMEDIATE_PUSH_STATUSES
+            ):
+                log.info(
+                    f"Push status: {PushStatusPb.Name(response_msg.push_response.status)}"
+                )
+                continue
+            if (
+                response_msg.message_type
+                != ws_pb2.WebsocketMessage.MessageType.PUSH_PROGRESS
+            ):
+                break
+            progress = response_msg.push_progress
+            if progress.output_lines:
+                for line in progress.output_lines:
+                    log.info(f"Build: {line}")
+                continue
+            log.info(
+                f"Push progress: {PushProgressStagePb.Name(progress.stage)} {progress.percent}%"
+            )
 
         msg_type = response_msg.message_type
         log.info(f"Received response from proxy: type={msg_type}")
@@ -83,6 +115,28 @@ class PushHandler:
             if status == PushStatusPb.COMPLETED:
                 if not push_future.done():
                     push_future.set_result(PushResult(push_future.push_id, "done"))
+            elif status == PushStatusPb.PENDING:
+                # Held by the sidecar for a push window; it's applied later.
+                held_until = response_msg.push_response.held_until.ToDatetime()
+                log.info(f"Push held until {held_until.isoformat()}Z")
+                if not push_future.done():
+                    push_future.set_result(PushResult(push_future.push_id, "held"))
+            elif status == PushStatusPb.AWAITING_APPROVAL:
+                # Staged by the sidecar; it's activated once someone approves it.
+                log.info("Push staged, awaiting approval")
+                if not push_future.done():
+                    push_future.set_result(
+                        PushResult(push_future.push_id, "awaiting_approval")
+                    )
+            elif status == PushStatusPb.SUPERSEDED:
+                # A later push carrying these files too was applied instead.
+                log.info(
+                    f"Push superseded by {response_msg.push_response.superseded_by}"
+                )
+                if not push_future.done():
+                    push_future.set_result(
+                        PushResult(push_future.push_id, "superseded")
+                    )
             else:
                 err_msg = f"Push failed with status {PushStatusPb.Name(status)}: {response_msg.push_response.error_message}"
                 log.error(err_msg)
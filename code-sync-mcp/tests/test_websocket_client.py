@@ -436,6 +436,66 @@ async def test_send_push_request_success(
     assert push_future.result() == PushResult(push_future.push_id, "done")
 
 
+@pytest.mark.asyncio
+async def test_send_push_request_skips_progress(
+    test_client: WebsocketClient,
+):
+    """Test that PUSH_PROGRESS updates are consumed while waiting for the PUSH_RESPONSE."""
+    mock_ws = test_client._websocket
+    mock_push_handler = test_client._push_handler
+    mock_push_handler.generate_batch.return_value = b"fake_batch_data"
+
+    progress_message = ws_pb2.WebsocketMessage(
+        message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_PROGRESS,
+        push_progress=ws_pb2.PushProgress(
+            stage=ws_pb2.PushProgress.Stage.APPLYING,
+            percent=50,
+        ),
+    )
+    response_message = ws_pb2.WebsocketMessage(
+        message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_RESPONSE,
+        push_response=ws_pb2.PushResponse(
+            status=PushStatusPb.COMPLETED,
+        ),
+    )
+    mock_ws.recv.side_effect = [
+        progress_message.SerializeToString(),
+        response_message.SerializeToString(),
+    ]
+
+    push_future = PushFuture("test-code-diff", "test-change-description")
+    await test_client._dispatch_request_handler(mock_ws, push_future)
+
+    assert mock_ws.recv.await_count == 2
+    assert push_future.done()
+    assert push_future.result() == PushResult(push_future.push_id, "done")
+
+
+@pytest.mark.asyncio
+async def test_send_push_request_superseded(
+    test_client: WebsocketClient,
+):
+    """Test that a push superseded by a later one in the sidecar's debounce window succeeds."""
+    mock_ws = test_client._websocket
+    mock_push_handler = test_client._push_handler
+    mock_push_handler.generate_batch.return_value = b"fake_batch_data"
+
+    response_message = ws_pb2.WebsocketMessage(
+        message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_RESPONSE,
+        push_response=ws_pb2.PushResponse(
+            status=PushStatusPb.SUPERSEDED,
+            superseded_by="later-push",
+        ),
+    )
+    mock_ws.recv.return_value = response_message.SerializeToString()
+
+    push_future = PushFuture("test-code-diff", "test-change-description")
+    await test_client._dispatch_request_handler(mock_ws, push_future)
+
+    assert push_future.done()
+    assert push_future.result() == PushResult(push_future.push_id, "superseded")
+
+
 @pytest.mark.asyncio
 async def test_send_push_request_no_changes(
     test_client: WebsocketClient,
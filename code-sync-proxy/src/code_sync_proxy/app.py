@@ -1,4 +1,12 @@
-from fastapi import FastAPI, APIRouter, WebSocket, WebSocketDisconnect
+from fastapi import (
+    FastAPI,
+    APIRouter,
+    HTTPException,
+    Request,
+    Response,
+    WebSocket,
+    WebSocketDisconnect,
+)
 
 from .ws.manager import default_manager
 from .config import init_config
@@ -52,6 +60,39 @@ async def sidecar_endpoint(websocket: WebSocket, app_id: str, deployment_id: str
             await websocket.close(code=1011)  # Internal server error
 
 
+@api.get("/api/v1/push/pending/{app_id}/{deployment_id}")
+async def sidecar_poll_endpoint(app_id: str, deployment_id: str, wait: float = 25.0):
+    """Long-poll endpoint for sidecars whose network blocks websockets.
+
+    Returns a serialized MessageBatch of the messages queued for the sidecar,
+    empty if none arrived within `wait` seconds.
+    """
+    try:
+        batch = await default_manager.poll_sidecar(app_id, deployment_id, wait)
+    except PermissionError as e:
+        raise HTTPException(status_code=403, detail=str(e))
+    except ConnectionError as e:
+        raise HTTPException(status_code=409, detail=str(e))
+    return Response(
+        content=batch.SerializeToString(), media_type="application/x-protobuf"
+    )
+
+
+@api.post("/api/v1/push/pending/{app_id}/{deployment_id}", status_code=204)
+async def sidecar_post_endpoint(app_id: str, deployment_id: str, request: Request):
+    """Receive one serialized WebsocketMessage from a long-polling sidecar."""
+    data = await request.body()
+    try:
+        await default_manager.post_from_sidecar(app_id, deployment_id, data)
+    except PermissionError as e:
+        raise HTTPException(status_code=403, detail=str(e))
+    except ConnectionError as e:
+        raise HTTPException(status_code=409, detail=str(e))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+    return Response(status_code=204)
+
+
 @api.get("/api/v1/push/ide/{app_id}/{deployment_id}/ready")
 async def check_sidecar_ready(app_id: str, deployment_id: str):
     """Check if a sidecar is ready for the specified app/deployment."""
@@ -1,5 +1,8 @@
+import asyncio
 import logging
-from typing import Optional, Dict, Protocol
+import secrets
+from dataclasses import dataclass, field
+from typing import Optional, Dict, List, Protocol, Set, Tuple
 
 from fastapi import WebSocket, WebSocketDisconnect
 
@@ -12,6 +15,10 @@ from code_sync_proxy.ws.registry import (
 )
 from code_sync_proxy.ws.connection_store import ConnectionStore, create_connection_store
 from code_sync_proxy.ws.message import MessageFactory
+from code_sync_proxy.ws.polling import (
+    PollingSidecarConnection,
+    POLL_SESSION_TIMEOUT_SECONDS,
+)
 from code_sync_proxy.ws.interfaces import (
     PushRepository,
     DeploymentVerifier,
@@ -32,6 +39,32 @@ class MessageHandler(Protocol):
 # Message type enums for cleaner reference
 PushStatusPb = ws_pb2.PushResponse.PushStatus
 
+# Push responses with these statuses are followed by the push's final response.
+INTERMEDIATE_PUSH_STATUSES = {
+    PushStatusPb.PENDING,
+    PushStatusPb.RECEIVED,
+    PushStatusPb.APPLYING,
+    PushStatusPb.AWAITING_APPROVAL,
+    PushStatusPb.RELOADING,
+    PushStatusPb.HEALTHY,
+}
+
+# Version of the sidecar protocol this proxy speaks, sent back in HELLO_ACK.
+PROTOCOL_VERSION = 1
+
+# Batches bigger than this are sent in BATCH_CHUNK messages of this size to
+# sidecars that accept them, so control messages aren't held up behind them.
+BATCH_CHUNK_SIZE = 1024 * 1024
+
+
+@dataclass
+class SidecarSession:
+    """What the proxy remembers about a sidecar across reconnects."""
+
+    resume_token: str
+    # Pushes forwarded to the sidecar that haven't had a final response yet
+    in_flight: Set[str] = field(default_factory=set)
+
 
 async def send_websocket_message(
     websocket: WebSocket, message: ws_pb2.WebsocketMessage
@@ -49,6 +82,13 @@ async def recv_websocket_message(websocket: WebSocket) -> ws_pb2.WebsocketMessag
         raise ValueError(f"Received invalid websocket message: {e}")
 
 
+def _describe_pod(pod: ws_pb2.PodMetadata) -> str:
+    """Format where a sidecar runs for a log line; empty outside Kubernetes."""
+    if not (pod.pod_name or pod.namespace or pod.node_name):
+        return ""
+    return f", pod={pod.namespace or '?'}/{pod.pod_name or '?'}, node={pod.node_name or '?'}"
+
+
 class BaseWebSocketManager:
     """Base WebSocket manager class with pluggable components for deployment."""
 
@@ -67,12 +107,51 @@ class BaseWebSocketManager:
         self.registry = self._local_registry
         self.cx_store: ConnectionStore = connection_store or create_connection_store()
 
+        # Capabilities each sidecar connected to this worker advertised in its HELLO
+        self._sidecar_capabilities: Dict[ConnectionKey, ws_pb2.Hello] = {}
+        # Kept across disconnects, so a reconnecting sidecar can resume its session
+        self._sidecar_sessions: Dict[ConnectionKey, SidecarSession] = {}
+        # Pushes whose batches are still being streamed to the sidecar, by push ID
+        self._batch_streams: Dict[str, asyncio.Task] = {}
+        # The last sequence given to each deployment's pushes, so the sidecar can
+        # reject one delayed past a newer one
+        self._push_sequences: Dict[ConnectionKey, int] = {}
+
         # Handlers for each message type
         self._message_handlers: Dict[
             ws_pb2.WebsocketMessage.MessageType, MessageHandler
         ] = {
             ws_pb2.WebsocketMessage.MessageType.PUSH_REQUEST: self._handle_push_request,
             ws_pb2.WebsocketMessage.MessageType.PUSH_RESPONSE: self._handle_push_response,
+            ws_pb2.WebsocketMessage.MessageType.STATUS_REPORT: self._handle_status_report,
+            ws_pb2.WebsocketMessage.MessageType.LOG_ENTRY: self._handle_log_entry,
+            ws_pb2.WebsocketMessage.MessageType.SIDECAR_LOG: self._handle_sidecar_log,
+            ws_pb2.WebsocketMessage.MessageType.HELLO: self._handle_hello,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_OPEN: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_STDIN: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_RESIZE: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_CLOSE: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_STDOUT: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.SHELL_EXIT: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.PUSH_CANCEL: self._handle_push_cancel,
+            ws_pb2.WebsocketMessage.MessageType.PUSH_PROGRESS: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.SNAPSHOT_CREATE: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SNAPSHOT_RESTORE: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SNAPSHOT_RESPONSE: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.MANIFEST_REQUEST: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.MANIFEST_RESPONSE: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.SYNC_STATUS_REQUEST: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.SYNC_STATUS_RESPONSE: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.FILE_GET_REQUEST: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.FILE_GET_RESPONSE: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.DIR_LIST_REQUEST: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.DIR_LIST_RESPONSE: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.LOG_TAIL_REQUEST: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.LOG_TAIL_STOP: self._forward_to_sidecar,
+            ws_pb2.WebsocketMessage.MessageType.LOG_TAIL_DATA: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.LOG_TAIL_END: self._forward_to_ide,
+            ws_pb2.WebsocketMessage.MessageType.LAUNCHER_EXITED: self._handle_launcher_exited,
+            ws_pb2.WebsocketMessage.MessageType.RESYNC_REQUEST: self._handle_resync_request,
         }
 
     def _make_key(
@@ -152,6 +231,8 @@ class BaseWebSocketManager:
         """Remove a connection from both local storage and the connection store."""
         self.cx_store.deregister_connection(conn_type, conn_key)
         self.registry.deregister_connection(conn_type, conn_key)
+        if conn_type == ConnectionType.SIDECAR:
+            self._sidecar_capabilities.pop(conn_key, None)
         log.info(
             f"{conn_type} connection removed from local store and cx_store by worker {settings.worker_id}.",
             extra=conn_key.log_fields(),
@@ -261,6 +342,12 @@ class BaseWebSocketManager:
             extra=log_extra,
         )
 
+        existing = self.registry.get_connection(ConnectionType.SIDECAR, conn_key)
+        if isinstance(existing, PollingSidecarConnection):
+            # The sidecar got through with a websocket again; stop queueing for its polls.
+            log.info("SIDECAR switched from long-polling to websocket", extra=log_extra)
+            self._remove_connection(ConnectionType.SIDECAR, conn_key)
+
         try:
             conn_type = ConnectionType.SIDECAR
             self._store_connection(conn_type, conn_key, websocket)
@@ -341,6 +428,85 @@ class BaseWebSocketManager:
                 code=1011, reason="Unexpected server error during IDE attach"
             )
 
+    # --- HTTP long-polling, for sidecars that can't open a websocket ---
+
+    def _polling_sidecar(
+        self,
+        app_id: str,
+        deployment_id: str,
+        org_id: Optional[str] = None,
+        user_id: Optional[str] = None,
+    ) -> Tuple[ConnectionKey, PollingSidecarConnection]:
+        """Return the sidecar's polling session, registering one on its first request.
+
+        Raises PermissionError if the deployment isn't valid, and ConnectionError
+        if the sidecar is already connected some other way.
+        """
+        is_valid, error_message, _ = self.deployment_verifier.verify_deployment(
+            app_id, deployment_id
+        )
+        if not is_valid:
+            raise PermissionError(error_message)
+
+        conn_key = self._make_key(app_id, deployment_id, org_id, user_id)
+        conn = self.registry.get_connection(ConnectionType.SIDECAR, conn_key)
+        if isinstance(conn, PollingSidecarConnection):
+            conn.touch()
+            return conn_key, conn
+
+        conn = PollingSidecarConnection()
+        self._store_connection(ConnectionType.SIDECAR, conn_key, conn)
+        log.info("SIDECAR connected by HTTP long-polling", extra=conn_key.log_fields())
+        asyncio.create_task(self._expire_polling_sidecar(conn_key, conn))
+        return conn_key, conn
+
+    async def _expire_polling_sidecar(
+        self, conn_key: ConnectionKey, conn: PollingSidecarConnection
+    ) -> None:
+        """Detach a polling sidecar once it stops polling."""
+        while not conn.expired():
+            await asyncio.sleep(POLL_SESSION_TIMEOUT_SECONDS / 2)
+        if self.registry.get_connection(ConnectionType.SIDECAR, conn_key) is conn:
+            log.info("Polling SIDECAR stopped polling", extra=conn_key.log_fields())
+            self._remove_connection(ConnectionType.SIDECAR, conn_key)
+
+    async def poll_sidecar(
+        self,
+        app_id: str,
+        deployment_id: str,
+        wait: float,
+        org_id: Optional[str] = None,
+        user_id: Optional[str] = None,
+    ) -> ws_pb2.MessageBatch:
+        """Wait up to `wait` seconds for messages for a polling sidecar."""
+        _, conn = self._polling_sidecar(app_id, deployment_id, org_id, user_id)
+        batch = ws_pb2.MessageBatch()
+        for data in await conn.drain(wait):
+            batch.messages.add().ParseFromString(data)
+        return batch
+
+    async def post_from_sidecar(
+        self,
+        app_id: str,
+        deployment_id: str,
+        data: bytes,
+        org_id: Optional[str] = None,
+        user_id: Optional[str] = None,
+    ) -> None:
+        """Handle a message a polling sidecar posted, as if it came over its websocket."""
+        conn_key, _ = self._polling_sidecar(app_id, deployment_id, org_id, user_id)
+        message = ws_pb2.WebsocketMessage()
+        try:
+            message.ParseFromString(data)
+        except Exception as e:
+            raise ValueError(f"Received invalid message: {e}")
+
+        handler = self._message_handlers.get(message.message_type)
+        if not handler:
+            enum_name = ws_pb2.WebsocketMessage.MessageType.Name(message.message_type)
+            raise ValueError(f"Invalid or unsupported message type: {enum_name}")
+        await handler(conn_key, message)
+
     # --- Message Handlers ---
 
     async def _handle_push_request(
@@ -394,14 +560,84 @@ class BaseWebSocketManager:
             self.push_repo.update(push_request.push_id, status=PushStatus.FAILED)
             return
 
-        # Forward the push request to the sidecar
-        try:
-            ws_msg = ws_pb2.WebsocketMessage(
-                message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_REQUEST,
-                push_message=push_request,
+        if push_request.sequence == 0:
+            push_request.sequence = self._push_sequences.get(key, 0) + 1
+        self._push_sequences[key] = max(
+            self._push_sequences.get(key, 0), push_request.sequence
+        )
+
+        # A big batch is streamed in the background, so the IDE's next messages,
+        # such as a PUSH_CANCEL for this push, are handled meanwhile.
+        hello = self._sidecar_capabilities.get(key)
+        if (
+            len(push_request.batch_file) > BATCH_CHUNK_SIZE
+            and hello is not None
+            and ws_pb2.WebsocketMessage.MessageType.BATCH_CHUNK
+            in hello.accepted_messages
+        ):
+            push_id = push_request.push_id
+            task = asyncio.create_task(
+                self._forward_push(key, sidecar_ws, push_request, streamed=True)
             )
-            await send_websocket_message(sidecar_ws, ws_msg)
-            log.info("Forwarded push data to sidecar", extra=key.log_fields())
+            self._batch_streams[push_id] = task
+            task.add_done_callback(lambda _: self._batch_streams.pop(push_id, None))
+            return
+
+        await self._forward_push(key, sidecar_ws, push_request, streamed=False)
+
+    async def _forward_push(
+        self,
+        key: ConnectionKey,
+        sidecar_ws: WebSocket,
+        push_request: ws_pb2.PushMessage,
+        streamed: bool,
+    ) -> None:
+        """Send a push to the sidecar, its batch in BATCH_CHUNK messages if streamed."""
+        from code_sync_proxy.ws.interfaces import PushStatus
+
+        try:
+            if streamed:
+                batch = push_request.batch_file
+                header = ws_pb2.PushMessage()
+                header.CopyFrom(push_request)
+                header.ClearField("batch_file")
+                header.streamed_batch_size = len(batch)
+                await send_websocket_message(
+                    sidecar_ws,
+                    ws_pb2.WebsocketMessage(
+                        message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_REQUEST,
+                        push_message=header,
+                    ),
+                )
+                for index, start in enumerate(range(0, len(batch), BATCH_CHUNK_SIZE)):
+                    end = start + BATCH_CHUNK_SIZE
+                    chunk = ws_pb2.BatchChunk(
+                        push_id=push_request.push_id,
+                        index=index,
+                        data=batch[start:end],
+                        last=end >= len(batch),
+                    )
+                    await send_websocket_message(
+                        sidecar_ws,
+                        ws_pb2.WebsocketMessage(
+                            message_type=ws_pb2.WebsocketMessage.MessageType.BATCH_CHUNK,
+                            batch_chunk=chunk,
+                        ),
+                    )
+                log.info(
+                    f"Streamed push data to sidecar in {index + 1} chunks",
+                    extra=key.log_fields(),
+                )
+            else:
+                ws_msg = ws_pb2.WebsocketMessage(
+                    message_type=ws_pb2.WebsocketMessage.MessageType.PUSH_REQUEST,
+                    push_message=push_request,
+                )
+                await send_websocket_message(sidecar_ws, ws_msg)
+                log.info("Forwarded push data to sidecar", extra=key.log_fields())
+            session = self._sidecar_sessions.get(key)
+            if session is not None:
+                session.in_flight.add(push_request.push_id)
             self.push_repo.update(push_request.push_id, status=PushStatus.PUSHED)
         except Exception as e:
             log.exception(
@@ -411,6 +647,20 @@ class BaseWebSocketManager:
             error_msg = MessageFactory.create_push_error("Failed to reach sidecar")
             await self._send_error_to_client(ConnectionType.IDE, key, error_msg)
 
+    async def _handle_push_cancel(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Stop streaming the push's batch, if it still is, and forward the cancel,
+        which also makes the sidecar drop the part it received."""
+        task = self._batch_streams.pop(message.push_cancel.push_id, None)
+        if task is not None:
+            task.cancel()
+            log.info(
+                f"Stopped streaming push {message.push_cancel.push_id} to the sidecar",
+                extra=key.log_fields(),
+            )
+        await self._forward_to_sidecar(key, message)
+
     async def _handle_push_response(
         self, key: ConnectionKey, response: ws_pb2.WebsocketMessage
     ) -> None:
@@ -421,9 +671,90 @@ class BaseWebSocketManager:
             extra=key.log_fields(),
         )
 
-        if push_response.status != PushStatusPb.COMPLETED:
+        session = self._sidecar_sessions.get(key)
+        if (
+            session is not None
+            and push_response.status not in INTERMEDIATE_PUSH_STATUSES
+        ):
+            session.in_flight.discard(push_response.push_id)
+        if push_response.replayed:
+            log.info(
+                f"Sidecar replayed the response to push {push_response.push_id} after reconnecting",
+                extra=key.log_fields(),
+            )
+
+        if (
+            push_response.status
+            not in (PushStatusPb.COMPLETED, PushStatusPb.SUPERSEDED)
+            and push_response.status not in INTERMEDIATE_PUSH_STATUSES
+        ):
             log.error(
-                f"Sidecar push not completed: status: {push_response.status}, error: {push_response.error_message}",
+                f"Sidecar push not completed: status: {push_response.status}, error: {push_response.error_message}"
+                f"{_describe_pod(push_response.pod)}",
+                extra=key.log_fields(),
+            )
+        # A coordinating sidecar reports the result of every replica.
+        for replica in push_response.replica_results:
+            role = " (leader)" if replica.leader else ""
+            detail = f": {replica.error_message}" if replica.error_message else ""
+            log.info(
+                f"Replica {replica.replica_id}{role} finished push {push_response.push_id} "
+                f"with status {PushStatusPb.Name(replica.status)}{detail}",
+                extra=key.log_fields(),
+            )
+        if push_response.status == PushStatusPb.OUT_OF_ORDER:
+            log.warning(
+                f"Push {push_response.push_id} arrived after a newer one, the sidecar is at sequence "
+                f"{push_response.last_sequence}",
+                extra=key.log_fields(),
+            )
+        if push_response.HasField("sequence_gap"):
+            gap = push_response.sequence_gap
+            log.warning(
+                f"Sidecar applied push {push_response.push_id} without pushes "
+                f"{gap.first}-{gap.last}, which never arrived",
+                extra=key.log_fields(),
+            )
+        if push_response.no_op:
+            log.info(
+                f"Push {push_response.push_id} matched the last applied batch, nothing was changed",
+                extra=key.log_fields(),
+            )
+        if push_response.missing_env:
+            log.warning(
+                f"Push {push_response.push_id} not applied, required env vars are missing: "
+                f"{', '.join(push_response.missing_env)}",
+                extra=key.log_fields(),
+            )
+        if push_response.rsync_attempts > 1 or push_response.signal_attempts > 1:
+            log.info(
+                f"Push {push_response.push_id} retried transient failures: "
+                f"rsync {push_response.rsync_attempts} attempt(s), signal {push_response.signal_attempts} attempt(s)",
+                extra=key.log_fields(),
+            )
+        if push_response.security_denials:
+            denials = ", ".join(f"{d.operation} {d.path}" for d in push_response.security_denials)
+            log.warning(
+                f"Push {push_response.push_id}: the sidecar's security module refused {denials}",
+                extra=key.log_fields(),
+            )
+        # Deployments with several launchers report each one.
+        for result in push_response.launcher_results:
+            if result.signalled:
+                continue
+            state = "not running" if result.not_running else "not signalled"
+            log.warning(
+                f"Launcher {result.name} (pid {result.pid}) {state} for push {push_response.push_id} "
+                f"after {result.attempts} attempt(s): {result.error_message}",
+                extra=key.log_fields(),
+            )
+        if push_response.HasField("timing"):
+            timing = push_response.timing
+            log.info(
+                f"Push {push_response.push_id} timing: download {timing.download_ms}ms, "
+                f"batch write {timing.batch_write_ms}ms, rsync {timing.rsync_ms}ms, "
+                f"env write {timing.env_write_ms}ms, install {timing.install_ms}ms, build {timing.build_ms}ms, warm {timing.warm_ms}ms, signal to healthy {timing.signal_to_healthy_ms}ms, "
+                f"total {timing.total_ms}ms",
                 extra=key.log_fields(),
             )
 
@@ -458,3 +789,168 @@ class BaseWebSocketManager:
         # Forward the response
         await send_websocket_message(ide_ws, response)
         log.info("Forwarded push response to IDE", extra=key.log_fields())
+
+    async def _handle_status_report(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle a periodic status report from the sidecar."""
+        report = message.status_report
+        launcher_state = ws_pb2.StatusReport.LauncherState.Name(report.launcher_state)
+        log.debug(
+            f"Sidecar status: uptime={report.uptime_seconds}s, last_push_id={report.last_push_id}, launcher_state={launcher_state}"
+            f"{_describe_pod(report.pod)}",
+            extra=key.log_fields(),
+        )
+        stats = report.connection_stats
+        if stats.degraded:
+            log.warning(
+                f"Sidecar connection degraded: rtt={stats.rtt_ms}ms, reconnects_last_hour={stats.reconnects_last_hour}, "
+                f"throughput={stats.throughput_bytes_per_second}B/s, chunk_size={stats.chunk_size_bytes}B",
+                extra=key.log_fields(),
+            )
+
+    async def _handle_hello(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle the sidecar's HELLO, which reports the last push it applied and
+        what the sidecar supports, and acknowledge it."""
+        hello = message.hello
+        log.info(
+            f"Sidecar hello: last_push_id={hello.last_push_id or '<none>'}, last_push_hash={hello.last_push_hash or '<none>'}, "
+            f"version={hello.sidecar_version or '<unknown>'}, protocol_version={hello.protocol_version}, features={list(hello.features)}",
+            extra=key.log_fields(),
+        )
+        # Sequences continue from the sidecar's, which outlive this worker's memory.
+        self._push_sequences[key] = max(
+            self._push_sequences.get(key, 0), hello.last_sequence
+        )
+        # Sidecars older than protocol version 1 don't list what they accept.
+        if hello.protocol_version > 0:
+            self._sidecar_capabilities[key] = hello
+            unacknowledged = self._resume_session(key, hello.resume_token)
+            ack = ws_pb2.WebsocketMessage(
+                message_type=ws_pb2.WebsocketMessage.MessageType.HELLO_ACK,
+                hello_ack=ws_pb2.HelloAck(
+                    protocol_version=PROTOCOL_VERSION,
+                    resume_token=self._sidecar_sessions[key].resume_token,
+                    unacknowledged_push_ids=unacknowledged,
+                ),
+            )
+            await self._forward(ConnectionType.SIDECAR, key, ack)
+
+    def _resume_session(self, key: ConnectionKey, resume_token: str) -> List[str]:
+        """Start a new session for the sidecar under a fresh token, carrying over
+        the in-flight pushes if it resumed the previous one. Returns those pushes."""
+        previous = self._sidecar_sessions.get(key)
+        in_flight: Set[str] = set()
+        if (
+            previous is not None
+            and resume_token
+            and resume_token == previous.resume_token
+        ):
+            in_flight = previous.in_flight
+            if in_flight:
+                log.info(
+                    f"Sidecar resumed its session with {len(in_flight)} unacknowledged pushes",
+                    extra=key.log_fields(),
+                )
+        elif resume_token:
+            log.info(
+                "Sidecar resume token is unknown, starting a new session",
+                extra=key.log_fields(),
+            )
+        self._sidecar_sessions[key] = SidecarSession(
+            resume_token=secrets.token_urlsafe(16), in_flight=in_flight
+        )
+        return sorted(in_flight)
+
+    async def _handle_launcher_exited(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle the sidecar reporting that the app's launcher exited, and tell the IDE."""
+        exited = message.launcher_exited
+        log.warning(
+            f"Launcher exited: pid={exited.pid}, reason={exited.reason or '<unknown>'}, app_status={exited.app_status or '<none>'}",
+            extra=key.log_fields(),
+        )
+        await self._forward_to_ide(key, message)
+
+    async def _handle_resync_request(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle the sidecar asking for a full resync, and tell the IDE so it can send one."""
+        req = message.resync_request
+        reason = ws_pb2.ResyncRequest.Reason.Name(req.reason)
+        log.warning(
+            f"Sidecar requested a full resync: reason={reason}, detail={req.detail or '<none>'}",
+            extra=key.log_fields(),
+        )
+        await self._forward_to_ide(key, message)
+
+    async def _handle_log_entry(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle a batch of application log lines streamed by the sidecar."""
+        for entry in message.log_batch.entries:
+            log.debug(
+                f"[app:{entry.source}] {entry.line}",
+                extra=key.log_fields(),
+            )
+
+    async def _handle_sidecar_log(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Handle a batch of the sidecar's own log entries."""
+        for entry in message.log_batch.entries:
+            level = logging.getLevelName(entry.level.upper())
+            log.log(
+                level if isinstance(level, int) else logging.INFO,
+                f"[sidecar] {entry.line}",
+                extra=key.log_fields(),
+            )
+
+    async def _forward(
+        self,
+        conn_type: ConnectionType,
+        key: ConnectionKey,
+        message: ws_pb2.WebsocketMessage,
+    ) -> None:
+        """Forward a message as-is to the given connection type for this key."""
+        message_type = ws_pb2.WebsocketMessage.MessageType.Name(message.message_type)
+        target_worker_id = self.cx_store.get_worker_id(conn_type, key)
+        if target_worker_id != settings.worker_id:
+            log.warning(
+                f"Cannot forward {message_type}: {conn_type} not connected to this worker ({target_worker_id})",
+                extra=key.log_fields(),
+            )
+            return
+
+        target_ws = self.registry.get_connection(conn_type, key)
+        if target_ws is None:
+            log.warning(
+                f"Cannot forward {message_type}: {conn_type} not found in local store",
+                extra=key.log_fields(),
+            )
+            return
+
+        await send_websocket_message(target_ws, message)
+
+    async def _forward_to_sidecar(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Forward an IDE message to the sidecar, unless the sidecar said it doesn't accept it."""
+        hello = self._sidecar_capabilities.get(key)
+        if hello is not None and message.message_type not in hello.accepted_messages:
+            message_type = ws_pb2.WebsocketMessage.MessageType.Name(message.message_type)
+            log.warning(
+                f"Not forwarding {message_type}: sidecar {hello.sidecar_version or '<unknown>'} doesn't accept it",
+                extra=key.log_fields(),
+            )
+            return
+        await self._forward(ConnectionType.SIDECAR, key, message)
+
+    async def _forward_to_ide(
+        self, key: ConnectionKey, message: ws_pb2.WebsocketMessage
+    ) -> None:
+        """Forward a sidecar message to the IDE."""
+        await self._forward(ConnectionType.IDE, key, message)
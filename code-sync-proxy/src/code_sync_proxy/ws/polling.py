@@ -0,0 +1,57 @@
+import asyncio
+import time
+from typing import List
+
+# How long a long-poll request is held open at most, whatever the sidecar asks for.
+MAX_POLL_WAIT_SECONDS = 30.0
+
+# A polling sidecar that hasn't made a request for this long is treated as disconnected.
+POLL_SESSION_TIMEOUT_SECONDS = 60.0
+
+
+class PollingSidecarConnection:
+    """Stands in for a sidecar's websocket when the sidecar long-polls over HTTP.
+
+    Messages sent to it are queued until the sidecar's next poll, so it can be
+    registered in place of a websocket and used by the existing forwarding code.
+    """
+
+    def __init__(self) -> None:
+        self._pending: asyncio.Queue[bytes] = asyncio.Queue()
+        self._active_polls = 0
+        self._last_seen = time.monotonic()
+
+    async def send_bytes(self, data: bytes) -> None:
+        await self._pending.put(data)
+
+    async def close(self, code: int = 1000, reason: str = "") -> None:
+        """Nothing to close; the session expires when the sidecar stops polling."""
+
+    def touch(self) -> None:
+        """Record a request from the sidecar."""
+        self._last_seen = time.monotonic()
+
+    def expired(self) -> bool:
+        return (
+            self._active_polls == 0
+            and time.monotonic() - self._last_seen > POLL_SESSION_TIMEOUT_SECONDS
+        )
+
+    async def drain(self, wait: float) -> List[bytes]:
+        """Wait up to `wait` seconds for queued messages and return all of them."""
+        self.touch()
+        self._active_polls += 1
+        try:
+            try:
+                first = await asyncio.wait_for(
+                    self._pending.get(), timeout=min(wait, MAX_POLL_WAIT_SECONDS)
+                )
+            except asyncio.TimeoutError:
+                return []
+            messages = [first]
+            while not self._pending.empty():
+                messages.append(self._pending.get_nowait())
+            return messages
+        finally:
+            self._active_polls -= 1
+            self.touch()
@@ -0,0 +1,190 @@
+import asyncio
+import time
+from unittest.mock import AsyncMock
+from uuid import uuid4
+
+import pytest
+from fastapi import WebSocket
+from fastapi.testclient import TestClient
+
+from code_sync_proxy.pb import ws_pb2
+from code_sync_proxy.ws import base_manager, polling
+from code_sync_proxy.ws.manager import WebSocketManager
+from code_sync_proxy.ws.polling import PollingSidecarConnection
+from code_sync_proxy.ws.registry import ConnectionType
+
+# Message type enums for cleaner reference
+MessageType = ws_pb2.WebsocketMessage.MessageType
+
+
+def new_app_id() -> str:
+    """Make an app ID of its own for a test, as managers share their registry."""
+    return f"app-{uuid4()}"
+
+
+def push_cancel(push_id: str) -> ws_pb2.WebsocketMessage:
+    return ws_pb2.WebsocketMessage(
+        message_type=MessageType.PUSH_CANCEL,
+        push_cancel=ws_pb2.PushCancel(push_id=push_id),
+    )
+
+
+@pytest.mark.asyncio
+async def test_drain_returns_queued_messages():
+    """A poll returns every message queued since the last one, and one that
+    arrives while it waits."""
+    conn = PollingSidecarConnection()
+    await conn.send_bytes(b"one")
+    await conn.send_bytes(b"two")
+    assert await conn.drain(1.0) == [b"one", b"two"]
+
+    poll = asyncio.create_task(conn.drain(5.0))
+    await asyncio.sleep(0.05)
+    assert not poll.done()
+    await conn.send_bytes(b"three")
+    assert await asyncio.wait_for(poll, timeout=1.0) == [b"three"]
+
+
+@pytest.mark.asyncio
+async def test_drain_times_out(monkeypatch):
+    """A poll with nothing to deliver returns empty after its wait, which is capped."""
+    conn = PollingSidecarConnection()
+    start = time.monotonic()
+    assert await conn.drain(0.1) == []
+    assert time.monotonic() - start >= 0.09
+
+    monkeypatch.setattr(polling, "MAX_POLL_WAIT_SECONDS", 0.1)
+    assert await asyncio.wait_for(conn.drain(30.0), timeout=1.0) == []
+
+
+@pytest.mark.asyncio
+async def test_poll_sidecar_delivers_queued_messages():
+    """Messages for a polling sidecar are queued for its polls, and the messages
+    it posts are handled like those from a websocket."""
+    connection_manager = WebSocketManager()
+    app_id, deployment_id = new_app_id(), "deployment"
+    key = connection_manager._make_key(app_id, deployment_id)
+
+    # The first poll registers the sidecar.
+    batch = await connection_manager.poll_sidecar(app_id, deployment_id, wait=0.01)
+    assert len(batch.messages) == 0
+    assert isinstance(
+        connection_manager.registry.get_connection(ConnectionType.SIDECAR, key),
+        PollingSidecarConnection,
+    )
+    assert connection_manager.is_sidecar_ready(app_id, deployment_id)
+
+    # Messages sent before a poll wait for it.
+    await connection_manager._forward_to_sidecar(key, push_cancel("push-1"))
+    await connection_manager._forward_to_sidecar(key, push_cancel("push-2"))
+    batch = await connection_manager.poll_sidecar(app_id, deployment_id, wait=1.0)
+    assert list(batch.messages) == [push_cancel("push-1"), push_cancel("push-2")]
+
+    # A message sent during a poll ends it.
+    poll = asyncio.create_task(
+        connection_manager.poll_sidecar(app_id, deployment_id, wait=5.0)
+    )
+    await asyncio.sleep(0.05)
+    await connection_manager._forward_to_sidecar(key, push_cancel("push-3"))
+    batch = await asyncio.wait_for(poll, timeout=1.0)
+    assert list(batch.messages) == [push_cancel("push-3")]
+
+    ide_ws = AsyncMock(spec=WebSocket)
+    ide_ws.send_bytes = AsyncMock()
+    connection_manager._store_connection(ConnectionType.IDE, key, ide_ws)
+    response = ws_pb2.WebsocketMessage(
+        message_type=MessageType.PUSH_RESPONSE,
+        push_response=ws_pb2.PushResponse(
+            push_id="push-1", status=ws_pb2.PushResponse.PushStatus.CANCELLED
+        ),
+    )
+    await connection_manager.post_from_sidecar(
+        app_id, deployment_id, response.SerializeToString()
+    )
+    ide_ws.send_bytes.assert_awaited_once_with(response.SerializeToString())
+
+    unsupported = ws_pb2.WebsocketMessage(message_type=MessageType.HELLO_ACK)
+    with pytest.raises(ValueError):
+        await connection_manager.post_from_sidecar(
+            app_id, deployment_id, unsupported.SerializeToString()
+        )
+
+
+@pytest.mark.asyncio
+async def test_polling_session_expires(monkeypatch):
+    """A sidecar that stops polling is detached, but not while a poll is open."""
+    monkeypatch.setattr(polling, "POLL_SESSION_TIMEOUT_SECONDS", 0.1)
+    monkeypatch.setattr(base_manager, "POLL_SESSION_TIMEOUT_SECONDS", 0.1)
+    connection_manager = WebSocketManager()
+    app_id, deployment_id = new_app_id(), "deployment"
+    key = connection_manager._make_key(app_id, deployment_id)
+
+    registry = connection_manager.registry
+    await connection_manager.poll_sidecar(app_id, deployment_id, wait=0.01)
+    conn = registry.get_connection(ConnectionType.SIDECAR, key)
+    assert conn is not None
+
+    # A poll longer than the session timeout keeps the session.
+    await connection_manager.poll_sidecar(app_id, deployment_id, wait=0.3)
+    assert registry.get_connection(ConnectionType.SIDECAR, key) is conn
+
+    await asyncio.sleep(0.4)
+    assert registry.get_connection(ConnectionType.SIDECAR, key) is None
+    assert not connection_manager.is_sidecar_ready(app_id, deployment_id)
+
+    # Polling again starts a new session.
+    await connection_manager.poll_sidecar(app_id, deployment_id, wait=0.01)
+    new_conn = registry.get_connection(ConnectionType.SIDECAR, key)
+    assert isinstance(new_conn, PollingSidecarConnection)
+    assert new_conn is not conn
+
+
+@pytest.mark.asyncio
+async def test_poll_sidecar_refused_while_websocket_connected():
+    """A sidecar connected by websocket can't poll at the same time."""
+    connection_manager = WebSocketManager()
+    app_id, deployment_id = new_app_id(), "deployment"
+    key = connection_manager._make_key(app_id, deployment_id)
+    connection_manager._store_connection(
+        ConnectionType.SIDECAR, key, AsyncMock(spec=WebSocket)
+    )
+
+    with pytest.raises(ConnectionError):
+        await connection_manager.poll_sidecar(app_id, deployment_id, wait=0.01)
+
+
+def test_pending_endpoints():
+    """The long-poll endpoints deliver message batches and take posted messages."""
+    from code_sync_proxy.app import api
+    from code_sync_proxy.ws.manager import default_manager
+
+    with TestClient(api) as client:
+        url = f"/api/v1/push/pending/{new_app_id()}/deployment"
+        response = client.get(url, params={"wait": 0.01})
+        assert response.status_code == 200
+        assert response.headers["content-type"] == "application/x-protobuf"
+        batch = ws_pb2.MessageBatch()
+        batch.ParseFromString(response.content)
+        assert len(batch.messages) == 0
+
+        status = ws_pb2.WebsocketMessage(
+            message_type=MessageType.STATUS_REPORT,
+            status_report=ws_pb2.StatusReport(uptime_seconds=5),
+        )
+        assert client.post(url, content=status.SerializeToString()).status_code == 204
+        unsupported = ws_pb2.WebsocketMessage(message_type=MessageType.HELLO_ACK)
+        assert (
+            client.post(url, content=unsupported.SerializeToString()).status_code
+            == 400
+        )
+
+        app_id = new_app_id()
+        default_manager._store_connection(
+            ConnectionType.SIDECAR,
+            default_manager._make_key(app_id, "deployment"),
+            AsyncMock(spec=WebSocket),
+        )
+        response = client.get(
+            f"/api/v1/push/pending/{app_id}/deployment", params={"wait": 0.01}
+        )
+        assert response.status_code == 409
@@ -1,12 +1,14 @@
 import pytest
 import asyncio
-from unittest.mock import AsyncMock, MagicMock
+from unittest.mock import AsyncMock, MagicMock, call
 from uuid import uuid4
 
 from fastapi import WebSocket, WebSocketDisconnect
 
 from code_sync_proxy.pb import ws_pb2
+from code_sync_proxy.ws.base_manager import BATCH_CHUNK_SIZE, SidecarSession
 from code_sync_proxy.ws.manager import WebSocketManager
+from code_sync_proxy.ws.registry import ConnectionKey, ConnectionType
 from code_sync_proxy.ws.interfaces import PushStatus
 from code_sync_proxy.ws.standalone import InMemoryPushRepository
 
@@ -17,6 +19,7 @@ log = logging.getLogger(__name__)
 
 # Message type enums for cleaner reference
 PushStatusPb = ws_pb2.PushResponse.PushStatus
+MessageType = ws_pb2.WebsocketMessage.MessageType
 
 
 def make_connection_manager() -> WebSocketManager:
@@ -27,6 +30,68 @@ def make_connection_manager() -> WebSocketManager:
     return manager
 
 
+def new_key(manager: WebSocketManager) -> ConnectionKey:
+    """Make a key of its own for a test, as managers share their registry."""
+    return manager._make_key(f"app-{uuid4()}", f"deployment-{uuid4()}")
+
+
+def attach_mock_websocket(
+    manager: WebSocketManager, conn_type: ConnectionType, key: ConnectionKey
+) -> AsyncMock:
+    """Register a mock websocket for key without running its receive loop."""
+    websocket = AsyncMock(spec=WebSocket)
+    websocket.send_bytes = AsyncMock()
+    manager._store_connection(conn_type, key, websocket)
+    return websocket
+
+
+def sent_messages(websocket: AsyncMock) -> list[ws_pb2.WebsocketMessage]:
+    """Parse the messages sent to a mock websocket."""
+    messages = []
+    for sent in websocket.send_bytes.call_args_list:
+        message = ws_pb2.WebsocketMessage()
+        message.ParseFromString(sent[0][0])
+        messages.append(message)
+    return messages
+
+
+def push_request(push_id: str, batch: bytes) -> ws_pb2.WebsocketMessage:
+    return ws_pb2.WebsocketMessage(
+        message_type=MessageType.PUSH_REQUEST,
+        push_message=ws_pb2.PushMessage(push_id=push_id, batch_file=batch),
+    )
+
+
+def push_response(
+    push_id: str, status: int, replayed: bool = False
+) -> ws_pb2.WebsocketMessage:
+    return ws_pb2.WebsocketMessage(
+        message_type=MessageType.PUSH_RESPONSE,
+        push_response=ws_pb2.PushResponse(
+            push_id=push_id, status=status, replayed=replayed
+        ),
+    )
+
+
+async def reconnect_sidecar(
+    manager: WebSocketManager, key: ConnectionKey, resume_token: str = ""
+) -> ws_pb2.HelloAck:
+    """Replace the sidecar's websocket with a new one, send its HELLO with
+    resume_token and return the HELLO_ACK it got."""
+    manager._remove_connection(ConnectionType.SIDECAR, key)
+    sidecar_ws = attach_mock_websocket(manager, ConnectionType.SIDECAR, key)
+    await manager._handle_hello(
+        key,
+        ws_pb2.WebsocketMessage(
+            message_type=MessageType.HELLO,
+            hello=ws_pb2.Hello(protocol_version=1, resume_token=resume_token),
+        ),
+    )
+    ack = sent_messages(sidecar_ws)[-1]
+    assert ack.message_type == MessageType.HELLO_ACK
+    return ack.hello_ack
+
+
 @pytest.mark.asyncio
 async def test_handle_push_request_via_websocket():
     """Test handling a push request from IDE to sidecar."""
@@ -133,3 +198,181 @@ async def test_handle_push_request_via_websocket():
         == ws_pb2.WebsocketMessage.MessageType.PUSH_RESPONSE
     )
     assert sent_to_ide_message.push_response == push_response_payload
+
+
+@pytest.mark.asyncio
+async def test_forward_push_streams_big_batch_in_chunks():
+    """A batch over BATCH_CHUNK_SIZE is sent after its push in BATCH_CHUNK messages."""
+    connection_manager = make_connection_manager()
+    key = new_key(connection_manager)
+    sidecar_ws = attach_mock_websocket(connection_manager, ConnectionType.SIDECAR, key)
+    connection_manager._sidecar_capabilities[key] = ws_pb2.Hello(
+        protocol_version=1,
+        accepted_messages=[MessageType.PUSH_REQUEST, MessageType.BATCH_CHUNK],
+    )
+    connection_manager._sidecar_sessions[key] = SidecarSession(resume_token="token")
+    push_id = str(uuid4())
+    batch = bytes(range(256)) * (2 * BATCH_CHUNK_SIZE // 256) + b"tail"
+
+    await connection_manager._handle_push_request(key, push_request(push_id, batch))
+    # The batch is streamed in the background.
+    task = connection_manager._batch_streams[push_id]
+    await asyncio.wait_for(task, timeout=2.0)
+    await asyncio.sleep(0)
+
+    messages = sent_messages(sidecar_ws)
+    header = messages[0]
+    assert header.message_type == MessageType.PUSH_REQUEST
+    assert header.push_message.push_id == push_id
+    assert header.push_message.batch_file == b""
+    assert header.push_message.streamed_batch_size == len(batch)
+
+    assert all(m.message_type == MessageType.BATCH_CHUNK for m in messages[1:])
+    chunks = [m.batch_chunk for m in messages[1:]]
+    assert [c.index for c in chunks] == [0, 1, 2]
+    assert [c.last for c in chunks] == [False, False, True]
+    assert all(c.push_id == push_id for c in chunks)
+    assert b"".join(c.data for c in chunks) == batch
+
+    assert push_id not in connection_manager._batch_streams
+    assert push_id in connection_manager._sidecar_sessions[key].in_flight
+    connection_manager.push_repo.update.assert_called_with(
+        push_id, status=PushStatus.PUSHED
+    )
+
+
+@pytest.mark.asyncio
+async def test_forward_push_sends_whole_batch_without_batch_chunk():
+    """A sidecar that doesn't accept BATCH_CHUNK gets a big batch in its push."""
+    connection_manager = make_connection_manager()
+    key = new_key(connection_manager)
+    sidecar_ws = attach_mock_websocket(connection_manager, ConnectionType.SIDECAR, key)
+    connection_manager._sidecar_capabilities[key] = ws_pb2.Hello(
+        protocol_version=1, accepted_messages=[MessageType.PUSH_REQUEST]
+    )
+    push_id = str(uuid4())
+    batch = b"x" * (BATCH_CHUNK_SIZE + 1)
+
+    await connection_manager._handle_push_request(key, push_request(push_id, batch))
+
+    assert push_id not in connection_manager._batch_streams
+    messages = sent_messages(sidecar_ws)
+    assert len(messages) == 1
+    assert messages[0].push_message.batch_file == batch
+    assert messages[0].push_message.streamed_batch_size == 0
+
+
+@pytest.mark.asyncio
+async def test_handle_push_cancel_stops_streaming():
+    """A PUSH_CANCEL stops the push's batch stream and is forwarded to the sidecar."""
+    connection_manager = make_connection_manager()
+    key = new_key(connection_manager)
+    sidecar_ws = attach_mock_websocket(connection_manager, ConnectionType.SIDECAR, key)
+    connection_manager._sidecar_capabilities[key] = ws_pb2.Hello(
+        protocol_version=1,
+        accepted_messages=[
+            MessageType.PUSH_REQUEST,
+            MessageType.BATCH_CHUNK,
+            MessageType.PUSH_CANCEL,
+        ],
+    )
+    sent = []
+    chunk_sending = asyncio.Event()
+
+    async def send_bytes(data: bytes) -> None:
+        message = ws_pb2.WebsocketMessage()
+        message.ParseFromString(data)
+        sent.append(message)
+        if message.message_type == MessageType.BATCH_CHUNK:
+            # The connection to the sidecar stalls on the first chunk.
+            chunk_sending.set()
+            await asyncio.Event().wait()
+
+    sidecar_ws.send_bytes = send_bytes
+    push_id = str(uuid4())
+
+    await connection_manager._handle_push_request(
+        key, push_request(push_id, b"x" * (BATCH_CHUNK_SIZE + 1))
+    )
+    task = connection_manager._batch_streams[push_id]
+    await asyncio.wait_for(chunk_sending.wait(), timeout=2.0)
+
+    await connection_manager._handle_push_cancel(
+        key,
+        ws_pb2.WebsocketMessage(
+            message_type=MessageType.PUSH_CANCEL,
+            push_cancel=ws_pb2.PushCancel(push_id=push_id),
+        ),
+    )
+    await asyncio.gather(task, return_exceptions=True)
+
+    assert task.cancelled()
+    assert push_id not in connection_manager._batch_streams
+    assert [m.message_type for m in sent] == [
+        MessageType.PUSH_REQUEST,
+        MessageType.BATCH_CHUNK,
+        MessageType.PUSH_CANCEL,
+    ]
+    assert sent[-1].push_cancel.push_id == push_id
+    assert (
+        call(push_id, status=PushStatus.PUSHED)
+        not in connection_manager.push_repo.update.call_args_list
+    )
+
+
+@pytest.mark.asyncio
+async def test_resumed_session_replays_in_flight_pushes_once():
+    """A sidecar resuming its session with the token from its last HELLO_ACK is
+    told which pushes never got a final response, and each only until it does."""
+    connection_manager = make_connection_manager()
+    key = new_key(connection_manager)
+    ide_ws = attach_mock_websocket(connection_manager, ConnectionType.IDE, key)
+
+    ack = await reconnect_sidecar(connection_manager, key)
+    assert ack.resume_token
+    assert list(ack.unacknowledged_push_ids) == []
+
+    push_id = str(uuid4())
+    await connection_manager._handle_push_request(key, push_request(push_id, b"batch"))
+    # An intermediate response leaves the push in flight.
+    await connection_manager._handle_push_response(
+        key, push_response(push_id, PushStatusPb.APPLYING)
+    )
+
+    # The connection drops before the final response reaches the proxy.
+    resumed = await reconnect_sidecar(connection_manager, key, ack.resume_token)
+    assert list(resumed.unacknowledged_push_ids) == [push_id]
+    assert resumed.resume_token != ack.resume_token
+
+    await connection_manager._handle_push_response(
+        key, push_response(push_id, PushStatusPb.COMPLETED, replayed=True)
+    )
+    forwarded = [m.push_response for m in sent_messages(ide_ws)]
+    assert [(r.push_id, r.status, r.replayed) for r in forwarded] == [
+        (push_id, PushStatusPb.APPLYING, False),
+        (push_id, PushStatusPb.COMPLETED, True),
+    ]
+
+    # With its final response received, the push isn't asked for again.
+    again = await reconnect_sidecar(connection_manager, key, resumed.resume_token)
+    assert list(again.unacknowledged_push_ids) == []
+
+
+@pytest.mark.asyncio
+async def test_unknown_resume_token_starts_new_session():
+    """A HELLO with a token other than the session's starts a new session, which
+    carries over no pushes."""
+    connection_manager = make_connection_manager()
+    key = new_key(connection_manager)
+
+    ack = await reconnect_sidecar(connection_manager, key)
+    push_id = str(uuid4())
+    await connection_manager._handle_push_request(key, push_request(push_id, b"batch"))
+
+    fresh = await reconnect_sidecar(connection_manager, key, "not-the-token")
+    assert list(fresh.unacknowledged_push_ids) == []
+    assert fresh.resume_token != ack.resume_token
+
+    # The session it replaced can't be resumed either.
+    stale = await reconnect_sidecar(connection_manager, key, ack.resume_token)
+    assert list(stale.unacknowledged_push_ids) == []
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestBinaryChecksums_MatchRepo(t *testing.T) {
+	sums, err := launcher.ParseChecksums(binaryChecksums)
+	require.NoError(t, err)
+
+	// The image renames the binaries it ships (see the Dockerfile).
+	files := map[string]string{
+		"rsync-launcher.sh": filepath.Join("launcher-script", "rsync-launcher.sh"),
+	}
+	for arch, name := range launcher.RsyncBinaries {
+		matches, err := filepath.Glob(filepath.Join("binaries", "rsync-*-linux-"+arch))
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		files[name] = matches[0]
+	}
+	for name, path := range files {
+		assert.NoError(t, launcher.VerifyChecksum(path, name, sums), "update binaries/SHA256SUMS after changing %s", path)
+	}
+	assert.Len(t, sums, len(files))
+}
+
+func TestEmbeddedAssets_MatchChecksums(t *testing.T) {
+	sums, err := launcher.ParseChecksums(binaryChecksums)
+	require.NoError(t, err)
+
+	// Provisioning from the compiled-in files doesn't need the image's copies.
+	filesDir := t.TempDir()
+	_, err = launcher.CopyBinaries(filesDir, launcher.Assets{LauncherScript: launcherScript, Rsync: embeddedRsync, Checksums: binaryChecksums})
+	if embeddedRsync == nil {
+		require.Error(t, err, "rsync isn't compiled in without embed_rsync")
+	}
+	assert.NoError(t, launcher.VerifyChecksum(filepath.Join(launcher.SidecarDir(filesDir), launcher.LauncherScriptName), launcher.LauncherScriptName, sums))
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launch"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/syncer"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+const usage = `Usage: code-sync-sidecar [command] [flags]
+
+Commands:
+  run           Run the sidecar (the default when no command is given)
+  status        Print the running sidecar's status from its local status endpoint
+  diagnostics   Save a diagnostics bundle from the running sidecar for a support case
+  validate      Check the configuration and environment without starting
+  version       Print the sidecar version
+  apply         Apply an rsync batch file by hand, for incident recovery
+  env-rollback  Restore a previous version of the env files and reload the app
+  launch        Run the app in its container, reloading it when the sidecar signals
+
+Run "code-sync-sidecar <command> -h" for a command's flags.
+`
+
+// runCLI runs the command named by args[0] and returns the process exit code.
+// Configuration always comes from BIFROST_CONFIG and the environment, as for run.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	command := "run"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "run":
+		if len(args) > 0 {
+			fmt.Fprintf(stderr, "run takes no arguments, got %q\n", args)
+			return 2
+		}
+		runSidecar()
+		return 0
+	case "status":
+		return statusCommand(args, stdout, stderr)
+	case "diagnostics":
+		return diagnosticsCommand(args, stdout, stderr)
+	case "validate":
+		return validateCommand(args, stdout, stderr)
+	case "version":
+		fmt.Fprintf(stdout, "code-sync-sidecar %s (protocol %d)\n", version, syncer.ProtocolVersion)
+		return 0
+	case "apply":
+		return applyCommand(args, stdout, stderr)
+	case "env-rollback":
+		return envRollbackCommand(args, stdout, stderr)
+	case "launch":
+		return launchCommand(args, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+}
+
+func newFlagSet(name, synopsis string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: code-sync-sidecar %s\n\nFlags:\n", synopsis)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// statusCommand prints the STATUS_REPORT of the sidecar running in this pod.
+func statusCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("status", "status [flags]", stderr)
+	addr := flags.String("addr", "", "status endpoint address (default status.listen_addr)")
+	timeout := flags.Duration("timeout", 5*time.Second, "how long to wait for the sidecar to answer")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	body, err := queryLocalEndpoint(*addr, syncer.StatusPath, *timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s\n", body)
+	return 0
+}
+
+// diagnosticsCommand saves the running sidecar's diagnostics bundle to a file.
+func diagnosticsCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("diagnostics", "diagnostics [flags]", stderr)
+	addr := flags.String("addr", "", "status endpoint address (default status.listen_addr)")
+	output := flags.String("o", "", `file to write the bundle to, or "-" for stdout (default code-sync-diagnostics-<time>.tar.gz)`)
+	timeout := flags.Duration("timeout", time.Minute, "how long to wait for the sidecar to build the bundle")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	bundle, err := queryLocalEndpoint(*addr, syncer.DiagnosticsPath, *timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	switch *output {
+	case "-":
+		stdout.Write(bundle)
+		return 0
+	case "":
+		*output = fmt.Sprintf("code-sync-diagnostics-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(*output, bundle, 0600); err != nil {
+		fmt.Fprintf(stderr, "Failed to write the diagnostics bundle: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote diagnostics bundle to %s (%d bytes)\n", *output, len(bundle))
+	return 0
+}
+
+// queryLocalEndpoint fetches path from the running sidecar's local status
+// endpoint, at addr or, when that is empty, status.listen_addr.
+func queryLocalEndpoint(addr, path string, timeout time.Duration) ([]byte, error) {
+	if addr == "" {
+		// Only the listen address is needed, so an otherwise invalid config doesn't matter here.
+		cfg, err := syncer.ReadConfig()
+		if err != nil {
+			return nil, err
+		}
+		addr = cfg.Status.ListenAddr
+	}
+	if addr == "" {
+		return nil, errors.New("the local status endpoint is disabled: set status.listen_addr or BIFROST_STATUS_ADDR, or pass -addr")
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get("http://" + addr + path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the sidecar at %s (is it running?): %w", addr, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the sidecar's response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the sidecar returned status %d: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// validateCommand checks the configuration, and in hardened mode the shared
+// volume, reporting every problem found.
+func validateCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("validate", "validate", stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	cfg, err := syncer.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if cfg.Security.Hardened {
+		if _, err := launcher.CheckHardenedVolume(cfg.Sync.FilesDir, cfg.Security.SharedGID); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stdout, "Configuration is valid: app %s, deployment %s, files directory %s, %s apply mode\n",
+		cfg.AppID, cfg.DeploymentID, cfg.Sync.FilesDir, cfg.Sync.ApplyMode)
+	return 0
+}
+
+// applyCommand applies a batch file as a push, the same way a PUSH_REQUEST would
+// be: hooks run, the launcher is reloaded and the push is recorded as applied.
+// It's for incident recovery when the control plane can't deliver the push.
+func applyCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("apply", "apply -batch FILE [flags]", stderr)
+	batchPath := flags.String("batch", "", "rsync batch file to apply (required)")
+	pushID := flags.String("push-id", "", "ID to record the push under (default manual-<unix time>)")
+	force := flags.Bool("force", false, "apply even if files were modified since the last push")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *batchPath == "" || flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	if *pushID == "" {
+		*pushID = fmt.Sprintf("manual-%d", time.Now().Unix())
+	}
+	batch, err := os.ReadFile(*batchPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read batch file: %v\n", err)
+		return 1
+	}
+	cfg, err := syncer.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	log.Init("code-sync-sidecar", map[string]string{
+		"appID":        cfg.AppID,
+		"deploymentID": cfg.DeploymentID,
+		"command":      "apply",
+	})
+	defer log.Sync()
+	if err := launcher.PrepareVolume(cfg.Sync.FilesDir, cfg.Security.Hardened, cfg.Security.SharedGID); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	authMode, _ := transport.ParseAuthMode(cfg.API.AuthMode) // Validated by LoadConfig
+	tokens, err := transport.NewTokenManager(authMode, cfg.API.URL, cfg.API.APIKey, cfg.API.IdentityTokenPath, cfg.AppID, cfg.DeploymentID)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	resp, err := syncer.ApplyPush(ctx, cfg, tokens, &pb.PushMessage{PushId: *pushID, BatchFile: batch, Force: *force})
+	if resp != nil {
+		data, marshalErr := syncer.StatusJSON.Marshal(resp)
+		if marshalErr == nil {
+			fmt.Fprintf(stdout, "%s\n", data)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Push %s failed: %v\n", *pushID, err)
+		return 1
+	}
+	return 0
+}
+
+// envRollbackCommand restores a version of the env files kept in the history
+// and reloads the app, or with -list prints the versions kept.
+func envRollbackCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("env-rollback", "env-rollback [flags]", stderr)
+	versionID := flags.String("version", "", "version to restore (default the one before the current version)")
+	list := flags.Bool("list", false, "print the versions kept instead of restoring one")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	cfg, err := syncer.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if *list {
+		versions, current, err := envfile.ListVersions(cfg.Sync.FilesDir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if len(versions) == 0 {
+			fmt.Fprintln(stdout, "No env versions are kept")
+			return 0
+		}
+		for _, v := range versions {
+			marker := " "
+			if v.ID == current {
+				marker = "*"
+			}
+			pushID := v.PushID
+			if pushID == "" {
+				pushID = "-"
+			}
+			fmt.Fprintf(stdout, "%s %s  %s  %s\n", marker, v.ID, v.CreatedAt.Format(time.RFC3339), pushID)
+		}
+		return 0
+	}
+
+	log.Init("code-sync-sidecar", map[string]string{
+		"appID":        cfg.AppID,
+		"deploymentID": cfg.DeploymentID,
+		"command":      "env-rollback",
+	})
+	defer log.Sync()
+	resp, err := syncer.RollbackEnv(context.Background(), cfg, *versionID)
+	if resp != nil {
+		data, marshalErr := syncer.StatusJSON.Marshal(resp)
+		if marshalErr == nil {
+			fmt.Fprintf(stdout, "%s\n", data)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Env rollback failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// launchCommand runs the app in the app container in place of the launcher
+// script: it starts the command, restarts it when the sidecar signals a
+// reload, and stops it on SIGTERM. It reads the script's environment
+// variables rather than the sidecar's configuration, which the app container
+// doesn't have.
+func launchCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("launch", "launch [flags] -- COMMAND [ARG...]", stderr)
+	filesDir := flags.String("files-dir", envOr("WATCH_DIR", launch.DefaultFilesDir), "volume shared with the sidecar, $WATCH_DIR when set")
+	appRoot := flags.String("app-root", envOr("APP_ROOT", launch.DefaultAppRoot), "directory the synced code is copied to on a reload, $APP_ROOT when set")
+	name := flags.String("name", envOr("BIFROST_LAUNCHER_NAME", launcher.MainLauncher), "name telling launchers that share the files dir apart, $BIFROST_LAUNCHER_NAME when set")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	l, err := launch.New(launch.Options{
+		FilesDir:   *filesDir,
+		AppRoot:    *appRoot,
+		Name:       *name,
+		Command:    flags.Args(),
+		EnvScope:   os.Getenv("BIFROST_ENV_SCOPE"),
+		EnvKeyPath: os.Getenv("BIFROST_ENV_KEY_PATH"),
+		Environ:    os.Environ(),
+		Stdout:     stdout,
+		Stderr:     stderr,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+	if err := l.Run(signals); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// envOr returns the value of the environment variable name, or fallback if
+// it is unset or empty.
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/syncer"
+)
+
+func runTestCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCLI_Version(t *testing.T) {
+	code, stdout, _ := runTestCLI("version")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "code-sync-sidecar dev (protocol 1)\n", stdout)
+
+	code, _, stderr := runTestCLI("frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `Unknown command "frobnicate"`)
+}
+
+// clearConfigEnv unsets every BIFROST_ variable for the test.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, "BIFROST_") {
+			t.Setenv(name, "")
+		}
+	}
+}
+
+func TestRunCLI_Validate(t *testing.T) {
+	clearConfigEnv(t)
+	code, _, stderr := runTestCLI("validate")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "app_id is required")
+
+	t.Setenv("BIFROST_APP_ID", "app1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "deployment1")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+	t.Setenv("BIFROST_API_KEY", "secret")
+	code, stdout, _ := runTestCLI("validate")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Configuration is valid: app app1, deployment deployment1")
+}
+
+func TestRunCLI_Status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, syncer.StatusPath, r.URL.Path)
+		data, err := syncer.StatusJSON.Marshal(&pb.StatusReport{LastPushId: "push-7"})
+		assert.NoError(t, err)
+		w.Write(data)
+	}))
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	code, stdout, stderr := runTestCLI("status", "-addr", addr)
+	require.Equal(t, 0, code, stderr)
+	var report map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	assert.Equal(t, "push-7", report["lastPushId"])
+
+	server.Close()
+	code, _, stderr = runTestCLI("status", "-addr", addr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "is it running?")
+}
+
+func TestRunCLI_EnvRollback(t *testing.T) {
+	clearConfigEnv(t)
+	filesDir := t.TempDir()
+	t.Setenv("BIFROST_APP_ID", "app1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "deployment1")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+	t.Setenv("BIFROST_API_KEY", "secret")
+	t.Setenv("BIFROST_FILES_DIR", filesDir)
+	t.Setenv("BIFROST_RELOAD_SIGNAL", "none")
+
+	code, stdout, _ := runTestCLI("env-rollback", "-list")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "No env versions are kept\n", stdout)
+
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	for _, content := range []string{"export A='1'\n", "export A='2'\n"} {
+		_, err := envfile.Write(filesDir, "", []byte(content), "")
+		require.NoError(t, err)
+		_, err = envfile.SaveVersion(filesDir, "", syncer.DefaultEnvHistory, time.Now())
+		require.NoError(t, err)
+	}
+	code, stdout, _ = runTestCLI("env-rollback", "-list")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "* 000002")
+
+	code, stdout, stderr := runTestCLI("env-rollback")
+	require.Equal(t, 0, code, stderr)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &resp))
+	assert.Equal(t, "000001", resp["currentVersion"])
+	data, err := os.ReadFile(envfile.Path(filesDir, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "export A='1'\n", string(data))
+
+	code, _, stderr = runTestCLI("env-rollback")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "is the oldest kept")
+}
+
+func TestRunCLI_Launch(t *testing.T) {
+	clearConfigEnv(t)
+	code, _, stderr := runTestCLI("launch")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: code-sync-sidecar launch [flags] -- COMMAND [ARG...]")
+
+	t.Setenv("BIFROST_LAUNCHER_NAME", "web/1")
+	code, _, stderr = runTestCLI("launch", "--", "node", "server.js")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `launcher name "web/1" may only contain letters, digits, - and _`)
+}
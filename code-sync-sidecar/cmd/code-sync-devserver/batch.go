@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// watch pushes the directory as soon as it starts, and again whenever its
+// contents change, checking every interval.
+func (s *devServer) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		fingerprint, err := dirFingerprint(s.opts.dir)
+		if err != nil {
+			log.Warn("Failed to check the directory for changes", zap.String("dir", s.opts.dir), zap.Error(err))
+		} else if fingerprint != last {
+			batch, err := writeBatch(ctx, s.opts.rsyncPath, s.opts.dir)
+			switch {
+			case err != nil:
+				log.Error("Failed to write rsync batch", zap.Error(err))
+			case len(batch) == 0:
+				log.Info("rsync wrote an empty batch, nothing to push")
+				last = fingerprint
+			default:
+				s.publish(batch)
+				last = fingerprint
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dirFingerprint summarizes the path, size, mode and modification time of
+// every file under dir except those in .git, so any change alters it.
+func dirFingerprint(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// A directory's mtime moves with anything created in it, .git included;
+			// its entries already show what changed.
+			fmt.Fprintf(hash, "%s\x00%s\n", rel, info.Mode())
+			return nil
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%s\x00%d\n", rel, info.Size(), info.Mode(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeBatch writes an rsync batch that brings an empty directory to the
+// contents of dir, the same way the MCP server does: .git and anything listed
+// in .gitignore files are left out, and --delete removes files that are gone.
+func writeBatch(ctx context.Context, rsyncPath, dir string) ([]byte, error) {
+	tmp, err := os.MkdirTemp("", "code-sync-devserver-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	target := filepath.Join(tmp, "target")
+	if err := os.Mkdir(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create empty target directory: %w", err)
+	}
+	source, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	batchPath := filepath.Join(tmp, "batch.bin")
+
+	cmd := exec.CommandContext(ctx, rsyncPath,
+		"-a",
+		"--delete",
+		"--checksum",
+		"--only-write-batch="+batchPath,
+		source+"/",
+		target+"/",
+		"--include=**.gitignore",
+		"--exclude=/.git",
+		"--filter=:- .gitignore",
+		"--delete-after",
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("rsync failed: %w. Output: %s", err, output)
+	}
+	batch, err := os.ReadFile(batchPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+	return batch, nil
+}
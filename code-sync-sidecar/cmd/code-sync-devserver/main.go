@@ -0,0 +1,83 @@
+// Command code-sync-devserver is a stand-in for the Bifrost control plane for
+// local development. It serves the sidecar's websocket and database env var
+// endpoints and pushes a local directory to every connected sidecar whenever
+// it changes, so the whole sidecar loop can be exercised without an account.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+// envVarFlags collects repeated -env NAME=VALUE flags.
+type envVarFlags []envfile.DatabaseEnvVar
+
+func (f *envVarFlags) String() string {
+	names := make([]string, len(*f))
+	for i, v := range *f {
+		names[i] = v.EnvVarName
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *envVarFlags) Set(value string) error {
+	name, uri, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q must be NAME=VALUE", value)
+	}
+	*f = append(*f, envfile.DatabaseEnvVar{EnvVarName: name, ConnectionURI: uri})
+	return nil
+}
+
+func main() {
+	var envVars envVarFlags
+	addr := flag.String("addr", "127.0.0.1:8000", "address to listen on; point the sidecar's BIFROST_API_URL here")
+	dir := flag.String("dir", ".", "directory to push to the sidecar")
+	apiKey := flag.String("api-key", "", "API key the sidecar must send (BIFROST_API_KEY); empty accepts any key")
+	rsyncPath := flag.String("rsync", "rsync", "rsync binary used to write batches")
+	interval := flag.Duration("interval", time.Second, "how often the directory is checked for changes")
+	force := flag.Bool("force", false, "push with force, overwriting files modified in the deployment")
+	flag.Var(&envVars, "env", "database env var served to the sidecar, as NAME=VALUE (repeatable)")
+	flag.Parse()
+
+	log.Init("code-sync-devserver", nil)
+	defer log.Sync()
+
+	if info, err := os.Stat(*dir); err != nil || !info.IsDir() {
+		log.Fatal("The directory to push doesn't exist", zap.String("dir", *dir), zap.Error(err))
+	}
+	server := newDevServer(devServerOptions{
+		dir:       *dir,
+		apiKey:    *apiKey,
+		rsyncPath: *rsyncPath,
+		force:     *force,
+		envVars:   envVars,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	go server.watch(ctx, *interval)
+
+	httpServer := &http.Server{Addr: *addr, Handler: server.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	log.Info("Serving the sidecar API", zap.String("addr", *addr), zap.String("dir", *dir))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("Server failed", zap.Error(err))
+	}
+}
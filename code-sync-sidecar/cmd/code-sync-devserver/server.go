@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+const serverVersion = "devserver"
+
+type devServerOptions struct {
+	dir       string
+	apiKey    string
+	rsyncPath string
+	force     bool
+	envVars   []envfile.DatabaseEnvVar
+}
+
+// devServer plays the control plane for the sidecars connected to it.
+type devServer struct {
+	opts     devServerOptions
+	upgrader websocket.Upgrader
+	// runID keeps push IDs unique across restarts, so a sidecar never mistakes
+	// a new push for one it applied from an earlier run.
+	runID int64
+
+	mu       sync.Mutex
+	sidecars map[*sidecarConn]struct{}
+	latest   *pb.PushMessage
+	pushes   int
+}
+
+// sidecarConn is a connected sidecar. gorilla/websocket supports one concurrent writer.
+type sidecarConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	logger  *zap.Logger
+}
+
+func (c *sidecarConn) send(msg *pb.WebsocketMessage) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", msg.MessageType, err)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+func newDevServer(opts devServerOptions) *devServer {
+	return &devServer{
+		opts:     opts,
+		runID:    time.Now().Unix(),
+		sidecars: make(map[*sidecarConn]struct{}),
+	}
+}
+
+func (s *devServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/deployments/{deployment}/database-env-vars", s.handleDatabaseEnvVars)
+	mux.HandleFunc("GET /api/v1/push/sidecar/{app}/{deployment}", s.handleSidecar)
+	return mux
+}
+
+// authorized checks the sidecar's API key. Only the api_key auth mode is supported.
+func (s *devServer) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.opts.apiKey != "" && r.Header.Get("X-Api-Key") != s.opts.apiKey {
+		http.Error(w, "invalid API key", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *devServer) handleDatabaseEnvVars(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	envVars := s.opts.envVars
+	if envVars == nil {
+		envVars = []envfile.DatabaseEnvVar{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(envVars)
+}
+
+func (s *devServer) handleSidecar(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("Failed to upgrade sidecar connection", zap.Error(err))
+		return
+	}
+	sidecar := &sidecarConn{
+		conn:   conn,
+		logger: log.With(zap.String("appID", r.PathValue("app")), zap.String("deploymentID", r.PathValue("deployment"))),
+	}
+	sidecar.logger.Info("Sidecar connected", zap.String("remoteAddr", r.RemoteAddr))
+	s.mu.Lock()
+	s.sidecars[sidecar] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.sidecars, sidecar)
+		s.mu.Unlock()
+		conn.Close()
+		sidecar.logger.Info("Sidecar disconnected")
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := &pb.WebsocketMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			sidecar.logger.Warn("Failed to decode message from sidecar", zap.Error(err))
+			continue
+		}
+		s.handleMessage(sidecar, msg)
+	}
+}
+
+func (s *devServer) handleMessage(sidecar *sidecarConn, msg *pb.WebsocketMessage) {
+	switch msg.MessageType {
+	case pb.WebsocketMessage_HELLO:
+		hello := msg.GetHello()
+		sidecar.logger.Info("Sidecar said hello",
+			zap.String("version", hello.GetSidecarVersion()),
+			zap.String("lastPushID", hello.GetLastPushId()),
+			zap.Strings("features", hello.GetFeatures()))
+		err := sidecar.send(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_HELLO_ACK,
+			Message:     &pb.WebsocketMessage_HelloAck{HelloAck: &pb.HelloAck{ProtocolVersion: 1, ServerVersion: serverVersion}},
+		})
+		if err != nil {
+			sidecar.logger.Warn("Failed to send HELLO_ACK", zap.Error(err))
+			return
+		}
+		// Bring the sidecar up to date unless it already has the latest push.
+		s.mu.Lock()
+		latest := s.latest
+		s.mu.Unlock()
+		if latest != nil && latest.PushId != hello.GetLastPushId() {
+			s.sendPush(sidecar, latest)
+		}
+	case pb.WebsocketMessage_PUSH_RESPONSE:
+		resp := msg.GetPushResponse()
+		fields := []zap.Field{zap.String("pushID", resp.PushId), zap.String("status", resp.Status.String())}
+		if resp.ErrorMessage != "" {
+			fields = append(fields, zap.String("error", resp.ErrorMessage))
+		}
+		if len(resp.CreatedFiles)+len(resp.ModifiedFiles)+len(resp.DeletedFiles) > 0 {
+			fields = append(fields,
+				zap.Int("created", len(resp.CreatedFiles)),
+				zap.Int("modified", len(resp.ModifiedFiles)),
+				zap.Int("deleted", len(resp.DeletedFiles)))
+		}
+		sidecar.logger.Info("Push response", fields...)
+	case pb.WebsocketMessage_PUSH_PROGRESS:
+		progress := msg.GetPushProgress()
+		sidecar.logger.Debug("Push progress",
+			zap.String("pushID", progress.PushId),
+			zap.String("stage", progress.Stage.String()),
+			zap.Int32("percent", progress.Percent))
+	case pb.WebsocketMessage_LOG_ENTRY, pb.WebsocketMessage_SIDECAR_LOG:
+		for _, entry := range msg.GetLogBatch().GetEntries() {
+			sidecar.logger.Info("Log", zap.String("source", entry.Source), zap.String("line", entry.Line))
+		}
+	default:
+		sidecar.logger.Debug("Message from sidecar", zap.String("type", msg.MessageType.String()))
+	}
+}
+
+// publish records batch as the latest push and sends it to every connected sidecar.
+func (s *devServer) publish(batch []byte) {
+	s.mu.Lock()
+	s.pushes++
+	push := &pb.PushMessage{
+		PushId:            fmt.Sprintf("dev-%d-%d", s.runID, s.pushes),
+		BatchFile:         batch,
+		ChangeDescription: "Local change from code-sync-devserver",
+		Force:             s.opts.force,
+	}
+	s.latest = push
+	sidecars := make([]*sidecarConn, 0, len(s.sidecars))
+	for sidecar := range s.sidecars {
+		sidecars = append(sidecars, sidecar)
+	}
+	s.mu.Unlock()
+
+	log.Info("Pushing changes", zap.String("pushID", push.PushId), zap.Int("batchBytes", len(batch)), zap.Int("sidecars", len(sidecars)))
+	for _, sidecar := range sidecars {
+		s.sendPush(sidecar, push)
+	}
+}
+
+func (s *devServer) sendPush(sidecar *sidecarConn, push *pb.PushMessage) {
+	err := sidecar.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_REQUEST,
+		Message:     &pb.WebsocketMessage_PushMessage{PushMessage: push},
+	})
+	if err != nil {
+		sidecar.logger.Warn("Failed to send push", zap.String("pushID", push.PushId), zap.Error(err))
+	}
+}
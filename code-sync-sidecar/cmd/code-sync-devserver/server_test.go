@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+// fakeRsync writes a script that stands in for rsync: it writes the source
+// directory's listing as the batch.
+func fakeRsync(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rsync")
+	script := `#!/bin/sh
+for arg; do
+	case "$arg" in
+	--only-write-batch=*) batch="${arg#--only-write-batch=}" ;;
+	-*) ;;
+	*) [ -z "$source" ] && source="$arg" ;;
+	esac
+done
+ls "$source" > "$batch"
+`
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) *pb.WebsocketMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	msg := &pb.WebsocketMessage{}
+	require.NoError(t, proto.Unmarshal(data, msg))
+	return msg
+}
+
+func TestDevServer_Sidecar(t *testing.T) {
+	s := newDevServer(devServerOptions{apiKey: "key", envVars: []envfile.DatabaseEnvVar{{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db"}}})
+	server := httptest.NewServer(s.routes())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/deployments/dep1/database-env-vars")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/deployments/dep1/database-env-vars", nil)
+	req.Header.Set("X-Api-Key", "key")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var envVars []envfile.DatabaseEnvVar
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&envVars))
+	resp.Body.Close()
+	assert.Equal(t, "postgres://db", envVars[0].ConnectionURI)
+
+	// A push published before the sidecar connects is sent after its HELLO.
+	s.publish([]byte("batch-1"))
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/push/sidecar/app1/dep1"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"X-Api-Key": []string{"key"}})
+	require.NoError(t, err)
+	defer conn.Close()
+	hello, err := proto.Marshal(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_HELLO,
+		Message:     &pb.WebsocketMessage_Hello{Hello: &pb.Hello{SidecarVersion: "test"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, hello))
+
+	assert.Equal(t, pb.WebsocketMessage_HELLO_ACK, readMessage(t, conn).MessageType)
+	msg := readMessage(t, conn)
+	require.Equal(t, pb.WebsocketMessage_PUSH_REQUEST, msg.MessageType)
+	first := msg.GetPushMessage()
+	assert.Equal(t, []byte("batch-1"), first.BatchFile)
+
+	s.publish([]byte("batch-2"))
+	second := readMessage(t, conn).GetPushMessage()
+	assert.Equal(t, []byte("batch-2"), second.BatchFile)
+	assert.NotEqual(t, first.PushId, second.PushId)
+}
+
+func TestWriteBatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("print()"), 0644))
+
+	batch, err := writeBatch(context.Background(), fakeRsync(t), dir)
+	require.NoError(t, err)
+	assert.Equal(t, "app.py\n", string(batch))
+
+	_, err = writeBatch(context.Background(), filepath.Join(t.TempDir(), "missing"), dir)
+	assert.ErrorContains(t, err, "rsync failed")
+}
+
+func TestDirFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("a"), 0644))
+	before, err := dirFingerprint(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0644))
+	unchanged, err := dirFingerprint(dir)
+	require.NoError(t, err)
+	assert.Equal(t, before, unchanged)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("changed"), 0644))
+	after, err := dirFingerprint(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
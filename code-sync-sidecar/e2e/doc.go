@@ -0,0 +1,8 @@
+// Package e2e tests the sidecar end to end: batches written by a real rsync
+// from the fixture directory pairs in testdata are pushed to a FileSyncer
+// through an in-process control plane and applied to a temp dir.
+//
+// The tests need rsync 3. They use $E2E_RSYNC if set, then the binary the
+// image ships in ../binaries for this architecture, then rsync on the PATH,
+// and are skipped if there is none.
+package e2e
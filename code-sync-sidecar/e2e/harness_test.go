@@ -0,0 +1,365 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/syncer"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+const (
+	appID        = "app-e2e"
+	deploymentID = "dep-e2e"
+	apiKey       = "e2e-key"
+
+	// fakeLauncherEnv makes the test binary act as the launcher: it records
+	// every SIGHUP in the file the variable names and otherwise waits.
+	fakeLauncherEnv = "E2E_FAKE_LAUNCHER_RELOADS"
+
+	responseTimeout = 30 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	if reloads := os.Getenv(fakeLauncherEnv); reloads != "" {
+		runFakeLauncher(reloads)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeLauncher(reloads string) {
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	for range hups {
+		f, err := os.OpenFile(reloads, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			os.Exit(1)
+		}
+		fmt.Fprintln(f, "SIGHUP")
+		f.Close()
+	}
+}
+
+// findRsync returns the rsync the tests run, or skips the test.
+func findRsync(t *testing.T) string {
+	t.Helper()
+	if path := os.Getenv("E2E_RSYNC"); path != "" {
+		return path
+	}
+	candidates := []string{filepath.Join("..", "binaries", "rsync-3.4.1-linux-"+runtime.GOARCH)}
+	if path, err := exec.LookPath("rsync"); err == nil {
+		candidates = append(candidates, path)
+	}
+	for _, path := range candidates {
+		if out, err := exec.Command(path, "--version").Output(); err == nil && bytes.Contains(out, []byte("version 3.")) {
+			abs, err := filepath.Abs(path)
+			require.NoError(t, err)
+			return abs
+		}
+	}
+	t.Skip("no rsync 3 found; set E2E_RSYNC to run the end-to-end tests")
+	return ""
+}
+
+// controlPlane plays the Bifrost API for one sidecar.
+type controlPlane struct {
+	t         *testing.T
+	server    *httptest.Server
+	upgrader  websocket.Upgrader
+	connected chan *websocket.Conn
+	responses chan *pb.PushResponse
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+}
+
+func newControlPlane(t *testing.T) *controlPlane {
+	t.Helper()
+	cp := &controlPlane{
+		t:         t,
+		connected: make(chan *websocket.Conn, 1),
+		responses: make(chan *pb.PushResponse, 64),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/push/sidecar/{app}/{deployment}", cp.handleSidecar)
+	cp.server = httptest.NewServer(mux)
+	t.Cleanup(cp.server.Close)
+	return cp
+}
+
+func (cp *controlPlane) handleSidecar(w http.ResponseWriter, r *http.Request) {
+	conn, err := cp.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	cp.writeMu.Lock()
+	cp.conn = conn
+	cp.writeMu.Unlock()
+	select {
+	case cp.connected <- conn:
+	default:
+	}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := &pb.WebsocketMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			continue
+		}
+		switch msg.MessageType {
+		case pb.WebsocketMessage_HELLO:
+			cp.send(&pb.WebsocketMessage{
+				MessageType: pb.WebsocketMessage_HELLO_ACK,
+				Message:     &pb.WebsocketMessage_HelloAck{HelloAck: &pb.HelloAck{ProtocolVersion: 1, ServerVersion: "e2e"}},
+			})
+		case pb.WebsocketMessage_PUSH_RESPONSE:
+			cp.responses <- msg.GetPushResponse()
+		}
+	}
+}
+
+func (cp *controlPlane) send(msg *pb.WebsocketMessage) {
+	data, err := proto.Marshal(msg)
+	require.NoError(cp.t, err)
+	cp.writeMu.Lock()
+	defer cp.writeMu.Unlock()
+	require.NotNil(cp.t, cp.conn, "the sidecar isn't connected")
+	require.NoError(cp.t, cp.conn.WriteMessage(websocket.BinaryMessage, data))
+}
+
+// push sends pushMsg and returns its final response.
+func (cp *controlPlane) push(pushMsg *pb.PushMessage) *pb.PushResponse {
+	cp.t.Helper()
+	cp.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_REQUEST,
+		Message:     &pb.WebsocketMessage_PushMessage{PushMessage: pushMsg},
+	})
+	deadline := time.After(responseTimeout)
+	for {
+		select {
+		case resp := <-cp.responses:
+			if resp.PushId == pushMsg.PushId && !intermediateStatuses[resp.Status] {
+				return resp
+			}
+		case <-deadline:
+			cp.t.Fatalf("no final response to push %s", pushMsg.PushId)
+		}
+	}
+}
+
+var intermediateStatuses = map[pb.PushResponse_PushStatus]bool{
+	pb.PushResponse_PENDING:     true,
+	pb.PushResponse_IN_PROGRESS: true,
+	pb.PushResponse_RECEIVED:    true,
+	pb.PushResponse_APPLYING:    true,
+	pb.PushResponse_RELOADING:   true,
+	pb.PushResponse_HEALTHY:     true,
+}
+
+// sidecar is a FileSyncer connected to a controlPlane, with a fake launcher.
+type sidecar struct {
+	filesDir string
+	hooksDir string
+	reloads  string
+	rsync    string
+	cp       *controlPlane
+}
+
+// startSidecar seeds a files directory with the before fixture and connects a
+// FileSyncer to a new control plane. configure, if set, adjusts the config.
+func startSidecar(t *testing.T, before string, configure func(*syncer.Config)) *sidecar {
+	t.Helper()
+	s := &sidecar{
+		filesDir: t.TempDir(),
+		hooksDir: t.TempDir(),
+		reloads:  filepath.Join(t.TempDir(), "reloads"),
+		rsync:    findRsync(t),
+		cp:       newControlPlane(t),
+	}
+	copyDir(t, before, s.filesDir)
+	s.startLauncher(t)
+
+	cfg := syncer.DefaultConfig()
+	cfg.AppID, cfg.DeploymentID = appID, deploymentID
+	cfg.API.URL = s.cp.server.URL
+	cfg.API.APIKey = apiKey
+	cfg.Sync.FilesDir = s.filesDir
+	cfg.Sync.HooksDir = s.hooksDir
+	cfg.Sync.RsyncPath = s.rsync
+	cfg.Sync.RetryBackoff = syncer.Duration(10 * time.Millisecond)
+	cfg.Timeouts.StatusInterval = 0
+	cfg.Status.ListenAddr = ""
+	cfg.Readiness.File = ""
+	if configure != nil {
+		configure(cfg)
+	}
+	require.NoError(t, cfg.Validate())
+
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, cfg.API.URL, apiKey, "", appID, deploymentID)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	fileSyncer, err := syncer.NewFileSyncer(ctx, cfg, tokens)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		cancel()
+		fileSyncer.Stop()
+	})
+
+	select {
+	case <-s.cp.connected:
+	case <-time.After(responseTimeout):
+		t.Fatal("the sidecar never connected")
+	}
+	return s
+}
+
+// startLauncher runs the test binary as the launcher and writes its PID file.
+func (s *sidecar) startLauncher(t *testing.T) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), fakeLauncherEnv+"="+s.reloads)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	require.NoError(t, os.MkdirAll(launcher.Dir(s.filesDir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(s.filesDir), "launcher.pid"), []byte(strconv.Itoa(cmd.Process.Pid)), 0644))
+	// Give the launcher time to install its SIGHUP handler.
+	time.Sleep(100 * time.Millisecond)
+}
+
+// reloadCount returns how many times the launcher was signalled.
+func (s *sidecar) reloadCount(t *testing.T) int {
+	t.Helper()
+	data, err := os.ReadFile(s.reloads)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return bytes.Count(data, []byte("\n"))
+}
+
+// writeHook installs a pre-sync or post-sync hook script.
+func (s *sidecar) writeHook(t *testing.T, name, script string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(s.hooksDir, name+".sh"), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+}
+
+// writeBatch returns the batch that rsync writes to bring a copy of before to
+// the contents of after, passing extraArgs, e.g. --delete. Like the MCP
+// server, it compares checksums rather than sizes and modification times.
+func writeBatch(t *testing.T, rsync, before, after string, extraArgs ...string) []byte {
+	t.Helper()
+	src, dest := t.TempDir(), t.TempDir()
+	copyDir(t, after, src)
+	copyDir(t, before, dest)
+	batchPath := filepath.Join(t.TempDir(), "batch.bin")
+	args := append([]string{"-a", "--checksum", "--only-write-batch=" + batchPath}, extraArgs...)
+	args = append(args, src+"/", dest+"/")
+	out, err := exec.Command(rsync, args...).CombinedOutput()
+	require.NoError(t, err, "rsync --write-batch: %s", out)
+	batch, err := os.ReadFile(batchPath)
+	require.NoError(t, err)
+	return batch
+}
+
+// fixtureTime is the modification time of every copied fixture file, so that
+// the files a fixture pair has in common look alike to rsync wherever they
+// were checked out.
+var fixtureTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// copyDir copies the regular files and directories under src into dst,
+// keeping their permissions. A missing src copies nothing, as git doesn't
+// keep empty fixture directories.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return
+	}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return os.Chtimes(target, fixtureTime, fixtureTime)
+	})
+	require.NoError(t, err)
+}
+
+// readTree maps every regular file under dir, which may be a symlink, to its
+// contents, leaving out the sidecar's and the launcher's directories.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	dir, err := filepath.EvalSymlinks(dir)
+	if os.IsNotExist(err) {
+		return tree
+	}
+	require.NoError(t, err)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (rel == ".sidecar" || rel == ".launcher") {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tree[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	require.NoError(t, err)
+	return tree
+}
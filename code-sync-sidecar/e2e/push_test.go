@@ -0,0 +1,105 @@
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/syncer"
+)
+
+func TestPush_AppliesBatch(t *testing.T) {
+	s := startSidecar(t, "testdata/basic/before", nil)
+	batch := writeBatch(t, s.rsync, "testdata/basic/before", "testdata/basic/after")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "push-1", BatchFile: batch})
+
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Equal(t, readTree(t, "testdata/basic/after"), readTree(t, s.filesDir))
+	assert.ElementsMatch(t, []string{"src/math.py", "static/site.css"}, resp.CreatedFiles)
+	assert.ElementsMatch(t, []string{"app.py"}, resp.ModifiedFiles)
+	assert.Empty(t, resp.DeletedFiles)
+	assert.Equal(t, 1, s.reloadCount(t))
+
+	// The same batch again is recognised as already applied.
+	resp = s.cp.push(&pb.PushMessage{PushId: "push-1", BatchFile: batch})
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.True(t, resp.AlreadyApplied)
+	assert.Equal(t, 1, s.reloadCount(t))
+}
+
+func TestPush_MirrorKeepsProtectedPaths(t *testing.T) {
+	s := startSidecar(t, "testdata/mirror/before", func(cfg *syncer.Config) {
+		cfg.Sync.ProtectedPaths = []string{"/data/"}
+	})
+	batch := writeBatch(t, s.rsync, "testdata/mirror/before", "testdata/mirror/after", "--delete")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "mirror-1", BatchFile: batch, Mirror: true})
+
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	want := readTree(t, "testdata/mirror/after")
+	want["data/app.sqlite"] = "rows\n"
+	assert.Equal(t, want, readTree(t, s.filesDir))
+	assert.ElementsMatch(t, []string{"old/legacy.py", "old"}, resp.DeletedFiles)
+	assert.DirExists(t, filepath.Join(s.filesDir, ".sidecar"))
+	assert.DirExists(t, filepath.Join(s.filesDir, ".launcher"))
+}
+
+func TestPush_MirrorRejectsMassDeletion(t *testing.T) {
+	s := startSidecar(t, "testdata/mirror/before", nil)
+	empty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(empty, "app.py"), []byte("v2\n"), 0644))
+	batch := writeBatch(t, s.rsync, "testdata/mirror/before", empty, "--delete")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "mirror-1", BatchFile: batch, Mirror: true})
+
+	require.Equal(t, pb.PushResponse_TOO_MANY_DELETIONS, resp.Status, resp.ErrorMessage)
+	assert.NotEmpty(t, resp.MirrorDeletions)
+	assert.Equal(t, readTree(t, "testdata/mirror/before"), readTree(t, s.filesDir))
+	assert.Zero(t, s.reloadCount(t))
+}
+
+func TestPush_FailedPreSyncHookLeavesFiles(t *testing.T) {
+	s := startSidecar(t, "testdata/basic/before", nil)
+	s.writeHook(t, "pre-sync", "echo not now >&2\nexit 1")
+	batch := writeBatch(t, s.rsync, "testdata/basic/before", "testdata/basic/after")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "push-1", BatchFile: batch})
+
+	require.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	require.Len(t, resp.HookResults, 1)
+	assert.Contains(t, resp.HookResults[0].Output, "not now")
+	assert.Equal(t, readTree(t, "testdata/basic/before"), readTree(t, s.filesDir))
+	assert.Zero(t, s.reloadCount(t))
+}
+
+func TestPush_FailedPostSyncHookDoesNotReload(t *testing.T) {
+	s := startSidecar(t, "testdata/basic/before", nil)
+	s.writeHook(t, "post-sync", "echo migration failed >&2\nexit 1")
+	batch := writeBatch(t, s.rsync, "testdata/basic/before", "testdata/basic/after")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "push-1", BatchFile: batch})
+
+	// The files stay applied, but the app isn't reloaded into them.
+	require.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "not activated")
+	assert.Equal(t, readTree(t, "testdata/basic/after"), readTree(t, s.filesDir))
+	assert.Zero(t, s.reloadCount(t))
+}
+
+func TestPush_SwapMode(t *testing.T) {
+	s := startSidecar(t, "testdata/basic/before", func(cfg *syncer.Config) {
+		cfg.Sync.ApplyMode = syncer.ApplyModeSwap
+	})
+	batch := writeBatch(t, s.rsync, "testdata/basic/before", "testdata/basic/after")
+
+	resp := s.cp.push(&pb.PushMessage{PushId: "push-1", BatchFile: batch})
+
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Equal(t, readTree(t, "testdata/basic/after"), readTree(t, filepath.Join(s.filesDir, "current")))
+	assert.Equal(t, 1, s.reloadCount(t))
+}
@@ -0,0 +1 @@
+stale
@@ -9,8 +9,37 @@ SIDECAR_DIR="${WATCH_DIR}/.sidecar"
 LAUNCHER_DIR="${WATCH_DIR}/.launcher"
 RSYNC_BINARY="/app/bin/rsync"
 
-APP_PID_FILE="${SIDECAR_DIR}/app.pid"
-APP_PGID_FILE="${SIDECAR_DIR}/app-pgid.pid"  # Added to track process group ID
+# Deployments running several launchers from one files dir (e.g. web and worker)
+# name each one, so their PID and exit files don't collide. The sidecar signals
+# every launcher with a PID file after a push.
+LAUNCHER_NAME="${BIFROST_LAUNCHER_NAME:-launcher}"
+case "$LAUNCHER_NAME" in
+    *[!A-Za-z0-9_-]*)
+        echo "[code-sync] BIFROST_LAUNCHER_NAME '$LAUNCHER_NAME' may only contain letters, digits, - and _" >&2
+        exit 1
+        ;;
+esac
+FILE_SUFFIX=""
+[ "$LAUNCHER_NAME" = "launcher" ] || FILE_SUFFIX=".${LAUNCHER_NAME}"
+
+EXIT_REASON_FILE="${LAUNCHER_DIR}/exit_reason${FILE_SUFFIX}"  # Read by the sidecar when the launcher exits
+APP_STATUS_FILE="${LAUNCHER_DIR}/app.status${FILE_SUFFIX}"
+
+APP_PID_FILE="${SIDECAR_DIR}/app${FILE_SUFFIX}.pid"
+APP_PGID_FILE="${SIDECAR_DIR}/app-pgid${FILE_SUFFIX}.pid"  # Added to track process group ID
+# The app an overlapping reload replaces, kept running until the new one is ready
+APP_OLD_PID_FILE="${SIDECAR_DIR}/app${FILE_SUFFIX}.old.pid"
+APP_OLD_PGID_FILE="${SIDECAR_DIR}/app-pgid${FILE_SUFFIX}.old.pid"
+KEEP_OLD_APP=false
+
+# Written by the sidecar before each reload, one JSON field per line
+HANDSHAKE_FILE="${LAUNCHER_DIR}/handshake.json"
+
+# Locked exclusively by the sidecar while it rewrites env files and binaries; the
+# launcher takes it shared while reading them. Needs flock(1) in the app image.
+SYNC_LOCK_FILE="${SIDECAR_DIR}/sync.lock"
+SYNC_LOCK_TIMEOUT=30
+FLOCK_BINARY=$(command -v flock 2>/dev/null)
 
 # Wait for both directories to be created
 while [ ! -d "${SIDECAR_DIR}" ] || [ ! -d "${LAUNCHER_DIR}" ]; do
@@ -20,29 +49,48 @@ done
 
 # Write the initial PID file with our own PID
 mkdir -p "${LAUNCHER_DIR}"
-echo $$ > "${LAUNCHER_DIR}/launcher.pid"
+rm -f "${EXIT_REASON_FILE}"
+echo $$ > "${LAUNCHER_DIR}/${LAUNCHER_NAME}.pid"
+# Record the names, never the values, of the non-empty container env vars so
+# the sidecar can check a push's required env before reloading the app
+awk 'BEGIN { for (name in ENVIRON) if (ENVIRON[name] != "") print name }' > "${LAUNCHER_DIR}/env_names"
 
 echo "[code-sync] Running rsync launcher script, watch_dir: ${WATCH_DIR} and app_root: ${APP_ROOT}"
 COMMAND="sh -c \"$*\""
 echo "[code-sync] Wrapping command: ${COMMAND}"
 
+# Function to take the sync lock shared on fd 9 until release_sync_lock. Without
+# flock or the lock file, reads go ahead uncoordinated.
+acquire_sync_lock() {
+    if [ -z "$FLOCK_BINARY" ] || [ ! -r "$SYNC_LOCK_FILE" ]; then
+        return 0
+    fi
+    exec 9<"$SYNC_LOCK_FILE"
+    if ! "$FLOCK_BINARY" -s -w "$SYNC_LOCK_TIMEOUT" 9; then
+        echo "[code-sync] Warning: timed out waiting for ${SYNC_LOCK_FILE}, continuing without it"
+    fi
+}
+
+release_sync_lock() {
+    exec 9<&-
+}
+
 # Function to sync files from WATCH_DIR to APP_ROOT using rsync
 update_files() {
-    # Find the appropriate rsync binary
-    rsync_binary=""
-    for binary in "${SIDECAR_DIR}/rsync_"*; do
-        if [ -x "$binary" ]; then
-            rsync_binary="$binary"
-            break
-        fi
-    done
-
-    if [ -z "$rsync_binary" ]; then
-        echo "[code-sync] Warning: No executable rsync binary found in ${SIDECAR_DIR}"
+    # The sidecar provisions the rsync built for this architecture
+    rsync_binary="${SIDECAR_DIR}/rsync"
+    if [ ! -x "$rsync_binary" ]; then
+        echo "[code-sync] Warning: No executable rsync binary found at ${rsync_binary}"
         return 1
     fi
 
-    echo "[code-sync] Syncing files from ${WATCH_DIR} to ${APP_ROOT} using ${rsync_binary}"
+    # In swap mode the synced code is in the release the "current" symlink points to
+    source_dir="${WATCH_DIR}"
+    if [ -L "${WATCH_DIR}/current" ]; then
+        source_dir="${WATCH_DIR}/current"
+    fi
+
+    echo "[code-sync] Syncing files from ${source_dir} to ${APP_ROOT} using ${rsync_binary}"
 
     # Ensure APP_ROOT exists
     mkdir -p "${APP_ROOT}"
@@ -54,7 +102,7 @@ update_files() {
         -a --delete \
         --exclude '.sidecar/' \
         --exclude '.launcher/' \
-        "${WATCH_DIR}"/* "${APP_ROOT}/"
+        "${source_dir}"/* "${APP_ROOT}/"
 
     rsync_exit_code=$?
     if [ $rsync_exit_code -ne 0 ]; then
@@ -105,34 +153,107 @@ kill_process_tree() {
     fi
 }
 
+# Function to stop the app an overlapping reload replaced: send it the drain
+# signal and give it up to the drain timeout to finish its requests
+drain_old_app() {
+    drain_signal=${1:-TERM}
+    drain_timeout=${2:-30}
+    if [ ! -f "$APP_OLD_PGID_FILE" ]; then
+        return 0
+    fi
+    old_pgid=$(cat "$APP_OLD_PGID_FILE")
+    echo "[code-sync] Draining previous application process group $old_pgid with SIG$drain_signal"
+    kill -$drain_signal -$old_pgid 2>/dev/null
+
+    wait_time=0
+    while kill -0 -$old_pgid 2>/dev/null && [ "$wait_time" -lt "$drain_timeout" ]; do
+        sleep 1
+        wait_time=$((wait_time + 1))
+    done
+
+    if kill -0 -$old_pgid 2>/dev/null; then
+        echo "[code-sync] Previous application did not drain within ${drain_timeout}s. Sending SIGKILL."
+        kill -9 -$old_pgid 2>/dev/null
+    fi
+    rm -f "$APP_OLD_PID_FILE" "$APP_OLD_PGID_FILE"
+}
+
+# Function to read a string or number field of the handshake file
+handshake_field() {
+    sed -n 's/^  "'"$1"'": "\{0,1\}\([^"]*\)"\{0,1\},\{0,1\}$/\1/p' "$HANDSHAKE_FILE" 2>/dev/null
+}
+
+# Function to source an env file written by the sidecar, or its encrypted form
+source_env_file() {
+    DATABASE_ENV_FILE="$1"
+    ENCRYPTED_ENV_FILE="$1.enc"
+    if [ -f "$ENCRYPTED_ENV_FILE" ]; then
+        # Written by the sidecar with BIFROST_ENV_KEY_PATH; the same key file must be mounted here.
+        if [ -z "${BIFROST_ENV_KEY_PATH:-}" ]; then
+            echo "[code-sync] $ENCRYPTED_ENV_FILE is encrypted but BIFROST_ENV_KEY_PATH is not set; skipping it"
+        elif decrypted_env=$(openssl enc -d -aes-256-cbc -pbkdf2 -iter 100000 -md sha256 \
+                -pass "file:${BIFROST_ENV_KEY_PATH}" -in "$ENCRYPTED_ENV_FILE"); then
+            echo "[code-sync] Sourcing encrypted database environment variables from $ENCRYPTED_ENV_FILE"
+            eval "$decrypted_env"
+            unset decrypted_env
+        else
+            echo "[code-sync] Failed to decrypt $ENCRYPTED_ENV_FILE"
+        fi
+    elif [ -f "$DATABASE_ENV_FILE" ]; then
+        echo "[code-sync] Sourcing database environment variables from $DATABASE_ENV_FILE"
+        . "$DATABASE_ENV_FILE"
+    else
+        echo "[code-sync] No database environment file found at $DATABASE_ENV_FILE"
+    fi
+}
+
 # Function to start or restart the application
 start_app() {
-    PUSH_ID_FILE="${LAUNCHER_DIR}/push_id"
-    if [ -f "$PUSH_ID_FILE" ]; then
-        PUSH_ID_VALUE=$(cat "$PUSH_ID_FILE")
+    if [ -f "$HANDSHAKE_FILE" ]; then
+        export BIFROST_LAUNCHER_HANDSHAKE="$HANDSHAKE_FILE"
+        PUSH_ID_VALUE=$(sed -n 's/^  "push_id": "\(.*\)",\{0,1\}$/\1/p' "$HANDSHAKE_FILE")
+        RELOAD_REASON_VALUE=$(sed -n 's/^  "reason": "\(.*\)",\{0,1\}$/\1/p' "$HANDSHAKE_FILE")
+        if [ -n "$RELOAD_REASON_VALUE" ]; then
+            export BIFROST_RELOAD_REASON="$RELOAD_REASON_VALUE"
+        else
+            unset BIFROST_RELOAD_REASON
+        fi
         if [ -n "$PUSH_ID_VALUE" ]; then
             export BIFROST_PUSH_ID="$PUSH_ID_VALUE"
-            echo "[code-sync] Setting BIFROST_PUSH_ID='$BIFROST_PUSH_ID' from $PUSH_ID_FILE"
+            echo "[code-sync] Setting BIFROST_PUSH_ID='$BIFROST_PUSH_ID' from $HANDSHAKE_FILE (reason: ${RELOAD_REASON_VALUE:-unknown})"
         else
-            echo "[code-sync] Push ID file $PUSH_ID_FILE is empty, unsetting BIFROST_PUSH_ID"
+            echo "[code-sync] Handshake file $HANDSHAKE_FILE has no push ID, unsetting BIFROST_PUSH_ID"
             unset BIFROST_PUSH_ID
         fi
     else
-        echo "[code-sync] Push ID file $PUSH_ID_FILE not found. Unsetting BIFROST_PUSH_ID."
-        unset BIFROST_PUSH_ID
+        echo "[code-sync] Handshake file $HANDSHAKE_FILE not found. Unsetting BIFROST_PUSH_ID."
+        unset BIFROST_PUSH_ID BIFROST_RELOAD_REASON BIFROST_LAUNCHER_HANDSHAKE
     fi
-
-    # Source database environment variables if they exist
-    DATABASE_ENV_FILE="${SIDECAR_DIR}/env.sh"
-    if [ -f "$DATABASE_ENV_FILE" ]; then
-        echo "[code-sync] Sourcing database environment variables from $DATABASE_ENV_FILE"
-        . "$DATABASE_ENV_FILE"
+    # Lists the paths each push changed, for frameworks with their own hot-reload
+    export BIFROST_CHANGES_FILE="${SIDECAR_DIR}/changes.json"
+    # The new app of an overlapping reload creates this file once it is serving
+    READY_FILE_VALUE=$(handshake_field ready_file)
+    if [ "$KEEP_OLD_APP" = true ] && [ -n "$READY_FILE_VALUE" ]; then
+        export BIFROST_APP_READY_FILE="$READY_FILE_VALUE"
     else
-        echo "[code-sync] No database environment file found at $DATABASE_ENV_FILE"
+        unset BIFROST_APP_READY_FILE
     fi
 
-    # Kill previous instance if it exists
-    if [ -f "$APP_PID_FILE" ]; then
+    # Source database environment variables if they exist, then those scoped to this process
+    acquire_sync_lock
+    source_env_file "${SIDECAR_DIR}/env.sh"
+    if [ -n "${BIFROST_ENV_SCOPE:-}" ]; then
+        source_env_file "${SIDECAR_DIR}/env.${BIFROST_ENV_SCOPE}.sh"
+    fi
+    release_sync_lock
+
+    # Kill previous instance if it exists, unless an overlapping reload keeps it
+    # running until the new one is ready
+    if [ "$KEEP_OLD_APP" = true ] && [ -f "$APP_PGID_FILE" ]; then
+        drain_old_app TERM 5  # Left over from an overlapping reload that never finished
+        mv -f "$APP_PID_FILE" "$APP_OLD_PID_FILE"
+        mv -f "$APP_PGID_FILE" "$APP_OLD_PGID_FILE"
+    elif [ -f "$APP_PID_FILE" ]; then
         old_pid=$(cat "$APP_PID_FILE")
         kill_process_tree "$old_pid"
     fi
@@ -156,7 +277,7 @@ start_app() {
         # Wait for the process and capture exit status
         wait $APP_PID
         _exit_status=$?
-        echo "Application command ('$*') exited with status $_exit_status" > "${LAUNCHER_DIR}/app.status"
+        echo "Application command ('$*') exited with status $_exit_status" > "$APP_STATUS_FILE"
     ) &
     
     # Give processes time to initialize
@@ -165,15 +286,44 @@ start_app() {
 
 # Function to handle SIGHUP
 handle_sighup() {
-    echo "[code-sync] Received SIGHUP, restarting application"
+    # An overlapping reload signals three times: to start the new app next to
+    # the old one, then to drain the old one once the new one is ready, or to
+    # abort and keep the old one if it never is
+    case "$(handshake_field overlap_phase)" in
+    drain)
+        echo "[code-sync] Received SIGHUP, new application is ready"
+        drain_old_app "$(handshake_field drain_signal)" "$(handshake_field drain_timeout_seconds)"
+        return
+        ;;
+    abort)
+        echo "[code-sync] Received SIGHUP, new application never became ready; keeping the previous one"
+        kill_process_tree "$(cat "$APP_PID_FILE" 2>/dev/null)"
+        if [ -f "$APP_OLD_PGID_FILE" ]; then
+            mv -f "$APP_OLD_PID_FILE" "$APP_PID_FILE"
+            mv -f "$APP_OLD_PGID_FILE" "$APP_PGID_FILE"
+        fi
+        return
+        ;;
+    start)
+        echo "[code-sync] Received SIGHUP, starting application alongside the running one"
+        KEEP_OLD_APP=true
+        ;;
+    *)
+        echo "[code-sync] Received SIGHUP, restarting application"
+        ;;
+    esac
+    acquire_sync_lock
     update_files
-    shift # Remove the HUP signal from the arguments
+    release_sync_lock
+    # The trap passes the launcher's own arguments, the app command
     start_app "$@"
+    KEEP_OLD_APP=false
 }
 
 # Set up signal handlers
 trap 'handle_sighup "$@"' HUP
-trap 'echo "[code-sync] Received SIGTERM, shutting down"; kill_process_tree "$(cat "$APP_PID_FILE" 2>/dev/null)"; exit 0' TERM INT
+trap 'echo "[code-sync] Received SIGTERM, shutting down"; echo "Launcher received SIGTERM or SIGINT" > "${EXIT_REASON_FILE}"; kill_process_tree "$(cat "$APP_PID_FILE" 2>/dev/null)"; exit 0' TERM INT
+trap '_status=$?; [ -f "${EXIT_REASON_FILE}" ] || echo "Launcher exited with status ${_status}" > "${EXIT_REASON_FILE}"' EXIT
 
 # Keep running to handle signals
 while true; do
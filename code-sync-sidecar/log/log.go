@@ -2,33 +2,61 @@ package log
 
 import (
 	"log"
+	"os"
+	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
 	// Log is the global logger instance. It's initialized with a no-op logger
 	// until Init is called, preventing nil pointer panics.
 	Log *zap.Logger = zap.NewNop()
+
+	// level is shared with the logger built by Init so it can be changed at runtime.
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	// initialLevel is the level chosen at Init, restored by SetLevel("").
+	initialLevel = zap.InfoLevel
 )
 
+// newConfig builds the zap configuration for the given LOG_LEVEL and LOG_FORMAT
+// values. Invalid values fall back to the defaults with a warning on the standard
+// logger, since zap isn't ready yet.
+func newConfig(levelName, format string) zap.Config {
+	config := zap.NewProductionConfig()
+
+	initialLevel = zap.InfoLevel
+	if levelName != "" {
+		parsed, err := zapcore.ParseLevel(levelName)
+		if err != nil {
+			log.Printf("Warning: Invalid LOG_LEVEL '%s', using default: %v", levelName, initialLevel)
+		} else {
+			initialLevel = parsed
+		}
+	}
+	level.SetLevel(initialLevel)
+	config.Level = level
+
+	switch strings.ToLower(format) {
+	case "", "json":
+	case "console":
+		config.Encoding = "console"
+		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		log.Printf("Warning: Invalid LOG_FORMAT '%s', using json", format)
+	}
+	return config
+}
+
 // Init initializes the global logger with the specified configuration.
 // It should be called once at the beginning of the application.
+//
+// LOG_LEVEL (debug, info, warn, error; default info) sets the initial level and
+// LOG_FORMAT (json or console; default json) the encoding.
 func Init(serviceName string, initialFields map[string]string) {
-	// Configure the logger (e.g., Production config)
-	// You might want to make the level configurable (e.g., via env var or flag)
-	config := zap.NewProductionConfig()
-	// Example: Set level based on an environment variable
-	// levelStr := os.Getenv("LOG_LEVEL")
-	// if levelStr != "" {
-	// 	var level zapcore.Level
-	// 	if err := level.Set(levelStr); err == nil {
-	// 		config.Level.SetLevel(level)
-	// 	} else {
-	// 		// Use default log package for this initial error as zap isn't ready
-	// 		log.Printf("Warning: Invalid LOG_LEVEL '%s', using default: %v", levelStr, config.Level.Level())
-	// 	}
-	// }
+	config := newConfig(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
 
 	// Build the base logger
 	baseLogger, err := config.Build(zap.AddCallerSkip(1)) // Add caller skip so log lines show caller of Info/Warn/etc.
@@ -62,6 +90,30 @@ func Init(serviceName string, initialFields map[string]string) {
 	Log.Info("Global logger initialized")
 }
 
+// SetLevel changes the minimum level of the global logger, e.g. "debug" or "warn".
+// It takes effect immediately, including for loggers derived with With. An empty
+// name restores the level chosen at Init.
+func SetLevel(name string) error {
+	if name == "" {
+		level.SetLevel(initialLevel)
+		return nil
+	}
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// Tee sends every entry logged through the global logger to core as well.
+// Fields added by Init are not passed on to core.
+func Tee(core zapcore.Core) {
+	Log = Log.WithOptions(zap.WrapCore(func(existing zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(existing, core)
+	}))
+}
+
 // Sync flushes any buffered log entries. Applications should take care to call
 // Sync before exiting. This is often done using `defer log.Sync()`.
 func Sync() {
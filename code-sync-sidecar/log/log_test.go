@@ -0,0 +1,32 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewConfig(t *testing.T) {
+	config := newConfig("debug", "console")
+	assert.Equal(t, "console", config.Encoding)
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+
+	// Invalid values fall back to the defaults.
+	config = newConfig("loud", "xml")
+	assert.Equal(t, "json", config.Encoding)
+	assert.Equal(t, zapcore.InfoLevel, level.Level())
+}
+
+func TestSetLevel(t *testing.T) {
+	newConfig("warn", "")
+	require.NoError(t, SetLevel("debug"))
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+
+	assert.Error(t, SetLevel("loud"))
+	assert.Equal(t, zapcore.DebugLevel, level.Level(), "an invalid level leaves the current one")
+
+	require.NoError(t, SetLevel(""))
+	assert.Equal(t, zapcore.WarnLevel, level.Level(), "an empty level restores the initial one")
+}
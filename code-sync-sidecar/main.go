@@ -1,92 +1,146 @@
+// Command code-sync-sidecar runs the sidecar, and the operator commands that
+// inspect it. The sidecar itself is in pkg/syncer; this command wires it up
+// with the launcher, env file and transport packages.
 package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
+	_ "embed"
 	stdlog "log"
-	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"runtime"
 	"syscall"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/syncer"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
 )
 
-const (
-	DefaultFilesDir = "/app-files"
-)
+// version is the sidecar's release, set at build time with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
+// binaryChecksums lists the SHA-256 sum of every file the sidecar provisions,
+// in sha256sum format and keyed by the file's name in binariesSourceDir. It is
+// compiled in, so a file changed in the image or on the volume doesn't match.
+//
+//go:embed binaries/SHA256SUMS
+var binaryChecksums string
+
+// launcherScript is the launcher script the sidecar provisions for the app.
+//
+//go:embed launcher-script/rsync-launcher.sh
+var launcherScript []byte
+
+// binariesSourceDir is where the sidecar image keeps the rsync binaries it
+// provisions when they aren't compiled in; see embeddedRsync.
+const binariesSourceDir = "/app/bin"
 
 func main() {
+	syncer.Version = version
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// runSidecar runs the sidecar until it receives SIGTERM or SIGINT.
+func runSidecar() {
 	// Use standard logger ONLY for errors *before* zap is initialized
 	stdLogger := stdlog.New(os.Stderr, "[INIT_ERROR] ", stdlog.LstdFlags)
 
-	// Read configuration from environment variables
-	appID := os.Getenv("BIFROST_APP_ID")
-	deploymentID := os.Getenv("BIFROST_DEPLOYMENT_ID")
-	filesDir := os.Getenv("BIFROST_FILES_DIR")
-	if filesDir == "" {
-		filesDir = DefaultFilesDir
+	// Read configuration from the optional config file and environment variables
+	cfg, err := syncer.LoadConfig()
+	if err != nil {
+		stdLogger.Fatal(err)
 	}
+	appID := cfg.AppID
+	deploymentID := cfg.DeploymentID
+	filesDir := cfg.Sync.FilesDir
+	apiURL := cfg.API.URL
+	authMode, _ := transport.ParseAuthMode(cfg.API.AuthMode) // Validated by LoadConfig
 
-	if appID == "" {
-		stdLogger.Fatal("BIFROST_APP_ID environment variable is required")
-	}
-	if deploymentID == "" {
-		stdLogger.Fatal("BIFROST_DEPLOYMENT_ID environment variable is required")
-	}
+	// Locate the pod before initializing the logger so every line carries it.
+	pod, podErr := syncer.DetectPodMetadata(context.Background())
+	syncer.Pod = pod
 
-	apiKey := os.Getenv("BIFROST_API_KEY")
-	if apiKey == "" {
-		stdLogger.Fatal("BIFROST_API_KEY environment variable is required")
+	// Initialize the global logger
+	initialFields := syncer.PodLogFields(pod)
+	initialFields["appID"] = appID
+	initialFields["deploymentID"] = deploymentID
+	log.Init("code-sync-sidecar", initialFields)
+	defer log.Sync() // Ensure logs are flushed on exit
+	log.Tee(syncer.DiagnosticLogs)
+	if err := log.SetLevel(cfg.Log.Level); err != nil {
+		log.Warn("Invalid log level, keeping LOG_LEVEL", zap.Error(err))
 	}
-
-	apiURL := os.Getenv("BIFROST_API_URL")
-	if apiURL == "" {
-		stdLogger.Fatal("BIFROST_API_URL environment variable is required")
+	var logShipper *syncer.SidecarLogShipper
+	if cfg.Log.Ship {
+		// Attach before any goroutines log; entries are buffered until the file syncer can send them.
+		logShipper = syncer.NewSidecarLogShipper(cfg.ShipLevel())
+		log.Tee(logShipper)
 	}
 
-	// Initialize the global logger
-	initialFields := map[string]string{
-		"appID":        appID,
-		"deploymentID": deploymentID,
+	if podErr != nil {
+		log.Warn("Failed to detect all pod metadata", zap.Error(podErr))
 	}
-	log.Init("code-sync-sidecar", initialFields)
-	defer log.Sync() // Ensure logs are flushed on exit
 
 	log.Info("Starting code-sync-sidecar",
 		zap.String("filesDir", filesDir),
 		zap.String("apiURL", apiURL),
+		zap.String("authMode", string(authMode)),
 	)
 
-	// Create the sidecar and launcher directories with very open permissions so can be accessed by the app and sidecar.
-	if err := os.MkdirAll(getSidecarDir(filesDir), 0777); err != nil {
-		log.Fatal("Failed to create sidecar directory", zap.Error(err), zap.String("path", getSidecarDir(filesDir)))
-	}
-	if err := os.MkdirAll(getLauncherDir(filesDir), 0777); err != nil {
-		log.Fatal("Failed to create launcher directory", zap.Error(err), zap.String("path", getLauncherDir(filesDir)))
+	tokens, err := transport.NewTokenManager(authMode, apiURL, cfg.API.APIKey, cfg.API.IdentityTokenPath, appID, deploymentID)
+	if err != nil {
+		log.Fatal("Failed to create token manager", zap.Error(err))
 	}
-	if err := os.Chmod(getSidecarDir(filesDir), 0777); err != nil {
-		log.Warn("Failed to change sidecar directory permissions", zap.Error(err), zap.String("path", getSidecarDir(filesDir)))
+	// Exchange the initial token up front so auth misconfiguration fails at startup, not at first push.
+	if _, err := tokens.Token(context.Background()); err != nil {
+		log.Fatal("Failed to obtain Bifrost access token", zap.Error(err))
 	}
-	if err := os.Chmod(getLauncherDir(filesDir), 0777); err != nil {
-		log.Warn("Failed to change launcher directory permissions", zap.Error(err), zap.String("path", getLauncherDir(filesDir)))
+
+	// A marker left by a previous run would report the pod ready before the volume is provisioned.
+	syncer.NewReadinessMarker(cfg.Readiness.File).Unready("sidecar starting")
+
+	// Create the sidecar and launcher directories so they can be accessed by the app and sidecar.
+	if err := launcher.PrepareVolume(filesDir, cfg.Security.Hardened, cfg.Security.SharedGID); err != nil {
+		log.Fatal("Failed to prepare the shared volume", zap.Error(err))
 	}
 
 	log.Info("Created sidecar and launcher directories")
-	if err := copyBinaries(filesDir); err != nil {
+	// The app container runs "code-sync-sidecar launch" from a copy of this binary.
+	sidecarBinary, err := os.Executable()
+	if err != nil {
+		log.Warn("Not provisioning the sidecar binary for the launch command", zap.Error(err))
+	}
+	// The provisioned rsync, launcher script and sidecar only run in Linux app containers,
+	// so development builds for other platforms don't provision them.
+	if runtime.GOOS != "linux" {
+		log.Info("Not provisioning the launcher's binaries outside Linux", zap.String("os", runtime.GOOS))
+	} else if info, err := launcher.CopyBinaries(filesDir, launcher.Assets{
+		LauncherScript: launcherScript,
+		Rsync:          embeddedRsync,
+		SourceDir:      binariesSourceDir,
+		Checksums:      binaryChecksums,
+		Sidecar:        sidecarBinary,
+	}); err != nil {
 		log.Fatal("Failed to copy binaries", zap.Error(err))
+	} else {
+		syncer.Rsync = info
 	}
 
 	// Fetch and write database environment variables
-	if err := writeDatabaseEnvFile(apiURL, apiKey, deploymentID, filesDir); err != nil {
+	if _, err := envfile.WriteDatabaseEnvFile(context.Background(), cfg.DatabaseEnvProvider(tokens), filesDir, cfg.EnvFileOptions()); err != nil {
 		log.Warn("Failed to write database environment file", zap.Error(err))
 		// Don't fail - let the app start without database URLs
+	} else if cfg.Sync.EnvHistory > 0 {
+		if _, err := envfile.SaveVersion(filesDir, "", cfg.Sync.EnvHistory, time.Now()); err != nil {
+			log.Warn("Failed to save env file version", zap.Error(err))
+		}
 	}
 
 	// Create a context that will be canceled on SIGTERM/SIGINT
@@ -102,172 +156,47 @@ func main() {
 		cancel()
 	}()
 
-	rsync, err := NewFileSyncer(
-		ctx,
-		apiURL,
-		apiKey,
-		appID,
-		deploymentID,
-		filesDir,
-	)
+	rsync, err := syncer.NewFileSyncer(ctx, cfg, tokens)
 	if err != nil {
 		log.Fatal("Failed to create file syncer", zap.Error(err))
 	}
-	// Wait for context cancellation (signal or other shutdown reason)
-	<-ctx.Done()
-	log.Info("Shutdown context cancelled, stopping components")
-	rsync.Stop()
+	rsync.MarkReady()
 
-	log.Info("Shutdown complete")
-}
-
-// copyFile now uses the global logger
-func copyFile(src, dst string) error {
-	log.Info("Copying file", zap.String("source", src), zap.String("destination", dst))
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file %s: %w", src, err)
+	if cfg.Status.ListenAddr != "" {
+		go rsync.ServeStatus(ctx, cfg.Status.ListenAddr)
 	}
-	defer srcFile.Close()
-
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file %s: %w", dst, err)
+	if cfg.Status.ControlSocket {
+		go rsync.ServeControl(ctx)
 	}
-	defer dstFile.Close()
-
-	bytesCopied, err := io.Copy(dstFile, srcFile)
-	if err != nil {
-		return fmt.Errorf("failed to copy data from %s to %s: %w", src, dst, err)
+	if cfg.Status.File {
+		go rsync.MaintainStatusFile(ctx, time.Duration(cfg.Status.FileInterval))
 	}
-
-	// Make the destination file executable (0777)
-	if err := os.Chmod(dst, 0777); err != nil {
-		log.Warn("Failed to set executable permission", zap.String("file", dst), zap.Error(err))
+	if cfg.Sync.AppLogDir != "" {
+		forwarder := syncer.NewLogForwarder(cfg.Sync.AppLogDir, rsync.Send)
+		go forwarder.Run(ctx)
 	}
-
-	log.Info("Successfully copied file",
-		zap.String("source", src),
-		zap.String("destination", dst),
-		zap.Int64("bytesCopied", bytesCopied),
-	)
-	return nil
-}
-
-func getSidecarDir(filesDir string) string {
-	return filepath.Join(filesDir, ".sidecar")
-}
-
-func getLauncherDir(filesDir string) string {
-	return filepath.Join(filesDir, ".launcher")
-}
-
-var filesToCopy = []string{
-	"rsync_amd64",
-	"rsync_arm64",
-	"rsync-launcher.sh",
-}
-
-// copyBinaries now uses the global logger
-func copyBinaries(filesDir string) error {
-	log.Info("Setting up binaries", zap.String("targetDir", filesDir))
-	binDir := getSidecarDir(filesDir)
-	if err := os.MkdirAll(binDir, 0777); err != nil {
-		return fmt.Errorf("failed to ensure sidecar directory exists %s: %w", binDir, err)
+	if logShipper != nil {
+		go logShipper.Run(ctx, rsync.Send)
 	}
 
-	for _, file := range filesToCopy {
-		src := filepath.Join("/app/bin", file)
-		dst := filepath.Join(binDir, file)
-		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
-			return fmt.Errorf("failed to create directory %s for binary %s: %w", filepath.Dir(dst), file, err)
-		}
-		if err := copyFile(src, dst); err != nil {
-			return fmt.Errorf("failed to copy binary %s: %w", file, err)
-		}
+	// Reload tunable settings when the config file changes or on SIGUSR1
+	reloadChan := make(chan os.Signal, 1)
+	if len(configReloadSignals) > 0 {
+		signal.Notify(reloadChan, configReloadSignals...)
 	}
-	log.Info("Successfully set up binaries", zap.String("targetDir", filesDir))
-	return nil
-}
-
-// DatabaseEnvVar represents a database environment variable
-type DatabaseEnvVar struct {
-	EnvVarName     string `json:"env_var_name"`
-	ConnectionURI  string `json:"connection_uri"`
-}
+	reloader := syncer.NewConfigReloader(os.Getenv("BIFROST_CONFIG"), cfg, func(newCfg *syncer.Config) {
+		if err := log.SetLevel(newCfg.Log.Level); err != nil {
+			log.Warn("Invalid log level in reloaded configuration", zap.Error(err))
+		}
+		rsync.ApplyConfig(newCfg)
+	})
+	go reloader.Run(ctx, reloadChan)
 
-// writeDatabaseEnvFile fetches database connection URIs from the API and writes them to an env file
-func writeDatabaseEnvFile(apiURL, apiKey, deploymentID, filesDir string) error {
-	log.Info("Fetching database environment variables", 
-		zap.String("deploymentID", deploymentID),
-		zap.String("apiURL", apiURL))
+	// Wait for context cancellation (signal or other shutdown reason)
+	<-ctx.Done()
+	log.Info("Shutdown context cancelled, stopping components")
+	rsync.Stop()
+	rsync.StopLauncher()
 
-	// Build the API endpoint URL
-	url := fmt.Sprintf("%s/api/v1/deployments/%s/database-env-vars", apiURL, deploymentID)
-	
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	
-	// Create request with API key header
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("X-Api-Key", apiKey)
-	
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to fetch database env vars: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-	
-	// Parse response
-	var envVars []DatabaseEnvVar
-	if err := json.NewDecoder(resp.Body).Decode(&envVars); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
-	}
-	
-	// If no databases, don't create the file
-	if len(envVars) == 0 {
-		log.Info("No database environment variables to inject")
-		return nil
-	}
-	
-	// Write to env.sh file
-	envFile := filepath.Join(getSidecarDir(filesDir), "env.sh")
-	f, err := os.Create(envFile)
-	if err != nil {
-		return fmt.Errorf("failed to create env file: %w", err)
-	}
-	defer f.Close()
-	
-	// Write each environment variable
-	for _, envVar := range envVars {
-		if _, err := fmt.Fprintf(f, "export %s=\"%s\"\n", envVar.EnvVarName, envVar.ConnectionURI); err != nil {
-			return fmt.Errorf("failed to write env var %s: %w", envVar.EnvVarName, err)
-		}
-		log.Info("Added database environment variable", 
-			zap.String("envVar", envVar.EnvVarName),
-			zap.String("envFile", envFile))
-	}
-	
-	// Make file readable by all
-	if err := os.Chmod(envFile, 0644); err != nil {
-		log.Warn("Failed to set env file permissions", zap.Error(err))
-	}
-	
-	log.Info("Successfully wrote database environment variables", 
-		zap.String("envFile", envFile),
-		zap.Int("count", len(envVars)))
-	
-	return nil
+	log.Info("Shutdown complete")
 }
@@ -22,31 +22,172 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type DeletedPathResult_Status int32
+
+const (
+	DeletedPathResult_UNKNOWN      DeletedPathResult_Status = 0
+	DeletedPathResult_REMOVED      DeletedPathResult_Status = 1
+	DeletedPathResult_NOT_FOUND    DeletedPathResult_Status = 2 // Nothing to remove; not an error
+	DeletedPathResult_WOULD_REMOVE DeletedPathResult_Status = 3 // With deleted_paths_dry_run
+	DeletedPathResult_FAILED       DeletedPathResult_Status = 4
+)
+
+// Enum value maps for DeletedPathResult_Status.
+var (
+	DeletedPathResult_Status_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "REMOVED",
+		2: "NOT_FOUND",
+		3: "WOULD_REMOVE",
+		4: "FAILED",
+	}
+	DeletedPathResult_Status_value = map[string]int32{
+		"UNKNOWN":      0,
+		"REMOVED":      1,
+		"NOT_FOUND":    2,
+		"WOULD_REMOVE": 3,
+		"FAILED":       4,
+	}
+)
+
+func (x DeletedPathResult_Status) Enum() *DeletedPathResult_Status {
+	p := new(DeletedPathResult_Status)
+	*p = x
+	return p
+}
+
+func (x DeletedPathResult_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeletedPathResult_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[0].Descriptor()
+}
+
+func (DeletedPathResult_Status) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[0]
+}
+
+func (x DeletedPathResult_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeletedPathResult_Status.Descriptor instead.
+func (DeletedPathResult_Status) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{6, 0}
+}
+
 type PushResponse_PushStatus int32
 
 const (
-	PushResponse_UNKNOWN     PushResponse_PushStatus = 0
-	PushResponse_PENDING     PushResponse_PushStatus = 1
-	PushResponse_IN_PROGRESS PushResponse_PushStatus = 2
-	PushResponse_FAILED      PushResponse_PushStatus = 3
-	PushResponse_COMPLETED   PushResponse_PushStatus = 4
+	PushResponse_UNKNOWN           PushResponse_PushStatus = 0
+	PushResponse_PENDING           PushResponse_PushStatus = 1 // Intermediate: held for not_before, a push window or a sequence gap; see held_until
+	PushResponse_IN_PROGRESS       PushResponse_PushStatus = 2
+	PushResponse_FAILED            PushResponse_PushStatus = 3
+	PushResponse_COMPLETED         PushResponse_PushStatus = 4
+	PushResponse_CANCELLED         PushResponse_PushStatus = 5
+	PushResponse_CONFLICT          PushResponse_PushStatus = 6 // Files the batch touches were modified in the deployment since the last push
+	PushResponse_INSUFFICIENT_DISK PushResponse_PushStatus = 7 // Not enough free space to apply the batch; nothing was changed
+	PushResponse_RELOAD_FAILED     PushResponse_PushStatus = 8 // Files applied and the app reloaded, but the health probe never passed
+	// Intermediate states, each sent at most once before the push's final response.
+	PushResponse_RECEIVED  PushResponse_PushStatus = 9  // Push queued by the sidecar
+	PushResponse_APPLYING  PushResponse_PushStatus = 10 // Hooks passed, rsync is applying the batch
+	PushResponse_RELOADING PushResponse_PushStatus = 11 // Files applied, the launcher is being signalled
+	PushResponse_HEALTHY   PushResponse_PushStatus = 12 // App passed the health probe after reloading (only with a probe configured)
+	// Final state when the push was activated but then rolled back to the
+	// previous release, or to the previous files after a stage of its
+	// activation_plan wasn't healthy; see failed_activation_stage.
+	PushResponse_ROLLED_BACK PushResponse_PushStatus = 13
+	// Not applied because a later push in the same debounce window carried the
+	// same files and more; see superseded_by.
+	PushResponse_SUPERSEDED PushResponse_PushStatus = 14
+	// Applied by the coordinating replica but not by every replica; see replica_results.
+	PushResponse_PARTIAL PushResponse_PushStatus = 15
+	// Not reloaded because required_env named vars missing from the app's env; see missing_env.
+	PushResponse_MISSING_ENV PushResponse_PushStatus = 16
+	// rsync used no CPU and did no I/O for the stall timeout, e.g. blocked on a
+	// dead network mount, and was killed. Files it changed were restored.
+	PushResponse_STALLED PushResponse_PushStatus = 17
+	// The push deletes more of the deployment's files than sync.max_delete_percent
+	// allows; nothing was changed. See mirror_deletions.
+	PushResponse_TOO_MANY_DELETIONS PushResponse_PushStatus = 18
+	// Applying the push would take the workspace over a hard quota; files it
+	// changed were restored. See workspace_usage.
+	PushResponse_QUOTA_EXCEEDED PushResponse_PushStatus = 19
+	// The batch would change paths outside the push's paths; nothing was
+	// changed. See out_of_scope_paths.
+	PushResponse_OUT_OF_SCOPE PushResponse_PushStatus = 20
+	// The push only gave batch_hash and the sidecar doesn't have that batch
+	// cached; nothing was changed. Send the push again with its batch.
+	PushResponse_BATCH_NOT_CACHED PushResponse_PushStatus = 21
+	// Files applied, but the launcher never wrote its PID or its process is
+	// gone, so the app wasn't reloaded. The app picks the files up when its
+	// container restarts the launcher.
+	PushResponse_LAUNCHER_NOT_RUNNING PushResponse_PushStatus = 22
+	// Intermediate: with sync.require_approval, the push is staged and
+	// waits for an ACTIVATE before the app runs it. PUSH_CANCEL discards it.
+	PushResponse_AWAITING_APPROVAL PushResponse_PushStatus = 23
+	// The push's sequence is older than one the sidecar already received,
+	// so applying it could overwrite newer files; nothing was changed. See
+	// last_sequence.
+	PushResponse_OUT_OF_ORDER PushResponse_PushStatus = 24
 )
 
 // Enum value maps for PushResponse_PushStatus.
 var (
 	PushResponse_PushStatus_name = map[int32]string{
-		0: "UNKNOWN",
-		1: "PENDING",
-		2: "IN_PROGRESS",
-		3: "FAILED",
-		4: "COMPLETED",
+		0:  "UNKNOWN",
+		1:  "PENDING",
+		2:  "IN_PROGRESS",
+		3:  "FAILED",
+		4:  "COMPLETED",
+		5:  "CANCELLED",
+		6:  "CONFLICT",
+		7:  "INSUFFICIENT_DISK",
+		8:  "RELOAD_FAILED",
+		9:  "RECEIVED",
+		10: "APPLYING",
+		11: "RELOADING",
+		12: "HEALTHY",
+		13: "ROLLED_BACK",
+		14: "SUPERSEDED",
+		15: "PARTIAL",
+		16: "MISSING_ENV",
+		17: "STALLED",
+		18: "TOO_MANY_DELETIONS",
+		19: "QUOTA_EXCEEDED",
+		20: "OUT_OF_SCOPE",
+		21: "BATCH_NOT_CACHED",
+		22: "LAUNCHER_NOT_RUNNING",
+		23: "AWAITING_APPROVAL",
+		24: "OUT_OF_ORDER",
 	}
 	PushResponse_PushStatus_value = map[string]int32{
-		"UNKNOWN":     0,
-		"PENDING":     1,
-		"IN_PROGRESS": 2,
-		"FAILED":      3,
-		"COMPLETED":   4,
+		"UNKNOWN":              0,
+		"PENDING":              1,
+		"IN_PROGRESS":          2,
+		"FAILED":               3,
+		"COMPLETED":            4,
+		"CANCELLED":            5,
+		"CONFLICT":             6,
+		"INSUFFICIENT_DISK":    7,
+		"RELOAD_FAILED":        8,
+		"RECEIVED":             9,
+		"APPLYING":             10,
+		"RELOADING":            11,
+		"HEALTHY":              12,
+		"ROLLED_BACK":          13,
+		"SUPERSEDED":           14,
+		"PARTIAL":              15,
+		"MISSING_ENV":          16,
+		"STALLED":              17,
+		"TOO_MANY_DELETIONS":   18,
+		"QUOTA_EXCEEDED":       19,
+		"OUT_OF_SCOPE":         20,
+		"BATCH_NOT_CACHED":     21,
+		"LAUNCHER_NOT_RUNNING": 22,
+		"AWAITING_APPROVAL":    23,
+		"OUT_OF_ORDER":         24,
 	}
 )
 
@@ -61,11 +202,11 @@ func (x PushResponse_PushStatus) String() string {
 }
 
 func (PushResponse_PushStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[0].Descriptor()
+	return file_ws_proto_enumTypes[1].Descriptor()
 }
 
 func (PushResponse_PushStatus) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[0]
+	return &file_ws_proto_enumTypes[1]
 }
 
 func (x PushResponse_PushStatus) Number() protoreflect.EnumNumber {
@@ -74,7 +215,65 @@ func (x PushResponse_PushStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use PushResponse_PushStatus.Descriptor instead.
 func (PushResponse_PushStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{2, 0}
+	return file_ws_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type PushProgress_Stage int32
+
+const (
+	PushProgress_UNKNOWN     PushProgress_Stage = 0
+	PushProgress_DOWNLOADING PushProgress_Stage = 1 // Batch received by the sidecar
+	PushProgress_APPLYING    PushProgress_Stage = 2 // rsync is applying the batch
+	PushProgress_RELOADING   PushProgress_Stage = 3 // Launcher is being signalled to pick up the new files
+	PushProgress_BUILDING    PushProgress_Stage = 4 // The build command is running; see output_lines
+	PushProgress_INSTALLING  PushProgress_Stage = 5 // The dependency install command is running
+)
+
+// Enum value maps for PushProgress_Stage.
+var (
+	PushProgress_Stage_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "DOWNLOADING",
+		2: "APPLYING",
+		3: "RELOADING",
+		4: "BUILDING",
+		5: "INSTALLING",
+	}
+	PushProgress_Stage_value = map[string]int32{
+		"UNKNOWN":     0,
+		"DOWNLOADING": 1,
+		"APPLYING":    2,
+		"RELOADING":   3,
+		"BUILDING":    4,
+		"INSTALLING":  5,
+	}
+)
+
+func (x PushProgress_Stage) Enum() *PushProgress_Stage {
+	p := new(PushProgress_Stage)
+	*p = x
+	return p
+}
+
+func (x PushProgress_Stage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PushProgress_Stage) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[2].Descriptor()
+}
+
+func (PushProgress_Stage) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[2]
+}
+
+func (x PushProgress_Stage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PushProgress_Stage.Descriptor instead.
+func (PushProgress_Stage) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{17, 0}
 }
 
 type ResponseAssertion_AssertionType int32
@@ -116,11 +315,11 @@ func (x ResponseAssertion_AssertionType) String() string {
 }
 
 func (ResponseAssertion_AssertionType) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[1].Descriptor()
+	return file_ws_proto_enumTypes[3].Descriptor()
 }
 
 func (ResponseAssertion_AssertionType) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[1]
+	return &file_ws_proto_enumTypes[3]
 }
 
 func (x ResponseAssertion_AssertionType) Number() protoreflect.EnumNumber {
@@ -129,7 +328,7 @@ func (x ResponseAssertion_AssertionType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ResponseAssertion_AssertionType.Descriptor instead.
 func (ResponseAssertion_AssertionType) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{3, 0}
+	return file_ws_proto_rawDescGZIP(), []int{20, 0}
 }
 
 type VariableExtraction_SourceType int32
@@ -168,11 +367,11 @@ func (x VariableExtraction_SourceType) String() string {
 }
 
 func (VariableExtraction_SourceType) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[2].Descriptor()
+	return file_ws_proto_enumTypes[4].Descriptor()
 }
 
 func (VariableExtraction_SourceType) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[2]
+	return &file_ws_proto_enumTypes[4]
 }
 
 func (x VariableExtraction_SourceType) Number() protoreflect.EnumNumber {
@@ -181,7 +380,7 @@ func (x VariableExtraction_SourceType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use VariableExtraction_SourceType.Descriptor instead.
 func (VariableExtraction_SourceType) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{4, 0}
+	return file_ws_proto_rawDescGZIP(), []int{21, 0}
 }
 
 type HTTPRequestStep_HttpMethod int32
@@ -229,11 +428,11 @@ func (x HTTPRequestStep_HttpMethod) String() string {
 }
 
 func (HTTPRequestStep_HttpMethod) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[3].Descriptor()
+	return file_ws_proto_enumTypes[5].Descriptor()
 }
 
 func (HTTPRequestStep_HttpMethod) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[3]
+	return &file_ws_proto_enumTypes[5]
 }
 
 func (x HTTPRequestStep_HttpMethod) Number() protoreflect.EnumNumber {
@@ -242,7 +441,7 @@ func (x HTTPRequestStep_HttpMethod) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use HTTPRequestStep_HttpMethod.Descriptor instead.
 func (HTTPRequestStep_HttpMethod) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{5, 0}
+	return file_ws_proto_rawDescGZIP(), []int{22, 0}
 }
 
 type TestResult_TestStatus int32
@@ -287,11 +486,11 @@ func (x TestResult_TestStatus) String() string {
 }
 
 func (TestResult_TestStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[4].Descriptor()
+	return file_ws_proto_enumTypes[6].Descriptor()
 }
 
 func (TestResult_TestStatus) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[4]
+	return &file_ws_proto_enumTypes[6]
 }
 
 func (x TestResult_TestStatus) Number() protoreflect.EnumNumber {
@@ -300,7 +499,7 @@ func (x TestResult_TestStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use TestResult_TestStatus.Descriptor instead.
 func (TestResult_TestStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{8, 0}
+	return file_ws_proto_rawDescGZIP(), []int{25, 0}
 }
 
 type VerificationProgressMessage_VerificationStage int32
@@ -360,11 +559,11 @@ func (x VerificationProgressMessage_VerificationStage) String() string {
 }
 
 func (VerificationProgressMessage_VerificationStage) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[5].Descriptor()
+	return file_ws_proto_enumTypes[7].Descriptor()
 }
 
 func (VerificationProgressMessage_VerificationStage) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[5]
+	return &file_ws_proto_enumTypes[7]
 }
 
 func (x VerificationProgressMessage_VerificationStage) Number() protoreflect.EnumNumber {
@@ -373,7 +572,7 @@ func (x VerificationProgressMessage_VerificationStage) Number() protoreflect.Enu
 
 // Deprecated: Use VerificationProgressMessage_VerificationStage.Descriptor instead.
 func (VerificationProgressMessage_VerificationStage) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{12, 0}
+	return file_ws_proto_rawDescGZIP(), []int{29, 0}
 }
 
 type VerificationProgressResponse_VerificationStatus int32
@@ -415,11 +614,11 @@ func (x VerificationProgressResponse_VerificationStatus) String() string {
 }
 
 func (VerificationProgressResponse_VerificationStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[6].Descriptor()
+	return file_ws_proto_enumTypes[8].Descriptor()
 }
 
 func (VerificationProgressResponse_VerificationStatus) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[6]
+	return &file_ws_proto_enumTypes[8]
 }
 
 func (x VerificationProgressResponse_VerificationStatus) Number() protoreflect.EnumNumber {
@@ -428,7 +627,7 @@ func (x VerificationProgressResponse_VerificationStatus) Number() protoreflect.E
 
 // Deprecated: Use VerificationProgressResponse_VerificationStatus.Descriptor instead.
 func (VerificationProgressResponse_VerificationStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{13, 0}
+	return file_ws_proto_rawDescGZIP(), []int{30, 0}
 }
 
 type AuthResponse_AuthStatus int32
@@ -464,11 +663,11 @@ func (x AuthResponse_AuthStatus) String() string {
 }
 
 func (AuthResponse_AuthStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[7].Descriptor()
+	return file_ws_proto_enumTypes[9].Descriptor()
 }
 
 func (AuthResponse_AuthStatus) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[7]
+	return &file_ws_proto_enumTypes[9]
 }
 
 func (x AuthResponse_AuthStatus) Number() protoreflect.EnumNumber {
@@ -477,272 +676,5616 @@ func (x AuthResponse_AuthStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use AuthResponse_AuthStatus.Descriptor instead.
 func (AuthResponse_AuthStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{15, 0}
+	return file_ws_proto_rawDescGZIP(), []int{32, 0}
 }
 
-type WebsocketMessage_MessageType int32
+type StatusReport_LauncherState int32
 
 const (
-	WebsocketMessage_UNKNOWN                        WebsocketMessage_MessageType = 0
-	WebsocketMessage_PUSH_REQUEST                   WebsocketMessage_MessageType = 1
-	WebsocketMessage_PUSH_RESPONSE                  WebsocketMessage_MessageType = 2
-	WebsocketMessage_VERIFICATION_PROGRESS          WebsocketMessage_MessageType = 3
-	WebsocketMessage_VERIFICATION_PROGRESS_RESPONSE WebsocketMessage_MessageType = 4
-	WebsocketMessage_AUTH_REQUEST                   WebsocketMessage_MessageType = 5
-	WebsocketMessage_AUTH_RESPONSE                  WebsocketMessage_MessageType = 6
+	StatusReport_UNKNOWN     StatusReport_LauncherState = 0
+	StatusReport_RUNNING     StatusReport_LauncherState = 1
+	StatusReport_NOT_RUNNING StatusReport_LauncherState = 2 // PID file exists but the process is gone
+	StatusReport_NO_PID_FILE StatusReport_LauncherState = 3 // Launcher has not started yet
 )
 
-// Enum value maps for WebsocketMessage_MessageType.
+// Enum value maps for StatusReport_LauncherState.
 var (
-	WebsocketMessage_MessageType_name = map[int32]string{
+	StatusReport_LauncherState_name = map[int32]string{
 		0: "UNKNOWN",
-		1: "PUSH_REQUEST",
-		2: "PUSH_RESPONSE",
-		3: "VERIFICATION_PROGRESS",
-		4: "VERIFICATION_PROGRESS_RESPONSE",
-		5: "AUTH_REQUEST",
-		6: "AUTH_RESPONSE",
+		1: "RUNNING",
+		2: "NOT_RUNNING",
+		3: "NO_PID_FILE",
 	}
-	WebsocketMessage_MessageType_value = map[string]int32{
-		"UNKNOWN":                        0,
-		"PUSH_REQUEST":                   1,
-		"PUSH_RESPONSE":                  2,
-		"VERIFICATION_PROGRESS":          3,
-		"VERIFICATION_PROGRESS_RESPONSE": 4,
-		"AUTH_REQUEST":                   5,
-		"AUTH_RESPONSE":                  6,
+	StatusReport_LauncherState_value = map[string]int32{
+		"UNKNOWN":     0,
+		"RUNNING":     1,
+		"NOT_RUNNING": 2,
+		"NO_PID_FILE": 3,
 	}
 )
 
-func (x WebsocketMessage_MessageType) Enum() *WebsocketMessage_MessageType {
-	p := new(WebsocketMessage_MessageType)
+func (x StatusReport_LauncherState) Enum() *StatusReport_LauncherState {
+	p := new(StatusReport_LauncherState)
 	*p = x
 	return p
 }
 
-func (x WebsocketMessage_MessageType) String() string {
+func (x StatusReport_LauncherState) String() string {
 	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
 }
 
-func (WebsocketMessage_MessageType) Descriptor() protoreflect.EnumDescriptor {
-	return file_ws_proto_enumTypes[8].Descriptor()
+func (StatusReport_LauncherState) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[10].Descriptor()
 }
 
-func (WebsocketMessage_MessageType) Type() protoreflect.EnumType {
-	return &file_ws_proto_enumTypes[8]
+func (StatusReport_LauncherState) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[10]
 }
 
-func (x WebsocketMessage_MessageType) Number() protoreflect.EnumNumber {
+func (x StatusReport_LauncherState) Number() protoreflect.EnumNumber {
 	return protoreflect.EnumNumber(x)
 }
 
-// Deprecated: Use WebsocketMessage_MessageType.Descriptor instead.
-func (WebsocketMessage_MessageType) EnumDescriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{16, 0}
+// Deprecated: Use StatusReport_LauncherState.Descriptor instead.
+func (StatusReport_LauncherState) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{34, 0}
 }
 
-type DatabaseBranchUpdate struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	DatabaseName     string                 `protobuf:"bytes,1,opt,name=database_name,json=databaseName,proto3" json:"database_name,omitempty"`
-	PreviousBranchId string                 `protobuf:"bytes,2,opt,name=previous_branch_id,json=previousBranchId,proto3" json:"previous_branch_id,omitempty"`
-	NewBranchId      string                 `protobuf:"bytes,3,opt,name=new_branch_id,json=newBranchId,proto3" json:"new_branch_id,omitempty"`
-	BranchCreated    bool                   `protobuf:"varint,4,opt,name=branch_created,json=branchCreated,proto3" json:"branch_created,omitempty"`     // True if new_branch was created
-	ParentBranchId   string                 `protobuf:"bytes,5,opt,name=parent_branch_id,json=parentBranchId,proto3" json:"parent_branch_id,omitempty"` // Set when branch_created=true
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
-}
+type SnapshotResponse_Status int32
 
-func (x *DatabaseBranchUpdate) Reset() {
-	*x = DatabaseBranchUpdate{}
-	mi := &file_ws_proto_msgTypes[0]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
+const (
+	SnapshotResponse_UNKNOWN   SnapshotResponse_Status = 0
+	SnapshotResponse_COMPLETED SnapshotResponse_Status = 1
+	SnapshotResponse_FAILED    SnapshotResponse_Status = 2
+)
 
-func (x *DatabaseBranchUpdate) String() string {
-	return protoimpl.X.MessageStringOf(x)
+// Enum value maps for SnapshotResponse_Status.
+var (
+	SnapshotResponse_Status_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "COMPLETED",
+		2: "FAILED",
+	}
+	SnapshotResponse_Status_value = map[string]int32{
+		"UNKNOWN":   0,
+		"COMPLETED": 1,
+		"FAILED":    2,
+	}
+)
+
+func (x SnapshotResponse_Status) Enum() *SnapshotResponse_Status {
+	p := new(SnapshotResponse_Status)
+	*p = x
+	return p
 }
 
-func (*DatabaseBranchUpdate) ProtoMessage() {}
+func (x SnapshotResponse_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
 
-func (x *DatabaseBranchUpdate) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[0]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
+func (SnapshotResponse_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[11].Descriptor()
 }
 
-// Deprecated: Use DatabaseBranchUpdate.ProtoReflect.Descriptor instead.
-func (*DatabaseBranchUpdate) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{0}
+func (SnapshotResponse_Status) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[11]
 }
 
-func (x *DatabaseBranchUpdate) GetDatabaseName() string {
-	if x != nil {
-		return x.DatabaseName
-	}
-	return ""
+func (x SnapshotResponse_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
 }
 
-func (x *DatabaseBranchUpdate) GetPreviousBranchId() string {
-	if x != nil {
-		return x.PreviousBranchId
-	}
-	return ""
+// Deprecated: Use SnapshotResponse_Status.Descriptor instead.
+func (SnapshotResponse_Status) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{49, 0}
 }
 
-func (x *DatabaseBranchUpdate) GetNewBranchId() string {
-	if x != nil {
-		return x.NewBranchId
+type EnvVarProvenance_Source int32
+
+const (
+	EnvVarProvenance_UNKNOWN         EnvVarProvenance_Source = 0
+	EnvVarProvenance_DATABASE        EnvVarProvenance_Source = 1 // The Bifrost API's database env vars
+	EnvVarProvenance_PUSH            EnvVarProvenance_Source = 2 // The same, after a push's database branch updates changed it
+	EnvVarProvenance_SECRET_PROVIDER EnvVarProvenance_Source = 3 // A Vault reference, or the AWS Secrets Manager or Kubernetes Secret provider
+)
+
+// Enum value maps for EnvVarProvenance_Source.
+var (
+	EnvVarProvenance_Source_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "DATABASE",
+		2: "PUSH",
+		3: "SECRET_PROVIDER",
 	}
-	return ""
+	EnvVarProvenance_Source_value = map[string]int32{
+		"UNKNOWN":         0,
+		"DATABASE":        1,
+		"PUSH":            2,
+		"SECRET_PROVIDER": 3,
+	}
+)
+
+func (x EnvVarProvenance_Source) Enum() *EnvVarProvenance_Source {
+	p := new(EnvVarProvenance_Source)
+	*p = x
+	return p
 }
 
-func (x *DatabaseBranchUpdate) GetBranchCreated() bool {
-	if x != nil {
-		return x.BranchCreated
-	}
-	return false
+func (x EnvVarProvenance_Source) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
 }
 
-func (x *DatabaseBranchUpdate) GetParentBranchId() string {
-	if x != nil {
-		return x.ParentBranchId
-	}
-	return ""
+func (EnvVarProvenance_Source) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[12].Descriptor()
 }
 
-type PushMessage struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	PushId            string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
-	BatchFile         []byte                 `protobuf:"bytes,2,opt,name=batch_file,json=batchFile,proto3" json:"batch_file,omitempty"`
-	CodeDiff          string                 `protobuf:"bytes,3,opt,name=code_diff,json=codeDiff,proto3" json:"code_diff,omitempty"`
-	ChangeDescription string                 `protobuf:"bytes,4,opt,name=change_description,json=changeDescription,proto3" json:"change_description,omitempty"`
-	// Diff metadata fields (merged from DiffMetadata)
-	FilesChanged int32 `protobuf:"varint,5,opt,name=files_changed,json=filesChanged,proto3" json:"files_changed,omitempty"`
-	Additions    int32 `protobuf:"varint,6,opt,name=additions,proto3" json:"additions,omitempty"`
-	Deletions    int32 `protobuf:"varint,7,opt,name=deletions,proto3" json:"deletions,omitempty"`
-	// New field for branch updates
-	DatabaseBranchUpdates []*DatabaseBranchUpdate `protobuf:"bytes,8,rep,name=database_branch_updates,json=databaseBranchUpdates,proto3" json:"database_branch_updates,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+func (EnvVarProvenance_Source) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[12]
 }
 
-func (x *PushMessage) Reset() {
-	*x = PushMessage{}
-	mi := &file_ws_proto_msgTypes[1]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x EnvVarProvenance_Source) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
 }
 
-func (x *PushMessage) String() string {
-	return protoimpl.X.MessageStringOf(x)
+// Deprecated: Use EnvVarProvenance_Source.Descriptor instead.
+func (EnvVarProvenance_Source) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{56, 0}
 }
 
-func (*PushMessage) ProtoMessage() {}
+type DirEntry_Type int32
 
-func (x *PushMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[1]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+const (
+	DirEntry_UNKNOWN   DirEntry_Type = 0
+	DirEntry_FILE      DirEntry_Type = 1
+	DirEntry_DIRECTORY DirEntry_Type = 2
+	DirEntry_SYMLINK   DirEntry_Type = 3 // Not followed; size and mode are the link's own
+	DirEntry_OTHER     DirEntry_Type = 4 // Sockets, devices and the like
+)
+
+// Enum value maps for DirEntry_Type.
+var (
+	DirEntry_Type_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "FILE",
+		2: "DIRECTORY",
+		3: "SYMLINK",
+		4: "OTHER",
 	}
-	return mi.MessageOf(x)
+	DirEntry_Type_value = map[string]int32{
+		"UNKNOWN":   0,
+		"FILE":      1,
+		"DIRECTORY": 2,
+		"SYMLINK":   3,
+		"OTHER":     4,
+	}
+)
+
+func (x DirEntry_Type) Enum() *DirEntry_Type {
+	p := new(DirEntry_Type)
+	*p = x
+	return p
 }
 
-// Deprecated: Use PushMessage.ProtoReflect.Descriptor instead.
-func (*PushMessage) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{1}
+func (x DirEntry_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
 }
 
-func (x *PushMessage) GetPushId() string {
-	if x != nil {
-		return x.PushId
-	}
-	return ""
+func (DirEntry_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[13].Descriptor()
 }
 
-func (x *PushMessage) GetBatchFile() []byte {
+func (DirEntry_Type) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[13]
+}
+
+func (x DirEntry_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DirEntry_Type.Descriptor instead.
+func (DirEntry_Type) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{61, 0}
+}
+
+type LogTailEnd_Reason int32
+
+const (
+	LogTailEnd_UNKNOWN LogTailEnd_Reason = 0
+	LogTailEnd_STOPPED LogTailEnd_Reason = 1 // By LOG_TAIL_STOP
+	LogTailEnd_EXPIRED LogTailEnd_Reason = 2 // The tail's duration ran out
+	LogTailEnd_FAILED  LogTailEnd_Reason = 3
+)
+
+// Enum value maps for LogTailEnd_Reason.
+var (
+	LogTailEnd_Reason_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "STOPPED",
+		2: "EXPIRED",
+		3: "FAILED",
+	}
+	LogTailEnd_Reason_value = map[string]int32{
+		"UNKNOWN": 0,
+		"STOPPED": 1,
+		"EXPIRED": 2,
+		"FAILED":  3,
+	}
+)
+
+func (x LogTailEnd_Reason) Enum() *LogTailEnd_Reason {
+	p := new(LogTailEnd_Reason)
+	*p = x
+	return p
+}
+
+func (x LogTailEnd_Reason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogTailEnd_Reason) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[14].Descriptor()
+}
+
+func (LogTailEnd_Reason) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[14]
+}
+
+func (x LogTailEnd_Reason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogTailEnd_Reason.Descriptor instead.
+func (LogTailEnd_Reason) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{66, 0}
+}
+
+type ResyncRequest_Reason int32
+
+const (
+	ResyncRequest_UNKNOWN       ResyncRequest_Reason = 0
+	ResyncRequest_DRIFT         ResyncRequest_Reason = 1 // Files pushes wrote were changed or removed in the deployment
+	ResyncRequest_CORRUPTION    ResyncRequest_Reason = 2 // The sidecar's state or manifest is unreadable, or a failed push couldn't be rolled back
+	ResyncRequest_MISSING_STATE ResyncRequest_Reason = 3 // The files directory has files but the sidecar has no record of the pushes that wrote them
+	ResyncRequest_APP_REQUESTED ResyncRequest_Reason = 4 // The app asked for one over the control socket
+)
+
+// Enum value maps for ResyncRequest_Reason.
+var (
+	ResyncRequest_Reason_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "DRIFT",
+		2: "CORRUPTION",
+		3: "MISSING_STATE",
+		4: "APP_REQUESTED",
+	}
+	ResyncRequest_Reason_value = map[string]int32{
+		"UNKNOWN":       0,
+		"DRIFT":         1,
+		"CORRUPTION":    2,
+		"MISSING_STATE": 3,
+		"APP_REQUESTED": 4,
+	}
+)
+
+func (x ResyncRequest_Reason) Enum() *ResyncRequest_Reason {
+	p := new(ResyncRequest_Reason)
+	*p = x
+	return p
+}
+
+func (x ResyncRequest_Reason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResyncRequest_Reason) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[15].Descriptor()
+}
+
+func (ResyncRequest_Reason) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[15]
+}
+
+func (x ResyncRequest_Reason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResyncRequest_Reason.Descriptor instead.
+func (ResyncRequest_Reason) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{68, 0}
+}
+
+type EnvRollbackResponse_Status int32
+
+const (
+	EnvRollbackResponse_UNKNOWN   EnvRollbackResponse_Status = 0
+	EnvRollbackResponse_COMPLETED EnvRollbackResponse_Status = 1
+	EnvRollbackResponse_FAILED    EnvRollbackResponse_Status = 2
+)
+
+// Enum value maps for EnvRollbackResponse_Status.
+var (
+	EnvRollbackResponse_Status_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "COMPLETED",
+		2: "FAILED",
+	}
+	EnvRollbackResponse_Status_value = map[string]int32{
+		"UNKNOWN":   0,
+		"COMPLETED": 1,
+		"FAILED":    2,
+	}
+)
+
+func (x EnvRollbackResponse_Status) Enum() *EnvRollbackResponse_Status {
+	p := new(EnvRollbackResponse_Status)
+	*p = x
+	return p
+}
+
+func (x EnvRollbackResponse_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EnvRollbackResponse_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[16].Descriptor()
+}
+
+func (EnvRollbackResponse_Status) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[16]
+}
+
+func (x EnvRollbackResponse_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EnvRollbackResponse_Status.Descriptor instead.
+func (EnvRollbackResponse_Status) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{71, 0}
+}
+
+type WebsocketMessage_MessageType int32
+
+const (
+	WebsocketMessage_UNKNOWN                        WebsocketMessage_MessageType = 0
+	WebsocketMessage_PUSH_REQUEST                   WebsocketMessage_MessageType = 1
+	WebsocketMessage_PUSH_RESPONSE                  WebsocketMessage_MessageType = 2
+	WebsocketMessage_VERIFICATION_PROGRESS          WebsocketMessage_MessageType = 3
+	WebsocketMessage_VERIFICATION_PROGRESS_RESPONSE WebsocketMessage_MessageType = 4
+	WebsocketMessage_AUTH_REQUEST                   WebsocketMessage_MessageType = 5
+	WebsocketMessage_AUTH_RESPONSE                  WebsocketMessage_MessageType = 6
+	WebsocketMessage_STATUS_REPORT                  WebsocketMessage_MessageType = 7
+	WebsocketMessage_LOG_ENTRY                      WebsocketMessage_MessageType = 8
+	WebsocketMessage_SHELL_OPEN                     WebsocketMessage_MessageType = 9
+	WebsocketMessage_SHELL_STDIN                    WebsocketMessage_MessageType = 10
+	WebsocketMessage_SHELL_STDOUT                   WebsocketMessage_MessageType = 11
+	WebsocketMessage_SHELL_RESIZE                   WebsocketMessage_MessageType = 12
+	WebsocketMessage_SHELL_CLOSE                    WebsocketMessage_MessageType = 13
+	WebsocketMessage_SHELL_EXIT                     WebsocketMessage_MessageType = 14
+	WebsocketMessage_PUSH_CANCEL                    WebsocketMessage_MessageType = 15
+	WebsocketMessage_PUSH_PROGRESS                  WebsocketMessage_MessageType = 16
+	WebsocketMessage_SIDECAR_LOG                    WebsocketMessage_MessageType = 17 // The sidecar's own logs, carried in log_batch
+	WebsocketMessage_HELLO                          WebsocketMessage_MessageType = 18
+	WebsocketMessage_SNAPSHOT_CREATE                WebsocketMessage_MessageType = 19 // Carried in snapshot_request
+	WebsocketMessage_SNAPSHOT_RESTORE               WebsocketMessage_MessageType = 20 // Carried in snapshot_request
+	WebsocketMessage_SNAPSHOT_RESPONSE              WebsocketMessage_MessageType = 21
+	WebsocketMessage_MANIFEST_REQUEST               WebsocketMessage_MessageType = 22
+	WebsocketMessage_MANIFEST_RESPONSE              WebsocketMessage_MessageType = 23
+	WebsocketMessage_LAUNCHER_EXITED                WebsocketMessage_MessageType = 24
+	WebsocketMessage_HELLO_ACK                      WebsocketMessage_MessageType = 25
+	WebsocketMessage_DIAGNOSTICS_REQUEST            WebsocketMessage_MessageType = 26
+	WebsocketMessage_DIAGNOSTICS_CHUNK              WebsocketMessage_MessageType = 27
+	WebsocketMessage_SYNC_STATUS_REQUEST            WebsocketMessage_MessageType = 28
+	WebsocketMessage_SYNC_STATUS_RESPONSE           WebsocketMessage_MessageType = 29
+	WebsocketMessage_FILE_GET_REQUEST               WebsocketMessage_MessageType = 30
+	WebsocketMessage_FILE_GET_RESPONSE              WebsocketMessage_MessageType = 31
+	WebsocketMessage_DIR_LIST_REQUEST               WebsocketMessage_MessageType = 32
+	WebsocketMessage_DIR_LIST_RESPONSE              WebsocketMessage_MessageType = 33
+	WebsocketMessage_LOG_TAIL_REQUEST               WebsocketMessage_MessageType = 34
+	WebsocketMessage_LOG_TAIL_STOP                  WebsocketMessage_MessageType = 35
+	WebsocketMessage_LOG_TAIL_DATA                  WebsocketMessage_MessageType = 36
+	WebsocketMessage_LOG_TAIL_END                   WebsocketMessage_MessageType = 37
+	WebsocketMessage_BATCH_CHUNK                    WebsocketMessage_MessageType = 38
+	WebsocketMessage_RESYNC_REQUEST                 WebsocketMessage_MessageType = 39
+	WebsocketMessage_ENV_ROLLBACK                   WebsocketMessage_MessageType = 40 // Carried in env_rollback_request
+	WebsocketMessage_ENV_ROLLBACK_RESPONSE          WebsocketMessage_MessageType = 41
+	WebsocketMessage_ACTIVATE                       WebsocketMessage_MessageType = 42 // Carried in activate_request
+)
+
+// Enum value maps for WebsocketMessage_MessageType.
+var (
+	WebsocketMessage_MessageType_name = map[int32]string{
+		0:  "UNKNOWN",
+		1:  "PUSH_REQUEST",
+		2:  "PUSH_RESPONSE",
+		3:  "VERIFICATION_PROGRESS",
+		4:  "VERIFICATION_PROGRESS_RESPONSE",
+		5:  "AUTH_REQUEST",
+		6:  "AUTH_RESPONSE",
+		7:  "STATUS_REPORT",
+		8:  "LOG_ENTRY",
+		9:  "SHELL_OPEN",
+		10: "SHELL_STDIN",
+		11: "SHELL_STDOUT",
+		12: "SHELL_RESIZE",
+		13: "SHELL_CLOSE",
+		14: "SHELL_EXIT",
+		15: "PUSH_CANCEL",
+		16: "PUSH_PROGRESS",
+		17: "SIDECAR_LOG",
+		18: "HELLO",
+		19: "SNAPSHOT_CREATE",
+		20: "SNAPSHOT_RESTORE",
+		21: "SNAPSHOT_RESPONSE",
+		22: "MANIFEST_REQUEST",
+		23: "MANIFEST_RESPONSE",
+		24: "LAUNCHER_EXITED",
+		25: "HELLO_ACK",
+		26: "DIAGNOSTICS_REQUEST",
+		27: "DIAGNOSTICS_CHUNK",
+		28: "SYNC_STATUS_REQUEST",
+		29: "SYNC_STATUS_RESPONSE",
+		30: "FILE_GET_REQUEST",
+		31: "FILE_GET_RESPONSE",
+		32: "DIR_LIST_REQUEST",
+		33: "DIR_LIST_RESPONSE",
+		34: "LOG_TAIL_REQUEST",
+		35: "LOG_TAIL_STOP",
+		36: "LOG_TAIL_DATA",
+		37: "LOG_TAIL_END",
+		38: "BATCH_CHUNK",
+		39: "RESYNC_REQUEST",
+		40: "ENV_ROLLBACK",
+		41: "ENV_ROLLBACK_RESPONSE",
+		42: "ACTIVATE",
+	}
+	WebsocketMessage_MessageType_value = map[string]int32{
+		"UNKNOWN":                        0,
+		"PUSH_REQUEST":                   1,
+		"PUSH_RESPONSE":                  2,
+		"VERIFICATION_PROGRESS":          3,
+		"VERIFICATION_PROGRESS_RESPONSE": 4,
+		"AUTH_REQUEST":                   5,
+		"AUTH_RESPONSE":                  6,
+		"STATUS_REPORT":                  7,
+		"LOG_ENTRY":                      8,
+		"SHELL_OPEN":                     9,
+		"SHELL_STDIN":                    10,
+		"SHELL_STDOUT":                   11,
+		"SHELL_RESIZE":                   12,
+		"SHELL_CLOSE":                    13,
+		"SHELL_EXIT":                     14,
+		"PUSH_CANCEL":                    15,
+		"PUSH_PROGRESS":                  16,
+		"SIDECAR_LOG":                    17,
+		"HELLO":                          18,
+		"SNAPSHOT_CREATE":                19,
+		"SNAPSHOT_RESTORE":               20,
+		"SNAPSHOT_RESPONSE":              21,
+		"MANIFEST_REQUEST":               22,
+		"MANIFEST_RESPONSE":              23,
+		"LAUNCHER_EXITED":                24,
+		"HELLO_ACK":                      25,
+		"DIAGNOSTICS_REQUEST":            26,
+		"DIAGNOSTICS_CHUNK":              27,
+		"SYNC_STATUS_REQUEST":            28,
+		"SYNC_STATUS_RESPONSE":           29,
+		"FILE_GET_REQUEST":               30,
+		"FILE_GET_RESPONSE":              31,
+		"DIR_LIST_REQUEST":               32,
+		"DIR_LIST_RESPONSE":              33,
+		"LOG_TAIL_REQUEST":               34,
+		"LOG_TAIL_STOP":                  35,
+		"LOG_TAIL_DATA":                  36,
+		"LOG_TAIL_END":                   37,
+		"BATCH_CHUNK":                    38,
+		"RESYNC_REQUEST":                 39,
+		"ENV_ROLLBACK":                   40,
+		"ENV_ROLLBACK_RESPONSE":          41,
+		"ACTIVATE":                       42,
+	}
+)
+
+func (x WebsocketMessage_MessageType) Enum() *WebsocketMessage_MessageType {
+	p := new(WebsocketMessage_MessageType)
+	*p = x
+	return p
+}
+
+func (x WebsocketMessage_MessageType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WebsocketMessage_MessageType) Descriptor() protoreflect.EnumDescriptor {
+	return file_ws_proto_enumTypes[17].Descriptor()
+}
+
+func (WebsocketMessage_MessageType) Type() protoreflect.EnumType {
+	return &file_ws_proto_enumTypes[17]
+}
+
+func (x WebsocketMessage_MessageType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WebsocketMessage_MessageType.Descriptor instead.
+func (WebsocketMessage_MessageType) EnumDescriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{75, 0}
+}
+
+type DatabaseBranchUpdate struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseName     string                 `protobuf:"bytes,1,opt,name=database_name,json=databaseName,proto3" json:"database_name,omitempty"`
+	PreviousBranchId string                 `protobuf:"bytes,2,opt,name=previous_branch_id,json=previousBranchId,proto3" json:"previous_branch_id,omitempty"`
+	NewBranchId      string                 `protobuf:"bytes,3,opt,name=new_branch_id,json=newBranchId,proto3" json:"new_branch_id,omitempty"`
+	BranchCreated    bool                   `protobuf:"varint,4,opt,name=branch_created,json=branchCreated,proto3" json:"branch_created,omitempty"`     // True if new_branch was created
+	ParentBranchId   string                 `protobuf:"bytes,5,opt,name=parent_branch_id,json=parentBranchId,proto3" json:"parent_branch_id,omitempty"` // Set when branch_created=true
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DatabaseBranchUpdate) Reset() {
+	*x = DatabaseBranchUpdate{}
+	mi := &file_ws_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseBranchUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseBranchUpdate) ProtoMessage() {}
+
+func (x *DatabaseBranchUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseBranchUpdate.ProtoReflect.Descriptor instead.
+func (*DatabaseBranchUpdate) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DatabaseBranchUpdate) GetDatabaseName() string {
+	if x != nil {
+		return x.DatabaseName
+	}
+	return ""
+}
+
+func (x *DatabaseBranchUpdate) GetPreviousBranchId() string {
+	if x != nil {
+		return x.PreviousBranchId
+	}
+	return ""
+}
+
+func (x *DatabaseBranchUpdate) GetNewBranchId() string {
+	if x != nil {
+		return x.NewBranchId
+	}
+	return ""
+}
+
+func (x *DatabaseBranchUpdate) GetBranchCreated() bool {
+	if x != nil {
+		return x.BranchCreated
+	}
+	return false
+}
+
+func (x *DatabaseBranchUpdate) GetParentBranchId() string {
+	if x != nil {
+		return x.ParentBranchId
+	}
+	return ""
+}
+
+type PushMessage struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PushId            string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	BatchFile         []byte                 `protobuf:"bytes,2,opt,name=batch_file,json=batchFile,proto3" json:"batch_file,omitempty"`
+	CodeDiff          string                 `protobuf:"bytes,3,opt,name=code_diff,json=codeDiff,proto3" json:"code_diff,omitempty"`
+	ChangeDescription string                 `protobuf:"bytes,4,opt,name=change_description,json=changeDescription,proto3" json:"change_description,omitempty"`
+	// Diff metadata fields (merged from DiffMetadata)
+	FilesChanged int32 `protobuf:"varint,5,opt,name=files_changed,json=filesChanged,proto3" json:"files_changed,omitempty"`
+	Additions    int32 `protobuf:"varint,6,opt,name=additions,proto3" json:"additions,omitempty"`
+	Deletions    int32 `protobuf:"varint,7,opt,name=deletions,proto3" json:"deletions,omitempty"`
+	// New field for branch updates
+	DatabaseBranchUpdates []*DatabaseBranchUpdate `protobuf:"bytes,8,rep,name=database_branch_updates,json=databaseBranchUpdates,proto3" json:"database_branch_updates,omitempty"`
+	Force                 bool                    `protobuf:"varint,9,opt,name=force,proto3" json:"force,omitempty"` // Apply even if files the batch touches were modified in the deployment
+	// Extra rsync flags for this push, e.g. "--checksum". Only flags the sidecar
+	// allows are accepted; anything else rejects the push.
+	RsyncFlags []string `protobuf:"bytes,10,rep,name=rsync_flags,json=rsyncFlags,proto3" json:"rsync_flags,omitempty"`
+	// Small files written directly by the sidecar after the batch is applied,
+	// keyed by path relative to the files directory.
+	Files map[string]*InjectedFile `protobuf:"bytes,11,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Paths to remove, relative to the files directory, for deletions that an
+	// rsync batch written without --delete can't carry. Removed after the batch
+	// is applied and before files are injected.
+	DeletedPaths       []string `protobuf:"bytes,12,rep,name=deleted_paths,json=deletedPaths,proto3" json:"deleted_paths,omitempty"`
+	DeletedPathsDryRun bool     `protobuf:"varint,13,opt,name=deleted_paths_dry_run,json=deletedPathsDryRun,proto3" json:"deleted_paths_dry_run,omitempty"` // Only report what deleted_paths would remove
+	// Env vars the app needs set and non-empty. The push fails with MISSING_ENV,
+	// before the app is signalled, if any isn't in the app's env.
+	RequiredEnv []string `protobuf:"bytes,14,rep,name=required_env,json=requiredEnv,proto3" json:"required_env,omitempty"`
+	// When set, batch_file is empty and the batch, this many bytes, follows in
+	// BATCH_CHUNK messages, so control messages aren't held up behind it.
+	StreamedBatchSize int64 `protobuf:"varint,15,opt,name=streamed_batch_size,json=streamedBatchSize,proto3" json:"streamed_batch_size,omitempty"`
+	// Who triggered the push, e.g. a user's email, recorded in the sidecar's audit log.
+	TriggeredBy string `protobuf:"bytes,16,opt,name=triggered_by,json=triggeredBy,proto3" json:"triggered_by,omitempty"`
+	// Make the deployment mirror the batch's source: files the source doesn't
+	// have are removed, as with rsync --delete. The sidecar's protected paths are
+	// never removed, and unless force is set the push fails with
+	// TOO_MANY_DELETIONS if it would remove more than the configured share of files.
+	Mirror bool `protobuf:"varint,17,opt,name=mirror,proto3" json:"mirror,omitempty"`
+	// Glob patterns, relative to the files directory, for the paths the batch
+	// is limited to, e.g. "src/api/**". When set, the sidecar checks the batch
+	// with an rsync dry run and rejects it with OUT_OF_SCOPE if it would change
+	// anything else, even with force. Empty means the batch may change any path.
+	Paths []string `protobuf:"bytes,18,rep,name=paths,proto3" json:"paths,omitempty"`
+	// "sha256:<hex>" of the batch. With batch_file set, the sidecar checks the
+	// batch against it. Without batch_file or streamed_batch_size, the sidecar
+	// applies the batch with this hash from its cache of received batches (see
+	// Hello.cached_batches), or answers BATCH_NOT_CACHED.
+	BatchHash string `protobuf:"bytes,19,opt,name=batch_hash,json=batchHash,proto3" json:"batch_hash,omitempty"`
+	// Hold the push until this time. The sidecar reports PENDING, with
+	// held_until, while it holds a push, and PUSH_CANCEL still cancels it.
+	NotBefore *timestamppb.Timestamp `protobuf:"bytes,20,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	// Apply the push even outside the sidecar's push windows, e.g. for a hotfix.
+	BypassPushWindows bool `protobuf:"varint,21,opt,name=bypass_push_windows,json=bypassPushWindows,proto3" json:"bypass_push_windows,omitempty"`
+	// Reload the launchers in stages, e.g. a worker before the web server: the
+	// sidecar signals each stage's launchers, waits for the health probe to
+	// pass, then goes on to the next. Launchers no stage names are reloaded
+	// last. Needs the signal reload strategy and a health probe.
+	ActivationPlan []*ActivationStage `protobuf:"bytes,22,rep,name=activation_plan,json=activationPlan,proto3" json:"activation_plan,omitempty"`
+	// The push's place among the deployment's pushes, going up by one with
+	// each, so a push delayed past a newer one can't overwrite the newer files.
+	// The sidecar rejects a push older than one it already received with
+	// OUT_OF_ORDER, and holds one that arrives ahead of a gap until the missing
+	// pushes arrive or its gap timeout passes; see sequence_gap. 0 leaves the
+	// push unsequenced, applied in arrival order.
+	Sequence int64 `protobuf:"varint,23,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Batches for separate sub-trees of the files directory, such as the
+	// packages of a monorepo, applied at once by a pool of rsyncs instead of one
+	// rsync walking the whole tree. Each holds its full sub-tree, so a later
+	// push's batch_file supersedes this push like any other. The sub-trees must
+	// not overlap, and a push has either subtree_batches or a batch_file, not
+	// both. If any sub-tree fails, every sub-tree is rolled back; see
+	// PushResponse.subtree_results.
+	SubtreeBatches []*SubtreeBatch `protobuf:"bytes,24,rep,name=subtree_batches,json=subtreeBatches,proto3" json:"subtree_batches,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PushMessage) Reset() {
+	*x = PushMessage{}
+	mi := &file_ws_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushMessage) ProtoMessage() {}
+
+func (x *PushMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushMessage.ProtoReflect.Descriptor instead.
+func (*PushMessage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PushMessage) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetBatchFile() []byte {
+	if x != nil {
+		return x.BatchFile
+	}
+	return nil
+}
+
+func (x *PushMessage) GetCodeDiff() string {
+	if x != nil {
+		return x.CodeDiff
+	}
+	return ""
+}
+
+func (x *PushMessage) GetChangeDescription() string {
+	if x != nil {
+		return x.ChangeDescription
+	}
+	return ""
+}
+
+func (x *PushMessage) GetFilesChanged() int32 {
+	if x != nil {
+		return x.FilesChanged
+	}
+	return 0
+}
+
+func (x *PushMessage) GetAdditions() int32 {
+	if x != nil {
+		return x.Additions
+	}
+	return 0
+}
+
+func (x *PushMessage) GetDeletions() int32 {
+	if x != nil {
+		return x.Deletions
+	}
+	return 0
+}
+
+func (x *PushMessage) GetDatabaseBranchUpdates() []*DatabaseBranchUpdate {
+	if x != nil {
+		return x.DatabaseBranchUpdates
+	}
+	return nil
+}
+
+func (x *PushMessage) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+func (x *PushMessage) GetRsyncFlags() []string {
+	if x != nil {
+		return x.RsyncFlags
+	}
+	return nil
+}
+
+func (x *PushMessage) GetFiles() map[string]*InjectedFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *PushMessage) GetDeletedPaths() []string {
+	if x != nil {
+		return x.DeletedPaths
+	}
+	return nil
+}
+
+func (x *PushMessage) GetDeletedPathsDryRun() bool {
+	if x != nil {
+		return x.DeletedPathsDryRun
+	}
+	return false
+}
+
+func (x *PushMessage) GetRequiredEnv() []string {
+	if x != nil {
+		return x.RequiredEnv
+	}
+	return nil
+}
+
+func (x *PushMessage) GetStreamedBatchSize() int64 {
+	if x != nil {
+		return x.StreamedBatchSize
+	}
+	return 0
+}
+
+func (x *PushMessage) GetTriggeredBy() string {
+	if x != nil {
+		return x.TriggeredBy
+	}
+	return ""
+}
+
+func (x *PushMessage) GetMirror() bool {
+	if x != nil {
+		return x.Mirror
+	}
+	return false
+}
+
+func (x *PushMessage) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *PushMessage) GetBatchHash() string {
+	if x != nil {
+		return x.BatchHash
+	}
+	return ""
+}
+
+func (x *PushMessage) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *PushMessage) GetBypassPushWindows() bool {
+	if x != nil {
+		return x.BypassPushWindows
+	}
+	return false
+}
+
+func (x *PushMessage) GetActivationPlan() []*ActivationStage {
+	if x != nil {
+		return x.ActivationPlan
+	}
+	return nil
+}
+
+func (x *PushMessage) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *PushMessage) GetSubtreeBatches() []*SubtreeBatch {
+	if x != nil {
+		return x.SubtreeBatches
+	}
+	return nil
+}
+
+// An rsync batch for one sub-tree of the files directory.
+type SubtreeBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // Relative to the files directory, e.g. "packages/api"
+	BatchFile     []byte                 `protobuf:"bytes,2,opt,name=batch_file,json=batchFile,proto3" json:"batch_file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubtreeBatch) Reset() {
+	*x = SubtreeBatch{}
+	mi := &file_ws_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubtreeBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubtreeBatch) ProtoMessage() {}
+
+func (x *SubtreeBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubtreeBatch.ProtoReflect.Descriptor instead.
+func (*SubtreeBatch) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubtreeBatch) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SubtreeBatch) GetBatchFile() []byte {
+	if x != nil {
+		return x.BatchFile
+	}
+	return nil
+}
+
+type ActivationStage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Launchers     []string               `protobuf:"bytes,1,rep,name=launchers,proto3" json:"launchers,omitempty"` // Launcher names; the main launcher is "launcher"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivationStage) Reset() {
+	*x = ActivationStage{}
+	mi := &file_ws_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivationStage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivationStage) ProtoMessage() {}
+
+func (x *ActivationStage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivationStage.ProtoReflect.Descriptor instead.
+func (*ActivationStage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ActivationStage) GetLaunchers() []string {
+	if x != nil {
+		return x.Launchers
+	}
+	return nil
+}
+
+// A file the control plane places in the deployment without going through rsync.
+type InjectedFile struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Content []byte                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Mode    uint32                 `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"` // Permission bits, e.g. 0644; 0 means 0644
+	// Render content as a Go template against the deployment's env vars and the
+	// push before writing it, e.g. {{ .Env.DATABASE_URL }}.
+	Template      bool `protobuf:"varint,3,opt,name=template,proto3" json:"template,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InjectedFile) Reset() {
+	*x = InjectedFile{}
+	mi := &file_ws_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InjectedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InjectedFile) ProtoMessage() {}
+
+func (x *InjectedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InjectedFile.ProtoReflect.Descriptor instead.
+func (*InjectedFile) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *InjectedFile) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *InjectedFile) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *InjectedFile) GetTemplate() bool {
+	if x != nil {
+		return x.Template
+	}
+	return false
+}
+
+type InjectedFileResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InjectedFileResult) Reset() {
+	*x = InjectedFileResult{}
+	mi := &file_ws_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InjectedFileResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InjectedFileResult) ProtoMessage() {}
+
+func (x *InjectedFileResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InjectedFileResult.ProtoReflect.Descriptor instead.
+func (*InjectedFileResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InjectedFileResult) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *InjectedFileResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *InjectedFileResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type DeletedPathResult struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Path          string                   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Status        DeletedPathResult_Status `protobuf:"varint,2,opt,name=status,proto3,enum=DeletedPathResult_Status" json:"status,omitempty"`
+	ErrorMessage  string                   `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedPathResult) Reset() {
+	*x = DeletedPathResult{}
+	mi := &file_ws_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedPathResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedPathResult) ProtoMessage() {}
+
+func (x *DeletedPathResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedPathResult.ProtoReflect.Descriptor instead.
+func (*DeletedPathResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeletedPathResult) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *DeletedPathResult) GetStatus() DeletedPathResult_Status {
+	if x != nil {
+		return x.Status
+	}
+	return DeletedPathResult_UNKNOWN
+}
+
+func (x *DeletedPathResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type HookResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // e.g. "pre-sync", "post-sync"
+	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Output        string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"` // Combined stdout/stderr, truncated
+	DurationMs    int64                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HookResult) Reset() {
+	*x = HookResult{}
+	mi := &file_ws_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HookResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HookResult) ProtoMessage() {}
+
+func (x *HookResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HookResult.ProtoReflect.Descriptor instead.
+func (*HookResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HookResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *HookResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *HookResult) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *HookResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+type PushResponse struct {
+	state            protoimpl.MessageState  `protogen:"open.v1"`
+	Status           PushResponse_PushStatus `protobuf:"varint,1,opt,name=status,proto3,enum=PushResponse_PushStatus" json:"status,omitempty"`
+	ErrorMessage     string                  `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	PushId           string                  `protobuf:"bytes,3,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	HookResults      []*HookResult           `protobuf:"bytes,4,rep,name=hook_results,json=hookResults,proto3" json:"hook_results,omitempty"`
+	AlreadyApplied   bool                    `protobuf:"varint,5,opt,name=already_applied,json=alreadyApplied,proto3" json:"already_applied,omitempty"` // Duplicate of a push the sidecar had already applied
+	ConflictingFiles []string                `protobuf:"bytes,6,rep,name=conflicting_files,json=conflictingFiles,proto3" json:"conflicting_files,omitempty"`
+	// Paths the batch created, modified and deleted, relative to the files directory.
+	// Sent with COMPLETED and RELOAD_FAILED; each list holds at most 1000 paths.
+	CreatedFiles         []string              `protobuf:"bytes,7,rep,name=created_files,json=createdFiles,proto3" json:"created_files,omitempty"`
+	ModifiedFiles        []string              `protobuf:"bytes,8,rep,name=modified_files,json=modifiedFiles,proto3" json:"modified_files,omitempty"`
+	DeletedFiles         []string              `protobuf:"bytes,9,rep,name=deleted_files,json=deletedFiles,proto3" json:"deleted_files,omitempty"`
+	FileChangesTruncated bool                  `protobuf:"varint,10,opt,name=file_changes_truncated,json=fileChangesTruncated,proto3" json:"file_changes_truncated,omitempty"` // Some lists were cut off at the limit
+	Replayed             bool                  `protobuf:"varint,11,opt,name=replayed,proto3" json:"replayed,omitempty"`                                                       // Resent after a reconnect because the original may have been lost
+	SupersededBy         string                `protobuf:"bytes,12,opt,name=superseded_by,json=supersededBy,proto3" json:"superseded_by,omitempty"`                            // With SUPERSEDED, the push that was applied instead
+	InjectedFiles        []*InjectedFileResult `protobuf:"bytes,13,rep,name=injected_files,json=injectedFiles,proto3" json:"injected_files,omitempty"`                         // One per entry in the push's files, sorted by path
+	DeletedPaths         []*DeletedPathResult  `protobuf:"bytes,14,rep,name=deleted_paths,json=deletedPaths,proto3" json:"deleted_paths,omitempty"`                            // One per entry in the push's deleted_paths
+	Pod                  *PodMetadata          `protobuf:"bytes,15,opt,name=pod,proto3" json:"pod,omitempty"`                                                                  // Where the sidecar runs; unset outside Kubernetes
+	// With coordination enabled, the final result on each replica, the leader's first.
+	ReplicaResults []*ReplicaResult `protobuf:"bytes,16,rep,name=replica_results,json=replicaResults,proto3" json:"replica_results,omitempty"`
+	Timing         *PushTiming      `protobuf:"bytes,17,opt,name=timing,proto3" json:"timing,omitempty"`                           // Sent with the final response of a push the sidecar started applying
+	NoOp           bool             `protobuf:"varint,18,opt,name=no_op,json=noOp,proto3" json:"no_op,omitempty"`                  // COMPLETED without applying: the batch matched the last applied one
+	MissingEnv     []string         `protobuf:"bytes,19,rep,name=missing_env,json=missingEnv,proto3" json:"missing_env,omitempty"` // With MISSING_ENV, the required env vars that are missing or empty
+	// How many times rsync and the reload signal were tried, including retries
+	// after transient failures; 0 if the push didn't get that far.
+	RsyncAttempts  int32 `protobuf:"varint,20,opt,name=rsync_attempts,json=rsyncAttempts,proto3" json:"rsync_attempts,omitempty"`
+	SignalAttempts int32 `protobuf:"varint,21,opt,name=signal_attempts,json=signalAttempts,proto3" json:"signal_attempts,omitempty"`
+	// With TOO_MANY_DELETIONS, the paths the push would have deleted; at most 1000.
+	MirrorDeletions []string `protobuf:"bytes,22,rep,name=mirror_deletions,json=mirrorDeletions,proto3" json:"mirror_deletions,omitempty"`
+	// The workspace's size once the push's files were applied. Sent with
+	// COMPLETED, RELOAD_FAILED and QUOTA_EXCEEDED.
+	WorkspaceUsage *WorkspaceUsage `protobuf:"bytes,23,opt,name=workspace_usage,json=workspaceUsage,proto3" json:"workspace_usage,omitempty"`
+	// With OUT_OF_SCOPE, the paths the batch would have changed outside the
+	// push's paths; at most 1000.
+	OutOfScopePaths []string `protobuf:"bytes,24,rep,name=out_of_scope_paths,json=outOfScopePaths,proto3" json:"out_of_scope_paths,omitempty"`
+	// With the signal reload strategy, how signalling each launcher went, the
+	// main launcher's first.
+	LauncherResults []*LauncherSignalResult `protobuf:"bytes,25,rep,name=launcher_results,json=launcherResults,proto3" json:"launcher_results,omitempty"`
+	// With PENDING: when the held push will be applied, at its not_before time,
+	// the next opening of the sidecar's push windows or, behind a sequence gap,
+	// when the sidecar stops waiting for the missing pushes.
+	HeldUntil *timestamppb.Timestamp `protobuf:"bytes,26,opt,name=held_until,json=heldUntil,proto3" json:"held_until,omitempty"`
+	// With a push that waited for approval, who approved it, from its ACTIVATE.
+	ApprovedBy string `protobuf:"bytes,27,opt,name=approved_by,json=approvedBy,proto3" json:"approved_by,omitempty"`
+	// With ROLLED_BACK after a stage of the push's activation_plan wasn't
+	// healthy, that stage, from 1.
+	FailedActivationStage int32 `protobuf:"varint,28,opt,name=failed_activation_stage,json=failedActivationStage,proto3" json:"failed_activation_stage,omitempty"`
+	// With OUT_OF_ORDER, the latest sequence the sidecar received.
+	LastSequence int64 `protobuf:"varint,29,opt,name=last_sequence,json=lastSequence,proto3" json:"last_sequence,omitempty"`
+	// The sequences the sidecar gave up waiting for before applying this push.
+	// Pushes with them that arrive later are rejected as OUT_OF_ORDER.
+	SequenceGap *SequenceGap `protobuf:"bytes,30,opt,name=sequence_gap,json=sequenceGap,proto3" json:"sequence_gap,omitempty"`
+	// How each of the push's subtree_batches went, in the order they were sent.
+	SubtreeResults []*SubtreeResult `protobuf:"bytes,31,rep,name=subtree_results,json=subtreeResults,proto3" json:"subtree_results,omitempty"`
+	// Changes to files the node's security module (SELinux or AppArmor)
+	// refused, which failed the push; capped at 1000.
+	SecurityDenials []*SecurityDenial `protobuf:"bytes,32,rep,name=security_denials,json=securityDenials,proto3" json:"security_denials,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PushResponse) Reset() {
+	*x = PushResponse{}
+	mi := &file_ws_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushResponse) ProtoMessage() {}
+
+func (x *PushResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushResponse.ProtoReflect.Descriptor instead.
+func (*PushResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PushResponse) GetStatus() PushResponse_PushStatus {
+	if x != nil {
+		return x.Status
+	}
+	return PushResponse_UNKNOWN
+}
+
+func (x *PushResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *PushResponse) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *PushResponse) GetHookResults() []*HookResult {
+	if x != nil {
+		return x.HookResults
+	}
+	return nil
+}
+
+func (x *PushResponse) GetAlreadyApplied() bool {
+	if x != nil {
+		return x.AlreadyApplied
+	}
+	return false
+}
+
+func (x *PushResponse) GetConflictingFiles() []string {
+	if x != nil {
+		return x.ConflictingFiles
+	}
+	return nil
+}
+
+func (x *PushResponse) GetCreatedFiles() []string {
+	if x != nil {
+		return x.CreatedFiles
+	}
+	return nil
+}
+
+func (x *PushResponse) GetModifiedFiles() []string {
+	if x != nil {
+		return x.ModifiedFiles
+	}
+	return nil
+}
+
+func (x *PushResponse) GetDeletedFiles() []string {
+	if x != nil {
+		return x.DeletedFiles
+	}
+	return nil
+}
+
+func (x *PushResponse) GetFileChangesTruncated() bool {
+	if x != nil {
+		return x.FileChangesTruncated
+	}
+	return false
+}
+
+func (x *PushResponse) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+func (x *PushResponse) GetSupersededBy() string {
+	if x != nil {
+		return x.SupersededBy
+	}
+	return ""
+}
+
+func (x *PushResponse) GetInjectedFiles() []*InjectedFileResult {
+	if x != nil {
+		return x.InjectedFiles
+	}
+	return nil
+}
+
+func (x *PushResponse) GetDeletedPaths() []*DeletedPathResult {
+	if x != nil {
+		return x.DeletedPaths
+	}
+	return nil
+}
+
+func (x *PushResponse) GetPod() *PodMetadata {
+	if x != nil {
+		return x.Pod
+	}
+	return nil
+}
+
+func (x *PushResponse) GetReplicaResults() []*ReplicaResult {
+	if x != nil {
+		return x.ReplicaResults
+	}
+	return nil
+}
+
+func (x *PushResponse) GetTiming() *PushTiming {
+	if x != nil {
+		return x.Timing
+	}
+	return nil
+}
+
+func (x *PushResponse) GetNoOp() bool {
+	if x != nil {
+		return x.NoOp
+	}
+	return false
+}
+
+func (x *PushResponse) GetMissingEnv() []string {
+	if x != nil {
+		return x.MissingEnv
+	}
+	return nil
+}
+
+func (x *PushResponse) GetRsyncAttempts() int32 {
+	if x != nil {
+		return x.RsyncAttempts
+	}
+	return 0
+}
+
+func (x *PushResponse) GetSignalAttempts() int32 {
+	if x != nil {
+		return x.SignalAttempts
+	}
+	return 0
+}
+
+func (x *PushResponse) GetMirrorDeletions() []string {
+	if x != nil {
+		return x.MirrorDeletions
+	}
+	return nil
+}
+
+func (x *PushResponse) GetWorkspaceUsage() *WorkspaceUsage {
+	if x != nil {
+		return x.WorkspaceUsage
+	}
+	return nil
+}
+
+func (x *PushResponse) GetOutOfScopePaths() []string {
+	if x != nil {
+		return x.OutOfScopePaths
+	}
+	return nil
+}
+
+func (x *PushResponse) GetLauncherResults() []*LauncherSignalResult {
+	if x != nil {
+		return x.LauncherResults
+	}
+	return nil
+}
+
+func (x *PushResponse) GetHeldUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.HeldUntil
+	}
+	return nil
+}
+
+func (x *PushResponse) GetApprovedBy() string {
+	if x != nil {
+		return x.ApprovedBy
+	}
+	return ""
+}
+
+func (x *PushResponse) GetFailedActivationStage() int32 {
+	if x != nil {
+		return x.FailedActivationStage
+	}
+	return 0
+}
+
+func (x *PushResponse) GetLastSequence() int64 {
+	if x != nil {
+		return x.LastSequence
+	}
+	return 0
+}
+
+func (x *PushResponse) GetSequenceGap() *SequenceGap {
+	if x != nil {
+		return x.SequenceGap
+	}
+	return nil
+}
+
+func (x *PushResponse) GetSubtreeResults() []*SubtreeResult {
+	if x != nil {
+		return x.SubtreeResults
+	}
+	return nil
+}
+
+func (x *PushResponse) GetSecurityDenials() []*SecurityDenial {
+	if x != nil {
+		return x.SecurityDenials
+	}
+	return nil
+}
+
+// A change to a file that the security module confining the sidecar refused.
+type SecurityDenial struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`           // Relative to the files directory
+	Operation     string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"` // "write", "chown", "chmod" or "relabel"
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecurityDenial) Reset() {
+	*x = SecurityDenial{}
+	mi := &file_ws_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecurityDenial) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecurityDenial) ProtoMessage() {}
+
+func (x *SecurityDenial) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecurityDenial.ProtoReflect.Descriptor instead.
+func (*SecurityDenial) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SecurityDenial) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SecurityDenial) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *SecurityDenial) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// The Linux security module confining the sidecar, if any.
+type SecurityModule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`   // "selinux" or "apparmor"
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`   // "enforcing" or "permissive" for SELinux, "enforce", "complain" or "unconfined" for AppArmor
+	Label         string                 `protobuf:"bytes,3,opt,name=label,proto3" json:"label,omitempty"` // The sidecar's SELinux context or AppArmor profile
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecurityModule) Reset() {
+	*x = SecurityModule{}
+	mi := &file_ws_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecurityModule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecurityModule) ProtoMessage() {}
+
+func (x *SecurityModule) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecurityModule.ProtoReflect.Descriptor instead.
+func (*SecurityModule) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SecurityModule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SecurityModule) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *SecurityModule) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type SubtreeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // Why rsync failed, or that another sub-tree's failure stopped it
+	FilesChanged  int32                  `protobuf:"varint,4,opt,name=files_changed,json=filesChanged,proto3" json:"files_changed,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,5,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubtreeResult) Reset() {
+	*x = SubtreeResult{}
+	mi := &file_ws_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubtreeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubtreeResult) ProtoMessage() {}
+
+func (x *SubtreeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubtreeResult.ProtoReflect.Descriptor instead.
+func (*SubtreeResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SubtreeResult) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SubtreeResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SubtreeResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *SubtreeResult) GetFilesChanged() int32 {
+	if x != nil {
+		return x.FilesChanged
+	}
+	return 0
+}
+
+func (x *SubtreeResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+// An inclusive range of push sequences that never arrived.
+type SequenceGap struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	First         int64                  `protobuf:"varint,1,opt,name=first,proto3" json:"first,omitempty"`
+	Last          int64                  `protobuf:"varint,2,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SequenceGap) Reset() {
+	*x = SequenceGap{}
+	mi := &file_ws_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SequenceGap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceGap) ProtoMessage() {}
+
+func (x *SequenceGap) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceGap.ProtoReflect.Descriptor instead.
+func (*SequenceGap) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SequenceGap) GetFirst() int64 {
+	if x != nil {
+		return x.First
+	}
+	return 0
+}
+
+func (x *SequenceGap) GetLast() int64 {
+	if x != nil {
+		return x.Last
+	}
+	return 0
+}
+
+// The reload signal sent to one of the launchers of a deployment that runs
+// several, such as a web server and a worker.
+type LauncherSignalResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // "launcher" for the main launcher, otherwise its BIFROST_LAUNCHER_NAME
+	Pid           int32                  `protobuf:"varint,2,opt,name=pid,proto3" json:"pid,omitempty"`  // 0 if the launcher's PID file couldn't be read
+	Signal        string                 `protobuf:"bytes,3,opt,name=signal,proto3" json:"signal,omitempty"`
+	Signalled     bool                   `protobuf:"varint,4,opt,name=signalled,proto3" json:"signalled,omitempty"`
+	NotRunning    bool                   `protobuf:"varint,5,opt,name=not_running,json=notRunning,proto3" json:"not_running,omitempty"` // The launcher's process is gone
+	ErrorMessage  string                 `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Attempts      int32                  `protobuf:"varint,7,opt,name=attempts,proto3" json:"attempts,omitempty"` // Including retries after transient failures
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LauncherSignalResult) Reset() {
+	*x = LauncherSignalResult{}
+	mi := &file_ws_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LauncherSignalResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LauncherSignalResult) ProtoMessage() {}
+
+func (x *LauncherSignalResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LauncherSignalResult.ProtoReflect.Descriptor instead.
+func (*LauncherSignalResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LauncherSignalResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LauncherSignalResult) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *LauncherSignalResult) GetSignal() string {
+	if x != nil {
+		return x.Signal
+	}
+	return ""
+}
+
+func (x *LauncherSignalResult) GetSignalled() bool {
+	if x != nil {
+		return x.Signalled
+	}
+	return false
+}
+
+func (x *LauncherSignalResult) GetNotRunning() bool {
+	if x != nil {
+		return x.NotRunning
+	}
+	return false
+}
+
+func (x *LauncherSignalResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *LauncherSignalResult) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+// Size of the synced files, excluding sidecar and launcher internals, against
+// the sidecar's quotas. A limit of 0 isn't enforced.
+type WorkspaceUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bytes         int64                  `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"`   // Size of regular files
+	Inodes        int64                  `protobuf:"varint,2,opt,name=inodes,proto3" json:"inodes,omitempty"` // Files, directories and symlinks
+	SoftBytes     int64                  `protobuf:"varint,3,opt,name=soft_bytes,json=softBytes,proto3" json:"soft_bytes,omitempty"`
+	HardBytes     int64                  `protobuf:"varint,4,opt,name=hard_bytes,json=hardBytes,proto3" json:"hard_bytes,omitempty"`
+	SoftInodes    int64                  `protobuf:"varint,5,opt,name=soft_inodes,json=softInodes,proto3" json:"soft_inodes,omitempty"`
+	HardInodes    int64                  `protobuf:"varint,6,opt,name=hard_inodes,json=hardInodes,proto3" json:"hard_inodes,omitempty"`
+	Warning       string                 `protobuf:"bytes,7,opt,name=warning,proto3" json:"warning,omitempty"` // Set when a soft or hard limit is exceeded
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WorkspaceUsage) Reset() {
+	*x = WorkspaceUsage{}
+	mi := &file_ws_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkspaceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkspaceUsage) ProtoMessage() {}
+
+func (x *WorkspaceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkspaceUsage.ProtoReflect.Descriptor instead.
+func (*WorkspaceUsage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WorkspaceUsage) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetInodes() int64 {
+	if x != nil {
+		return x.Inodes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetSoftBytes() int64 {
+	if x != nil {
+		return x.SoftBytes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetHardBytes() int64 {
+	if x != nil {
+		return x.HardBytes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetSoftInodes() int64 {
+	if x != nil {
+		return x.SoftInodes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetHardInodes() int64 {
+	if x != nil {
+		return x.HardInodes
+	}
+	return 0
+}
+
+func (x *WorkspaceUsage) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+// How long the stages of a push took on the sidecar, in milliseconds, so slow
+// pushes can be attributed to the network, the disk or the app's reload.
+// Stages a push didn't go through are 0.
+type PushTiming struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	DownloadMs        int64                  `protobuf:"varint,1,opt,name=download_ms,json=downloadMs,proto3" json:"download_ms,omitempty"`                          // Receiving the push message over the websocket
+	BatchWriteMs      int64                  `protobuf:"varint,2,opt,name=batch_write_ms,json=batchWriteMs,proto3" json:"batch_write_ms,omitempty"`                  // Writing the batch to disk for rsync
+	RsyncMs           int64                  `protobuf:"varint,3,opt,name=rsync_ms,json=rsyncMs,proto3" json:"rsync_ms,omitempty"`                                   // Running rsync, including the conflict check
+	EnvWriteMs        int64                  `protobuf:"varint,4,opt,name=env_write_ms,json=envWriteMs,proto3" json:"env_write_ms,omitempty"`                        // Fetching the database env vars and writing the env file
+	SignalToHealthyMs int64                  `protobuf:"varint,5,opt,name=signal_to_healthy_ms,json=signalToHealthyMs,proto3" json:"signal_to_healthy_ms,omitempty"` // From signalling the launcher until the health probe passed
+	TotalMs           int64                  `protobuf:"varint,6,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`                                   // From starting to apply the push until its final response
+	BuildMs           int64                  `protobuf:"varint,7,opt,name=build_ms,json=buildMs,proto3" json:"build_ms,omitempty"`                                   // Running the build command
+	InstallMs         int64                  `protobuf:"varint,8,opt,name=install_ms,json=installMs,proto3" json:"install_ms,omitempty"`                             // Running the dependency install command
+	WarmMs            int64                  `protobuf:"varint,9,opt,name=warm_ms,json=warmMs,proto3" json:"warm_ms,omitempty"`                                      // Warming the page cache with the push's files
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PushTiming) Reset() {
+	*x = PushTiming{}
+	mi := &file_ws_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushTiming) ProtoMessage() {}
+
+func (x *PushTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushTiming.ProtoReflect.Descriptor instead.
+func (*PushTiming) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PushTiming) GetDownloadMs() int64 {
+	if x != nil {
+		return x.DownloadMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetBatchWriteMs() int64 {
+	if x != nil {
+		return x.BatchWriteMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetRsyncMs() int64 {
+	if x != nil {
+		return x.RsyncMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetEnvWriteMs() int64 {
+	if x != nil {
+		return x.EnvWriteMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetSignalToHealthyMs() int64 {
+	if x != nil {
+		return x.SignalToHealthyMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetTotalMs() int64 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetBuildMs() int64 {
+	if x != nil {
+		return x.BuildMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetInstallMs() int64 {
+	if x != nil {
+		return x.InstallMs
+	}
+	return 0
+}
+
+func (x *PushTiming) GetWarmMs() int64 {
+	if x != nil {
+		return x.WarmMs
+	}
+	return 0
+}
+
+// One replica's final result for a push applied across a coordinated deployment.
+type ReplicaResult struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	ReplicaId     string                  `protobuf:"bytes,1,opt,name=replica_id,json=replicaId,proto3" json:"replica_id,omitempty"` // Pod name, or the replica's peer address
+	Status        PushResponse_PushStatus `protobuf:"varint,2,opt,name=status,proto3,enum=PushResponse_PushStatus" json:"status,omitempty"`
+	ErrorMessage  string                  `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Leader        bool                    `protobuf:"varint,4,opt,name=leader,proto3" json:"leader,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicaResult) Reset() {
+	*x = ReplicaResult{}
+	mi := &file_ws_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicaResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicaResult) ProtoMessage() {}
+
+func (x *ReplicaResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicaResult.ProtoReflect.Descriptor instead.
+func (*ReplicaResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReplicaResult) GetReplicaId() string {
+	if x != nil {
+		return x.ReplicaId
+	}
+	return ""
+}
+
+func (x *ReplicaResult) GetStatus() PushResponse_PushStatus {
+	if x != nil {
+		return x.Status
+	}
+	return PushResponse_UNKNOWN
+}
+
+func (x *ReplicaResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *ReplicaResult) GetLeader() bool {
+	if x != nil {
+		return x.Leader
+	}
+	return false
+}
+
+// Reports how far the sidecar has got with a push, sent before the final PushResponse.
+type PushProgress struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	PushId     string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	Stage      PushProgress_Stage     `protobuf:"varint,2,opt,name=stage,proto3,enum=PushProgress_Stage" json:"stage,omitempty"`
+	Percent    int32                  `protobuf:"varint,3,opt,name=percent,proto3" json:"percent,omitempty"` // 0-100 within the stage
+	BytesDone  int64                  `protobuf:"varint,4,opt,name=bytes_done,json=bytesDone,proto3" json:"bytes_done,omitempty"`
+	BytesTotal int64                  `protobuf:"varint,5,opt,name=bytes_total,json=bytesTotal,proto3" json:"bytes_total,omitempty"` // 0 when unknown
+	// With BUILDING, the lines the build command wrote since the last update,
+	// stdout and stderr interleaved.
+	OutputLines   []string `protobuf:"bytes,6,rep,name=output_lines,json=outputLines,proto3" json:"output_lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushProgress) Reset() {
+	*x = PushProgress{}
+	mi := &file_ws_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushProgress) ProtoMessage() {}
+
+func (x *PushProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushProgress.ProtoReflect.Descriptor instead.
+func (*PushProgress) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PushProgress) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *PushProgress) GetStage() PushProgress_Stage {
+	if x != nil {
+		return x.Stage
+	}
+	return PushProgress_UNKNOWN
+}
+
+func (x *PushProgress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *PushProgress) GetBytesDone() int64 {
+	if x != nil {
+		return x.BytesDone
+	}
+	return 0
+}
+
+func (x *PushProgress) GetBytesTotal() int64 {
+	if x != nil {
+		return x.BytesTotal
+	}
+	return 0
+}
+
+func (x *PushProgress) GetOutputLines() []string {
+	if x != nil {
+		return x.OutputLines
+	}
+	return nil
+}
+
+// Asks the sidecar to abort a queued or running push.
+type PushCancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PushId        string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushCancel) Reset() {
+	*x = PushCancel{}
+	mi := &file_ws_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushCancel) ProtoMessage() {}
+
+func (x *PushCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushCancel.ProtoReflect.Descriptor instead.
+func (*PushCancel) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PushCancel) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+// Approves a push waiting with AWAITING_APPROVAL (ACTIVATE): the sidecar
+// activates its staged release and reloads the app.
+type ActivateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PushId        string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	ApprovedBy    string                 `protobuf:"bytes,2,opt,name=approved_by,json=approvedBy,proto3" json:"approved_by,omitempty"` // Recorded in the audit log and echoed in the push's final response
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateRequest) Reset() {
+	*x = ActivateRequest{}
+	mi := &file_ws_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateRequest) ProtoMessage() {}
+
+func (x *ActivateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateRequest.ProtoReflect.Descriptor instead.
+func (*ActivateRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ActivateRequest) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *ActivateRequest) GetApprovedBy() string {
+	if x != nil {
+		return x.ApprovedBy
+	}
+	return ""
+}
+
+type ResponseAssertion struct {
+	state         protoimpl.MessageState          `protogen:"open.v1"`
+	Type          ResponseAssertion_AssertionType `protobuf:"varint,1,opt,name=type,proto3,enum=ResponseAssertion_AssertionType" json:"type,omitempty"`
+	Expected      string                          `protobuf:"bytes,2,opt,name=expected,proto3" json:"expected,omitempty"` // JSON-encoded expected value
+	Path          *string                         `protobuf:"bytes,3,opt,name=path,proto3,oneof" json:"path,omitempty"`   // JSONPath for json_path assertions, header name for header assertions
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseAssertion) Reset() {
+	*x = ResponseAssertion{}
+	mi := &file_ws_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseAssertion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseAssertion) ProtoMessage() {}
+
+func (x *ResponseAssertion) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseAssertion.ProtoReflect.Descriptor instead.
+func (*ResponseAssertion) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ResponseAssertion) GetType() ResponseAssertion_AssertionType {
+	if x != nil {
+		return x.Type
+	}
+	return ResponseAssertion_UNKNOWN
+}
+
+func (x *ResponseAssertion) GetExpected() string {
+	if x != nil {
+		return x.Expected
+	}
+	return ""
+}
+
+func (x *ResponseAssertion) GetPath() string {
+	if x != nil && x.Path != nil {
+		return *x.Path
+	}
+	return ""
+}
+
+type VariableExtraction struct {
+	state         protoimpl.MessageState        `protogen:"open.v1"`
+	Name          string                        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Source        VariableExtraction_SourceType `protobuf:"varint,2,opt,name=source,proto3,enum=VariableExtraction_SourceType" json:"source,omitempty"`
+	Path          *string                       `protobuf:"bytes,3,opt,name=path,proto3,oneof" json:"path,omitempty"` // JSONPath for json source, header name for header source
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VariableExtraction) Reset() {
+	*x = VariableExtraction{}
+	mi := &file_ws_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VariableExtraction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariableExtraction) ProtoMessage() {}
+
+func (x *VariableExtraction) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariableExtraction.ProtoReflect.Descriptor instead.
+func (*VariableExtraction) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *VariableExtraction) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *VariableExtraction) GetSource() VariableExtraction_SourceType {
+	if x != nil {
+		return x.Source
+	}
+	return VariableExtraction_UNKNOWN
+}
+
+func (x *VariableExtraction) GetPath() string {
+	if x != nil && x.Path != nil {
+		return *x.Path
+	}
+	return ""
+}
+
+type HTTPRequestStep struct {
+	state             protoimpl.MessageState     `protogen:"open.v1"`
+	StepName          string                     `protobuf:"bytes,1,opt,name=step_name,json=stepName,proto3" json:"step_name,omitempty"`
+	Method            HTTPRequestStep_HttpMethod `protobuf:"varint,2,opt,name=method,proto3,enum=HTTPRequestStep_HttpMethod" json:"method,omitempty"`
+	Path              string                     `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Headers           map[string]string          `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body              *string                    `protobuf:"bytes,5,opt,name=body,proto3,oneof" json:"body,omitempty"`
+	ExtractVariables  []*VariableExtraction      `protobuf:"bytes,6,rep,name=extract_variables,json=extractVariables,proto3" json:"extract_variables,omitempty"`
+	Assertions        []*ResponseAssertion       `protobuf:"bytes,7,rep,name=assertions,proto3" json:"assertions,omitempty"`
+	DependsOn         []string                   `protobuf:"bytes,8,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	ContinueOnFailure bool                       `protobuf:"varint,9,opt,name=continue_on_failure,json=continueOnFailure,proto3" json:"continue_on_failure,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *HTTPRequestStep) Reset() {
+	*x = HTTPRequestStep{}
+	mi := &file_ws_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HTTPRequestStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HTTPRequestStep) ProtoMessage() {}
+
+func (x *HTTPRequestStep) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HTTPRequestStep.ProtoReflect.Descriptor instead.
+func (*HTTPRequestStep) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *HTTPRequestStep) GetStepName() string {
+	if x != nil {
+		return x.StepName
+	}
+	return ""
+}
+
+func (x *HTTPRequestStep) GetMethod() HTTPRequestStep_HttpMethod {
+	if x != nil {
+		return x.Method
+	}
+	return HTTPRequestStep_UNKNOWN
+}
+
+func (x *HTTPRequestStep) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *HTTPRequestStep) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *HTTPRequestStep) GetBody() string {
+	if x != nil && x.Body != nil {
+		return *x.Body
+	}
+	return ""
+}
+
+func (x *HTTPRequestStep) GetExtractVariables() []*VariableExtraction {
+	if x != nil {
+		return x.ExtractVariables
+	}
+	return nil
+}
+
+func (x *HTTPRequestStep) GetAssertions() []*ResponseAssertion {
+	if x != nil {
+		return x.Assertions
+	}
+	return nil
+}
+
+func (x *HTTPRequestStep) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *HTTPRequestStep) GetContinueOnFailure() bool {
+	if x != nil {
+		return x.ContinueOnFailure
+	}
+	return false
+}
+
+type HttpTest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Steps              []*HTTPRequestStep     `protobuf:"bytes,1,rep,name=steps,proto3" json:"steps,omitempty"`
+	InitialVariables   map[string]string      `protobuf:"bytes,2,rep,name=initial_variables,json=initialVariables,proto3" json:"initial_variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // JSON-encoded initial variables
+	StopOnFirstFailure bool                   `protobuf:"varint,3,opt,name=stop_on_first_failure,json=stopOnFirstFailure,proto3" json:"stop_on_first_failure,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *HttpTest) Reset() {
+	*x = HttpTest{}
+	mi := &file_ws_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HttpTest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HttpTest) ProtoMessage() {}
+
+func (x *HttpTest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HttpTest.ProtoReflect.Descriptor instead.
+func (*HttpTest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *HttpTest) GetSteps() []*HTTPRequestStep {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *HttpTest) GetInitialVariables() map[string]string {
+	if x != nil {
+		return x.InitialVariables
+	}
+	return nil
+}
+
+func (x *HttpTest) GetStopOnFirstFailure() bool {
+	if x != nil {
+		return x.StopOnFirstFailure
+	}
+	return false
+}
+
+type BrowserTest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkflowSteps []string               `protobuf:"bytes,1,rep,name=workflow_steps,json=workflowSteps,proto3" json:"workflow_steps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BrowserTest) Reset() {
+	*x = BrowserTest{}
+	mi := &file_ws_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BrowserTest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrowserTest) ProtoMessage() {}
+
+func (x *BrowserTest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrowserTest.ProtoReflect.Descriptor instead.
+func (*BrowserTest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BrowserTest) GetWorkflowSteps() []string {
+	if x != nil {
+		return x.WorkflowSteps
+	}
+	return nil
+}
+
+type TestResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TestId        string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
+	Status        TestResult_TestStatus  `protobuf:"varint,2,opt,name=status,proto3,enum=TestResult_TestStatus" json:"status,omitempty"`
+	Description   *string                `protobuf:"bytes,3,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	DetailsJson   string                 `protobuf:"bytes,4,opt,name=details_json,json=detailsJson,proto3" json:"details_json,omitempty"` // JSON-encoded details
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestResult) Reset() {
+	*x = TestResult{}
+	mi := &file_ws_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestResult) ProtoMessage() {}
+
+func (x *TestResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestResult.ProtoReflect.Descriptor instead.
+func (*TestResult) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TestResult) GetTestId() string {
+	if x != nil {
+		return x.TestId
+	}
+	return ""
+}
+
+func (x *TestResult) GetStatus() TestResult_TestStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TestResult_UNKNOWN
+}
+
+func (x *TestResult) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *TestResult) GetDetailsJson() string {
+	if x != nil {
+		return x.DetailsJson
+	}
+	return ""
+}
+
+func (x *TestResult) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type ClaudeMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CostUsd       float64                `protobuf:"fixed64,1,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	DurationApiMs int64                  `protobuf:"varint,3,opt,name=duration_api_ms,json=durationApiMs,proto3" json:"duration_api_ms,omitempty"`
+	NumTurns      int32                  `protobuf:"varint,4,opt,name=num_turns,json=numTurns,proto3" json:"num_turns,omitempty"`
+	SessionId     string                 `protobuf:"bytes,5,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClaudeMetadata) Reset() {
+	*x = ClaudeMetadata{}
+	mi := &file_ws_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClaudeMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaudeMetadata) ProtoMessage() {}
+
+func (x *ClaudeMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaudeMetadata.ProtoReflect.Descriptor instead.
+func (*ClaudeMetadata) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ClaudeMetadata) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+func (x *ClaudeMetadata) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ClaudeMetadata) GetDurationApiMs() int64 {
+	if x != nil {
+		return x.DurationApiMs
+	}
+	return 0
+}
+
+func (x *ClaudeMetadata) GetNumTurns() int32 {
+	if x != nil {
+		return x.NumTurns
+	}
+	return 0
+}
+
+func (x *ClaudeMetadata) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type TestLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TestId        string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	LogType       string                 `protobuf:"bytes,3,opt,name=log_type,json=logType,proto3" json:"log_type,omitempty"`
+	DetailsJson   string                 `protobuf:"bytes,4,opt,name=details_json,json=detailsJson,proto3" json:"details_json,omitempty"` // JSON-encoded details
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestLog) Reset() {
+	*x = TestLog{}
+	mi := &file_ws_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestLog) ProtoMessage() {}
+
+func (x *TestLog) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestLog.ProtoReflect.Descriptor instead.
+func (*TestLog) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TestLog) GetTestId() string {
+	if x != nil {
+		return x.TestId
+	}
+	return ""
+}
+
+func (x *TestLog) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *TestLog) GetLogType() string {
+	if x != nil {
+		return x.LogType
+	}
+	return ""
+}
+
+func (x *TestLog) GetDetailsJson() string {
+	if x != nil {
+		return x.DetailsJson
+	}
+	return ""
+}
+
+type TestInfo struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	TestId      string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// Types that are valid to be assigned to Test:
+	//
+	//	*TestInfo_HttpTest
+	//	*TestInfo_BrowserTest
+	Test          isTestInfo_Test `protobuf_oneof:"test"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestInfo) Reset() {
+	*x = TestInfo{}
+	mi := &file_ws_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestInfo) ProtoMessage() {}
+
+func (x *TestInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestInfo.ProtoReflect.Descriptor instead.
+func (*TestInfo) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TestInfo) GetTestId() string {
+	if x != nil {
+		return x.TestId
+	}
+	return ""
+}
+
+func (x *TestInfo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *TestInfo) GetTest() isTestInfo_Test {
+	if x != nil {
+		return x.Test
+	}
+	return nil
+}
+
+func (x *TestInfo) GetHttpTest() *HttpTest {
+	if x != nil {
+		if x, ok := x.Test.(*TestInfo_HttpTest); ok {
+			return x.HttpTest
+		}
+	}
+	return nil
+}
+
+func (x *TestInfo) GetBrowserTest() *BrowserTest {
+	if x != nil {
+		if x, ok := x.Test.(*TestInfo_BrowserTest); ok {
+			return x.BrowserTest
+		}
+	}
+	return nil
+}
+
+type isTestInfo_Test interface {
+	isTestInfo_Test()
+}
+
+type TestInfo_HttpTest struct {
+	HttpTest *HttpTest `protobuf:"bytes,3,opt,name=http_test,json=httpTest,proto3,oneof"`
+}
+
+type TestInfo_BrowserTest struct {
+	BrowserTest *BrowserTest `protobuf:"bytes,4,opt,name=browser_test,json=browserTest,proto3,oneof"`
+}
+
+func (*TestInfo_HttpTest) isTestInfo_Test() {}
+
+func (*TestInfo_BrowserTest) isTestInfo_Test() {}
+
+type VerificationProgressMessage struct {
+	state          protoimpl.MessageState                        `protogen:"open.v1"`
+	PushId         string                                        `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	Stage          VerificationProgressMessage_VerificationStage `protobuf:"varint,2,opt,name=stage,proto3,enum=VerificationProgressMessage_VerificationStage" json:"stage,omitempty"`
+	Tests          []*TestInfo                                   `protobuf:"bytes,3,rep,name=tests,proto3" json:"tests,omitempty"`
+	TestResults    []*TestResult                                 `protobuf:"bytes,4,rep,name=test_results,json=testResults,proto3" json:"test_results,omitempty"`
+	ErrorMessage   *string                                       `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
+	StartedAt      *timestamppb.Timestamp                        `protobuf:"bytes,6,opt,name=started_at,json=startedAt,proto3,oneof" json:"started_at,omitempty"`
+	CompletedAt    *timestamppb.Timestamp                        `protobuf:"bytes,7,opt,name=completed_at,json=completedAt,proto3,oneof" json:"completed_at,omitempty"`
+	ClaudeMetadata *ClaudeMetadata                               `protobuf:"bytes,8,opt,name=claude_metadata,json=claudeMetadata,proto3,oneof" json:"claude_metadata,omitempty"`
+	TestLogs       []*TestLog                                    `protobuf:"bytes,9,rep,name=test_logs,json=testLogs,proto3" json:"test_logs,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *VerificationProgressMessage) Reset() {
+	*x = VerificationProgressMessage{}
+	mi := &file_ws_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerificationProgressMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerificationProgressMessage) ProtoMessage() {}
+
+func (x *VerificationProgressMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerificationProgressMessage.ProtoReflect.Descriptor instead.
+func (*VerificationProgressMessage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *VerificationProgressMessage) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *VerificationProgressMessage) GetStage() VerificationProgressMessage_VerificationStage {
+	if x != nil {
+		return x.Stage
+	}
+	return VerificationProgressMessage_UNKNOWN
+}
+
+func (x *VerificationProgressMessage) GetTests() []*TestInfo {
+	if x != nil {
+		return x.Tests
+	}
+	return nil
+}
+
+func (x *VerificationProgressMessage) GetTestResults() []*TestResult {
+	if x != nil {
+		return x.TestResults
+	}
+	return nil
+}
+
+func (x *VerificationProgressMessage) GetErrorMessage() string {
+	if x != nil && x.ErrorMessage != nil {
+		return *x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *VerificationProgressMessage) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *VerificationProgressMessage) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *VerificationProgressMessage) GetClaudeMetadata() *ClaudeMetadata {
+	if x != nil {
+		return x.ClaudeMetadata
+	}
+	return nil
+}
+
+func (x *VerificationProgressMessage) GetTestLogs() []*TestLog {
+	if x != nil {
+		return x.TestLogs
+	}
+	return nil
+}
+
+type VerificationProgressResponse struct {
+	state         protoimpl.MessageState                          `protogen:"open.v1"`
+	PushId        string                                          `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	Status        VerificationProgressResponse_VerificationStatus `protobuf:"varint,2,opt,name=status,proto3,enum=VerificationProgressResponse_VerificationStatus" json:"status,omitempty"`
+	ErrorMessage  *string                                         `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
+	AgentReport   *string                                         `protobuf:"bytes,4,opt,name=agent_report,json=agentReport,proto3,oneof" json:"agent_report,omitempty"`
+	HumanReport   *string                                         `protobuf:"bytes,5,opt,name=human_report,json=humanReport,proto3,oneof" json:"human_report,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerificationProgressResponse) Reset() {
+	*x = VerificationProgressResponse{}
+	mi := &file_ws_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerificationProgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerificationProgressResponse) ProtoMessage() {}
+
+func (x *VerificationProgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerificationProgressResponse.ProtoReflect.Descriptor instead.
+func (*VerificationProgressResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *VerificationProgressResponse) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *VerificationProgressResponse) GetStatus() VerificationProgressResponse_VerificationStatus {
+	if x != nil {
+		return x.Status
+	}
+	return VerificationProgressResponse_UNKNOWN
+}
+
+func (x *VerificationProgressResponse) GetErrorMessage() string {
+	if x != nil && x.ErrorMessage != nil {
+		return *x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *VerificationProgressResponse) GetAgentReport() string {
+	if x != nil && x.AgentReport != nil {
+		return *x.AgentReport
+	}
+	return ""
+}
+
+func (x *VerificationProgressResponse) GetHumanReport() string {
+	if x != nil && x.HumanReport != nil {
+		return *x.HumanReport
+	}
+	return ""
+}
+
+type AuthMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionToken  string                 `protobuf:"bytes,1,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthMessage) Reset() {
+	*x = AuthMessage{}
+	mi := &file_ws_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthMessage) ProtoMessage() {}
+
+func (x *AuthMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthMessage.ProtoReflect.Descriptor instead.
+func (*AuthMessage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *AuthMessage) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+type AuthResponse struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Status        AuthResponse_AuthStatus `protobuf:"varint,1,opt,name=status,proto3,enum=AuthResponse_AuthStatus" json:"status,omitempty"`
+	ErrorMessage  *string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthResponse) Reset() {
+	*x = AuthResponse{}
+	mi := &file_ws_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthResponse) ProtoMessage() {}
+
+func (x *AuthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
+func (*AuthResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *AuthResponse) GetStatus() AuthResponse_AuthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return AuthResponse_UNKNOWN
+}
+
+func (x *AuthResponse) GetErrorMessage() string {
+	if x != nil && x.ErrorMessage != nil {
+		return *x.ErrorMessage
+	}
+	return ""
+}
+
+type ConnectionStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ConnectedSince   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=connected_since,json=connectedSince,proto3" json:"connected_since,omitempty"`
+	ReconnectCount   int32                  `protobuf:"varint,2,opt,name=reconnect_count,json=reconnectCount,proto3" json:"reconnect_count,omitempty"`
+	MessagesSent     int64                  `protobuf:"varint,3,opt,name=messages_sent,json=messagesSent,proto3" json:"messages_sent,omitempty"`
+	MessagesReceived int64                  `protobuf:"varint,4,opt,name=messages_received,json=messagesReceived,proto3" json:"messages_received,omitempty"`
+	// Smoothed round trip time of the websocket's pings, and its variation;
+	// 0 until a pong arrived.
+	RttMs              int64 `protobuf:"varint,5,opt,name=rtt_ms,json=rttMs,proto3" json:"rtt_ms,omitempty"`
+	RttVarianceMs      int64 `protobuf:"varint,6,opt,name=rtt_variance_ms,json=rttVarianceMs,proto3" json:"rtt_variance_ms,omitempty"`
+	ReconnectsLastHour int32 `protobuf:"varint,7,opt,name=reconnects_last_hour,json=reconnectsLastHour,proto3" json:"reconnects_last_hour,omitempty"`
+	// Smoothed time to receive a message once its first frame arrived.
+	MessageLatencyMs int64 `protobuf:"varint,8,opt,name=message_latency_ms,json=messageLatencyMs,proto3" json:"message_latency_ms,omitempty"`
+	// Measured from large messages sent and received; 0 until there was one.
+	ThroughputBytesPerSecond int64 `protobuf:"varint,9,opt,name=throughput_bytes_per_second,json=throughputBytesPerSecond,proto3" json:"throughput_bytes_per_second,omitempty"`
+	// How long the sidecar waits for a pong before reconnecting, and how big it
+	// makes the chunks it sends; both adapt to the measurements above. Chunks
+	// sent to the sidecar should be no bigger than chunk_size_bytes either.
+	ReadDeadlineMs int64 `protobuf:"varint,10,opt,name=read_deadline_ms,json=readDeadlineMs,proto3" json:"read_deadline_ms,omitempty"`
+	ChunkSizeBytes int32 `protobuf:"varint,11,opt,name=chunk_size_bytes,json=chunkSizeBytes,proto3" json:"chunk_size_bytes,omitempty"`
+	// The round trip time or reconnects are past what a healthy network shows.
+	Degraded      bool `protobuf:"varint,12,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionStats) Reset() {
+	*x = ConnectionStats{}
+	mi := &file_ws_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionStats) ProtoMessage() {}
+
+func (x *ConnectionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionStats.ProtoReflect.Descriptor instead.
+func (*ConnectionStats) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ConnectionStats) GetConnectedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ConnectedSince
+	}
+	return nil
+}
+
+func (x *ConnectionStats) GetReconnectCount() int32 {
+	if x != nil {
+		return x.ReconnectCount
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetMessagesSent() int64 {
+	if x != nil {
+		return x.MessagesSent
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetMessagesReceived() int64 {
+	if x != nil {
+		return x.MessagesReceived
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetRttMs() int64 {
+	if x != nil {
+		return x.RttMs
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetRttVarianceMs() int64 {
+	if x != nil {
+		return x.RttVarianceMs
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetReconnectsLastHour() int32 {
+	if x != nil {
+		return x.ReconnectsLastHour
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetMessageLatencyMs() int64 {
+	if x != nil {
+		return x.MessageLatencyMs
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetThroughputBytesPerSecond() int64 {
+	if x != nil {
+		return x.ThroughputBytesPerSecond
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetReadDeadlineMs() int64 {
+	if x != nil {
+		return x.ReadDeadlineMs
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetChunkSizeBytes() int32 {
+	if x != nil {
+		return x.ChunkSizeBytes
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+type StatusReport struct {
+	state            protoimpl.MessageState     `protogen:"open.v1"`
+	Timestamp        *timestamppb.Timestamp     `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	UptimeSeconds    int64                      `protobuf:"varint,2,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	LastPushId       string                     `protobuf:"bytes,3,opt,name=last_push_id,json=lastPushId,proto3" json:"last_push_id,omitempty"`
+	LauncherState    StatusReport_LauncherState `protobuf:"varint,4,opt,name=launcher_state,json=launcherState,proto3,enum=StatusReport_LauncherState" json:"launcher_state,omitempty"`
+	LauncherPid      int32                      `protobuf:"varint,5,opt,name=launcher_pid,json=launcherPid,proto3" json:"launcher_pid,omitempty"`
+	SyncDirBytes     int64                      `protobuf:"varint,6,opt,name=sync_dir_bytes,json=syncDirBytes,proto3" json:"sync_dir_bytes,omitempty"`               // Size of synced files, excluding sidecar/launcher internals
+	SyncDirFreeBytes int64                      `protobuf:"varint,7,opt,name=sync_dir_free_bytes,json=syncDirFreeBytes,proto3" json:"sync_dir_free_bytes,omitempty"` // Free space on the sync dir's filesystem
+	ConnectionStats  *ConnectionStats           `protobuf:"bytes,8,opt,name=connection_stats,json=connectionStats,proto3" json:"connection_stats,omitempty"`
+	Pod              *PodMetadata               `protobuf:"bytes,9,opt,name=pod,proto3" json:"pod,omitempty"`                                              // Where the sidecar runs; unset outside Kubernetes
+	WorkspaceUsage   *WorkspaceUsage            `protobuf:"bytes,10,opt,name=workspace_usage,json=workspaceUsage,proto3" json:"workspace_usage,omitempty"` // As measured after the last push that changed files
+	Resources        *ResourceUsage             `protobuf:"bytes,11,opt,name=resources,proto3" json:"resources,omitempty"`
+	AppReadiness     *AppReadiness              `protobuf:"bytes,12,opt,name=app_readiness,json=appReadiness,proto3" json:"app_readiness,omitempty"` // As the app last reported it over the control socket; unset if it never did
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StatusReport) Reset() {
+	*x = StatusReport{}
+	mi := &file_ws_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusReport) ProtoMessage() {}
+
+func (x *StatusReport) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusReport.ProtoReflect.Descriptor instead.
+func (*StatusReport) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *StatusReport) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *StatusReport) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *StatusReport) GetLastPushId() string {
+	if x != nil {
+		return x.LastPushId
+	}
+	return ""
+}
+
+func (x *StatusReport) GetLauncherState() StatusReport_LauncherState {
+	if x != nil {
+		return x.LauncherState
+	}
+	return StatusReport_UNKNOWN
+}
+
+func (x *StatusReport) GetLauncherPid() int32 {
+	if x != nil {
+		return x.LauncherPid
+	}
+	return 0
+}
+
+func (x *StatusReport) GetSyncDirBytes() int64 {
+	if x != nil {
+		return x.SyncDirBytes
+	}
+	return 0
+}
+
+func (x *StatusReport) GetSyncDirFreeBytes() int64 {
+	if x != nil {
+		return x.SyncDirFreeBytes
+	}
+	return 0
+}
+
+func (x *StatusReport) GetConnectionStats() *ConnectionStats {
+	if x != nil {
+		return x.ConnectionStats
+	}
+	return nil
+}
+
+func (x *StatusReport) GetPod() *PodMetadata {
+	if x != nil {
+		return x.Pod
+	}
+	return nil
+}
+
+func (x *StatusReport) GetWorkspaceUsage() *WorkspaceUsage {
+	if x != nil {
+		return x.WorkspaceUsage
+	}
+	return nil
+}
+
+func (x *StatusReport) GetResources() *ResourceUsage {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *StatusReport) GetAppReadiness() *AppReadiness {
+	if x != nil {
+		return x.AppReadiness
+	}
+	return nil
+}
+
+// Whether the app says it is ready to serve, reported over the sidecar's
+// local control socket.
+type AppReadiness struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ready         bool                   `protobuf:"varint,1,opt,name=ready,proto3" json:"ready,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	ReportedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=reported_at,json=reportedAt,proto3" json:"reported_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppReadiness) Reset() {
+	*x = AppReadiness{}
+	mi := &file_ws_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppReadiness) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppReadiness) ProtoMessage() {}
+
+func (x *AppReadiness) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppReadiness.ProtoReflect.Descriptor instead.
+func (*AppReadiness) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *AppReadiness) GetReady() bool {
+	if x != nil {
+		return x.Ready
+	}
+	return false
+}
+
+func (x *AppReadiness) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *AppReadiness) GetReportedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReportedAt
+	}
+	return nil
+}
+
+// What the sidecar itself uses, so it can be checked against the app it serves.
+type ResourceUsage struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	RssBytes                int64                  `protobuf:"varint,1,opt,name=rss_bytes,json=rssBytes,proto3" json:"rss_bytes,omitempty"`    // The sidecar's resident memory
+	CpuMillis               int64                  `protobuf:"varint,2,opt,name=cpu_millis,json=cpuMillis,proto3" json:"cpu_millis,omitempty"` // CPU time the sidecar has used, user and system
+	Goroutines              int32                  `protobuf:"varint,3,opt,name=goroutines,proto3" json:"goroutines,omitempty"`
+	BufferedBatchBytes      int64                  `protobuf:"varint,4,opt,name=buffered_batch_bytes,json=bufferedBatchBytes,proto3" json:"buffered_batch_bytes,omitempty"` // Reserved for streamed batches being received
+	BufferedBatchLimitBytes int64                  `protobuf:"varint,5,opt,name=buffered_batch_limit_bytes,json=bufferedBatchLimitBytes,proto3" json:"buffered_batch_limit_bytes,omitempty"`
+	MemoryLimitBytes        int64                  `protobuf:"varint,6,opt,name=memory_limit_bytes,json=memoryLimitBytes,proto3" json:"memory_limit_bytes,omitempty"` // The sidecar's soft memory limit; 0 if none
+	// The container's cgroup memory use and limit; 0 if unknown or unlimited.
+	CgroupMemoryBytes      int64 `protobuf:"varint,7,opt,name=cgroup_memory_bytes,json=cgroupMemoryBytes,proto3" json:"cgroup_memory_bytes,omitempty"`
+	CgroupMemoryLimitBytes int64 `protobuf:"varint,8,opt,name=cgroup_memory_limit_bytes,json=cgroupMemoryLimitBytes,proto3" json:"cgroup_memory_limit_bytes,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *ResourceUsage) Reset() {
+	*x = ResourceUsage{}
+	mi := &file_ws_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceUsage) ProtoMessage() {}
+
+func (x *ResourceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceUsage.ProtoReflect.Descriptor instead.
+func (*ResourceUsage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ResourceUsage) GetRssBytes() int64 {
+	if x != nil {
+		return x.RssBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetCpuMillis() int64 {
+	if x != nil {
+		return x.CpuMillis
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetGoroutines() int32 {
+	if x != nil {
+		return x.Goroutines
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetBufferedBatchBytes() int64 {
+	if x != nil {
+		return x.BufferedBatchBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetBufferedBatchLimitBytes() int64 {
+	if x != nil {
+		return x.BufferedBatchLimitBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetMemoryLimitBytes() int64 {
+	if x != nil {
+		return x.MemoryLimitBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetCgroupMemoryBytes() int64 {
+	if x != nil {
+		return x.CgroupMemoryBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetCgroupMemoryLimitBytes() int64 {
+	if x != nil {
+		return x.CgroupMemoryLimitBytes
+	}
+	return 0
+}
+
+// Locates the sidecar's pod in Kubernetes, from the downward API or the
+// Kubernetes API. Fields that couldn't be determined are empty.
+type PodMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PodName       string                 `protobuf:"bytes,1,opt,name=pod_name,json=podName,proto3" json:"pod_name,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	NodeName      string                 `protobuf:"bytes,3,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PodMetadata) Reset() {
+	*x = PodMetadata{}
+	mi := &file_ws_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PodMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodMetadata) ProtoMessage() {}
+
+func (x *PodMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodMetadata.ProtoReflect.Descriptor instead.
+func (*PodMetadata) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *PodMetadata) GetPodName() string {
+	if x != nil {
+		return x.PodName
+	}
+	return ""
+}
+
+func (x *PodMetadata) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *PodMetadata) GetNodeName() string {
+	if x != nil {
+		return x.NodeName
+	}
+	return ""
+}
+
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"` // Log file path relative to the log dir, or the FIFO name
+	Line          string                 `protobuf:"bytes,3,opt,name=line,proto3" json:"line,omitempty"`
+	Truncated     bool                   `protobuf:"varint,4,opt,name=truncated,proto3" json:"truncated,omitempty"` // Line exceeded the maximum length and was cut
+	Level         string                 `protobuf:"bytes,5,opt,name=level,proto3" json:"level,omitempty"`          // Log level, set for SIDECAR_LOG entries
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_ws_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *LogEntry) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *LogEntry) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *LogEntry) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+func (x *LogEntry) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *LogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type LogBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogBatch) Reset() {
+	*x = LogBatch{}
+	mi := &file_ws_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogBatch) ProtoMessage() {}
+
+func (x *LogBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogBatch.ProtoReflect.Descriptor instead.
+func (*LogBatch) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *LogBatch) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type ShellOpen struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Rows          uint32                 `protobuf:"varint,2,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols          uint32                 `protobuf:"varint,3,opt,name=cols,proto3" json:"cols,omitempty"`
+	Command       string                 `protobuf:"bytes,4,opt,name=command,proto3" json:"command,omitempty"` // Shell to run; defaults to the sidecar's /bin/sh
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellOpen) Reset() {
+	*x = ShellOpen{}
+	mi := &file_ws_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellOpen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellOpen) ProtoMessage() {}
+
+func (x *ShellOpen) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellOpen.ProtoReflect.Descriptor instead.
+func (*ShellOpen) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ShellOpen) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellOpen) GetRows() uint32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *ShellOpen) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+func (x *ShellOpen) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+// Carries terminal input (SHELL_STDIN) or output (SHELL_STDOUT) for a session.
+type ShellData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellData) Reset() {
+	*x = ShellData{}
+	mi := &file_ws_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellData) ProtoMessage() {}
+
+func (x *ShellData) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellData.ProtoReflect.Descriptor instead.
+func (*ShellData) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ShellData) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ShellResize struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Rows          uint32                 `protobuf:"varint,2,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols          uint32                 `protobuf:"varint,3,opt,name=cols,proto3" json:"cols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellResize) Reset() {
+	*x = ShellResize{}
+	mi := &file_ws_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellResize) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellResize) ProtoMessage() {}
+
+func (x *ShellResize) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellResize.ProtoReflect.Descriptor instead.
+func (*ShellResize) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ShellResize) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellResize) GetRows() uint32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *ShellResize) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+type ShellClose struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellClose) Reset() {
+	*x = ShellClose{}
+	mi := &file_ws_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellClose) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellClose) ProtoMessage() {}
+
+func (x *ShellClose) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellClose.ProtoReflect.Descriptor instead.
+func (*ShellClose) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ShellClose) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type ShellExit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellExit) Reset() {
+	*x = ShellExit{}
+	mi := &file_ws_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellExit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellExit) ProtoMessage() {}
+
+func (x *ShellExit) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellExit.ProtoReflect.Descriptor instead.
+func (*ShellExit) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ShellExit) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellExit) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *ShellExit) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// Sent by the sidecar after every (re)connect so the control plane can replay
+// pushes it missed while it was down.
+type Hello struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	LastPushId      string                 `protobuf:"bytes,1,opt,name=last_push_id,json=lastPushId,proto3" json:"last_push_id,omitempty"`       // Empty if nothing was ever applied
+	LastPushHash    string                 `protobuf:"bytes,2,opt,name=last_push_hash,json=lastPushHash,proto3" json:"last_push_hash,omitempty"` // "sha256:<hex>" of the last applied batch
+	LastAppliedAt   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_applied_at,json=lastAppliedAt,proto3" json:"last_applied_at,omitempty"`
+	SidecarVersion  string                 `protobuf:"bytes,4,opt,name=sidecar_version,json=sidecarVersion,proto3" json:"sidecar_version,omitempty"`
+	ProtocolVersion int32                  `protobuf:"varint,5,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Features        []string               `protobuf:"bytes,6,rep,name=features,proto3" json:"features,omitempty"` // Optional features enabled in this sidecar, e.g. "snapshots", "shell"
+	// Message types the sidecar handles; anything else is rejected as unexpected.
+	AcceptedMessages []WebsocketMessage_MessageType `protobuf:"varint,7,rep,packed,name=accepted_messages,json=acceptedMessages,proto3,enum=WebsocketMessage_MessageType" json:"accepted_messages,omitempty"`
+	ResumeToken      string                         `protobuf:"bytes,8,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // From the last HELLO_ACK; empty on the first connection
+	// The rsync provisioned for the launcher, from `rsync --version`. Empty and
+	// zero when the sidecar doesn't provision one (outside Linux).
+	RsyncVersion  string `protobuf:"bytes,9,opt,name=rsync_version,json=rsyncVersion,proto3" json:"rsync_version,omitempty"`
+	RsyncProtocol int32  `protobuf:"varint,10,opt,name=rsync_protocol,json=rsyncProtocol,proto3" json:"rsync_protocol,omitempty"`
+	// Hashes of the batches the sidecar has cached, most recently used first. A
+	// push of one of them can be sent with only its batch_hash.
+	CachedBatches []string `protobuf:"bytes,11,rep,name=cached_batches,json=cachedBatches,proto3" json:"cached_batches,omitempty"`
+	// The latest push sequence the sidecar received; 0 if it never got a
+	// sequenced push. Sequences continue from here.
+	LastSequence int64 `protobuf:"varint,12,opt,name=last_sequence,json=lastSequence,proto3" json:"last_sequence,omitempty"`
+	// The security module confining the sidecar; unset when there is none.
+	SecurityModule *SecurityModule `protobuf:"bytes,13,opt,name=security_module,json=securityModule,proto3" json:"security_module,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_ws_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *Hello) GetLastPushId() string {
+	if x != nil {
+		return x.LastPushId
+	}
+	return ""
+}
+
+func (x *Hello) GetLastPushHash() string {
+	if x != nil {
+		return x.LastPushHash
+	}
+	return ""
+}
+
+func (x *Hello) GetLastAppliedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAppliedAt
+	}
+	return nil
+}
+
+func (x *Hello) GetSidecarVersion() string {
+	if x != nil {
+		return x.SidecarVersion
+	}
+	return ""
+}
+
+func (x *Hello) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Hello) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *Hello) GetAcceptedMessages() []WebsocketMessage_MessageType {
+	if x != nil {
+		return x.AcceptedMessages
+	}
+	return nil
+}
+
+func (x *Hello) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *Hello) GetRsyncVersion() string {
+	if x != nil {
+		return x.RsyncVersion
+	}
+	return ""
+}
+
+func (x *Hello) GetRsyncProtocol() int32 {
+	if x != nil {
+		return x.RsyncProtocol
+	}
+	return 0
+}
+
+func (x *Hello) GetCachedBatches() []string {
+	if x != nil {
+		return x.CachedBatches
+	}
+	return nil
+}
+
+func (x *Hello) GetLastSequence() int64 {
+	if x != nil {
+		return x.LastSequence
+	}
+	return 0
+}
+
+func (x *Hello) GetSecurityModule() *SecurityModule {
+	if x != nil {
+		return x.SecurityModule
+	}
+	return nil
+}
+
+// The server's answer to HELLO.
+type HelloAck struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ProtocolVersion int32                  `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	ServerVersion   string                 `protobuf:"bytes,2,opt,name=server_version,json=serverVersion,proto3" json:"server_version,omitempty"`
+	Features        []string               `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	ResumeToken     string                 `protobuf:"bytes,4,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // Identifies this session; sent back in the next HELLO
+	// Pushes sent to the sidecar in the resumed session that never got a final
+	// response. The sidecar replays their responses.
+	UnacknowledgedPushIds []string `protobuf:"bytes,5,rep,name=unacknowledged_push_ids,json=unacknowledgedPushIds,proto3" json:"unacknowledged_push_ids,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *HelloAck) Reset() {
+	*x = HelloAck{}
+	mi := &file_ws_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HelloAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelloAck) ProtoMessage() {}
+
+func (x *HelloAck) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelloAck.ProtoReflect.Descriptor instead.
+func (*HelloAck) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *HelloAck) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *HelloAck) GetServerVersion() string {
+	if x != nil {
+		return x.ServerVersion
+	}
+	return ""
+}
+
+func (x *HelloAck) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *HelloAck) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *HelloAck) GetUnacknowledgedPushIds() []string {
+	if x != nil {
+		return x.UnacknowledgedPushIds
+	}
+	return nil
+}
+
+// Asks the sidecar to create (SNAPSHOT_CREATE) or restore (SNAPSHOT_RESTORE) a
+// named snapshot of the synced files.
+type SnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // Generated from the time when empty on create
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotRequest) Reset() {
+	*x = SnapshotRequest{}
+	mi := &file_ws_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotRequest) ProtoMessage() {}
+
+func (x *SnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *SnapshotRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type SnapshotInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,2,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"` // Size of the compressed archive
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotInfo) Reset() {
+	*x = SnapshotInfo{}
+	mi := &file_ws_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotInfo) ProtoMessage() {}
+
+func (x *SnapshotInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotInfo.ProtoReflect.Descriptor instead.
+func (*SnapshotInfo) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *SnapshotInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SnapshotInfo) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *SnapshotInfo) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type SnapshotResponse struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Name          string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Status        SnapshotResponse_Status `protobuf:"varint,2,opt,name=status,proto3,enum=SnapshotResponse_Status" json:"status,omitempty"`
+	ErrorMessage  string                  `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Snapshot      *SnapshotInfo           `protobuf:"bytes,4,opt,name=snapshot,proto3" json:"snapshot,omitempty"`   // The snapshot created or restored
+	Snapshots     []*SnapshotInfo         `protobuf:"bytes,5,rep,name=snapshots,proto3" json:"snapshots,omitempty"` // Every snapshot kept after the request, oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotResponse) Reset() {
+	*x = SnapshotResponse{}
+	mi := &file_ws_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotResponse) ProtoMessage() {}
+
+func (x *SnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotResponse.ProtoReflect.Descriptor instead.
+func (*SnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *SnapshotResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SnapshotResponse) GetStatus() SnapshotResponse_Status {
+	if x != nil {
+		return x.Status
+	}
+	return SnapshotResponse_UNKNOWN
+}
+
+func (x *SnapshotResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *SnapshotResponse) GetSnapshot() *SnapshotInfo {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *SnapshotResponse) GetSnapshots() []*SnapshotInfo {
+	if x != nil {
+		return x.Snapshots
+	}
+	return nil
+}
+
+// Asks the sidecar for an inventory of the synced files (MANIFEST_REQUEST).
+type ManifestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Echoed in the response
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ManifestRequest) Reset() {
+	*x = ManifestRequest{}
+	mi := &file_ws_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManifestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManifestRequest) ProtoMessage() {}
+
+func (x *ManifestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManifestRequest.ProtoReflect.Descriptor instead.
+func (*ManifestRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ManifestRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type FileEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // Relative to the files directory
+	SizeBytes     int64                  `protobuf:"varint,2,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	ModifiedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"` // Hex-encoded
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileEntry) Reset() {
+	*x = FileEntry{}
+	mi := &file_ws_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileEntry) ProtoMessage() {}
+
+func (x *FileEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileEntry.ProtoReflect.Descriptor instead.
+func (*FileEntry) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *FileEntry) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileEntry) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileEntry) GetModifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return nil
+}
+
+func (x *FileEntry) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type ManifestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Files         []*FileEntry           `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`                                   // Regular files, sorted by path; .sidecar and .launcher are excluded
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // Set when the inventory couldn't be built
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ManifestResponse) Reset() {
+	*x = ManifestResponse{}
+	mi := &file_ws_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManifestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManifestResponse) ProtoMessage() {}
+
+func (x *ManifestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManifestResponse.ProtoReflect.Descriptor instead.
+func (*ManifestResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ManifestResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ManifestResponse) GetFiles() []*FileEntry {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ManifestResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// Asks the sidecar for its sync state (SYNC_STATUS_REQUEST), answered right away
+// with a SYNC_STATUS_RESPONSE instead of waiting for the next status report.
+type SyncStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`           // Echoed in the response
+	AuditEntries  int32                  `protobuf:"varint,2,opt,name=audit_entries,json=auditEntries,proto3" json:"audit_entries,omitempty"` // How many of the latest audit log entries to include; 0 for none
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncStatusRequest) Reset() {
+	*x = SyncStatusRequest{}
+	mi := &file_ws_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncStatusRequest) ProtoMessage() {}
+
+func (x *SyncStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncStatusRequest.ProtoReflect.Descriptor instead.
+func (*SyncStatusRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *SyncStatusRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SyncStatusRequest) GetAuditEntries() int32 {
+	if x != nil {
+		return x.AuditEntries
+	}
+	return 0
+}
+
+// One push in the sidecar's audit log, written once its final response is sent.
+type AuditEntry struct {
+	state          protoimpl.MessageState  `protogen:"open.v1"`
+	PushId         string                  `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	Time           *timestamppb.Timestamp  `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	FilesChanged   int32                   `protobuf:"varint,3,opt,name=files_changed,json=filesChanged,proto3" json:"files_changed,omitempty"`        // Files created, modified and deleted by the batch
+	EnvKeysChanged []string                `protobuf:"bytes,4,rep,name=env_keys_changed,json=envKeysChanged,proto3" json:"env_keys_changed,omitempty"` // Env vars the push rewrote, names only
+	Outcome        PushResponse_PushStatus `protobuf:"varint,5,opt,name=outcome,proto3,enum=PushResponse_PushStatus" json:"outcome,omitempty"`
+	ErrorMessage   string                  `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	TriggeredBy    string                  `protobuf:"bytes,7,opt,name=triggered_by,json=triggeredBy,proto3" json:"triggered_by,omitempty"` // From the push's triggered_by, if set
+	ApprovedBy     string                  `protobuf:"bytes,8,opt,name=approved_by,json=approvedBy,proto3" json:"approved_by,omitempty"`    // From the ACTIVATE that approved the push, if it waited for approval
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AuditEntry) Reset() {
+	*x = AuditEntry{}
+	mi := &file_ws_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEntry) ProtoMessage() {}
+
+func (x *AuditEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEntry.ProtoReflect.Descriptor instead.
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *AuditEntry) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *AuditEntry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *AuditEntry) GetFilesChanged() int32 {
+	if x != nil {
+		return x.FilesChanged
+	}
+	return 0
+}
+
+func (x *AuditEntry) GetEnvKeysChanged() []string {
+	if x != nil {
+		return x.EnvKeysChanged
+	}
+	return nil
+}
+
+func (x *AuditEntry) GetOutcome() PushResponse_PushStatus {
+	if x != nil {
+		return x.Outcome
+	}
+	return PushResponse_UNKNOWN
+}
+
+func (x *AuditEntry) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *AuditEntry) GetTriggeredBy() string {
+	if x != nil {
+		return x.TriggeredBy
+	}
+	return ""
+}
+
+func (x *AuditEntry) GetApprovedBy() string {
+	if x != nil {
+		return x.ApprovedBy
+	}
+	return ""
+}
+
+type EnvFileVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`       // Relative to the files directory
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"` // Changes whenever the file's contents do
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnvFileVersion) Reset() {
+	*x = EnvFileVersion{}
+	mi := &file_ws_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnvFileVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnvFileVersion) ProtoMessage() {}
+
+func (x *EnvFileVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnvFileVersion.ProtoReflect.Descriptor instead.
+func (*EnvFileVersion) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *EnvFileVersion) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *EnvFileVersion) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// Where one variable in the env files came from. The value is never sent.
+type EnvVarProvenance struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Name          string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Scope         string                  `protobuf:"bytes,2,opt,name=scope,proto3" json:"scope,omitempty"` // Empty for the shared env file
+	Source        EnvVarProvenance_Source `protobuf:"varint,3,opt,name=source,proto3,enum=EnvVarProvenance_Source" json:"source,omitempty"`
+	Provider      string                  `protobuf:"bytes,4,opt,name=provider,proto3" json:"provider,omitempty"`                    // The sidecar's database.provider
+	SecretRef     string                  `protobuf:"bytes,5,opt,name=secret_ref,json=secretRef,proto3" json:"secret_ref,omitempty"` // The reference the value was resolved from, e.g. "vault:kv/data/app#API_KEY"
+	PushId        string                  `protobuf:"bytes,6,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`          // The push whose env refresh last changed the value
+	Version       string                  `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`                      // Changes whenever the value does
+	UpdatedAt     *timestamppb.Timestamp  `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnvVarProvenance) Reset() {
+	*x = EnvVarProvenance{}
+	mi := &file_ws_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnvVarProvenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnvVarProvenance) ProtoMessage() {}
+
+func (x *EnvVarProvenance) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnvVarProvenance.ProtoReflect.Descriptor instead.
+func (*EnvVarProvenance) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *EnvVarProvenance) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetSource() EnvVarProvenance_Source {
+	if x != nil {
+		return x.Source
+	}
+	return EnvVarProvenance_UNKNOWN
+}
+
+func (x *EnvVarProvenance) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetSecretRef() string {
+	if x != nil {
+		return x.SecretRef
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *EnvVarProvenance) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type SyncStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	LastPushId    string                 `protobuf:"bytes,2,opt,name=last_push_id,json=lastPushId,proto3" json:"last_push_id,omitempty"`       // Empty if nothing was ever applied
+	LastPushHash  string                 `protobuf:"bytes,3,opt,name=last_push_hash,json=lastPushHash,proto3" json:"last_push_hash,omitempty"` // "sha256:<hex>" of the last applied batch
+	LastAppliedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_applied_at,json=lastAppliedAt,proto3" json:"last_applied_at,omitempty"`
+	// "sha256:<hex>" over the path and hash of every file in the inventory a
+	// MANIFEST_REQUEST returns; equal hashes mean identical synced code.
+	WorkspaceHash string                     `protobuf:"bytes,5,opt,name=workspace_hash,json=workspaceHash,proto3" json:"workspace_hash,omitempty"`
+	EnvFiles      []*EnvFileVersion          `protobuf:"bytes,6,rep,name=env_files,json=envFiles,proto3" json:"env_files,omitempty"`
+	LauncherState StatusReport_LauncherState `protobuf:"varint,7,opt,name=launcher_state,json=launcherState,proto3,enum=StatusReport_LauncherState" json:"launcher_state,omitempty"`
+	LauncherPid   int32                      `protobuf:"varint,8,opt,name=launcher_pid,json=launcherPid,proto3" json:"launcher_pid,omitempty"`
+	ActivePushId  string                     `protobuf:"bytes,9,opt,name=active_push_id,json=activePushId,proto3" json:"active_push_id,omitempty"`   // The push being applied, if any
+	QueuedPushes  int32                      `protobuf:"varint,10,opt,name=queued_pushes,json=queuedPushes,proto3" json:"queued_pushes,omitempty"`   // Pushes waiting behind it, not counting cancelled ones
+	ErrorMessage  string                     `protobuf:"bytes,11,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`    // Set when part of the state couldn't be read
+	AuditLog      []*AuditEntry              `protobuf:"bytes,12,rep,name=audit_log,json=auditLog,proto3" json:"audit_log,omitempty"`                // The latest audit_entries entries, oldest first
+	EnvProvenance []*EnvVarProvenance        `protobuf:"bytes,13,rep,name=env_provenance,json=envProvenance,proto3" json:"env_provenance,omitempty"` // Sorted by scope and name
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncStatusResponse) Reset() {
+	*x = SyncStatusResponse{}
+	mi := &file_ws_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncStatusResponse) ProtoMessage() {}
+
+func (x *SyncStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncStatusResponse.ProtoReflect.Descriptor instead.
+func (*SyncStatusResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *SyncStatusResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetLastPushId() string {
+	if x != nil {
+		return x.LastPushId
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetLastPushHash() string {
+	if x != nil {
+		return x.LastPushHash
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetLastAppliedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAppliedAt
+	}
+	return nil
+}
+
+func (x *SyncStatusResponse) GetWorkspaceHash() string {
+	if x != nil {
+		return x.WorkspaceHash
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetEnvFiles() []*EnvFileVersion {
+	if x != nil {
+		return x.EnvFiles
+	}
+	return nil
+}
+
+func (x *SyncStatusResponse) GetLauncherState() StatusReport_LauncherState {
+	if x != nil {
+		return x.LauncherState
+	}
+	return StatusReport_UNKNOWN
+}
+
+func (x *SyncStatusResponse) GetLauncherPid() int32 {
+	if x != nil {
+		return x.LauncherPid
+	}
+	return 0
+}
+
+func (x *SyncStatusResponse) GetActivePushId() string {
+	if x != nil {
+		return x.ActivePushId
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetQueuedPushes() int32 {
+	if x != nil {
+		return x.QueuedPushes
+	}
+	return 0
+}
+
+func (x *SyncStatusResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *SyncStatusResponse) GetAuditLog() []*AuditEntry {
+	if x != nil {
+		return x.AuditLog
+	}
+	return nil
+}
+
+func (x *SyncStatusResponse) GetEnvProvenance() []*EnvVarProvenance {
+	if x != nil {
+		return x.EnvProvenance
+	}
+	return nil
+}
+
+// Asks the sidecar for the content of one synced file (FILE_GET_REQUEST).
+type FileGetRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Echoed in the response
+	Path      string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                            // Relative to the files directory
+	// Larger files are refused. 0, or anything above the sidecar's limit, means the limit.
+	MaxBytes      int64 `protobuf:"varint,3,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileGetRequest) Reset() {
+	*x = FileGetRequest{}
+	mi := &file_ws_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileGetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileGetRequest) ProtoMessage() {}
+
+func (x *FileGetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileGetRequest.ProtoReflect.Descriptor instead.
+func (*FileGetRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *FileGetRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *FileGetRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileGetRequest) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+type FileGetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Content       []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,4,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Mode          uint32                 `protobuf:"varint,5,opt,name=mode,proto3" json:"mode,omitempty"` // Permission bits
+	ModifiedAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,7,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // Set when the file couldn't be read; content is empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileGetResponse) Reset() {
+	*x = FileGetResponse{}
+	mi := &file_ws_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileGetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileGetResponse) ProtoMessage() {}
+
+func (x *FileGetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileGetResponse.ProtoReflect.Descriptor instead.
+func (*FileGetResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *FileGetResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *FileGetResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileGetResponse) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *FileGetResponse) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileGetResponse) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *FileGetResponse) GetModifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return nil
+}
+
+func (x *FileGetResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// Asks the sidecar for the entries of one directory of the synced code (DIR_LIST_REQUEST).
+type DirListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Echoed in the response
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                            // Relative to the files directory; empty for the files directory itself
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DirListRequest) Reset() {
+	*x = DirListRequest{}
+	mi := &file_ws_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DirListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DirListRequest) ProtoMessage() {}
+
+func (x *DirListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DirListRequest.ProtoReflect.Descriptor instead.
+func (*DirListRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *DirListRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *DirListRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type DirEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          DirEntry_Type          `protobuf:"varint,2,opt,name=type,proto3,enum=DirEntry_Type" json:"type,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Mode          uint32                 `protobuf:"varint,4,opt,name=mode,proto3" json:"mode,omitempty"` // Permission bits
+	ModifiedAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DirEntry) Reset() {
+	*x = DirEntry{}
+	mi := &file_ws_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DirEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DirEntry) ProtoMessage() {}
+
+func (x *DirEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DirEntry.ProtoReflect.Descriptor instead.
+func (*DirEntry) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *DirEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DirEntry) GetType() DirEntry_Type {
+	if x != nil {
+		return x.Type
+	}
+	return DirEntry_UNKNOWN
+}
+
+func (x *DirEntry) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *DirEntry) GetMode() uint32 {
 	if x != nil {
-		return x.BatchFile
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *DirEntry) GetModifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedAt
 	}
 	return nil
 }
 
-func (x *PushMessage) GetCodeDiff() string {
+type DirListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Entries       []*DirEntry            `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`                               // Sorted by name; .sidecar and .launcher are excluded
+	Truncated     bool                   `protobuf:"varint,4,opt,name=truncated,proto3" json:"truncated,omitempty"`                          // Some entries were cut off at the limit
+	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // Set when the directory couldn't be listed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DirListResponse) Reset() {
+	*x = DirListResponse{}
+	mi := &file_ws_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DirListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DirListResponse) ProtoMessage() {}
+
+func (x *DirListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[62]
 	if x != nil {
-		return x.CodeDiff
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *PushMessage) GetChangeDescription() string {
+// Deprecated: Use DirListResponse.ProtoReflect.Descriptor instead.
+func (*DirListResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *DirListResponse) GetRequestId() string {
 	if x != nil {
-		return x.ChangeDescription
+		return x.RequestId
 	}
 	return ""
 }
 
-func (x *PushMessage) GetFilesChanged() int32 {
+func (x *DirListResponse) GetPath() string {
 	if x != nil {
-		return x.FilesChanged
+		return x.Path
 	}
-	return 0
+	return ""
 }
 
-func (x *PushMessage) GetAdditions() int32 {
+func (x *DirListResponse) GetEntries() []*DirEntry {
 	if x != nil {
-		return x.Additions
+		return x.Entries
 	}
-	return 0
+	return nil
 }
 
-func (x *PushMessage) GetDeletions() int32 {
+func (x *DirListResponse) GetTruncated() bool {
 	if x != nil {
-		return x.Deletions
+		return x.Truncated
 	}
-	return 0
+	return false
 }
 
-func (x *PushMessage) GetDatabaseBranchUpdates() []*DatabaseBranchUpdate {
+func (x *DirListResponse) GetErrorMessage() string {
 	if x != nil {
-		return x.DatabaseBranchUpdates
+		return x.ErrorMessage
 	}
-	return nil
+	return ""
 }
 
-type PushResponse struct {
-	state         protoimpl.MessageState  `protogen:"open.v1"`
-	Status        PushResponse_PushStatus `protobuf:"varint,1,opt,name=status,proto3,enum=PushResponse_PushStatus" json:"status,omitempty"`
-	ErrorMessage  string                  `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
-	PushId        string                  `protobuf:"bytes,3,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// Starts tailing a file in the app log directory (LOG_TAIL_REQUEST). Its last
+// lines are sent in LOG_TAIL_DATA, then lines appended to it as they are
+// written, until a LOG_TAIL_STOP, the tail's duration runs out or the connection
+// drops. A LOG_TAIL_END says why the tail stopped.
+type LogTailRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TailId          string                 `protobuf:"bytes,1,opt,name=tail_id,json=tailId,proto3" json:"tail_id,omitempty"`
+	Path            string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                                               // Relative to sync.app_log_dir
+	Lines           int32                  `protobuf:"varint,3,opt,name=lines,proto3" json:"lines,omitempty"`                                            // Last lines to send first; 0 means 100, at most 1000
+	DurationSeconds int32                  `protobuf:"varint,4,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"` // 0 means 10 minutes, at most an hour
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *PushResponse) Reset() {
-	*x = PushResponse{}
-	mi := &file_ws_proto_msgTypes[2]
+func (x *LogTailRequest) Reset() {
+	*x = LogTailRequest{}
+	mi := &file_ws_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PushResponse) String() string {
+func (x *LogTailRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PushResponse) ProtoMessage() {}
+func (*LogTailRequest) ProtoMessage() {}
 
-func (x *PushResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[2]
+func (x *LogTailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -753,56 +6296,61 @@ func (x *PushResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PushResponse.ProtoReflect.Descriptor instead.
-func (*PushResponse) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use LogTailRequest.ProtoReflect.Descriptor instead.
+func (*LogTailRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{63}
 }
 
-func (x *PushResponse) GetStatus() PushResponse_PushStatus {
+func (x *LogTailRequest) GetTailId() string {
 	if x != nil {
-		return x.Status
+		return x.TailId
 	}
-	return PushResponse_UNKNOWN
+	return ""
 }
 
-func (x *PushResponse) GetErrorMessage() string {
+func (x *LogTailRequest) GetPath() string {
 	if x != nil {
-		return x.ErrorMessage
+		return x.Path
 	}
 	return ""
 }
 
-func (x *PushResponse) GetPushId() string {
+func (x *LogTailRequest) GetLines() int32 {
 	if x != nil {
-		return x.PushId
+		return x.Lines
 	}
-	return ""
+	return 0
 }
 
-type ResponseAssertion struct {
-	state         protoimpl.MessageState          `protogen:"open.v1"`
-	Type          ResponseAssertion_AssertionType `protobuf:"varint,1,opt,name=type,proto3,enum=ResponseAssertion_AssertionType" json:"type,omitempty"`
-	Expected      string                          `protobuf:"bytes,2,opt,name=expected,proto3" json:"expected,omitempty"` // JSON-encoded expected value
-	Path          *string                         `protobuf:"bytes,3,opt,name=path,proto3,oneof" json:"path,omitempty"`   // JSONPath for json_path assertions, header name for header assertions
+func (x *LogTailRequest) GetDurationSeconds() int32 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+type LogTailStop struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TailId        string                 `protobuf:"bytes,1,opt,name=tail_id,json=tailId,proto3" json:"tail_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResponseAssertion) Reset() {
-	*x = ResponseAssertion{}
-	mi := &file_ws_proto_msgTypes[3]
+func (x *LogTailStop) Reset() {
+	*x = LogTailStop{}
+	mi := &file_ws_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResponseAssertion) String() string {
+func (x *LogTailStop) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResponseAssertion) ProtoMessage() {}
+func (*LogTailStop) ProtoMessage() {}
 
-func (x *ResponseAssertion) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[3]
+func (x *LogTailStop) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -813,56 +6361,102 @@ func (x *ResponseAssertion) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResponseAssertion.ProtoReflect.Descriptor instead.
-func (*ResponseAssertion) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use LogTailStop.ProtoReflect.Descriptor instead.
+func (*LogTailStop) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{64}
 }
 
-func (x *ResponseAssertion) GetType() ResponseAssertion_AssertionType {
+func (x *LogTailStop) GetTailId() string {
 	if x != nil {
-		return x.Type
+		return x.TailId
 	}
-	return ResponseAssertion_UNKNOWN
+	return ""
 }
 
-func (x *ResponseAssertion) GetExpected() string {
+type LogTailData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TailId        string                 `protobuf:"bytes,1,opt,name=tail_id,json=tailId,proto3" json:"tail_id,omitempty"`
+	Lines         []string               `protobuf:"bytes,2,rep,name=lines,proto3" json:"lines,omitempty"`
+	DroppedLines  int64                  `protobuf:"varint,3,opt,name=dropped_lines,json=droppedLines,proto3" json:"dropped_lines,omitempty"` // Lines skipped since the previous message to stay within the rate limit
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogTailData) Reset() {
+	*x = LogTailData{}
+	mi := &file_ws_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogTailData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogTailData) ProtoMessage() {}
+
+func (x *LogTailData) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[65]
 	if x != nil {
-		return x.Expected
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *ResponseAssertion) GetPath() string {
-	if x != nil && x.Path != nil {
-		return *x.Path
+// Deprecated: Use LogTailData.ProtoReflect.Descriptor instead.
+func (*LogTailData) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *LogTailData) GetTailId() string {
+	if x != nil {
+		return x.TailId
 	}
 	return ""
 }
 
-type VariableExtraction struct {
-	state         protoimpl.MessageState        `protogen:"open.v1"`
-	Name          string                        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Source        VariableExtraction_SourceType `protobuf:"varint,2,opt,name=source,proto3,enum=VariableExtraction_SourceType" json:"source,omitempty"`
-	Path          *string                       `protobuf:"bytes,3,opt,name=path,proto3,oneof" json:"path,omitempty"` // JSONPath for json source, header name for header source
+func (x *LogTailData) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *LogTailData) GetDroppedLines() int64 {
+	if x != nil {
+		return x.DroppedLines
+	}
+	return 0
+}
+
+type LogTailEnd struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TailId        string                 `protobuf:"bytes,1,opt,name=tail_id,json=tailId,proto3" json:"tail_id,omitempty"`
+	Reason        LogTailEnd_Reason      `protobuf:"varint,2,opt,name=reason,proto3,enum=LogTailEnd_Reason" json:"reason,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VariableExtraction) Reset() {
-	*x = VariableExtraction{}
-	mi := &file_ws_proto_msgTypes[4]
+func (x *LogTailEnd) Reset() {
+	*x = LogTailEnd{}
+	mi := &file_ws_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VariableExtraction) String() string {
+func (x *LogTailEnd) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VariableExtraction) ProtoMessage() {}
+func (*LogTailEnd) ProtoMessage() {}
 
-func (x *VariableExtraction) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[4]
+func (x *LogTailEnd) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -873,62 +6467,59 @@ func (x *VariableExtraction) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VariableExtraction.ProtoReflect.Descriptor instead.
-func (*VariableExtraction) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use LogTailEnd.ProtoReflect.Descriptor instead.
+func (*LogTailEnd) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{66}
 }
 
-func (x *VariableExtraction) GetName() string {
+func (x *LogTailEnd) GetTailId() string {
 	if x != nil {
-		return x.Name
+		return x.TailId
 	}
 	return ""
 }
 
-func (x *VariableExtraction) GetSource() VariableExtraction_SourceType {
+func (x *LogTailEnd) GetReason() LogTailEnd_Reason {
 	if x != nil {
-		return x.Source
+		return x.Reason
 	}
-	return VariableExtraction_UNKNOWN
+	return LogTailEnd_UNKNOWN
 }
 
-func (x *VariableExtraction) GetPath() string {
-	if x != nil && x.Path != nil {
-		return *x.Path
+func (x *LogTailEnd) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
 	}
 	return ""
 }
 
-type HTTPRequestStep struct {
-	state             protoimpl.MessageState     `protogen:"open.v1"`
-	StepName          string                     `protobuf:"bytes,1,opt,name=step_name,json=stepName,proto3" json:"step_name,omitempty"`
-	Method            HTTPRequestStep_HttpMethod `protobuf:"varint,2,opt,name=method,proto3,enum=HTTPRequestStep_HttpMethod" json:"method,omitempty"`
-	Path              string                     `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
-	Headers           map[string]string          `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body              *string                    `protobuf:"bytes,5,opt,name=body,proto3,oneof" json:"body,omitempty"`
-	ExtractVariables  []*VariableExtraction      `protobuf:"bytes,6,rep,name=extract_variables,json=extractVariables,proto3" json:"extract_variables,omitempty"`
-	Assertions        []*ResponseAssertion       `protobuf:"bytes,7,rep,name=assertions,proto3" json:"assertions,omitempty"`
-	DependsOn         []string                   `protobuf:"bytes,8,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
-	ContinueOnFailure bool                       `protobuf:"varint,9,opt,name=continue_on_failure,json=continueOnFailure,proto3" json:"continue_on_failure,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+// One piece of a push's batch sent after its PUSH_REQUEST, which set
+// streamed_batch_size. Chunks are sent in order; the last one has last set.
+type BatchChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PushId        string                 `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
+	Index         int32                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"` // 0 for the first chunk
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Last          bool                   `protobuf:"varint,4,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HTTPRequestStep) Reset() {
-	*x = HTTPRequestStep{}
-	mi := &file_ws_proto_msgTypes[5]
+func (x *BatchChunk) Reset() {
+	*x = BatchChunk{}
+	mi := &file_ws_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HTTPRequestStep) String() string {
+func (x *BatchChunk) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HTTPRequestStep) ProtoMessage() {}
+func (*BatchChunk) ProtoMessage() {}
 
-func (x *HTTPRequestStep) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[5]
+func (x *BatchChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -939,98 +6530,146 @@ func (x *HTTPRequestStep) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HTTPRequestStep.ProtoReflect.Descriptor instead.
-func (*HTTPRequestStep) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use BatchChunk.ProtoReflect.Descriptor instead.
+func (*BatchChunk) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{67}
 }
 
-func (x *HTTPRequestStep) GetStepName() string {
+func (x *BatchChunk) GetPushId() string {
 	if x != nil {
-		return x.StepName
+		return x.PushId
 	}
 	return ""
 }
 
-func (x *HTTPRequestStep) GetMethod() HTTPRequestStep_HttpMethod {
+func (x *BatchChunk) GetIndex() int32 {
 	if x != nil {
-		return x.Method
+		return x.Index
 	}
-	return HTTPRequestStep_UNKNOWN
+	return 0
 }
 
-func (x *HTTPRequestStep) GetPath() string {
+func (x *BatchChunk) GetData() []byte {
 	if x != nil {
-		return x.Path
+		return x.Data
 	}
-	return ""
+	return nil
 }
 
-func (x *HTTPRequestStep) GetHeaders() map[string]string {
+func (x *BatchChunk) GetLast() bool {
 	if x != nil {
-		return x.Headers
+		return x.Last
 	}
-	return nil
+	return false
 }
 
-func (x *HTTPRequestStep) GetBody() string {
-	if x != nil && x.Body != nil {
-		return *x.Body
+// Sent unsolicited when the synced files can no longer be trusted to match
+// what was pushed, asking the control plane for a batch of the full tree, e.g.
+// a mirror push. The request stands until a mirror push is applied.
+type ResyncRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Reason ResyncRequest_Reason   `protobuf:"varint,1,opt,name=reason,proto3,enum=ResyncRequest_Reason" json:"reason,omitempty"`
+	Detail string                 `protobuf:"bytes,2,opt,name=detail,proto3" json:"detail,omitempty"`
+	// Hash of the synced files, as in SyncStatusResponse. The control plane can
+	// compare it with the tree it would send and, when they differ, fetch the
+	// inventory with MANIFEST_REQUEST to send only what differs.
+	WorkspaceHash string   `protobuf:"bytes,3,opt,name=workspace_hash,json=workspaceHash,proto3" json:"workspace_hash,omitempty"`
+	LastPushId    string   `protobuf:"bytes,4,opt,name=last_push_id,json=lastPushId,proto3" json:"last_push_id,omitempty"`     // Empty if the sidecar has no record of a push
+	DriftedFiles  []string `protobuf:"bytes,5,rep,name=drifted_files,json=driftedFiles,proto3" json:"drifted_files,omitempty"` // With DRIFT, at most 1000, sorted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResyncRequest) Reset() {
+	*x = ResyncRequest{}
+	mi := &file_ws_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResyncRequest) ProtoMessage() {}
+
+func (x *ResyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *HTTPRequestStep) GetExtractVariables() []*VariableExtraction {
+// Deprecated: Use ResyncRequest.ProtoReflect.Descriptor instead.
+func (*ResyncRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *ResyncRequest) GetReason() ResyncRequest_Reason {
 	if x != nil {
-		return x.ExtractVariables
+		return x.Reason
 	}
-	return nil
+	return ResyncRequest_UNKNOWN
 }
 
-func (x *HTTPRequestStep) GetAssertions() []*ResponseAssertion {
+func (x *ResyncRequest) GetDetail() string {
 	if x != nil {
-		return x.Assertions
+		return x.Detail
 	}
-	return nil
+	return ""
 }
 
-func (x *HTTPRequestStep) GetDependsOn() []string {
+func (x *ResyncRequest) GetWorkspaceHash() string {
 	if x != nil {
-		return x.DependsOn
+		return x.WorkspaceHash
 	}
-	return nil
+	return ""
 }
 
-func (x *HTTPRequestStep) GetContinueOnFailure() bool {
+func (x *ResyncRequest) GetLastPushId() string {
 	if x != nil {
-		return x.ContinueOnFailure
+		return x.LastPushId
 	}
-	return false
+	return ""
 }
 
-type HttpTest struct {
-	state              protoimpl.MessageState `protogen:"open.v1"`
-	Steps              []*HTTPRequestStep     `protobuf:"bytes,1,rep,name=steps,proto3" json:"steps,omitempty"`
-	InitialVariables   map[string]string      `protobuf:"bytes,2,rep,name=initial_variables,json=initialVariables,proto3" json:"initial_variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // JSON-encoded initial variables
-	StopOnFirstFailure bool                   `protobuf:"varint,3,opt,name=stop_on_first_failure,json=stopOnFirstFailure,proto3" json:"stop_on_first_failure,omitempty"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+func (x *ResyncRequest) GetDriftedFiles() []string {
+	if x != nil {
+		return x.DriftedFiles
+	}
+	return nil
 }
 
-func (x *HttpTest) Reset() {
-	*x = HttpTest{}
-	mi := &file_ws_proto_msgTypes[6]
+// Asks the sidecar to restore a version of the env files it kept under
+// .sidecar/env-history and reload the app (ENV_ROLLBACK).
+type EnvRollbackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Echoed in the response
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`                      // The version before the current one when empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnvRollbackRequest) Reset() {
+	*x = EnvRollbackRequest{}
+	mi := &file_ws_proto_msgTypes[69]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HttpTest) String() string {
+func (x *EnvRollbackRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HttpTest) ProtoMessage() {}
+func (*EnvRollbackRequest) ProtoMessage() {}
 
-func (x *HttpTest) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[6]
+func (x *EnvRollbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[69]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1041,54 +6680,50 @@ func (x *HttpTest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HttpTest.ProtoReflect.Descriptor instead.
-func (*HttpTest) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{6}
-}
-
-func (x *HttpTest) GetSteps() []*HTTPRequestStep {
-	if x != nil {
-		return x.Steps
-	}
-	return nil
+// Deprecated: Use EnvRollbackRequest.ProtoReflect.Descriptor instead.
+func (*EnvRollbackRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{69}
 }
 
-func (x *HttpTest) GetInitialVariables() map[string]string {
+func (x *EnvRollbackRequest) GetRequestId() string {
 	if x != nil {
-		return x.InitialVariables
+		return x.RequestId
 	}
-	return nil
+	return ""
 }
 
-func (x *HttpTest) GetStopOnFirstFailure() bool {
+func (x *EnvRollbackRequest) GetVersion() string {
 	if x != nil {
-		return x.StopOnFirstFailure
+		return x.Version
 	}
-	return false
+	return ""
 }
 
-type BrowserTest struct {
+type EnvVersion struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	WorkflowSteps []string               `protobuf:"bytes,1,rep,name=workflow_steps,json=workflowSteps,proto3" json:"workflow_steps,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PushId        string                 `protobuf:"bytes,2,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"` // The push whose env refresh wrote the files, if any
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Files         []string               `protobuf:"bytes,4,rep,name=files,proto3" json:"files,omitempty"` // Names of the env files in the version
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *BrowserTest) Reset() {
-	*x = BrowserTest{}
-	mi := &file_ws_proto_msgTypes[7]
+func (x *EnvVersion) Reset() {
+	*x = EnvVersion{}
+	mi := &file_ws_proto_msgTypes[70]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BrowserTest) String() string {
+func (x *EnvVersion) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BrowserTest) ProtoMessage() {}
+func (*EnvVersion) ProtoMessage() {}
 
-func (x *BrowserTest) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[7]
+func (x *EnvVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[70]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1099,44 +6734,66 @@ func (x *BrowserTest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BrowserTest.ProtoReflect.Descriptor instead.
-func (*BrowserTest) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use EnvVersion.ProtoReflect.Descriptor instead.
+func (*EnvVersion) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{70}
 }
 
-func (x *BrowserTest) GetWorkflowSteps() []string {
+func (x *EnvVersion) GetId() string {
 	if x != nil {
-		return x.WorkflowSteps
+		return x.Id
+	}
+	return ""
+}
+
+func (x *EnvVersion) GetPushId() string {
+	if x != nil {
+		return x.PushId
+	}
+	return ""
+}
+
+func (x *EnvVersion) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
 	}
 	return nil
 }
 
-type TestResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TestId        string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
-	Status        TestResult_TestStatus  `protobuf:"varint,2,opt,name=status,proto3,enum=TestResult_TestStatus" json:"status,omitempty"`
-	Description   *string                `protobuf:"bytes,3,opt,name=description,proto3,oneof" json:"description,omitempty"`
-	DetailsJson   string                 `protobuf:"bytes,4,opt,name=details_json,json=detailsJson,proto3" json:"details_json,omitempty"` // JSON-encoded details
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *EnvVersion) GetFiles() []string {
+	if x != nil {
+		return x.Files
+	}
+	return nil
 }
 
-func (x *TestResult) Reset() {
-	*x = TestResult{}
-	mi := &file_ws_proto_msgTypes[8]
+type EnvRollbackResponse struct {
+	state          protoimpl.MessageState     `protogen:"open.v1"`
+	RequestId      string                     `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Status         EnvRollbackResponse_Status `protobuf:"varint,2,opt,name=status,proto3,enum=EnvRollbackResponse_Status" json:"status,omitempty"`
+	ErrorMessage   string                     `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Version        *EnvVersion                `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`   // The version restored
+	Versions       []*EnvVersion              `protobuf:"bytes,5,rep,name=versions,proto3" json:"versions,omitempty"` // Every version kept after the request, oldest first
+	CurrentVersion string                     `protobuf:"bytes,6,opt,name=current_version,json=currentVersion,proto3" json:"current_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *EnvRollbackResponse) Reset() {
+	*x = EnvRollbackResponse{}
+	mi := &file_ws_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TestResult) String() string {
+func (x *EnvRollbackResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TestResult) ProtoMessage() {}
+func (*EnvRollbackResponse) ProtoMessage() {}
 
-func (x *TestResult) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[8]
+func (x *EnvRollbackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1147,72 +6804,80 @@ func (x *TestResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TestResult.ProtoReflect.Descriptor instead.
-func (*TestResult) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use EnvRollbackResponse.ProtoReflect.Descriptor instead.
+func (*EnvRollbackResponse) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{71}
 }
 
-func (x *TestResult) GetTestId() string {
+func (x *EnvRollbackResponse) GetRequestId() string {
 	if x != nil {
-		return x.TestId
+		return x.RequestId
 	}
 	return ""
 }
 
-func (x *TestResult) GetStatus() TestResult_TestStatus {
+func (x *EnvRollbackResponse) GetStatus() EnvRollbackResponse_Status {
 	if x != nil {
 		return x.Status
 	}
-	return TestResult_UNKNOWN
+	return EnvRollbackResponse_UNKNOWN
 }
 
-func (x *TestResult) GetDescription() string {
-	if x != nil && x.Description != nil {
-		return *x.Description
+func (x *EnvRollbackResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
 	}
 	return ""
 }
 
-func (x *TestResult) GetDetailsJson() string {
+func (x *EnvRollbackResponse) GetVersion() *EnvVersion {
 	if x != nil {
-		return x.DetailsJson
+		return x.Version
 	}
-	return ""
+	return nil
 }
 
-func (x *TestResult) GetTimestamp() *timestamppb.Timestamp {
+func (x *EnvRollbackResponse) GetVersions() []*EnvVersion {
 	if x != nil {
-		return x.Timestamp
+		return x.Versions
 	}
 	return nil
 }
 
-type ClaudeMetadata struct {
+func (x *EnvRollbackResponse) GetCurrentVersion() string {
+	if x != nil {
+		return x.CurrentVersion
+	}
+	return ""
+}
+
+// Sent unsolicited when the launcher process the sidecar saw running has exited.
+type LauncherExited struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	CostUsd       float64                `protobuf:"fixed64,1,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
-	DurationMs    int64                  `protobuf:"varint,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
-	DurationApiMs int64                  `protobuf:"varint,3,opt,name=duration_api_ms,json=durationApiMs,proto3" json:"duration_api_ms,omitempty"`
-	NumTurns      int32                  `protobuf:"varint,4,opt,name=num_turns,json=numTurns,proto3" json:"num_turns,omitempty"`
-	SessionId     string                 `protobuf:"bytes,5,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	DetectedAt    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=detected_at,json=detectedAt,proto3" json:"detected_at,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                        // Written by the launcher on exit; empty if it was killed without a chance to
+	AppStatus     string                 `protobuf:"bytes,4,opt,name=app_status,json=appStatus,proto3" json:"app_status,omitempty"` // Last exit status the launcher recorded for the app, if any
+	LastPushId    string                 `protobuf:"bytes,5,opt,name=last_push_id,json=lastPushId,proto3" json:"last_push_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ClaudeMetadata) Reset() {
-	*x = ClaudeMetadata{}
-	mi := &file_ws_proto_msgTypes[9]
+func (x *LauncherExited) Reset() {
+	*x = LauncherExited{}
+	mi := &file_ws_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ClaudeMetadata) String() string {
+func (x *LauncherExited) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ClaudeMetadata) ProtoMessage() {}
+func (*LauncherExited) ProtoMessage() {}
 
-func (x *ClaudeMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[9]
+func (x *LauncherExited) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1223,71 +6888,69 @@ func (x *ClaudeMetadata) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ClaudeMetadata.ProtoReflect.Descriptor instead.
-func (*ClaudeMetadata) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use LauncherExited.ProtoReflect.Descriptor instead.
+func (*LauncherExited) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{72}
 }
 
-func (x *ClaudeMetadata) GetCostUsd() float64 {
+func (x *LauncherExited) GetPid() int32 {
 	if x != nil {
-		return x.CostUsd
+		return x.Pid
 	}
 	return 0
 }
 
-func (x *ClaudeMetadata) GetDurationMs() int64 {
+func (x *LauncherExited) GetDetectedAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.DurationMs
+		return x.DetectedAt
 	}
-	return 0
+	return nil
 }
 
-func (x *ClaudeMetadata) GetDurationApiMs() int64 {
+func (x *LauncherExited) GetReason() string {
 	if x != nil {
-		return x.DurationApiMs
+		return x.Reason
 	}
-	return 0
+	return ""
 }
 
-func (x *ClaudeMetadata) GetNumTurns() int32 {
+func (x *LauncherExited) GetAppStatus() string {
 	if x != nil {
-		return x.NumTurns
+		return x.AppStatus
 	}
-	return 0
+	return ""
 }
 
-func (x *ClaudeMetadata) GetSessionId() string {
+func (x *LauncherExited) GetLastPushId() string {
 	if x != nil {
-		return x.SessionId
+		return x.LastPushId
 	}
 	return ""
 }
 
-type TestLog struct {
+// Asks the sidecar for a support bundle (DIAGNOSTICS_REQUEST).
+type DiagnosticsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TestId        string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	LogType       string                 `protobuf:"bytes,3,opt,name=log_type,json=logType,proto3" json:"log_type,omitempty"`
-	DetailsJson   string                 `protobuf:"bytes,4,opt,name=details_json,json=detailsJson,proto3" json:"details_json,omitempty"` // JSON-encoded details
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Echoed in every chunk
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TestLog) Reset() {
-	*x = TestLog{}
-	mi := &file_ws_proto_msgTypes[10]
+func (x *DiagnosticsRequest) Reset() {
+	*x = DiagnosticsRequest{}
+	mi := &file_ws_proto_msgTypes[73]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TestLog) String() string {
+func (x *DiagnosticsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TestLog) ProtoMessage() {}
+func (*DiagnosticsRequest) ProtoMessage() {}
 
-func (x *TestLog) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[10]
+func (x *DiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[73]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1298,67 +6961,48 @@ func (x *TestLog) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TestLog.ProtoReflect.Descriptor instead.
-func (*TestLog) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{10}
-}
-
-func (x *TestLog) GetTestId() string {
-	if x != nil {
-		return x.TestId
-	}
-	return ""
-}
-
-func (x *TestLog) GetTimestamp() *timestamppb.Timestamp {
-	if x != nil {
-		return x.Timestamp
-	}
-	return nil
-}
-
-func (x *TestLog) GetLogType() string {
-	if x != nil {
-		return x.LogType
-	}
-	return ""
+// Deprecated: Use DiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*DiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{73}
 }
 
-func (x *TestLog) GetDetailsJson() string {
+func (x *DiagnosticsRequest) GetRequestId() string {
 	if x != nil {
-		return x.DetailsJson
+		return x.RequestId
 	}
 	return ""
 }
 
-type TestInfo struct {
-	state       protoimpl.MessageState `protogen:"open.v1"`
-	TestId      string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
-	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	// Types that are valid to be assigned to Test:
-	//
-	//	*TestInfo_HttpTest
-	//	*TestInfo_BrowserTest
-	Test          isTestInfo_Test `protobuf_oneof:"test"`
+// One piece of a gzipped tarball of diagnostics: recent sidecar logs, the
+// redacted config, the state file, env file keys, the last rsync output and
+// directory stats. Chunks are sent in order; the last one has last set, and
+// error_message if the bundle couldn't be built.
+type DiagnosticsChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Index         int32                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"` // 0 for the first chunk
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Last          bool                   `protobuf:"varint,4,opt,name=last,proto3" json:"last,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TestInfo) Reset() {
-	*x = TestInfo{}
-	mi := &file_ws_proto_msgTypes[11]
+func (x *DiagnosticsChunk) Reset() {
+	*x = DiagnosticsChunk{}
+	mi := &file_ws_proto_msgTypes[74]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TestInfo) String() string {
+func (x *DiagnosticsChunk) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TestInfo) ProtoMessage() {}
+func (*DiagnosticsChunk) ProtoMessage() {}
 
-func (x *TestInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[11]
+func (x *DiagnosticsChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[74]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1369,96 +7013,110 @@ func (x *TestInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TestInfo.ProtoReflect.Descriptor instead.
-func (*TestInfo) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use DiagnosticsChunk.ProtoReflect.Descriptor instead.
+func (*DiagnosticsChunk) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{74}
 }
 
-func (x *TestInfo) GetTestId() string {
+func (x *DiagnosticsChunk) GetRequestId() string {
 	if x != nil {
-		return x.TestId
+		return x.RequestId
 	}
 	return ""
 }
 
-func (x *TestInfo) GetDescription() string {
+func (x *DiagnosticsChunk) GetIndex() int32 {
 	if x != nil {
-		return x.Description
+		return x.Index
 	}
-	return ""
+	return 0
 }
 
-func (x *TestInfo) GetTest() isTestInfo_Test {
+func (x *DiagnosticsChunk) GetData() []byte {
 	if x != nil {
-		return x.Test
+		return x.Data
 	}
 	return nil
 }
 
-func (x *TestInfo) GetHttpTest() *HttpTest {
+func (x *DiagnosticsChunk) GetLast() bool {
 	if x != nil {
-		if x, ok := x.Test.(*TestInfo_HttpTest); ok {
-			return x.HttpTest
-		}
+		return x.Last
 	}
-	return nil
+	return false
 }
 
-func (x *TestInfo) GetBrowserTest() *BrowserTest {
+func (x *DiagnosticsChunk) GetErrorMessage() string {
 	if x != nil {
-		if x, ok := x.Test.(*TestInfo_BrowserTest); ok {
-			return x.BrowserTest
-		}
+		return x.ErrorMessage
 	}
-	return nil
-}
-
-type isTestInfo_Test interface {
-	isTestInfo_Test()
-}
-
-type TestInfo_HttpTest struct {
-	HttpTest *HttpTest `protobuf:"bytes,3,opt,name=http_test,json=httpTest,proto3,oneof"`
-}
-
-type TestInfo_BrowserTest struct {
-	BrowserTest *BrowserTest `protobuf:"bytes,4,opt,name=browser_test,json=browserTest,proto3,oneof"`
+	return ""
 }
 
-func (*TestInfo_HttpTest) isTestInfo_Test() {}
-
-func (*TestInfo_BrowserTest) isTestInfo_Test() {}
-
-type VerificationProgressMessage struct {
-	state          protoimpl.MessageState                        `protogen:"open.v1"`
-	PushId         string                                        `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
-	Stage          VerificationProgressMessage_VerificationStage `protobuf:"varint,2,opt,name=stage,proto3,enum=VerificationProgressMessage_VerificationStage" json:"stage,omitempty"`
-	Tests          []*TestInfo                                   `protobuf:"bytes,3,rep,name=tests,proto3" json:"tests,omitempty"`
-	TestResults    []*TestResult                                 `protobuf:"bytes,4,rep,name=test_results,json=testResults,proto3" json:"test_results,omitempty"`
-	ErrorMessage   *string                                       `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
-	StartedAt      *timestamppb.Timestamp                        `protobuf:"bytes,6,opt,name=started_at,json=startedAt,proto3,oneof" json:"started_at,omitempty"`
-	CompletedAt    *timestamppb.Timestamp                        `protobuf:"bytes,7,opt,name=completed_at,json=completedAt,proto3,oneof" json:"completed_at,omitempty"`
-	ClaudeMetadata *ClaudeMetadata                               `protobuf:"bytes,8,opt,name=claude_metadata,json=claudeMetadata,proto3,oneof" json:"claude_metadata,omitempty"`
-	TestLogs       []*TestLog                                    `protobuf:"bytes,9,rep,name=test_logs,json=testLogs,proto3" json:"test_logs,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type WebsocketMessage struct {
+	state       protoimpl.MessageState       `protogen:"open.v1"`
+	MessageType WebsocketMessage_MessageType `protobuf:"varint,1,opt,name=message_type,json=messageType,proto3,enum=WebsocketMessage_MessageType" json:"message_type,omitempty"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*WebsocketMessage_PushMessage
+	//	*WebsocketMessage_PushResponse
+	//	*WebsocketMessage_VerificationProgress
+	//	*WebsocketMessage_VerificationProgressResponse
+	//	*WebsocketMessage_AuthMessage
+	//	*WebsocketMessage_AuthResponse
+	//	*WebsocketMessage_StatusReport
+	//	*WebsocketMessage_LogBatch
+	//	*WebsocketMessage_ShellOpen
+	//	*WebsocketMessage_ShellData
+	//	*WebsocketMessage_ShellResize
+	//	*WebsocketMessage_ShellClose
+	//	*WebsocketMessage_ShellExit
+	//	*WebsocketMessage_PushCancel
+	//	*WebsocketMessage_PushProgress
+	//	*WebsocketMessage_Hello
+	//	*WebsocketMessage_SnapshotRequest
+	//	*WebsocketMessage_SnapshotResponse
+	//	*WebsocketMessage_ManifestRequest
+	//	*WebsocketMessage_ManifestResponse
+	//	*WebsocketMessage_LauncherExited
+	//	*WebsocketMessage_HelloAck
+	//	*WebsocketMessage_DiagnosticsRequest
+	//	*WebsocketMessage_DiagnosticsChunk
+	//	*WebsocketMessage_SyncStatusRequest
+	//	*WebsocketMessage_SyncStatusResponse
+	//	*WebsocketMessage_FileGetRequest
+	//	*WebsocketMessage_FileGetResponse
+	//	*WebsocketMessage_DirListRequest
+	//	*WebsocketMessage_DirListResponse
+	//	*WebsocketMessage_LogTailRequest
+	//	*WebsocketMessage_LogTailStop
+	//	*WebsocketMessage_LogTailData
+	//	*WebsocketMessage_LogTailEnd
+	//	*WebsocketMessage_BatchChunk
+	//	*WebsocketMessage_ResyncRequest
+	//	*WebsocketMessage_EnvRollbackRequest
+	//	*WebsocketMessage_EnvRollbackResponse
+	//	*WebsocketMessage_ActivateRequest
+	Message       isWebsocketMessage_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VerificationProgressMessage) Reset() {
-	*x = VerificationProgressMessage{}
-	mi := &file_ws_proto_msgTypes[12]
+func (x *WebsocketMessage) Reset() {
+	*x = WebsocketMessage{}
+	mi := &file_ws_proto_msgTypes[75]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VerificationProgressMessage) String() string {
+func (x *WebsocketMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VerificationProgressMessage) ProtoMessage() {}
+func (*WebsocketMessage) ProtoMessage() {}
 
-func (x *VerificationProgressMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[12]
+func (x *WebsocketMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[75]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1469,355 +7127,371 @@ func (x *VerificationProgressMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VerificationProgressMessage.ProtoReflect.Descriptor instead.
-func (*VerificationProgressMessage) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use WebsocketMessage.ProtoReflect.Descriptor instead.
+func (*WebsocketMessage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{75}
 }
 
-func (x *VerificationProgressMessage) GetPushId() string {
+func (x *WebsocketMessage) GetMessageType() WebsocketMessage_MessageType {
 	if x != nil {
-		return x.PushId
+		return x.MessageType
 	}
-	return ""
+	return WebsocketMessage_UNKNOWN
 }
 
-func (x *VerificationProgressMessage) GetStage() VerificationProgressMessage_VerificationStage {
+func (x *WebsocketMessage) GetMessage() isWebsocketMessage_Message {
 	if x != nil {
-		return x.Stage
+		return x.Message
 	}
-	return VerificationProgressMessage_UNKNOWN
+	return nil
 }
 
-func (x *VerificationProgressMessage) GetTests() []*TestInfo {
+func (x *WebsocketMessage) GetPushMessage() *PushMessage {
 	if x != nil {
-		return x.Tests
+		if x, ok := x.Message.(*WebsocketMessage_PushMessage); ok {
+			return x.PushMessage
+		}
 	}
 	return nil
 }
 
-func (x *VerificationProgressMessage) GetTestResults() []*TestResult {
+func (x *WebsocketMessage) GetPushResponse() *PushResponse {
 	if x != nil {
-		return x.TestResults
+		if x, ok := x.Message.(*WebsocketMessage_PushResponse); ok {
+			return x.PushResponse
+		}
 	}
 	return nil
 }
 
-func (x *VerificationProgressMessage) GetErrorMessage() string {
-	if x != nil && x.ErrorMessage != nil {
-		return *x.ErrorMessage
+func (x *WebsocketMessage) GetVerificationProgress() *VerificationProgressMessage {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_VerificationProgress); ok {
+			return x.VerificationProgress
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *VerificationProgressMessage) GetStartedAt() *timestamppb.Timestamp {
+func (x *WebsocketMessage) GetVerificationProgressResponse() *VerificationProgressResponse {
 	if x != nil {
-		return x.StartedAt
+		if x, ok := x.Message.(*WebsocketMessage_VerificationProgressResponse); ok {
+			return x.VerificationProgressResponse
+		}
 	}
 	return nil
 }
 
-func (x *VerificationProgressMessage) GetCompletedAt() *timestamppb.Timestamp {
+func (x *WebsocketMessage) GetAuthMessage() *AuthMessage {
 	if x != nil {
-		return x.CompletedAt
+		if x, ok := x.Message.(*WebsocketMessage_AuthMessage); ok {
+			return x.AuthMessage
+		}
 	}
 	return nil
 }
 
-func (x *VerificationProgressMessage) GetClaudeMetadata() *ClaudeMetadata {
+func (x *WebsocketMessage) GetAuthResponse() *AuthResponse {
 	if x != nil {
-		return x.ClaudeMetadata
+		if x, ok := x.Message.(*WebsocketMessage_AuthResponse); ok {
+			return x.AuthResponse
+		}
 	}
 	return nil
 }
 
-func (x *VerificationProgressMessage) GetTestLogs() []*TestLog {
+func (x *WebsocketMessage) GetStatusReport() *StatusReport {
 	if x != nil {
-		return x.TestLogs
+		if x, ok := x.Message.(*WebsocketMessage_StatusReport); ok {
+			return x.StatusReport
+		}
 	}
 	return nil
 }
 
-type VerificationProgressResponse struct {
-	state         protoimpl.MessageState                          `protogen:"open.v1"`
-	PushId        string                                          `protobuf:"bytes,1,opt,name=push_id,json=pushId,proto3" json:"push_id,omitempty"`
-	Status        VerificationProgressResponse_VerificationStatus `protobuf:"varint,2,opt,name=status,proto3,enum=VerificationProgressResponse_VerificationStatus" json:"status,omitempty"`
-	ErrorMessage  *string                                         `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
-	AgentReport   *string                                         `protobuf:"bytes,4,opt,name=agent_report,json=agentReport,proto3,oneof" json:"agent_report,omitempty"`
-	HumanReport   *string                                         `protobuf:"bytes,5,opt,name=human_report,json=humanReport,proto3,oneof" json:"human_report,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *VerificationProgressResponse) Reset() {
-	*x = VerificationProgressResponse{}
-	mi := &file_ws_proto_msgTypes[13]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *VerificationProgressResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*VerificationProgressResponse) ProtoMessage() {}
-
-func (x *VerificationProgressResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[13]
+func (x *WebsocketMessage) GetLogBatch() *LogBatch {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
+		if x, ok := x.Message.(*WebsocketMessage_LogBatch); ok {
+			return x.LogBatch
 		}
-		return ms
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use VerificationProgressResponse.ProtoReflect.Descriptor instead.
-func (*VerificationProgressResponse) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{13}
+	return nil
 }
 
-func (x *VerificationProgressResponse) GetPushId() string {
+func (x *WebsocketMessage) GetShellOpen() *ShellOpen {
 	if x != nil {
-		return x.PushId
+		if x, ok := x.Message.(*WebsocketMessage_ShellOpen); ok {
+			return x.ShellOpen
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *VerificationProgressResponse) GetStatus() VerificationProgressResponse_VerificationStatus {
+func (x *WebsocketMessage) GetShellData() *ShellData {
 	if x != nil {
-		return x.Status
+		if x, ok := x.Message.(*WebsocketMessage_ShellData); ok {
+			return x.ShellData
+		}
 	}
-	return VerificationProgressResponse_UNKNOWN
+	return nil
 }
 
-func (x *VerificationProgressResponse) GetErrorMessage() string {
-	if x != nil && x.ErrorMessage != nil {
-		return *x.ErrorMessage
+func (x *WebsocketMessage) GetShellResize() *ShellResize {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_ShellResize); ok {
+			return x.ShellResize
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *VerificationProgressResponse) GetAgentReport() string {
-	if x != nil && x.AgentReport != nil {
-		return *x.AgentReport
+func (x *WebsocketMessage) GetShellClose() *ShellClose {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_ShellClose); ok {
+			return x.ShellClose
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *VerificationProgressResponse) GetHumanReport() string {
-	if x != nil && x.HumanReport != nil {
-		return *x.HumanReport
+func (x *WebsocketMessage) GetShellExit() *ShellExit {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_ShellExit); ok {
+			return x.ShellExit
+		}
 	}
-	return ""
-}
-
-type AuthMessage struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionToken  string                 `protobuf:"bytes,1,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	return nil
 }
 
-func (x *AuthMessage) Reset() {
-	*x = AuthMessage{}
-	mi := &file_ws_proto_msgTypes[14]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *WebsocketMessage) GetPushCancel() *PushCancel {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_PushCancel); ok {
+			return x.PushCancel
+		}
+	}
+	return nil
 }
 
-func (x *AuthMessage) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *WebsocketMessage) GetPushProgress() *PushProgress {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_PushProgress); ok {
+			return x.PushProgress
+		}
+	}
+	return nil
 }
 
-func (*AuthMessage) ProtoMessage() {}
-
-func (x *AuthMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[14]
+func (x *WebsocketMessage) GetHello() *Hello {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
+		if x, ok := x.Message.(*WebsocketMessage_Hello); ok {
+			return x.Hello
 		}
-		return ms
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use AuthMessage.ProtoReflect.Descriptor instead.
-func (*AuthMessage) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{14}
+func (x *WebsocketMessage) GetSnapshotRequest() *SnapshotRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_SnapshotRequest); ok {
+			return x.SnapshotRequest
+		}
+	}
+	return nil
 }
 
-func (x *AuthMessage) GetSessionToken() string {
+func (x *WebsocketMessage) GetSnapshotResponse() *SnapshotResponse {
 	if x != nil {
-		return x.SessionToken
+		if x, ok := x.Message.(*WebsocketMessage_SnapshotResponse); ok {
+			return x.SnapshotResponse
+		}
 	}
-	return ""
+	return nil
 }
 
-type AuthResponse struct {
-	state         protoimpl.MessageState  `protogen:"open.v1"`
-	Status        AuthResponse_AuthStatus `protobuf:"varint,1,opt,name=status,proto3,enum=AuthResponse_AuthStatus" json:"status,omitempty"`
-	ErrorMessage  *string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3,oneof" json:"error_message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *WebsocketMessage) GetManifestRequest() *ManifestRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_ManifestRequest); ok {
+			return x.ManifestRequest
+		}
+	}
+	return nil
 }
 
-func (x *AuthResponse) Reset() {
-	*x = AuthResponse{}
-	mi := &file_ws_proto_msgTypes[15]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *WebsocketMessage) GetManifestResponse() *ManifestResponse {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_ManifestResponse); ok {
+			return x.ManifestResponse
+		}
+	}
+	return nil
 }
 
-func (x *AuthResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *WebsocketMessage) GetLauncherExited() *LauncherExited {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_LauncherExited); ok {
+			return x.LauncherExited
+		}
+	}
+	return nil
 }
 
-func (*AuthResponse) ProtoMessage() {}
-
-func (x *AuthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[15]
+func (x *WebsocketMessage) GetHelloAck() *HelloAck {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
+		if x, ok := x.Message.(*WebsocketMessage_HelloAck); ok {
+			return x.HelloAck
 		}
-		return ms
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
-func (*AuthResponse) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{15}
+func (x *WebsocketMessage) GetDiagnosticsRequest() *DiagnosticsRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_DiagnosticsRequest); ok {
+			return x.DiagnosticsRequest
+		}
+	}
+	return nil
 }
 
-func (x *AuthResponse) GetStatus() AuthResponse_AuthStatus {
+func (x *WebsocketMessage) GetDiagnosticsChunk() *DiagnosticsChunk {
 	if x != nil {
-		return x.Status
+		if x, ok := x.Message.(*WebsocketMessage_DiagnosticsChunk); ok {
+			return x.DiagnosticsChunk
+		}
 	}
-	return AuthResponse_UNKNOWN
+	return nil
 }
 
-func (x *AuthResponse) GetErrorMessage() string {
-	if x != nil && x.ErrorMessage != nil {
-		return *x.ErrorMessage
+func (x *WebsocketMessage) GetSyncStatusRequest() *SyncStatusRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_SyncStatusRequest); ok {
+			return x.SyncStatusRequest
+		}
 	}
-	return ""
+	return nil
 }
 
-type WebsocketMessage struct {
-	state       protoimpl.MessageState       `protogen:"open.v1"`
-	MessageType WebsocketMessage_MessageType `protobuf:"varint,1,opt,name=message_type,json=messageType,proto3,enum=WebsocketMessage_MessageType" json:"message_type,omitempty"`
-	// Types that are valid to be assigned to Message:
-	//
-	//	*WebsocketMessage_PushMessage
-	//	*WebsocketMessage_PushResponse
-	//	*WebsocketMessage_VerificationProgress
-	//	*WebsocketMessage_VerificationProgressResponse
-	//	*WebsocketMessage_AuthMessage
-	//	*WebsocketMessage_AuthResponse
-	Message       isWebsocketMessage_Message `protobuf_oneof:"message"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *WebsocketMessage) GetSyncStatusResponse() *SyncStatusResponse {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_SyncStatusResponse); ok {
+			return x.SyncStatusResponse
+		}
+	}
+	return nil
 }
 
-func (x *WebsocketMessage) Reset() {
-	*x = WebsocketMessage{}
-	mi := &file_ws_proto_msgTypes[16]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *WebsocketMessage) GetFileGetRequest() *FileGetRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_FileGetRequest); ok {
+			return x.FileGetRequest
+		}
+	}
+	return nil
 }
 
-func (x *WebsocketMessage) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *WebsocketMessage) GetFileGetResponse() *FileGetResponse {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_FileGetResponse); ok {
+			return x.FileGetResponse
+		}
+	}
+	return nil
 }
 
-func (*WebsocketMessage) ProtoMessage() {}
+func (x *WebsocketMessage) GetDirListRequest() *DirListRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_DirListRequest); ok {
+			return x.DirListRequest
+		}
+	}
+	return nil
+}
 
-func (x *WebsocketMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_ws_proto_msgTypes[16]
+func (x *WebsocketMessage) GetDirListResponse() *DirListResponse {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
+		if x, ok := x.Message.(*WebsocketMessage_DirListResponse); ok {
+			return x.DirListResponse
 		}
-		return ms
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use WebsocketMessage.ProtoReflect.Descriptor instead.
-func (*WebsocketMessage) Descriptor() ([]byte, []int) {
-	return file_ws_proto_rawDescGZIP(), []int{16}
+func (x *WebsocketMessage) GetLogTailRequest() *LogTailRequest {
+	if x != nil {
+		if x, ok := x.Message.(*WebsocketMessage_LogTailRequest); ok {
+			return x.LogTailRequest
+		}
+	}
+	return nil
 }
 
-func (x *WebsocketMessage) GetMessageType() WebsocketMessage_MessageType {
+func (x *WebsocketMessage) GetLogTailStop() *LogTailStop {
 	if x != nil {
-		return x.MessageType
+		if x, ok := x.Message.(*WebsocketMessage_LogTailStop); ok {
+			return x.LogTailStop
+		}
 	}
-	return WebsocketMessage_UNKNOWN
+	return nil
 }
 
-func (x *WebsocketMessage) GetMessage() isWebsocketMessage_Message {
+func (x *WebsocketMessage) GetLogTailData() *LogTailData {
 	if x != nil {
-		return x.Message
+		if x, ok := x.Message.(*WebsocketMessage_LogTailData); ok {
+			return x.LogTailData
+		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetPushMessage() *PushMessage {
+func (x *WebsocketMessage) GetLogTailEnd() *LogTailEnd {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_PushMessage); ok {
-			return x.PushMessage
+		if x, ok := x.Message.(*WebsocketMessage_LogTailEnd); ok {
+			return x.LogTailEnd
 		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetPushResponse() *PushResponse {
+func (x *WebsocketMessage) GetBatchChunk() *BatchChunk {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_PushResponse); ok {
-			return x.PushResponse
+		if x, ok := x.Message.(*WebsocketMessage_BatchChunk); ok {
+			return x.BatchChunk
 		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetVerificationProgress() *VerificationProgressMessage {
+func (x *WebsocketMessage) GetResyncRequest() *ResyncRequest {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_VerificationProgress); ok {
-			return x.VerificationProgress
+		if x, ok := x.Message.(*WebsocketMessage_ResyncRequest); ok {
+			return x.ResyncRequest
 		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetVerificationProgressResponse() *VerificationProgressResponse {
+func (x *WebsocketMessage) GetEnvRollbackRequest() *EnvRollbackRequest {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_VerificationProgressResponse); ok {
-			return x.VerificationProgressResponse
+		if x, ok := x.Message.(*WebsocketMessage_EnvRollbackRequest); ok {
+			return x.EnvRollbackRequest
 		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetAuthMessage() *AuthMessage {
+func (x *WebsocketMessage) GetEnvRollbackResponse() *EnvRollbackResponse {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_AuthMessage); ok {
-			return x.AuthMessage
+		if x, ok := x.Message.(*WebsocketMessage_EnvRollbackResponse); ok {
+			return x.EnvRollbackResponse
 		}
 	}
 	return nil
 }
 
-func (x *WebsocketMessage) GetAuthResponse() *AuthResponse {
+func (x *WebsocketMessage) GetActivateRequest() *ActivateRequest {
 	if x != nil {
-		if x, ok := x.Message.(*WebsocketMessage_AuthResponse); ok {
-			return x.AuthResponse
+		if x, ok := x.Message.(*WebsocketMessage_ActivateRequest); ok {
+			return x.ActivateRequest
 		}
 	}
 	return nil
@@ -1851,6 +7525,138 @@ type WebsocketMessage_AuthResponse struct {
 	AuthResponse *AuthResponse `protobuf:"bytes,7,opt,name=auth_response,json=authResponse,proto3,oneof"`
 }
 
+type WebsocketMessage_StatusReport struct {
+	StatusReport *StatusReport `protobuf:"bytes,8,opt,name=status_report,json=statusReport,proto3,oneof"`
+}
+
+type WebsocketMessage_LogBatch struct {
+	LogBatch *LogBatch `protobuf:"bytes,9,opt,name=log_batch,json=logBatch,proto3,oneof"`
+}
+
+type WebsocketMessage_ShellOpen struct {
+	ShellOpen *ShellOpen `protobuf:"bytes,10,opt,name=shell_open,json=shellOpen,proto3,oneof"`
+}
+
+type WebsocketMessage_ShellData struct {
+	ShellData *ShellData `protobuf:"bytes,11,opt,name=shell_data,json=shellData,proto3,oneof"`
+}
+
+type WebsocketMessage_ShellResize struct {
+	ShellResize *ShellResize `protobuf:"bytes,12,opt,name=shell_resize,json=shellResize,proto3,oneof"`
+}
+
+type WebsocketMessage_ShellClose struct {
+	ShellClose *ShellClose `protobuf:"bytes,13,opt,name=shell_close,json=shellClose,proto3,oneof"`
+}
+
+type WebsocketMessage_ShellExit struct {
+	ShellExit *ShellExit `protobuf:"bytes,14,opt,name=shell_exit,json=shellExit,proto3,oneof"`
+}
+
+type WebsocketMessage_PushCancel struct {
+	PushCancel *PushCancel `protobuf:"bytes,15,opt,name=push_cancel,json=pushCancel,proto3,oneof"`
+}
+
+type WebsocketMessage_PushProgress struct {
+	PushProgress *PushProgress `protobuf:"bytes,16,opt,name=push_progress,json=pushProgress,proto3,oneof"`
+}
+
+type WebsocketMessage_Hello struct {
+	Hello *Hello `protobuf:"bytes,17,opt,name=hello,proto3,oneof"`
+}
+
+type WebsocketMessage_SnapshotRequest struct {
+	SnapshotRequest *SnapshotRequest `protobuf:"bytes,18,opt,name=snapshot_request,json=snapshotRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_SnapshotResponse struct {
+	SnapshotResponse *SnapshotResponse `protobuf:"bytes,19,opt,name=snapshot_response,json=snapshotResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_ManifestRequest struct {
+	ManifestRequest *ManifestRequest `protobuf:"bytes,20,opt,name=manifest_request,json=manifestRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_ManifestResponse struct {
+	ManifestResponse *ManifestResponse `protobuf:"bytes,21,opt,name=manifest_response,json=manifestResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_LauncherExited struct {
+	LauncherExited *LauncherExited `protobuf:"bytes,22,opt,name=launcher_exited,json=launcherExited,proto3,oneof"`
+}
+
+type WebsocketMessage_HelloAck struct {
+	HelloAck *HelloAck `protobuf:"bytes,23,opt,name=hello_ack,json=helloAck,proto3,oneof"`
+}
+
+type WebsocketMessage_DiagnosticsRequest struct {
+	DiagnosticsRequest *DiagnosticsRequest `protobuf:"bytes,24,opt,name=diagnostics_request,json=diagnosticsRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_DiagnosticsChunk struct {
+	DiagnosticsChunk *DiagnosticsChunk `protobuf:"bytes,25,opt,name=diagnostics_chunk,json=diagnosticsChunk,proto3,oneof"`
+}
+
+type WebsocketMessage_SyncStatusRequest struct {
+	SyncStatusRequest *SyncStatusRequest `protobuf:"bytes,26,opt,name=sync_status_request,json=syncStatusRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_SyncStatusResponse struct {
+	SyncStatusResponse *SyncStatusResponse `protobuf:"bytes,27,opt,name=sync_status_response,json=syncStatusResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_FileGetRequest struct {
+	FileGetRequest *FileGetRequest `protobuf:"bytes,28,opt,name=file_get_request,json=fileGetRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_FileGetResponse struct {
+	FileGetResponse *FileGetResponse `protobuf:"bytes,29,opt,name=file_get_response,json=fileGetResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_DirListRequest struct {
+	DirListRequest *DirListRequest `protobuf:"bytes,30,opt,name=dir_list_request,json=dirListRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_DirListResponse struct {
+	DirListResponse *DirListResponse `protobuf:"bytes,31,opt,name=dir_list_response,json=dirListResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_LogTailRequest struct {
+	LogTailRequest *LogTailRequest `protobuf:"bytes,32,opt,name=log_tail_request,json=logTailRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_LogTailStop struct {
+	LogTailStop *LogTailStop `protobuf:"bytes,33,opt,name=log_tail_stop,json=logTailStop,proto3,oneof"`
+}
+
+type WebsocketMessage_LogTailData struct {
+	LogTailData *LogTailData `protobuf:"bytes,34,opt,name=log_tail_data,json=logTailData,proto3,oneof"`
+}
+
+type WebsocketMessage_LogTailEnd struct {
+	LogTailEnd *LogTailEnd `protobuf:"bytes,35,opt,name=log_tail_end,json=logTailEnd,proto3,oneof"`
+}
+
+type WebsocketMessage_BatchChunk struct {
+	BatchChunk *BatchChunk `protobuf:"bytes,36,opt,name=batch_chunk,json=batchChunk,proto3,oneof"`
+}
+
+type WebsocketMessage_ResyncRequest struct {
+	ResyncRequest *ResyncRequest `protobuf:"bytes,37,opt,name=resync_request,json=resyncRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_EnvRollbackRequest struct {
+	EnvRollbackRequest *EnvRollbackRequest `protobuf:"bytes,38,opt,name=env_rollback_request,json=envRollbackRequest,proto3,oneof"`
+}
+
+type WebsocketMessage_EnvRollbackResponse struct {
+	EnvRollbackResponse *EnvRollbackResponse `protobuf:"bytes,39,opt,name=env_rollback_response,json=envRollbackResponse,proto3,oneof"`
+}
+
+type WebsocketMessage_ActivateRequest struct {
+	ActivateRequest *ActivateRequest `protobuf:"bytes,40,opt,name=activate_request,json=activateRequest,proto3,oneof"`
+}
+
 func (*WebsocketMessage_PushMessage) isWebsocketMessage_Message() {}
 
 func (*WebsocketMessage_PushResponse) isWebsocketMessage_Message() {}
@@ -1863,6 +7669,117 @@ func (*WebsocketMessage_AuthMessage) isWebsocketMessage_Message() {}
 
 func (*WebsocketMessage_AuthResponse) isWebsocketMessage_Message() {}
 
+func (*WebsocketMessage_StatusReport) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LogBatch) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ShellOpen) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ShellData) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ShellResize) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ShellClose) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ShellExit) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_PushCancel) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_PushProgress) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_Hello) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_SnapshotRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_SnapshotResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ManifestRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ManifestResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LauncherExited) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_HelloAck) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_DiagnosticsRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_DiagnosticsChunk) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_SyncStatusRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_SyncStatusResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_FileGetRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_FileGetResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_DirListRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_DirListResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LogTailRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LogTailStop) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LogTailData) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_LogTailEnd) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_BatchChunk) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ResyncRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_EnvRollbackRequest) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_EnvRollbackResponse) isWebsocketMessage_Message() {}
+
+func (*WebsocketMessage_ActivateRequest) isWebsocketMessage_Message() {}
+
+// Body of a long-poll response: the messages queued for a sidecar that can't use websockets.
+type MessageBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*WebsocketMessage    `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageBatch) Reset() {
+	*x = MessageBatch{}
+	mi := &file_ws_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageBatch) ProtoMessage() {}
+
+func (x *MessageBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageBatch.ProtoReflect.Descriptor instead.
+func (*MessageBatch) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *MessageBatch) GetMessages() []*WebsocketMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
 var File_ws_proto protoreflect.FileDescriptor
 
 const file_ws_proto_rawDesc = "" +
@@ -1873,7 +7790,7 @@ const file_ws_proto_rawDesc = "" +
 	"\x12previous_branch_id\x18\x02 \x01(\tR\x10previousBranchId\x12\"\n" +
 	"\rnew_branch_id\x18\x03 \x01(\tR\vnewBranchId\x12%\n" +
 	"\x0ebranch_created\x18\x04 \x01(\bR\rbranchCreated\x12(\n" +
-	"\x10parent_branch_id\x18\x05 \x01(\tR\x0eparentBranchId\"\xc1\x02\n" +
+	"\x10parent_branch_id\x18\x05 \x01(\tR\x0eparentBranchId\"\x85\b\n" +
 	"\vPushMessage\x12\x17\n" +
 	"\apush_id\x18\x01 \x01(\tR\x06pushId\x12\x1d\n" +
 	"\n" +
@@ -1883,11 +7800,100 @@ const file_ws_proto_rawDesc = "" +
 	"\rfiles_changed\x18\x05 \x01(\x05R\ffilesChanged\x12\x1c\n" +
 	"\tadditions\x18\x06 \x01(\x05R\tadditions\x12\x1c\n" +
 	"\tdeletions\x18\a \x01(\x05R\tdeletions\x12M\n" +
-	"\x17database_branch_updates\x18\b \x03(\v2\x15.DatabaseBranchUpdateR\x15databaseBranchUpdates\"\xd2\x01\n" +
+	"\x17database_branch_updates\x18\b \x03(\v2\x15.DatabaseBranchUpdateR\x15databaseBranchUpdates\x12\x14\n" +
+	"\x05force\x18\t \x01(\bR\x05force\x12\x1f\n" +
+	"\vrsync_flags\x18\n" +
+	" \x03(\tR\n" +
+	"rsyncFlags\x12-\n" +
+	"\x05files\x18\v \x03(\v2\x17.PushMessage.FilesEntryR\x05files\x12#\n" +
+	"\rdeleted_paths\x18\f \x03(\tR\fdeletedPaths\x121\n" +
+	"\x15deleted_paths_dry_run\x18\r \x01(\bR\x12deletedPathsDryRun\x12!\n" +
+	"\frequired_env\x18\x0e \x03(\tR\vrequiredEnv\x12.\n" +
+	"\x13streamed_batch_size\x18\x0f \x01(\x03R\x11streamedBatchSize\x12!\n" +
+	"\ftriggered_by\x18\x10 \x01(\tR\vtriggeredBy\x12\x16\n" +
+	"\x06mirror\x18\x11 \x01(\bR\x06mirror\x12\x14\n" +
+	"\x05paths\x18\x12 \x03(\tR\x05paths\x12\x1d\n" +
+	"\n" +
+	"batch_hash\x18\x13 \x01(\tR\tbatchHash\x129\n" +
+	"\n" +
+	"not_before\x18\x14 \x01(\v2\x1a.google.protobuf.TimestampR\tnotBefore\x12.\n" +
+	"\x13bypass_push_windows\x18\x15 \x01(\bR\x11bypassPushWindows\x129\n" +
+	"\x0factivation_plan\x18\x16 \x03(\v2\x10.ActivationStageR\x0eactivationPlan\x12\x1a\n" +
+	"\bsequence\x18\x17 \x01(\x03R\bsequence\x126\n" +
+	"\x0fsubtree_batches\x18\x18 \x03(\v2\r.SubtreeBatchR\x0esubtreeBatches\x1aG\n" +
+	"\n" +
+	"FilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\x05value\x18\x02 \x01(\v2\r.InjectedFileR\x05value:\x028\x01\"A\n" +
+	"\fSubtreeBatch\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"batch_file\x18\x02 \x01(\fR\tbatchFile\"/\n" +
+	"\x0fActivationStage\x12\x1c\n" +
+	"\tlaunchers\x18\x01 \x03(\tR\tlaunchers\"X\n" +
+	"\fInjectedFile\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\fR\acontent\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\rR\x04mode\x12\x1a\n" +
+	"\btemplate\x18\x03 \x01(\bR\btemplate\"g\n" +
+	"\x12InjectedFileResult\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\xd0\x01\n" +
+	"\x11DeletedPathResult\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x121\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x19.DeletedPathResult.StatusR\x06status\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"O\n" +
+	"\x06Status\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aREMOVED\x10\x01\x12\r\n" +
+	"\tNOT_FOUND\x10\x02\x12\x10\n" +
+	"\fWOULD_REMOVE\x10\x03\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x04\"v\n" +
+	"\n" +
+	"HookResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1b\n" +
+	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12\x16\n" +
+	"\x06output\x18\x03 \x01(\tR\x06output\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x03R\n" +
+	"durationMs\"\xcf\x0e\n" +
 	"\fPushResponse\x120\n" +
 	"\x06status\x18\x01 \x01(\x0e2\x18.PushResponse.PushStatusR\x06status\x12#\n" +
 	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\x12\x17\n" +
-	"\apush_id\x18\x03 \x01(\tR\x06pushId\"R\n" +
+	"\apush_id\x18\x03 \x01(\tR\x06pushId\x12.\n" +
+	"\fhook_results\x18\x04 \x03(\v2\v.HookResultR\vhookResults\x12'\n" +
+	"\x0falready_applied\x18\x05 \x01(\bR\x0ealreadyApplied\x12+\n" +
+	"\x11conflicting_files\x18\x06 \x03(\tR\x10conflictingFiles\x12#\n" +
+	"\rcreated_files\x18\a \x03(\tR\fcreatedFiles\x12%\n" +
+	"\x0emodified_files\x18\b \x03(\tR\rmodifiedFiles\x12#\n" +
+	"\rdeleted_files\x18\t \x03(\tR\fdeletedFiles\x124\n" +
+	"\x16file_changes_truncated\x18\n" +
+	" \x01(\bR\x14fileChangesTruncated\x12\x1a\n" +
+	"\breplayed\x18\v \x01(\bR\breplayed\x12#\n" +
+	"\rsuperseded_by\x18\f \x01(\tR\fsupersededBy\x12:\n" +
+	"\x0einjected_files\x18\r \x03(\v2\x13.InjectedFileResultR\rinjectedFiles\x127\n" +
+	"\rdeleted_paths\x18\x0e \x03(\v2\x12.DeletedPathResultR\fdeletedPaths\x12\x1e\n" +
+	"\x03pod\x18\x0f \x01(\v2\f.PodMetadataR\x03pod\x127\n" +
+	"\x0freplica_results\x18\x10 \x03(\v2\x0e.ReplicaResultR\x0ereplicaResults\x12#\n" +
+	"\x06timing\x18\x11 \x01(\v2\v.PushTimingR\x06timing\x12\x13\n" +
+	"\x05no_op\x18\x12 \x01(\bR\x04noOp\x12\x1f\n" +
+	"\vmissing_env\x18\x13 \x03(\tR\n" +
+	"missingEnv\x12%\n" +
+	"\x0ersync_attempts\x18\x14 \x01(\x05R\rrsyncAttempts\x12'\n" +
+	"\x0fsignal_attempts\x18\x15 \x01(\x05R\x0esignalAttempts\x12)\n" +
+	"\x10mirror_deletions\x18\x16 \x03(\tR\x0fmirrorDeletions\x128\n" +
+	"\x0fworkspace_usage\x18\x17 \x01(\v2\x0f.WorkspaceUsageR\x0eworkspaceUsage\x12+\n" +
+	"\x12out_of_scope_paths\x18\x18 \x03(\tR\x0foutOfScopePaths\x12@\n" +
+	"\x10launcher_results\x18\x19 \x03(\v2\x15.LauncherSignalResultR\x0flauncherResults\x129\n" +
+	"\n" +
+	"held_until\x18\x1a \x01(\v2\x1a.google.protobuf.TimestampR\theldUntil\x12\x1f\n" +
+	"\vapproved_by\x18\x1b \x01(\tR\n" +
+	"approvedBy\x126\n" +
+	"\x17failed_activation_stage\x18\x1c \x01(\x05R\x15failedActivationStage\x12#\n" +
+	"\rlast_sequence\x18\x1d \x01(\x03R\flastSequence\x12/\n" +
+	"\fsequence_gap\x18\x1e \x01(\v2\f.SequenceGapR\vsequenceGap\x127\n" +
+	"\x0fsubtree_results\x18\x1f \x03(\v2\x0e.SubtreeResultR\x0esubtreeResults\x12:\n" +
+	"\x10security_denials\x18  \x03(\v2\x0f.SecurityDenialR\x0fsecurityDenials\"\xb4\x03\n" +
 	"\n" +
 	"PushStatus\x12\v\n" +
 	"\aUNKNOWN\x10\x00\x12\v\n" +
@@ -1895,7 +7901,112 @@ const file_ws_proto_rawDesc = "" +
 	"\vIN_PROGRESS\x10\x02\x12\n" +
 	"\n" +
 	"\x06FAILED\x10\x03\x12\r\n" +
-	"\tCOMPLETED\x10\x04\"\xe4\x01\n" +
+	"\tCOMPLETED\x10\x04\x12\r\n" +
+	"\tCANCELLED\x10\x05\x12\f\n" +
+	"\bCONFLICT\x10\x06\x12\x15\n" +
+	"\x11INSUFFICIENT_DISK\x10\a\x12\x11\n" +
+	"\rRELOAD_FAILED\x10\b\x12\f\n" +
+	"\bRECEIVED\x10\t\x12\f\n" +
+	"\bAPPLYING\x10\n" +
+	"\x12\r\n" +
+	"\tRELOADING\x10\v\x12\v\n" +
+	"\aHEALTHY\x10\f\x12\x0f\n" +
+	"\vROLLED_BACK\x10\r\x12\x0e\n" +
+	"\n" +
+	"SUPERSEDED\x10\x0e\x12\v\n" +
+	"\aPARTIAL\x10\x0f\x12\x0f\n" +
+	"\vMISSING_ENV\x10\x10\x12\v\n" +
+	"\aSTALLED\x10\x11\x12\x16\n" +
+	"\x12TOO_MANY_DELETIONS\x10\x12\x12\x12\n" +
+	"\x0eQUOTA_EXCEEDED\x10\x13\x12\x10\n" +
+	"\fOUT_OF_SCOPE\x10\x14\x12\x14\n" +
+	"\x10BATCH_NOT_CACHED\x10\x15\x12\x18\n" +
+	"\x14LAUNCHER_NOT_RUNNING\x10\x16\x12\x15\n" +
+	"\x11AWAITING_APPROVAL\x10\x17\x12\x10\n" +
+	"\fOUT_OF_ORDER\x10\x18\"g\n" +
+	"\x0eSecurityDenial\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
+	"\toperation\x18\x02 \x01(\tR\toperation\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"N\n" +
+	"\x0eSecurityModule\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x14\n" +
+	"\x05label\x18\x03 \x01(\tR\x05label\"\xa8\x01\n" +
+	"\rSubtreeResult\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12#\n" +
+	"\rfiles_changed\x18\x04 \x01(\x05R\ffilesChanged\x12\x1f\n" +
+	"\vduration_ms\x18\x05 \x01(\x03R\n" +
+	"durationMs\"7\n" +
+	"\vSequenceGap\x12\x14\n" +
+	"\x05first\x18\x01 \x01(\x03R\x05first\x12\x12\n" +
+	"\x04last\x18\x02 \x01(\x03R\x04last\"\xd4\x01\n" +
+	"\x14LauncherSignalResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03pid\x18\x02 \x01(\x05R\x03pid\x12\x16\n" +
+	"\x06signal\x18\x03 \x01(\tR\x06signal\x12\x1c\n" +
+	"\tsignalled\x18\x04 \x01(\bR\tsignalled\x12\x1f\n" +
+	"\vnot_running\x18\x05 \x01(\bR\n" +
+	"notRunning\x12#\n" +
+	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\x12\x1a\n" +
+	"\battempts\x18\a \x01(\x05R\battempts\"\xd8\x01\n" +
+	"\x0eWorkspaceUsage\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytes\x12\x16\n" +
+	"\x06inodes\x18\x02 \x01(\x03R\x06inodes\x12\x1d\n" +
+	"\n" +
+	"soft_bytes\x18\x03 \x01(\x03R\tsoftBytes\x12\x1d\n" +
+	"\n" +
+	"hard_bytes\x18\x04 \x01(\x03R\thardBytes\x12\x1f\n" +
+	"\vsoft_inodes\x18\x05 \x01(\x03R\n" +
+	"softInodes\x12\x1f\n" +
+	"\vhard_inodes\x18\x06 \x01(\x03R\n" +
+	"hardInodes\x12\x18\n" +
+	"\awarning\x18\a \x01(\tR\awarning\"\xaf\x02\n" +
+	"\n" +
+	"PushTiming\x12\x1f\n" +
+	"\vdownload_ms\x18\x01 \x01(\x03R\n" +
+	"downloadMs\x12$\n" +
+	"\x0ebatch_write_ms\x18\x02 \x01(\x03R\fbatchWriteMs\x12\x19\n" +
+	"\brsync_ms\x18\x03 \x01(\x03R\arsyncMs\x12 \n" +
+	"\fenv_write_ms\x18\x04 \x01(\x03R\n" +
+	"envWriteMs\x12/\n" +
+	"\x14signal_to_healthy_ms\x18\x05 \x01(\x03R\x11signalToHealthyMs\x12\x19\n" +
+	"\btotal_ms\x18\x06 \x01(\x03R\atotalMs\x12\x19\n" +
+	"\bbuild_ms\x18\a \x01(\x03R\abuildMs\x12\x1d\n" +
+	"\n" +
+	"install_ms\x18\b \x01(\x03R\tinstallMs\x12\x17\n" +
+	"\awarm_ms\x18\t \x01(\x03R\x06warmMs\"\x9d\x01\n" +
+	"\rReplicaResult\x12\x1d\n" +
+	"\n" +
+	"replica_id\x18\x01 \x01(\tR\treplicaId\x120\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x18.PushResponse.PushStatusR\x06status\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12\x16\n" +
+	"\x06leader\x18\x04 \x01(\bR\x06leader\"\xb1\x02\n" +
+	"\fPushProgress\x12\x17\n" +
+	"\apush_id\x18\x01 \x01(\tR\x06pushId\x12)\n" +
+	"\x05stage\x18\x02 \x01(\x0e2\x13.PushProgress.StageR\x05stage\x12\x18\n" +
+	"\apercent\x18\x03 \x01(\x05R\apercent\x12\x1d\n" +
+	"\n" +
+	"bytes_done\x18\x04 \x01(\x03R\tbytesDone\x12\x1f\n" +
+	"\vbytes_total\x18\x05 \x01(\x03R\n" +
+	"bytesTotal\x12!\n" +
+	"\foutput_lines\x18\x06 \x03(\tR\voutputLines\"`\n" +
+	"\x05Stage\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\x0f\n" +
+	"\vDOWNLOADING\x10\x01\x12\f\n" +
+	"\bAPPLYING\x10\x02\x12\r\n" +
+	"\tRELOADING\x10\x03\x12\f\n" +
+	"\bBUILDING\x10\x04\x12\x0e\n" +
+	"\n" +
+	"INSTALLING\x10\x05\"%\n" +
+	"\n" +
+	"PushCancel\x12\x17\n" +
+	"\apush_id\x18\x01 \x01(\tR\x06pushId\"K\n" +
+	"\x0fActivateRequest\x12\x17\n" +
+	"\apush_id\x18\x01 \x01(\tR\x06pushId\x12\x1f\n" +
+	"\vapproved_by\x18\x02 \x01(\tR\n" +
+	"approvedBy\"\xe4\x01\n" +
 	"\x11ResponseAssertion\x124\n" +
 	"\x04type\x18\x01 \x01(\x0e2 .ResponseAssertion.AssertionTypeR\x04type\x12\x1a\n" +
 	"\bexpected\x18\x02 \x01(\tR\bexpected\x12\x17\n" +
@@ -2044,7 +8155,328 @@ const file_ws_proto_rawDesc = "" +
 	"\aUNKNOWN\x10\x00\x12\x11\n" +
 	"\rAUTHENTICATED\x10\x01\x12\x10\n" +
 	"\fUNAUTHORIZED\x10\x02B\x10\n" +
-	"\x0e_error_message\"\x93\x05\n" +
+	"\x0e_error_message\"\x9f\x04\n" +
+	"\x0fConnectionStats\x12C\n" +
+	"\x0fconnected_since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x0econnectedSince\x12'\n" +
+	"\x0freconnect_count\x18\x02 \x01(\x05R\x0ereconnectCount\x12#\n" +
+	"\rmessages_sent\x18\x03 \x01(\x03R\fmessagesSent\x12+\n" +
+	"\x11messages_received\x18\x04 \x01(\x03R\x10messagesReceived\x12\x15\n" +
+	"\x06rtt_ms\x18\x05 \x01(\x03R\x05rttMs\x12&\n" +
+	"\x0frtt_variance_ms\x18\x06 \x01(\x03R\rrttVarianceMs\x120\n" +
+	"\x14reconnects_last_hour\x18\a \x01(\x05R\x12reconnectsLastHour\x12,\n" +
+	"\x12message_latency_ms\x18\b \x01(\x03R\x10messageLatencyMs\x12=\n" +
+	"\x1bthroughput_bytes_per_second\x18\t \x01(\x03R\x18throughputBytesPerSecond\x12(\n" +
+	"\x10read_deadline_ms\x18\n" +
+	" \x01(\x03R\x0ereadDeadlineMs\x12(\n" +
+	"\x10chunk_size_bytes\x18\v \x01(\x05R\x0echunkSizeBytes\x12\x1a\n" +
+	"\bdegraded\x18\f \x01(\bR\bdegraded\"\x93\x05\n" +
+	"\fStatusReport\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12%\n" +
+	"\x0euptime_seconds\x18\x02 \x01(\x03R\ruptimeSeconds\x12 \n" +
+	"\flast_push_id\x18\x03 \x01(\tR\n" +
+	"lastPushId\x12B\n" +
+	"\x0elauncher_state\x18\x04 \x01(\x0e2\x1b.StatusReport.LauncherStateR\rlauncherState\x12!\n" +
+	"\flauncher_pid\x18\x05 \x01(\x05R\vlauncherPid\x12$\n" +
+	"\x0esync_dir_bytes\x18\x06 \x01(\x03R\fsyncDirBytes\x12-\n" +
+	"\x13sync_dir_free_bytes\x18\a \x01(\x03R\x10syncDirFreeBytes\x12;\n" +
+	"\x10connection_stats\x18\b \x01(\v2\x10.ConnectionStatsR\x0fconnectionStats\x12\x1e\n" +
+	"\x03pod\x18\t \x01(\v2\f.PodMetadataR\x03pod\x128\n" +
+	"\x0fworkspace_usage\x18\n" +
+	" \x01(\v2\x0f.WorkspaceUsageR\x0eworkspaceUsage\x12,\n" +
+	"\tresources\x18\v \x01(\v2\x0e.ResourceUsageR\tresources\x122\n" +
+	"\rapp_readiness\x18\f \x01(\v2\r.AppReadinessR\fappReadiness\"K\n" +
+	"\rLauncherState\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aRUNNING\x10\x01\x12\x0f\n" +
+	"\vNOT_RUNNING\x10\x02\x12\x0f\n" +
+	"\vNO_PID_FILE\x10\x03\"{\n" +
+	"\fAppReadiness\x12\x14\n" +
+	"\x05ready\x18\x01 \x01(\bR\x05ready\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12;\n" +
+	"\vreported_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"reportedAt\"\xf3\x02\n" +
+	"\rResourceUsage\x12\x1b\n" +
+	"\trss_bytes\x18\x01 \x01(\x03R\brssBytes\x12\x1d\n" +
+	"\n" +
+	"cpu_millis\x18\x02 \x01(\x03R\tcpuMillis\x12\x1e\n" +
+	"\n" +
+	"goroutines\x18\x03 \x01(\x05R\n" +
+	"goroutines\x120\n" +
+	"\x14buffered_batch_bytes\x18\x04 \x01(\x03R\x12bufferedBatchBytes\x12;\n" +
+	"\x1abuffered_batch_limit_bytes\x18\x05 \x01(\x03R\x17bufferedBatchLimitBytes\x12,\n" +
+	"\x12memory_limit_bytes\x18\x06 \x01(\x03R\x10memoryLimitBytes\x12.\n" +
+	"\x13cgroup_memory_bytes\x18\a \x01(\x03R\x11cgroupMemoryBytes\x129\n" +
+	"\x19cgroup_memory_limit_bytes\x18\b \x01(\x03R\x16cgroupMemoryLimitBytes\"c\n" +
+	"\vPodMetadata\x12\x19\n" +
+	"\bpod_name\x18\x01 \x01(\tR\apodName\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1b\n" +
+	"\tnode_name\x18\x03 \x01(\tR\bnodeName\"\xa4\x01\n" +
+	"\bLogEntry\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x12\n" +
+	"\x04line\x18\x03 \x01(\tR\x04line\x12\x1c\n" +
+	"\ttruncated\x18\x04 \x01(\bR\ttruncated\x12\x14\n" +
+	"\x05level\x18\x05 \x01(\tR\x05level\"/\n" +
+	"\bLogBatch\x12#\n" +
+	"\aentries\x18\x01 \x03(\v2\t.LogEntryR\aentries\"l\n" +
+	"\tShellOpen\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04rows\x18\x02 \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\x03 \x01(\rR\x04cols\x12\x18\n" +
+	"\acommand\x18\x04 \x01(\tR\acommand\">\n" +
+	"\tShellData\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"T\n" +
+	"\vShellResize\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04rows\x18\x02 \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\x03 \x01(\rR\x04cols\"+\n" +
+	"\n" +
+	"ShellClose\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"l\n" +
+	"\tShellExit\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1b\n" +
+	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\xc4\x04\n" +
+	"\x05Hello\x12 \n" +
+	"\flast_push_id\x18\x01 \x01(\tR\n" +
+	"lastPushId\x12$\n" +
+	"\x0elast_push_hash\x18\x02 \x01(\tR\flastPushHash\x12B\n" +
+	"\x0flast_applied_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\rlastAppliedAt\x12'\n" +
+	"\x0fsidecar_version\x18\x04 \x01(\tR\x0esidecarVersion\x12)\n" +
+	"\x10protocol_version\x18\x05 \x01(\x05R\x0fprotocolVersion\x12\x1a\n" +
+	"\bfeatures\x18\x06 \x03(\tR\bfeatures\x12J\n" +
+	"\x11accepted_messages\x18\a \x03(\x0e2\x1d.WebsocketMessage.MessageTypeR\x10acceptedMessages\x12!\n" +
+	"\fresume_token\x18\b \x01(\tR\vresumeToken\x12#\n" +
+	"\rrsync_version\x18\t \x01(\tR\frsyncVersion\x12%\n" +
+	"\x0ersync_protocol\x18\n" +
+	" \x01(\x05R\rrsyncProtocol\x12%\n" +
+	"\x0ecached_batches\x18\v \x03(\tR\rcachedBatches\x12#\n" +
+	"\rlast_sequence\x18\f \x01(\x03R\flastSequence\x128\n" +
+	"\x0fsecurity_module\x18\r \x01(\v2\x0f.SecurityModuleR\x0esecurityModule\"\xd3\x01\n" +
+	"\bHelloAck\x12)\n" +
+	"\x10protocol_version\x18\x01 \x01(\x05R\x0fprotocolVersion\x12%\n" +
+	"\x0eserver_version\x18\x02 \x01(\tR\rserverVersion\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\x12!\n" +
+	"\fresume_token\x18\x04 \x01(\tR\vresumeToken\x126\n" +
+	"\x17unacknowledged_push_ids\x18\x05 \x03(\tR\x15unacknowledgedPushIds\"%\n" +
+	"\x0fSnapshotRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"|\n" +
+	"\fSnapshotInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x02 \x01(\x03R\tsizeBytes\x129\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\x87\x02\n" +
+	"\x10SnapshotResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x120\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x18.SnapshotResponse.StatusR\x06status\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12)\n" +
+	"\bsnapshot\x18\x04 \x01(\v2\r.SnapshotInfoR\bsnapshot\x12+\n" +
+	"\tsnapshots\x18\x05 \x03(\v2\r.SnapshotInfoR\tsnapshots\"0\n" +
+	"\x06Status\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\r\n" +
+	"\tCOMPLETED\x10\x01\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x02\"0\n" +
+	"\x0fManifestRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\x93\x01\n" +
+	"\tFileEntry\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x02 \x01(\x03R\tsizeBytes\x12;\n" +
+	"\vmodified_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"modifiedAt\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\"x\n" +
+	"\x10ManifestResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12 \n" +
+	"\x05files\x18\x02 \x03(\v2\n" +
+	".FileEntryR\x05files\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"W\n" +
+	"\x11SyncStatusRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12#\n" +
+	"\raudit_entries\x18\x02 \x01(\x05R\fauditEntries\"\xc1\x02\n" +
+	"\n" +
+	"AuditEntry\x12\x17\n" +
+	"\apush_id\x18\x01 \x01(\tR\x06pushId\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12#\n" +
+	"\rfiles_changed\x18\x03 \x01(\x05R\ffilesChanged\x12(\n" +
+	"\x10env_keys_changed\x18\x04 \x03(\tR\x0eenvKeysChanged\x122\n" +
+	"\aoutcome\x18\x05 \x01(\x0e2\x18.PushResponse.PushStatusR\aoutcome\x12#\n" +
+	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\x12!\n" +
+	"\ftriggered_by\x18\a \x01(\tR\vtriggeredBy\x12\x1f\n" +
+	"\vapproved_by\x18\b \x01(\tR\n" +
+	"approvedBy\">\n" +
+	"\x0eEnvFileVersion\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\xdb\x02\n" +
+	"\x10EnvVarProvenance\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05scope\x18\x02 \x01(\tR\x05scope\x120\n" +
+	"\x06source\x18\x03 \x01(\x0e2\x18.EnvVarProvenance.SourceR\x06source\x12\x1a\n" +
+	"\bprovider\x18\x04 \x01(\tR\bprovider\x12\x1d\n" +
+	"\n" +
+	"secret_ref\x18\x05 \x01(\tR\tsecretRef\x12\x17\n" +
+	"\apush_id\x18\x06 \x01(\tR\x06pushId\x12\x18\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"B\n" +
+	"\x06Source\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\f\n" +
+	"\bDATABASE\x10\x01\x12\b\n" +
+	"\x04PUSH\x10\x02\x12\x13\n" +
+	"\x0fSECRET_PROVIDER\x10\x03\"\xcf\x04\n" +
+	"\x12SyncStatusResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12 \n" +
+	"\flast_push_id\x18\x02 \x01(\tR\n" +
+	"lastPushId\x12$\n" +
+	"\x0elast_push_hash\x18\x03 \x01(\tR\flastPushHash\x12B\n" +
+	"\x0flast_applied_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\rlastAppliedAt\x12%\n" +
+	"\x0eworkspace_hash\x18\x05 \x01(\tR\rworkspaceHash\x12,\n" +
+	"\tenv_files\x18\x06 \x03(\v2\x0f.EnvFileVersionR\benvFiles\x12B\n" +
+	"\x0elauncher_state\x18\a \x01(\x0e2\x1b.StatusReport.LauncherStateR\rlauncherState\x12!\n" +
+	"\flauncher_pid\x18\b \x01(\x05R\vlauncherPid\x12$\n" +
+	"\x0eactive_push_id\x18\t \x01(\tR\factivePushId\x12#\n" +
+	"\rqueued_pushes\x18\n" +
+	" \x01(\x05R\fqueuedPushes\x12#\n" +
+	"\rerror_message\x18\v \x01(\tR\ferrorMessage\x12(\n" +
+	"\taudit_log\x18\f \x03(\v2\v.AuditEntryR\bauditLog\x128\n" +
+	"\x0eenv_provenance\x18\r \x03(\v2\x11.EnvVarProvenanceR\renvProvenance\"`\n" +
+	"\x0eFileGetRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1b\n" +
+	"\tmax_bytes\x18\x03 \x01(\x03R\bmaxBytes\"\xf3\x01\n" +
+	"\x0fFileGetResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\fR\acontent\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x04 \x01(\x03R\tsizeBytes\x12\x12\n" +
+	"\x04mode\x18\x05 \x01(\rR\x04mode\x12;\n" +
+	"\vmodified_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"modifiedAt\x12#\n" +
+	"\rerror_message\x18\a \x01(\tR\ferrorMessage\"C\n" +
+	"\x0eDirListRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\"\xf8\x01\n" +
+	"\bDirEntry\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\"\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x0e.DirEntry.TypeR\x04type\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12\x12\n" +
+	"\x04mode\x18\x04 \x01(\rR\x04mode\x12;\n" +
+	"\vmodified_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"modifiedAt\"D\n" +
+	"\x04Type\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\b\n" +
+	"\x04FILE\x10\x01\x12\r\n" +
+	"\tDIRECTORY\x10\x02\x12\v\n" +
+	"\aSYMLINK\x10\x03\x12\t\n" +
+	"\x05OTHER\x10\x04\"\xac\x01\n" +
+	"\x0fDirListResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12#\n" +
+	"\aentries\x18\x03 \x03(\v2\t.DirEntryR\aentries\x12\x1c\n" +
+	"\ttruncated\x18\x04 \x01(\bR\ttruncated\x12#\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\"~\n" +
+	"\x0eLogTailRequest\x12\x17\n" +
+	"\atail_id\x18\x01 \x01(\tR\x06tailId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x14\n" +
+	"\x05lines\x18\x03 \x01(\x05R\x05lines\x12)\n" +
+	"\x10duration_seconds\x18\x04 \x01(\x05R\x0fdurationSeconds\"&\n" +
+	"\vLogTailStop\x12\x17\n" +
+	"\atail_id\x18\x01 \x01(\tR\x06tailId\"a\n" +
+	"\vLogTailData\x12\x17\n" +
+	"\atail_id\x18\x01 \x01(\tR\x06tailId\x12\x14\n" +
+	"\x05lines\x18\x02 \x03(\tR\x05lines\x12#\n" +
+	"\rdropped_lines\x18\x03 \x01(\x03R\fdroppedLines\"\xb3\x01\n" +
+	"\n" +
+	"LogTailEnd\x12\x17\n" +
+	"\atail_id\x18\x01 \x01(\tR\x06tailId\x12*\n" +
+	"\x06reason\x18\x02 \x01(\x0e2\x12.LogTailEnd.ReasonR\x06reason\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\";\n" +
+	"\x06Reason\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aSTOPPED\x10\x01\x12\v\n" +
+	"\aEXPIRED\x10\x02\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x03\"c\n" +
+	"\n" +
+	"BatchChunk\x12\x17\n" +
+	"\apush_id\x18\x01 \x01(\tR\x06pushId\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04last\x18\x04 \x01(\bR\x04last\"\x9c\x02\n" +
+	"\rResyncRequest\x12-\n" +
+	"\x06reason\x18\x01 \x01(\x0e2\x15.ResyncRequest.ReasonR\x06reason\x12\x16\n" +
+	"\x06detail\x18\x02 \x01(\tR\x06detail\x12%\n" +
+	"\x0eworkspace_hash\x18\x03 \x01(\tR\rworkspaceHash\x12 \n" +
+	"\flast_push_id\x18\x04 \x01(\tR\n" +
+	"lastPushId\x12#\n" +
+	"\rdrifted_files\x18\x05 \x03(\tR\fdriftedFiles\"V\n" +
+	"\x06Reason\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\t\n" +
+	"\x05DRIFT\x10\x01\x12\x0e\n" +
+	"\n" +
+	"CORRUPTION\x10\x02\x12\x11\n" +
+	"\rMISSING_STATE\x10\x03\x12\x11\n" +
+	"\rAPP_REQUESTED\x10\x04\"M\n" +
+	"\x12EnvRollbackRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\x86\x01\n" +
+	"\n" +
+	"EnvVersion\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\apush_id\x18\x02 \x01(\tR\x06pushId\x129\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x14\n" +
+	"\x05files\x18\x04 \x03(\tR\x05files\"\xb9\x02\n" +
+	"\x13EnvRollbackResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x123\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x1b.EnvRollbackResponse.StatusR\x06status\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12%\n" +
+	"\aversion\x18\x04 \x01(\v2\v.EnvVersionR\aversion\x12'\n" +
+	"\bversions\x18\x05 \x03(\v2\v.EnvVersionR\bversions\x12'\n" +
+	"\x0fcurrent_version\x18\x06 \x01(\tR\x0ecurrentVersion\"0\n" +
+	"\x06Status\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\r\n" +
+	"\tCOMPLETED\x10\x01\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x02\"\xb8\x01\n" +
+	"\x0eLauncherExited\x12\x10\n" +
+	"\x03pid\x18\x01 \x01(\x05R\x03pid\x12;\n" +
+	"\vdetected_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"detectedAt\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"app_status\x18\x04 \x01(\tR\tappStatus\x12 \n" +
+	"\flast_push_id\x18\x05 \x01(\tR\n" +
+	"lastPushId\"3\n" +
+	"\x12DiagnosticsRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\x94\x01\n" +
+	"\x10DiagnosticsChunk\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04last\x18\x04 \x01(\bR\x04last\x12#\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\"\xb7\x19\n" +
 	"\x10WebsocketMessage\x12@\n" +
 	"\fmessage_type\x18\x01 \x01(\x0e2\x1d.WebsocketMessage.MessageTypeR\vmessageType\x121\n" +
 	"\fpush_message\x18\x02 \x01(\v2\f.PushMessageH\x00R\vpushMessage\x124\n" +
@@ -2052,7 +8484,51 @@ const file_ws_proto_rawDesc = "" +
 	"\x15verification_progress\x18\x04 \x01(\v2\x1c.VerificationProgressMessageH\x00R\x14verificationProgress\x12e\n" +
 	"\x1everification_progress_response\x18\x05 \x01(\v2\x1d.VerificationProgressResponseH\x00R\x1cverificationProgressResponse\x121\n" +
 	"\fauth_message\x18\x06 \x01(\v2\f.AuthMessageH\x00R\vauthMessage\x124\n" +
-	"\rauth_response\x18\a \x01(\v2\r.AuthResponseH\x00R\fauthResponse\"\xa3\x01\n" +
+	"\rauth_response\x18\a \x01(\v2\r.AuthResponseH\x00R\fauthResponse\x124\n" +
+	"\rstatus_report\x18\b \x01(\v2\r.StatusReportH\x00R\fstatusReport\x12(\n" +
+	"\tlog_batch\x18\t \x01(\v2\t.LogBatchH\x00R\blogBatch\x12+\n" +
+	"\n" +
+	"shell_open\x18\n" +
+	" \x01(\v2\n" +
+	".ShellOpenH\x00R\tshellOpen\x12+\n" +
+	"\n" +
+	"shell_data\x18\v \x01(\v2\n" +
+	".ShellDataH\x00R\tshellData\x121\n" +
+	"\fshell_resize\x18\f \x01(\v2\f.ShellResizeH\x00R\vshellResize\x12.\n" +
+	"\vshell_close\x18\r \x01(\v2\v.ShellCloseH\x00R\n" +
+	"shellClose\x12+\n" +
+	"\n" +
+	"shell_exit\x18\x0e \x01(\v2\n" +
+	".ShellExitH\x00R\tshellExit\x12.\n" +
+	"\vpush_cancel\x18\x0f \x01(\v2\v.PushCancelH\x00R\n" +
+	"pushCancel\x124\n" +
+	"\rpush_progress\x18\x10 \x01(\v2\r.PushProgressH\x00R\fpushProgress\x12\x1e\n" +
+	"\x05hello\x18\x11 \x01(\v2\x06.HelloH\x00R\x05hello\x12=\n" +
+	"\x10snapshot_request\x18\x12 \x01(\v2\x10.SnapshotRequestH\x00R\x0fsnapshotRequest\x12@\n" +
+	"\x11snapshot_response\x18\x13 \x01(\v2\x11.SnapshotResponseH\x00R\x10snapshotResponse\x12=\n" +
+	"\x10manifest_request\x18\x14 \x01(\v2\x10.ManifestRequestH\x00R\x0fmanifestRequest\x12@\n" +
+	"\x11manifest_response\x18\x15 \x01(\v2\x11.ManifestResponseH\x00R\x10manifestResponse\x12:\n" +
+	"\x0flauncher_exited\x18\x16 \x01(\v2\x0f.LauncherExitedH\x00R\x0elauncherExited\x12(\n" +
+	"\thello_ack\x18\x17 \x01(\v2\t.HelloAckH\x00R\bhelloAck\x12F\n" +
+	"\x13diagnostics_request\x18\x18 \x01(\v2\x13.DiagnosticsRequestH\x00R\x12diagnosticsRequest\x12@\n" +
+	"\x11diagnostics_chunk\x18\x19 \x01(\v2\x11.DiagnosticsChunkH\x00R\x10diagnosticsChunk\x12D\n" +
+	"\x13sync_status_request\x18\x1a \x01(\v2\x12.SyncStatusRequestH\x00R\x11syncStatusRequest\x12G\n" +
+	"\x14sync_status_response\x18\x1b \x01(\v2\x13.SyncStatusResponseH\x00R\x12syncStatusResponse\x12;\n" +
+	"\x10file_get_request\x18\x1c \x01(\v2\x0f.FileGetRequestH\x00R\x0efileGetRequest\x12>\n" +
+	"\x11file_get_response\x18\x1d \x01(\v2\x10.FileGetResponseH\x00R\x0ffileGetResponse\x12;\n" +
+	"\x10dir_list_request\x18\x1e \x01(\v2\x0f.DirListRequestH\x00R\x0edirListRequest\x12>\n" +
+	"\x11dir_list_response\x18\x1f \x01(\v2\x10.DirListResponseH\x00R\x0fdirListResponse\x12;\n" +
+	"\x10log_tail_request\x18  \x01(\v2\x0f.LogTailRequestH\x00R\x0elogTailRequest\x122\n" +
+	"\rlog_tail_stop\x18! \x01(\v2\f.LogTailStopH\x00R\vlogTailStop\x122\n" +
+	"\rlog_tail_data\x18\" \x01(\v2\f.LogTailDataH\x00R\vlogTailData\x12/\n" +
+	"\flog_tail_end\x18# \x01(\v2\v.LogTailEndH\x00R\n" +
+	"logTailEnd\x12.\n" +
+	"\vbatch_chunk\x18$ \x01(\v2\v.BatchChunkH\x00R\n" +
+	"batchChunk\x127\n" +
+	"\x0eresync_request\x18% \x01(\v2\x0e.ResyncRequestH\x00R\rresyncRequest\x12G\n" +
+	"\x14env_rollback_request\x18& \x01(\v2\x13.EnvRollbackRequestH\x00R\x12envRollbackRequest\x12J\n" +
+	"\x15env_rollback_response\x18' \x01(\v2\x14.EnvRollbackResponseH\x00R\x13envRollbackResponse\x12=\n" +
+	"\x10activate_request\x18( \x01(\v2\x10.ActivateRequestH\x00R\x0factivateRequest\"\xe9\x06\n" +
 	"\vMessageType\x12\v\n" +
 	"\aUNKNOWN\x10\x00\x12\x10\n" +
 	"\fPUSH_REQUEST\x10\x01\x12\x11\n" +
@@ -2060,8 +8536,49 @@ const file_ws_proto_rawDesc = "" +
 	"\x15VERIFICATION_PROGRESS\x10\x03\x12\"\n" +
 	"\x1eVERIFICATION_PROGRESS_RESPONSE\x10\x04\x12\x10\n" +
 	"\fAUTH_REQUEST\x10\x05\x12\x11\n" +
-	"\rAUTH_RESPONSE\x10\x06B\t\n" +
-	"\amessageB:Z8github.com/bifrostinc/code-sync-mcp/code-sync-sidecar/pbb\x06proto3"
+	"\rAUTH_RESPONSE\x10\x06\x12\x11\n" +
+	"\rSTATUS_REPORT\x10\a\x12\r\n" +
+	"\tLOG_ENTRY\x10\b\x12\x0e\n" +
+	"\n" +
+	"SHELL_OPEN\x10\t\x12\x0f\n" +
+	"\vSHELL_STDIN\x10\n" +
+	"\x12\x10\n" +
+	"\fSHELL_STDOUT\x10\v\x12\x10\n" +
+	"\fSHELL_RESIZE\x10\f\x12\x0f\n" +
+	"\vSHELL_CLOSE\x10\r\x12\x0e\n" +
+	"\n" +
+	"SHELL_EXIT\x10\x0e\x12\x0f\n" +
+	"\vPUSH_CANCEL\x10\x0f\x12\x11\n" +
+	"\rPUSH_PROGRESS\x10\x10\x12\x0f\n" +
+	"\vSIDECAR_LOG\x10\x11\x12\t\n" +
+	"\x05HELLO\x10\x12\x12\x13\n" +
+	"\x0fSNAPSHOT_CREATE\x10\x13\x12\x14\n" +
+	"\x10SNAPSHOT_RESTORE\x10\x14\x12\x15\n" +
+	"\x11SNAPSHOT_RESPONSE\x10\x15\x12\x14\n" +
+	"\x10MANIFEST_REQUEST\x10\x16\x12\x15\n" +
+	"\x11MANIFEST_RESPONSE\x10\x17\x12\x13\n" +
+	"\x0fLAUNCHER_EXITED\x10\x18\x12\r\n" +
+	"\tHELLO_ACK\x10\x19\x12\x17\n" +
+	"\x13DIAGNOSTICS_REQUEST\x10\x1a\x12\x15\n" +
+	"\x11DIAGNOSTICS_CHUNK\x10\x1b\x12\x17\n" +
+	"\x13SYNC_STATUS_REQUEST\x10\x1c\x12\x18\n" +
+	"\x14SYNC_STATUS_RESPONSE\x10\x1d\x12\x14\n" +
+	"\x10FILE_GET_REQUEST\x10\x1e\x12\x15\n" +
+	"\x11FILE_GET_RESPONSE\x10\x1f\x12\x14\n" +
+	"\x10DIR_LIST_REQUEST\x10 \x12\x15\n" +
+	"\x11DIR_LIST_RESPONSE\x10!\x12\x14\n" +
+	"\x10LOG_TAIL_REQUEST\x10\"\x12\x11\n" +
+	"\rLOG_TAIL_STOP\x10#\x12\x11\n" +
+	"\rLOG_TAIL_DATA\x10$\x12\x10\n" +
+	"\fLOG_TAIL_END\x10%\x12\x0f\n" +
+	"\vBATCH_CHUNK\x10&\x12\x12\n" +
+	"\x0eRESYNC_REQUEST\x10'\x12\x10\n" +
+	"\fENV_ROLLBACK\x10(\x12\x19\n" +
+	"\x15ENV_ROLLBACK_RESPONSE\x10)\x12\f\n" +
+	"\bACTIVATE\x10*B\t\n" +
+	"\amessage\"=\n" +
+	"\fMessageBatch\x12-\n" +
+	"\bmessages\x18\x01 \x03(\v2\x11.WebsocketMessageR\bmessagesB:Z8github.com/bifrostinc/code-sync-mcp/code-sync-sidecar/pbb\x06proto3"
 
 var (
 	file_ws_proto_rawDescOnce sync.Once
@@ -2075,76 +8592,240 @@ func file_ws_proto_rawDescGZIP() []byte {
 	return file_ws_proto_rawDescData
 }
 
-var file_ws_proto_enumTypes = make([]protoimpl.EnumInfo, 9)
-var file_ws_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_ws_proto_enumTypes = make([]protoimpl.EnumInfo, 18)
+var file_ws_proto_msgTypes = make([]protoimpl.MessageInfo, 80)
 var file_ws_proto_goTypes = []any{
-	(PushResponse_PushStatus)(0),                         // 0: PushResponse.PushStatus
-	(ResponseAssertion_AssertionType)(0),                 // 1: ResponseAssertion.AssertionType
-	(VariableExtraction_SourceType)(0),                   // 2: VariableExtraction.SourceType
-	(HTTPRequestStep_HttpMethod)(0),                      // 3: HTTPRequestStep.HttpMethod
-	(TestResult_TestStatus)(0),                           // 4: TestResult.TestStatus
-	(VerificationProgressMessage_VerificationStage)(0),   // 5: VerificationProgressMessage.VerificationStage
-	(VerificationProgressResponse_VerificationStatus)(0), // 6: VerificationProgressResponse.VerificationStatus
-	(AuthResponse_AuthStatus)(0),                         // 7: AuthResponse.AuthStatus
-	(WebsocketMessage_MessageType)(0),                    // 8: WebsocketMessage.MessageType
-	(*DatabaseBranchUpdate)(nil),                         // 9: DatabaseBranchUpdate
-	(*PushMessage)(nil),                                  // 10: PushMessage
-	(*PushResponse)(nil),                                 // 11: PushResponse
-	(*ResponseAssertion)(nil),                            // 12: ResponseAssertion
-	(*VariableExtraction)(nil),                           // 13: VariableExtraction
-	(*HTTPRequestStep)(nil),                              // 14: HTTPRequestStep
-	(*HttpTest)(nil),                                     // 15: HttpTest
-	(*BrowserTest)(nil),                                  // 16: BrowserTest
-	(*TestResult)(nil),                                   // 17: TestResult
-	(*ClaudeMetadata)(nil),                               // 18: ClaudeMetadata
-	(*TestLog)(nil),                                      // 19: TestLog
-	(*TestInfo)(nil),                                     // 20: TestInfo
-	(*VerificationProgressMessage)(nil),                  // 21: VerificationProgressMessage
-	(*VerificationProgressResponse)(nil),                 // 22: VerificationProgressResponse
-	(*AuthMessage)(nil),                                  // 23: AuthMessage
-	(*AuthResponse)(nil),                                 // 24: AuthResponse
-	(*WebsocketMessage)(nil),                             // 25: WebsocketMessage
-	nil,                                                  // 26: HTTPRequestStep.HeadersEntry
-	nil,                                                  // 27: HttpTest.InitialVariablesEntry
-	(*timestamppb.Timestamp)(nil),                        // 28: google.protobuf.Timestamp
+	(DeletedPathResult_Status)(0),                        // 0: DeletedPathResult.Status
+	(PushResponse_PushStatus)(0),                         // 1: PushResponse.PushStatus
+	(PushProgress_Stage)(0),                              // 2: PushProgress.Stage
+	(ResponseAssertion_AssertionType)(0),                 // 3: ResponseAssertion.AssertionType
+	(VariableExtraction_SourceType)(0),                   // 4: VariableExtraction.SourceType
+	(HTTPRequestStep_HttpMethod)(0),                      // 5: HTTPRequestStep.HttpMethod
+	(TestResult_TestStatus)(0),                           // 6: TestResult.TestStatus
+	(VerificationProgressMessage_VerificationStage)(0),   // 7: VerificationProgressMessage.VerificationStage
+	(VerificationProgressResponse_VerificationStatus)(0), // 8: VerificationProgressResponse.VerificationStatus
+	(AuthResponse_AuthStatus)(0),                         // 9: AuthResponse.AuthStatus
+	(StatusReport_LauncherState)(0),                      // 10: StatusReport.LauncherState
+	(SnapshotResponse_Status)(0),                         // 11: SnapshotResponse.Status
+	(EnvVarProvenance_Source)(0),                         // 12: EnvVarProvenance.Source
+	(DirEntry_Type)(0),                                   // 13: DirEntry.Type
+	(LogTailEnd_Reason)(0),                               // 14: LogTailEnd.Reason
+	(ResyncRequest_Reason)(0),                            // 15: ResyncRequest.Reason
+	(EnvRollbackResponse_Status)(0),                      // 16: EnvRollbackResponse.Status
+	(WebsocketMessage_MessageType)(0),                    // 17: WebsocketMessage.MessageType
+	(*DatabaseBranchUpdate)(nil),                         // 18: DatabaseBranchUpdate
+	(*PushMessage)(nil),                                  // 19: PushMessage
+	(*SubtreeBatch)(nil),                                 // 20: SubtreeBatch
+	(*ActivationStage)(nil),                              // 21: ActivationStage
+	(*InjectedFile)(nil),                                 // 22: InjectedFile
+	(*InjectedFileResult)(nil),                           // 23: InjectedFileResult
+	(*DeletedPathResult)(nil),                            // 24: DeletedPathResult
+	(*HookResult)(nil),                                   // 25: HookResult
+	(*PushResponse)(nil),                                 // 26: PushResponse
+	(*SecurityDenial)(nil),                               // 27: SecurityDenial
+	(*SecurityModule)(nil),                               // 28: SecurityModule
+	(*SubtreeResult)(nil),                                // 29: SubtreeResult
+	(*SequenceGap)(nil),                                  // 30: SequenceGap
+	(*LauncherSignalResult)(nil),                         // 31: LauncherSignalResult
+	(*WorkspaceUsage)(nil),                               // 32: WorkspaceUsage
+	(*PushTiming)(nil),                                   // 33: PushTiming
+	(*ReplicaResult)(nil),                                // 34: ReplicaResult
+	(*PushProgress)(nil),                                 // 35: PushProgress
+	(*PushCancel)(nil),                                   // 36: PushCancel
+	(*ActivateRequest)(nil),                              // 37: ActivateRequest
+	(*ResponseAssertion)(nil),                            // 38: ResponseAssertion
+	(*VariableExtraction)(nil),                           // 39: VariableExtraction
+	(*HTTPRequestStep)(nil),                              // 40: HTTPRequestStep
+	(*HttpTest)(nil),                                     // 41: HttpTest
+	(*BrowserTest)(nil),                                  // 42: BrowserTest
+	(*TestResult)(nil),                                   // 43: TestResult
+	(*ClaudeMetadata)(nil),                               // 44: ClaudeMetadata
+	(*TestLog)(nil),                                      // 45: TestLog
+	(*TestInfo)(nil),                                     // 46: TestInfo
+	(*VerificationProgressMessage)(nil),                  // 47: VerificationProgressMessage
+	(*VerificationProgressResponse)(nil),                 // 48: VerificationProgressResponse
+	(*AuthMessage)(nil),                                  // 49: AuthMessage
+	(*AuthResponse)(nil),                                 // 50: AuthResponse
+	(*ConnectionStats)(nil),                              // 51: ConnectionStats
+	(*StatusReport)(nil),                                 // 52: StatusReport
+	(*AppReadiness)(nil),                                 // 53: AppReadiness
+	(*ResourceUsage)(nil),                                // 54: ResourceUsage
+	(*PodMetadata)(nil),                                  // 55: PodMetadata
+	(*LogEntry)(nil),                                     // 56: LogEntry
+	(*LogBatch)(nil),                                     // 57: LogBatch
+	(*ShellOpen)(nil),                                    // 58: ShellOpen
+	(*ShellData)(nil),                                    // 59: ShellData
+	(*ShellResize)(nil),                                  // 60: ShellResize
+	(*ShellClose)(nil),                                   // 61: ShellClose
+	(*ShellExit)(nil),                                    // 62: ShellExit
+	(*Hello)(nil),                                        // 63: Hello
+	(*HelloAck)(nil),                                     // 64: HelloAck
+	(*SnapshotRequest)(nil),                              // 65: SnapshotRequest
+	(*SnapshotInfo)(nil),                                 // 66: SnapshotInfo
+	(*SnapshotResponse)(nil),                             // 67: SnapshotResponse
+	(*ManifestRequest)(nil),                              // 68: ManifestRequest
+	(*FileEntry)(nil),                                    // 69: FileEntry
+	(*ManifestResponse)(nil),                             // 70: ManifestResponse
+	(*SyncStatusRequest)(nil),                            // 71: SyncStatusRequest
+	(*AuditEntry)(nil),                                   // 72: AuditEntry
+	(*EnvFileVersion)(nil),                               // 73: EnvFileVersion
+	(*EnvVarProvenance)(nil),                             // 74: EnvVarProvenance
+	(*SyncStatusResponse)(nil),                           // 75: SyncStatusResponse
+	(*FileGetRequest)(nil),                               // 76: FileGetRequest
+	(*FileGetResponse)(nil),                              // 77: FileGetResponse
+	(*DirListRequest)(nil),                               // 78: DirListRequest
+	(*DirEntry)(nil),                                     // 79: DirEntry
+	(*DirListResponse)(nil),                              // 80: DirListResponse
+	(*LogTailRequest)(nil),                               // 81: LogTailRequest
+	(*LogTailStop)(nil),                                  // 82: LogTailStop
+	(*LogTailData)(nil),                                  // 83: LogTailData
+	(*LogTailEnd)(nil),                                   // 84: LogTailEnd
+	(*BatchChunk)(nil),                                   // 85: BatchChunk
+	(*ResyncRequest)(nil),                                // 86: ResyncRequest
+	(*EnvRollbackRequest)(nil),                           // 87: EnvRollbackRequest
+	(*EnvVersion)(nil),                                   // 88: EnvVersion
+	(*EnvRollbackResponse)(nil),                          // 89: EnvRollbackResponse
+	(*LauncherExited)(nil),                               // 90: LauncherExited
+	(*DiagnosticsRequest)(nil),                           // 91: DiagnosticsRequest
+	(*DiagnosticsChunk)(nil),                             // 92: DiagnosticsChunk
+	(*WebsocketMessage)(nil),                             // 93: WebsocketMessage
+	(*MessageBatch)(nil),                                 // 94: MessageBatch
+	nil,                                                  // 95: PushMessage.FilesEntry
+	nil,                                                  // 96: HTTPRequestStep.HeadersEntry
+	nil,                                                  // 97: HttpTest.InitialVariablesEntry
+	(*timestamppb.Timestamp)(nil),                        // 98: google.protobuf.Timestamp
 }
 var file_ws_proto_depIdxs = []int32{
-	9,  // 0: PushMessage.database_branch_updates:type_name -> DatabaseBranchUpdate
-	0,  // 1: PushResponse.status:type_name -> PushResponse.PushStatus
-	1,  // 2: ResponseAssertion.type:type_name -> ResponseAssertion.AssertionType
-	2,  // 3: VariableExtraction.source:type_name -> VariableExtraction.SourceType
-	3,  // 4: HTTPRequestStep.method:type_name -> HTTPRequestStep.HttpMethod
-	26, // 5: HTTPRequestStep.headers:type_name -> HTTPRequestStep.HeadersEntry
-	13, // 6: HTTPRequestStep.extract_variables:type_name -> VariableExtraction
-	12, // 7: HTTPRequestStep.assertions:type_name -> ResponseAssertion
-	14, // 8: HttpTest.steps:type_name -> HTTPRequestStep
-	27, // 9: HttpTest.initial_variables:type_name -> HttpTest.InitialVariablesEntry
-	4,  // 10: TestResult.status:type_name -> TestResult.TestStatus
-	28, // 11: TestResult.timestamp:type_name -> google.protobuf.Timestamp
-	28, // 12: TestLog.timestamp:type_name -> google.protobuf.Timestamp
-	15, // 13: TestInfo.http_test:type_name -> HttpTest
-	16, // 14: TestInfo.browser_test:type_name -> BrowserTest
-	5,  // 15: VerificationProgressMessage.stage:type_name -> VerificationProgressMessage.VerificationStage
-	20, // 16: VerificationProgressMessage.tests:type_name -> TestInfo
-	17, // 17: VerificationProgressMessage.test_results:type_name -> TestResult
-	28, // 18: VerificationProgressMessage.started_at:type_name -> google.protobuf.Timestamp
-	28, // 19: VerificationProgressMessage.completed_at:type_name -> google.protobuf.Timestamp
-	18, // 20: VerificationProgressMessage.claude_metadata:type_name -> ClaudeMetadata
-	19, // 21: VerificationProgressMessage.test_logs:type_name -> TestLog
-	6,  // 22: VerificationProgressResponse.status:type_name -> VerificationProgressResponse.VerificationStatus
-	7,  // 23: AuthResponse.status:type_name -> AuthResponse.AuthStatus
-	8,  // 24: WebsocketMessage.message_type:type_name -> WebsocketMessage.MessageType
-	10, // 25: WebsocketMessage.push_message:type_name -> PushMessage
-	11, // 26: WebsocketMessage.push_response:type_name -> PushResponse
-	21, // 27: WebsocketMessage.verification_progress:type_name -> VerificationProgressMessage
-	22, // 28: WebsocketMessage.verification_progress_response:type_name -> VerificationProgressResponse
-	23, // 29: WebsocketMessage.auth_message:type_name -> AuthMessage
-	24, // 30: WebsocketMessage.auth_response:type_name -> AuthResponse
-	31, // [31:31] is the sub-list for method output_type
-	31, // [31:31] is the sub-list for method input_type
-	31, // [31:31] is the sub-list for extension type_name
-	31, // [31:31] is the sub-list for extension extendee
-	0,  // [0:31] is the sub-list for field type_name
+	18,  // 0: PushMessage.database_branch_updates:type_name -> DatabaseBranchUpdate
+	95,  // 1: PushMessage.files:type_name -> PushMessage.FilesEntry
+	98,  // 2: PushMessage.not_before:type_name -> google.protobuf.Timestamp
+	21,  // 3: PushMessage.activation_plan:type_name -> ActivationStage
+	20,  // 4: PushMessage.subtree_batches:type_name -> SubtreeBatch
+	0,   // 5: DeletedPathResult.status:type_name -> DeletedPathResult.Status
+	1,   // 6: PushResponse.status:type_name -> PushResponse.PushStatus
+	25,  // 7: PushResponse.hook_results:type_name -> HookResult
+	23,  // 8: PushResponse.injected_files:type_name -> InjectedFileResult
+	24,  // 9: PushResponse.deleted_paths:type_name -> DeletedPathResult
+	55,  // 10: PushResponse.pod:type_name -> PodMetadata
+	34,  // 11: PushResponse.replica_results:type_name -> ReplicaResult
+	33,  // 12: PushResponse.timing:type_name -> PushTiming
+	32,  // 13: PushResponse.workspace_usage:type_name -> WorkspaceUsage
+	31,  // 14: PushResponse.launcher_results:type_name -> LauncherSignalResult
+	98,  // 15: PushResponse.held_until:type_name -> google.protobuf.Timestamp
+	30,  // 16: PushResponse.sequence_gap:type_name -> SequenceGap
+	29,  // 17: PushResponse.subtree_results:type_name -> SubtreeResult
+	27,  // 18: PushResponse.security_denials:type_name -> SecurityDenial
+	1,   // 19: ReplicaResult.status:type_name -> PushResponse.PushStatus
+	2,   // 20: PushProgress.stage:type_name -> PushProgress.Stage
+	3,   // 21: ResponseAssertion.type:type_name -> ResponseAssertion.AssertionType
+	4,   // 22: VariableExtraction.source:type_name -> VariableExtraction.SourceType
+	5,   // 23: HTTPRequestStep.method:type_name -> HTTPRequestStep.HttpMethod
+	96,  // 24: HTTPRequestStep.headers:type_name -> HTTPRequestStep.HeadersEntry
+	39,  // 25: HTTPRequestStep.extract_variables:type_name -> VariableExtraction
+	38,  // 26: HTTPRequestStep.assertions:type_name -> ResponseAssertion
+	40,  // 27: HttpTest.steps:type_name -> HTTPRequestStep
+	97,  // 28: HttpTest.initial_variables:type_name -> HttpTest.InitialVariablesEntry
+	6,   // 29: TestResult.status:type_name -> TestResult.TestStatus
+	98,  // 30: TestResult.timestamp:type_name -> google.protobuf.Timestamp
+	98,  // 31: TestLog.timestamp:type_name -> google.protobuf.Timestamp
+	41,  // 32: TestInfo.http_test:type_name -> HttpTest
+	42,  // 33: TestInfo.browser_test:type_name -> BrowserTest
+	7,   // 34: VerificationProgressMessage.stage:type_name -> VerificationProgressMessage.VerificationStage
+	46,  // 35: VerificationProgressMessage.tests:type_name -> TestInfo
+	43,  // 36: VerificationProgressMessage.test_results:type_name -> TestResult
+	98,  // 37: VerificationProgressMessage.started_at:type_name -> google.protobuf.Timestamp
+	98,  // 38: VerificationProgressMessage.completed_at:type_name -> google.protobuf.Timestamp
+	44,  // 39: VerificationProgressMessage.claude_metadata:type_name -> ClaudeMetadata
+	45,  // 40: VerificationProgressMessage.test_logs:type_name -> TestLog
+	8,   // 41: VerificationProgressResponse.status:type_name -> VerificationProgressResponse.VerificationStatus
+	9,   // 42: AuthResponse.status:type_name -> AuthResponse.AuthStatus
+	98,  // 43: ConnectionStats.connected_since:type_name -> google.protobuf.Timestamp
+	98,  // 44: StatusReport.timestamp:type_name -> google.protobuf.Timestamp
+	10,  // 45: StatusReport.launcher_state:type_name -> StatusReport.LauncherState
+	51,  // 46: StatusReport.connection_stats:type_name -> ConnectionStats
+	55,  // 47: StatusReport.pod:type_name -> PodMetadata
+	32,  // 48: StatusReport.workspace_usage:type_name -> WorkspaceUsage
+	54,  // 49: StatusReport.resources:type_name -> ResourceUsage
+	53,  // 50: StatusReport.app_readiness:type_name -> AppReadiness
+	98,  // 51: AppReadiness.reported_at:type_name -> google.protobuf.Timestamp
+	98,  // 52: LogEntry.timestamp:type_name -> google.protobuf.Timestamp
+	56,  // 53: LogBatch.entries:type_name -> LogEntry
+	98,  // 54: Hello.last_applied_at:type_name -> google.protobuf.Timestamp
+	17,  // 55: Hello.accepted_messages:type_name -> WebsocketMessage.MessageType
+	28,  // 56: Hello.security_module:type_name -> SecurityModule
+	98,  // 57: SnapshotInfo.created_at:type_name -> google.protobuf.Timestamp
+	11,  // 58: SnapshotResponse.status:type_name -> SnapshotResponse.Status
+	66,  // 59: SnapshotResponse.snapshot:type_name -> SnapshotInfo
+	66,  // 60: SnapshotResponse.snapshots:type_name -> SnapshotInfo
+	98,  // 61: FileEntry.modified_at:type_name -> google.protobuf.Timestamp
+	69,  // 62: ManifestResponse.files:type_name -> FileEntry
+	98,  // 63: AuditEntry.time:type_name -> google.protobuf.Timestamp
+	1,   // 64: AuditEntry.outcome:type_name -> PushResponse.PushStatus
+	12,  // 65: EnvVarProvenance.source:type_name -> EnvVarProvenance.Source
+	98,  // 66: EnvVarProvenance.updated_at:type_name -> google.protobuf.Timestamp
+	98,  // 67: SyncStatusResponse.last_applied_at:type_name -> google.protobuf.Timestamp
+	73,  // 68: SyncStatusResponse.env_files:type_name -> EnvFileVersion
+	10,  // 69: SyncStatusResponse.launcher_state:type_name -> StatusReport.LauncherState
+	72,  // 70: SyncStatusResponse.audit_log:type_name -> AuditEntry
+	74,  // 71: SyncStatusResponse.env_provenance:type_name -> EnvVarProvenance
+	98,  // 72: FileGetResponse.modified_at:type_name -> google.protobuf.Timestamp
+	13,  // 73: DirEntry.type:type_name -> DirEntry.Type
+	98,  // 74: DirEntry.modified_at:type_name -> google.protobuf.Timestamp
+	79,  // 75: DirListResponse.entries:type_name -> DirEntry
+	14,  // 76: LogTailEnd.reason:type_name -> LogTailEnd.Reason
+	15,  // 77: ResyncRequest.reason:type_name -> ResyncRequest.Reason
+	98,  // 78: EnvVersion.created_at:type_name -> google.protobuf.Timestamp
+	16,  // 79: EnvRollbackResponse.status:type_name -> EnvRollbackResponse.Status
+	88,  // 80: EnvRollbackResponse.version:type_name -> EnvVersion
+	88,  // 81: EnvRollbackResponse.versions:type_name -> EnvVersion
+	98,  // 82: LauncherExited.detected_at:type_name -> google.protobuf.Timestamp
+	17,  // 83: WebsocketMessage.message_type:type_name -> WebsocketMessage.MessageType
+	19,  // 84: WebsocketMessage.push_message:type_name -> PushMessage
+	26,  // 85: WebsocketMessage.push_response:type_name -> PushResponse
+	47,  // 86: WebsocketMessage.verification_progress:type_name -> VerificationProgressMessage
+	48,  // 87: WebsocketMessage.verification_progress_response:type_name -> VerificationProgressResponse
+	49,  // 88: WebsocketMessage.auth_message:type_name -> AuthMessage
+	50,  // 89: WebsocketMessage.auth_response:type_name -> AuthResponse
+	52,  // 90: WebsocketMessage.status_report:type_name -> StatusReport
+	57,  // 91: WebsocketMessage.log_batch:type_name -> LogBatch
+	58,  // 92: WebsocketMessage.shell_open:type_name -> ShellOpen
+	59,  // 93: WebsocketMessage.shell_data:type_name -> ShellData
+	60,  // 94: WebsocketMessage.shell_resize:type_name -> ShellResize
+	61,  // 95: WebsocketMessage.shell_close:type_name -> ShellClose
+	62,  // 96: WebsocketMessage.shell_exit:type_name -> ShellExit
+	36,  // 97: WebsocketMessage.push_cancel:type_name -> PushCancel
+	35,  // 98: WebsocketMessage.push_progress:type_name -> PushProgress
+	63,  // 99: WebsocketMessage.hello:type_name -> Hello
+	65,  // 100: WebsocketMessage.snapshot_request:type_name -> SnapshotRequest
+	67,  // 101: WebsocketMessage.snapshot_response:type_name -> SnapshotResponse
+	68,  // 102: WebsocketMessage.manifest_request:type_name -> ManifestRequest
+	70,  // 103: WebsocketMessage.manifest_response:type_name -> ManifestResponse
+	90,  // 104: WebsocketMessage.launcher_exited:type_name -> LauncherExited
+	64,  // 105: WebsocketMessage.hello_ack:type_name -> HelloAck
+	91,  // 106: WebsocketMessage.diagnostics_request:type_name -> DiagnosticsRequest
+	92,  // 107: WebsocketMessage.diagnostics_chunk:type_name -> DiagnosticsChunk
+	71,  // 108: WebsocketMessage.sync_status_request:type_name -> SyncStatusRequest
+	75,  // 109: WebsocketMessage.sync_status_response:type_name -> SyncStatusResponse
+	76,  // 110: WebsocketMessage.file_get_request:type_name -> FileGetRequest
+	77,  // 111: WebsocketMessage.file_get_response:type_name -> FileGetResponse
+	78,  // 112: WebsocketMessage.dir_list_request:type_name -> DirListRequest
+	80,  // 113: WebsocketMessage.dir_list_response:type_name -> DirListResponse
+	81,  // 114: WebsocketMessage.log_tail_request:type_name -> LogTailRequest
+	82,  // 115: WebsocketMessage.log_tail_stop:type_name -> LogTailStop
+	83,  // 116: WebsocketMessage.log_tail_data:type_name -> LogTailData
+	84,  // 117: WebsocketMessage.log_tail_end:type_name -> LogTailEnd
+	85,  // 118: WebsocketMessage.batch_chunk:type_name -> BatchChunk
+	86,  // 119: WebsocketMessage.resync_request:type_name -> ResyncRequest
+	87,  // 120: WebsocketMessage.env_rollback_request:type_name -> EnvRollbackRequest
+	89,  // 121: WebsocketMessage.env_rollback_response:type_name -> EnvRollbackResponse
+	37,  // 122: WebsocketMessage.activate_request:type_name -> ActivateRequest
+	93,  // 123: MessageBatch.messages:type_name -> WebsocketMessage
+	22,  // 124: PushMessage.FilesEntry.value:type_name -> InjectedFile
+	125, // [125:125] is the sub-list for method output_type
+	125, // [125:125] is the sub-list for method input_type
+	125, // [125:125] is the sub-list for extension type_name
+	125, // [125:125] is the sub-list for extension extendee
+	0,   // [0:125] is the sub-list for field type_name
 }
 
 func init() { file_ws_proto_init() }
@@ -2152,32 +8833,65 @@ func file_ws_proto_init() {
 	if File_ws_proto != nil {
 		return
 	}
-	file_ws_proto_msgTypes[3].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[4].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[5].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[8].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[11].OneofWrappers = []any{
+	file_ws_proto_msgTypes[20].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[21].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[22].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[25].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[28].OneofWrappers = []any{
 		(*TestInfo_HttpTest)(nil),
 		(*TestInfo_BrowserTest)(nil),
 	}
-	file_ws_proto_msgTypes[12].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[13].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[15].OneofWrappers = []any{}
-	file_ws_proto_msgTypes[16].OneofWrappers = []any{
+	file_ws_proto_msgTypes[29].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[30].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[32].OneofWrappers = []any{}
+	file_ws_proto_msgTypes[75].OneofWrappers = []any{
 		(*WebsocketMessage_PushMessage)(nil),
 		(*WebsocketMessage_PushResponse)(nil),
 		(*WebsocketMessage_VerificationProgress)(nil),
 		(*WebsocketMessage_VerificationProgressResponse)(nil),
 		(*WebsocketMessage_AuthMessage)(nil),
 		(*WebsocketMessage_AuthResponse)(nil),
+		(*WebsocketMessage_StatusReport)(nil),
+		(*WebsocketMessage_LogBatch)(nil),
+		(*WebsocketMessage_ShellOpen)(nil),
+		(*WebsocketMessage_ShellData)(nil),
+		(*WebsocketMessage_ShellResize)(nil),
+		(*WebsocketMessage_ShellClose)(nil),
+		(*WebsocketMessage_ShellExit)(nil),
+		(*WebsocketMessage_PushCancel)(nil),
+		(*WebsocketMessage_PushProgress)(nil),
+		(*WebsocketMessage_Hello)(nil),
+		(*WebsocketMessage_SnapshotRequest)(nil),
+		(*WebsocketMessage_SnapshotResponse)(nil),
+		(*WebsocketMessage_ManifestRequest)(nil),
+		(*WebsocketMessage_ManifestResponse)(nil),
+		(*WebsocketMessage_LauncherExited)(nil),
+		(*WebsocketMessage_HelloAck)(nil),
+		(*WebsocketMessage_DiagnosticsRequest)(nil),
+		(*WebsocketMessage_DiagnosticsChunk)(nil),
+		(*WebsocketMessage_SyncStatusRequest)(nil),
+		(*WebsocketMessage_SyncStatusResponse)(nil),
+		(*WebsocketMessage_FileGetRequest)(nil),
+		(*WebsocketMessage_FileGetResponse)(nil),
+		(*WebsocketMessage_DirListRequest)(nil),
+		(*WebsocketMessage_DirListResponse)(nil),
+		(*WebsocketMessage_LogTailRequest)(nil),
+		(*WebsocketMessage_LogTailStop)(nil),
+		(*WebsocketMessage_LogTailData)(nil),
+		(*WebsocketMessage_LogTailEnd)(nil),
+		(*WebsocketMessage_BatchChunk)(nil),
+		(*WebsocketMessage_ResyncRequest)(nil),
+		(*WebsocketMessage_EnvRollbackRequest)(nil),
+		(*WebsocketMessage_EnvRollbackResponse)(nil),
+		(*WebsocketMessage_ActivateRequest)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ws_proto_rawDesc), len(file_ws_proto_rawDesc)),
-			NumEnums:      9,
-			NumMessages:   19,
+			NumEnums:      18,
+			NumMessages:   80,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,287 @@
+// Package control is the sidecar's local control socket: a small JSON-RPC 2.0
+// API the launcher and the app use to talk back to the sidecar over a Unix
+// domain socket on the shared volume. Requests and responses are one JSON
+// object per line.
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// SocketName is the control socket's name in the .sidecar directory.
+const SocketName = "control.sock"
+
+// Methods the sidecar answers.
+const (
+	// MethodGetEnvVersion returns an EnvVersionResult.
+	MethodGetEnvVersion = "get_env_version"
+	// MethodGetLastPush returns a LastPushResult.
+	MethodGetLastPush = "get_last_push"
+	// MethodReportReadiness takes ReadinessParams and records whether the app
+	// is ready to serve.
+	MethodReportReadiness = "report_readiness"
+	// MethodRequestResync takes ResyncParams and asks the control plane for a
+	// full resync of the files.
+	MethodRequestResync = "request_resync"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessageSize bounds a request or response line.
+const maxMessageSize = 1 << 20
+
+// EnvVersionResult lists the env files the launcher may source, each with a
+// version that changes with its contents, as in the reload handshake.
+type EnvVersionResult struct {
+	EnvFiles []launcher.EnvFile `json:"env_files"`
+}
+
+// LastPushResult is the last push applied to the files. PushID is empty if
+// none has been.
+type LastPushResult struct {
+	PushID    string     `json:"push_id"`
+	PushHash  string     `json:"push_hash,omitempty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// ReadinessParams reports whether the app is ready to serve, with an optional
+// explanation such as what it is still waiting for.
+type ReadinessParams struct {
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+// ResyncParams says why the app wants the files resynced.
+type ResyncParams struct {
+	Detail string `json:"detail,omitempty"`
+}
+
+// Request is a JSON-RPC 2.0 request. A request without an ID is a
+// notification and gets no response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response, with either Result or Error set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("control: %s (code %d)", e.Message, e.Code)
+}
+
+// InvalidParams returns the error for a request whose params can't be used.
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler answers a request with a result to encode as JSON. Returning an
+// *Error sets its code; any other error is reported as an internal error.
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// SocketPath returns the path of the control socket in filesDir.
+func SocketPath(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), SocketName)
+}
+
+// Serve listens on the socket at path, replacing one left by a previous
+// sidecar, and answers requests with handler until ctx is done. The socket is
+// made accessible to every user, since the app container may run as another
+// one; access is limited by the directory it is in.
+func Serve(ctx context.Context, path string, handler Handler) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0666); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set control socket permissions: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	conns := make(map[net.Conn]bool)
+	var mu sync.Mutex
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			conn.Close()
+		}
+	}()
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("control socket stopped accepting connections: %w", err)
+		}
+		mu.Lock()
+		conns[conn] = true
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(conn, handler)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+// serveConn answers the requests on conn, one per line, until it is closed.
+func serveConn(conn net.Conn, handler Handler) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		resp := handle(scanner.Bytes(), handler)
+		if resp == nil {
+			continue
+		}
+		if err := encoder.Encode(resp); err != nil {
+			log.Debug("Failed to write control response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// handle answers one request line, or returns nil for a notification.
+func handle(line []byte, handler Handler) *Response {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &Response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}}
+	}
+	id := req.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: CodeInvalidRequest, Message: `a request needs "jsonrpc": "2.0" and a method`}}
+	}
+
+	result, err := handler(req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return &Response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: CodeInternalError, Message: err.Error()}}
+	}
+	return &Response{JSONRPC: "2.0", ID: id, Result: data}
+}
+
+// Client calls the sidecar over its control socket. It is safe for concurrent
+// use; calls are sent one at a time.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+}
+
+// Dial connects to the control socket at path.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control socket: %w", err)
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	return &Client{conn: conn, scanner: scanner}, nil
+}
+
+// Call sends a request for method with params, which may be nil, and decodes
+// its result into result, which may be nil to discard it. An error the sidecar
+// answers with is an *Error.
+func (c *Client) Call(method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	req := Request{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(c.nextID)), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		req.Params = data
+	}
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read %s response: %w", method, err)
+		}
+		return fmt.Errorf("control socket closed before answering %s", method)
+	}
+	var resp Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
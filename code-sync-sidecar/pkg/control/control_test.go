@@ -0,0 +1,113 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve starts Serve on a socket in a temporary directory and returns its path.
+func serve(t *testing.T, handler Handler) string {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir can exceed.
+	dir, err := os.MkdirTemp("", "control")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, SocketName)
+	// A socket left by a previous sidecar is replaced.
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, path, handler) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Error("Serve didn't return")
+		}
+	})
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode()&os.ModeSocket != 0
+	}, 5*time.Second, 10*time.Millisecond)
+	return path
+}
+
+func TestClient_Call(t *testing.T) {
+	var reported ReadinessParams
+	path := serve(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case MethodGetLastPush:
+			return LastPushResult{PushID: "push-1"}, nil
+		case MethodReportReadiness:
+			if err := json.Unmarshal(params, &reported); err != nil {
+				return nil, InvalidParams("bad params: %v", err)
+			}
+			return struct{}{}, nil
+		case MethodRequestResync:
+			return nil, errors.New("not connected")
+		}
+		return nil, &Error{Code: CodeMethodNotFound, Message: "unknown method " + method}
+	})
+
+	client, err := Dial(path)
+	require.NoError(t, err)
+	defer client.Close()
+
+	var last LastPushResult
+	require.NoError(t, client.Call(MethodGetLastPush, nil, &last))
+	assert.Equal(t, "push-1", last.PushID)
+
+	require.NoError(t, client.Call(MethodReportReadiness, ReadinessParams{Ready: true, Message: "warmed up"}, nil))
+	assert.Equal(t, ReadinessParams{Ready: true, Message: "warmed up"}, reported)
+
+	var rpcErr *Error
+	require.ErrorAs(t, client.Call(MethodReportReadiness, []string{"ready"}, nil), &rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	require.ErrorAs(t, client.Call(MethodRequestResync, nil, nil), &rpcErr)
+	assert.Equal(t, CodeInternalError, rpcErr.Code)
+	assert.Equal(t, "not connected", rpcErr.Message)
+	require.ErrorAs(t, client.Call("reboot", nil, nil), &rpcErr)
+	assert.Equal(t, CodeMethodNotFound, rpcErr.Code)
+}
+
+func TestServe_RawRequests(t *testing.T) {
+	path := serve(t, func(method string, params json.RawMessage) (any, error) {
+		return map[string]string{"method": method}, nil
+	})
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+
+	// A notification gets no response, so the next line answers the request after it.
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","method":"report_readiness","params":{"ready":true}}` + "\n" + `{"jsonrpc":"2.0","id":"a","method":"get_last_push"}` + "\n"))
+	require.NoError(t, err)
+	var resp Response
+	require.NoError(t, decoder.Decode(&resp))
+	assert.JSONEq(t, `"a"`, string(resp.ID))
+	assert.JSONEq(t, `{"method":"get_last_push"}`, string(resp.Result))
+
+	_, err = conn.Write([]byte("not json\n" + `{"id":2,"method":"get_last_push"}` + "\n"))
+	require.NoError(t, err)
+	resp = Response{}
+	require.NoError(t, decoder.Decode(&resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, CodeParseError, resp.Error.Code)
+	resp = Response{}
+	require.NoError(t, decoder.Decode(&resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, CodeInvalidRequest, resp.Error.Code)
+	assert.JSONEq(t, "2", string(resp.ID))
+}
@@ -0,0 +1,296 @@
+package coordination
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// Headers a follower sends when connecting to the leader's Hub.
+const (
+	PeerTokenHeader = "X-Bifrost-Peer-Token"
+	ReplicaIDHeader = "X-Bifrost-Replica"
+)
+
+// The Hub's HELLO_ACK, as the control plane would send it.
+const (
+	hubServerVersion   = "coordination-hub"
+	hubProtocolVersion = 1
+)
+
+// Hub runs on the leader. Followers connect to it as they would to the control
+// plane, on the same websocket path, and it forwards each push the leader runs
+// to them and collects their results.
+type Hub struct {
+	token    string
+	upgrader websocket.Upgrader
+
+	mu        sync.Mutex
+	followers map[string]*follower
+	latest    *pb.PushMessage
+	// results holds the final response of each follower, by push ID and then
+	// replica ID, for pushes forwarded with Forward.
+	results map[string]map[string]*pb.PushResponse
+	// resultAdded is closed and replaced whenever a result arrives.
+	resultAdded chan struct{}
+}
+
+type follower struct {
+	id      string
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (f *follower) send(msg *pb.WebsocketMessage) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", msg.MessageType, err)
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	return f.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// NewHub creates a Hub. Followers must present token in PeerTokenHeader unless
+// it is empty.
+func NewHub(token string) *Hub {
+	return &Hub{
+		token:       token,
+		followers:   make(map[string]*follower),
+		results:     make(map[string]map[string]*pb.PushResponse),
+		resultAdded: make(chan struct{}),
+	}
+}
+
+// Handler serves follower connections.
+func (h *Hub) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/push/sidecar/{app}/{deployment}", h.handleFollower)
+	return mux
+}
+
+// Followers returns the IDs of the connected followers, sorted.
+func (h *Hub) Followers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.followers))
+	for id := range h.followers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (h *Hub) handleFollower(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(PeerTokenHeader)), []byte(h.token)) != 1 {
+		http.Error(w, "invalid peer token", http.StatusUnauthorized)
+		return
+	}
+	id := r.Header.Get(ReplicaIDHeader)
+	if id == "" {
+		id = r.RemoteAddr
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("Failed to upgrade follower connection", zap.String("replica", id), zap.Error(err))
+		return
+	}
+	f := &follower{id: id, conn: conn}
+	h.mu.Lock()
+	if previous := h.followers[id]; previous != nil {
+		// A follower that reconnects replaces its stale connection.
+		previous.conn.Close()
+	}
+	h.followers[id] = f
+	h.mu.Unlock()
+	log.Info("Follower connected", zap.String("replica", id), zap.String("remoteAddr", r.RemoteAddr))
+
+	defer func() {
+		h.mu.Lock()
+		if h.followers[id] == f {
+			delete(h.followers, id)
+		}
+		h.mu.Unlock()
+		conn.Close()
+		log.Info("Follower disconnected", zap.String("replica", id))
+	}()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := &pb.WebsocketMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			log.Warn("Failed to decode message from follower", zap.String("replica", id), zap.Error(err))
+			continue
+		}
+		h.handleMessage(f, msg)
+	}
+}
+
+func (h *Hub) handleMessage(f *follower, msg *pb.WebsocketMessage) {
+	switch msg.MessageType {
+	case pb.WebsocketMessage_HELLO:
+		err := f.send(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_HELLO_ACK,
+			Message: &pb.WebsocketMessage_HelloAck{HelloAck: &pb.HelloAck{
+				ProtocolVersion: hubProtocolVersion,
+				ServerVersion:   hubServerVersion,
+			}},
+		})
+		if err != nil {
+			log.Warn("Failed to send HELLO_ACK to follower", zap.String("replica", f.id), zap.Error(err))
+			return
+		}
+		// Bring a follower that missed pushes up to date with the latest one.
+		h.mu.Lock()
+		latest := h.latest
+		h.mu.Unlock()
+		if latest != nil && latest.PushId != msg.GetHello().GetLastPushId() {
+			h.sendPush(f, latest)
+		}
+	case pb.WebsocketMessage_PUSH_RESPONSE:
+		resp := msg.GetPushResponse()
+		if isFinal(resp.GetStatus()) {
+			h.addResult(f.id, resp)
+		}
+	default:
+		log.Debug("Ignoring message from follower", zap.String("replica", f.id), zap.String("type", msg.MessageType.String()))
+	}
+}
+
+// isFinal reports whether status ends a push, as opposed to reporting progress.
+func isFinal(status pb.PushResponse_PushStatus) bool {
+	switch status {
+	case pb.PushResponse_RECEIVED, pb.PushResponse_APPLYING, pb.PushResponse_RELOADING, pb.PushResponse_HEALTHY:
+		return false
+	}
+	return true
+}
+
+func (h *Hub) addResult(id string, resp *pb.PushResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	results, ok := h.results[resp.PushId]
+	if !ok {
+		// Not a push this leader forwarded, or one it has stopped waiting for.
+		return
+	}
+	results[id] = resp
+	close(h.resultAdded)
+	h.resultAdded = make(chan struct{})
+}
+
+func (h *Hub) sendPush(f *follower, push *pb.PushMessage) {
+	err := f.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_REQUEST,
+		Message:     &pb.WebsocketMessage_PushMessage{PushMessage: push},
+	})
+	if err != nil {
+		log.Warn("Failed to forward push to follower", zap.String("replica", f.id), zap.String("pushID", push.PushId), zap.Error(err))
+	}
+}
+
+// Forward sends push to every connected follower and returns their IDs. Their
+// results are collected until Results is called.
+func (h *Hub) Forward(push *pb.PushMessage) []string {
+	h.mu.Lock()
+	h.latest = push
+	h.results[push.PushId] = make(map[string]*pb.PushResponse)
+	followers := make([]*follower, 0, len(h.followers))
+	for _, f := range h.followers {
+		followers = append(followers, f)
+	}
+	h.mu.Unlock()
+
+	ids := make([]string, 0, len(followers))
+	for _, f := range followers {
+		h.sendPush(f, push)
+		ids = append(ids, f.id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Cancel forwards a PUSH_CANCEL to every connected follower.
+func (h *Hub) Cancel(pushID string) {
+	h.mu.Lock()
+	followers := make([]*follower, 0, len(h.followers))
+	for _, f := range h.followers {
+		followers = append(followers, f)
+	}
+	h.mu.Unlock()
+	for _, f := range followers {
+		err := f.send(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_PUSH_CANCEL,
+			Message:     &pb.WebsocketMessage_PushCancel{PushCancel: &pb.PushCancel{PushId: pushID}},
+		})
+		if err != nil {
+			log.Warn("Failed to forward push cancel to follower", zap.String("replica", f.id), zap.Error(err))
+		}
+	}
+}
+
+// Results waits up to timeout for the final response of each of the followers
+// a push was forwarded to, and returns a result per follower in the order
+// given. A follower that didn't answer in time, or before stop was closed, is
+// reported with status UNKNOWN.
+func (h *Hub) Results(stop <-chan struct{}, pushID string, followers []string, timeout time.Duration) []*pb.ReplicaResult {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+wait:
+	for {
+		h.mu.Lock()
+		missing := 0
+		for _, id := range followers {
+			if h.results[pushID][id] == nil {
+				missing++
+			}
+		}
+		added := h.resultAdded
+		h.mu.Unlock()
+		if missing == 0 {
+			break
+		}
+		select {
+		case <-added:
+		case <-stop:
+			break wait
+		case <-timer.C:
+			break wait
+		}
+	}
+
+	h.mu.Lock()
+	results := h.results[pushID]
+	delete(h.results, pushID)
+	h.mu.Unlock()
+	replicaResults := make([]*pb.ReplicaResult, 0, len(followers))
+	for _, id := range followers {
+		resp := results[id]
+		if resp == nil {
+			replicaResults = append(replicaResults, &pb.ReplicaResult{
+				ReplicaId:    id,
+				Status:       pb.PushResponse_UNKNOWN,
+				ErrorMessage: fmt.Sprintf("no result within %s", timeout),
+			})
+			continue
+		}
+		replicaResults = append(replicaResults, &pb.ReplicaResult{
+			ReplicaId:    id,
+			Status:       resp.Status,
+			ErrorMessage: resp.ErrorMessage,
+		})
+	}
+	return replicaResults
+}
@@ -0,0 +1,165 @@
+package coordination
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// testFollower is a follower's connection to a Hub.
+type testFollower struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialHub(t *testing.T, server *httptest.Server, id, token string) (*testFollower, *http.Response, error) {
+	t.Helper()
+	headers := http.Header{}
+	headers.Set(ReplicaIDHeader, id)
+	headers.Set(PeerTokenHeader, token)
+	u := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/push/sidecar/app-1/dep-1"
+	conn, resp, err := websocket.DefaultDialer.Dial(u, headers)
+	if err != nil {
+		return nil, resp, err
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testFollower{t: t, conn: conn}, resp, nil
+}
+
+func (f *testFollower) send(msg *pb.WebsocketMessage) {
+	data, err := proto.Marshal(msg)
+	require.NoError(f.t, err)
+	require.NoError(f.t, f.conn.WriteMessage(websocket.BinaryMessage, data))
+}
+
+func (f *testFollower) receive() *pb.WebsocketMessage {
+	require.NoError(f.t, f.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, data, err := f.conn.ReadMessage()
+	require.NoError(f.t, err)
+	msg := &pb.WebsocketMessage{}
+	require.NoError(f.t, proto.Unmarshal(data, msg))
+	return msg
+}
+
+func (f *testFollower) hello(lastPushID string) {
+	f.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_HELLO,
+		Message:     &pb.WebsocketMessage_Hello{Hello: &pb.Hello{LastPushId: lastPushID}},
+	})
+	require.Equal(f.t, pb.WebsocketMessage_HELLO_ACK, f.receive().MessageType)
+}
+
+func (f *testFollower) respond(pushID string, status pb.PushResponse_PushStatus, errorMessage string) {
+	f.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_RESPONSE,
+		Message: &pb.WebsocketMessage_PushResponse{PushResponse: &pb.PushResponse{
+			PushId:       pushID,
+			Status:       status,
+			ErrorMessage: errorMessage,
+		}},
+	})
+}
+
+func startHub(t *testing.T) (*Hub, *httptest.Server) {
+	t.Helper()
+	hub := NewHub("peer-secret")
+	server := httptest.NewServer(hub.Handler())
+	t.Cleanup(server.Close)
+	return hub, server
+}
+
+func waitForFollowers(t *testing.T, hub *Hub, ids ...string) {
+	t.Helper()
+	require.Eventually(t, func() bool { return assert.ObjectsAreEqual(ids, hub.Followers()) }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestHub_RejectsWrongPeerToken(t *testing.T) {
+	_, server := startHub(t)
+	_, resp, err := dialHub(t, server, "pod-b", "wrong")
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHub_ForwardAndCollectResults(t *testing.T) {
+	hub, server := startHub(t)
+	b, _, err := dialHub(t, server, "pod-b", "peer-secret")
+	require.NoError(t, err)
+	c, _, err := dialHub(t, server, "pod-c", "peer-secret")
+	require.NoError(t, err)
+	b.hello("")
+	c.hello("")
+	waitForFollowers(t, hub, "pod-b", "pod-c")
+
+	followers := hub.Forward(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.Equal(t, []string{"pod-b", "pod-c"}, followers)
+	for _, f := range []*testFollower{b, c} {
+		msg := f.receive()
+		require.Equal(t, pb.WebsocketMessage_PUSH_REQUEST, msg.MessageType)
+		assert.Equal(t, "push-1", msg.GetPushMessage().PushId)
+		assert.Equal(t, []byte("batch"), msg.GetPushMessage().BatchFile)
+	}
+
+	// Progress is ignored; only final responses count.
+	b.respond("push-1", pb.PushResponse_APPLYING, "")
+	b.respond("push-1", pb.PushResponse_COMPLETED, "")
+	c.respond("push-1", pb.PushResponse_FAILED, "rsync failed")
+
+	results := hub.Results(nil, "push-1", followers, 2*time.Second)
+	require.Len(t, results, 2)
+	assert.Equal(t, "pod-b", results[0].ReplicaId)
+	assert.Equal(t, pb.PushResponse_COMPLETED, results[0].Status)
+	assert.Equal(t, "pod-c", results[1].ReplicaId)
+	assert.Equal(t, pb.PushResponse_FAILED, results[1].Status)
+	assert.Equal(t, "rsync failed", results[1].ErrorMessage)
+}
+
+func TestHub_ResultsTimeout(t *testing.T) {
+	hub, server := startHub(t)
+	b, _, err := dialHub(t, server, "pod-b", "peer-secret")
+	require.NoError(t, err)
+	b.hello("")
+	waitForFollowers(t, hub, "pod-b")
+
+	followers := hub.Forward(&pb.PushMessage{PushId: "push-1"})
+	b.receive()
+
+	results := hub.Results(nil, "push-1", followers, 50*time.Millisecond)
+	require.Len(t, results, 1)
+	assert.Equal(t, pb.PushResponse_UNKNOWN, results[0].Status)
+	assert.Contains(t, results[0].ErrorMessage, "no result within 50ms")
+
+	// A late result for a push no longer waited for is dropped.
+	b.respond("push-1", pb.PushResponse_COMPLETED, "")
+	stop := make(chan struct{})
+	close(stop)
+	assert.Equal(t, pb.PushResponse_UNKNOWN, hub.Results(stop, "push-1", followers, time.Second)[0].Status)
+}
+
+func TestHub_ReplaysLatestPushToReconnectingFollower(t *testing.T) {
+	hub, server := startHub(t)
+	hub.Forward(&pb.PushMessage{PushId: "push-2"})
+
+	b, _, err := dialHub(t, server, "pod-b", "peer-secret")
+	require.NoError(t, err)
+	b.hello("push-1")
+	msg := b.receive()
+	require.Equal(t, pb.WebsocketMessage_PUSH_REQUEST, msg.MessageType)
+	assert.Equal(t, "push-2", msg.GetPushMessage().PushId)
+
+	// A follower that already applied it isn't sent it again.
+	c, _, err := dialHub(t, server, "pod-c", "peer-secret")
+	require.NoError(t, err)
+	c.hello("push-2")
+	hub.Cancel("push-2")
+	assert.Equal(t, pb.WebsocketMessage_PUSH_CANCEL, c.receive().MessageType)
+}
@@ -0,0 +1,354 @@
+// Package coordination lets the sidecars of a deployment's replicas act as
+// one. They elect a leader with a Kubernetes Lease; the leader alone connects to
+// the control plane and forwards each push to the others through a Hub, so every
+// replica applies pushes in the same order and the control plane gets a single
+// result covering all of them.
+package coordination
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// AddressAnnotation holds the leader's peer address on the Lease.
+const AddressAnnotation = "bifrost.dev/peer-address"
+
+// microTimeFormat is the format of a Lease's acquireTime and renewTime.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// LeaseConfig configures a LeaseElector.
+type LeaseConfig struct {
+	// APIURL is the Kubernetes API server, e.g. https://10.0.0.1:443.
+	APIURL    string
+	Namespace string
+	Name      string
+	// Identity names this replica in the Lease; normally the pod name.
+	Identity string
+	// Address is the host:port other replicas reach this one's Hub on.
+	Address string
+	// Duration is how long a Lease is held without renewal before another
+	// replica may take it over.
+	Duration time.Duration
+	Client   *http.Client
+	// Token returns the bearer token for the Kubernetes API.
+	Token func() (string, error)
+}
+
+// Leader is the replica currently holding the Lease.
+type Leader struct {
+	Identity string
+	Address  string
+}
+
+// LeaseElector takes part in electing a leader among the replicas that share
+// a Kubernetes Lease.
+type LeaseElector struct {
+	cfg     LeaseConfig
+	changed chan struct{}
+
+	mu        sync.Mutex
+	leader    Leader
+	isLeader  bool
+	lastRenew time.Time
+	// observed is the last Lease record seen and observedAt when it was first
+	// seen. Expiry is judged by this replica's clock from observedAt, not by the
+	// holder's renewTime, so clock skew between nodes doesn't matter.
+	observed   leaseSpec
+	observedAt time.Time
+}
+
+// NewLeaseElector creates a LeaseElector. Call Run to start taking part.
+func NewLeaseElector(cfg LeaseConfig) *LeaseElector {
+	return &LeaseElector{cfg: cfg, changed: make(chan struct{}, 1)}
+}
+
+// Leader returns the current leader, and whether it is this replica. The
+// leader is empty until the Lease has been read.
+func (e *LeaseElector) Leader() (Leader, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader, e.isLeader
+}
+
+// Changed is signalled whenever the leader changes.
+func (e *LeaseElector) Changed() <-chan struct{} {
+	return e.changed
+}
+
+// Run acquires or renews the Lease every third of its duration until ctx is
+// done, then releases it if this replica holds it.
+func (e *LeaseElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Duration / 3)
+	defer ticker.Stop()
+	for {
+		if err := e.tryAcquireOrRenew(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Warn("Failed to acquire or renew the coordination lease", zap.String("lease", e.cfg.Name), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+var errLeaseConflict = errors.New("lease was updated by another replica")
+
+// tryAcquireOrRenew makes one attempt to take or keep the Lease, and records
+// who holds it.
+func (e *LeaseElector) tryAcquireOrRenew(ctx context.Context, now time.Time) error {
+	current, err := e.get(ctx)
+	if err != nil {
+		e.stepDownIfStale(now)
+		return err
+	}
+	if current == nil {
+		created := e.newLease(now)
+		if err := e.write(ctx, http.MethodPost, created); err != nil {
+			if errors.Is(err, errLeaseConflict) {
+				return nil
+			}
+			e.stepDownIfStale(now)
+			return err
+		}
+		e.record(created, now, true)
+		return nil
+	}
+
+	e.mu.Lock()
+	if current.Spec != e.observed {
+		e.observed = current.Spec
+		e.observedAt = now
+	}
+	expired := now.After(e.observedAt.Add(time.Duration(current.Spec.LeaseDurationSeconds) * time.Second))
+	e.mu.Unlock()
+
+	held := current.Spec.HolderIdentity
+	if held != "" && held != e.cfg.Identity && !expired {
+		e.record(current, now, false)
+		return nil
+	}
+
+	updated := *current
+	updated.Metadata.Annotations = copyAnnotations(current.Metadata.Annotations)
+	updated.Metadata.Annotations[AddressAnnotation] = e.cfg.Address
+	updated.Spec.LeaseDurationSeconds = int(e.cfg.Duration / time.Second)
+	updated.Spec.RenewTime = now.UTC().Format(microTimeFormat)
+	if held != e.cfg.Identity {
+		updated.Spec.HolderIdentity = e.cfg.Identity
+		updated.Spec.AcquireTime = updated.Spec.RenewTime
+		updated.Spec.LeaseTransitions++
+	}
+	if err := e.write(ctx, http.MethodPut, &updated); err != nil {
+		if errors.Is(err, errLeaseConflict) {
+			// Another replica got there first; its record is read on the next attempt.
+			return nil
+		}
+		e.stepDownIfStale(now)
+		return err
+	}
+	e.record(&updated, now, true)
+	return nil
+}
+
+func (e *LeaseElector) newLease(now time.Time) *lease {
+	stamp := now.UTC().Format(microTimeFormat)
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata: leaseMetadata{
+			Name:        e.cfg.Name,
+			Namespace:   e.cfg.Namespace,
+			Annotations: map[string]string{AddressAnnotation: e.cfg.Address},
+		},
+		Spec: leaseSpec{
+			HolderIdentity:       e.cfg.Identity,
+			LeaseDurationSeconds: int(e.cfg.Duration / time.Second),
+			AcquireTime:          stamp,
+			RenewTime:            stamp,
+		},
+	}
+}
+
+// record updates the known leader from l, which this replica holds if self.
+func (e *LeaseElector) record(l *lease, now time.Time, self bool) {
+	leader := Leader{Identity: l.Spec.HolderIdentity, Address: l.Metadata.Annotations[AddressAnnotation]}
+	e.mu.Lock()
+	if self {
+		e.lastRenew = now
+		e.observed = l.Spec
+		e.observedAt = now
+	}
+	e.set(leader, self)
+	e.mu.Unlock()
+}
+
+// stepDownIfStale gives up leadership once the Lease can no longer be renewed
+// in time, since another replica may take it over after it expires.
+func (e *LeaseElector) stepDownIfStale(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.isLeader && now.Sub(e.lastRenew) >= e.cfg.Duration {
+		e.set(Leader{}, false)
+	}
+}
+
+// set must be called with mu held.
+func (e *LeaseElector) set(leader Leader, self bool) {
+	if leader == e.leader && self == e.isLeader {
+		return
+	}
+	log.Info("Coordination leader changed",
+		zap.String("leader", leader.Identity),
+		zap.String("address", leader.Address),
+		zap.Bool("self", self))
+	e.leader, e.isLeader = leader, self
+	select {
+	case e.changed <- struct{}{}:
+	default:
+	}
+}
+
+// release gives up the Lease so another replica can take over right away.
+func (e *LeaseElector) release() {
+	e.mu.Lock()
+	wasLeader := e.isLeader
+	e.set(Leader{}, false)
+	e.mu.Unlock()
+	if !wasLeader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Duration/3)
+	defer cancel()
+	current, err := e.get(ctx)
+	if err != nil || current == nil || current.Spec.HolderIdentity != e.cfg.Identity {
+		return
+	}
+	current.Spec.HolderIdentity = ""
+	current.Spec.LeaseDurationSeconds = 1
+	if err := e.write(ctx, http.MethodPut, current); err != nil {
+		log.Warn("Failed to release the coordination lease", zap.String("lease", e.cfg.Name), zap.Error(err))
+		return
+	}
+	log.Info("Released the coordination lease", zap.String("lease", e.cfg.Name))
+}
+
+func (e *LeaseElector) leasesURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", strings.TrimSuffix(e.cfg.APIURL, "/"), url.PathEscape(e.cfg.Namespace))
+}
+
+// get reads the Lease, returning nil if it doesn't exist.
+func (e *LeaseElector) get(ctx context.Context) (*lease, error) {
+	resp, err := e.do(ctx, http.MethodGet, e.leasesURL()+"/"+url.PathEscape(e.cfg.Name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var l lease
+	if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+		return nil, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return &l, nil
+}
+
+// write creates (POST) or replaces (PUT) the Lease. A PUT carries the
+// resourceVersion it was read at, so it fails with errLeaseConflict if another
+// replica changed the Lease meanwhile.
+func (e *LeaseElector) write(ctx context.Context, method string, l *lease) error {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease: %w", err)
+	}
+	u := e.leasesURL()
+	if method == http.MethodPut {
+		u += "/" + url.PathEscape(e.cfg.Name)
+	}
+	resp, err := e.do(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return errLeaseConflict
+	case resp.StatusCode >= 300:
+		return statusError(resp)
+	}
+	var written lease
+	if err := json.NewDecoder(resp.Body).Decode(&written); err == nil {
+		l.Metadata.ResourceVersion = written.Metadata.ResourceVersion
+	}
+	return nil
+}
+
+func (e *LeaseElector) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.Token != nil {
+		token, err := e.cfg.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the Kubernetes API token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return e.cfg.Client.Do(req)
+}
+
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("Kubernetes API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	copied := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,215 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeaseAPI serves the Lease endpoints of the Kubernetes API for one Lease,
+// rejecting writes made against a stale resourceVersion like the real server.
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+	tokens  []string
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+	const prefix = "/apis/coordination.k8s.io/v1/namespaces/apps/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == prefix+"/sidecars":
+		if f.lease == nil {
+			http.Error(w, `{"reason":"NotFound"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(f.lease)
+	case r.Method == http.MethodPost && r.URL.Path == prefix:
+		if f.lease != nil {
+			http.Error(w, `{"reason":"AlreadyExists"}`, http.StatusConflict)
+			return
+		}
+		f.store(w, r)
+	case r.Method == http.MethodPut && r.URL.Path == prefix+"/sidecars":
+		var l lease
+		json.NewDecoder(r.Body).Decode(&l)
+		if f.lease == nil || l.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion {
+			http.Error(w, `{"reason":"Conflict"}`, http.StatusConflict)
+			return
+		}
+		f.lease = &l
+		f.version++
+		f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		json.NewEncoder(w).Encode(f.lease)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeLeaseAPI) store(w http.ResponseWriter, r *http.Request) {
+	var l lease
+	json.NewDecoder(r.Body).Decode(&l)
+	f.lease = &l
+	f.version++
+	f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f.lease)
+}
+
+func (f *fakeLeaseAPI) holder() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lease == nil {
+		return ""
+	}
+	return f.lease.Spec.HolderIdentity
+}
+
+func newTestElector(t *testing.T, api *httptest.Server, identity string) *LeaseElector {
+	t.Helper()
+	return NewLeaseElector(LeaseConfig{
+		APIURL:    api.URL,
+		Namespace: "apps",
+		Name:      "sidecars",
+		Identity:  identity,
+		Address:   identity + ":7980",
+		Duration:  15 * time.Second,
+		Client:    api.Client(),
+		Token:     func() (string, error) { return "sa-token", nil },
+	})
+}
+
+func TestLeaseElector_AcquireAndFollow(t *testing.T) {
+	fake := &fakeLeaseAPI{}
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	a := newTestElector(t, api, "pod-a")
+	b := newTestElector(t, api, "pod-b")
+	now := time.Now()
+	ctx := context.Background()
+
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now))
+	leader, self := a.Leader()
+	assert.True(t, self)
+	assert.Equal(t, Leader{Identity: "pod-a", Address: "pod-a:7980"}, leader)
+	assert.Equal(t, "pod-a", fake.holder())
+	select {
+	case <-a.Changed():
+	default:
+		t.Fatal("acquiring the lease should signal Changed")
+	}
+
+	require.NoError(t, b.tryAcquireOrRenew(ctx, now))
+	leader, self = b.Leader()
+	assert.False(t, self)
+	assert.Equal(t, Leader{Identity: "pod-a", Address: "pod-a:7980"}, leader)
+
+	// Renewing keeps the lease with pod-a.
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now.Add(5*time.Second)))
+	require.NoError(t, b.tryAcquireOrRenew(ctx, now.Add(10*time.Second)))
+	_, self = b.Leader()
+	assert.False(t, self)
+	assert.Equal(t, "pod-a", fake.holder())
+	assert.Contains(t, fake.tokens, "Bearer sa-token")
+}
+
+func TestLeaseElector_TakesOverExpiredLease(t *testing.T) {
+	fake := &fakeLeaseAPI{}
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	a := newTestElector(t, api, "pod-a")
+	b := newTestElector(t, api, "pod-b")
+	now := time.Now()
+	ctx := context.Background()
+
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now))
+	require.NoError(t, b.tryAcquireOrRenew(ctx, now))
+
+	// pod-a stops renewing. Expiry is judged from when pod-b last saw the
+	// record change, not from pod-a's renewTime.
+	require.NoError(t, b.tryAcquireOrRenew(ctx, now.Add(14*time.Second)))
+	_, self := b.Leader()
+	assert.False(t, self)
+	require.NoError(t, b.tryAcquireOrRenew(ctx, now.Add(16*time.Second)))
+	leader, self := b.Leader()
+	assert.True(t, self)
+	assert.Equal(t, "pod-b", leader.Identity)
+	assert.Equal(t, "pod-b", fake.holder())
+	assert.Equal(t, 1, fake.lease.Spec.LeaseTransitions)
+
+	// pod-a finds out on its next attempt.
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now.Add(17*time.Second)))
+	leader, self = a.Leader()
+	assert.False(t, self)
+	assert.Equal(t, "pod-b:7980", leader.Address)
+}
+
+func TestLeaseElector_ConflictLosesRace(t *testing.T) {
+	fake := &fakeLeaseAPI{}
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	a := newTestElector(t, api, "pod-a")
+	now := time.Now()
+	ctx := context.Background()
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now))
+
+	// Another replica takes the lease between pod-a's read and its write.
+	current, err := a.get(ctx)
+	require.NoError(t, err)
+	stolen := *current
+	stolen.Spec.HolderIdentity = "pod-c"
+	require.NoError(t, a.write(ctx, http.MethodPut, &stolen))
+	assert.ErrorIs(t, a.write(ctx, http.MethodPut, current), errLeaseConflict)
+	assert.Equal(t, "pod-c", fake.holder())
+}
+
+func TestLeaseElector_StepsDownWhenRenewalFails(t *testing.T) {
+	fake := &fakeLeaseAPI{}
+	api := httptest.NewServer(fake)
+	a := newTestElector(t, api, "pod-a")
+	now := time.Now()
+	ctx := context.Background()
+	require.NoError(t, a.tryAcquireOrRenew(ctx, now))
+	api.Close()
+
+	// Still the leader while the lease it last renewed is valid.
+	assert.Error(t, a.tryAcquireOrRenew(ctx, now.Add(5*time.Second)))
+	_, self := a.Leader()
+	assert.True(t, self)
+
+	assert.Error(t, a.tryAcquireOrRenew(ctx, now.Add(15*time.Second)))
+	leader, self := a.Leader()
+	assert.False(t, self)
+	assert.Empty(t, leader.Identity)
+}
+
+func TestLeaseElector_RunReleasesOnExit(t *testing.T) {
+	fake := &fakeLeaseAPI{}
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	a := newTestElector(t, api, "pod-a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return fake.holder() == "pod-a" }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.Empty(t, fake.holder())
+	_, self := a.Leader()
+	assert.False(t, self)
+}
@@ -0,0 +1,443 @@
+package envfile
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	awsRequestTimeout = 10 * time.Second
+	// Temporary credentials are refreshed this long before they expire.
+	awsCredentialRefreshWindow = 5 * time.Minute
+	awsRoleSessionName         = "bifrost-sidecar"
+)
+
+// These are replaced in tests.
+var (
+	awsContainerCredentialsHost = "http://169.254.170.2"
+	awsSTSEndpoint              = func(region string) string {
+		return "https://sts." + region + ".amazonaws.com"
+	}
+	awsNow = time.Now
+)
+
+// AWSSecretsConfig configures reading database env vars from AWS Secrets Manager.
+type AWSSecretsConfig struct {
+	Region string `yaml:"region"`
+	// Endpoint overrides the Secrets Manager endpoint, e.g. for a VPC endpoint.
+	Endpoint string      `yaml:"endpoint"`
+	Secrets  []AWSSecret `yaml:"secrets"`
+}
+
+// AWSSecret maps a secret in Secrets Manager to an env var. A secret holding
+// JSON in the layout RDS uses (engine, host, port, username, password, dbname)
+// is turned into a connection URI; any other secret is used as-is. The other
+// fields fill in what the secret lacks, e.g. the host of an RDS-managed master
+// user secret, which only holds the username and password.
+type AWSSecret struct {
+	EnvVar   string `yaml:"env_var"`
+	SecretID string `yaml:"secret_id"`
+	Scope    string `yaml:"scope"`
+	Engine   string `yaml:"engine"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Database string `yaml:"database"`
+}
+
+// AWSSecretsProvider reads database env vars from AWS Secrets Manager, with
+// credentials found the way the AWS SDKs find them in a pod: access keys in
+// the environment, the container credentials endpoint of EKS Pod Identity and
+// ECS, or the web identity token of IAM roles for service accounts.
+type AWSSecretsProvider struct {
+	cfg    AWSSecretsConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	creds awsCredentials
+}
+
+// NewAWSSecretsProvider returns a provider for the secrets in cfg.
+func NewAWSSecretsProvider(cfg AWSSecretsConfig) *AWSSecretsProvider {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://secretsmanager." + cfg.Region + ".amazonaws.com"
+	}
+	return &AWSSecretsProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: awsRequestTimeout},
+	}
+}
+
+// Name implements DatabaseEnvProvider.
+func (p *AWSSecretsProvider) Name() string {
+	return ProviderAWSSecretsManager
+}
+
+// DatabaseEnvVars implements DatabaseEnvProvider.
+func (p *AWSSecretsProvider) DatabaseEnvVars(ctx context.Context) ([]DatabaseEnvVar, error) {
+	creds, err := p.credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	envVars := make([]DatabaseEnvVar, 0, len(p.cfg.Secrets))
+	for _, secret := range p.cfg.Secrets {
+		value, err := p.getSecretValue(ctx, creds, secret.SecretID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", secret.EnvVar, err)
+		}
+		uri, err := secret.connectionURI(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: secret %s: %w", secret.EnvVar, secret.SecretID, err)
+		}
+		envVars = append(envVars, DatabaseEnvVar{EnvVarName: secret.EnvVar, ConnectionURI: uri, Scope: secret.Scope})
+	}
+	return envVars, nil
+}
+
+// getSecretValue reads the current version of a secret.
+func (p *AWSSecretsProvider) getSecretValue(ctx context.Context, creds awsCredentials, secretID string) (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, creds, p.cfg.Region, "secretsmanager", awsNow())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", secretID, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", secretID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Errors name their type, and the message key is "message" or "Message"
+		// depending on the error, which decoding matches either way.
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Type != "" {
+			return "", fmt.Errorf("failed to read secret %s: %s: %s", secretID, apiErr.Type, apiErr.Message)
+		}
+		return "", fmt.Errorf("failed to read secret %s: status %d: %s", secretID, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var value struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", fmt.Errorf("failed to decode secret %s: %w", secretID, err)
+	}
+	if value.SecretString == "" && value.SecretBinary != nil {
+		return string(value.SecretBinary), nil
+	}
+	return value.SecretString, nil
+}
+
+// rdsSecret is the JSON layout of the secrets RDS and Aurora create. The port
+// is a number in secrets RDS manages but often a string in hand-made ones.
+type rdsSecret struct {
+	Engine   string      `json:"engine"`
+	Host     string      `json:"host"`
+	Port     json.Number `json:"port"`
+	Username string      `json:"username"`
+	Password string      `json:"password"`
+	DBName   string      `json:"dbname"`
+}
+
+// connectionURI turns the value of the secret into a connection URI.
+func (s AWSSecret) connectionURI(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "{") {
+		return value, nil
+	}
+	var secret rdsSecret
+	if err := json.Unmarshal([]byte(value), &secret); err != nil {
+		return "", fmt.Errorf("failed to decode: %w", err)
+	}
+	if s.Engine != "" {
+		secret.Engine = s.Engine
+	}
+	if s.Host != "" {
+		secret.Host = s.Host
+	}
+	if s.Port != 0 {
+		secret.Port = json.Number(fmt.Sprint(s.Port))
+	}
+	if s.Database != "" {
+		secret.DBName = s.Database
+	}
+	switch {
+	case secret.Engine == "":
+		return "", fmt.Errorf("no engine; set engine for the secret")
+	case secret.Host == "":
+		return "", fmt.Errorf("no host; set host for the secret")
+	case secret.Username == "":
+		return "", fmt.Errorf("no username")
+	}
+
+	u := url.URL{
+		Scheme: engineScheme(secret.Engine),
+		User:   url.UserPassword(secret.Username, secret.Password),
+		Host:   secret.Host,
+	}
+	if secret.Port != "" {
+		u.Host = net.JoinHostPort(secret.Host, secret.Port.String())
+	}
+	if secret.DBName != "" {
+		u.Path = "/" + secret.DBName
+	}
+	return u.String(), nil
+}
+
+// engineScheme returns the URI scheme for an RDS engine name such as
+// "postgres", "aurora-postgresql" or "mariadb".
+func engineScheme(engine string) string {
+	engine = strings.ToLower(engine)
+	switch {
+	case strings.Contains(engine, "postgres"):
+		return "postgresql"
+	case strings.Contains(engine, "mysql"), engine == "mariadb", engine == "aurora":
+		return "mysql"
+	case strings.HasPrefix(engine, "sqlserver"):
+		return "sqlserver"
+	case strings.HasPrefix(engine, "oracle"):
+		return "oracle"
+	}
+	return engine
+}
+
+// awsCredentials are the credentials requests are signed with. Expires is
+// zero for long-term access keys.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// credentials returns the cached credentials, fetching new ones when they are
+// about to expire.
+func (p *AWSSecretsProvider) credentials(ctx context.Context) (awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.creds.AccessKeyID != "" && awsNow().Add(awsCredentialRefreshWindow).Before(p.creds.Expires) {
+		return p.creds, nil
+	}
+	var creds awsCredentials
+	var err error
+	switch {
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		creds, err = p.containerCredentials(ctx)
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && os.Getenv("AWS_ROLE_ARN") != "":
+		creds, err = p.webIdentityCredentials(ctx)
+	default:
+		return awsCredentials{}, fmt.Errorf("none found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or give the pod an IAM role through EKS Pod Identity or IRSA")
+	}
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	p.creds = creds
+	return creds, nil
+}
+
+// containerCredentials fetches credentials from the endpoint EKS Pod Identity
+// and ECS tasks provide.
+func (p *AWSSecretsProvider) containerCredentials(ctx context.Context) (awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if endpoint == "" {
+		endpoint = awsContainerCredentialsHost + os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if path := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); path != "" {
+		// Re-read on every fetch because it is rotated.
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("failed to read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to fetch container credentials: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return awsCredentials{}, fmt.Errorf("container credentials request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var creds struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&creds); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode container credentials: %w", err)
+	}
+	return awsCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+		Expires:         creds.Expiration,
+	}, nil
+}
+
+// assumeRoleWithWebIdentityResponse is the part of the STS response the sidecar uses.
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// webIdentityCredentials exchanges the service account token EKS projects for
+// IAM roles for service accounts for credentials of AWS_ROLE_ARN.
+func (p *AWSSecretsProvider) webIdentityCredentials(ctx context.Context) (awsCredentials, error) {
+	token, err := os.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = awsRoleSessionName
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {os.Getenv("AWS_ROLE_ARN")},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, awsSTSEndpoint(p.cfg.Region), strings.NewReader(form.Encode()))
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to assume role: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return awsCredentials{}, fmt.Errorf("assume role request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var result assumeRoleWithWebIdentityResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode assume role response: %w", err)
+	}
+	return awsCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Expires:         result.Credentials.Expiration,
+	}, nil
+}
+
+// signAWSRequest signs req with AWS Signature Version 4, covering the host
+// and every header already set on it.
+func signAWSRequest(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	credentialScope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + credentialScope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, credentialScope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,190 @@
+package envfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+// DatabaseEnvVar represents a database environment variable
+type DatabaseEnvVar struct {
+	EnvVarName    string `json:"env_var_name"`
+	ConnectionURI string `json:"connection_uri"`
+	// Scope limits the variable to processes whose launcher sets a matching
+	// BIFROST_ENV_SCOPE; empty means every process receives it.
+	Scope string `json:"scope,omitempty"`
+	// SecretRef is the secret reference ConnectionURI was resolved from, if
+	// it was one. Set by FetchDatabaseEnvVars, never by a provider.
+	SecretRef string `json:"-"`
+}
+
+// key identifies the variable within its scope, so the same name may have a
+// different value in each scope.
+func (v DatabaseEnvVar) key() string {
+	if v.Scope == "" {
+		return v.EnvVarName
+	}
+	return v.Scope + "/" + v.EnvVarName
+}
+
+// Names of the DatabaseEnvProvider implementations, used to select one in the
+// configuration.
+const (
+	ProviderBifrost           = "bifrost"
+	ProviderAWSSecretsManager = "aws_secrets_manager"
+	ProviderKubernetesSecret  = "kubernetes_secret"
+)
+
+// DatabaseEnvProvider is a source of the database env vars written to the env
+// file. Values may be secret references, which are resolved after fetching.
+type DatabaseEnvProvider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	DatabaseEnvVars(ctx context.Context) ([]DatabaseEnvVar, error)
+}
+
+// APIProvider reads the deployment's database env vars from the Bifrost API,
+// which points them at the database branches pushes switch to.
+type APIProvider struct {
+	APIURL       string
+	Tokens       *transport.TokenManager
+	DeploymentID string
+}
+
+// Name implements DatabaseEnvProvider.
+func (p *APIProvider) Name() string {
+	return ProviderBifrost
+}
+
+// DatabaseEnvVars implements DatabaseEnvProvider.
+func (p *APIProvider) DatabaseEnvVars(ctx context.Context) ([]DatabaseEnvVar, error) {
+	// Build the API endpoint URL
+	endpoint, err := transport.EndpointURL(p.APIURL, fmt.Sprintf("/api/v1/deployments/%s/database-env-vars", p.DeploymentID))
+	if err != nil {
+		return nil, err
+	}
+	url := endpoint.String()
+
+	// Create HTTP client with timeout
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	// Create request with API key header
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := p.Tokens.SetAuthHeader(req); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	// Make the request
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch database env vars: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Force a fresh token exchange on the next request
+		p.Tokens.Invalidate()
+	}
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var envVars []DatabaseEnvVar
+	if err := json.NewDecoder(resp.Body).Decode(&envVars); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return envVars, nil
+}
+
+// FetchDatabaseEnvVars fetches the database env vars from provider, with
+// secret references resolved.
+func FetchDatabaseEnvVars(ctx context.Context, provider DatabaseEnvProvider, secrets *SecretResolver) ([]DatabaseEnvVar, error) {
+	log.Info("Fetching database environment variables", zap.String("provider", provider.Name()))
+
+	envVars, err := provider.DatabaseEnvVars(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s provider: %w", provider.Name(), err)
+	}
+
+	values := make(map[string]string, len(envVars))
+	for _, envVar := range envVars {
+		values[envVar.key()] = envVar.ConnectionURI
+	}
+	values, err = secrets.ResolveAll(ctx, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve secret references: %w", err)
+	}
+	for i := range envVars {
+		if isSecretRef(envVars[i].ConnectionURI) {
+			envVars[i].SecretRef = envVars[i].ConnectionURI
+		}
+		envVars[i].ConnectionURI = values[envVars[i].key()]
+	}
+	return envVars, nil
+}
+
+// WriteDatabaseEnvFile fetches database connection URIs from provider and writes them to an env file,
+// and returns the variables written. Values that are secret references are resolved first; if any
+// fails, the file is left unchanged.
+func WriteDatabaseEnvFile(ctx context.Context, provider DatabaseEnvProvider, filesDir string, opts Options) ([]DatabaseEnvVar, error) {
+	envVars, err := FetchDatabaseEnvVars(ctx, provider, opts.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no databases, don't create the file
+	if len(envVars) == 0 {
+		log.Info("No database environment variables to inject")
+		return nil, nil
+	}
+
+	// The shared file is always written so variables removed from it don't linger.
+	contents := map[string]*bytes.Buffer{"": {}}
+	for _, envVar := range envVars {
+		content, ok := contents[envVar.Scope]
+		if !ok {
+			content = &bytes.Buffer{}
+			contents[envVar.Scope] = content
+		}
+		line, err := formatExport(envVar.EnvVarName, envVar.ConnectionURI)
+		if err != nil {
+			return nil, fmt.Errorf("%s provider: %w", provider.Name(), err)
+		}
+		content.WriteString(line)
+		log.Info("Added database environment variable",
+			zap.String("envVar", envVar.EnvVarName),
+			zap.String("scope", envVar.Scope))
+	}
+	files := make(map[string][]byte, len(contents))
+	for scope, content := range contents {
+		files[scope] = content.Bytes()
+	}
+	envFiles, err := WriteScoped(filesDir, files, opts.EncryptionKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Successfully wrote database environment variables",
+		zap.Strings("envFiles", envFiles),
+		zap.Int("count", len(envVars)))
+
+	return envVars, nil
+}
@@ -0,0 +1,285 @@
+// Package envfile writes the env files the launcher sources before starting
+// the app: the deployment's database connection URIs, with secret references
+// resolved, optionally encrypted, and split by scope.
+package envfile
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	fileName          = "env.sh"
+	encryptedFileName = "env.sh.enc"
+	// Variables with a scope are written to env.<scope>.sh (or env.<scope>.sh.enc)
+	// instead, which the launcher sources after env.sh when its BIFROST_ENV_SCOPE
+	// matches, so processes sharing a files dir only see their own variables.
+	scopedFilePattern = "env.*.sh"
+
+	// Encrypted env files use the format of
+	// "openssl enc -aes-256-cbc -pbkdf2 -iter 100000 -md sha256", so the
+	// launcher can decrypt them with the openssl CLI and the same key file.
+	envKeyIterations = 100000
+	opensslMagic     = "Salted__"
+	opensslSaltSize  = 8
+)
+
+// Options controls how the database env file is written.
+type Options struct {
+	Secrets *SecretResolver
+	// EncryptionKeyPath names a file shared with the launcher; when set the env
+	// file is written encrypted with it instead of in plain text.
+	EncryptionKeyPath string
+}
+
+var (
+	envScopePattern   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ValidateScope checks that scope can be used in a file name. The empty scope
+// is the shared env file every process sources.
+func ValidateScope(scope string) error {
+	if scope != "" && !envScopePattern.MatchString(scope) {
+		return fmt.Errorf("invalid env scope %q: use letters, digits, '-' and '_'", scope)
+	}
+	return nil
+}
+
+// ValidateEnvVarName checks that name can be exported by a shell.
+func ValidateEnvVarName(name string) error {
+	if !envVarNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid env var name %q: use letters, digits and '_', not starting with a digit", name)
+	}
+	return nil
+}
+
+// Path returns the path of the plain text env file for scope.
+func Path(filesDir, scope string) string {
+	if scope == "" {
+		return filepath.Join(launcher.SidecarDir(filesDir), fileName)
+	}
+	return filepath.Join(launcher.SidecarDir(filesDir), "env."+scope+".sh")
+}
+
+// EncryptedPath returns the path of the encrypted env file for scope.
+func EncryptedPath(filesDir, scope string) string {
+	return Path(filesDir, scope) + ".enc"
+}
+
+// Write atomically replaces the env file for scope that the launcher
+// sources, either env.sh or, with an encryption key, env.sh.enc. The other form
+// is removed so the launcher never reads stale values.
+func Write(filesDir, scope string, content []byte, encryptionKeyPath string) (string, error) {
+	if err := ValidateScope(scope); err != nil {
+		return "", err
+	}
+	path, stale := Path(filesDir, scope), EncryptedPath(filesDir, scope)
+	if encryptionKeyPath != "" {
+		path, stale = stale, path
+		key, err := os.ReadFile(encryptionKeyPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read env encryption key %s: %w", encryptionKeyPath, err)
+		}
+		if content, err = encryptEnvFile(content, key); err != nil {
+			return "", err
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, launcher.Volume.EnvFile); err != nil {
+		return "", fmt.Errorf("failed to write env file %s: %w", tmpPath, err)
+	}
+	// Readable by the app container, which may run as a different user (or only its group in hardened mode).
+	if err := os.Chmod(tmpPath, launcher.Volume.EnvFile); err != nil {
+		return "", fmt.Errorf("failed to set env file permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("failed to replace env file %s: %w", path, err)
+	}
+	if err := os.Remove(stale); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to remove stale env file %s: %w", stale, err)
+	}
+	return path, nil
+}
+
+// WriteScoped writes content for every scope, keyed by scope with ""
+// for the shared file, and removes the files of scopes that no longer have any
+// variables. It returns the paths written, sorted. The sync lock is held
+// throughout, so the launcher sources either the old set of files or the new.
+func WriteScoped(filesDir string, contents map[string][]byte, encryptionKeyPath string) ([]string, error) {
+	for scope := range contents {
+		if err := ValidateScope(scope); err != nil {
+			return nil, err
+		}
+	}
+	lock, err := launcher.LockSync(filesDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	var written []string
+	for scope, content := range contents {
+		path, err := Write(filesDir, scope, content, encryptionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	sort.Strings(written)
+
+	pattern := filepath.Join(launcher.SidecarDir(filesDir), scopedFilePattern)
+	plain, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scoped env files: %w", err)
+	}
+	encrypted, err := filepath.Glob(pattern + ".enc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scoped env files: %w", err)
+	}
+	for _, path := range append(plain, encrypted...) {
+		scope := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".enc"), ".sh")
+		if _, ok := contents[strings.TrimPrefix(scope, "env.")]; ok {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale env file %s: %w", path, err)
+		}
+	}
+	return written, nil
+}
+
+// List returns the shared and scoped env files in the sidecar dir, in either
+// form, with a version derived from their contents.
+func List(filesDir string) ([]launcher.EnvFile, error) {
+	sidecarDir := launcher.SidecarDir(filesDir)
+	var paths []string
+	for _, pattern := range []string{fileName, encryptedFileName, scopedFilePattern, scopedFilePattern + ".enc"} {
+		matches, err := filepath.Glob(filepath.Join(sidecarDir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list env files: %w", err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var files []launcher.EnvFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+		}
+		sum := sha256.Sum256(data)
+		files = append(files, launcher.EnvFile{Path: path, Version: hex.EncodeToString(sum[:8])})
+	}
+	return files, nil
+}
+
+// Read parses the env file for scope the way the launcher sources it: the
+// encrypted form, decrypted with the key file at encryptionKeyPath, if it
+// exists, and else the plain text one. It returns the path read, and an error
+// wrapping fs.ErrNotExist if there is neither.
+func Read(filesDir, scope, encryptionKeyPath string) ([]Var, string, error) {
+	if err := ValidateScope(scope); err != nil {
+		return nil, "", err
+	}
+	path := EncryptedPath(filesDir, scope)
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if encryptionKeyPath == "" {
+			return nil, path, fmt.Errorf("%s is encrypted but no env encryption key is set", path)
+		}
+		key, err := os.ReadFile(encryptionKeyPath)
+		if err != nil {
+			return nil, path, fmt.Errorf("failed to read env encryption key %s: %w", encryptionKeyPath, err)
+		}
+		if content, err = decryptEnvFile(content, key); err != nil {
+			return nil, path, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		path = Path(filesDir, scope)
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, path, fmt.Errorf("failed to read env file: %w", err)
+		}
+	default:
+		return nil, path, fmt.Errorf("failed to read env file: %w", err)
+	}
+	vars, err := Parse(content)
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return vars, path, nil
+}
+
+// encryptEnvFile encrypts plaintext with AES-256-CBC, deriving the key and IV
+// from the key file's contents the way openssl's -pbkdf2 option does.
+func encryptEnvFile(plaintext, keyFile []byte) ([]byte, error) {
+	password := strings.TrimRight(string(keyFile), "\r\n")
+	if password == "" {
+		return nil, fmt.Errorf("env encryption key is empty")
+	}
+	salt := make([]byte, opensslSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	derived, err := pbkdf2.Key(sha256.New, password, salt, envKeyIterations, 32+aes.BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive env encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(bytes.Clone(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	out := make([]byte, 0, len(opensslMagic)+len(salt)+len(padded))
+	out = append(out, opensslMagic...)
+	out = append(out, salt...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, derived[32:]).CryptBlocks(ciphertext, padded)
+	return append(out, ciphertext...), nil
+}
+
+// decryptEnvFile reverses encryptEnvFile, as "openssl enc -d" would.
+func decryptEnvFile(data, keyFile []byte) ([]byte, error) {
+	password := strings.TrimRight(string(keyFile), "\r\n")
+	if password == "" {
+		return nil, fmt.Errorf("env encryption key is empty")
+	}
+	header := len(opensslMagic) + opensslSaltSize
+	if len(data) < header+aes.BlockSize || !bytes.HasPrefix(data, []byte(opensslMagic)) || (len(data)-header)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("not an encrypted env file")
+	}
+	derived, err := pbkdf2.Key(sha256.New, password, data[len(opensslMagic):header], envKeyIterations, 32+aes.BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive env encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	plaintext := make([]byte, len(data)-header)
+	cipher.NewCBCDecrypter(block, derived[32:]).CryptBlocks(plaintext, data[header:])
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("bad decrypt: wrong env encryption key?")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
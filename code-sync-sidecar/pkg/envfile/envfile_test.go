@@ -0,0 +1,222 @@
+package envfile
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+func TestWriteEnvFile_PlainAndEncrypted(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	content := []byte("export DATABASE_URL=\"postgres://localhost/app\"\n")
+
+	path, err := Write(filesDir, "", content, "")
+	require.NoError(t, err)
+	assert.Equal(t, Path(filesDir, ""), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	keyPath := filepath.Join(t.TempDir(), "env.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("correct horse battery staple\n"), 0600))
+	path, err = Write(filesDir, "", content, keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, EncryptedPath(filesDir, ""), path)
+	assert.NoFileExists(t, Path(filesDir, ""), "the plain text file is removed")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "postgres://")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// The launcher decrypts with the openssl CLI.
+	if _, err := exec.LookPath("openssl"); err != nil {
+		t.Skip("openssl not installed")
+	}
+	out, err := exec.Command("openssl", "enc", "-d", "-aes-256-cbc", "-pbkdf2", "-iter", "100000", "-md", "sha256",
+		"-pass", "file:"+keyPath, "-in", path).Output()
+	require.NoError(t, err)
+	assert.Equal(t, content, out)
+}
+
+func TestWriteEnvFile_MissingKey(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+
+	_, err := Write(filesDir, "", []byte("export A=\"1\"\n"), filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to read env encryption key")
+	assert.NoFileExists(t, EncryptedPath(filesDir, ""))
+}
+
+func TestRead(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	_, _, err := Read(filesDir, "", "")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	content := []byte("export DATABASE_URL='postgres://localhost/app'\nexport GREETING='it'\\''s'\n")
+	want := []Var{{Name: "DATABASE_URL", Value: "postgres://localhost/app"}, {Name: "GREETING", Value: "it's"}}
+	_, err = Write(filesDir, "worker", content, "")
+	require.NoError(t, err)
+	vars, path, err := Read(filesDir, "worker", "")
+	require.NoError(t, err)
+	assert.Equal(t, Path(filesDir, "worker"), path)
+	assert.Equal(t, want, vars)
+
+	keyPath := filepath.Join(t.TempDir(), "env.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("correct horse battery staple\n"), 0600))
+	_, err = Write(filesDir, "", content, keyPath)
+	require.NoError(t, err)
+	vars, path, err = Read(filesDir, "", keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, EncryptedPath(filesDir, ""), path)
+	assert.Equal(t, want, vars)
+
+	_, _, err = Read(filesDir, "", "")
+	assert.ErrorContains(t, err, "no env encryption key is set")
+	wrongKey := filepath.Join(t.TempDir(), "wrong.key")
+	require.NoError(t, os.WriteFile(wrongKey, []byte("hunter2\n"), 0600))
+	_, _, err = Read(filesDir, "", wrongKey)
+	assert.Error(t, err, "a wrong key fails the padding check, or very rarely the parse")
+}
+
+func TestWriteScopedEnvFiles(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+
+	written, err := WriteScoped(filesDir, map[string][]byte{
+		"":       []byte("export SHARED=\"1\"\n"),
+		"web":    []byte("export WEB=\"1\"\n"),
+		"worker": []byte("export WORKER=\"1\"\n"),
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		Path(filesDir, ""),
+		Path(filesDir, "web"),
+		Path(filesDir, "worker"),
+	}, written)
+	data, err := os.ReadFile(Path(filesDir, "worker"))
+	require.NoError(t, err)
+	assert.Equal(t, "export WORKER=\"1\"\n", string(data))
+
+	// A scope that no longer has variables loses its file.
+	_, err = WriteScoped(filesDir, map[string][]byte{
+		"":    []byte("export SHARED=\"2\"\n"),
+		"web": []byte("export WEB=\"2\"\n"),
+	}, "")
+	require.NoError(t, err)
+	assert.FileExists(t, Path(filesDir, "web"))
+	assert.NoFileExists(t, Path(filesDir, "worker"))
+
+	_, err = WriteScoped(filesDir, map[string][]byte{"../web": nil}, "")
+	assert.ErrorContains(t, err, "invalid env scope")
+}
+
+func TestWriteDatabaseEnvFile_Scopes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/deployments/deployment1/database-env-vars", r.URL.Path)
+		json.NewEncoder(w).Encode([]DatabaseEnvVar{
+			{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://shared"},
+			{EnvVarName: "QUEUE_URL", ConnectionURI: "redis://worker", Scope: "worker"},
+			{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://web", Scope: "web"},
+		})
+	}))
+	defer server.Close()
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, server.URL, "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+
+	envVars, err := WriteDatabaseEnvFile(context.Background(), &APIProvider{APIURL: server.URL, Tokens: tokens, DeploymentID: "deployment1"}, filesDir, Options{})
+	require.NoError(t, err)
+	assert.Len(t, envVars, 3)
+
+	for scope, want := range map[string]string{
+		"":       "export DATABASE_URL='postgres://shared'\n",
+		"web":    "export DATABASE_URL='postgres://web'\n",
+		"worker": "export QUEUE_URL='redis://worker'\n",
+	} {
+		data, err := os.ReadFile(Path(filesDir, scope))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data), "scope %q", scope)
+	}
+}
+
+func TestWriteDatabaseEnvFile_QuotesValues(t *testing.T) {
+	uri := "postgres://app:p'a\"s$(touch pwned)`id`@db/app\nsslmode=require"
+	envVars := []DatabaseEnvVar{{EnvVarName: "DATABASE_URL", ConnectionURI: uri}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(envVars)
+	}))
+	defer server.Close()
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, server.URL, "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+	provider := &APIProvider{APIURL: server.URL, Tokens: tokens, DeploymentID: "deployment1"}
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+
+	_, err = WriteDatabaseEnvFile(context.Background(), provider, filesDir, Options{})
+	require.NoError(t, err)
+	data, err := os.ReadFile(Path(filesDir, ""))
+	require.NoError(t, err)
+	assert.Equal(t, uri, sourceInShell(t, string(data), "DATABASE_URL"))
+
+	// A value the shell can't hold leaves the file unchanged.
+	envVars[0].ConnectionURI = "postgres://db/app\x00"
+	_, err = WriteDatabaseEnvFile(context.Background(), provider, filesDir, Options{})
+	assert.ErrorContains(t, err, "NUL byte")
+	after, err := os.ReadFile(Path(filesDir, ""))
+	require.NoError(t, err)
+	assert.Equal(t, data, after)
+}
+
+func TestWrite_HardenedMode(t *testing.T) {
+	launcher.Volume = launcher.HardenedModes
+	t.Cleanup(func() { launcher.Volume = launcher.DefaultModes })
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+
+	path, err := Write(filesDir, "", []byte("export A=1\n"), "")
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestList(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	_, err := WriteScoped(filesDir, map[string][]byte{
+		"":    []byte("export DATABASE_URL=\"postgres://shared\"\n"),
+		"web": []byte("export DATABASE_URL=\"postgres://web\"\n"),
+	}, "")
+	require.NoError(t, err)
+
+	before, err := List(filesDir)
+	require.NoError(t, err)
+	require.Len(t, before, 2)
+	assert.Equal(t, Path(filesDir, ""), before[0].Path)
+	assert.Equal(t, Path(filesDir, "web"), before[1].Path)
+
+	// The version follows the env file's contents.
+	_, err = Write(filesDir, "web", []byte("export DATABASE_URL=\"postgres://other\"\n"), "")
+	require.NoError(t, err)
+	after, err := List(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, before[0].Version, after[0].Version)
+	assert.NotEqual(t, before[1].Version, after[1].Version)
+}
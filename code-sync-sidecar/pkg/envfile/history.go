@@ -0,0 +1,298 @@
+package envfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// Every set of env files written is kept as a version under
+// .sidecar/env-history/<id>, with the current version named by a pointer
+// file, so a bad set can be rolled back without a push.
+const (
+	historyDirName     = "env-history"
+	historyCurrentFile = "current"
+	historyVersionFile = "version.json"
+	// Versions are numbered, zero-padded so they sort by name.
+	historyIDFormat = "%06d"
+)
+
+// Version is one set of env files kept in the history.
+type Version struct {
+	ID string `json:"id"`
+	// PushID is the push whose env refresh wrote the files, if any.
+	PushID    string    `json:"push_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Files are the names of the files in the sidecar dir, env files and the
+	// provenance file, as they were when the version was saved.
+	Files []string `json:"files"`
+}
+
+// HistoryDir returns the directory the env file versions are kept in.
+func HistoryDir(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), historyDirName)
+}
+
+func versionDir(filesDir, id string) string {
+	return filepath.Join(HistoryDir(filesDir), id)
+}
+
+// SaveVersion records the env files now in the sidecar dir as a new version
+// and makes it the current one, then removes the oldest versions so at most
+// keep remain. If the files match the current version, that version is
+// returned and nothing is saved.
+func SaveVersion(filesDir, pushID string, keep int, now time.Time) (Version, error) {
+	lock, err := launcher.LockSync(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	defer lock.Unlock()
+
+	files, err := currentFiles(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	versions, current, err := ListVersions(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	for _, version := range versions {
+		if version.ID == current && sameFiles(filesDir, version, files) {
+			return version, nil
+		}
+	}
+
+	next := 1
+	if len(versions) > 0 {
+		last, _ := strconv.Atoi(versions[len(versions)-1].ID)
+		next = last + 1
+	}
+	version := Version{ID: fmt.Sprintf(historyIDFormat, next), PushID: pushID, CreatedAt: now.UTC()}
+	dir := versionDir(filesDir, version.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Version{}, fmt.Errorf("failed to create env history directory %s: %w", dir, err)
+	}
+	for _, name := range sortedNames(files) {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0600); err != nil {
+			os.RemoveAll(dir)
+			return Version{}, fmt.Errorf("failed to save env file %s to history: %w", name, err)
+		}
+		version.Files = append(version.Files, name)
+	}
+	data, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return Version{}, fmt.Errorf("failed to encode env version: %w", err)
+	}
+	// Written last: a directory without it is an interrupted save and is ignored.
+	if err := os.WriteFile(filepath.Join(dir, historyVersionFile), data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return Version{}, fmt.Errorf("failed to save env version %s: %w", version.ID, err)
+	}
+	if err := setCurrentVersion(filesDir, version.ID); err != nil {
+		return Version{}, err
+	}
+
+	versions = append(versions, version)
+	var errs []error
+	for i := 0; i < len(versions)-keep; i++ {
+		if err := os.RemoveAll(versionDir(filesDir, versions[i].ID)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove env version %s: %w", versions[i].ID, err))
+		}
+	}
+	return version, errors.Join(errs...)
+}
+
+// ListVersions returns the saved versions, oldest first, and the ID of the
+// current one, which is empty if there is none.
+func ListVersions(filesDir string) ([]Version, string, error) {
+	entries, err := os.ReadDir(HistoryDir(filesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list env history: %w", err)
+	}
+	var versions []Version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(HistoryDir(filesDir), entry.Name(), historyVersionFile))
+		if err != nil {
+			continue // Interrupted save, or removed since it was listed
+		}
+		var version Version
+		if err := json.Unmarshal(data, &version); err != nil || version.ID != entry.Name() {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+
+	current, err := os.ReadFile(filepath.Join(HistoryDir(filesDir), historyCurrentFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("failed to read the current env version: %w", err)
+	}
+	return versions, strings.TrimSpace(string(current)), nil
+}
+
+// RestoreVersion replaces the env files in the sidecar dir with those of the
+// version with id, or of the one before the current version when id is
+// empty, and makes it the current one. Env files the version doesn't have
+// are removed. The sync lock is held throughout, so the launcher sources
+// either the old set of files or the restored one.
+func RestoreVersion(filesDir, id string) (Version, error) {
+	lock, err := launcher.LockSync(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	defer lock.Unlock()
+
+	versions, current, err := ListVersions(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	version, err := findVersion(versions, current, id)
+	if err != nil {
+		return Version{}, err
+	}
+
+	sidecarDir := launcher.SidecarDir(filesDir)
+	restored := make(map[string]bool, len(version.Files))
+	for _, name := range version.Files {
+		if filepath.Base(name) != name {
+			return Version{}, fmt.Errorf("env version %s names an unsafe file %q", version.ID, name)
+		}
+		data, err := os.ReadFile(filepath.Join(versionDir(filesDir, version.ID), name))
+		if err != nil {
+			return Version{}, fmt.Errorf("failed to read env version %s: %w", version.ID, err)
+		}
+		mode := launcher.Volume.EnvFile
+		if name == provenanceFileName {
+			mode = 0644
+		}
+		path := filepath.Join(sidecarDir, name)
+		tmpPath := path + ".tmp"
+		if err := os.WriteFile(tmpPath, data, mode); err != nil {
+			return Version{}, fmt.Errorf("failed to write env file %s: %w", tmpPath, err)
+		}
+		if err := os.Chmod(tmpPath, mode); err != nil {
+			return Version{}, fmt.Errorf("failed to set env file permissions: %w", err)
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			return Version{}, fmt.Errorf("failed to replace env file %s: %w", path, err)
+		}
+		restored[name] = true
+	}
+
+	files, err := currentFiles(filesDir)
+	if err != nil {
+		return Version{}, err
+	}
+	for name := range files {
+		if restored[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(sidecarDir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Version{}, fmt.Errorf("failed to remove env file %s: %w", name, err)
+		}
+	}
+	return version, setCurrentVersion(filesDir, version.ID)
+}
+
+// findVersion returns the version with id, or the one before current when id
+// is empty.
+func findVersion(versions []Version, current, id string) (Version, error) {
+	if id != "" {
+		for _, version := range versions {
+			if version.ID == id {
+				return version, nil
+			}
+		}
+		return Version{}, fmt.Errorf("env version %q does not exist", id)
+	}
+	for i, version := range versions {
+		if version.ID != current {
+			continue
+		}
+		if i == 0 {
+			return Version{}, fmt.Errorf("env version %s is the oldest kept, there is none to roll back to", current)
+		}
+		return versions[i-1], nil
+	}
+	return Version{}, errors.New("no env version is current, name the version to roll back to")
+}
+
+// currentFiles reads the env files and the provenance file in the sidecar
+// dir, keyed by name.
+func currentFiles(filesDir string) (map[string][]byte, error) {
+	envFiles, err := List(filesDir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(envFiles)+1)
+	for _, envFile := range envFiles {
+		data, err := os.ReadFile(envFile.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file %s: %w", envFile.Path, err)
+		}
+		files[filepath.Base(envFile.Path)] = data
+	}
+	data, err := os.ReadFile(ProvenancePath(filesDir))
+	if err == nil {
+		files[provenanceFileName] = data
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read env provenance: %w", err)
+	}
+	return files, nil
+}
+
+// sameFiles reports whether version holds exactly files.
+func sameFiles(filesDir string, version Version, files map[string][]byte) bool {
+	if len(version.Files) != len(files) {
+		return false
+	}
+	for _, name := range version.Files {
+		content, ok := files[name]
+		if !ok {
+			return false
+		}
+		saved, err := os.ReadFile(filepath.Join(versionDir(filesDir, version.ID), name))
+		if err != nil || !bytes.Equal(saved, content) {
+			return false
+		}
+	}
+	return true
+}
+
+func setCurrentVersion(filesDir, id string) error {
+	path := filepath.Join(HistoryDir(filesDir), historyCurrentFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(id+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write the current env version: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write the current env version: %w", err)
+	}
+	return nil
+}
+
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package envfile
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestEnvHistory(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_, err := RestoreVersion(filesDir, "")
+	assert.ErrorContains(t, err, "no env version is current")
+
+	// The first version has a scoped file; the second drops it.
+	_, err = WriteScoped(filesDir, map[string][]byte{"": []byte("export A='1'\n"), "worker": []byte("export W='1'\n")}, "")
+	require.NoError(t, err)
+	first, err := SaveVersion(filesDir, "", 3, now)
+	require.NoError(t, err)
+	assert.Equal(t, Version{ID: "000001", CreatedAt: now, Files: []string{"env.sh", "env.worker.sh"}}, first)
+
+	again, err := SaveVersion(filesDir, "push-0", 3, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "unchanged files aren't saved again")
+
+	_, err = WriteScoped(filesDir, map[string][]byte{"": []byte("export A='2'\n")}, "")
+	require.NoError(t, err)
+	_, err = RecordProvenance(filesDir, "push-1", ProviderBifrost, []DatabaseEnvVar{{EnvVarName: "A", ConnectionURI: "2"}}, now)
+	require.NoError(t, err)
+	second, err := SaveVersion(filesDir, "push-1", 3, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "000002", second.ID)
+	assert.Equal(t, []string{"env.sh", "env_provenance.json"}, second.Files)
+
+	// Rolling back with no version named restores the one before the current one.
+	restored, err := RestoreVersion(filesDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, restored)
+	content, err := os.ReadFile(Path(filesDir, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "export A='1'\n", string(content))
+	assert.FileExists(t, Path(filesDir, "worker"))
+	assert.NoFileExists(t, ProvenancePath(filesDir), "files the version didn't have are removed")
+	versions, current, err := ListVersions(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, []Version{first, second}, versions)
+	assert.Equal(t, "000001", current)
+
+	_, err = RestoreVersion(filesDir, "")
+	assert.ErrorContains(t, err, "is the oldest kept")
+	_, err = RestoreVersion(filesDir, "000009")
+	assert.ErrorContains(t, err, `env version "000009" does not exist`)
+
+	restored, err = RestoreVersion(filesDir, "000002")
+	require.NoError(t, err)
+	assert.Equal(t, second, restored)
+	assert.NoFileExists(t, Path(filesDir, "worker"))
+	assert.FileExists(t, ProvenancePath(filesDir))
+
+	// Saving beyond keep removes the oldest versions.
+	for i := 3; i <= 5; i++ {
+		_, err = WriteScoped(filesDir, map[string][]byte{"": []byte("export A='" + string(rune('0'+i)) + "'\n")}, "")
+		require.NoError(t, err)
+		_, err = SaveVersion(filesDir, "", 3, now)
+		require.NoError(t, err)
+	}
+	versions, current, err = ListVersions(filesDir)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, "000003", versions[0].ID)
+	assert.Equal(t, "000005", current)
+}
@@ -0,0 +1,117 @@
+package envfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// KubernetesSecretConfig configures reading database env vars from a
+// Kubernetes Secret.
+type KubernetesSecretConfig struct {
+	Name string `yaml:"name"`
+	// Namespace defaults to the pod's.
+	Namespace string `yaml:"namespace"`
+	// Keys maps env var names to keys of the Secret. When empty, every key
+	// that is a valid env var name becomes a variable of the same name.
+	Keys  map[string]string `yaml:"keys"`
+	Scope string            `yaml:"scope"`
+}
+
+// KubernetesSecretProvider reads database env vars from a Secret through the
+// Kubernetes API, which needs permission to get it. Unlike a Secret mounted
+// as env vars, changes reach the app on the next push without a restart.
+type KubernetesSecretProvider struct {
+	Secret KubernetesSecretConfig
+	APIURL string
+	// Client returns the client for the API, which trusts the cluster CA.
+	Client func() (*http.Client, error)
+	// Token returns the bearer token for the API; it is called for every read.
+	Token func() (string, error)
+}
+
+// Name implements DatabaseEnvProvider.
+func (p *KubernetesSecretProvider) Name() string {
+	return ProviderKubernetesSecret
+}
+
+// DatabaseEnvVars implements DatabaseEnvProvider.
+func (p *KubernetesSecretProvider) DatabaseEnvVars(ctx context.Context) ([]DatabaseEnvVar, error) {
+	data, err := p.readSecret(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s/%s: %w", p.Secret.Namespace, p.Secret.Name, err)
+	}
+
+	keys := p.Secret.Keys
+	if len(keys) == 0 {
+		keys = make(map[string]string, len(data))
+		for key := range data {
+			if ValidateEnvVarName(key) == nil {
+				keys[key] = key
+			}
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	envVars := make([]DatabaseEnvVar, 0, len(names))
+	for _, name := range names {
+		value, ok := data[keys[name]]
+		if !ok {
+			return nil, fmt.Errorf("secret %s/%s has no key %q for %s", p.Secret.Namespace, p.Secret.Name, keys[name], name)
+		}
+		envVars = append(envVars, DatabaseEnvVar{EnvVarName: name, ConnectionURI: strings.TrimSpace(string(value)), Scope: p.Secret.Scope})
+	}
+	return envVars, nil
+}
+
+// readSecret returns the decoded data of the Secret.
+func (p *KubernetesSecretProvider) readSecret(ctx context.Context) (map[string][]byte, error) {
+	if p.APIURL == "" {
+		return nil, fmt.Errorf("the Kubernetes API is not available: KUBERNETES_SERVICE_HOST is not set")
+	}
+	if p.Secret.Namespace == "" {
+		return nil, fmt.Errorf("the namespace is unknown; set it in the configuration")
+	}
+	token, err := p.Token()
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the cluster CA: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", p.APIURL, url.PathEscape(p.Secret.Namespace), url.PathEscape(p.Secret.Name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// The API base64-encodes the values, which decoding into []byte reverses.
+	var secret struct {
+		Data map[string][]byte `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return secret.Data, nil
+}
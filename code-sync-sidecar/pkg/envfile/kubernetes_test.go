@@ -0,0 +1,59 @@
+package envfile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSecretProvider(t *testing.T, secret KubernetesSecretConfig) *KubernetesSecretProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sa-token", r.Header.Get("Authorization"))
+		if r.URL.Path != "/api/v1/namespaces/apps/secrets/db-credentials" {
+			http.Error(w, `{"kind": "Status", "reason": "NotFound"}`, http.StatusNotFound)
+			return
+		}
+		// DATABASE_URL=postgres://db/app, REPLICA_URL=postgres://replica/app, "tls.crt"=cert
+		w.Write([]byte(`{"data": {"DATABASE_URL": "cG9zdGdyZXM6Ly9kYi9hcHA=", "REPLICA_URL": "cG9zdGdyZXM6Ly9yZXBsaWNhL2FwcAo=", "tls.crt": "Y2VydA=="}}`))
+	}))
+	t.Cleanup(server.Close)
+	return &KubernetesSecretProvider{
+		Secret: secret,
+		APIURL: server.URL,
+		Client: func() (*http.Client, error) { return server.Client(), nil },
+		Token:  func() (string, error) { return "sa-token", nil },
+	}
+}
+
+func TestKubernetesSecretProvider_DatabaseEnvVars(t *testing.T) {
+	provider := newTestSecretProvider(t, KubernetesSecretConfig{Name: "db-credentials", Namespace: "apps"})
+	envVars, err := provider.DatabaseEnvVars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/app"},
+		{EnvVarName: "REPLICA_URL", ConnectionURI: "postgres://replica/app"},
+	}, envVars, "keys that aren't env var names are skipped")
+
+	provider = newTestSecretProvider(t, KubernetesSecretConfig{
+		Name:      "db-credentials",
+		Namespace: "apps",
+		Keys:      map[string]string{"READ_DATABASE_URL": "REPLICA_URL"},
+		Scope:     "worker",
+	})
+	envVars, err = provider.DatabaseEnvVars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []DatabaseEnvVar{{EnvVarName: "READ_DATABASE_URL", ConnectionURI: "postgres://replica/app", Scope: "worker"}}, envVars)
+
+	provider.Secret.Keys = map[string]string{"DATABASE_URL": "url"}
+	_, err = provider.DatabaseEnvVars(context.Background())
+	assert.ErrorContains(t, err, `secret apps/db-credentials has no key "url" for DATABASE_URL`)
+
+	provider.Secret.Name = "other"
+	_, err = provider.DatabaseEnvVars(context.Background())
+	assert.ErrorContains(t, err, "failed to read secret apps/other: API request failed with status 404")
+}
@@ -0,0 +1,133 @@
+package envfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// provenanceFileName is written to the sidecar dir next to the env files,
+// recording where each variable in them came from.
+const provenanceFileName = "env_provenance.json"
+
+// Where an env var's value came from, as recorded in Provenance.Source.
+const (
+	// SourceDatabase is the database env vars endpoint of the Bifrost API.
+	SourceDatabase = "database"
+	// SourcePush is the same endpoint, after a push's database branch
+	// updates changed the value.
+	SourcePush = "push"
+	// SourceSecretProvider is a secret store: a Vault reference, or the AWS
+	// Secrets Manager or Kubernetes Secret provider.
+	SourceSecretProvider = "secret_provider"
+)
+
+// Provenance records where one env var's value came from and when it last
+// changed. The value itself is never recorded.
+type Provenance struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope,omitempty"`
+	// Source is SourceDatabase, SourcePush or SourceSecretProvider.
+	Source string `json:"source"`
+	// Provider is the DatabaseEnvProvider the variable was fetched from.
+	Provider string `json:"provider"`
+	// SecretRef is the reference the value was resolved from, e.g.
+	// "vault:kv/data/app#API_KEY", if it was one.
+	SecretRef string `json:"secret_ref,omitempty"`
+	// PushID is the push whose env refresh last changed the value.
+	PushID string `json:"push_id,omitempty"`
+	// Version changes whenever the value does.
+	Version   string    `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProvenancePath returns the path of the provenance file in filesDir.
+func ProvenancePath(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), provenanceFileName)
+}
+
+// ReadProvenance returns the provenance of the variables in the env files,
+// sorted by scope and name, or nothing if none has been recorded.
+func ReadProvenance(filesDir string) ([]Provenance, error) {
+	data, err := os.ReadFile(ProvenancePath(filesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env provenance: %w", err)
+	}
+	var records []Provenance
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse env provenance: %w", err)
+	}
+	return records, nil
+}
+
+// RecordProvenance replaces the provenance file with a record for each of
+// envVars, just written to the env files by pushID from provider, and returns
+// the records. A variable whose value didn't change keeps its record.
+func RecordProvenance(filesDir, pushID, provider string, envVars []DatabaseEnvVar, now time.Time) ([]Provenance, error) {
+	previous, err := ReadProvenance(filesDir)
+	if err != nil {
+		// Start over rather than keep a broken file around.
+		previous = nil
+	}
+	byKey := make(map[string]Provenance, len(previous))
+	for _, record := range previous {
+		byKey[DatabaseEnvVar{EnvVarName: record.Name, Scope: record.Scope}.key()] = record
+	}
+
+	records := make([]Provenance, 0, len(envVars))
+	for _, envVar := range envVars {
+		sum := sha256.Sum256([]byte(envVar.ConnectionURI))
+		record := Provenance{
+			Name:      envVar.EnvVarName,
+			Scope:     envVar.Scope,
+			Source:    SourceDatabase,
+			Provider:  provider,
+			SecretRef: envVar.SecretRef,
+			PushID:    pushID,
+			Version:   hex.EncodeToString(sum[:8]),
+			UpdatedAt: now.UTC(),
+		}
+		old, seen := byKey[envVar.key()]
+		switch {
+		case seen && old.Version == record.Version && old.Provider == record.Provider && old.SecretRef == record.SecretRef:
+			record = old
+		case record.SecretRef != "" || provider == ProviderAWSSecretsManager || provider == ProviderKubernetesSecret:
+			record.Source = SourceSecretProvider
+		case seen && pushID != "":
+			record.Source = SourcePush
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Scope != records[j].Scope {
+			return records[i].Scope < records[j].Scope
+		}
+		return records[i].Name < records[j].Name
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode env provenance: %w", err)
+	}
+	path := ProvenancePath(filesDir)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write env provenance %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("failed to replace env provenance %s: %w", path, err)
+	}
+	return records, nil
+}
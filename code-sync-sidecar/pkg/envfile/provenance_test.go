@@ -0,0 +1,64 @@
+package envfile
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestRecordProvenance(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	records, err := ReadProvenance(filesDir)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	records, err = RecordProvenance(filesDir, "push-1", ProviderBifrost, []DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/app"},
+		{EnvVarName: "API_KEY", ConnectionURI: "secret", SecretRef: "vault:kv/data/app#API_KEY"},
+		{EnvVarName: "QUEUE_URL", ConnectionURI: "redis://queue", Scope: "worker"},
+	}, first)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, Provenance{Name: "API_KEY", Source: SourceSecretProvider, Provider: ProviderBifrost, SecretRef: "vault:kv/data/app#API_KEY",
+		PushID: "push-1", Version: records[0].Version, UpdatedAt: first}, records[0])
+	assert.Equal(t, "DATABASE_URL", records[1].Name)
+	assert.Equal(t, SourceDatabase, records[1].Source)
+	assert.Equal(t, "worker", records[2].Scope, "scoped variables sort after the shared ones")
+
+	read, err := ReadProvenance(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, records, read)
+
+	// A push that switches a branch changes DATABASE_URL; the rest keep their records.
+	second := first.Add(time.Hour)
+	records, err = RecordProvenance(filesDir, "push-2", ProviderBifrost, []DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/branch"},
+		{EnvVarName: "API_KEY", ConnectionURI: "secret", SecretRef: "vault:kv/data/app#API_KEY"},
+	}, second)
+	require.NoError(t, err)
+	require.Len(t, records, 2, "removed variables are dropped")
+	assert.Equal(t, read[0], records[0])
+	assert.Equal(t, SourcePush, records[1].Source)
+	assert.Equal(t, "push-2", records[1].PushID)
+	assert.Equal(t, second, records[1].UpdatedAt)
+	assert.NotEqual(t, read[1].Version, records[1].Version)
+
+	// Secret store providers are secret providers whatever the value.
+	records, err = RecordProvenance(filesDir, "push-3", ProviderAWSSecretsManager, []DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/branch"},
+	}, second)
+	require.NoError(t, err)
+	assert.Equal(t, SourceSecretProvider, records[0].Source)
+	assert.Equal(t, "push-3", records[0].PushID)
+
+	require.NoError(t, os.WriteFile(ProvenancePath(filesDir), []byte("{"), 0644))
+	_, err = ReadProvenance(filesDir)
+	assert.ErrorContains(t, err, "failed to parse env provenance")
+}
@@ -0,0 +1,145 @@
+package envfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultRefPrefix marks an env value as a reference to a secret in Vault,
+// written "vault:<path>#<key>", e.g. "vault:kv/data/app#API_KEY". The value is
+// resolved when the env file is written, so the secret itself never passes
+// through the Bifrost API.
+const vaultRefPrefix = "vault:"
+
+const vaultRequestTimeout = 10 * time.Second
+
+// SecretsConfig configures the stores that secret references in env values
+// are resolved from.
+type SecretsConfig struct {
+	Vault VaultConfig `yaml:"vault"`
+}
+
+// VaultConfig configures reading "vault:<path>#<key>" references from HashiCorp Vault.
+type VaultConfig struct {
+	Address string `yaml:"address"`
+	// TokenPath is a file holding the Vault token, re-read on every resolution.
+	TokenPath string `yaml:"token_path"`
+	Namespace string `yaml:"namespace"`
+}
+
+// SecretResolver replaces secret references in env values with the secrets
+// they point to. A nil resolver, or one without Vault configured, fails on
+// any reference.
+type SecretResolver struct {
+	vault  VaultConfig
+	client *http.Client
+}
+
+// NewSecretResolver returns a resolver for the configured secret stores.
+func NewSecretResolver(cfg SecretsConfig) *SecretResolver {
+	return &SecretResolver{
+		vault:  cfg.Vault,
+		client: &http.Client{Timeout: vaultRequestTimeout},
+	}
+}
+
+// isSecretRef reports whether value is a secret reference rather than a literal value.
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, vaultRefPrefix)
+}
+
+// parseVaultRef splits "vault:<path>#<key>" into its path and key.
+func parseVaultRef(ref string) (path, key string, err error) {
+	path, key, ok := strings.Cut(strings.TrimPrefix(ref, vaultRefPrefix), "#")
+	path = strings.Trim(path, "/")
+	if !ok || path == "" || key == "" {
+		return "", "", fmt.Errorf("invalid secret reference %q: use vault:<path>#<key>", ref)
+	}
+	return path, key, nil
+}
+
+// ResolveAll resolves every secret reference in values, keyed by env var
+// name, reading each Vault path once. Literal values are returned unchanged.
+func (sr *SecretResolver) ResolveAll(ctx context.Context, values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(values))
+	secrets := make(map[string]map[string]any)
+	for name, value := range values {
+		if !isSecretRef(value) {
+			resolved[name] = value
+			continue
+		}
+		path, key, err := parseVaultRef(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if sr == nil || sr.vault.Address == "" {
+			return nil, fmt.Errorf("%s references a Vault secret, but secrets.vault.address is not set", name)
+		}
+		data, ok := secrets[path]
+		if !ok {
+			if data, err = sr.readVault(ctx, path); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			secrets[path] = data
+		}
+		secret, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("%s: Vault secret %s has no key %q", name, path, key)
+		}
+		str, ok := secret.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: Vault secret %s key %q is not a string", name, path, key)
+		}
+		resolved[name] = str
+	}
+	return resolved, nil
+}
+
+// vaultSecretResponse is the part of a Vault read response the sidecar uses.
+// KV version 2 nests the secret one level deeper, next to its metadata.
+type vaultSecretResponse struct {
+	Data map[string]any `json:"data"`
+}
+
+// readVault reads the secret at path, from either a KV version 1 or version 2 mount.
+func (sr *SecretResolver) readVault(ctx context.Context, path string) (map[string]any, error) {
+	token, err := os.ReadFile(sr.vault.TokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault token %s: %w", sr.vault.TokenPath, err)
+	}
+	url := strings.TrimRight(sr.vault.Address, "/") + "/v1/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", strings.TrimSpace(string(token)))
+	if sr.vault.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", sr.vault.Namespace)
+	}
+
+	resp, err := sr.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to read Vault secret %s: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var secret vaultSecretResponse
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode Vault secret %s: %w", path, err)
+	}
+	if inner, ok := secret.Data["data"].(map[string]any); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			return inner, nil
+		}
+	}
+	return secret.Data, nil
+}
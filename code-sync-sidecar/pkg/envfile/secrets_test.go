@@ -0,0 +1,76 @@
+package envfile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVault(t *testing.T) (*SecretResolver, *int) {
+	t.Helper()
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Vault-Namespace"))
+		switch r.URL.Path {
+		case "/v1/kv/data/app":
+			w.Write([]byte(`{"data": {"data": {"API_KEY": "s3cret", "PORT": 8080}, "metadata": {"version": 3}}}`))
+		case "/v1/secret/legacy":
+			w.Write([]byte(`{"data": {"PASSWORD": "hunter2"}}`))
+		default:
+			http.Error(w, `{"errors": []}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("vault-token\n"), 0600))
+	return NewSecretResolver(SecretsConfig{Vault: VaultConfig{Address: server.URL, TokenPath: tokenPath, Namespace: "team-a"}}), &reads
+}
+
+func TestSecretResolver_ResolveAll(t *testing.T) {
+	resolver, reads := newTestVault(t)
+
+	resolved, err := resolver.ResolveAll(context.Background(), map[string]string{
+		"DATABASE_URL": "postgres://localhost/app",
+		"API_KEY":      "vault:kv/data/app#API_KEY",
+		"API_KEY_COPY": "vault:/kv/data/app#API_KEY",
+		"PASSWORD":     "vault:secret/legacy#PASSWORD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DATABASE_URL": "postgres://localhost/app",
+		"API_KEY":      "s3cret",
+		"API_KEY_COPY": "s3cret",
+		"PASSWORD":     "hunter2",
+	}, resolved)
+	assert.Equal(t, 2, *reads, "each Vault path is read once")
+}
+
+func TestSecretResolver_Errors(t *testing.T) {
+	resolver, _ := newTestVault(t)
+	ctx := context.Background()
+
+	_, err := resolver.ResolveAll(ctx, map[string]string{"A": "vault:kv/data/app"})
+	assert.ErrorContains(t, err, "use vault:<path>#<key>")
+	_, err = resolver.ResolveAll(ctx, map[string]string{"A": "vault:kv/data/app#MISSING"})
+	assert.ErrorContains(t, err, `has no key "MISSING"`)
+	_, err = resolver.ResolveAll(ctx, map[string]string{"A": "vault:kv/data/app#PORT"})
+	assert.ErrorContains(t, err, "is not a string")
+	_, err = resolver.ResolveAll(ctx, map[string]string{"A": "vault:kv/data/other#KEY"})
+	assert.ErrorContains(t, err, "status 404")
+
+	_, err = NewSecretResolver(SecretsConfig{}).ResolveAll(ctx, map[string]string{"A": "vault:kv/data/app#KEY"})
+	assert.ErrorContains(t, err, "secrets.vault.address is not set")
+	var none *SecretResolver
+	resolved, err := none.ResolveAll(ctx, map[string]string{"A": "literal"})
+	require.NoError(t, err)
+	assert.Equal(t, "literal", resolved["A"])
+}
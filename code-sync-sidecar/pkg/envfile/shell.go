@@ -0,0 +1,239 @@
+package envfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Var is a variable exported by an env file.
+type Var struct {
+	Name  string
+	Value string
+}
+
+// escapeEnvValue quotes value for a POSIX shell. Inside single quotes nothing
+// is special, so only single quotes need escaping: each one closes the quotes,
+// adds an escaped quote and reopens them, and the shell reads back exactly value.
+//
+//	it's  ->  'it'\''s'
+func escapeEnvValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// formatExport returns the line of an env file that exports name as value.
+// Shell variables can't hold NUL bytes, so a value with one is rejected rather
+// than silently truncated when the launcher sources the file.
+func formatExport(name, value string) (string, error) {
+	if err := ValidateEnvVarName(name); err != nil {
+		return "", err
+	}
+	if strings.IndexByte(value, 0) >= 0 {
+		return "", fmt.Errorf("value of env var %s contains a NUL byte, which a shell variable can't hold", name)
+	}
+	return "export " + name + "=" + escapeEnvValue(value) + "\n", nil
+}
+
+// Parse reads the variables an env file exports, in order. It understands the
+// assignments the sidecar writes and the quoting a shell would apply to them
+// (single and double quotes and backslash escapes), so quoted values may span
+// lines; blank lines and comments are skipped. It doesn't expand variables or
+// run commands, and rejects lines that aren't assignments.
+func Parse(content []byte) ([]Var, error) {
+	p := &envParser{src: content, line: 1}
+	var vars []Var
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return vars, nil
+		}
+		v, err := p.assignment()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		vars = append(vars, v)
+	}
+}
+
+// Names returns the names of the variables an env file exports. A file Parse
+// rejects is scanned line by line instead, so a diagnostics bundle still lists
+// what it can.
+func Names(content []byte) []string {
+	if vars, err := Parse(content); err == nil {
+		names := make([]string, len(vars))
+		for i, v := range vars {
+			names[i] = v.Name
+		}
+		return names
+	}
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export ")
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), "="); ok && ValidateEnvVarName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type envParser struct {
+	src  []byte
+	pos  int
+	line int
+}
+
+func (p *envParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *envParser) peek() byte { return p.src[p.pos] }
+
+func (p *envParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipBlank skips whitespace, newlines and comments between assignments.
+func (p *envParser) skipBlank() {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\n' || c == ';':
+			p.next()
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipSpaces skips spaces and tabs within a line.
+func (p *envParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.next()
+	}
+}
+
+// assignment reads "[export] NAME=value" up to the end of its line.
+func (p *envParser) assignment() (Var, error) {
+	name := p.bareWord()
+	if name == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipSpaces()
+		name = p.bareWord()
+	}
+	if p.eof() || p.peek() != '=' {
+		return Var{}, fmt.Errorf("expected NAME=value")
+	}
+	if err := ValidateEnvVarName(name); err != nil {
+		return Var{}, err
+	}
+	p.next()
+	value, err := p.value()
+	if err != nil {
+		return Var{}, fmt.Errorf("env var %s: %w", name, err)
+	}
+	p.skipSpaces()
+	if !p.eof() {
+		switch p.peek() {
+		case '\n', ';':
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return Var{}, fmt.Errorf("env var %s: unexpected %q after the value", name, p.peek())
+		}
+	}
+	return Var{Name: name, Value: value}, nil
+}
+
+// bareWord reads the characters that may make up a variable name.
+func (p *envParser) bareWord() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			break
+		}
+		p.next()
+	}
+	return string(p.src[start:p.pos])
+}
+
+// value reads a shell word: runs of unquoted, single-quoted and double-quoted
+// text, ending at an unquoted space, tab, newline or ';'. Unquoted characters
+// that the shell would expand or treat as an operator are rejected.
+func (p *envParser) value() (string, error) {
+	var b strings.Builder
+	for !p.eof() {
+		switch c := p.peek(); c {
+		case ' ', '\t', '\n', ';':
+			return b.String(), nil
+		case '\'':
+			p.next()
+			for {
+				if p.eof() {
+					return "", fmt.Errorf("unterminated single quote")
+				}
+				c := p.next()
+				if c == '\'' {
+					break
+				}
+				b.WriteByte(c)
+			}
+		case '"':
+			p.next()
+			if err := p.doubleQuoted(&b); err != nil {
+				return "", err
+			}
+		case '\\':
+			p.next()
+			if p.eof() {
+				return b.String(), nil
+			}
+			if c := p.next(); c != '\n' { // A backslash-newline continues the line
+				b.WriteByte(c)
+			}
+		case '$', '`', '~', '|', '&', '<', '>', '(', ')':
+			return "", fmt.Errorf("unquoted %q is special to the shell", c)
+		default:
+			b.WriteByte(p.next())
+		}
+	}
+	return b.String(), nil
+}
+
+// doubleQuoted reads the rest of a double-quoted string, after its opening quote.
+func (p *envParser) doubleQuoted(b *strings.Builder) error {
+	for {
+		if p.eof() {
+			return fmt.Errorf("unterminated double quote")
+		}
+		switch c := p.next(); c {
+		case '"':
+			return nil
+		case '\\':
+			if p.eof() {
+				return fmt.Errorf("unterminated double quote")
+			}
+			switch c := p.next(); c {
+			case '$', '`', '"', '\\':
+				b.WriteByte(c)
+			case '\n':
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			}
+		case '$', '`':
+			return fmt.Errorf("%q in double quotes would be expanded by the shell", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
@@ -0,0 +1,491 @@
+// Package launch runs the app in its container on the sidecar's behalf, as
+// "code-sync-sidecar launch -- <command>". It replaces the rsync launcher
+// script and keeps its contract with the sidecar: the PID, exit reason and app
+// status files in the launcher dir, the handshake, and the reload signals. It
+// starts the command without a shell, so its arguments are passed as given,
+// and reads the env files itself under the sync lock, so the app's environment
+// is built from one consistent set of them on every start.
+package launch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	stdlog "log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	// DefaultFilesDir and DefaultAppRoot are the launcher script's defaults for
+	// WATCH_DIR and APP_ROOT.
+	DefaultFilesDir = "/app-files"
+	DefaultAppRoot  = "/app"
+
+	// dirPoll is how often the launcher checks for the directories the sidecar
+	// creates before it starts the app.
+	dirPoll = 2 * time.Second
+	// checkInterval is how often the launcher checks that the app is running.
+	checkInterval = time.Second
+	// stopTimeout is how long an app being stopped has to exit after SIGTERM
+	// before it is killed.
+	stopTimeout = 5 * time.Second
+	// defaultDrainTimeout applies when the handshake of an overlapping reload
+	// doesn't set one.
+	defaultDrainTimeout = 30 * time.Second
+	// groupPoll is how often a stopping process group is checked.
+	groupPoll = 100 * time.Millisecond
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Options configures a Launcher. The launch command fills them from the same
+// environment variables the launcher script reads.
+type Options struct {
+	// FilesDir is the volume shared with the sidecar (WATCH_DIR).
+	FilesDir string
+	// AppRoot is where the synced code is copied on a reload (APP_ROOT).
+	AppRoot string
+	// Name tells several launchers sharing FilesDir apart
+	// (BIFROST_LAUNCHER_NAME). It defaults to launcher.MainLauncher.
+	Name string
+	// Command is the app's command and arguments, run without a shell.
+	Command []string
+	// EnvScope selects the scoped env file sourced after the shared one
+	// (BIFROST_ENV_SCOPE).
+	EnvScope string
+	// EnvKeyPath is the key file for encrypted env files (BIFROST_ENV_KEY_PATH).
+	EnvKeyPath string
+	// Environ is the environment the app starts from, usually os.Environ().
+	Environ []string
+	// Stdout and Stderr receive the app's output; the launcher logs to Stdout.
+	Stdout, Stderr io.Writer
+}
+
+// Launcher runs the app and restarts it when the sidecar signals a reload or
+// it exits. It isn't safe for concurrent use; Run does all the work.
+type Launcher struct {
+	opts   Options
+	suffix string
+	logger *stdlog.Logger
+	// app is the running app, or nil. old is the app an overlapping reload is
+	// replacing, kept running until the new one is ready.
+	app, old *appProcess
+}
+
+// appProcess is a started app, the leader of its own process group.
+type appProcess struct {
+	pid  int
+	done chan struct{}
+}
+
+// New checks opts and returns a Launcher for them.
+func New(opts Options) (*Launcher, error) {
+	if err := checkPlatform(); err != nil {
+		return nil, err
+	}
+	if len(opts.Command) == 0 {
+		return nil, errors.New("no command to launch")
+	}
+	if opts.Name == "" {
+		opts.Name = launcher.MainLauncher
+	}
+	if !namePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf("launcher name %q may only contain letters, digits, - and _", opts.Name)
+	}
+	if err := envfile.ValidateScope(opts.EnvScope); err != nil {
+		return nil, err
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	l := &Launcher{opts: opts, logger: stdlog.New(opts.Stdout, "[code-sync] ", 0)}
+	// The main launcher's files keep the names the sidecar has always read.
+	if opts.Name != launcher.MainLauncher {
+		l.suffix = "." + opts.Name
+	}
+	return l, nil
+}
+
+// Run starts the app and supervises it until it receives SIGTERM or SIGINT on
+// signals, when it stops the app and returns nil. SIGHUP reloads the app as
+// the handshake says.
+func (l *Launcher) Run(signals <-chan os.Signal) (err error) {
+	for !isDir(launcher.SidecarDir(l.opts.FilesDir)) || !isDir(launcher.Dir(l.opts.FilesDir)) {
+		l.logger.Print("Waiting for directories to be created")
+		select {
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return nil
+			}
+		case <-time.After(dirPoll):
+		}
+	}
+
+	exitReason := l.launcherFile(launcher.ExitReasonFile)
+	os.Remove(exitReason)
+	defer func() {
+		if err != nil {
+			writeFile(exitReason, fmt.Sprintf("Launcher failed: %v\n", err))
+		}
+	}()
+	if err := writeFile(filepath.Join(launcher.Dir(l.opts.FilesDir), l.opts.Name+".pid"), fmt.Sprintf("%d\n", os.Getpid())); err != nil {
+		return err
+	}
+	// Only the names of the non-empty variables, never their values, so the
+	// sidecar can check a push's required env before reloading the app.
+	if err := writeFile(filepath.Join(launcher.Dir(l.opts.FilesDir), "env_names"), envNames(l.opts.Environ)); err != nil {
+		return err
+	}
+
+	l.logger.Printf("Launching %s, files dir: %s, app root: %s", quoteCommand(l.opts.Command), l.opts.FilesDir, l.opts.AppRoot)
+	l.startApp(false)
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				l.reload()
+				continue
+			}
+			l.logger.Printf("Received %v, shutting down", sig)
+			writeFile(exitReason, "Launcher received SIGTERM or SIGINT\n")
+			l.stopApp(l.app, syscall.SIGTERM, stopTimeout)
+			l.stopApp(l.old, syscall.SIGTERM, stopTimeout)
+			return nil
+		case <-ticker.C:
+			if l.app == nil {
+				l.startApp(false)
+			} else if !groupAlive(l.app.pid) {
+				l.logger.Printf("Application process group %d exited. Restarting.", l.app.pid)
+				l.startApp(false)
+			}
+		}
+	}
+}
+
+// reload handles SIGHUP. An overlapping reload signals three times: to start
+// the new app next to the old one, then to drain the old one once the new one
+// is ready, or to abort and keep the old one if it never is.
+func (l *Launcher) reload() {
+	hs, err := l.readHandshake()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.logger.Printf("Ignoring the handshake: %v", err)
+	}
+	switch hs.OverlapPhase {
+	case launcher.OverlapDrain:
+		l.logger.Print("Received SIGHUP, new application is ready")
+		sig, err := launcher.ParseSignal(hs.DrainSignal)
+		if hs.DrainSignal == "" || err != nil {
+			sig = syscall.SIGTERM
+		}
+		timeout := defaultDrainTimeout
+		if hs.DrainTimeoutSeconds > 0 {
+			timeout = time.Duration(hs.DrainTimeoutSeconds) * time.Second
+		}
+		l.drainOld(sig, timeout)
+	case launcher.OverlapAbort:
+		l.logger.Print("Received SIGHUP, new application never became ready; keeping the previous one")
+		l.stopApp(l.app, syscall.SIGTERM, stopTimeout)
+		l.app = nil
+		if l.old != nil {
+			l.app, l.old = l.old, nil
+			os.Rename(l.appPIDFile(true), l.appPIDFile(false))
+			os.Rename(l.appPGIDFile(true), l.appPGIDFile(false))
+		}
+	case launcher.OverlapStart:
+		l.logger.Print("Received SIGHUP, starting application alongside the running one")
+		l.updateFiles()
+		l.startApp(true)
+	default:
+		l.logger.Print("Received SIGHUP, restarting application")
+		l.updateFiles()
+		l.startApp(false)
+	}
+}
+
+// startApp starts the app with a fresh environment, first stopping the one
+// running unless keepOld has it replaced only once the new one is ready.
+func (l *Launcher) startApp(keepOld bool) {
+	env := l.appEnv(keepOld)
+	if keepOld && l.app != nil {
+		// Left over from an overlapping reload that never finished.
+		l.drainOld(syscall.SIGTERM, stopTimeout)
+		l.old, l.app = l.app, nil
+		os.Rename(l.appPIDFile(false), l.appPIDFile(true))
+		os.Rename(l.appPGIDFile(false), l.appPGIDFile(true))
+	} else if l.app != nil {
+		l.stopApp(l.app, syscall.SIGTERM, stopTimeout)
+		l.app = nil
+	}
+
+	command := quoteCommand(l.opts.Command)
+	cmd := exec.Command(l.opts.Command[0], l.opts.Command[1:]...)
+	cmd.Env = env
+	cmd.Stdout = l.opts.Stdout
+	cmd.Stderr = l.opts.Stderr
+	setNewSession(cmd)
+	if err := cmd.Start(); err != nil {
+		l.logger.Printf("Failed to start application: %v", err)
+		writeFile(l.launcherFile(launcher.AppStatusFile), fmt.Sprintf("Application command (%s) failed to start: %v\n", command, err))
+		return
+	}
+	app := &appProcess{pid: cmd.Process.Pid, done: make(chan struct{})}
+	l.app = app
+	// In a new session the process group ID is the PID.
+	writeFile(l.appPIDFile(false), fmt.Sprintf("%d\n", app.pid))
+	writeFile(l.appPGIDFile(false), fmt.Sprintf("%d\n", app.pid))
+	l.logger.Printf("Application started with PID %d", app.pid)
+
+	statusFile := l.launcherFile(launcher.AppStatusFile)
+	go func() {
+		defer close(app.done)
+		cmd.Wait()
+		writeFile(statusFile, fmt.Sprintf("Application command (%s) exited with status %d\n", command, exitStatus(cmd.ProcessState)))
+	}()
+}
+
+// appEnv returns the app's environment: the launcher's own, with the
+// handshake's variables and then the env files' on top. It is rebuilt on
+// every start, so a variable removed from the env files is gone from the app.
+func (l *Launcher) appEnv(keepOld bool) []string {
+	env := slices.Clone(l.opts.Environ)
+	for _, name := range []string{"BIFROST_LAUNCHER_HANDSHAKE", "BIFROST_PUSH_ID", "BIFROST_RELOAD_REASON", "BIFROST_APP_READY_FILE"} {
+		env = unsetEnv(env, name)
+	}
+	hs, err := l.readHandshake()
+	switch {
+	case err == nil:
+		env = setEnv(env, "BIFROST_LAUNCHER_HANDSHAKE", launcher.HandshakePath(l.opts.FilesDir))
+		if hs.PushID != "" {
+			env = setEnv(env, "BIFROST_PUSH_ID", hs.PushID)
+		}
+		if hs.Reason != "" {
+			env = setEnv(env, "BIFROST_RELOAD_REASON", string(hs.Reason))
+		}
+		// The new app of an overlapping reload creates this file once it is serving.
+		if keepOld && hs.ReadyFile != "" {
+			env = setEnv(env, "BIFROST_APP_READY_FILE", hs.ReadyFile)
+		}
+		l.logger.Printf("Setting BIFROST_PUSH_ID=%q from the handshake (reason: %s)", hs.PushID, hs.Reason)
+	case errors.Is(err, fs.ErrNotExist):
+		l.logger.Print("No handshake yet, BIFROST_PUSH_ID is unset")
+	default:
+		l.logger.Printf("Ignoring the handshake: %v", err)
+	}
+	// Lists the paths each push changed, for frameworks with their own hot-reload.
+	env = setEnv(env, "BIFROST_CHANGES_FILE", launcher.ChangeEventPath(l.opts.FilesDir))
+
+	unlock := l.lockSync()
+	defer unlock()
+	scopes := []string{""}
+	if l.opts.EnvScope != "" {
+		scopes = append(scopes, l.opts.EnvScope)
+	}
+	for _, scope := range scopes {
+		vars, path, err := envfile.Read(l.opts.FilesDir, scope, l.opts.EnvKeyPath)
+		switch {
+		case err == nil:
+			l.logger.Printf("Loaded %d environment variables from %s", len(vars), path)
+			for _, v := range vars {
+				env = setEnv(env, v.Name, v.Value)
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			l.logger.Printf("No environment file found at %s", path)
+		default:
+			l.logger.Printf("Skipping environment file: %v", err)
+		}
+	}
+	return env
+}
+
+// updateFiles copies the synced code to the app root with the provisioned
+// rsync, as the launcher script does: the top-level entries of the files dir,
+// or in swap mode of the release "current" points to, except hidden ones.
+func (l *Launcher) updateFiles() {
+	rsync := launcher.RsyncPath(l.opts.FilesDir)
+	if info, err := os.Stat(rsync); err != nil || info.Mode()&0111 == 0 {
+		l.logger.Printf("Warning: No executable rsync binary found at %s", rsync)
+		return
+	}
+	unlock := l.lockSync()
+	defer unlock()
+
+	source := l.opts.FilesDir
+	if info, err := os.Lstat(filepath.Join(source, "current")); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		source = filepath.Join(source, "current")
+	}
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		l.logger.Printf("Error: failed to list %s: %v", source, err)
+		return
+	}
+	args := []string{"-a", "--delete", "--exclude", ".sidecar/", "--exclude", ".launcher/"}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			args = append(args, filepath.Join(source, entry.Name()))
+		}
+	}
+	if len(args) == 6 {
+		l.logger.Printf("No files to sync from %s", source)
+		return
+	}
+	if err := os.MkdirAll(l.opts.AppRoot, 0755); err != nil {
+		l.logger.Printf("Error: failed to create %s: %v", l.opts.AppRoot, err)
+		return
+	}
+	l.logger.Printf("Syncing files from %s to %s", source, l.opts.AppRoot)
+	cmd := exec.Command(rsync, append(args, l.opts.AppRoot+"/")...)
+	cmd.Stdout = l.opts.Stdout
+	cmd.Stderr = l.opts.Stderr
+	if err := cmd.Run(); err != nil {
+		l.logger.Printf("Error: rsync failed: %v", err)
+		return
+	}
+	l.logger.Printf("Successfully synced files to %s", l.opts.AppRoot)
+}
+
+// lockSync takes the sync lock shared while the launcher reads files the
+// sidecar rewrites, and returns the function that releases it. Without the
+// lock file, or when it can't be had, reads go ahead uncoordinated.
+func (l *Launcher) lockSync() func() {
+	if _, err := os.Stat(launcher.SyncLockPath(l.opts.FilesDir)); err != nil {
+		return func() {}
+	}
+	lock, err := launcher.LockSyncShared(l.opts.FilesDir)
+	if err != nil {
+		l.logger.Printf("Warning: %v, continuing without it", err)
+		return func() {}
+	}
+	return func() { lock.Unlock() }
+}
+
+// drainOld sends sig to the app an overlapping reload replaced and gives it
+// timeout to finish its requests before killing it.
+func (l *Launcher) drainOld(sig syscall.Signal, timeout time.Duration) {
+	if l.old == nil {
+		return
+	}
+	l.logger.Printf("Draining previous application process group %d with %v", l.old.pid, sig)
+	l.stopApp(l.old, sig, timeout)
+	l.old = nil
+	os.Remove(l.appPIDFile(true))
+	os.Remove(l.appPGIDFile(true))
+}
+
+// stopApp sends sig to app's process group and kills it if it is still
+// running after timeout.
+func (l *Launcher) stopApp(app *appProcess, sig syscall.Signal, timeout time.Duration) {
+	if app == nil || !groupAlive(app.pid) {
+		return
+	}
+	signalGroup(app.pid, sig)
+	deadline := time.Now().Add(timeout)
+	for groupAlive(app.pid) && time.Now().Before(deadline) {
+		time.Sleep(groupPoll)
+	}
+	if groupAlive(app.pid) {
+		l.logger.Printf("Process group %d did not stop within %v. Sending SIGKILL.", app.pid, timeout)
+		signalGroup(app.pid, syscall.SIGKILL)
+	}
+	<-app.done
+}
+
+func (l *Launcher) readHandshake() (launcher.Handshake, error) {
+	var hs launcher.Handshake
+	data, err := os.ReadFile(launcher.HandshakePath(l.opts.FilesDir))
+	if err != nil {
+		return hs, err
+	}
+	if err := json.Unmarshal(data, &hs); err != nil {
+		return launcher.Handshake{}, fmt.Errorf("failed to parse %s: %w", launcher.HandshakePath(l.opts.FilesDir), err)
+	}
+	return hs, nil
+}
+
+// launcherFile returns the path of this launcher's copy of a file in the
+// launcher dir.
+func (l *Launcher) launcherFile(name string) string {
+	return filepath.Join(launcher.Dir(l.opts.FilesDir), name+l.suffix)
+}
+
+// appPIDFile and appPGIDFile return where the app's PID and process group
+// are recorded in the sidecar dir, or with old those of the app an
+// overlapping reload is replacing.
+func (l *Launcher) appPIDFile(old bool) string {
+	return l.sidecarFile("app", old)
+}
+
+func (l *Launcher) appPGIDFile(old bool) string {
+	return l.sidecarFile("app-pgid", old)
+}
+
+func (l *Launcher) sidecarFile(base string, old bool) string {
+	name := base + l.suffix
+	if old {
+		name += ".old"
+	}
+	return filepath.Join(launcher.SidecarDir(l.opts.FilesDir), name+".pid")
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func writeFile(path, content string) error {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// envNames lists the names of the non-empty variables in environ, one per line.
+func envNames(environ []string) string {
+	var b strings.Builder
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && name != "" && value != "" {
+			b.WriteString(name + "\n")
+		}
+	}
+	return b.String()
+}
+
+func setEnv(env []string, name, value string) []string {
+	return append(unsetEnv(env, name), name+"="+value)
+}
+
+func unsetEnv(env []string, name string) []string {
+	return slices.DeleteFunc(env, func(kv string) bool { return strings.HasPrefix(kv, name+"=") })
+}
+
+// quoteCommand joins command for logs, quoting the arguments that need it.
+func quoteCommand(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+		}) < 0 {
+			quoted[i] = arg
+		} else {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
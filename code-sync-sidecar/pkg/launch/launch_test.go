@@ -0,0 +1,237 @@
+//go:build unix
+
+package launch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// syncBuffer is a bytes.Buffer the app and the launcher can both write to.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// newFilesDir returns a files dir with the directories the sidecar creates.
+func newFilesDir(t *testing.T) string {
+	t.Helper()
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0755))
+	require.NoError(t, os.MkdirAll(launcher.Dir(filesDir), 0755))
+	return filesDir
+}
+
+// runLauncher starts a launcher running script with sh and returns the channel
+// that signals it. The launcher is stopped when the test ends.
+func runLauncher(t *testing.T, opts Options, script string) chan<- os.Signal {
+	t.Helper()
+	opts.Command = []string{"sh", "-c", script}
+	if opts.Stdout == nil {
+		opts.Stdout = &syncBuffer{}
+	}
+	l, err := New(opts)
+	require.NoError(t, err)
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- l.Run(signals) }()
+	t.Cleanup(func() {
+		select {
+		case signals <- syscall.SIGTERM:
+		case err := <-done:
+			assert.NoError(t, err)
+			return
+		}
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Error("Run didn't return after SIGTERM")
+		}
+	})
+	return signals
+}
+
+func readLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// hasPID reports whether the PID file at path has been written, which it is
+// just after the app starts.
+func hasPID(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	_, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil
+}
+
+func readPID(t *testing.T, path string) int {
+	t.Helper()
+	pid, err := strconv.Atoi(strings.TrimSpace(string(mustRead(t, path))))
+	require.NoError(t, err)
+	return pid
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Options{})
+	assert.ErrorContains(t, err, "no command to launch")
+	_, err = New(Options{Command: []string{"true"}, Name: "web worker"})
+	assert.ErrorContains(t, err, `launcher name "web worker" may only contain`)
+	_, err = New(Options{Command: []string{"true"}, EnvScope: "../x"})
+	assert.ErrorContains(t, err, "invalid env scope")
+}
+
+func TestRun_StartsAppWithEnv(t *testing.T) {
+	filesDir := newFilesDir(t)
+	// Values with quotes and spaces reach the app as they are.
+	_, err := envfile.Write(filesDir, "", []byte("export DATABASE_URL='postgres://db/app?opt=a b'\nexport SHARED='shared'\n"), "")
+	require.NoError(t, err)
+	_, err = envfile.Write(filesDir, "worker", []byte("export SHARED='it'\\''s scoped'\n"), "")
+	require.NoError(t, err)
+	require.NoError(t, launcher.WriteHandshake(filesDir, launcher.NewHandshake("push-1", launcher.ReloadReasonPush, nil, nil)))
+	out := filepath.Join(t.TempDir(), "env")
+
+	signals := runLauncher(t, Options{
+		FilesDir: filesDir,
+		AppRoot:  t.TempDir(),
+		Name:     "worker",
+		EnvScope: "worker",
+		Environ:  []string{"PATH=" + os.Getenv("PATH"), "OUT=" + out, "BIFROST_PUSH_ID=stale", "EMPTY="},
+	}, `printf '%s\n%s\n%s\n%s\n' "$DATABASE_URL" "$SHARED" "$BIFROST_PUSH_ID" "$BIFROST_RELOAD_REASON" > "$OUT.tmp" && mv "$OUT.tmp" "$OUT"; exec sleep 60`)
+
+	appPIDFile := filepath.Join(launcher.SidecarDir(filesDir), "app.worker.pid")
+	require.Eventually(t, func() bool { _, err := os.Stat(out); return err == nil && hasPID(appPIDFile) }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "postgres://db/app?opt=a b\nit's scoped\npush-1\npush\n", string(mustRead(t, out)))
+	assert.Equal(t, os.Getpid(), readPID(t, filepath.Join(launcher.Dir(filesDir), "worker.pid")))
+	assert.ElementsMatch(t, []string{"PATH", "OUT", "BIFROST_PUSH_ID"}, readLines(filepath.Join(launcher.Dir(filesDir), "env_names")))
+	appPID := readPID(t, appPIDFile)
+	assert.True(t, groupAlive(appPID))
+
+	signals <- syscall.SIGTERM
+	require.Eventually(t, func() bool { return !groupAlive(appPID) }, 10*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return launcher.ReadFile(filesDir, launcher.ExitReasonFile+".worker") == "Launcher received SIGTERM or SIGINT"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestRun_ReloadRestartsAppAndSyncsFiles(t *testing.T) {
+	filesDir := newFilesDir(t)
+	appRoot := t.TempDir()
+	starts := filepath.Join(t.TempDir(), "starts")
+	rsyncArgs := filepath.Join(t.TempDir(), "rsync-args")
+	// A stand-in for the provisioned rsync that records what it was asked to copy.
+	require.NoError(t, os.WriteFile(launcher.RsyncPath(filesDir), []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > "+rsyncArgs+"\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(filesDir, "main.py"), []byte("print()\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(filesDir, ".hidden"), nil, 0644))
+
+	signals := runLauncher(t, Options{
+		FilesDir: filesDir,
+		AppRoot:  appRoot,
+		Environ:  []string{"PATH=" + os.Getenv("PATH"), "STARTS=" + starts},
+	}, `echo "${BIFROST_PUSH_ID:-none}" >> "$STARTS"; exec sleep 60`)
+
+	appPIDFile := filepath.Join(launcher.SidecarDir(filesDir), "app.pid")
+	require.Eventually(t, func() bool { return len(readLines(starts)) == 1 && hasPID(appPIDFile) }, 5*time.Second, 20*time.Millisecond)
+	first := readPID(t, appPIDFile)
+	assert.Equal(t, []string{"none"}, readLines(starts))
+	assert.NoFileExists(t, rsyncArgs, "files are only copied on a reload")
+
+	require.NoError(t, launcher.WriteHandshake(filesDir, launcher.NewHandshake("push-2", launcher.ReloadReasonPush, nil, nil)))
+	signals <- syscall.SIGHUP
+	require.Eventually(t, func() bool { return len(readLines(starts)) == 2 }, 10*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"none", "push-2"}, readLines(starts))
+	assert.False(t, groupAlive(first), "the old app is stopped")
+	assert.Equal(t, []string{"-a", "--delete", "--exclude", ".sidecar/", "--exclude", ".launcher/",
+		filepath.Join(filesDir, "main.py"), appRoot + "/"}, readLines(rsyncArgs))
+}
+
+func TestRun_RestartsExitedApp(t *testing.T) {
+	filesDir := newFilesDir(t)
+	starts := filepath.Join(t.TempDir(), "starts")
+
+	runLauncher(t, Options{
+		FilesDir: filesDir,
+		Environ:  []string{"PATH=" + os.Getenv("PATH"), "STARTS=" + starts},
+	}, `echo start >> "$STARTS"; exit 3`)
+
+	require.Eventually(t, func() bool {
+		return len(readLines(starts)) >= 2 && strings.Contains(launcher.ReadFile(filesDir, launcher.AppStatusFile), "exited with status 3")
+	}, 10*time.Second, 20*time.Millisecond)
+}
+
+func TestRun_OverlappingReload(t *testing.T) {
+	filesDir := newFilesDir(t)
+	readyFiles := filepath.Join(t.TempDir(), "ready-files")
+
+	signals := runLauncher(t, Options{
+		FilesDir: filesDir,
+		Environ:  []string{"PATH=" + os.Getenv("PATH"), "READY_FILES=" + readyFiles},
+	}, `echo "${BIFROST_APP_READY_FILE:-none}" >> "$READY_FILES"; exec sleep 60`)
+	appPIDFile := filepath.Join(launcher.SidecarDir(filesDir), "app.pid")
+	oldPIDFile := filepath.Join(launcher.SidecarDir(filesDir), "app.old.pid")
+	require.Eventually(t, func() bool { return len(readLines(readyFiles)) == 1 && hasPID(appPIDFile) }, 5*time.Second, 20*time.Millisecond)
+	first := readPID(t, appPIDFile)
+
+	overlap := func(phase launcher.OverlapPhase) {
+		hs := launcher.NewHandshake("push-2", launcher.ReloadReasonPush, nil, nil)
+		hs.OverlapPhase = phase
+		hs.ReadyFile = filepath.Join(launcher.Dir(filesDir), launcher.ReadyFileName)
+		hs.DrainSignal = "TERM"
+		hs.DrainTimeoutSeconds = 5
+		require.NoError(t, launcher.WriteHandshake(filesDir, hs))
+		signals <- syscall.SIGHUP
+	}
+
+	// Abort keeps the old app and stops the new one.
+	overlap(launcher.OverlapStart)
+	require.Eventually(t, func() bool { return len(readLines(readyFiles)) == 2 && hasPID(appPIDFile) }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, filepath.Join(launcher.Dir(filesDir), launcher.ReadyFileName), readLines(readyFiles)[1])
+	second := readPID(t, appPIDFile)
+	assert.Equal(t, first, readPID(t, oldPIDFile))
+	assert.True(t, groupAlive(first), "the old app keeps running while the new one starts")
+	overlap(launcher.OverlapAbort)
+	require.Eventually(t, func() bool { return !groupAlive(second) }, 10*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool { _, err := os.Stat(oldPIDFile); return os.IsNotExist(err) }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, first, readPID(t, appPIDFile))
+	assert.True(t, groupAlive(first))
+
+	// Drain stops the old app once the new one is ready.
+	overlap(launcher.OverlapStart)
+	require.Eventually(t, func() bool { return len(readLines(readyFiles)) == 3 && hasPID(appPIDFile) }, 5*time.Second, 20*time.Millisecond)
+	third := readPID(t, appPIDFile)
+	overlap(launcher.OverlapDrain)
+	require.Eventually(t, func() bool { return !groupAlive(first) }, 10*time.Second, 20*time.Millisecond)
+	assert.True(t, groupAlive(third))
+}
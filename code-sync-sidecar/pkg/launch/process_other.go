@@ -0,0 +1,29 @@
+//go:build !unix
+
+package launch
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// The launcher runs in the app's Linux container; other builds of the sidecar
+// are for local development and can't signal process groups.
+
+func checkPlatform() error {
+	return errors.New("launch is only supported on Unix systems")
+}
+
+func setNewSession(cmd *exec.Cmd) {}
+
+func groupAlive(pgid int) bool {
+	return false
+}
+
+func signalGroup(pgid int, sig syscall.Signal) {}
+
+func exitStatus(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package launch
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func checkPlatform() error {
+	return nil
+}
+
+// setNewSession starts cmd in a session and process group of its own, so the
+// launcher can signal the app and everything it starts together.
+func setNewSession(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func groupAlive(pgid int) bool {
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func signalGroup(pgid int, sig syscall.Signal) {
+	syscall.Kill(-pgid, sig)
+}
+
+// exitStatus returns the status a shell would report for the app: its exit
+// code, or 128 plus the signal that killed it.
+func exitStatus(state *os.ProcessState) int {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
@@ -0,0 +1,126 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// HandshakeFileName is written to the launcher dir before every reload signal.
+// The launcher script reads the push ID from it and exports its path as
+// BIFROST_LAUNCHER_HANDSHAKE for the app.
+const HandshakeFileName = "handshake.json"
+
+// ReloadReason says why the sidecar asked the launcher to reload the app.
+type ReloadReason string
+
+const (
+	ReloadReasonPush            ReloadReason = "push"
+	ReloadReasonDatabaseUpdate  ReloadReason = "database_update"
+	ReloadReasonSnapshotRestore ReloadReason = "snapshot_restore"
+	ReloadReasonEnvRollback     ReloadReason = "env_rollback"
+	ReloadReasonStageRollback   ReloadReason = "stage_rollback"
+)
+
+// ReadyFileName is created in Dir by the new app of an overlapping reload,
+// at the path the launcher exports as BIFROST_APP_READY_FILE, once it serves.
+const ReadyFileName = "app.ready"
+
+// OverlapPhase is the step of a reload that runs the new app alongside the old
+// one, each announced to the launcher with a handshake and a reload signal.
+type OverlapPhase string
+
+const (
+	// OverlapStart has the launcher start the new app and keep the old one running.
+	OverlapStart OverlapPhase = "start"
+	// OverlapDrain has the launcher send the drain signal to the old app, once
+	// the new one is ready.
+	OverlapDrain OverlapPhase = "drain"
+	// OverlapAbort has the launcher stop the new app, which never became ready,
+	// and keep the old one.
+	OverlapAbort OverlapPhase = "abort"
+)
+
+// Handshake is the context handed to the launcher with a reload. The overlap
+// fields are set only for an overlapping reload, and are top-level so the
+// launcher script can read them line by line.
+type Handshake struct {
+	PushID              string                    `json:"push_id"`
+	WrittenAt           time.Time                 `json:"written_at"`
+	Reason              ReloadReason              `json:"reason"`
+	Snapshot            string                    `json:"snapshot,omitempty"`
+	OverlapPhase        OverlapPhase              `json:"overlap_phase,omitempty"`
+	ReadyFile           string                    `json:"ready_file,omitempty"`
+	DrainSignal         string                    `json:"drain_signal,omitempty"`
+	DrainTimeoutSeconds int                       `json:"drain_timeout_seconds,omitempty"`
+	EnvFiles            []EnvFile                 `json:"env_files,omitempty"`
+	DatabaseUpdates     []HandshakeDatabaseUpdate `json:"database_updates,omitempty"`
+}
+
+// EnvFile is an env file the launcher may source. Version changes whenever
+// the file's contents do.
+type EnvFile struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// HandshakeDatabaseUpdate mirrors a DatabaseBranchUpdate from the push.
+type HandshakeDatabaseUpdate struct {
+	DatabaseName     string `json:"database_name"`
+	PreviousBranchID string `json:"previous_branch_id,omitempty"`
+	NewBranchID      string `json:"new_branch_id"`
+	BranchCreated    bool   `json:"branch_created,omitempty"`
+	ParentBranchID   string `json:"parent_branch_id,omitempty"`
+}
+
+// HandshakePath returns the path of the handshake file in filesDir.
+func HandshakePath(filesDir string) string {
+	return filepath.Join(Dir(filesDir), HandshakeFileName)
+}
+
+// NewHandshake describes a reload for pushID.
+func NewHandshake(pushID string, reason ReloadReason, envFiles []EnvFile, updates []*pb.DatabaseBranchUpdate) Handshake {
+	hs := Handshake{
+		PushID:    pushID,
+		WrittenAt: time.Now().UTC(),
+		Reason:    reason,
+		EnvFiles:  envFiles,
+	}
+	for _, u := range updates {
+		hs.DatabaseUpdates = append(hs.DatabaseUpdates, HandshakeDatabaseUpdate{
+			DatabaseName:     u.DatabaseName,
+			PreviousBranchID: u.PreviousBranchId,
+			NewBranchID:      u.NewBranchId,
+			BranchCreated:    u.BranchCreated,
+			ParentBranchID:   u.ParentBranchId,
+		})
+	}
+	return hs
+}
+
+// WriteHandshake atomically replaces the handshake file. It's indented with
+// one field per line so the launcher script can read it without jq.
+func WriteHandshake(filesDir string, hs Handshake) error {
+	launcherDir := Dir(filesDir)
+	// Should be created by the launcher script, but double-check.
+	if err := os.MkdirAll(launcherDir, Volume.InternalDir); err != nil {
+		return fmt.Errorf("failed to ensure launcher directory exists: %w", err)
+	}
+	data, err := json.MarshalIndent(hs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal launcher handshake: %w", err)
+	}
+	path := HandshakePath(filesDir)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write launcher handshake: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace launcher handshake: %w", err)
+	}
+	return nil
+}
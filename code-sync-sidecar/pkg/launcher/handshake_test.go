@@ -0,0 +1,62 @@
+package launcher
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestWriteHandshake(t *testing.T) {
+	filesDir := t.TempDir()
+	envFiles := []EnvFile{{Path: "/app-files/.sidecar/env.sh", Version: "0123456789abcdef"}}
+
+	hs := NewHandshake("push-1", ReloadReasonPush, envFiles, []*pb.DatabaseBranchUpdate{
+		{DatabaseName: "main", PreviousBranchId: "br-1", NewBranchId: "br-2", BranchCreated: true, ParentBranchId: "br-1"},
+	})
+	require.NoError(t, WriteHandshake(filesDir, hs))
+
+	data, err := os.ReadFile(HandshakePath(filesDir))
+	require.NoError(t, err)
+	// The launcher script reads these lines with sed.
+	assert.Contains(t, string(data), "\n  \"push_id\": \"push-1\",\n")
+	assert.Contains(t, string(data), "\n  \"reason\": \"push\",\n")
+
+	var got Handshake
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "push-1", got.PushID)
+	assert.Equal(t, ReloadReasonPush, got.Reason)
+	assert.False(t, got.WrittenAt.IsZero())
+	assert.Equal(t, envFiles, got.EnvFiles)
+	assert.Equal(t, []HandshakeDatabaseUpdate{
+		{DatabaseName: "main", PreviousBranchID: "br-1", NewBranchID: "br-2", BranchCreated: true, ParentBranchID: "br-1"},
+	}, got.DatabaseUpdates)
+}
+
+func TestWriteHandshake_Overlap(t *testing.T) {
+	filesDir := t.TempDir()
+	hs := NewHandshake("push-1", ReloadReasonPush, nil, nil)
+	hs.OverlapPhase = OverlapDrain
+	hs.ReadyFile = "/app-files/.launcher/app.ready"
+	hs.DrainSignal = "TERM"
+	hs.DrainTimeoutSeconds = 30
+	require.NoError(t, WriteHandshake(filesDir, hs))
+
+	data, err := os.ReadFile(HandshakePath(filesDir))
+	require.NoError(t, err)
+	// The launcher script reads these lines with sed.
+	assert.Contains(t, string(data), "\n  \"overlap_phase\": \"drain\",\n")
+	assert.Contains(t, string(data), "\n  \"ready_file\": \"/app-files/.launcher/app.ready\",\n")
+	assert.Contains(t, string(data), "\n  \"drain_signal\": \"TERM\",\n")
+	assert.Contains(t, string(data), "\n  \"drain_timeout_seconds\": 30\n")
+
+	// A plain reload leaves them out, so the launcher restarts the app.
+	require.NoError(t, WriteHandshake(filesDir, NewHandshake("push-2", ReloadReasonPush, nil, nil)))
+	data, err = os.ReadFile(HandshakePath(filesDir))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "overlap_phase")
+}
@@ -0,0 +1,468 @@
+// Package launcher is the sidecar's side of its contract with the launcher
+// running in the app container, the launch command (see pkg/launch) or the
+// rsync launcher script. The two share a volume: the sidecar provisions rsync,
+// the launcher script and itself into SidecarDir, and the
+// launcher writes its PID and exit status to Dir. The sidecar reloads the app
+// by writing a Handshake and signalling the launcher.
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// MainLauncher is the name of the launcher script started without
+// BIFROST_LAUNCHER_NAME, the only one in most deployments. The sidecar
+// restarts, stops and watches it; other launchers only get the reload signal.
+const MainLauncher = "launcher"
+
+// pidFileExt ends the name of the file each launcher writes its PID to in Dir
+// on startup, which is named after the launcher.
+const pidFileExt = ".pid"
+
+// pidFileName is the main launcher's PID file.
+const pidFileName = MainLauncher + pidFileExt
+
+// envNamesFileName is written by the launcher script to Dir on startup, one
+// name per line of the non-empty variables in the app container's environment.
+const envNamesFileName = "env_names"
+
+// changeEventFileName is replaced in the .sidecar directory before the app is
+// reloaded for a push that changed files, so a framework with its own
+// hot-reload can pick up exactly what changed instead of rescanning the tree.
+const changeEventFileName = "changes.json"
+
+// SidecarDir returns the directory in filesDir that the sidecar keeps its own
+// files in: the provisioned binaries, env files, state and backups.
+func SidecarDir(filesDir string) string {
+	return filepath.Join(filesDir, ".sidecar")
+}
+
+// Dir returns the directory in filesDir that the launcher writes to.
+func Dir(filesDir string) string {
+	return filepath.Join(filesDir, ".launcher")
+}
+
+// ChangeEventPath returns the path of the change event file in filesDir, which
+// the launch command passes to the app as BIFROST_CHANGES_FILE.
+func ChangeEventPath(filesDir string) string {
+	return filepath.Join(SidecarDir(filesDir), changeEventFileName)
+}
+
+// ProcessSignaler is an interface for sending signals to processes
+type ProcessSignaler interface {
+	Signal(sig syscall.Signal) error
+}
+
+// OSProcess wraps os.Process to implement ProcessSignaler
+type OSProcess struct {
+	*os.Process
+}
+
+func (p *OSProcess) Signal(sig syscall.Signal) error {
+	return signalOSProcess(p.Process, sig)
+}
+
+// ProcessFinder is an interface for finding processes
+type ProcessFinder interface {
+	FindProcess(pid int) (ProcessSignaler, error)
+}
+
+// DefaultProcessFinder uses os.FindProcess
+type DefaultProcessFinder struct{}
+
+func (f *DefaultProcessFinder) FindProcess(pid int) (ProcessSignaler, error) {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	return &OSProcess{proc}, nil
+}
+
+// pidFile returns the path of the named launcher's PID file in filesDir.
+func pidFile(filesDir, name string) string {
+	return filepath.Join(Dir(filesDir), name+pidFileExt)
+}
+
+// Launchers returns the names of the launchers that have written a PID file
+// in filesDir, MainLauncher first and the others sorted.
+func Launchers(filesDir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(Dir(filesDir), "*"+pidFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list launcher pid files: %w", err)
+	}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), pidFileExt))
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == MainLauncher) != (names[j] == MainLauncher) {
+			return names[i] == MainLauncher
+		}
+		return names[i] < names[j]
+	})
+	return names, nil
+}
+
+// ReadPID reads the PID the main launcher script wrote on startup.
+func ReadPID(filesDir string) (int, error) {
+	return readPIDFile(pidFile(filesDir, MainLauncher))
+}
+
+func readPIDFile(path string) (int, error) {
+	pidBytes, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pid file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert pid to int: %w", err)
+	}
+	return pid, nil
+}
+
+// ContainerEnvNames returns the names of the non-empty variables set in the
+// app container, as the launcher script recorded them on startup. Values never
+// leave the app container. It returns an error wrapping os.ErrNotExist if the
+// launcher hasn't recorded them, e.g. because it predates the record.
+func ContainerEnvNames(filesDir string) (map[string]bool, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(filesDir), envNamesFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container env names: %w", err)
+	}
+	names := make(map[string]bool)
+	for _, name := range strings.Split(string(data), "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+// ErrNotRunning is returned when the launcher can't be signalled because it
+// never wrote its PID or the process with that PID is gone.
+var ErrNotRunning = errors.New("launcher is not running")
+
+// State reports whether the main launcher process is alive, probing it with
+// signal 0.
+func State(filesDir string, processFinder ProcessFinder) (pb.StatusReport_LauncherState, int) {
+	pid, err := livePID(pidFile(filesDir, MainLauncher), processFinder)
+	switch {
+	case err == nil:
+		return pb.StatusReport_RUNNING, pid
+	case errors.Is(err, os.ErrNotExist):
+		return pb.StatusReport_NO_PID_FILE, 0
+	case errors.Is(err, ErrNotRunning):
+		return pb.StatusReport_NOT_RUNNING, pid
+	default:
+		return pb.StatusReport_UNKNOWN, 0
+	}
+}
+
+// livePID returns the PID of the running launcher in the PID file at path,
+// checking with signal 0 that the process is alive. When it isn't, the file is
+// read again in case a new launcher replaced it unnoticed, and an error
+// wrapping ErrNotRunning is returned with the PID if it still isn't. A missing
+// PID file returns an error wrapping both ErrNotRunning and os.ErrNotExist.
+func livePID(path string, processFinder ProcessFinder) (int, error) {
+	pid, err := launcherPIDs.pid(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %w", ErrNotRunning, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	probeErr := probe(processFinder, pid)
+	if probeErr == nil {
+		return pid, nil
+	}
+	launcherPIDs.invalidate(path)
+	if reread, err := launcherPIDs.pid(path); err == nil && reread != pid && probe(processFinder, reread) == nil {
+		return reread, nil
+	}
+	return pid, fmt.Errorf("%w: signal 0 to pid %d failed: %w", ErrNotRunning, pid, probeErr)
+}
+
+// probe checks with signal 0 that the process at pid is alive. EPERM means it
+// is, but belongs to someone the sidecar can't signal; that, like a process
+// that can't be looked up at all, is left for signalling it for real to report.
+func probe(processFinder ProcessFinder, pid int) error {
+	process, err := processFinder.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	if err := process.Signal(syscall.Signal(0)); err != nil && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	return nil
+}
+
+// SignalTarget selects which processes a signal to the launcher reaches.
+type SignalTarget string
+
+const (
+	// SignalProcess signals only the launcher process.
+	SignalProcess SignalTarget = "process"
+	// SignalGroup signals the launcher's process group, which includes the
+	// workers it forks unless they start groups of their own.
+	SignalGroup SignalTarget = "group"
+	// SignalTree signals the launcher and then every descendant found in /proc,
+	// for apps whose workers leave the launcher's process group.
+	SignalTree SignalTarget = "tree"
+)
+
+// ParseSignalTarget converts a signals.target value into a SignalTarget,
+// defaulting to SignalProcess.
+func ParseSignalTarget(value string) (SignalTarget, error) {
+	switch target := SignalTarget(strings.ToLower(strings.TrimSpace(value))); target {
+	case "":
+		return SignalProcess, nil
+	case SignalProcess, SignalGroup, SignalTree:
+		return target, nil
+	default:
+		return "", fmt.Errorf("unsupported signal target %q (expected %s, %s or %s)", value, SignalProcess, SignalGroup, SignalTree)
+	}
+}
+
+// ParseSignal parses a signal name such as "SIGHUP" or "hup".
+func ParseSignal(name string) (syscall.Signal, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(normalized, "SIG") {
+		normalized = "SIG" + normalized
+	}
+	sig, ok := signalsByName[normalized]
+	if !ok {
+		names := slices.Sorted(maps.Keys(signalsByName))
+		return 0, fmt.Errorf("unsupported signal %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return sig, nil
+}
+
+// SignalName returns the name ParseSignal parses into sig, such as "SIGHUP".
+func SignalName(sig syscall.Signal) string {
+	for name, known := range signalsByName {
+		if known == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// These are replaced in tests.
+var (
+	procDir   = "/proc"
+	getpgid   = processGroup
+	killGroup = signalProcessGroup
+)
+
+// NoSignal as the reload signal skips signalling the launcher, which then only
+// learns of a reload from the handshake file: for platforms without SIGHUP,
+// and local setups where a file watcher restarts the app.
+const NoSignal syscall.Signal = -1
+
+// Signal sends sig to the main launcher whose PID is in filesDir, and with
+// SignalGroup or SignalTree to the processes it started as well. It returns an
+// error wrapping ErrNotRunning, without signalling anything, if the launcher
+// isn't running.
+func Signal(filesDir string, processFinder ProcessFinder, sig syscall.Signal, target SignalTarget) error {
+	_, err := SignalNamed(filesDir, MainLauncher, processFinder, sig, target)
+	return err
+}
+
+// SignalNamed signals the named launcher as Signal does the main one, and
+// returns the PID it signalled.
+func SignalNamed(filesDir, name string, processFinder ProcessFinder, sig syscall.Signal, target SignalTarget) (int, error) {
+	if sig == NoSignal {
+		log.Info("Reload signal disabled, leaving the launcher to read the handshake", zap.String("launcher", name), zap.String("path", HandshakePath(filesDir)))
+		return 0, nil
+	}
+	pid, err := livePID(pidFile(filesDir, name), processFinder)
+	if err != nil {
+		return pid, err
+	}
+	return pid, signalLauncher(processFinder, pid, sig, target)
+}
+
+// signalLauncher sends sig to the launcher at pid and, depending on target,
+// the processes it started.
+func signalLauncher(processFinder ProcessFinder, pid int, sig syscall.Signal, target SignalTarget) error {
+	switch target {
+	case SignalGroup:
+		return signalGroup(pid, sig)
+	case SignalTree:
+		if err := signalProcess(processFinder, pid, sig); err != nil {
+			return err
+		}
+		children, err := descendants(pid)
+		if err != nil {
+			return fmt.Errorf("failed to find the launcher's child processes: %w", err)
+		}
+		for _, child := range children {
+			// A child may exit before it is signalled, so failures don't fail the reload.
+			if err := signalProcess(processFinder, child, sig); err != nil {
+				log.Warn("Failed to signal launcher child process", zap.Int("pid", child), zap.Error(err))
+			}
+		}
+		return nil
+	default:
+		return signalProcess(processFinder, pid, sig)
+	}
+}
+
+func signalProcess(processFinder ProcessFinder, pid int, sig syscall.Signal) error {
+	log.Info("Sending signal to pid", zap.Int("pid", pid), zap.String("signal", sig.String()))
+	process, err := processFinder.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process: %w", err)
+	}
+	err = process.Signal(sig)
+	if err != nil {
+		return fmt.Errorf("failed to send signal: %w", err)
+	}
+	return nil
+}
+
+// stopPollInterval is how often Stop and Restart check on the launcher.
+var stopPollInterval = 100 * time.Millisecond
+
+// Stop sends sig to the launcher as Signal does and waits up to timeout for the
+// launcher process to exit. A launcher that isn't running is left alone.
+func Stop(filesDir string, processFinder ProcessFinder, sig syscall.Signal, target SignalTarget, timeout time.Duration) error {
+	if state, _ := State(filesDir, processFinder); state != pb.StatusReport_RUNNING {
+		return nil
+	}
+	if err := Signal(filesDir, processFinder, sig, target); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		state, pid := State(filesDir, processFinder)
+		if state != pb.StatusReport_RUNNING {
+			log.Info("Launcher exited", zap.Int("pid", pid))
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("launcher (pid %d) still running %s after %s", pid, timeout, sig)
+		}
+		time.Sleep(stopPollInterval)
+	}
+}
+
+// Restart stops the launcher with sig and waits up to timeout for a new one to
+// start, which the app container's restart policy does once the launcher exits.
+// The PID file is removed before the launcher is signalled, so a Watcher
+// doesn't report the planned exit, and the next launcher to write one is the
+// new launcher. It returns the new launcher's PID.
+func Restart(filesDir string, processFinder ProcessFinder, sig syscall.Signal, target SignalTarget, timeout time.Duration) (int, error) {
+	oldPID, err := ReadPID(filesDir)
+	if err != nil {
+		return 0, err
+	}
+	pidFile := pidFile(filesDir, MainLauncher)
+	if err := os.Remove(pidFile); err != nil {
+		return 0, fmt.Errorf("failed to remove pid file: %w", err)
+	}
+	if err := signalLauncher(processFinder, oldPID, sig, target); err != nil {
+		// The launcher is presumably still running, so keep it in view.
+		if restoreErr := os.WriteFile(pidFile, []byte(strconv.Itoa(oldPID)+"\n"), 0644); restoreErr != nil {
+			log.Warn("Failed to restore launcher pid file", zap.Error(restoreErr))
+		}
+		return 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if state, pid := State(filesDir, processFinder); state == pb.StatusReport_RUNNING {
+			log.Info("Launcher restarted", zap.Int("oldPID", oldPID), zap.Int("pid", pid))
+			return pid, nil
+		}
+		if !time.Now().Before(deadline) {
+			return 0, fmt.Errorf("no launcher started within %s of sending %s to pid %d", timeout, sig, oldPID)
+		}
+		time.Sleep(stopPollInterval)
+	}
+}
+
+// signalGroup signals the process group of the launcher at pid. It refuses
+// when that is the sidecar's own group, which happens if both run in one
+// container, rather than signal the sidecar too.
+func signalGroup(pid int, sig syscall.Signal) error {
+	pgid, err := getpgid(pid)
+	if err != nil {
+		return fmt.Errorf("failed to get the process group of pid %d: %w", pid, err)
+	}
+	if own, err := getpgid(0); err == nil && own == pgid {
+		return fmt.Errorf("the launcher (pid %d) is in the sidecar's own process group %d; use signal target %s or %s", pid, pgid, SignalProcess, SignalTree)
+	}
+	log.Info("Sending signal to process group", zap.Int("pgid", pgid), zap.Int("pid", pid), zap.String("signal", sig.String()))
+	if err := killGroup(pgid, sig); err != nil {
+		return fmt.Errorf("failed to send signal to process group %d: %w", pgid, err)
+	}
+	return nil
+}
+
+// descendants returns the PIDs of every process below pid, parents before
+// their children, by reading the parent PID of each process in /proc.
+func descendants(pid int) ([]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "stat"))
+		if err != nil {
+			// The process exited while listing.
+			continue
+		}
+		if parent, ok := parseParentPID(string(stat)); ok {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	var found []int
+	queue := []int{pid}
+	for len(queue) > 0 {
+		next := children[queue[0]]
+		queue = queue[1:]
+		sort.Ints(next)
+		found = append(found, next...)
+		queue = append(queue, next...)
+	}
+	return found, nil
+}
+
+// parseParentPID reads the parent PID from the contents of /proc/<pid>/stat,
+// "pid (comm) state ppid ...". comm may contain spaces and parentheses, so the
+// fields are read after its last closing parenthesis.
+func parseParentPID(stat string) (int, bool) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 2 {
+		return 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	return ppid, err == nil
+}
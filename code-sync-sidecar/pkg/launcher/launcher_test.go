@@ -0,0 +1,321 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLauncherPID writes the launcher's PID file into a new files dir.
+func writeLauncherPID(t *testing.T, pid int) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), pidFileName), []byte(fmt.Sprintf("%d\n", pid)), 0644))
+	return dir
+}
+
+// fakeProc points procDir at a directory with a stat file for each pid in parents.
+func fakeProc(t *testing.T, parents map[int]int) {
+	t.Helper()
+	dir := t.TempDir()
+	for pid, ppid := range parents {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, fmt.Sprint(pid)), 0755))
+		stat := fmt.Sprintf("%d (worker) S %d %d 0 0 -1\n", pid, ppid, pid)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprint(pid), "stat"), []byte(stat), 0644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "self"), 0755))
+	original := procDir
+	procDir = dir
+	t.Cleanup(func() { procDir = original })
+}
+
+func TestParseSignalTarget(t *testing.T) {
+	for value, want := range map[string]SignalTarget{
+		"":         SignalProcess,
+		"process":  SignalProcess,
+		" Group ":  SignalGroup,
+		"tree":     SignalTree,
+		"TREE":     SignalTree,
+		"children": "",
+	} {
+		target, err := ParseSignalTarget(value)
+		if want == "" {
+			assert.Error(t, err, value)
+			continue
+		}
+		require.NoError(t, err, value)
+		assert.Equal(t, want, target, value)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGHUP", "hup", " Hup "} {
+		sig, err := ParseSignal(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, syscall.SIGHUP, sig, name)
+	}
+	assert.Equal(t, "SIGTERM", SignalName(syscall.SIGTERM))
+
+	_, err := ParseSignal("SIGWINCH")
+	assert.ErrorContains(t, err, "unsupported signal")
+}
+
+func TestContainerEnvNames(t *testing.T) {
+	dir := t.TempDir()
+	_, err := ContainerEnvNames(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.MkdirAll(Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), envNamesFileName), []byte("PATH\nSECRET_KEY\n\n"), 0644))
+	names, err := ContainerEnvNames(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"PATH": true, "SECRET_KEY": true}, names)
+}
+
+func TestSignal_Process(t *testing.T) {
+	dir := writeLauncherPID(t, 100)
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+
+	require.NoError(t, Signal(dir, finder, syscall.SIGHUP, SignalProcess))
+	assert.Equal(t, []syscall.Signal{syscall.SIGHUP}, finder.processes[100].signalCalls)
+	assert.Len(t, finder.processes, 1)
+}
+
+func TestLaunchers(t *testing.T) {
+	dir := t.TempDir()
+	names, err := Launchers(dir)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	dir = writeLauncherPID(t, 100)
+	for name, pid := range map[string]string{"worker": "200", "cron": "300"} {
+		require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), name+".pid"), []byte(pid), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), ExitReasonFile), []byte("exited"), 0644))
+	names, err = Launchers(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{MainLauncher, "cron", "worker"}, names)
+
+	finder := &mockProcessFinder{processes: map[int]*mockProcess{200: {}}}
+	pid, err := SignalNamed(dir, "worker", finder, syscall.SIGHUP, SignalProcess)
+	require.NoError(t, err)
+	assert.Equal(t, 200, pid)
+	assert.Equal(t, []syscall.Signal{syscall.SIGHUP}, finder.processes[200].signalCalls)
+	_, err = SignalNamed(dir, "web", finder, syscall.SIGHUP, SignalProcess)
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestSignal_NotRunning(t *testing.T) {
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	err := Signal(t.TempDir(), finder, syscall.SIGHUP, SignalProcess)
+	assert.ErrorIs(t, err, ErrNotRunning)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	dir := writeLauncherPID(t, 100)
+	finder.processes[100] = &mockProcess{signalErr: syscall.ESRCH}
+	err = Signal(dir, finder, syscall.SIGHUP, SignalGroup)
+	assert.ErrorIs(t, err, ErrNotRunning)
+	assert.ErrorContains(t, err, "signal 0 to pid 100 failed")
+
+	// A process the sidecar isn't allowed to signal is still running.
+	finder.processes[100] = &mockProcess{signalErr: syscall.EPERM}
+	err = Signal(dir, finder, syscall.SIGHUP, SignalProcess)
+	assert.NotErrorIs(t, err, ErrNotRunning)
+	assert.ErrorIs(t, err, syscall.EPERM)
+}
+
+func TestSignal_NoSignal(t *testing.T) {
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+
+	// Nothing is signalled, so a launcher needn't have written its PID.
+	require.NoError(t, Signal(t.TempDir(), finder, NoSignal, SignalGroup))
+	assert.Empty(t, finder.processes)
+}
+
+func TestSignal_Group(t *testing.T) {
+	dir := writeLauncherPID(t, 100)
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	originalGetpgid, originalKillGroup := getpgid, killGroup
+	t.Cleanup(func() { getpgid, killGroup = originalGetpgid, originalKillGroup })
+
+	pgids := map[int]int{0: 1, 100: 100}
+	getpgid = func(pid int) (int, error) { return pgids[pid], nil }
+	var killed []int
+	killGroup = func(pgid int, sig syscall.Signal) error {
+		assert.Equal(t, syscall.SIGUSR2, sig)
+		killed = append(killed, pgid)
+		return nil
+	}
+
+	require.NoError(t, Signal(dir, finder, syscall.SIGUSR2, SignalGroup))
+	assert.Equal(t, []int{100}, killed)
+	assert.Empty(t, finder.processes[100].signalCalls, "the group is signalled instead of the launcher alone")
+
+	// A launcher in the sidecar's own group can't be group-signalled safely.
+	pgids[100] = 1
+	err := Signal(dir, finder, syscall.SIGUSR2, SignalGroup)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sidecar's own process group")
+	assert.Equal(t, []int{100}, killed)
+
+	getpgid = func(int) (int, error) { return 0, syscall.ESRCH }
+	assert.ErrorIs(t, Signal(dir, finder, syscall.SIGUSR2, SignalGroup), syscall.ESRCH)
+}
+
+func TestSignal_Tree(t *testing.T) {
+	dir := writeLauncherPID(t, 100)
+	// 100 forked 101 and 102; 102 forked 200. 300 belongs to another parent.
+	fakeProc(t, map[int]int{1: 0, 100: 1, 101: 100, 102: 100, 200: 102, 300: 1})
+	finder := &mockProcessFinder{processes: map[int]*mockProcess{
+		// A worker that exited mid-walk doesn't fail the reload.
+		101: {signalErr: errors.New("os: process already finished")},
+	}}
+
+	require.NoError(t, Signal(dir, finder, syscall.SIGHUP, SignalTree))
+	for _, pid := range []int{100, 102, 200} {
+		assert.Equal(t, []syscall.Signal{syscall.SIGHUP}, finder.processes[pid].signalCalls, "pid %d", pid)
+	}
+	assert.NotContains(t, finder.processes, 300)
+	assert.NotContains(t, finder.processes, 1)
+}
+
+func TestDescendants_Order(t *testing.T) {
+	fakeProc(t, map[int]int{100: 1, 150: 100, 120: 100, 130: 120})
+
+	children, err := descendants(100)
+	require.NoError(t, err)
+	assert.Equal(t, []int{120, 150, 130}, children)
+}
+
+func TestParseParentPID(t *testing.T) {
+	ppid, ok := parseParentPID("4242 (python app.py) S 4200 4242 4242 0 -1")
+	assert.True(t, ok)
+	assert.Equal(t, 4200, ppid)
+
+	// comm is free-form and may itself contain ") ".
+	ppid, ok = parseParentPID("4243 (a) b (c)) R 17 4243")
+	assert.True(t, ok)
+	assert.Equal(t, 17, ppid)
+
+	_, ok = parseParentPID("garbage")
+	assert.False(t, ok)
+	_, ok = parseParentPID("1 (init) S")
+	assert.False(t, ok)
+}
+
+// exitingProcess is a launcher that exits once it receives exitOn.
+type exitingProcess struct {
+	exitOn  syscall.Signal
+	exited  bool
+	signals []syscall.Signal
+}
+
+func (p *exitingProcess) Signal(sig syscall.Signal) error {
+	if p.exited {
+		return errors.New("os: process already finished")
+	}
+	p.signals = append(p.signals, sig)
+	if sig == p.exitOn {
+		p.exited = true
+	}
+	return nil
+}
+
+type singleProcessFinder struct {
+	process ProcessSignaler
+}
+
+func (f *singleProcessFinder) FindProcess(int) (ProcessSignaler, error) {
+	return f.process, nil
+}
+
+func TestStop(t *testing.T) {
+	originalInterval := stopPollInterval
+	stopPollInterval = time.Millisecond
+	t.Cleanup(func() { stopPollInterval = originalInterval })
+	dir := writeLauncherPID(t, 100)
+
+	process := &exitingProcess{exitOn: syscall.SIGTERM}
+	require.NoError(t, Stop(dir, &singleProcessFinder{process}, syscall.SIGTERM, SignalProcess, time.Second))
+	assert.Equal(t, []syscall.Signal{0, 0, syscall.SIGTERM}, process.signals)
+
+	// A launcher that has already exited isn't signalled.
+	require.NoError(t, Stop(dir, &singleProcessFinder{process}, syscall.SIGTERM, SignalProcess, time.Second))
+	assert.Equal(t, []syscall.Signal{0, 0, syscall.SIGTERM}, process.signals)
+	require.NoError(t, Stop(t.TempDir(), &singleProcessFinder{process}, syscall.SIGTERM, SignalProcess, time.Second))
+
+	// One that ignores the signal is given up on after the timeout.
+	stubborn := &exitingProcess{exitOn: syscall.SIGKILL}
+	err := Stop(dir, &singleProcessFinder{stubborn}, syscall.SIGTERM, SignalProcess, 20*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "launcher (pid 100) still running")
+	assert.Contains(t, stubborn.signals, syscall.SIGTERM)
+}
+
+// restartingFinder starts a new launcher, writing its PID file, when the
+// process it hands out is sent restartOn.
+type restartingFinder struct {
+	t         *testing.T
+	filesDir  string
+	restartOn syscall.Signal
+	newPID    int
+	old       *exitingProcess
+}
+
+func (f *restartingFinder) FindProcess(pid int) (ProcessSignaler, error) {
+	if pid == f.newPID {
+		return &mockProcess{}, nil
+	}
+	return f, nil
+}
+
+func (f *restartingFinder) Signal(sig syscall.Signal) error {
+	if err := f.old.Signal(sig); err != nil {
+		return err
+	}
+	if sig == f.restartOn && f.newPID != 0 {
+		require.NoError(f.t, os.WriteFile(filepath.Join(Dir(f.filesDir), pidFileName), []byte(fmt.Sprint(f.newPID)), 0644))
+	}
+	return nil
+}
+
+func TestRestart(t *testing.T) {
+	originalInterval := stopPollInterval
+	stopPollInterval = time.Millisecond
+	t.Cleanup(func() { stopPollInterval = originalInterval })
+
+	dir := writeLauncherPID(t, 100)
+	finder := &restartingFinder{t: t, filesDir: dir, restartOn: syscall.SIGTERM, newPID: 200, old: &exitingProcess{exitOn: syscall.SIGTERM}}
+	watcher := NewWatcher(dir, finder)
+	assert.Nil(t, watcher.Check(time.Now()))
+
+	pid, err := Restart(dir, finder, syscall.SIGTERM, SignalProcess, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 200, pid)
+	assert.Equal(t, []syscall.Signal{0, syscall.SIGTERM}, finder.old.signals)
+	// The old launcher exiting isn't reported.
+	assert.Nil(t, watcher.Check(time.Now()))
+	assert.Equal(t, 200, watcher.runningPID)
+
+	// Without a new launcher the restart times out.
+	dir = writeLauncherPID(t, 100)
+	finder = &restartingFinder{t: t, filesDir: dir, restartOn: syscall.SIGTERM, old: &exitingProcess{exitOn: syscall.SIGTERM}}
+	_, err = Restart(dir, finder, syscall.SIGTERM, SignalProcess, 20*time.Millisecond)
+	assert.ErrorContains(t, err, "no launcher started within 20ms")
+
+	// A launcher that can't be signalled keeps its PID file.
+	dir = writeLauncherPID(t, 100)
+	_, err = Restart(dir, &singleProcessFinder{&mockProcess{signalErr: os.ErrPermission}}, syscall.SIGTERM, SignalProcess, time.Second)
+	assert.ErrorIs(t, err, os.ErrPermission)
+	pid, err = ReadPID(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 100, pid)
+}
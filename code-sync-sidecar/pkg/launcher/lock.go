@@ -0,0 +1,84 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// syncLockFileName is the file in the sidecar dir that is flocked to keep the
+	// sidecar's writes of env files and binaries from interleaving with the
+	// launcher's reads of them. The sidecar locks it exclusively, the launcher
+	// shared.
+	syncLockFileName = "sync.lock"
+	// SyncLockTimeout bounds the wait for the sync lock. The launcher holds it
+	// only while it sources the env files and copies the synced code.
+	SyncLockTimeout = 30 * time.Second
+	// syncLockPoll is how often a blocked lock is retried.
+	syncLockPoll = 50 * time.Millisecond
+)
+
+// SyncLockPath returns the lock file coordinating the sidecar and launcher.
+func SyncLockPath(filesDir string) string {
+	return filepath.Join(SidecarDir(filesDir), syncLockFileName)
+}
+
+// SyncLock is a held flock on the sync lock file.
+type SyncLock struct {
+	f *os.File
+}
+
+// LockSync takes the sync lock exclusively, for rewriting files the launcher
+// reads. It creates the lock file, readable by the launcher, if needed.
+func LockSync(filesDir string) (*SyncLock, error) {
+	return lockSync(filesDir, true, SyncLockTimeout)
+}
+
+// LockSyncShared takes the sync lock shared, as the launcher does while it
+// reads the env files and copies the synced code.
+func LockSyncShared(filesDir string) (*SyncLock, error) {
+	return lockSync(filesDir, false, SyncLockTimeout)
+}
+
+// errLockBusy is returned by tryLock when another process holds the lock.
+var errLockBusy = errors.New("lock held by another process")
+
+// lockSync takes the sync lock, exclusively or shared as the launcher does,
+// waiting up to timeout for it.
+func lockSync(filesDir string, exclusive bool, timeout time.Duration) (*SyncLock, error) {
+	path := SyncLockPath(filesDir)
+	if err := os.MkdirAll(filepath.Dir(path), Volume.InternalDir); err != nil {
+		return nil, fmt.Errorf("failed to create sidecar directory for %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, Volume.EnvFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sync lock %s: %w", path, err)
+	}
+	// The launcher opens it read-only to take its shared lock, whatever the umask.
+	f.Chmod(Volume.EnvFile)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f, exclusive)
+		if err == nil {
+			return &SyncLock{f: f}, nil
+		}
+		if !errors.Is(err, errLockBusy) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %v waiting for the launcher to release %s", timeout, path)
+		}
+		time.Sleep(syncLockPoll)
+	}
+}
+
+// Unlock releases the lock.
+func (l *SyncLock) Unlock() error {
+	return l.f.Close()
+}
@@ -0,0 +1,38 @@
+package launcher
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockSync(t *testing.T) {
+	filesDir := t.TempDir()
+
+	// The launcher's shared lock keeps the sidecar from writing.
+	reader, err := lockSync(filesDir, false, time.Second)
+	require.NoError(t, err)
+	other, err := lockSync(filesDir, false, time.Second)
+	require.NoError(t, err, "readers don't block each other")
+	require.NoError(t, other.Unlock())
+	_, err = lockSync(filesDir, true, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+
+	// The writer gets the lock once the reader lets go.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		reader.Unlock()
+	}()
+	writer, err := LockSync(filesDir)
+	require.NoError(t, err)
+	info, err := os.Stat(SyncLockPath(filesDir))
+	require.NoError(t, err)
+	assert.Equal(t, Volume.EnvFile, info.Mode().Perm(), "the launcher can open it")
+
+	_, err = lockSync(filesDir, false, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out", "readers wait for the writer")
+	require.NoError(t, writer.Unlock())
+}
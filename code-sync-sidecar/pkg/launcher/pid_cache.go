@@ -0,0 +1,95 @@
+package launcher
+
+import (
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// dirWatcher reports changes to PID files in watched launcher directories.
+type dirWatcher interface {
+	// watch starts watching dir. Watching a directory twice is a no-op.
+	watch(dir string) error
+	// changes returns the watched directories where a PID file changed since
+	// the last call, or all set if changes may have been missed. A directory
+	// that was removed is returned and no longer watched.
+	changes() (dirs []string, all bool, err error)
+}
+
+// pidCache remembers the PID in each launcher's PID file, so signalling the
+// launcher for every push doesn't read and parse the file again.
+// A file watch invalidates an entry when the file changes; the kernel queues
+// the change before the write returns, so pending changes are read before
+// every lookup and a cached PID is never older than the file. Without a
+// watch, as outside Linux or once inotify runs out of watches, every lookup
+// reads the file.
+type pidCache struct {
+	mu      sync.Mutex
+	started bool
+	watcher dirWatcher
+	pids    map[string]int // Keyed by PID file path
+}
+
+// launcherPIDs is shared by everything that looks up the launcher's PID.
+var launcherPIDs pidCache
+
+// pid returns the PID in the PID file at path.
+func (c *pidCache) pid(path string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		c.started = true
+		watcher, err := newDirWatcher()
+		if err != nil {
+			log.Info("Not caching the launcher PID", zap.Error(err))
+		} else {
+			c.watcher, c.pids = watcher, make(map[string]int)
+		}
+	}
+	if c.watcher == nil {
+		return readPIDFile(path)
+	}
+
+	dirs, all, err := c.watcher.changes()
+	if err != nil {
+		log.Warn("Failed to read launcher directory changes, dropping cached PIDs", zap.Error(err))
+		all = true
+	}
+	if all {
+		clear(c.pids)
+	}
+	for _, dir := range dirs {
+		for cached := range c.pids {
+			if filepath.Dir(cached) == dir {
+				delete(c.pids, cached)
+			}
+		}
+	}
+
+	if pid, ok := c.pids[path]; ok {
+		return pid, nil
+	}
+	// Watch before reading, so a change made meanwhile isn't missed.
+	if err := c.watcher.watch(filepath.Dir(path)); err != nil {
+		log.Debug("Not caching the launcher PID", zap.String("path", path), zap.Error(err))
+		return readPIDFile(path)
+	}
+	pid, err := readPIDFile(path)
+	if err != nil {
+		return 0, err
+	}
+	c.pids[path] = pid
+	return pid, nil
+}
+
+// invalidate drops the cached PID in the PID file at path, for when the
+// process it names is gone and the file may not be watched reliably, as on a
+// network volume.
+func (c *pidCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pids, path)
+}
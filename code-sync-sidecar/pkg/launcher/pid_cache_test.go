@@ -0,0 +1,74 @@
+//go:build linux
+
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIDCache(t *testing.T) {
+	dir := writeLauncherPID(t, 100)
+	pidFile := pidFile(dir, MainLauncher)
+	var cache pidCache
+
+	pid, err := cache.pid(pidFile)
+	require.NoError(t, err)
+	assert.Equal(t, 100, pid)
+	assert.Equal(t, map[string]int{pidFile: 100}, cache.pids)
+
+	// Another launcher's PID file in the same directory is cached on its own,
+	// and changing either drops both.
+	workerFile := filepath.Join(Dir(dir), "worker.pid")
+	require.NoError(t, os.WriteFile(workerFile, []byte("150\n"), 0644))
+	pid, err = cache.pid(workerFile)
+	require.NoError(t, err)
+	assert.Equal(t, 150, pid)
+	_, err = cache.pid(pidFile)
+	require.NoError(t, err)
+	assert.Len(t, cache.pids, 2)
+	require.NoError(t, os.WriteFile(workerFile, []byte("160\n"), 0644))
+	pid, err = cache.pid(workerFile)
+	require.NoError(t, err)
+	assert.Equal(t, 160, pid)
+	assert.Equal(t, map[string]int{workerFile: 160}, cache.pids)
+
+	// A new launcher's PID is seen as soon as it's written.
+	require.NoError(t, os.WriteFile(pidFile, []byte("200\n"), 0644))
+	pid, err = cache.pid(pidFile)
+	require.NoError(t, err)
+	assert.Equal(t, 200, pid)
+
+	// Other files in the launcher directory don't matter.
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(dir), ExitReasonFile), []byte("exited"), 0644))
+	dirs, all, err := cache.watcher.changes()
+	require.NoError(t, err)
+	assert.Empty(t, dirs)
+	assert.False(t, all)
+
+	require.NoError(t, os.Remove(pidFile))
+	_, err = cache.pid(pidFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NotContains(t, cache.pids, pidFile)
+
+	// A removed directory stops being watched, and is watched again once back.
+	require.NoError(t, os.WriteFile(pidFile, []byte("300\n"), 0644))
+	pid, err = cache.pid(pidFile)
+	require.NoError(t, err)
+	assert.Equal(t, 300, pid)
+	require.NoError(t, os.RemoveAll(Dir(dir)))
+	_, err = cache.pid(pidFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Empty(t, cache.watcher.(*inotifyWatcher).wds)
+
+	require.NoError(t, os.MkdirAll(Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(pidFile, []byte("400\n"), 0644))
+	pid, err = cache.pid(pidFile)
+	require.NoError(t, err)
+	assert.Equal(t, 400, pid)
+	assert.Len(t, cache.watcher.(*inotifyWatcher).wds, 1)
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package launcher
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// pidWatchMask is what an inotify watch on a launcher directory reports: PID
+// files being written, replaced or removed, and the directory itself going
+// away.
+const pidWatchMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher watches launcher directories with a non-blocking inotify
+// instance, read by whoever asks for changes rather than a goroutine.
+type inotifyWatcher struct {
+	fd   int
+	dirs map[int32]string // Keyed by watch descriptor
+	wds  map[string]int32
+}
+
+func newDirWatcher() (dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inotify instance: %w", err)
+	}
+	return &inotifyWatcher{fd: fd, dirs: make(map[int32]string), wds: make(map[string]int32)}, nil
+}
+
+func (w *inotifyWatcher) watch(dir string) error {
+	if _, ok := w.wds[dir]; ok {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, pidWatchMask)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	w.dirs[int32(wd)], w.wds[dir] = dir, int32(wd)
+	return nil
+}
+
+func (w *inotifyWatcher) changes() ([]string, bool, error) {
+	var dirs []string
+	var buf [4096]byte
+	for {
+		n, err := syscall.Read(w.fd, buf[:])
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if errors.Is(err, syscall.EAGAIN) {
+			return dirs, false, nil
+		}
+		if err != nil {
+			return dirs, true, fmt.Errorf("failed to read inotify events: %w", err)
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := buf[offset:n]
+			wd := int32(binary.NativeEndian.Uint32(event[0:]))
+			mask := binary.NativeEndian.Uint32(event[4:])
+			nameLen := int(binary.NativeEndian.Uint32(event[12:]))
+			name := strings.TrimRight(string(event[syscall.SizeofInotifyEvent:syscall.SizeofInotifyEvent+nameLen]), "\x00")
+			offset += syscall.SizeofInotifyEvent + nameLen
+
+			if mask&syscall.IN_Q_OVERFLOW != 0 {
+				return nil, true, nil
+			}
+			dir, ok := w.dirs[wd]
+			switch {
+			case !ok:
+			case mask&(syscall.IN_IGNORED|syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+				// A moved directory is still watched under its old name, so drop the watch.
+				syscall.InotifyRmWatch(w.fd, uint32(wd))
+				delete(w.dirs, wd)
+				delete(w.wds, dir)
+				dirs = append(dirs, dir)
+			case strings.HasSuffix(name, pidFileExt):
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package launcher
+
+import "errors"
+
+func newDirWatcher() (dirWatcher, error) {
+	return nil, errors.New("file watches are only used on Linux")
+}
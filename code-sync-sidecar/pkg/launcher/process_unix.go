@@ -0,0 +1,53 @@
+//go:build unix
+
+package launcher
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// signalsByName holds the signals ParseSignal accepts.
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+func signalOSProcess(p *os.Process, sig syscall.Signal) error {
+	return p.Signal(sig)
+}
+
+func processGroup(pid int) (int, error) {
+	return syscall.Getpgid(pid)
+}
+
+func signalProcessGroup(pgid int, sig syscall.Signal) error {
+	return syscall.Kill(-pgid, sig)
+}
+
+// fileGID returns the group owning a file, or -1 if unknown.
+func fileGID(info fs.FileInfo) int {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Gid)
+	}
+	return -1
+}
+
+// tryLock flocks f without waiting, returning errLockBusy if another process
+// holds a conflicting lock.
+func tryLock(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLockBusy
+	}
+	return err
+}
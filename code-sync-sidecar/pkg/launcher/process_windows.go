@@ -0,0 +1,74 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Windows builds are for running the sidecar in local development. Windows
+// processes can't be sent signals other than SIGKILL and have no process
+// groups, so reloads there use NoSignal, the default.
+
+// signalsByName holds the signals ParseSignal accepts.
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+var errProcessGroupsUnsupported = errors.New("process groups aren't supported on windows")
+
+func signalOSProcess(p *os.Process, sig syscall.Signal) error {
+	switch sig {
+	case 0:
+		return nil // os.FindProcess opened the process, so it exists
+	case syscall.SIGKILL:
+		return p.Kill()
+	default:
+		return fmt.Errorf("can't send %s on windows; set signals.reload to none", sig)
+	}
+}
+
+func processGroup(int) (int, error) {
+	return 0, errProcessGroupsUnsupported
+}
+
+func signalProcessGroup(int, syscall.Signal) error {
+	return errProcessGroupsUnsupported
+}
+
+// fileGID returns -1: Windows files have no owning group.
+func fileGID(fs.FileInfo) int {
+	return -1
+}
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// tryLock locks f with LockFileEx without waiting, returning errLockBusy if
+// another process holds a conflicting lock. Closing f releases it.
+func tryLock(f *os.File, exclusive bool) error {
+	flags := uintptr(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	var overlapped syscall.Overlapped
+	r, _, err := procLockFileEx.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errLockBusy
+	}
+	return err
+}
@@ -0,0 +1,373 @@
+package launcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+const (
+	// rsyncVerifyTimeout bounds the `rsync --version` check of a provisioned binary.
+	rsyncVerifyTimeout = 10 * time.Second
+)
+
+// execCommand is replaced in tests.
+var execCommand = exec.CommandContext
+
+// RsyncBinaries maps each architecture the image ships rsync for to its file
+// in the binaries source directory.
+var RsyncBinaries = map[string]string{
+	"amd64": "rsync_amd64",
+	"arm64": "rsync_arm64",
+}
+
+// LauncherScriptName is the launcher script's name in the image and in the
+// sidecar dir.
+const LauncherScriptName = "rsync-launcher.sh"
+
+// SidecarBinaryName is the sidecar executable's name in the sidecar dir, from
+// where the app container runs "code-sync-sidecar launch".
+const SidecarBinaryName = "code-sync-sidecar"
+
+// SidecarBinaryPath returns the path the app container runs the sidecar from.
+func SidecarBinaryPath(filesDir string) string {
+	return filepath.Join(SidecarDir(filesDir), SidecarBinaryName)
+}
+
+// RsyncPath returns the path the launcher runs rsync from.
+func RsyncPath(filesDir string) string {
+	return filepath.Join(SidecarDir(filesDir), "rsync")
+}
+
+// Assets are the files CopyBinaries provisions. The launcher script is
+// compiled into the sidecar, and so is the rsync binary for its architecture
+// when it is built with the embed_rsync tag; a file that isn't is copied from
+// SourceDir instead.
+type Assets struct {
+	LauncherScript []byte
+	Rsync          []byte
+	SourceDir      string
+	// Checksums lists the SHA-256 sum of every file, in sha256sum format and
+	// keyed by the file's name in SourceDir (see RsyncBinaries). They should
+	// be compiled in, so a file changed in the image or on the volume doesn't
+	// match.
+	Checksums string
+	// Sidecar is the running sidecar's executable, copied for the app container
+	// to launch the app with. It has no recorded checksum, being the binary that
+	// holds them; it's compared with the copy on the volume instead.
+	Sidecar string
+}
+
+// CopyBinaries provisions the launcher script, the sidecar itself for the
+// launch command, and the rsync binary for this architecture into the sidecar
+// dir, checking the script and rsync against their checksums. A
+// file already provisioned with the right content is left as it is. The sync
+// lock is held throughout, as a running launcher may be reading the files
+// being replaced. It returns what ProbeRsync found out about the provisioned
+// rsync.
+func CopyBinaries(filesDir string, assets Assets) (RsyncInfo, error) {
+	log.Info("Setting up binaries", zap.String("targetDir", filesDir))
+	binDir := SidecarDir(filesDir)
+	if err := os.MkdirAll(binDir, Volume.InternalDir); err != nil {
+		return RsyncInfo{}, fmt.Errorf("failed to ensure sidecar directory exists %s: %w", binDir, err)
+	}
+	lock, err := LockSync(filesDir)
+	if err != nil {
+		return RsyncInfo{}, err
+	}
+	defer lock.Unlock()
+
+	sums, err := ParseChecksums(assets.Checksums)
+	if err != nil {
+		return RsyncInfo{}, fmt.Errorf("failed to parse binary checksums: %w", err)
+	}
+
+	launcherDst := filepath.Join(binDir, LauncherScriptName)
+	if err := provisionFile(launcherDst, LauncherScriptName, assets.LauncherScript, assets.SourceDir, sums); err != nil {
+		return RsyncInfo{}, err
+	}
+
+	if assets.Sidecar != "" {
+		if err := provisionSidecar(SidecarBinaryPath(filesDir), assets.Sidecar); err != nil {
+			return RsyncInfo{}, err
+		}
+	}
+
+	// Only the rsync built for this architecture is provisioned, as .sidecar/rsync.
+	rsyncBinary, err := rsyncBinaryFor(runtime.GOARCH)
+	if err != nil {
+		return RsyncInfo{}, err
+	}
+	rsyncDst := RsyncPath(filesDir)
+	if err := provisionFile(rsyncDst, rsyncBinary, assets.Rsync, assets.SourceDir, sums); err != nil {
+		return RsyncInfo{}, err
+	}
+	info, err := ProbeRsync(rsyncDst)
+	if err != nil {
+		return RsyncInfo{}, err
+	}
+	log.Info("Successfully set up binaries", zap.String("targetDir", filesDir),
+		zap.String("rsyncVersion", info.Version), zap.Int("rsyncProtocol", info.Protocol))
+	return info, nil
+}
+
+// provisionFile writes the file called name in the image to dst: data if it
+// was compiled in, or else the file in sourceDir. Compiled-in data is checked
+// before it's written and the file on disk after.
+func provisionFile(dst, name string, data []byte, sourceDir string, sums map[string]string) error {
+	if data != nil {
+		sum := sha256.Sum256(data)
+		if err := verifySum(hex.EncodeToString(sum[:]), "embedded "+name, name, sums); err != nil {
+			return err
+		}
+	}
+	if VerifyChecksum(dst, name, sums) == nil {
+		log.Info("Binary is up to date", zap.String("file", dst))
+		return nil
+	}
+	var err error
+	if data != nil {
+		err = writeExecutable(dst, bytes.NewReader(data), "embedded "+name)
+	} else {
+		err = copyFile(filepath.Join(sourceDir, name), dst)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to provision binary %s: %w", name, err)
+	}
+	return VerifyChecksum(dst, name, sums)
+}
+
+// provisionSidecar copies the sidecar executable at src to dst, unless dst
+// already has the same content.
+func provisionSidecar(dst, src string) error {
+	want, err := hashFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to provision the sidecar binary: %w", err)
+	}
+	if got, err := hashFile(dst); err == nil && got == want {
+		log.Info("Binary is up to date", zap.String("file", dst))
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return fmt.Errorf("failed to provision the sidecar binary: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst with writeExecutable.
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file %s: %w", src, err)
+	}
+	defer srcFile.Close()
+	return writeExecutable(dst, srcFile, src)
+}
+
+// writeExecutable writes what r holds, from source, to dst and makes it
+// executable. dst is replaced with a rename, so a launcher script or rsync
+// that is running keeps its old copy.
+func writeExecutable(dst string, r io.Reader, source string) error {
+	log.Info("Copying file", zap.String("source", source), zap.String("destination", dst))
+	tmpPath := dst + ".tmp"
+	dstFile, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file %s: %w", tmpPath, err)
+	}
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	bytesCopied, err := io.Copy(dstFile, r)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy data from %s to %s: %w", source, dst, err)
+	}
+
+	// Make the destination file executable
+	if err := os.Chmod(tmpPath, Volume.Executable); err != nil {
+		log.Warn("Failed to set executable permission", zap.String("file", dst), zap.Error(err))
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", dst, err)
+	}
+
+	log.Info("Successfully copied file",
+		zap.String("source", source),
+		zap.String("destination", dst),
+		zap.Int64("bytesCopied", bytesCopied),
+	)
+	return nil
+}
+
+// rsyncBinaryFor returns the rsync binary built for goarch. The app container
+// runs on the same node as the sidecar, so the sidecar's architecture is the
+// one the launcher needs.
+func rsyncBinaryFor(goarch string) (string, error) {
+	binary, ok := RsyncBinaries[goarch]
+	if !ok {
+		supported := make([]string, 0, len(RsyncBinaries))
+		for arch := range RsyncBinaries {
+			supported = append(supported, arch)
+		}
+		sort.Strings(supported)
+		return "", fmt.Errorf("unsupported architecture %s: rsync is only provided for %s", goarch, strings.Join(supported, ", "))
+	}
+	return binary, nil
+}
+
+// MinRsyncProtocol is the oldest rsync protocol the sidecar applies batches
+// with. Protocol 30 came with rsync 3.0, which the client side requires too.
+const MinRsyncProtocol = 30
+
+// RsyncInfo describes an rsync binary, as reported by `rsync --version`.
+type RsyncInfo struct {
+	Version  string // e.g. "3.4.1"
+	Protocol int    // e.g. 32
+}
+
+// ProbeRsync checks that the rsync binary at path runs, speaks a recent enough
+// protocol and supports batch mode, so a binary for the wrong architecture or
+// libc, or a noexec volume, is reported at startup instead of on the first push.
+func ProbeRsync(path string) (RsyncInfo, error) {
+	output, err := runRsync(path, "--version")
+	if err != nil {
+		return RsyncInfo{}, err
+	}
+	firstLine, _, _ := strings.Cut(output, "\n")
+	info, err := parseRsyncVersion(firstLine)
+	if err != nil {
+		return RsyncInfo{}, fmt.Errorf("unexpected output from %s --version: %w", path, err)
+	}
+	if info.Protocol < MinRsyncProtocol {
+		return info, fmt.Errorf("rsync at %s speaks protocol %d (version %s), but protocol %d or later is required", path, info.Protocol, info.Version, MinRsyncProtocol)
+	}
+	help, err := runRsync(path, "--help")
+	if err != nil {
+		return info, err
+	}
+	if !strings.Contains(help, "--read-batch") {
+		return info, fmt.Errorf("rsync at %s (version %s) was built without batch mode", path, info.Version)
+	}
+	return info, nil
+}
+
+// runRsync runs the rsync binary at path with a single option and returns its
+// output, explaining the usual reasons a bundled binary can't run on a node.
+func runRsync(path, option string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rsyncVerifyTimeout)
+	defer cancel()
+	output, err := execCommand(ctx, path, option).CombinedOutput()
+	if err != nil {
+		trimmed := strings.TrimSpace(string(output))
+		return "", fmt.Errorf("rsync at %s does not run: %w%s (output: %s)", path, err, rsyncExecHint(path, err, trimmed), trimmed)
+	}
+	return string(output), nil
+}
+
+// rsyncExecHint suggests why the rsync binary at path failed to run with err
+// and output, or returns "" when there's nothing more specific to say.
+func rsyncExecHint(path string, err error, output string) string {
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, syscall.ENOEXEC):
+		return "; the binary was built for another architecture"
+	case errors.Is(err, os.ErrNotExist):
+		if _, statErr := os.Stat(path); statErr == nil {
+			// The kernel reports a missing ELF interpreter as the binary not existing.
+			return "; its dynamic loader is missing, as when a binary built against glibc runs on musl (or the reverse)"
+		}
+	case errors.Is(err, os.ErrPermission):
+		return "; the volume may be mounted noexec"
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 127 && strings.Contains(output, "shared librar"):
+		return "; a shared library it links against is missing on this node"
+	}
+	return ""
+}
+
+// parseRsyncVersion parses the first line of `rsync --version`, e.g.
+// "rsync  version 3.4.1  protocol version 32".
+func parseRsyncVersion(line string) (RsyncInfo, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 || fields[0] != "rsync" || fields[1] != "version" || fields[3] != "protocol" || fields[4] != "version" {
+		return RsyncInfo{}, fmt.Errorf("%q is not an rsync version line", strings.TrimSpace(line))
+	}
+	protocol, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return RsyncInfo{}, fmt.Errorf("invalid protocol version %q", fields[5])
+	}
+	return RsyncInfo{Version: strings.TrimPrefix(fields[2], "v"), Protocol: protocol}, nil
+}
+
+// ParseChecksums parses sha256sum output into a map of file name to hex digest.
+func ParseChecksums(data string) (map[string]string, error) {
+	sums := make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok || len(sum) != hex.EncodedLen(sha256.Size) {
+			return nil, fmt.Errorf("invalid checksum line %d: %q", i+1, line)
+		}
+		sums[name] = sum
+	}
+	return sums, nil
+}
+
+// VerifyChecksum checks that the file at path, provisioned from name, matches
+// the sum recorded for name.
+func VerifyChecksum(path, name string, sums map[string]string) error {
+	if _, ok := sums[name]; !ok {
+		return fmt.Errorf("no checksum recorded for %s", name)
+	}
+	got, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	return verifySum(got, path, name, sums)
+}
+
+// verifySum checks that got, the hex SHA-256 of what label describes, is the
+// sum recorded for name.
+func verifySum(got, label, name string, sums map[string]string) error {
+	want, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("no checksum recorded for %s", name)
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", label, got, want)
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
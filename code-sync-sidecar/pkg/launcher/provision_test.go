@@ -0,0 +1,184 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperCommandContext runs TestHelperProcess in place of command.
+func helperCommandContext(ctx context.Context, command string, args ...string) *exec.Cmd {
+	cs := append([]string{"-test.run=TestHelperProcess", "--", command}, args...)
+	cmd := exec.CommandContext(ctx, os.Args[0], cs...)
+	cmd.Env = append([]string{"GO_WANT_HELPER_PROCESS=1"}, os.Environ()...)
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It simulates `rsync --version` and
+// `rsync --help`, for an rsync that's too old as rsync-2.6 and for one without
+// batch mode as rsync-nobatch, and fails for any other command.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) == 3 {
+		switch name := filepath.Base(args[1]); {
+		case args[2] == "--version" && name == "rsync-2.6":
+			fmt.Println("rsync  version 2.6.9  protocol version 29")
+			os.Exit(0)
+		case args[2] == "--version" && (name == "rsync" || name == "rsync-nobatch"):
+			fmt.Println("rsync  version v3.4.1  protocol version 32")
+			fmt.Println("Copyright (C) 1996-2025 by Andrew Tridgell, Wayne Davison, and others.")
+			os.Exit(0)
+		case args[2] == "--help" && name == "rsync":
+			fmt.Println(" --write-batch=FILE       write a batched update to FILE")
+			fmt.Println(" --read-batch=FILE        read a batched update from FILE")
+			os.Exit(0)
+		case args[2] == "--help" && name == "rsync-nobatch":
+			fmt.Println(" --archive, -a            archive mode is -rlptgoD (no -A,-X,-U,-N,-H)")
+			os.Exit(0)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "HelperProcess: unexpected command %q\n", args)
+	os.Exit(1)
+}
+
+func TestRsyncBinaryFor(t *testing.T) {
+	binary, err := rsyncBinaryFor("amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "rsync_amd64", binary)
+	binary, err = rsyncBinaryFor("arm64")
+	require.NoError(t, err)
+	assert.Equal(t, "rsync_arm64", binary)
+
+	_, err = rsyncBinaryFor("s390x")
+	assert.ErrorContains(t, err, "unsupported architecture s390x: rsync is only provided for amd64, arm64")
+}
+
+func TestCopyBinaries(t *testing.T) {
+	if _, ok := RsyncBinaries[runtime.GOARCH]; !ok {
+		t.Skipf("no rsync binary for %s", runtime.GOARCH)
+	}
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	srcDir := t.TempDir()
+	checksums := ""
+	for _, name := range []string{"rsync_amd64", "rsync_arm64", "rsync-launcher.sh"} {
+		require.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte(name), 0755))
+		sum, err := hashFile(filepath.Join(srcDir, name))
+		require.NoError(t, err)
+		checksums += fmt.Sprintf("%s  %s\n", sum, name)
+	}
+
+	filesDir := t.TempDir()
+	info, err := CopyBinaries(filesDir, Assets{LauncherScript: []byte("rsync-launcher.sh"), SourceDir: srcDir, Checksums: checksums})
+	require.NoError(t, err)
+	assert.Equal(t, RsyncInfo{Version: "3.4.1", Protocol: 32}, info)
+
+	rsync, err := os.ReadFile(RsyncPath(filesDir))
+	require.NoError(t, err)
+	assert.Equal(t, "rsync_"+runtime.GOARCH, string(rsync), "only the matching binary is provisioned")
+	assert.FileExists(t, filepath.Join(SidecarDir(filesDir), LauncherScriptName))
+	assert.NoFileExists(t, filepath.Join(SidecarDir(filesDir), "rsync_amd64"))
+	assert.NoFileExists(t, filepath.Join(SidecarDir(filesDir), "rsync_arm64"))
+
+	assert.NoFileExists(t, SidecarBinaryPath(filesDir), "the sidecar is only provisioned when given")
+
+	// Files already provisioned with the right content aren't replaced.
+	sidecar := filepath.Join(t.TempDir(), "code-sync-sidecar")
+	require.NoError(t, os.WriteFile(sidecar, []byte("sidecar v1"), 0755))
+	before, err := os.Stat(RsyncPath(filesDir))
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(srcDir))
+	_, err = CopyBinaries(filesDir, Assets{LauncherScript: []byte("rsync-launcher.sh"), Rsync: []byte("rsync_" + runtime.GOARCH), Checksums: checksums, Sidecar: sidecar})
+	require.NoError(t, err)
+	after, err := os.Stat(RsyncPath(filesDir))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+	data, err := os.ReadFile(SidecarBinaryPath(filesDir))
+	require.NoError(t, err)
+	assert.Equal(t, "sidecar v1", string(data))
+
+	// A new sidecar replaces the copy on the volume.
+	require.NoError(t, os.WriteFile(sidecar, []byte("sidecar v2"), 0755))
+	_, err = CopyBinaries(filesDir, Assets{LauncherScript: []byte("rsync-launcher.sh"), Rsync: []byte("rsync_" + runtime.GOARCH), Checksums: checksums, Sidecar: sidecar})
+	require.NoError(t, err)
+	data, err = os.ReadFile(SidecarBinaryPath(filesDir))
+	require.NoError(t, err)
+	assert.Equal(t, "sidecar v2", string(data))
+
+	// A modified file is caught at startup, whether compiled in or copied.
+	_, err = CopyBinaries(t.TempDir(), Assets{LauncherScript: []byte("rsync-"), Checksums: checksums})
+	assert.ErrorContains(t, err, "checksum mismatch for embedded rsync-launcher.sh")
+	require.NoError(t, os.MkdirAll(srcDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "rsync_"+runtime.GOARCH), []byte("rsync-"), 0755))
+	_, err = CopyBinaries(t.TempDir(), Assets{LauncherScript: []byte("rsync-launcher.sh"), SourceDir: srcDir, Checksums: checksums})
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestParseChecksums(t *testing.T) {
+	_, err := ParseChecksums("abc  rsync_amd64\n")
+	assert.ErrorContains(t, err, "invalid checksum line 1")
+
+	sums, err := ParseChecksums("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  rsync_amd64\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rsync_amd64": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}, sums)
+	assert.ErrorContains(t, VerifyChecksum("/nonexistent", "unknown", sums), "no checksum recorded for unknown")
+}
+
+func TestProbeRsync(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	info, err := ProbeRsync("/tmp/.sidecar/rsync")
+	require.NoError(t, err)
+	assert.Equal(t, RsyncInfo{Version: "3.4.1", Protocol: 32}, info)
+
+	_, err = ProbeRsync("/tmp/.sidecar/rsync-2.6")
+	assert.ErrorContains(t, err, "speaks protocol 29 (version 2.6.9), but protocol 30 or later is required")
+	_, err = ProbeRsync("/tmp/.sidecar/rsync-nobatch")
+	assert.ErrorContains(t, err, "was built without batch mode")
+	// The helper process exits with an error for any command it doesn't simulate.
+	_, err = ProbeRsync("/tmp/.sidecar/broken")
+	assert.ErrorContains(t, err, "does not run")
+}
+
+func TestProbeRsync_ExecFailures(t *testing.T) {
+	dir := t.TempDir()
+	// An ELF header for a 32-bit big-endian machine no test runner uses.
+	foreign := filepath.Join(dir, "rsync-foreign")
+	require.NoError(t, os.WriteFile(foreign, append([]byte("\x7fELF\x01\x02\x01"), make([]byte, 64)...), 0755))
+	_, err := ProbeRsync(foreign)
+	assert.ErrorContains(t, err, "does not run")
+
+	// A binary whose ELF interpreter is missing fails as if it didn't exist.
+	assert.Contains(t, rsyncExecHint(foreign, &os.PathError{Op: "fork/exec", Path: foreign, Err: syscall.ENOENT}, ""), "glibc runs on musl")
+	assert.Empty(t, rsyncExecHint(filepath.Join(dir, "missing"), &os.PathError{Op: "fork/exec", Err: syscall.ENOENT}, ""))
+	assert.Contains(t, rsyncExecHint(foreign, &os.PathError{Op: "fork/exec", Err: syscall.ENOEXEC}, ""), "another architecture")
+}
+
+func TestParseRsyncVersion(t *testing.T) {
+	info, err := parseRsyncVersion("rsync  version 3.2.7  protocol version 31")
+	require.NoError(t, err)
+	assert.Equal(t, RsyncInfo{Version: "3.2.7", Protocol: 31}, info)
+
+	_, err = parseRsyncVersion("openrsync: protocol version 29")
+	assert.ErrorContains(t, err, "is not an rsync version line")
+	_, err = parseRsyncVersion("rsync  version 3.2.7  protocol version x")
+	assert.ErrorContains(t, err, `invalid protocol version "x"`)
+}
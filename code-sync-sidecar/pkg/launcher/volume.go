@@ -0,0 +1,130 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// Modes are the permissions the sidecar gives what it creates on the shared
+// volume for the launcher in the app container.
+type Modes struct {
+	// InternalDir is used for .sidecar and .launcher, which both containers write to.
+	InternalDir os.FileMode
+	Executable  os.FileMode
+	EnvFile     os.FileMode
+}
+
+var (
+	// By default anything goes, so the app container may run as any user.
+	DefaultModes = Modes{InternalDir: 0777, Executable: 0777, EnvFile: 0644}
+	// In hardened mode only the owner and the group shared by the containers
+	// get access. Directories are setgid so new files keep the shared group.
+	HardenedModes = Modes{InternalDir: 0770 | os.ModeSetgid, Executable: 0750, EnvFile: 0640}
+)
+
+// Volume holds the modes in use; it is set once at startup by PrepareVolume.
+var Volume = DefaultModes
+
+// geteuid is replaced in tests, which usually run as root.
+var geteuid = os.Geteuid
+
+// PrepareVolume creates the sidecar and launcher directories in filesDir. In
+// hardened mode it first checks the volume is set up for a non-root sidecar
+// sharing a group with the app, and fails with a diagnostic if it isn't.
+func PrepareVolume(filesDir string, hardened bool, sharedGID int) error {
+	dirs := []string{SidecarDir(filesDir), Dir(filesDir)}
+	if !hardened {
+		Volume = DefaultModes
+		// Very open permissions so they can be accessed by the app and sidecar.
+		for _, dir := range dirs {
+			if err := os.MkdirAll(dir, Volume.InternalDir); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+			if err := os.Chmod(dir, Volume.InternalDir); err != nil {
+				log.Warn("Failed to change directory permissions", zap.Error(err), zap.String("path", dir))
+			}
+		}
+		return nil
+	}
+
+	Volume = HardenedModes
+	gid, err := CheckHardenedVolume(filesDir, sharedGID)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, Volume.InternalDir); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := os.Chown(dir, -1, gid); err != nil {
+			return fmt.Errorf("failed to give %s to the shared group %d: %w", dir, gid, err)
+		}
+		if err := os.Chmod(dir, Volume.InternalDir); err != nil {
+			return fmt.Errorf("failed to set permissions of %s: %w", dir, err)
+		}
+	}
+	log.Info("Prepared shared volume in hardened mode", zap.String("filesDir", filesDir), zap.Int("sharedGID", gid))
+	return nil
+}
+
+// CheckHardenedVolume checks every requirement of hardened mode and reports
+// all that aren't met at once. It returns the shared group: sharedGID, or the
+// files directory's group when that is -1.
+func CheckHardenedVolume(filesDir string, sharedGID int) (int, error) {
+	var problems []string
+	if geteuid() == 0 {
+		problems = append(problems, "the sidecar is running as root; run it as a non-root user (runAsUser in Kubernetes, USER in Docker)")
+	}
+
+	info, err := os.Stat(filesDir)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("the files directory %s can't be read: %v; mount the shared volume there", filesDir, err))
+		return sharedGID, hardenedVolumeError(problems)
+	}
+	if !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("the files directory %s is not a directory", filesDir))
+		return sharedGID, hardenedVolumeError(problems)
+	}
+
+	dirGID := fileGID(info)
+	gid := sharedGID
+	if gid < 0 {
+		gid = dirGID
+	}
+	if dirGID >= 0 && dirGID != gid {
+		problems = append(problems, fmt.Sprintf("the files directory %s belongs to group %d, not the shared group %d; set fsGroup to %d so the volume is group-owned", filesDir, dirGID, gid, gid))
+	}
+	if info.Mode().Perm()&0070 != 0070 {
+		problems = append(problems, fmt.Sprintf("the files directory %s has mode %04o; the shared group needs read, write and search access (chmod g+rwx, or fsGroup)", filesDir, info.Mode().Perm()))
+	}
+
+	groups, err := os.Getgroups()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to list the sidecar's groups: %v", err))
+	} else if gid >= 0 && os.Getegid() != gid && !slices.Contains(groups, gid) {
+		problems = append(problems, fmt.Sprintf("the sidecar (uid %d, gid %d, groups %v) is not in the shared group %d; add it with fsGroup or supplementalGroups", geteuid(), os.Getegid(), groups, gid))
+	}
+
+	if probe, err := os.CreateTemp(filesDir, ".sidecar-write-check-*"); err != nil {
+		problems = append(problems, fmt.Sprintf("the sidecar can't write to the files directory %s: %v", filesDir, err))
+	} else {
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+	return gid, hardenedVolumeError(problems)
+}
+
+func hardenedVolumeError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the shared volume is not set up for hardened mode (security.hardened):\n  - %s\n"+
+		"The sidecar and the app container must share a group that owns the volume and can write to it; see \"Hardened mode\" in the README",
+		strings.Join(problems, "\n  - "))
+}
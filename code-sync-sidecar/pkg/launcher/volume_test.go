@@ -0,0 +1,71 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asNonRoot makes the hardened checks see a non-root sidecar and restores the
+// default modes afterwards.
+func asNonRoot(t *testing.T) {
+	t.Helper()
+	geteuid = func() int { return 1000 }
+	t.Cleanup(func() {
+		geteuid = os.Geteuid
+		Volume = DefaultModes
+	})
+}
+
+func TestPrepareVolume_Default(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, PrepareVolume(filesDir, false, -1))
+
+	info, err := os.Stat(Dir(filesDir))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0777), info.Mode().Perm())
+	assert.Equal(t, DefaultModes, Volume)
+}
+
+func TestPrepareVolume_Hardened(t *testing.T) {
+	asNonRoot(t)
+	filesDir := t.TempDir()
+	require.NoError(t, os.Chmod(filesDir, 0770))
+
+	require.NoError(t, PrepareVolume(filesDir, true, os.Getegid()))
+	for _, dir := range []string{SidecarDir(filesDir), Dir(filesDir)} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0770), info.Mode().Perm())
+		assert.NotZero(t, info.Mode()&os.ModeSetgid)
+	}
+	assert.Equal(t, HardenedModes, Volume)
+}
+
+func TestPrepareVolume_HardenedDiagnostics(t *testing.T) {
+	asNonRoot(t)
+	filesDir := t.TempDir()
+	require.NoError(t, os.Chmod(filesDir, 0755))
+
+	err := PrepareVolume(filesDir, true, 4242)
+	require.Error(t, err)
+	for _, problem := range []string{
+		"not set up for hardened mode",
+		"not the shared group 4242",
+		"has mode 0755",
+		"is not in the shared group 4242",
+		`see "Hardened mode" in the README`,
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+	assert.NoDirExists(t, SidecarDir(filesDir))
+
+	geteuid = func() int { return 0 }
+	err = PrepareVolume(filepath.Join(filesDir, "missing"), true, -1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "running as root")
+	assert.Contains(t, err.Error(), "mount the shared volume there")
+}
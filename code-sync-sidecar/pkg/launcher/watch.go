@@ -0,0 +1,64 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// The launcher writes why it exited to ExitReasonFile, and the last exit
+	// status of the app to AppStatusFile, both in the launcher directory.
+	ExitReasonFile = "exit_reason"
+	AppStatusFile  = "app.status"
+)
+
+// Watcher detects the launcher exiting by probing its PID with signal 0.
+type Watcher struct {
+	filesDir      string
+	processFinder ProcessFinder
+	// runningPID is the launcher PID seen alive on the last check, or 0.
+	runningPID int
+}
+
+// NewWatcher creates a Watcher for the launcher of the app in filesDir.
+func NewWatcher(filesDir string, processFinder ProcessFinder) *Watcher {
+	return &Watcher{filesDir: filesDir, processFinder: processFinder}
+}
+
+// Check probes the launcher and returns a LauncherExited event if the launcher
+// that was running on the previous check has since exited.
+func (w *Watcher) Check(now time.Time) *pb.LauncherExited {
+	state, pid := State(w.filesDir, w.processFinder)
+	if state == pb.StatusReport_RUNNING {
+		w.runningPID = pid
+		return nil
+	}
+	if w.runningPID == 0 || state != pb.StatusReport_NOT_RUNNING {
+		// Never seen running, or the PID file is gone or unreadable.
+		return nil
+	}
+	exited := w.runningPID
+	w.runningPID = 0
+	return &pb.LauncherExited{
+		Pid:        int32(exited),
+		DetectedAt: timestamppb.New(now),
+		Reason:     ReadFile(w.filesDir, ExitReasonFile),
+		AppStatus:  ReadFile(w.filesDir, AppStatusFile),
+	}
+}
+
+// ReadFile returns the trimmed contents of a file in the launcher directory,
+// or "" if it can't be read.
+func ReadFile(filesDir, name string) string {
+	data, err := os.ReadFile(filepath.Join(Dir(filesDir), name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,73 @@
+package launcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProcess implements ProcessSignaler for testing
+type mockProcess struct {
+	signalCalls []syscall.Signal
+	signalErr   error
+}
+
+func (m *mockProcess) Signal(sig syscall.Signal) error {
+	if m.signalErr != nil {
+		return m.signalErr
+	}
+	if sig == 0 {
+		return nil // Liveness probes aren't recorded
+	}
+	m.signalCalls = append(m.signalCalls, sig)
+	return nil
+}
+
+// mockProcessFinder implements ProcessFinder for testing
+type mockProcessFinder struct {
+	processes map[int]*mockProcess
+}
+
+func (m *mockProcessFinder) FindProcess(pid int) (ProcessSignaler, error) {
+	proc, ok := m.processes[pid]
+	if !ok {
+		proc = &mockProcess{}
+		m.processes[pid] = proc
+	}
+	return proc, nil
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	launcherDir := Dir(dir)
+	require.NoError(t, os.MkdirAll(launcherDir, 0755))
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	watcher := NewWatcher(dir, finder)
+	now := time.Now()
+
+	// No PID file yet: the launcher hasn't started, which isn't an exit.
+	assert.Nil(t, watcher.Check(now))
+
+	require.NoError(t, os.WriteFile(filepath.Join(launcherDir, "launcher.pid"), []byte("4242"), 0644))
+	assert.Nil(t, watcher.Check(now))
+	assert.Equal(t, 4242, watcher.runningPID)
+
+	require.NoError(t, os.WriteFile(filepath.Join(launcherDir, ExitReasonFile), []byte("Launcher exited with status 137\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(launcherDir, AppStatusFile), []byte("Application command ('python app.py') exited with status 1\n"), 0644))
+	finder.processes[4242] = &mockProcess{signalErr: errors.New("os: process already finished")}
+
+	event := watcher.Check(now)
+	require.NotNil(t, event)
+	assert.Equal(t, int32(4242), event.Pid)
+	assert.Equal(t, "Launcher exited with status 137", event.Reason)
+	assert.Equal(t, "Application command ('python app.py') exited with status 1", event.AppStatus)
+	assert.Equal(t, now.Unix(), event.DetectedAt.AsTime().Unix())
+
+	assert.Nil(t, watcher.Check(now), "an exit is reported once")
+}
@@ -0,0 +1,33 @@
+// Package metrics records the sidecar's metrics through a Sink, so they can be
+// sent wherever a deployment collects them. StatsD sends them to a StatsD
+// server or, with tags, to the Datadog agent's DogStatsD.
+package metrics
+
+import "time"
+
+// Sink receives metrics. Names are relative to the sink's prefix, and tags are
+// "key:value" strings added to the sink's own. Implementations must be safe
+// for concurrent use and must not block the caller on the network.
+type Sink interface {
+	// Count adds value to a counter.
+	Count(name string, value int64, tags ...string)
+	// Gauge sets a gauge to value.
+	Gauge(name string, value float64, tags ...string)
+	// Timing records how long something took.
+	Timing(name string, value time.Duration, tags ...string)
+	// Close sends what is still buffered and releases the sink.
+	Close() error
+}
+
+// Nop is a Sink that discards every metric, for when none is configured.
+type Nop struct{}
+
+func (Nop) Count(string, int64, ...string)          {}
+func (Nop) Gauge(string, float64, ...string)        {}
+func (Nop) Timing(string, time.Duration, ...string) {}
+func (Nop) Close() error                            { return nil }
+
+// Tag formats a "key:value" tag.
+func Tag(key, value string) string {
+	return key + ":" + value
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+const (
+	// DefaultPort is the port StatsD and DogStatsD listen on.
+	DefaultPort = "8125"
+
+	// maxPacketSize keeps each UDP packet within a typical 1500 byte MTU, which
+	// is what the Datadog agent recommends.
+	maxPacketSize = 1432
+	// flushInterval is how long a metric waits for others to share its packet.
+	flushInterval = time.Second
+)
+
+// StatsDOptions configures a StatsD sink.
+type StatsDOptions struct {
+	// Address is the server's host:port; empty uses DefaultAddress.
+	Address string
+	// Prefix is prepended to every metric name, e.g. "bifrost.sidecar.".
+	Prefix string
+	// Tags are added to every metric. Plain StatsD has no tags, so they are
+	// only sent with DogStatsD.
+	Tags      []string
+	DogStatsD bool
+}
+
+// StatsD is a Sink that sends metrics over UDP in the StatsD line protocol, or
+// DogStatsD's extension of it with tags. Metrics are batched into packets
+// that are sent once full or every flushInterval; a server that isn't
+// listening loses them without slowing the sidecar down.
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	tags      []string
+	dogStatsD bool
+
+	mu  sync.Mutex
+	buf []byte
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// DefaultAddress returns the address of the local Datadog agent as its
+// admission controller injects it, DD_AGENT_HOST and DD_DOGSTATSD_PORT, or
+// else 127.0.0.1:8125.
+func DefaultAddress() string {
+	host, port := os.Getenv("DD_AGENT_HOST"), os.Getenv("DD_DOGSTATSD_PORT")
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == "" {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// NewStatsD creates a StatsD sink sending to opts.Address.
+func NewStatsD(opts StatsDOptions) (*StatsD, error) {
+	address := opts.Address
+	if address == "" {
+		address = DefaultAddress()
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open statsd socket to %s: %w", address, err)
+	}
+	s := &StatsD{
+		conn:      conn,
+		prefix:    opts.Prefix,
+		dogStatsD: opts.DogStatsD,
+		buf:       make([]byte, 0, maxPacketSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, tag := range opts.Tags {
+		s.tags = append(s.tags, sanitizeTag(tag))
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+func (s *StatsD) Count(name string, value int64, tags ...string) {
+	s.send(name, strconv.AppendInt(nil, value, 10), "c", tags)
+}
+
+func (s *StatsD) Gauge(name string, value float64, tags ...string) {
+	s.send(name, strconv.AppendFloat(nil, value, 'f', -1, 64), "g", tags)
+}
+
+func (s *StatsD) Timing(name string, value time.Duration, tags ...string) {
+	ms := float64(value) / float64(time.Millisecond)
+	s.send(name, strconv.AppendFloat(nil, ms, 'f', -1, 64), "ms", tags)
+}
+
+// Close sends the buffered metrics and closes the socket.
+func (s *StatsD) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.mu.Lock()
+		s.flushLocked()
+		s.mu.Unlock()
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// send buffers one metric's line, flushing the buffer first if the line
+// doesn't fit in its packet.
+func (s *StatsD) send(name string, value []byte, kind string, tags []string) {
+	line := appendName(nil, s.prefix+name)
+	line = append(line, ':')
+	line = append(line, value...)
+	line = append(line, '|')
+	line = append(line, kind...)
+	if s.dogStatsD && len(s.tags)+len(tags) > 0 {
+		line = append(line, "|#"...)
+		for i, tag := range s.tags {
+			if i > 0 {
+				line = append(line, ',')
+			}
+			line = append(line, tag...)
+		}
+		for i, tag := range tags {
+			if i > 0 || len(s.tags) > 0 {
+				line = append(line, ',')
+			}
+			line = append(line, sanitizeTag(tag)...)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > maxPacketSize {
+		s.flushLocked()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+}
+
+func (s *StatsD) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *StatsD) flushLocked() {
+	if len(s.buf) == 0 {
+		return
+	}
+	if _, err := s.conn.Write(s.buf); err != nil {
+		// Most likely nothing is listening yet; the next packet tries again.
+		log.Debug("Failed to send metrics", zap.Int("bytes", len(s.buf)), zap.Error(err))
+	}
+	s.buf = s.buf[:0]
+}
+
+// appendName appends name with the characters StatsD servers don't accept
+// in metric names replaced by underscores.
+func appendName(dst []byte, name string) []byte {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
+
+// sanitizeTag replaces the characters that delimit DogStatsD fields and tags.
+func sanitizeTag(tag string) string {
+	b := []byte(tag)
+	for i, c := range b {
+		switch c {
+		case '|', ',', '#', '\n', '\r':
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
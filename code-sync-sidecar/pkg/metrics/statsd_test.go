@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen returns a UDP socket standing in for a StatsD server.
+func listen(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readPacket returns the next packet the server received.
+func readPacket(t *testing.T, conn *net.UDPConn) string {
+	t.Helper()
+	buf := make([]byte, 2*maxPacketSize)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestStatsD_DogStatsD(t *testing.T) {
+	server := listen(t)
+	s, err := NewStatsD(StatsDOptions{
+		Address:   server.LocalAddr().String(),
+		Prefix:    "bifrost.sidecar.",
+		Tags:      []string{"app_id:app1", "env:a|b"},
+		DogStatsD: true,
+	})
+	require.NoError(t, err)
+
+	s.Count("push.count", 1, Tag("status", "completed"))
+	s.Gauge("connection.rtt_ms", 12.5)
+	s.Timing("push duration", 1500*time.Millisecond, "stage:rsync,build")
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Close(), "closing twice is harmless")
+
+	assert.Equal(t, strings.Join([]string{
+		"bifrost.sidecar.push.count:1|c|#app_id:app1,env:a_b,status:completed",
+		"bifrost.sidecar.connection.rtt_ms:12.5|g|#app_id:app1,env:a_b",
+		"bifrost.sidecar.push_duration:1500|ms|#app_id:app1,env:a_b,stage:rsync_build",
+	}, "\n"), readPacket(t, server))
+}
+
+func TestStatsD_Plain(t *testing.T) {
+	server := listen(t)
+	s, err := NewStatsD(StatsDOptions{Address: server.LocalAddr().String(), Tags: []string{"app_id:app1"}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.Count("push.count", 2, "status:failed")
+	assert.Equal(t, "push.count:2|c", readPacket(t, server), "metrics are flushed without waiting for Close")
+}
+
+func TestStatsD_Packets(t *testing.T) {
+	server := listen(t)
+	s, err := NewStatsD(StatsDOptions{Address: server.LocalAddr().String()})
+	require.NoError(t, err)
+
+	for range 200 {
+		s.Count("messages.sent", 1)
+	}
+	require.NoError(t, s.Close())
+
+	var lines int
+	for lines < 200 {
+		packet := readPacket(t, server)
+		assert.LessOrEqual(t, len(packet), maxPacketSize)
+		lines += strings.Count(packet, "\n") + 1
+	}
+	assert.Equal(t, 200, lines)
+}
+
+func TestDefaultAddress(t *testing.T) {
+	t.Setenv("DD_AGENT_HOST", "")
+	t.Setenv("DD_DOGSTATSD_PORT", "")
+	assert.Equal(t, "127.0.0.1:8125", DefaultAddress())
+
+	t.Setenv("DD_AGENT_HOST", "10.0.0.7")
+	t.Setenv("DD_DOGSTATSD_PORT", "18125")
+	assert.Equal(t, "10.0.0.7:18125", DefaultAddress())
+}
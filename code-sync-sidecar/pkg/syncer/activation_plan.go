@@ -0,0 +1,113 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// stageUnhealthyError is returned by reloadInStages when the health probe
+// didn't pass after a stage of the activation plan was reloaded.
+type stageUnhealthyError struct {
+	stage    int      // From 1
+	reloaded []string // Every launcher signalled so far, the failed stage's included
+	output   string   // The last probe's output
+	err      error
+}
+
+func (e *stageUnhealthyError) Error() string {
+	return fmt.Sprintf("activation stage %d is not healthy: %v", e.stage, e.err)
+}
+
+func (e *stageUnhealthyError) Unwrap() error { return e.err }
+
+// validateActivationPlan checks that a push's activation plan can be carried
+// out with the reload settings, before anything is changed.
+func validateActivationPlan(plan []*pb.ActivationStage, reloader Reloader, health *HealthProber) error {
+	if len(plan) == 0 {
+		return nil
+	}
+	if _, ok := reloader.(*signalReloader); !ok {
+		return fmt.Errorf("an activation plan needs reload.strategy %q, not %q", ReloadStrategySignal, reloader.Strategy())
+	}
+	if health == nil {
+		return errors.New("an activation plan needs a health probe (health.url or health.tcp_address) to confirm each stage")
+	}
+	seen := make(map[string]bool)
+	for i, stage := range plan {
+		if len(stage.GetLaunchers()) == 0 {
+			return fmt.Errorf("activation stage %d names no launchers", i+1)
+		}
+		for _, name := range stage.Launchers {
+			if !launcherNamePattern.MatchString(name) {
+				return fmt.Errorf("activation stage %d: launcher name %q may only contain letters, digits, - and _", i+1, name)
+			}
+			if seen[name] {
+				return fmt.Errorf("launcher %s is in more than one activation stage", name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// reloadInStages signals the launchers of each stage of plan in turn, waiting
+// for the health probe to pass before going on to the next, and then every
+// launcher no stage named. The probe after that last reload is left to the
+// caller. A stage that isn't healthy stops the activation with a
+// *stageUnhealthyError.
+func (rw *FileSyncer) reloadInStages(ctx context.Context, retry retryPolicy, signaller *signalReloader, plan []*pb.ActivationStage, health *HealthProber, timer *pushTimer) error {
+	ctx = context.WithoutCancel(ctx)
+	var launchers []*pb.LauncherSignalResult
+	defer func() { timer.launcherResults(launchers) }()
+	signal := func(names []string) error {
+		attempts, err := retry.run(ctx, retryStepSignal, isTransientReloadError, func() error {
+			return signaller.signalLaunchers(&launchers, names)
+		})
+		timer.attempts(retryStepSignal, attempts)
+		return err
+	}
+
+	var reloaded []string
+	for i, stage := range plan {
+		if err := signal(stage.Launchers); err != nil {
+			return err
+		}
+		reloaded = append(reloaded, stage.Launchers...)
+		log.Info("Reloaded activation stage, waiting for it to be healthy", zap.Int("stage", i+1), zap.Strings("launchers", stage.Launchers))
+		if output, err := health.Wait(ctx); err != nil {
+			return &stageUnhealthyError{stage: i + 1, reloaded: reloaded, output: output, err: err}
+		}
+	}
+	return signal(nil)
+}
+
+// rollBackStages puts back the files a push replaced after a stage of its
+// activation plan wasn't healthy, and reloads the launchers that had been
+// signalled so they run the previous files again.
+func (rw *FileSyncer) rollBackStages(pushID string, backup *syncBackup, signaller *signalReloader, unhealthy *stageUnhealthyError) error {
+	if backup.release != "" {
+		if _, err := rollbackRelease(rw.targetSyncDir); err != nil {
+			return fmt.Errorf("failed to roll back release: %w", err)
+		}
+	} else if err := backup.restore(); err != nil {
+		rw.requestResync(pb.ResyncRequest_CORRUPTION, fmt.Sprintf("failed to roll back push %s: %v", pushID, err))
+		return fmt.Errorf("failed to restore the previous files: %w", err)
+	}
+	if _, err := rw.writeReloadHandshake(pushID, launcher.ReloadReasonStageRollback, nil, ""); err != nil {
+		return err
+	}
+	var results []*pb.LauncherSignalResult
+	if err := signaller.signalLaunchers(&results, unhealthy.reloaded); err != nil {
+		return fmt.Errorf("previous files restored but launchers %s could not be reloaded: %w", strings.Join(unhealthy.reloaded, ", "), err)
+	}
+	log.Info("Rolled back staged activation", zap.String("pushID", pushID), zap.Strings("launchers", unhealthy.reloaded))
+	return nil
+}
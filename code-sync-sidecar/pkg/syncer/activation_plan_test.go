@@ -0,0 +1,137 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestValidateActivationPlan(t *testing.T) {
+	signal := &signalReloader{}
+	health := NewHealthProber(HealthConfig{URL: "http://localhost:8080/healthz"})
+	stages := func(launchers ...[]string) []*pb.ActivationStage {
+		var plan []*pb.ActivationStage
+		for _, names := range launchers {
+			plan = append(plan, &pb.ActivationStage{Launchers: names})
+		}
+		return plan
+	}
+
+	assert.NoError(t, validateActivationPlan(nil, &httpReloader{}, nil), "no plan needs nothing")
+	assert.NoError(t, validateActivationPlan(stages([]string{"worker"}, []string{"launcher"}), signal, health))
+	assert.ErrorContains(t, validateActivationPlan(stages([]string{"worker"}), &httpReloader{}, health), `needs reload.strategy "signal", not "http"`)
+	assert.ErrorContains(t, validateActivationPlan(stages([]string{"worker"}), signal, nil), "needs a health probe")
+	assert.ErrorContains(t, validateActivationPlan(stages([]string{"worker"}, nil), signal, health), "activation stage 2 names no launchers")
+	assert.ErrorContains(t, validateActivationPlan(stages([]string{"../worker"}), signal, health), `launcher name "../worker" may only contain`)
+	assert.ErrorContains(t, validateActivationPlan(stages([]string{"worker"}, []string{"worker"}), signal, health), "launcher worker is in more than one activation stage")
+}
+
+func TestHandlePushRequest_ActivationPlan(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(rw.targetSyncDir), "worker.pid"), []byte("200\n"), 0644))
+	finder := rw.processFinder.(*mockProcessFinder)
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	rw.health = NewHealthProber(HealthConfig{URL: server.URL, Timeout: Duration(200 * time.Millisecond), Interval: Duration(10 * time.Millisecond)})
+	plan := []*pb.ActivationStage{{Launchers: []string{"worker"}}}
+
+	// The worker is reloaded first, then the launcher no stage names.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch"), ActivationPlan: plan}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	require.Len(t, resp.LauncherResults, 2)
+	assert.Equal(t, "worker", resp.LauncherResults[0].Name)
+	assert.Equal(t, launcher.MainLauncher, resp.LauncherResults[1].Name)
+	assert.Len(t, finder.processes[200].signalCalls, 1)
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+
+	// A stage that isn't healthy is reloaded again into the previous files,
+	// and the rest are never reloaded.
+	healthy.Store(false)
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2"), ActivationPlan: plan})
+	assert.ErrorContains(t, err, "activation stage 1 is not healthy")
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_ROLLED_BACK, resp.Status)
+	assert.Equal(t, int32(1), resp.FailedActivationStage)
+	assert.Contains(t, resp.ErrorMessage, "Activation stage 1 is not healthy, rolled back to the previous files")
+	assert.Equal(t, []syscall.Signal{syscall.SIGHUP, syscall.SIGHUP, syscall.SIGHUP}, finder.processes[200].signalCalls)
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+
+	var hs launcher.Handshake
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, launcher.HandshakePath(rw.targetSyncDir))), &hs))
+	assert.Equal(t, launcher.ReloadReasonStageRollback, hs.Reason)
+	assert.Equal(t, "push-2", hs.PushID)
+}
+
+// blockingEnvProvider holds a push in its required env check until released.
+type blockingEnvProvider struct {
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (p *blockingEnvProvider) Name() string { return "blocking" }
+
+func (p *blockingEnvProvider) DatabaseEnvVars(ctx context.Context) ([]envfile.DatabaseEnvVar, error) {
+	close(p.fetching)
+	<-p.release
+	return []envfile.DatabaseEnvVar{{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/app"}}, nil
+}
+
+func TestHandlePushRequest_ActivationPlanSurvivesConfigReload(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(rw.targetSyncDir), "worker.pid"), []byte("200\n"), 0644))
+	finder := rw.processFinder.(*mockProcessFinder)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	rw.health = NewHealthProber(HealthConfig{URL: server.URL, Timeout: Duration(200 * time.Millisecond), Interval: Duration(10 * time.Millisecond)})
+	provider := &blockingEnvProvider{fetching: make(chan struct{}), release: make(chan struct{})}
+	rw.databaseEnv = provider
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- rw.handlePushRequest(context.Background(), &pb.PushMessage{
+			PushId:         "push-1",
+			BatchFile:      []byte("batch"),
+			RequiredEnv:    []string{"DATABASE_URL"},
+			ActivationPlan: []*pb.ActivationStage{{Launchers: []string{"worker"}}},
+		})
+	}()
+
+	// A reload that switches the strategy and drops the health probe after the
+	// plan was checked doesn't affect the push.
+	<-provider.fetching
+	cfg := DefaultConfig()
+	cfg.Reload.Strategy = ReloadStrategyHTTP
+	cfg.Reload.URL = server.URL + "/reload"
+	rw.ApplyConfig(cfg)
+	require.Equal(t, ReloadStrategyHTTP, rw.getReloader().Strategy())
+	close(provider.release)
+
+	require.NoError(t, <-errs)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	require.Len(t, resp.LauncherResults, 2)
+	assert.Equal(t, "worker", resp.LauncherResults[0].Name)
+	assert.Len(t, finder.processes[200].signalCalls, 1)
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+}
@@ -0,0 +1,41 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+// ApplyPush applies pushMsg without a connection to the control plane, going
+// through the same steps as a PUSH_REQUEST: conflict detection, hooks, backup
+// and rollback, reloading the launcher, and recording the push as applied.
+// It returns the push's final response. It's meant for incident recovery, and
+// doesn't hold off pushes a running sidecar applies meanwhile. The operator
+// running it approves the push, so it doesn't wait for an ACTIVATE.
+func ApplyPush(ctx context.Context, cfg *Config, tokens *transport.TokenManager, pushMsg *pb.PushMessage) (*pb.PushResponse, error) {
+	rw := newFileSyncer(cfg, tokens)
+	rw.requireApproval = false
+	return applyBatch(ctx, rw, pushMsg)
+}
+
+// applyBatch applies pushMsg on a syncer that isn't connected and returns the
+// push's final response.
+func applyBatch(ctx context.Context, rw *FileSyncer, pushMsg *pb.PushMessage) (*pb.PushResponse, error) {
+	var final *pb.PushResponse
+	rw.sendOffline = func(msg proto.Message) {
+		if wsMsg, ok := msg.(*pb.WebsocketMessage); ok {
+			if resp := wsMsg.GetPushResponse(); resp != nil && !isIntermediatePushStatus(resp.Status) {
+				final = resp
+			}
+		}
+	}
+	err := rw.handlePushRequest(ctx, pushMsg)
+	if err == nil && final == nil {
+		err = errors.New("the push finished without a response")
+	}
+	return final, err
+}
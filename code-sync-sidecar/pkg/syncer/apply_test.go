@@ -0,0 +1,28 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestApplyBatch(t *testing.T) {
+	rw, _ := newRsyncOptionsTestSyncer(t)
+	rw.conn = nil
+
+	resp, err := applyBatch(context.Background(), rw, &pb.PushMessage{PushId: "manual-1", BatchFile: []byte("batch")})
+	require.NoError(t, err)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, "manual-1", resp.GetPushId())
+	assert.True(t, rw.hasApplied("manual-1"))
+
+	resp, err = applyBatch(context.Background(), rw, &pb.PushMessage{
+		PushId: "manual-2", BatchFile: []byte("batch"), RsyncFlags: []string{"--backup-dir=/etc"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+}
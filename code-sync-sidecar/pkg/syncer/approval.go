@@ -0,0 +1,72 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// awaitApproval reports AWAITING_APPROVAL for the running push, whose release
+// is staged, and waits for an ACTIVATE approving it. It returns who approved
+// the push, or an error if the approval timeout passes or the sidecar stops
+// first; a cancelled push leaves ctx done.
+func (rw *FileSyncer) awaitApproval(ctx context.Context, pushID string) (string, error) {
+	q := &rw.pushes
+	approve := make(chan string, 1)
+	q.mu.Lock()
+	q.approve = approve
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.approve = nil
+		q.mu.Unlock()
+	}()
+
+	timeout := rw.getApprovalTimeout()
+	log.Info("Push staged, waiting for approval", zap.String("pushID", pushID), zap.Duration("timeout", timeout))
+	rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_AWAITING_APPROVAL, ""))
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case approvedBy := <-approve:
+		rw.audit.approved(pushID, approvedBy)
+		return approvedBy, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-expired:
+		return "", fmt.Errorf("not approved within %v", timeout)
+	case <-rw.done:
+		return "", errors.New("the sidecar stopped before the push was approved")
+	}
+}
+
+// activatePush approves the running push if it is waiting for approval.
+func (rw *FileSyncer) activatePush(req *pb.ActivateRequest) error {
+	if req == nil || req.PushId == "" {
+		return fmt.Errorf("received ACTIVATE without a push id")
+	}
+	q := &rw.pushes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.activeID != req.PushId || q.approve == nil {
+		return fmt.Errorf("push %s is not awaiting approval", req.PushId)
+	}
+	select {
+	case q.approve <- req.ApprovedBy:
+		log.Info("Approving staged push", zap.String("pushID", req.PushId), zap.String("approvedBy", req.ApprovedBy))
+	default:
+		// Already approved; a repeated ACTIVATE changes nothing.
+	}
+	return nil
+}
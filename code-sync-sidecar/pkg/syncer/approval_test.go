@@ -0,0 +1,92 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestPushQueue_WaitsForApproval(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir:   dir,
+		applyMode:       ApplyModeSwap,
+		requireApproval: true,
+		processFinder:   &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:            conn,
+		done:            make(chan struct{}),
+	}
+	defer close(rw.done)
+
+	awaitingApproval := func(pushID string) {
+		t.Helper()
+		for {
+			select {
+			case message := <-mockServer.messages:
+				var wsMessage pb.WebsocketMessage
+				require.NoError(t, proto.Unmarshal(message, &wsMessage))
+				if resp := wsMessage.GetPushResponse(); resp.GetStatus() == pb.PushResponse_AWAITING_APPROVAL {
+					require.Equal(t, pushID, resp.PushId)
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for AWAITING_APPROVAL")
+			}
+		}
+	}
+
+	// The push is staged but not activated until it is approved.
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}, 0))
+	awaitingApproval("push-1")
+	current, err := currentRelease(dir)
+	require.NoError(t, err)
+	assert.Empty(t, current, "nothing is activated before approval")
+	assert.ErrorContains(t, rw.activatePush(&pb.ActivateRequest{PushId: "push-9"}), "not awaiting approval")
+
+	require.NoError(t, rw.activatePush(&pb.ActivateRequest{PushId: "push-1", ApprovedBy: "reviewer@example.com"}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Equal(t, "reviewer@example.com", resp.ApprovedBy)
+	first, err := currentRelease(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, first)
+	audit := rw.buildSyncStatus(&pb.SyncStatusRequest{RequestId: "req-1", AuditEntries: 1}).AuditLog
+	require.Len(t, audit, 1)
+	assert.Equal(t, "reviewer@example.com", audit[0].ApprovedBy)
+
+	// Cancelling a push waiting for approval discards its staged release.
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}, 0))
+	awaitingApproval("push-2")
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-2"}))
+	assert.Equal(t, pb.PushResponse_CANCELLED, waitForPushResponse(t, mockServer).Status)
+
+	// So does a push that isn't approved in time.
+	rw.approvalTimeout = 50 * time.Millisecond
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-3", BatchFile: []byte("batch-3")}, 0))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "Push staged but not activated: not approved within 50ms")
+
+	current, err = currentRelease(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first, current)
+	releases, err := listReleases(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, releases)
+}
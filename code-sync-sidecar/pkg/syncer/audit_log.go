@@ -0,0 +1,293 @@
+package syncer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	auditDirName  = "audit"
+	auditFileName = "pushes.jsonl"
+	// maxAuditFileSize is the size at which the audit log is rotated.
+	maxAuditFileSize = 1 << 20
+	// maxAuditRotations is how many rotated audit logs are kept beside the current one.
+	maxAuditRotations = 4
+	// maxPendingAudits bounds the pushes whose details are kept until their
+	// final response, in case a push never gets one.
+	maxPendingAudits = 64
+	// maxAuditErrorLength bounds the error message kept in a record, which can
+	// include rsync's output.
+	maxAuditErrorLength = 4096
+)
+
+// auditRecord is one line of the audit log. Env var values are never recorded.
+type auditRecord struct {
+	PushID         string    `json:"push_id"`
+	Time           time.Time `json:"time"`
+	FilesChanged   int       `json:"files_changed"`
+	EnvKeysChanged []string  `json:"env_keys_changed,omitempty"`
+	Outcome        string    `json:"outcome"`
+	Error          string    `json:"error,omitempty"`
+	TriggeredBy    string    `json:"triggered_by,omitempty"`
+	ApprovedBy     string    `json:"approved_by,omitempty"`
+}
+
+// auditLog appends a record of every push to .sidecar/audit/pushes.jsonl once
+// its final response is sent. What the response doesn't carry, such as who
+// triggered the push, is noted while the push is applied.
+type auditLog struct {
+	mu      sync.Mutex
+	pending map[string]*auditPending
+	// envHashes holds a hash of each database env var's value as last written,
+	// to tell which ones a push changed.
+	envHashes map[string][32]byte
+}
+
+type auditPending struct {
+	started        time.Time
+	triggeredBy    string
+	filesChanged   int
+	envKeysChanged []string
+	approvedBy     string
+}
+
+// note returns the pending details of pushID, adding them if needed. The caller
+// holds a.mu.
+func (a *auditLog) note(pushID string) *auditPending {
+	if a.pending == nil {
+		a.pending = make(map[string]*auditPending)
+	}
+	if p, ok := a.pending[pushID]; ok {
+		return p
+	}
+	if len(a.pending) >= maxPendingAudits {
+		// Drop the push that has waited longest for a final response.
+		var oldest string
+		for id, p := range a.pending {
+			if oldest == "" || p.started.Before(a.pending[oldest].started) {
+				oldest = id
+			}
+		}
+		delete(a.pending, oldest)
+	}
+	p := &auditPending{started: time.Now()}
+	a.pending[pushID] = p
+	return p
+}
+
+// begin notes who triggered pushMsg.
+func (a *auditLog) begin(pushMsg *pb.PushMessage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.note(pushMsg.PushId).triggeredBy = pushMsg.TriggeredBy
+}
+
+// filesChanged notes how many files the push's batch changed.
+func (a *auditLog) filesChanged(pushID string, n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.note(pushID).filesChanged = n
+}
+
+// approved notes who approved a push that waited for approval.
+func (a *auditLog) approved(pushID, by string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.note(pushID).approvedBy = by
+}
+
+// addApprovedBy sets who approved the push on its final response.
+func (a *auditLog) addApprovedBy(resp *pb.PushResponse) {
+	if resp == nil || resp.ApprovedBy != "" || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p, ok := a.pending[resp.PushId]; ok {
+		resp.ApprovedBy = p.approvedBy
+	}
+}
+
+// envWritten notes which of envVars, just written to the env files, the push
+// changed: those added, removed or with a new value since the last write. After
+// a restart, every variable of the first write counts as changed. Scoped
+// variables are named scope/NAME.
+func (a *auditLog) envWritten(pushID string, envVars []envfile.DatabaseEnvVar) {
+	hashes := make(map[string][32]byte, len(envVars))
+	for _, envVar := range envVars {
+		key := envVar.EnvVarName
+		if envVar.Scope != "" {
+			key = envVar.Scope + "/" + key
+		}
+		hashes[key] = sha256.Sum256([]byte(envVar.ConnectionURI))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var changed []string
+	for key, hash := range hashes {
+		if previous, ok := a.envHashes[key]; !ok || previous != hash {
+			changed = append(changed, key)
+		}
+	}
+	for key := range a.envHashes {
+		if _, ok := hashes[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	slices.Sort(changed)
+	a.envHashes = hashes
+	p := a.note(pushID)
+	p.envKeysChanged = append(p.envKeysChanged, changed...)
+}
+
+// recordAudit appends the final response of a push to the audit log. Replayed
+// responses were recorded when first sent.
+func (rw *FileSyncer) recordAudit(resp *pb.PushResponse) {
+	if resp == nil || resp.PushId == "" || resp.Replayed || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	record := auditRecord{
+		PushID:     resp.PushId,
+		Time:       time.Now().UTC(),
+		Outcome:    resp.Status.String(),
+		Error:      resp.ErrorMessage,
+		ApprovedBy: resp.ApprovedBy,
+	}
+	if len(record.Error) > maxAuditErrorLength {
+		record.Error = record.Error[:maxAuditErrorLength] + "..."
+	}
+	a := &rw.audit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p, ok := a.pending[resp.PushId]; ok {
+		record.TriggeredBy = p.triggeredBy
+		record.FilesChanged = p.filesChanged
+		record.EnvKeysChanged = slices.Compact(slices.Sorted(slices.Values(p.envKeysChanged)))
+		delete(a.pending, resp.PushId)
+	}
+	if err := appendAuditRecord(rw.targetSyncDir, record); err != nil {
+		log.Warn("Failed to write audit log", zap.String("pushID", resp.PushId), zap.Error(err))
+	}
+}
+
+// auditLogPath returns the current audit log, or its nth rotation for n > 0.
+func auditLogPath(filesDir string, n int) string {
+	path := filepath.Join(launcher.SidecarDir(filesDir), auditDirName, auditFileName)
+	if n > 0 {
+		path = fmt.Sprintf("%s.%d", path, n)
+	}
+	return path
+}
+
+// appendAuditRecord writes record as a line of the audit log, rotating the log
+// first if the line would take it over maxAuditFileSize.
+func appendAuditRecord(filesDir string, record auditRecord) error {
+	if filesDir == "" {
+		return errNoFilesDir
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	path := auditLogPath(filesDir, 0)
+	if err := os.MkdirAll(filepath.Dir(path), launcher.Volume.InternalDir); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > maxAuditFileSize {
+		if err := rotateAuditLog(filesDir); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// rotateAuditLog shifts each audit log to the next rotation, dropping the oldest.
+func rotateAuditLog(filesDir string) error {
+	if err := os.Remove(auditLogPath(filesDir, maxAuditRotations)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove oldest audit log: %w", err)
+	}
+	for n := maxAuditRotations - 1; n >= 0; n-- {
+		if err := os.Rename(auditLogPath(filesDir, n), auditLogPath(filesDir, n+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// readAuditLog returns the latest n records of the audit log, oldest first.
+// Lines that can't be parsed are skipped.
+func readAuditLog(filesDir string, n int) ([]auditRecord, error) {
+	var records []auditRecord
+	for rotation := maxAuditRotations; rotation >= 0; rotation-- {
+		f, err := os.Open(auditLogPath(filesDir, rotation))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, maxAuditFileSize)
+		for scanner.Scan() {
+			var record auditRecord
+			if json.Unmarshal(scanner.Bytes(), &record) == nil {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+	if len(records) > n {
+		records = records[len(records)-n:]
+	}
+	return records, nil
+}
+
+// auditEntries converts audit records for a SYNC_STATUS_RESPONSE.
+func auditEntries(records []auditRecord) []*pb.AuditEntry {
+	entries := make([]*pb.AuditEntry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, &pb.AuditEntry{
+			PushId:         record.PushID,
+			Time:           timestamppb.New(record.Time),
+			FilesChanged:   int32(record.FilesChanged),
+			EnvKeysChanged: record.EnvKeysChanged,
+			Outcome:        pb.PushResponse_PushStatus(pb.PushResponse_PushStatus_value[record.Outcome]),
+			ErrorMessage:   record.Error,
+			TriggeredBy:    record.TriggeredBy,
+			ApprovedBy:     record.ApprovedBy,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// assertOnlyAuditLog checks that nothing but the audit log was written to .sidecar.
+func assertOnlyAuditLog(t *testing.T, filesDir string, msg string) {
+	t.Helper()
+	entries, err := os.ReadDir(launcher.SidecarDir(filesDir))
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{auditDirName}, names, msg)
+}
+
+func TestAuditLog_RecordsPushes(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f+++++++++ app.py;>f.st...... lib.py;cd+++++++++ pkg/")
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch-1"), TriggeredBy: "dev@example.com"}, 0))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	t.Setenv("HELPER_RSYNC_FAIL", "1")
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}, 0))
+	assert.Equal(t, pb.PushResponse_FAILED, waitForPushResponse(t, mockServer).GetStatus())
+
+	resp := rw.buildSyncStatus(&pb.SyncStatusRequest{RequestId: "req-1", AuditEntries: 10})
+	require.Len(t, resp.AuditLog, 2)
+	first, second := resp.AuditLog[0], resp.AuditLog[1]
+	assert.Equal(t, "push-1", first.PushId)
+	assert.Equal(t, "dev@example.com", first.TriggeredBy)
+	assert.Equal(t, int32(2), first.FilesChanged, "directories aren't counted")
+	assert.Equal(t, pb.PushResponse_COMPLETED, first.Outcome)
+	assert.WithinDuration(t, time.Now(), first.Time.AsTime(), time.Minute)
+	assert.Equal(t, "push-2", second.PushId)
+	assert.Equal(t, pb.PushResponse_FAILED, second.Outcome)
+	assert.Contains(t, second.ErrorMessage, "rsync command failed")
+	assert.Empty(t, rw.audit.pending)
+
+	assert.Empty(t, rw.buildSyncStatus(&pb.SyncStatusRequest{RequestId: "req-2"}).AuditLog)
+}
+
+func TestAuditLog_EnvWritten(t *testing.T) {
+	var a auditLog
+	a.envWritten("push-1", []envfile.DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/main"},
+		{EnvVarName: "QUEUE_URL", ConnectionURI: "redis://queue", Scope: "worker"},
+	})
+	assert.Equal(t, []string{"DATABASE_URL", "worker/QUEUE_URL"}, a.pending["push-1"].envKeysChanged)
+
+	a.envWritten("push-2", []envfile.DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/branch"},
+		{EnvVarName: "CACHE_URL", ConnectionURI: "redis://cache"},
+	})
+	assert.Equal(t, []string{"CACHE_URL", "DATABASE_URL", "worker/QUEUE_URL"}, a.pending["push-2"].envKeysChanged,
+		"added, changed and removed variables all count")
+
+	a.envWritten("push-3", []envfile.DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/branch"},
+		{EnvVarName: "CACHE_URL", ConnectionURI: "redis://cache"},
+	})
+	assert.Empty(t, a.pending["push-3"].envKeysChanged)
+}
+
+func TestAppendAuditRecord_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	padding := strings.Repeat("x", 100*1024)
+	for i := range 60 {
+		require.NoError(t, appendAuditRecord(dir, auditRecord{PushID: fmt.Sprintf("push-%d", i), Outcome: "FAILED", Error: padding}))
+	}
+	for n := 1; n <= maxAuditRotations; n++ {
+		assert.FileExists(t, auditLogPath(dir, n))
+	}
+	assert.NoFileExists(t, auditLogPath(dir, maxAuditRotations+1))
+
+	records, err := readAuditLog(dir, 1000)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Less(t, len(records), 60, "the oldest records were rotated away")
+	for i, record := range records {
+		assert.Equal(t, fmt.Sprintf("push-%d", 60-len(records)+i), record.PushID)
+	}
+
+	records, err = readAuditLog(dir, 2)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "push-59", records[1].PushID)
+}
@@ -0,0 +1,304 @@
+package syncer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// DefaultBatchCacheSize is how many bytes of received batches are kept in
+// .sidecar/cache by default.
+const DefaultBatchCacheSize = 256 << 20
+
+// batchCacheFileSuffix is the extension of a cached batch, named after the
+// hex SHA-256 of its content.
+const batchCacheFileSuffix = ".batch"
+
+// batchCacheDir returns where received batches are kept.
+func batchCacheDir(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), "cache")
+}
+
+// batchCacheFile returns the file a batch with hash, "sha256:<hex>", is cached
+// in, or an error if hash isn't one.
+func batchCacheFile(filesDir, hash string) (string, error) {
+	digest, ok := strings.CutPrefix(hash, "sha256:")
+	if !ok || len(digest) != hex.EncodedLen(32) || strings.ToLower(digest) != digest {
+		return "", fmt.Errorf("invalid batch hash %q: want sha256:<lowercase hex>", hash)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", fmt.Errorf("invalid batch hash %q: %w", hash, err)
+	}
+	return filepath.Join(batchCacheDir(filesDir), digest+batchCacheFileSuffix), nil
+}
+
+// cacheBatch keeps data in the batch cache, then evicts the least recently used
+// batches until the cache fits in maxBytes. A batch bigger than maxBytes isn't kept.
+func (rw *FileSyncer) cacheBatch(data []byte, maxBytes int64) error {
+	return rw.storeCachedBatch(batchHash(data), int64(len(data)), maxBytes, func(tmpPath string) error {
+		return os.WriteFile(tmpPath, data, 0600)
+	})
+}
+
+// cacheSpooledBatch keeps a spooled batch in the batch cache like cacheBatch,
+// linking the spool file rather than reading it.
+func (rw *FileSyncer) cacheSpooledBatch(batch *pushBatch, maxBytes int64) error {
+	return rw.storeCachedBatch(batch.hash, batch.size, maxBytes, func(tmpPath string) error {
+		return linkOrCopyFile(batch.path, tmpPath)
+	})
+}
+
+// storeCachedBatch caches the batch with hash and size, which write puts in
+// the file it's given.
+func (rw *FileSyncer) storeCachedBatch(hash string, size, maxBytes int64, write func(tmpPath string) error) error {
+	if maxBytes <= 0 || size > maxBytes {
+		return nil
+	}
+	rw.batchCacheMu.Lock()
+	defer rw.batchCacheMu.Unlock()
+
+	path, err := batchCacheFile(rw.targetSyncDir, hash)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return nil // Already cached; now the most recently used
+	}
+	if err := os.MkdirAll(filepath.Dir(path), launcher.Volume.InternalDir); err != nil {
+		return fmt.Errorf("failed to create batch cache directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := write(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cached batch: %w", err)
+	}
+	// A linked spool file is touched too, so it isn't the first evicted.
+	os.Chtimes(tmpPath, now, now)
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cached batch: %w", err)
+	}
+	return rw.evictCachedBatchesLocked(maxBytes)
+}
+
+// cachedBatch returns the cached batch with hash and marks it as the most
+// recently used, or reports that it isn't cached.
+func (rw *FileSyncer) cachedBatch(hash string) ([]byte, bool, error) {
+	path, err := batchCacheFile(rw.targetSyncDir, hash)
+	if err != nil {
+		return nil, false, err
+	}
+	rw.batchCacheMu.Lock()
+	defer rw.batchCacheMu.Unlock()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached batch: %w", err)
+	}
+	if batchHash(data) != hash {
+		// Corrupted on disk; the control plane has to send it again.
+		log.Warn("Dropping corrupted cached batch", zap.String("hash", hash))
+		os.Remove(path)
+		return nil, false, nil
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true, nil
+}
+
+// spoolCachedBatch copies the cached batch with hash to the spool, for a batch
+// too big to read into memory, like cachedBatch.
+func (rw *FileSyncer) spoolCachedBatch(hash string) (*pushBatch, bool, error) {
+	path, err := batchCacheFile(rw.targetSyncDir, hash)
+	if err != nil {
+		return nil, false, err
+	}
+	rw.batchCacheMu.Lock()
+	defer rw.batchCacheMu.Unlock()
+	batch, err := rw.spoolFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to spool cached batch: %w", err)
+	}
+	if batch.hash != hash {
+		log.Warn("Dropping corrupted cached batch", zap.String("hash", hash))
+		os.Remove(batch.path)
+		os.Remove(path)
+		return nil, false, nil
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return batch, true, nil
+}
+
+// cachedBatchEntry is a batch in the cache.
+type cachedBatchEntry struct {
+	path    string
+	hash    string
+	size    int64
+	modTime time.Time
+}
+
+// listCachedBatchesLocked returns the cached batches, the most recently used
+// first.
+func (rw *FileSyncer) listCachedBatchesLocked() ([]cachedBatchEntry, error) {
+	dir := batchCacheDir(rw.targetSyncDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch cache: %w", err)
+	}
+	var batches []cachedBatchEntry
+	for _, entry := range entries {
+		digest, ok := strings.CutSuffix(entry.Name(), batchCacheFileSuffix)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Evicted meanwhile
+		}
+		batches = append(batches, cachedBatchEntry{
+			path:    filepath.Join(dir, entry.Name()),
+			hash:    "sha256:" + digest,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].modTime.After(batches[j].modTime) })
+	return batches, nil
+}
+
+// evictCachedBatchesLocked removes the least recently used batches until the
+// cache holds at most maxBytes.
+func (rw *FileSyncer) evictCachedBatchesLocked(maxBytes int64) error {
+	batches, err := rw.listCachedBatchesLocked()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, batch := range batches {
+		total += batch.size
+		if total <= maxBytes {
+			continue
+		}
+		if err := os.Remove(batch.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to evict cached batch: %w", err)
+		}
+		log.Debug("Evicted cached batch", zap.String("hash", batch.hash), zap.Int64("sizeBytes", batch.size))
+	}
+	return nil
+}
+
+// cachedBatchHashes returns the hashes of the cached batches, the most recently
+// used first, for HELLO.
+func (rw *FileSyncer) cachedBatchHashes() []string {
+	if rw.getBatchCacheSize() <= 0 {
+		return nil
+	}
+	rw.batchCacheMu.Lock()
+	batches, err := rw.listCachedBatchesLocked()
+	rw.batchCacheMu.Unlock()
+	if err != nil {
+		log.Warn("Failed to list cached batches", zap.Error(err))
+		return nil
+	}
+	hashes := make([]string, len(batches))
+	for i, batch := range batches {
+		hashes[i] = batch.hash
+	}
+	return hashes
+}
+
+// errBatchNotCached is returned for a push that refers to a batch by its hash
+// when the batch isn't in the cache.
+var errBatchNotCached = errors.New("batch is not cached")
+
+// resolveBatch makes sure pushMsg carries its batch before it's applied. A push
+// sent with its batch has the batch cached, after checking it against
+// batch_hash if that is set too; a push sent with only batch_hash gets the
+// cached batch, or errBatchNotCached. A cached batch bigger than the memory
+// buffer for batches is spooled rather than read into memory.
+func (rw *FileSyncer) resolveBatch(pushMsg *pb.PushMessage) error {
+	maxBytes := rw.getBatchCacheSize()
+	if spooled := rw.spooledBatch(pushMsg); spooled != nil {
+		if pushMsg.BatchHash != "" && pushMsg.BatchHash != spooled.hash {
+			return fmt.Errorf("batch_hash %s doesn't match the batch, whose hash is %s", pushMsg.BatchHash, spooled.hash)
+		}
+		if err := rw.cacheSpooledBatch(spooled, maxBytes); err != nil {
+			log.Warn("Failed to cache batch", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		}
+		return nil
+	}
+	if len(pushMsg.BatchFile) > 0 {
+		if pushMsg.BatchHash != "" && pushMsg.BatchHash != batchHash(pushMsg.BatchFile) {
+			return fmt.Errorf("batch_hash %s doesn't match the batch, whose hash is %s", pushMsg.BatchHash, batchHash(pushMsg.BatchFile))
+		}
+		if err := rw.cacheBatch(pushMsg.BatchFile, maxBytes); err != nil {
+			// The cache only saves a re-send; the push can go ahead without it.
+			log.Warn("Failed to cache batch", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		}
+		return nil
+	}
+	if pushMsg.BatchHash == "" {
+		return nil
+	}
+	if maxBytes <= 0 {
+		return fmt.Errorf("%w: the batch cache is disabled", errBatchNotCached)
+	}
+	if rw.cachedBatchTooBig(pushMsg.BatchHash) {
+		batch, ok, err := rw.spoolCachedBatch(pushMsg.BatchHash)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s", errBatchNotCached, pushMsg.BatchHash)
+		}
+		log.Info("Applying spooled batch from cache", zap.String("pushID", pushMsg.PushId), zap.String("hash", pushMsg.BatchHash), zap.Int64("sizeBytes", batch.size))
+		rw.attachSpooledBatch(pushMsg, batch)
+		return nil
+	}
+	data, ok, err := rw.cachedBatch(pushMsg.BatchHash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", errBatchNotCached, pushMsg.BatchHash)
+	}
+	log.Info("Applying batch from cache", zap.String("pushID", pushMsg.PushId), zap.String("hash", pushMsg.BatchHash), zap.Int("sizeBytes", len(data)))
+	pushMsg.BatchFile = data
+	return nil
+}
+
+// cachedBatchTooBig reports whether the cached batch with hash is bigger than
+// the memory buffer for batches.
+func (rw *FileSyncer) cachedBatchTooBig(hash string) bool {
+	buffer := rw.getResourceLimits().batchBudget
+	if buffer <= 0 {
+		return false
+	}
+	path, err := batchCacheFile(rw.targetSyncDir, hash)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > buffer
+}
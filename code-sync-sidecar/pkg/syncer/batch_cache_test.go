@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestBatchCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir(), batchCacheSize: 25}
+	batches := [][]byte{[]byte("batch-one!"), []byte("batch-two!"), []byte("batch-3!!!")}
+	age := func(data []byte, ago time.Duration) {
+		path, err := batchCacheFile(rw.targetSyncDir, batchHash(data))
+		require.NoError(t, err)
+		require.NoError(t, os.Chtimes(path, time.Now().Add(-ago), time.Now().Add(-ago)))
+	}
+
+	require.NoError(t, rw.cacheBatch(batches[0], 25))
+	age(batches[0], 2*time.Minute)
+	require.NoError(t, rw.cacheBatch(batches[1], 25))
+	age(batches[1], time.Minute)
+	// Using the older batch makes the other one the least recently used.
+	data, ok, err := rw.cachedBatch(batchHash(batches[0]))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, batches[0], data)
+
+	require.NoError(t, rw.cacheBatch(batches[2], 25))
+	assert.ElementsMatch(t, []string{batchHash(batches[0]), batchHash(batches[2])}, rw.cachedBatchHashes())
+	_, ok, err = rw.cachedBatch(batchHash(batches[1]))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A batch bigger than the whole cache isn't kept.
+	require.NoError(t, rw.cacheBatch(make([]byte, 26), 25))
+	assert.Len(t, rw.cachedBatchHashes(), 2)
+}
+
+func TestBatchCache_DropsCorruptedBatch(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir(), batchCacheSize: 1 << 20}
+	require.NoError(t, rw.cacheBatch([]byte("batch"), 1<<20))
+	path, err := batchCacheFile(rw.targetSyncDir, batchHash([]byte("batch")))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("btach"), 0600))
+
+	_, ok, err := rw.cachedBatch(batchHash([]byte("batch")))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoFileExists(t, path)
+
+	_, err = batchCacheFile(rw.targetSyncDir, "sha256:../../etc/passwd")
+	assert.ErrorContains(t, err, "invalid batch hash")
+}
+
+func TestResolveBatch(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir(), batchCacheSize: 1 << 20}
+
+	err := rw.resolveBatch(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch"), BatchHash: batchHash([]byte("other"))})
+	assert.ErrorContains(t, err, "doesn't match the batch")
+	err = rw.resolveBatch(&pb.PushMessage{PushId: "push-1", BatchHash: batchHash([]byte("batch"))})
+	assert.ErrorIs(t, err, errBatchNotCached)
+
+	require.NoError(t, rw.resolveBatch(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resent := &pb.PushMessage{PushId: "push-1", BatchHash: batchHash([]byte("batch"))}
+	require.NoError(t, rw.resolveBatch(resent))
+	assert.Equal(t, []byte("batch"), resent.BatchFile)
+
+	rw.batchCacheSize = 0
+	err = rw.resolveBatch(&pb.PushMessage{PushId: "push-2", BatchHash: batchHash([]byte("batch"))})
+	assert.ErrorContains(t, err, "the batch cache is disabled")
+}
+
+func TestPushQueue_AppliesCachedBatch(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.batchCacheSize = 1 << 20
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-0", BatchHash: batchHash([]byte("batch-1"))}, 0))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_BATCH_NOT_CACHED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "send it again with the batch")
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch-1")}, 0))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}, 0))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.ElementsMatch(t, []string{batchHash([]byte("batch-1")), batchHash([]byte("batch-2"))}, rw.buildHello().GetHello().GetCachedBatches())
+
+	// Going back to the first batch only takes its hash.
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-3", BatchHash: batchHash([]byte("batch-1"))}, 0))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.False(t, resp.GetNoOp())
+	assert.Equal(t, batchHash([]byte("batch-1")), rw.buildHello().GetHello().GetLastPushHash())
+}
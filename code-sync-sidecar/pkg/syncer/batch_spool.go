@@ -0,0 +1,269 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// getBatchSpoolDir returns where batches that don't fit in the sidecar's
+// memory buffer for batches are kept until their pushes are applied.
+func getBatchSpoolDir(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), "spool")
+}
+
+// pushBatch is a push's rsync batch, held in memory or spooled to a file. A
+// spooled batch is only ever read from disk, so how big a batch the sidecar
+// can take is bounded by its disk rather than its memory.
+type pushBatch struct {
+	data []byte
+	path string // The spooled batch; data is nil
+	size int64
+	hash string
+	// subtree is the directory, relative to the files directory, that a
+	// sub-tree batch applies to; "" for the whole tree.
+	subtree string
+}
+
+func (b *pushBatch) empty() bool {
+	return b.size == 0
+}
+
+// writeTemp puts the batch in a new temporary file in sidecarDir for rsync to
+// read. The caller must remove the file.
+func (b *pushBatch) writeTemp(sidecarDir string) (string, error) {
+	if b.path == "" {
+		return writeBatchFile(sidecarDir, b.data)
+	}
+	tempBatchFile, err := os.CreateTemp(sidecarDir, "sync_batch_*.bin")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary batch file in %s: %w", sidecarDir, err)
+	}
+	tempBatchPath := tempBatchFile.Name()
+	tempBatchFile.Close()
+	if err := linkOrCopyFile(b.path, tempBatchPath); err != nil {
+		os.Remove(tempBatchPath)
+		return "", fmt.Errorf("failed to copy spooled batch to %s: %w", tempBatchPath, err)
+	}
+	log.Info("Linked spooled batch", zap.String("path", tempBatchPath), zap.Int64("sizeBytes", b.size))
+	return tempBatchPath, nil
+}
+
+// bytes returns the batch's content, reading a spooled batch into memory.
+func (b *pushBatch) bytes() ([]byte, error) {
+	if b.path == "" {
+		return b.data, nil
+	}
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spooled batch: %w", err)
+	}
+	return data, nil
+}
+
+// linkOrCopyFile makes dst a hard link to src, replacing it, or a copy where
+// links aren't supported.
+func linkOrCopyFile(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// batchSpool receives a batch into a file in .sidecar/spool, hashing it as it
+// is written.
+type batchSpool struct {
+	file *os.File
+	hash hash.Hash
+	size int64
+}
+
+// newBatchSpool creates a spool file. The first one the sidecar creates clears
+// out any left by an earlier run.
+func (rw *FileSyncer) newBatchSpool() (*batchSpool, error) {
+	dir := getBatchSpoolDir(rw.targetSyncDir)
+	rw.batches.spoolClear.Do(func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warn("Failed to clear spooled batches left by an earlier run", zap.Error(err))
+		}
+	})
+	if err := os.MkdirAll(dir, launcher.Volume.InternalDir); err != nil {
+		return nil, fmt.Errorf("failed to create batch spool directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "*.batch")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	return &batchSpool{file: file, hash: sha256.New()}, nil
+}
+
+func (s *batchSpool) Write(p []byte) (int, error) {
+	n, err := s.file.Write(p)
+	s.hash.Write(p[:n])
+	s.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write spooled batch: %w", err)
+	}
+	return n, nil
+}
+
+// finish closes the spool file and returns the batch in it.
+func (s *batchSpool) finish() (*pushBatch, error) {
+	if err := s.file.Close(); err != nil {
+		os.Remove(s.file.Name())
+		return nil, fmt.Errorf("failed to write spooled batch: %w", err)
+	}
+	return &pushBatch{path: s.file.Name(), size: s.size, hash: "sha256:" + hex.EncodeToString(s.hash.Sum(nil))}, nil
+}
+
+// abort removes the spool file.
+func (s *batchSpool) abort() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// attachSpooledBatch makes batch pushMsg's batch. The push keeps its
+// streamed_batch_size, with no batch_file, while its batch is spooled.
+func (rw *FileSyncer) attachSpooledBatch(pushMsg *pb.PushMessage, batch *pushBatch) {
+	s := &rw.batches
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.spooled == nil {
+		s.spooled = make(map[*pb.PushMessage]*pushBatch)
+	}
+	pushMsg.BatchFile = nil
+	pushMsg.StreamedBatchSize = batch.size
+	s.spooled[pushMsg] = batch
+}
+
+// spooledBatch returns pushMsg's spooled batch, or nil if it has none.
+func (rw *FileSyncer) spooledBatch(pushMsg *pb.PushMessage) *pushBatch {
+	s := &rw.batches
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spooled[pushMsg]
+}
+
+// batchOf returns pushMsg's batch, wherever it is kept.
+func (rw *FileSyncer) batchOf(pushMsg *pb.PushMessage) *pushBatch {
+	if batch := rw.spooledBatch(pushMsg); batch != nil {
+		return batch
+	}
+	batch := &pushBatch{data: pushMsg.BatchFile, size: int64(len(pushMsg.BatchFile))}
+	if len(batch.data) > 0 {
+		batch.hash = batchHash(batch.data)
+	}
+	return batch
+}
+
+// carriesBatch reports whether pushMsg has a batch, in memory or spooled.
+func carriesBatch(pushMsg *pb.PushMessage) bool {
+	return len(pushMsg.BatchFile) > 0 || pushMsg.StreamedBatchSize > 0
+}
+
+// releaseBatch removes pushMsg's spooled batch once the push is done with.
+func (rw *FileSyncer) releaseBatch(pushMsg *pb.PushMessage) {
+	s := &rw.batches
+	s.mu.Lock()
+	batch, ok := s.spooled[pushMsg]
+	delete(s.spooled, pushMsg)
+	s.mu.Unlock()
+	if ok {
+		if err := os.Remove(batch.path); err != nil {
+			log.Warn("Failed to remove spooled batch", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		}
+	}
+}
+
+// spoolOversizedBatch moves a batch sent whole in its PUSH_REQUEST to disk if
+// it is bigger than the memory buffer for batches, so it isn't held in memory
+// while the push waits in the queue.
+func (rw *FileSyncer) spoolOversizedBatch(pushMsg *pb.PushMessage) {
+	buffer := rw.getResourceLimits().batchBudget
+	if buffer <= 0 || int64(len(pushMsg.BatchFile)) <= buffer {
+		return
+	}
+	batch, err := rw.spoolData(pushMsg.BatchFile)
+	if err != nil {
+		// The push can still be applied from memory.
+		log.Warn("Failed to spool batch", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		return
+	}
+	log.Info("Spooled batch bigger than the memory buffer for batches", zap.String("pushID", pushMsg.PushId),
+		zap.Int64("sizeBytes", batch.size), zap.Int64("bufferBytes", buffer))
+	rw.attachSpooledBatch(pushMsg, batch)
+}
+
+// spoolData writes data to a new spool file.
+func (rw *FileSyncer) spoolData(data []byte) (*pushBatch, error) {
+	spool, err := rw.newBatchSpool()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := spool.Write(data); err != nil {
+		spool.abort()
+		return nil, err
+	}
+	return spool.finish()
+}
+
+// spoolFile copies the file at path, such as a cached batch, to a new spool
+// file, hashing it on the way.
+func (rw *FileSyncer) spoolFile(path string) (*pushBatch, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	spool, err := rw.newBatchSpool()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(spool, in); err != nil {
+		spool.abort()
+		return nil, err
+	}
+	return spool.finish()
+}
+
+// withBatchData returns pushMsg carrying its batch in batch_file, as sent to
+// followers, which get the batch in the push itself.
+func (rw *FileSyncer) withBatchData(pushMsg *pb.PushMessage) (*pb.PushMessage, error) {
+	batch := rw.spooledBatch(pushMsg)
+	if batch == nil {
+		return pushMsg, nil
+	}
+	data, err := batch.bytes()
+	if err != nil {
+		return nil, err
+	}
+	forwarded := proto.Clone(pushMsg).(*pb.PushMessage)
+	forwarded.BatchFile = data
+	forwarded.StreamedBatchSize = 0
+	return forwarded, nil
+}
@@ -0,0 +1,218 @@
+package syncer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// maxBatchStreams bounds the pushes whose batches are being received at once.
+	maxBatchStreams = 4
+	// maxStreamedBatchSize bounds a streamed batch.
+	maxStreamedBatchSize = 1 << 30
+	// maxInMemoryStreamedBatchSize is the biggest streamed batch assembled in
+	// memory; bigger ones are spooled even if the memory buffer has room.
+	maxInMemoryStreamedBatchSize = 64 << 20
+)
+
+// batchStreams assembles the batches of pushes sent with streamed_batch_size,
+// which arrive in BATCH_CHUNK messages after their PUSH_REQUEST. Sending a big
+// batch in chunks keeps the read loop free for the control messages sent
+// meanwhile, such as a PUSH_CANCEL for the push itself. Batches of up to
+// maxInMemoryStreamedBatchSize are assembled in memory, as long as they fit in
+// the sidecar's memory buffer for batches; the rest are written straight to
+// .sidecar/spool.
+type batchStreams struct {
+	mu      sync.Mutex
+	streams map[string]*batchStream
+	// spooled holds the spooled batches of pushes received but not yet done with.
+	spooled    map[*pb.PushMessage]*pushBatch
+	spoolClear sync.Once
+}
+
+// batchStream is a push waiting for the rest of its batch.
+type batchStream struct {
+	push     *pb.PushMessage
+	data     []byte
+	spool    *batchSpool // Receives the batch instead of data when set
+	received int64
+	next     int32
+	started  time.Time
+}
+
+// abort drops what was received of the batch.
+func (stream *batchStream) abort() {
+	if stream.spool != nil {
+		stream.spool.abort()
+	}
+}
+
+// startBatchStream holds pushMsg until its batch has been received.
+func (rw *FileSyncer) startBatchStream(pushMsg *pb.PushMessage) error {
+	pushID := pushMsg.PushId
+	size := pushMsg.StreamedBatchSize
+	fail := func(err error) error {
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return fmt.Errorf("rejecting push %s: %w", pushID, err)
+	}
+	if len(pushMsg.BatchFile) > 0 {
+		return fail(fmt.Errorf("push has both a batch file and a streamed batch"))
+	}
+	if size > maxStreamedBatchSize {
+		return fail(fmt.Errorf("streamed batch of %d bytes is over the %d-byte limit", size, maxStreamedBatchSize))
+	}
+
+	s := &rw.batches
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		s.streams = make(map[string]*batchStream)
+	}
+	if _, restarted := s.streams[pushID]; !restarted && len(s.streams) >= maxBatchStreams {
+		return fail(fmt.Errorf("too many batches are being received"))
+	}
+	stream := &batchStream{push: pushMsg, started: time.Now()}
+	budget := rw.getResourceLimits().batchBudget
+	if buffered := s.bufferedLocked(pushID); size > maxInMemoryStreamedBatchSize || (budget > 0 && buffered+size > budget) {
+		spool, err := rw.newBatchSpool()
+		if err != nil {
+			return fail(err)
+		}
+		stream.spool = spool
+		log.Info("Spooling streamed batch, it is too big to hold in memory", zap.String("pushID", pushID),
+			zap.Int64("sizeBytes", size), zap.Int64("bufferBytes", budget), zap.Int64("bufferedBytes", buffered))
+	} else {
+		// The batch grows as its chunks arrive, so a stream that is dropped
+		// early never takes up all the memory it announced.
+		log.Info("Receiving streamed batch", zap.String("pushID", pushID), zap.Int64("sizeBytes", size))
+	}
+	// A push sent again starts over; whatever was received of it is dropped.
+	if previous, ok := s.streams[pushID]; ok {
+		previous.abort()
+	}
+	s.streams[pushID] = stream
+	return nil
+}
+
+// bufferedBytes returns the memory reserved for batches being received.
+func (s *batchStreams) bufferedBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bufferedLocked("")
+}
+
+// bufferedLocked sums the sizes of the batches being received in memory,
+// except pushID's. s.mu must be held.
+func (s *batchStreams) bufferedLocked(pushID string) int64 {
+	var total int64
+	for id, stream := range s.streams {
+		if id != pushID && stream.spool == nil {
+			total += stream.push.StreamedBatchSize
+		}
+	}
+	return total
+}
+
+// handleBatchChunk adds chunk to its push's batch, and queues the push once the
+// last chunk arrives. A chunk out of order or past the batch's size fails the push.
+func (rw *FileSyncer) handleBatchChunk(chunk *pb.BatchChunk) error {
+	if chunk == nil {
+		return fmt.Errorf("received BATCH_CHUNK but batch_chunk field is nil")
+	}
+	s := &rw.batches
+	s.mu.Lock()
+	stream, ok := s.streams[chunk.PushId]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("received batch chunk for push %s, which isn't being received", chunk.PushId)
+	}
+	var err error
+	switch {
+	case chunk.Index != stream.next:
+		err = fmt.Errorf("batch chunk %d arrived when chunk %d was expected", chunk.Index, stream.next)
+	case stream.received+int64(len(chunk.Data)) > stream.push.StreamedBatchSize:
+		err = fmt.Errorf("batch chunks are over the announced %d bytes", stream.push.StreamedBatchSize)
+	case chunk.Last && stream.received+int64(len(chunk.Data)) != stream.push.StreamedBatchSize:
+		err = fmt.Errorf("batch ended after %d of the announced %d bytes", stream.received+int64(len(chunk.Data)), stream.push.StreamedBatchSize)
+	}
+	if err == nil {
+		if stream.spool != nil {
+			_, err = stream.spool.Write(chunk.Data)
+		} else {
+			stream.data = append(stream.data, chunk.Data...)
+		}
+		stream.received += int64(len(chunk.Data))
+		stream.next++
+	}
+	if err != nil || chunk.Last {
+		delete(s.streams, chunk.PushId)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		stream.abort()
+	}
+
+	if err != nil {
+		rw.sendProtoMessage(buildPushResponse(chunk.PushId, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return fmt.Errorf("rejecting push %s: %w", chunk.PushId, err)
+	}
+	if !chunk.Last {
+		return nil
+	}
+	pushMsg := stream.push
+	if stream.spool != nil {
+		batch, err := stream.spool.finish()
+		if err != nil {
+			rw.sendProtoMessage(buildPushResponse(chunk.PushId, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+			return fmt.Errorf("rejecting push %s: %w", chunk.PushId, err)
+		}
+		rw.attachSpooledBatch(pushMsg, batch)
+	} else {
+		pushMsg.BatchFile = stream.data
+		pushMsg.StreamedBatchSize = 0
+	}
+	log.Info("Received streamed batch", zap.String("pushID", pushMsg.PushId), zap.Int("chunks", int(stream.next)))
+	return rw.enqueuePush(pushMsg, time.Since(stream.started))
+}
+
+// cancelBatchStream drops a push whose batch is still being received, and
+// reports whether there was one.
+func (rw *FileSyncer) cancelBatchStream(pushID string) bool {
+	s := &rw.batches
+	s.mu.Lock()
+	stream, ok := s.streams[pushID]
+	delete(s.streams, pushID)
+	s.mu.Unlock()
+	if ok {
+		stream.abort()
+		log.Info("Cancelling push before its batch was received", zap.String("pushID", pushID))
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_CANCELLED, "Push cancelled before its batch was received"))
+	}
+	return ok
+}
+
+// dropBatchStreams fails the pushes whose batches were still being received
+// when the connection closed. The responses are replayed once the sidecar
+// reconnects, so the control plane knows to send those pushes again.
+func (rw *FileSyncer) dropBatchStreams() {
+	s := &rw.batches
+	s.mu.Lock()
+	pushIDs := make([]string, 0, len(s.streams))
+	for pushID, stream := range s.streams {
+		pushIDs = append(pushIDs, pushID)
+		stream.abort()
+	}
+	clear(s.streams)
+	s.mu.Unlock()
+
+	for _, pushID := range pushIDs {
+		log.Info("Dropping partly received batch, connection closed", zap.String("pushID", pushID))
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, "Push failed: the connection closed before its batch was received"))
+	}
+}
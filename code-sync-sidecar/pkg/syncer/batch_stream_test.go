@@ -0,0 +1,141 @@
+package syncer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func batchChunkMessage(pushID string, index int32, data string, last bool) *pb.WebsocketMessage {
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_BATCH_CHUNK,
+		Message: &pb.WebsocketMessage_BatchChunk{
+			BatchChunk: &pb.BatchChunk{PushId: pushID, Index: index, Data: []byte(data), Last: last},
+		},
+	}
+}
+
+func streamedPushMessage(pushID string, size int64) *pb.WebsocketMessage {
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_REQUEST,
+		Message: &pb.WebsocketMessage_PushMessage{
+			PushMessage: &pb.PushMessage{PushId: pushID, StreamedBatchSize: size},
+		},
+	}
+}
+
+func TestBatchStream_AssemblesBatch(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-1", 9), 0))
+	require.NoError(t, rw.handleProtoMessage(batchChunkMessage("push-1", 0, "bat", false), 0))
+	// Control messages for other pushes are handled between the chunks.
+	assert.Error(t, rw.handleProtoMessage(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_CANCEL,
+		Message:     &pb.WebsocketMessage_PushCancel{PushCancel: &pb.PushCancel{PushId: "push-0"}},
+	}, 0))
+	require.NoError(t, rw.handleProtoMessage(batchChunkMessage("push-1", 1, "ch-", false), 0))
+	require.NoError(t, rw.handleProtoMessage(batchChunkMessage("push-1", 2, "one", true), 0))
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-1", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, batchHash([]byte("batch-one")), rw.applied.LastPushHash)
+	assert.Empty(t, rw.batches.streams)
+}
+
+func TestBatchStream_Rejects(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-1", 6), 0))
+	assert.ErrorContains(t, rw.handleProtoMessage(batchChunkMessage("push-1", 1, "abc", false), 0), "batch chunk 1 arrived when chunk 0 was expected")
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Error(t, rw.handleProtoMessage(batchChunkMessage("push-1", 1, "def", true), 0), "the failed push is forgotten")
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-2", 6), 0))
+	assert.ErrorContains(t, rw.handleProtoMessage(batchChunkMessage("push-2", 0, "abc", true), 0), "batch ended after 3 of the announced 6 bytes")
+	assert.Equal(t, pb.PushResponse_FAILED, waitForPushResponse(t, mockServer).GetStatus())
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-3", 6), 0))
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-3"}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "before its batch was received")
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-4", 6), 0))
+	rw.dropBatchStreams()
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-4", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+}
+
+func TestBatchStream_MemoryBudget(t *testing.T) {
+	rw, _ := newRsyncOptionsTestSyncer(t)
+	rw.resources = resourceLimits{batchBudget: 10}
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-1", 6), 0))
+	assert.Equal(t, int64(6), rw.batches.bufferedBytes())
+	// A batch that doesn't fit beside push-1's is spooled to disk instead.
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-2", 5), 0))
+	assert.Equal(t, int64(6), rw.batches.bufferedBytes())
+	require.NotNil(t, rw.batches.streams["push-2"].spool)
+
+	// A push sent again replaces what was received of it.
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-1", 9), 0))
+	assert.Equal(t, int64(9), rw.batches.bufferedBytes())
+	assert.Zero(t, cap(rw.batches.streams["push-1"].data), "memory is taken as chunks arrive, not up front")
+
+	// A batch too big to assemble in memory is spooled whatever the buffer.
+	rw.resources = resourceLimits{batchBudget: maxBatchStreams * maxStreamedBatchSize}
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-3", maxInMemoryStreamedBatchSize+1), 0))
+	require.NotNil(t, rw.batches.streams["push-3"].spool)
+	assert.Equal(t, int64(9), rw.batches.bufferedBytes())
+}
+
+func TestBatchStream_SpoolsToDisk(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.resources = resourceLimits{batchBudget: 4}
+
+	require.NoError(t, rw.handleProtoMessage(streamedPushMessage("push-1", 9), 0))
+	require.NoError(t, rw.handleProtoMessage(batchChunkMessage("push-1", 0, "batch", false), 0))
+	require.NoError(t, rw.handleProtoMessage(batchChunkMessage("push-1", 1, "-one", true), 0))
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, batchHash([]byte("batch-one")), rw.applied.LastPushHash)
+	assert.Equal(t, int64(0), rw.batches.bufferedBytes())
+	spoolEmpty := func() bool {
+		entries, err := os.ReadDir(getBatchSpoolDir(rw.targetSyncDir))
+		return err == nil && len(entries) == 0
+	}
+	assert.Eventually(t, spoolEmpty, 5*time.Second, 10*time.Millisecond, "the spooled batch is removed once applied")
+
+	// So is a batch sent whole that is bigger than the buffer.
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-two")}, 0))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, batchHash([]byte("batch-two")), rw.applied.LastPushHash)
+	assert.Eventually(t, spoolEmpty, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBatchSpool_Abort(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	spool, err := rw.newBatchSpool()
+	require.NoError(t, err)
+	_, err = spool.Write([]byte("partial"))
+	require.NoError(t, err)
+	spool.abort()
+	entries, err := os.ReadDir(getBatchSpoolDir(rw.targetSyncDir))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
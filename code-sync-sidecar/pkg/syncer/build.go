@@ -0,0 +1,193 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// BuildHook names the HookResult of the build command.
+	BuildHook = "build"
+
+	DefaultBuildTimeout = 10 * time.Minute
+
+	// maxBuildLineLength cuts off the long lines some builds write, such as
+	// minified source quoted in an error, before they are streamed.
+	maxBuildLineLength = 1024
+)
+
+var errBuildFailed = errors.New("build failed")
+
+// BuildRunner runs the configured build command, such as tsc or webpack, once
+// a push's files are applied and before the app is reloaded into them.
+type BuildRunner struct {
+	command  string
+	timeout  time.Duration
+	filesDir string
+}
+
+// NewBuildRunner creates a BuildRunner, or returns nil if command is empty.
+func NewBuildRunner(command string, timeout time.Duration, filesDir string) *BuildRunner {
+	if command == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultBuildTimeout
+	}
+	return &BuildRunner{command: command, timeout: timeout, filesDir: filesDir}
+}
+
+// Run runs the command with sh in dir, where the push's files were applied,
+// passing the lines it writes to onOutput as it goes, at most every
+// minProgressInterval. It returns an error when the command exits non-zero,
+// times out or ctx is cancelled.
+func (br *BuildRunner) Run(ctx context.Context, dir, pushID string, onOutput func(lines []string)) (*pb.HookResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, br.timeout)
+	defer cancel()
+
+	output := &buildOutput{onLines: onOutput}
+	cmd := execCommand(ctx, "/bin/sh", "-c", br.command)
+	cmd.Dir = dir
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = append(os.Environ(),
+		"BIFROST_PUSH_ID="+pushID,
+		"BIFROST_FILES_DIR="+br.filesDir,
+		"BIFROST_BUILD_DIR="+dir,
+	)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	log.Info("Running build", zap.String("pushID", pushID), zap.String("command", br.command))
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(minProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				output.flush(false)
+			}
+		}
+	}()
+	startTime := time.Now()
+	err := cmd.Run()
+	duration := time.Since(startTime)
+	close(done)
+	output.flush(true)
+
+	result := &pb.HookResult{
+		Name:       BuildHook,
+		ExitCode:   int32(cmd.ProcessState.ExitCode()),
+		Output:     output.tail(),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return result, fmt.Errorf("%w: timed out after %v", errBuildFailed, br.timeout)
+		case context.Canceled:
+			return result, fmt.Errorf("build cancelled: %w", ctx.Err())
+		}
+		return result, fmt.Errorf("%w: %w. Output: %s", errBuildFailed, err, result.Output)
+	}
+	log.Info("Build succeeded", zap.String("pushID", pushID), zap.Duration("duration", duration))
+	return result, nil
+}
+
+// buildOutput collects what the build command writes: the tail of it for its
+// HookResult, and the lines not yet passed to onLines.
+type buildOutput struct {
+	onLines func(lines []string)
+
+	mu      sync.Mutex
+	all     []byte
+	partial []byte
+	lines   []string
+}
+
+func (o *buildOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.all = append(o.all, p...)
+	if extra := len(o.all) - 2*maxHookOutputLength; extra > 0 {
+		o.all = append(o.all[:0], o.all[extra:]...)
+	}
+	o.partial = append(o.partial, p...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i < 0 {
+			break
+		}
+		o.lines = append(o.lines, buildLine(o.partial[:i]))
+		o.partial = o.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes the complete lines written since the last flush to onLines,
+// and with final set the last line even if it has no newline.
+func (o *buildOutput) flush(final bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if final && len(o.partial) > 0 {
+		o.lines = append(o.lines, buildLine(o.partial))
+		o.partial = nil
+	}
+	if len(o.lines) == 0 || o.onLines == nil {
+		return
+	}
+	// Sent under the lock so lines from two flushes can't be reordered.
+	o.onLines(o.lines)
+	o.lines = nil
+}
+
+func (o *buildOutput) tail() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return truncateOutput(o.all, maxHookOutputLength)
+}
+
+func buildLine(line []byte) string {
+	s := strings.TrimSuffix(string(line), "\r")
+	if len(s) > maxBuildLineLength {
+		s = s[:maxBuildLineLength] + "..."
+	}
+	return s
+}
+
+// runBuild runs the build command in dir, streaming its output as BUILDING
+// progress. It returns a nil result if no command is configured; the error
+// wraps errBuildFailed unless the push was cancelled.
+func (rw *FileSyncer) runBuild(ctx context.Context, dir, pushID string, progress *pushProgress, timer *pushTimer) (*pb.HookResult, error) {
+	build := rw.getBuild()
+	if build == nil {
+		return nil, nil
+	}
+	progress.report(pb.PushProgress_BUILDING, 0, 0, 0)
+	start := time.Now()
+	result, err := build.Run(ctx, dir, pushID, func(lines []string) {
+		progress.output(pb.PushProgress_BUILDING, lines)
+	})
+	timer.since(stageBuild, start)
+	return result, err
+}
+
+// getBuild returns the runner for the build command, or nil if none is configured.
+func (rw *FileSyncer) getBuild() *BuildRunner {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.build
+}
@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestBuildRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var streamed []string
+	onOutput := func(lines []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed = append(streamed, lines...)
+	}
+
+	runner := NewBuildRunner(`echo "building $BIFROST_PUSH_ID"; echo warning >&2; printf 'done in %s' "$(basename "$BIFROST_BUILD_DIR")"`, time.Second, dir)
+	result, err := runner.Run(context.Background(), dir, "push-1", onOutput)
+	require.NoError(t, err)
+	assert.Equal(t, BuildHook, result.Name)
+	assert.Equal(t, "building push-1\nwarning\ndone in "+filepath.Base(dir), result.Output)
+	assert.Equal(t, []string{"building push-1", "warning", "done in " + filepath.Base(dir)}, streamed, "the last line is sent without a newline")
+
+	runner = NewBuildRunner(`echo "src/app.ts(3,1): error TS2304"; exit 2`, time.Second, dir)
+	result, err = runner.Run(context.Background(), dir, "push-2", nil)
+	assert.ErrorIs(t, err, errBuildFailed)
+	assert.ErrorContains(t, err, "error TS2304")
+	assert.Equal(t, int32(2), result.ExitCode)
+
+	runner = NewBuildRunner("sleep 5", 50*time.Millisecond, dir)
+	_, err = runner.Run(context.Background(), dir, "push-3", nil)
+	assert.ErrorContains(t, err, "build failed: timed out after 50ms")
+
+	assert.Nil(t, NewBuildRunner("", time.Second, dir))
+}
+
+func TestBuildOutput_CutsLongLines(t *testing.T) {
+	var streamed []string
+	output := &buildOutput{onLines: func(lines []string) { streamed = append(streamed, lines...) }}
+	_, err := output.Write([]byte(strings.Repeat("x", maxBuildLineLength+10) + "\r\nnext"))
+	require.NoError(t, err)
+	output.flush(false)
+	require.Len(t, streamed, 1)
+	assert.Equal(t, strings.Repeat("x", maxBuildLineLength)+"...", streamed[0])
+	output.flush(true)
+	assert.Equal(t, "next", streamed[1])
+}
+
+func TestHandlePushRequest_Build(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+	// The build runs with the real shell.
+	execCommand = exec.CommandContext
+	finder := rw.processFinder.(*mockProcessFinder)
+	rw.build = NewBuildRunner(`cat src/app.ts > app.js && echo compiled`, 5*time.Second, rw.targetSyncDir)
+
+	pushMsg := &pb.PushMessage{PushId: "push-1", Files: map[string]*pb.InjectedFile{"src/app.ts": {Content: []byte("let a = 1;\n")}}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	var streamed []string
+	var resp *pb.PushResponse
+	for resp == nil {
+		select {
+		case message := <-mockServer.messages:
+			var wsMessage pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+			if progress := wsMessage.GetPushProgress(); progress.GetStage() == pb.PushProgress_BUILDING {
+				streamed = append(streamed, progress.OutputLines...)
+			} else if r := wsMessage.GetPushResponse(); r != nil && !isIntermediatePushStatus(r.Status) {
+				resp = r
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for push response")
+		}
+	}
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Equal(t, []string{"compiled"}, streamed)
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, BuildHook, resp.HookResults[0].Name)
+	assert.Equal(t, "let a = 1;\n", readFile(t, filepath.Join(rw.targetSyncDir, "app.js")), "the build sees the push's files")
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+
+	// A failed build stops the push before the app is reloaded.
+	pushMsg = &pb.PushMessage{PushId: "push-2", Files: map[string]*pb.InjectedFile{"src/app.ts": {Content: []byte("let a = ;\n")}}}
+	rw.build = NewBuildRunner(`echo "error TS1109: Expression expected" >&2; exit 1`, 5*time.Second, rw.targetSyncDir)
+	assert.ErrorIs(t, rw.handlePushRequest(context.Background(), pushMsg), errBuildFailed)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "Push application failed: build failed: exit status 1. Output: error TS1109")
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, int32(1), resp.HookResults[0].ExitCode)
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+}
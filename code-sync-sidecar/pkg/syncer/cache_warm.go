@@ -0,0 +1,188 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// WarmHook names the HookResult of the cache warm command.
+	WarmHook = "warm"
+
+	DefaultWarmTimeout = time.Minute
+
+	// warmReaders is how many files are read at once: a network-backed volume
+	// serves reads in parallel far faster than one after another.
+	warmReaders = 8
+)
+
+// CacheWarmer warms the page cache once a push's files are applied and before
+// the app is reloaded, so its first requests don't wait on files read cold
+// from a network-backed volume. It either reads the files the push changed or
+// runs the configured warm command. Warming is best effort: a failure is
+// logged and doesn't fail the push.
+type CacheWarmer struct {
+	command  string // Run instead of reading the files when set
+	timeout  time.Duration
+	filesDir string
+}
+
+// NewCacheWarmer creates a CacheWarmer, or returns nil if it neither reads
+// files nor has a command.
+func NewCacheWarmer(cfg WarmConfig, filesDir string) *CacheWarmer {
+	if !cfg.ReadFiles && cfg.Command == "" {
+		return nil
+	}
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultWarmTimeout
+	}
+	return &CacheWarmer{command: cfg.Command, timeout: timeout, filesDir: filesDir}
+}
+
+// Run warms the cache with paths, the files the push changed relative to dir.
+// The warm command, if configured, is run instead of reading them, with sh in
+// dir and the paths on its stdin, one per line; its result is returned.
+func (cw *CacheWarmer) Run(ctx context.Context, dir, pushID string, paths []string) (*pb.HookResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, cw.timeout)
+	defer cancel()
+	if cw.command != "" {
+		return cw.runCommand(ctx, dir, pushID, paths)
+	}
+
+	start := time.Now()
+	files, bytes, err := warmFiles(ctx, dir, paths)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("reading changed files timed out after %v, %d of %d read", cw.timeout, files, len(paths))
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Warmed cache with changed files", zap.String("pushID", pushID), zap.Int("files", files),
+		zap.Int64("bytes", bytes), zap.Duration("duration", time.Since(start)))
+	return nil, nil
+}
+
+func (cw *CacheWarmer) runCommand(ctx context.Context, dir, pushID string, paths []string) (*pb.HookResult, error) {
+	cmd := execCommand(ctx, "/bin/sh", "-c", cw.command)
+	cmd.Dir = dir
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = append(os.Environ(),
+		"BIFROST_PUSH_ID="+pushID,
+		"BIFROST_FILES_DIR="+cw.filesDir,
+	)
+	// On stdin rather than in the env, which can't hold a large push's paths.
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+
+	log.Info("Running cache warm command", zap.String("pushID", pushID), zap.String("command", cw.command))
+	startTime := time.Now()
+	output, err := cmd.CombinedOutput()
+	duration := time.Since(startTime)
+
+	result := &pb.HookResult{
+		Name:       WarmHook,
+		ExitCode:   int32(cmd.ProcessState.ExitCode()),
+		Output:     truncateOutput(output, maxHookOutputLength),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return result, fmt.Errorf("cache warm command timed out after %v", cw.timeout)
+		case context.Canceled:
+			return result, fmt.Errorf("cache warm command cancelled: %w", ctx.Err())
+		}
+		return result, fmt.Errorf("cache warm command failed: %w. Output: %s", err, result.Output)
+	}
+	log.Info("Cache warm command succeeded", zap.String("pushID", pushID), zap.Duration("duration", duration))
+	return result, nil
+}
+
+// warmFiles reads the regular files among paths, relative to dir, and
+// discards what they hold, returning how many it read and their size. Paths
+// that are gone or aren't regular files, which could block or never end, are
+// skipped.
+func warmFiles(ctx context.Context, dir string, paths []string) (int, int64, error) {
+	var files, bytes atomic.Int64
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(warmReaders, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				n, err := warmFile(filepath.Join(dir, filepath.FromSlash(p)))
+				if err != nil {
+					log.Debug("Failed to read file to warm the cache", zap.String("path", p), zap.Error(err))
+					continue
+				}
+				if n >= 0 {
+					files.Add(1)
+					bytes.Add(n)
+				}
+			}
+		}()
+	}
+	var err error
+	for _, p := range paths {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+	return int(files.Load()), bytes.Load(), err
+}
+
+// warmFile reads the file at path to its end, returning how many bytes it
+// held, or -1 if it isn't a regular file.
+func warmFile(path string) (int64, error) {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return -1, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+	return io.Copy(io.Discard, f)
+}
+
+// warmCache warms the cache with the push's changed files. It returns a nil
+// result if warming isn't configured or reads the files itself.
+func (rw *FileSyncer) warmCache(ctx context.Context, dir, pushID string, paths []string, timer *pushTimer) *pb.HookResult {
+	warmer := rw.getCacheWarmer()
+	if warmer == nil || len(paths) == 0 {
+		return nil
+	}
+	start := time.Now()
+	result, err := warmer.Run(ctx, dir, pushID, paths)
+	timer.since(stageWarm, start)
+	if err != nil && ctx.Err() == nil {
+		// The app just starts colder.
+		log.Warn("Failed to warm the cache", zap.String("pushID", pushID), zap.Error(err))
+	}
+	return result
+}
+
+// getCacheWarmer returns the cache warmer, or nil if none is configured.
+func (rw *FileSyncer) getCacheWarmer() *CacheWarmer {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.warm
+}
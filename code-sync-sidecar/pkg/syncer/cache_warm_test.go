@@ -0,0 +1,62 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestWarmFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src/app.js"), []byte("console.log(1)\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>"), 0644))
+	require.NoError(t, os.Symlink("index.html", filepath.Join(dir, "link.html")))
+
+	files, bytes, err := warmFiles(context.Background(), dir, []string{"src/app.js", "index.html", "link.html", "gone.js", "src"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, files, "only regular files that exist are read")
+	assert.Equal(t, int64(21), bytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = warmFiles(ctx, dir, []string{"index.html"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Nil(t, NewCacheWarmer(WarmConfig{Timeout: Duration(time.Second)}, dir))
+}
+
+func TestHandlePushRequest_WarmCache(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+	// The warm command runs with the real shell.
+	execCommand = exec.CommandContext
+	rw.warm = NewCacheWarmer(WarmConfig{Command: `cat > warmed.log; echo "$BIFROST_PUSH_ID" >> warmed.log`, Timeout: Duration(5 * time.Second)}, rw.targetSyncDir)
+
+	pushMsg := &pb.PushMessage{PushId: "push-1", Files: map[string]*pb.InjectedFile{
+		"src/app.ts": {Content: []byte("let a = 1;\n")},
+		"README.md":  {Content: []byte("# App\n")},
+	}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, WarmHook, resp.HookResults[0].Name)
+	assert.Equal(t, "README.md\nsrc/app.ts\npush-1\n", readFile(t, filepath.Join(rw.targetSyncDir, "warmed.log")))
+
+	// A failed warm command doesn't fail the push.
+	rw.warm = NewCacheWarmer(WarmConfig{Command: "exit 3", Timeout: Duration(5 * time.Second)}, rw.targetSyncDir)
+	pushMsg = &pb.PushMessage{PushId: "push-2", Files: map[string]*pb.InjectedFile{"src/app.ts": {Content: []byte("let a = 2;\n")}}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, int32(3), resp.HookResults[0].ExitCode)
+}
@@ -0,0 +1,136 @@
+package syncer
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// Version is the sidecar's release, sent in HELLO. The command sets it from
+// its own build-time version.
+var Version = "dev"
+
+// Rsync is the rsync provisioned for the launcher, reported in HELLO so the
+// server can tell a push that can't be applied from one that failed. The
+// command sets it from launcher.CopyBinaries; it is zero when nothing was
+// provisioned.
+var Rsync launcher.RsyncInfo
+
+// ProtocolVersion is sent in HELLO. Bump it when the sidecar changes how it
+// handles a message the control plane already sends.
+const ProtocolVersion = 1
+
+// Optional features advertised in HELLO. A feature is listed only while it is
+// enabled, so the control plane can hide actions the sidecar would refuse.
+const (
+	FeatureSnapshots   = "snapshots"
+	FeatureShell       = "shell"
+	FeatureSwapApply   = "swap_apply"
+	FeatureHealthProbe = "health_probe"
+	FeatureLogTail     = "log_tail"
+	FeatureBatchCache  = "batch_cache"
+	FeatureEnvHistory  = "env_history"
+	FeatureApproval    = "approval"
+	// FeatureSubtreeBatches means the sidecar applies PushMessage.subtree_batches;
+	// an older one would ignore them and report the push completed.
+	FeatureSubtreeBatches = "subtree_batches"
+)
+
+// acceptedMessageTypes are the message types handleProtoMessage handles. Keep it in
+// sync when adding a case there.
+var acceptedMessageTypes = []pb.WebsocketMessage_MessageType{
+	pb.WebsocketMessage_PUSH_REQUEST,
+	pb.WebsocketMessage_PUSH_CANCEL,
+	pb.WebsocketMessage_ACTIVATE,
+	pb.WebsocketMessage_BATCH_CHUNK,
+	pb.WebsocketMessage_SNAPSHOT_CREATE,
+	pb.WebsocketMessage_SNAPSHOT_RESTORE,
+	pb.WebsocketMessage_ENV_ROLLBACK,
+	pb.WebsocketMessage_MANIFEST_REQUEST,
+	pb.WebsocketMessage_SYNC_STATUS_REQUEST,
+	pb.WebsocketMessage_FILE_GET_REQUEST,
+	pb.WebsocketMessage_DIR_LIST_REQUEST,
+	pb.WebsocketMessage_LOG_TAIL_REQUEST,
+	pb.WebsocketMessage_LOG_TAIL_STOP,
+	pb.WebsocketMessage_SHELL_OPEN,
+	pb.WebsocketMessage_SHELL_STDIN,
+	pb.WebsocketMessage_SHELL_RESIZE,
+	pb.WebsocketMessage_SHELL_CLOSE,
+	pb.WebsocketMessage_HELLO_ACK,
+	pb.WebsocketMessage_DIAGNOSTICS_REQUEST,
+}
+
+// enabledFeatures lists the optional features currently enabled.
+func (rw *FileSyncer) enabledFeatures() []string {
+	var features []string
+	if rw.getMaxSnapshots() > 0 {
+		features = append(features, FeatureSnapshots)
+	}
+	if rw.shells != nil && rw.shells.Enabled() {
+		features = append(features, FeatureShell)
+	}
+	if rw.applyMode == ApplyModeSwap {
+		features = append(features, FeatureSwapApply)
+	}
+	if rw.getHealthProber() != nil {
+		features = append(features, FeatureHealthProbe)
+	}
+	if rw.tails != nil && rw.tails.dir != "" {
+		features = append(features, FeatureLogTail)
+	}
+	if rw.coordinator != nil {
+		features = append(features, FeatureCoordination)
+	}
+	if rw.getBatchCacheSize() > 0 {
+		features = append(features, FeatureBatchCache)
+	}
+	if rw.getEnvHistory() > 0 {
+		features = append(features, FeatureEnvHistory)
+	}
+	if rw.requireApproval {
+		features = append(features, FeatureApproval)
+	}
+	if rw.getSubtreeWorkers() > 0 {
+		features = append(features, FeatureSubtreeBatches)
+	}
+	return features
+}
+
+// buildHello builds the HELLO sent at the start of every connection: what was
+// last applied, and what this sidecar supports.
+func (rw *FileSyncer) buildHello() *pb.WebsocketMessage {
+	rw.stateMu.Lock()
+	msg := buildHelloMessage(rw.applied)
+	rw.stateMu.Unlock()
+
+	hello := msg.GetHello()
+	hello.SidecarVersion = Version
+	hello.ProtocolVersion = ProtocolVersion
+	hello.Features = rw.enabledFeatures()
+	hello.AcceptedMessages = acceptedMessageTypes
+	hello.RsyncVersion = Rsync.Version
+	hello.RsyncProtocol = int32(Rsync.Protocol)
+	hello.CachedBatches = rw.cachedBatchHashes()
+	hello.SecurityModule = currentSecurityModule().proto()
+	return msg
+}
+
+// handleHelloAck records what the server supports and the session's resume
+// token, and replays responses to pushes the server didn't hear back about.
+func (rw *FileSyncer) handleHelloAck(ack *pb.HelloAck) error {
+	if ack == nil {
+		return nil
+	}
+	log.Info("Server acknowledged hello",
+		zap.Int32("ProtocolVersion", ack.ProtocolVersion),
+		zap.String("serverVersion", ack.ServerVersion),
+		zap.Strings("features", ack.Features))
+	rw.stateMu.Lock()
+	rw.serverHello = ack
+	rw.stateMu.Unlock()
+	rw.recordResumeToken(ack.ResumeToken)
+	rw.replayPushResponses(ack.UnacknowledgedPushIds)
+	return nil
+}
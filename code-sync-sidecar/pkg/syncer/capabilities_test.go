@@ -0,0 +1,70 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestBuildHello_AdvertisesCapabilities(t *testing.T) {
+	rw := &FileSyncer{
+		targetSyncDir: t.TempDir(),
+		applyMode:     ApplyModeInPlace,
+		shells:        NewShellManager(false, t.TempDir(), nil),
+	}
+	rw.recordApplied("push-1", "")
+
+	hello := rw.buildHello().GetHello()
+	assert.Equal(t, "push-1", hello.GetLastPushId())
+	assert.Equal(t, Version, hello.GetSidecarVersion())
+	assert.Equal(t, int32(ProtocolVersion), hello.GetProtocolVersion())
+	assert.Empty(t, hello.GetFeatures())
+	assert.Equal(t, acceptedMessageTypes, hello.GetAcceptedMessages())
+	assert.Empty(t, hello.GetRsyncVersion(), "no rsync was provisioned")
+
+	originalRsync := Rsync
+	Rsync = launcher.RsyncInfo{Version: "3.4.1", Protocol: 32}
+	t.Cleanup(func() { Rsync = originalRsync })
+	hello = rw.buildHello().GetHello()
+	assert.Equal(t, "3.4.1", hello.GetRsyncVersion())
+	assert.Equal(t, int32(32), hello.GetRsyncProtocol())
+
+	rw.applyMode = ApplyModeSwap
+	rw.maxSnapshots = 3
+	rw.shells.SetEnabled(true)
+	rw.health = NewHealthProber(HealthConfig{URL: "http://localhost:8080/healthz"})
+	rw.envHistory = 10
+	rw.requireApproval = true
+	rw.subtreeWorkers = DefaultSubtreeWorkers
+	assert.Equal(t, []string{FeatureSnapshots, FeatureShell, FeatureSwapApply, FeatureHealthProbe, FeatureEnvHistory, FeatureApproval, FeatureSubtreeBatches},
+		rw.buildHello().GetHello().GetFeatures())
+}
+
+func TestAcceptedMessageTypes_AreHandled(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	for _, messageType := range acceptedMessageTypes {
+		data, err := proto.Marshal(&pb.WebsocketMessage{MessageType: messageType})
+		require.NoError(t, err)
+		err = rw.handleMessage(websocket.BinaryMessage, data, 0)
+		if err != nil {
+			assert.NotContains(t, err.Error(), "unexpected message type", messageType.String())
+		}
+	}
+
+	data, err := proto.Marshal(&pb.WebsocketMessage{MessageType: pb.WebsocketMessage_PUSH_RESPONSE})
+	require.NoError(t, err)
+	assert.ErrorContains(t, rw.handleMessage(websocket.BinaryMessage, data, 0), "unexpected message type")
+}
+
+func TestHandleHelloAck_RecordsServerCapabilities(t *testing.T) {
+	rw := &FileSyncer{}
+	ack := &pb.HelloAck{ProtocolVersion: 1, ServerVersion: "1.2.0", Features: []string{"manifest"}}
+	require.NoError(t, rw.handleHelloAck(ack))
+	assert.Same(t, ack, rw.serverHello)
+}
@@ -0,0 +1,78 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// ChangeEvent lists the paths a push changed, relative to the files directory.
+// Unlike the lists in a PushResponse, they are never cut off.
+type ChangeEvent struct {
+	// Sequence goes up by one with every event, so a consumer can tell it
+	// missed one and fall back to a full rescan.
+	Sequence  int64     `json:"sequence"`
+	PushID    string    `json:"push_id"`
+	WrittenAt time.Time `json:"written_at"`
+	Created   []string  `json:"created"`
+	Modified  []string  `json:"modified"`
+	Deleted   []string  `json:"deleted"`
+}
+
+// newChangeEvent builds the event for a push from rsync's itemized changes,
+// the injected files it wrote and the paths it deleted. Injected files are
+// listed as modified.
+func newChangeEvent(pushID string, changes []itemizedChange, injected []*pb.InjectedFileResult, deletions []*pb.DeletedPathResult) ChangeEvent {
+	event := ChangeEvent{PushID: pushID, Created: []string{}, Modified: []string{}, Deleted: []string{}}
+	for _, change := range changes {
+		switch change.kind() {
+		case changeCreated:
+			event.Created = append(event.Created, change.path)
+		case changeModified:
+			event.Modified = append(event.Modified, change.path)
+		case changeDeleted:
+			event.Deleted = append(event.Deleted, change.path)
+		}
+	}
+	for _, file := range injected {
+		if file.Success {
+			event.Modified = append(event.Modified, file.Path)
+		}
+	}
+	for _, deletion := range deletions {
+		if deletion.Status == pb.DeletedPathResult_REMOVED {
+			event.Deleted = append(event.Deleted, deletion.Path)
+		}
+	}
+	return event
+}
+
+// writeChangeEvent atomically replaces the change event file with event,
+// numbered one after the event it replaces.
+func writeChangeEvent(filesDir string, event ChangeEvent) error {
+	path := launcher.ChangeEventPath(filesDir)
+	var previous ChangeEvent
+	if data, err := os.ReadFile(path); err == nil {
+		// An unreadable previous event restarts the sequence.
+		_ = json.Unmarshal(data, &previous)
+	}
+	event.Sequence = previous.Sequence + 1
+	event.WrittenAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode change event: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write change event %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace change event %s: %w", path, err)
+	}
+	return nil
+}
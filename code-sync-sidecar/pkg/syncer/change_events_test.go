@@ -0,0 +1,65 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func readChangeEvent(t *testing.T, filesDir string) ChangeEvent {
+	t.Helper()
+	var event ChangeEvent
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, launcher.ChangeEventPath(filesDir))), &event))
+	return event
+}
+
+func TestNewChangeEvent(t *testing.T) {
+	changes := parseItemizedChanges([]byte(">f+++++++++ src/new.go\n>f.st...... src/main.go\n.f...p..... src/mode.go\ncd+++++++++ src/pkg\n*deleting   old.go\n"))
+	injected := []*pb.InjectedFileResult{{Path: "config/app.yaml", Success: true}, {Path: "bad.yaml", ErrorMessage: "denied"}}
+	deletions := []*pb.DeletedPathResult{{Path: "tmp/cache", Status: pb.DeletedPathResult_REMOVED}, {Path: "gone", Status: pb.DeletedPathResult_NOT_FOUND}}
+
+	event := newChangeEvent("push-1", changes, injected, deletions)
+	assert.Equal(t, "push-1", event.PushID)
+	assert.Equal(t, []string{"src/new.go"}, event.Created)
+	assert.Equal(t, []string{"src/main.go", "config/app.yaml"}, event.Modified)
+	assert.Equal(t, []string{"old.go", "tmp/cache"}, event.Deleted)
+}
+
+func TestWriteChangeEvent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+
+	require.NoError(t, writeChangeEvent(dir, newChangeEvent("push-1", nil, nil, nil)))
+	event := readChangeEvent(t, dir)
+	assert.Equal(t, int64(1), event.Sequence)
+	assert.False(t, event.WrittenAt.IsZero())
+	assert.Equal(t, []string{}, event.Created, "empty lists are written as []")
+
+	require.NoError(t, writeChangeEvent(dir, newChangeEvent("push-2", nil, nil, nil)))
+	assert.Equal(t, int64(2), readChangeEvent(t, dir).Sequence)
+
+	require.NoError(t, os.WriteFile(launcher.ChangeEventPath(dir), []byte("garbage"), 0644))
+	require.NoError(t, writeChangeEvent(dir, newChangeEvent("push-3", nil, nil, nil)))
+	assert.Equal(t, int64(1), readChangeEvent(t, dir).Sequence, "an unreadable event restarts the sequence")
+}
+
+func TestHandlePushRequest_WritesChangeEvent(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+
+	pushMsg := &pb.PushMessage{PushId: "push-1", Files: map[string]*pb.InjectedFile{"src/app.ts": {Content: []byte("let a = 1;\n")}}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp := waitForPushResponse(t, mockServer)
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+
+	event := readChangeEvent(t, rw.targetSyncDir)
+	assert.Equal(t, int64(1), event.Sequence)
+	assert.Equal(t, "push-1", event.PushID)
+	assert.Equal(t, []string{"src/app.ts"}, event.Modified)
+}
@@ -0,0 +1,1363 @@
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+const (
+	DefaultFilesDir  = "/app-files"
+	DefaultRsyncPath = "/app/bin/rsync"
+
+	// ReloadSignalNone as signals.reload sends the launcher no signal.
+	ReloadSignalNone = "none"
+
+	DefaultReconnectBackoff = 5 * time.Second
+	DefaultShutdownTimeout  = 25 * time.Second
+	DefaultMaxSnapshots     = 5
+	DefaultEnvHistory       = 10
+	DefaultRetryAttempts    = 3
+	DefaultRetryBackoff     = time.Second
+	DefaultMaxDeletePercent = 50
+	DefaultApprovalTimeout  = 24 * time.Hour
+	// DefaultSequenceGapTimeout is how long a push that arrives ahead of its
+	// sequence waits for the pushes before it.
+	DefaultSequenceGapTimeout = 10 * time.Second
+	// DefaultSubtreeWorkers is how many sub-tree batches of a push are applied
+	// at once.
+	DefaultSubtreeWorkers = 4
+
+	DefaultStatusListenAddr = "127.0.0.1:7979"
+
+	DefaultCoordinationListenAddr     = ":7980"
+	DefaultCoordinationLeaseDuration  = 15 * time.Second
+	DefaultCoordinationReplicaTimeout = 2 * time.Minute
+)
+
+// Coordination modes for coordination.mode.
+const (
+	// CoordinationModeNone has every replica connect to the control plane on its own.
+	CoordinationModeNone = ""
+	// CoordinationModeKubernetes elects a leader among the replicas with a
+	// Kubernetes Lease (see package coordination).
+	CoordinationModeKubernetes = "kubernetes"
+)
+
+// Reload strategies for reload.strategy.
+const (
+	// ReloadStrategySignal sends signals.reload to the launcher, which restarts the app.
+	ReloadStrategySignal = "signal"
+	// ReloadStrategyHTTP POSTs the launcher handshake to reload.url, for apps
+	// that reload themselves.
+	ReloadStrategyHTTP = "http"
+	// ReloadStrategyCommand runs reload.command in the sidecar container.
+	ReloadStrategyCommand = "command"
+	// ReloadStrategyRestart stops the launcher and waits for the app container's
+	// restart policy to start it again, for apps that can only start cold.
+	ReloadStrategyRestart = "restart"
+	// ReloadStrategyOverlap has the launcher start the new app alongside the old
+	// one and drain the old one once the new one is ready, for apps that hand
+	// their listening socket over.
+	ReloadStrategyOverlap = "overlap"
+
+	DefaultReloadTimeout      = 60 * time.Second
+	DefaultReloadDrainSignal  = "SIGTERM"
+	DefaultReloadDrainTimeout = 30 * time.Second
+)
+
+// Apply modes for sync.apply_mode.
+const (
+	// ApplyModeInPlace applies each batch directly to the files directory.
+	ApplyModeInPlace = "in_place"
+	// ApplyModeSwap applies each batch to a hard-linked copy of the current release
+	// and then switches the "current" symlink to it.
+	ApplyModeSwap = "swap"
+)
+
+// Config holds the sidecar's settings. Values come from the YAML file named by
+// BIFROST_CONFIG (if set), with BIFROST_* environment variables overriding file values.
+type Config struct {
+	AppID        string                `yaml:"app_id"`
+	DeploymentID string                `yaml:"deployment_id"`
+	API          APIConfig             `yaml:"api"`
+	Sync         SyncConfig            `yaml:"sync"`
+	Quota        QuotaConfig           `yaml:"quota"`
+	Resources    ResourcesConfig       `yaml:"resources"`
+	Signals      SignalsConfig         `yaml:"signals"`
+	Reload       ReloadConfig          `yaml:"reload"`
+	Timeouts     TimeoutsConfig        `yaml:"timeouts"`
+	Shell        ShellConfig           `yaml:"shell"`
+	Health       HealthConfig          `yaml:"health"`
+	Install      InstallConfig         `yaml:"install"`
+	Build        BuildConfig           `yaml:"build"`
+	Warm         WarmConfig            `yaml:"warm"`
+	Status       StatusConfig          `yaml:"status"`
+	Readiness    ReadinessConfig       `yaml:"readiness"`
+	Coordination CoordinationConfig    `yaml:"coordination"`
+	Database     DatabaseConfig        `yaml:"database"`
+	Secrets      envfile.SecretsConfig `yaml:"secrets"`
+	Env          EnvConfig             `yaml:"env"`
+	Permissions  PermissionsConfig     `yaml:"permissions"`
+	Security     SecurityConfig        `yaml:"security"`
+	Webhooks     WebhooksConfig        `yaml:"webhooks"`
+	Metrics      MetricsConfig         `yaml:"metrics"`
+	Log          LogConfig             `yaml:"log"`
+}
+
+// APIConfig configures how the sidecar reaches and authenticates to the Bifrost API.
+type APIConfig struct {
+	URL               string `yaml:"url"`
+	AuthMode          string `yaml:"auth_mode"`
+	APIKey            string `yaml:"api_key"`
+	IdentityTokenPath string `yaml:"identity_token_path"`
+}
+
+// SyncConfig configures the directories the sidecar works with.
+type SyncConfig struct {
+	FilesDir  string `yaml:"files_dir"`
+	HooksDir  string `yaml:"hooks_dir"`
+	AppLogDir string `yaml:"app_log_dir"`
+	ApplyMode string `yaml:"apply_mode"`
+	// RsyncPath is the rsync binary that applies batches, in the sidecar's image.
+	RsyncPath string `yaml:"rsync_path"`
+	// MaxSnapshots is how many workspace snapshots are kept; 0 disables snapshots.
+	MaxSnapshots int `yaml:"max_snapshots"`
+	// SnapshotRetention is how long snapshots are kept; 0 keeps them until MaxSnapshots is reached.
+	SnapshotRetention Duration `yaml:"snapshot_retention"`
+	// EnvHistory is how many versions of the env files are kept under
+	// .sidecar/env-history for rollback; 0 disables the history.
+	EnvHistory int `yaml:"env_history"`
+	// PushDebounce is how long the sidecar waits for further pushes before
+	// applying one, so a burst is applied once; 0 applies every push right away.
+	PushDebounce Duration `yaml:"push_debounce"`
+	// SequenceGapTimeout is how long a push that arrives ahead of its sequence
+	// waits for the missing pushes before it is applied anyway; 0 applies it
+	// right away.
+	SequenceGapTimeout Duration `yaml:"sequence_gap_timeout"`
+	// PushWindows are when pushes may be applied, e.g. "Mon-Fri 09:00-17:00"; a
+	// push arriving outside them is held until the next one opens. Empty
+	// applies pushes whenever they arrive.
+	PushWindows []string `yaml:"push_windows"`
+	// PushWindowTimezone is the IANA time zone of PushWindows (default UTC).
+	PushWindowTimezone string `yaml:"push_window_timezone"`
+	// RequireApproval holds each push staged, with apply_mode swap, until an
+	// ACTIVATE message approves it, so a person can review a change before the
+	// app runs it. ApprovalTimeout is how long a staged push waits for approval
+	// before it is discarded; 0 waits until it is approved or cancelled.
+	RequireApproval bool     `yaml:"require_approval"`
+	ApprovalTimeout Duration `yaml:"approval_timeout"`
+	// RetryAttempts is how many times rsync and the reload signal are tried when
+	// they fail transiently; 1 never retries. RetryBackoff is the wait before
+	// the first retry, doubled for each one after.
+	RetryAttempts int      `yaml:"retry_attempts"`
+	RetryBackoff  Duration `yaml:"retry_backoff"`
+	// ProtectedPaths are rsync filter patterns, e.g. "/data/" or "*.sqlite",
+	// for paths that pushes deleting with rsync (mirror pushes and the --delete
+	// flag) never remove. The .sidecar and .launcher directories are always protected.
+	ProtectedPaths []string `yaml:"protected_paths"`
+	// MaxDeletePercent is the largest share of the deployment's files and
+	// directories that such a push may remove without force; 0 disables the check.
+	MaxDeletePercent int `yaml:"max_delete_percent"`
+	// BatchCacheSize caps the received batches kept in .sidecar/cache, so a
+	// push the control plane sends again by its batch hash needn't carry the
+	// batch; 0 disables the cache.
+	BatchCacheSize ByteSize `yaml:"batch_cache_size"`
+	// SubtreeWorkers is how many of a push's sub-tree batches are applied at
+	// once; 0 refuses pushes split into sub-trees.
+	SubtreeWorkers int `yaml:"subtree_workers"`
+}
+
+// QuotaConfig limits the size of the synced files, excluding the sidecar's and
+// the launcher's internals. A limit of 0 isn't enforced.
+type QuotaConfig struct {
+	// SoftBytes and SoftInodes add a warning to push responses and status
+	// reports once exceeded.
+	SoftBytes  ByteSize `yaml:"soft_bytes"`
+	SoftInodes int      `yaml:"soft_inodes"`
+	// HardBytes and HardInodes reject a push with QUOTA_EXCEEDED if the
+	// workspace exceeds them once its files are applied.
+	HardBytes  ByteSize `yaml:"hard_bytes"`
+	HardInodes int      `yaml:"hard_inodes"`
+}
+
+// ResourcesConfig keeps the sidecar from competing with the app it serves for
+// CPU, disk and memory.
+type ResourcesConfig struct {
+	// RsyncNice is the nice value, 0 to 19, that rsync applying a push runs at;
+	// 0 leaves it unchanged.
+	RsyncNice int `yaml:"rsync_nice"`
+	// RsyncIOClass is rsync's I/O scheduling class: "" leaves it unchanged,
+	// "best-effort" runs it at the lowest best-effort priority and "idle" lets
+	// it use the disk only when nothing else does.
+	RsyncIOClass string `yaml:"rsync_io_class"`
+	// MemoryLimit is a soft limit on the sidecar's memory; 0 uses 90% of the
+	// container's cgroup limit, if it has one.
+	MemoryLimit ByteSize `yaml:"memory_limit"`
+	// BatchMemoryBuffer bounds the batches held in memory at once; a batch that
+	// doesn't fit is written to .sidecar/spool instead. 0 uses half of
+	// MemoryLimit.
+	BatchMemoryBuffer ByteSize `yaml:"batch_memory_buffer"`
+}
+
+// SignalsConfig configures the signals sent to the launcher.
+type SignalsConfig struct {
+	// Reload is sent to the launcher once a push is applied; "none" sends
+	// nothing, leaving the launcher to notice the handshake file.
+	Reload string `yaml:"reload"`
+	// Target is which processes the reload signal reaches: "process" (the
+	// launcher only), "group" (its process group) or "tree" (the launcher and
+	// every descendant).
+	Target string `yaml:"target"`
+	// Shutdown is forwarded to the launcher when the sidecar is asked to stop;
+	// empty leaves the launcher running.
+	Shutdown string `yaml:"shutdown"`
+	// Launchers overrides Reload and Target for launchers started with
+	// BIFROST_LAUNCHER_NAME, keyed by that name, in deployments that run more
+	// than one. An empty field, or a launcher without an entry, gets the
+	// setting above.
+	Launchers map[string]LauncherSignals `yaml:"launchers"`
+}
+
+// LauncherSignals configures the reload signal of one named launcher.
+type LauncherSignals struct {
+	Reload string `yaml:"reload"`
+	Target string `yaml:"target"`
+}
+
+// launcherNamePattern matches the names the launcher script accepts in
+// BIFROST_LAUNCHER_NAME.
+var launcherNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ReloadConfig configures how the app is told to pick up a push once it is
+// applied and the launcher handshake is written.
+type ReloadConfig struct {
+	// Strategy is "signal" (send signals.reload to the launcher), "http" (POST
+	// the handshake to URL), "command" (run Command), "restart" (restart the
+	// launcher through the app container's restart policy) or "overlap" (start
+	// the new app before draining the old one).
+	Strategy string `yaml:"strategy"`
+	// URL is the app endpoint the http strategy calls.
+	URL string `yaml:"url"`
+	// Command is run with /bin/sh in the files directory by the command strategy.
+	Command string `yaml:"command"`
+	// Timeout bounds the HTTP call, the command, the wait for the restarted
+	// launcher, or the wait for an overlapping new app to become ready.
+	Timeout Duration `yaml:"timeout"`
+	// DrainSignal is sent to the old app once an overlapping new app is ready.
+	DrainSignal string `yaml:"drain_signal"`
+	// DrainTimeout is how long the old app may take to drain before the
+	// launcher kills it.
+	DrainTimeout Duration `yaml:"drain_timeout"`
+}
+
+// TimeoutsConfig configures timeouts and intervals.
+type TimeoutsConfig struct {
+	Hook             Duration `yaml:"hook"`
+	StatusInterval   Duration `yaml:"status_interval"`
+	ReconnectBackoff Duration `yaml:"reconnect_backoff"`
+	GCInterval       Duration `yaml:"gc_interval"`
+	Rsync            Duration `yaml:"rsync"`
+	// RsyncStall is how long rsync may use no CPU and do no I/O before it is
+	// killed and the push fails with STALLED; 0 disables the watchdog.
+	RsyncStall Duration `yaml:"rsync_stall"`
+	// Shutdown is how long the sidecar waits for the launcher to exit after
+	// forwarding signals.shutdown.
+	Shutdown Duration `yaml:"shutdown"`
+}
+
+// HealthConfig configures the probe that checks the app is healthy again after
+// a push reloads it. At most one of URL and TCPAddress may be set; with neither,
+// a push completes as soon as the launcher is signalled.
+type HealthConfig struct {
+	URL        string   `yaml:"url"`
+	TCPAddress string   `yaml:"tcp_address"`
+	Timeout    Duration `yaml:"timeout"`
+	Interval   Duration `yaml:"interval"`
+}
+
+// InstallConfig configures installing dependencies when a push changes a
+// manifest such as package.json or requirements.txt.
+type InstallConfig struct {
+	// Command is run with sh once a push's files are applied, before the
+	// build command, if they include a manifest; empty runs nothing.
+	Command string `yaml:"command"`
+	// Manifests are the file names, or patterns such as requirements*.txt,
+	// that count as manifests wherever they are in the tree.
+	Manifests []string `yaml:"manifests"`
+	Timeout   Duration `yaml:"timeout"`
+}
+
+// BuildConfig configures the build step some stacks need after their files
+// change, such as tsc or webpack.
+type BuildConfig struct {
+	// Command is run with sh once a push's files are applied, before the app
+	// is reloaded; empty runs nothing.
+	Command string   `yaml:"command"`
+	Timeout Duration `yaml:"timeout"`
+}
+
+// WarmConfig configures warming the page cache once a push's files are
+// applied, before the app is reloaded, for volumes where reading files cold is
+// slow, such as network-backed ones.
+type WarmConfig struct {
+	// ReadFiles reads the files the push changed.
+	ReadFiles bool `yaml:"read_files"`
+	// Command is run with sh instead, with the changed files on its stdin;
+	// empty runs nothing.
+	Command string   `yaml:"command"`
+	Timeout Duration `yaml:"timeout"`
+}
+
+// StatusConfig configures the local status endpoint that `code-sync-sidecar
+// status` queries.
+type StatusConfig struct {
+	// ListenAddr is the host:port it listens on; empty disables it.
+	ListenAddr string `yaml:"listen_addr"`
+	// ControlSocket serves the JSON-RPC API of package control on
+	// .sidecar/control.sock, for the launcher and the app.
+	ControlSocket bool `yaml:"control_socket"`
+	// File keeps .sidecar/status.json up to date for other containers in the
+	// pod, rewriting it at least every FileInterval.
+	File         bool     `yaml:"file"`
+	FileInterval Duration `yaml:"file_interval"`
+}
+
+// ReadinessConfig configures the marker file the app container's readiness probe
+// can check (see ReadinessMarker).
+type ReadinessConfig struct {
+	// File is the marker's absolute path, normally on the shared volume; empty
+	// disables it.
+	File string `yaml:"file"`
+	// UnreadyDuringPush removes the marker while a push is applied and the app
+	// reloads, and restores it once the push has finished.
+	UnreadyDuringPush bool `yaml:"unready_during_push"`
+}
+
+// CoordinationConfig configures coordination between the sidecars of a
+// deployment's replicas. The leader connects to the control plane and forwards
+// each push to the others, then reports one result covering all of them.
+type CoordinationConfig struct {
+	// Mode is "" (each replica connects on its own) or "kubernetes".
+	Mode string `yaml:"mode"`
+	// LeaseName is the Lease the replicas compete for; it defaults to
+	// "bifrost-sidecar-<deployment_id>".
+	LeaseName     string   `yaml:"lease_name"`
+	LeaseDuration Duration `yaml:"lease_duration"`
+	// ListenAddr is where the leader accepts the other replicas' connections.
+	ListenAddr string `yaml:"listen_addr"`
+	// AdvertiseAddr is the host:port the other replicas reach ListenAddr on; it
+	// defaults to BIFROST_POD_IP with ListenAddr's port.
+	AdvertiseAddr string `yaml:"advertise_addr"`
+	// PeerTokenPath is a file holding a secret shared by the replicas, which
+	// they present to the leader; empty accepts any connection.
+	PeerTokenPath string `yaml:"peer_token_path"`
+	// ReplicaTimeout is how long the leader waits for the other replicas to
+	// finish a push before reporting them as unknown.
+	ReplicaTimeout Duration `yaml:"replica_timeout"`
+}
+
+// DatabaseConfig configures where the database env vars come from and what
+// happens when a push switches the app to a new database branch.
+type DatabaseConfig struct {
+	// Provider is the source of the database env vars: "bifrost" (the Bifrost
+	// API, which follows database branches), "aws_secrets_manager" or
+	// "kubernetes_secret".
+	Provider   string                         `yaml:"provider"`
+	AWS        envfile.AWSSecretsConfig       `yaml:"aws"`
+	Kubernetes envfile.KubernetesSecretConfig `yaml:"kubernetes"`
+	// MigrationCommand is run with sh for each database branch a push created,
+	// before the app is reloaded to use it; empty runs nothing.
+	MigrationCommand string   `yaml:"migration_command"`
+	MigrationTimeout Duration `yaml:"migration_timeout"`
+}
+
+// EnvConfig configures the env file the launcher sources before starting the app.
+type EnvConfig struct {
+	// EncryptionKeyPath is a key file mounted into both the sidecar and the app
+	// container. When set, the env file is written encrypted with it.
+	EncryptionKeyPath string `yaml:"encryption_key_path"`
+}
+
+// PermissionsConfig maps the ownership and permissions of the files a push
+// writes, for apps that run as a different user than the sidecar.
+type PermissionsConfig struct {
+	// UID and GID that changed files are chowned to; -1 leaves them unchanged.
+	UID int `yaml:"uid"`
+	GID int `yaml:"gid"`
+	// AddMode and RemoveMode are octal mode bits set on and cleared from changed
+	// files and directories, e.g. "0060" and "0007".
+	AddMode    string `yaml:"add_mode"`
+	RemoveMode string `yaml:"remove_mode"`
+}
+
+// SecurityConfig configures hardened mode, where the sidecar runs as a non-root
+// user and shares the volume with the app through a group instead of
+// world-writable permissions.
+type SecurityConfig struct {
+	Hardened bool `yaml:"hardened"`
+	// SharedGID is the group shared with the app container; -1 uses the files directory's group.
+	SharedGID int `yaml:"shared_gid"`
+	// PreserveLabels gives files a push replaces the security labels, such as
+	// the SELinux context, of the files they replace.
+	PreserveLabels bool `yaml:"preserve_labels"`
+}
+
+// WebhooksConfig configures outbound webhooks the sidecar posts its events to:
+// pushes applied and failed, drift detected and the launcher crashing.
+type WebhooksConfig struct {
+	// URLs are posted every event; empty disables webhooks.
+	URLs []string `yaml:"urls"`
+	// SecretPath is a file holding the key requests are signed with; empty
+	// leaves them unsigned.
+	SecretPath string `yaml:"secret_path"`
+	// Events limits the events posted, e.g. ["push.failed"]; empty posts all.
+	Events  []string `yaml:"events"`
+	Timeout Duration `yaml:"timeout"`
+}
+
+// MetricsConfig configures the sink the sidecar sends its push, rsync and
+// connection metrics to.
+type MetricsConfig struct {
+	// Sink is "statsd", "dogstatsd" (StatsD with tags, for the Datadog agent)
+	// or empty to send none.
+	Sink string `yaml:"sink"`
+	// Address is the server's host:port; empty uses DD_AGENT_HOST and
+	// DD_DOGSTATSD_PORT, or else 127.0.0.1:8125.
+	Address string `yaml:"address"`
+	// Prefix is prepended to every metric name.
+	Prefix string `yaml:"prefix"`
+	// Tags are "key:value" tags added to every metric, after app_id,
+	// deployment_id and pod.
+	Tags []string `yaml:"tags"`
+	// Interval is how often the connection's gauges are sent.
+	Interval Duration `yaml:"interval"`
+}
+
+// ShellConfig configures remote shell sessions.
+type ShellConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// LogConfig configures the sidecar's own logging.
+type LogConfig struct {
+	// Level overrides LOG_LEVEL when set.
+	Level string `yaml:"level"`
+	// Ship sends the sidecar's own logs upstream as SIDECAR_LOG messages.
+	Ship      bool   `yaml:"ship"`
+	ShipLevel string `yaml:"ship_level"`
+	// Wire logs every websocket message decoded, with secrets redacted.
+	Wire bool `yaml:"wire"`
+}
+
+// Duration is a time.Duration that is written as a Go duration string ("30s") in YAML.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q (use a value like \"30s\" or \"2m\")", node.Line, node.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ByteSize is a size in bytes that is written as a number with an optional
+// unit ("512MiB", "2GB") in YAML.
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := parseByteSize(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid size %q (use a value like \"500MB\" or \"2GiB\")", node.Line, node.Value)
+	}
+	*b = ByteSize(parsed)
+	return nil
+}
+
+// byteUnits are the size suffixes parseByteSize accepts, longest first so "MiB"
+// isn't read as "B".
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseByteSize parses a whole number of bytes with an optional unit.
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = strings.TrimSpace(number), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt64/multiplier || n < math.MinInt64/multiplier {
+		return 0, fmt.Errorf("size %s out of range", value)
+	}
+	return n * multiplier, nil
+}
+
+// DefaultConfig returns the settings used when neither the config file nor the environment sets a value.
+func DefaultConfig() *Config {
+	return &Config{
+		API: APIConfig{
+			AuthMode: string(transport.AuthModeAPIKey),
+		},
+		Sync: SyncConfig{
+			FilesDir:           DefaultFilesDir,
+			ApplyMode:          ApplyModeInPlace,
+			RsyncPath:          DefaultRsyncPath,
+			MaxSnapshots:       DefaultMaxSnapshots,
+			SnapshotRetention:  Duration(DefaultSnapshotRetention),
+			EnvHistory:         DefaultEnvHistory,
+			RetryAttempts:      DefaultRetryAttempts,
+			RetryBackoff:       Duration(DefaultRetryBackoff),
+			MaxDeletePercent:   DefaultMaxDeletePercent,
+			BatchCacheSize:     DefaultBatchCacheSize,
+			ApprovalTimeout:    Duration(DefaultApprovalTimeout),
+			SequenceGapTimeout: Duration(DefaultSequenceGapTimeout),
+			SubtreeWorkers:     DefaultSubtreeWorkers,
+		},
+		Signals: SignalsConfig{
+			Reload: DefaultReloadSignal,
+			Target: string(launcher.SignalProcess),
+		},
+		Reload: ReloadConfig{
+			Strategy:     ReloadStrategySignal,
+			Timeout:      Duration(DefaultReloadTimeout),
+			DrainSignal:  DefaultReloadDrainSignal,
+			DrainTimeout: Duration(DefaultReloadDrainTimeout),
+		},
+		Timeouts: TimeoutsConfig{
+			Hook:             Duration(DefaultHookTimeout),
+			StatusInterval:   Duration(DefaultStatusInterval),
+			ReconnectBackoff: Duration(DefaultReconnectBackoff),
+			GCInterval:       Duration(DefaultGCInterval),
+			Rsync:            Duration(DefaultRsyncTimeout),
+			RsyncStall:       Duration(DefaultRsyncStallTimeout),
+			Shutdown:         Duration(DefaultShutdownTimeout),
+		},
+		Health: HealthConfig{
+			Timeout:  Duration(DefaultHealthTimeout),
+			Interval: Duration(DefaultHealthInterval),
+		},
+		Status: StatusConfig{
+			ListenAddr:    DefaultStatusListenAddr,
+			ControlSocket: true,
+			File:          true,
+			FileInterval:  Duration(DefaultStatusFileInterval),
+		},
+		Coordination: CoordinationConfig{
+			LeaseDuration:  Duration(DefaultCoordinationLeaseDuration),
+			ListenAddr:     DefaultCoordinationListenAddr,
+			ReplicaTimeout: Duration(DefaultCoordinationReplicaTimeout),
+		},
+		Install: InstallConfig{
+			Manifests: slices.Clone(DefaultInstallManifests),
+			Timeout:   Duration(DefaultInstallTimeout),
+		},
+		Build: BuildConfig{
+			Timeout: Duration(DefaultBuildTimeout),
+		},
+		Warm: WarmConfig{
+			Timeout: Duration(DefaultWarmTimeout),
+		},
+		Database: DatabaseConfig{
+			Provider:         envfile.ProviderBifrost,
+			MigrationTimeout: Duration(DefaultMigrationTimeout),
+		},
+		Permissions: PermissionsConfig{
+			UID: -1,
+			GID: -1,
+		},
+		Security: SecurityConfig{
+			SharedGID: -1,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout: Duration(DefaultWebhookTimeout),
+		},
+		Metrics: MetricsConfig{
+			Prefix:   DefaultMetricsPrefix,
+			Interval: Duration(DefaultMetricsInterval),
+		},
+	}
+}
+
+// LoadConfig builds the sidecar configuration from the optional BIFROST_CONFIG
+// file and the environment, and validates the result.
+func LoadConfig() (*Config, error) {
+	cfg, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfig builds the configuration like LoadConfig but without validating
+// it, for commands that only need a few settings.
+func ReadConfig() (*Config, error) {
+	cfg := DefaultConfig()
+
+	if path := os.Getenv("BIFROST_CONFIG"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if cfg.Sync.HooksDir == "" {
+		cfg.Sync.HooksDir = getHooksDir(cfg.Sync.FilesDir)
+	}
+	if cfg.Database.Provider == envfile.ProviderAWSSecretsManager && cfg.Database.AWS.Region == "" {
+		cfg.Database.AWS.Region = os.Getenv("AWS_REGION")
+		if cfg.Database.AWS.Region == "" {
+			cfg.Database.AWS.Region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+	}
+	if cfg.Coordination.Mode != CoordinationModeNone {
+		if cfg.Coordination.LeaseName == "" && cfg.DeploymentID != "" {
+			cfg.Coordination.LeaseName = "bifrost-sidecar-" + strings.ToLower(cfg.DeploymentID)
+		}
+		if cfg.Coordination.AdvertiseAddr == "" {
+			cfg.Coordination.AdvertiseAddr = defaultAdvertiseAddr(cfg.Coordination.ListenAddr)
+		}
+	}
+	return cfg, nil
+}
+
+// defaultAdvertiseAddr joins the pod IP from the downward API with the port of
+// listenAddr, or returns "" if either is missing.
+func defaultAdvertiseAddr(listenAddr string) string {
+	ip := os.Getenv(podIPEnv)
+	_, port, err := net.SplitHostPort(listenAddr)
+	if ip == "" || err != nil || port == "" {
+		return ""
+	}
+	return net.JoinHostPort(ip, port)
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s (set by BIFROST_CONFIG): %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// Reject unknown keys so typos surface instead of being silently ignored.
+	decoder.KnownFields(true)
+	// An empty file decodes to io.EOF; treat it as "no overrides".
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides config values with any BIFROST_* environment variables that are set.
+func (c *Config) applyEnv() error {
+	envString(&c.AppID, "BIFROST_APP_ID")
+	envString(&c.DeploymentID, "BIFROST_DEPLOYMENT_ID")
+	envString(&c.API.URL, "BIFROST_API_URL")
+	envString(&c.API.AuthMode, "BIFROST_AUTH_MODE")
+	envString(&c.API.APIKey, "BIFROST_API_KEY")
+	envString(&c.API.IdentityTokenPath, "BIFROST_IDENTITY_TOKEN_PATH")
+	envString(&c.Sync.FilesDir, "BIFROST_FILES_DIR")
+	envString(&c.Sync.HooksDir, "BIFROST_HOOKS_DIR")
+	envString(&c.Sync.AppLogDir, "BIFROST_APP_LOG_DIR")
+	envString(&c.Sync.ApplyMode, "BIFROST_APPLY_MODE")
+	envString(&c.Signals.Reload, "BIFROST_RELOAD_SIGNAL")
+	envString(&c.Signals.Target, "BIFROST_SIGNAL_TARGET")
+	envString(&c.Signals.Shutdown, "BIFROST_SHUTDOWN_SIGNAL")
+	envString(&c.Reload.Strategy, "BIFROST_RELOAD_STRATEGY")
+	envString(&c.Reload.URL, "BIFROST_RELOAD_URL")
+	envString(&c.Reload.Command, "BIFROST_RELOAD_COMMAND")
+	envString(&c.Reload.DrainSignal, "BIFROST_RELOAD_DRAIN_SIGNAL")
+	envString(&c.Health.URL, "BIFROST_HEALTH_URL")
+	envString(&c.Health.TCPAddress, "BIFROST_HEALTH_TCP_ADDRESS")
+	envString(&c.Status.ListenAddr, "BIFROST_STATUS_ADDR")
+	envString(&c.Readiness.File, "BIFROST_READINESS_FILE")
+	envString(&c.Database.Provider, "BIFROST_DATABASE_PROVIDER")
+	envString(&c.Database.AWS.Region, "BIFROST_DATABASE_AWS_REGION")
+	envString(&c.Database.Kubernetes.Name, "BIFROST_DATABASE_KUBERNETES_SECRET")
+	envString(&c.Database.MigrationCommand, "BIFROST_MIGRATION_COMMAND")
+	envString(&c.Install.Command, "BIFROST_INSTALL_COMMAND")
+	envString(&c.Build.Command, "BIFROST_BUILD_COMMAND")
+	envString(&c.Warm.Command, "BIFROST_WARM_COMMAND")
+	envString(&c.Webhooks.SecretPath, "BIFROST_WEBHOOK_SECRET_PATH")
+	envString(&c.Metrics.Sink, "BIFROST_METRICS_SINK")
+	envString(&c.Metrics.Address, "BIFROST_METRICS_ADDRESS")
+	envString(&c.Metrics.Prefix, "BIFROST_METRICS_PREFIX")
+	if id := os.Getenv("BIFROST_DATABASE_AWS_SECRET_ID"); id != "" {
+		c.Database.AWS.Secrets = []envfile.AWSSecret{{EnvVar: "DATABASE_URL", SecretID: id}}
+	}
+	envString(&c.Coordination.Mode, "BIFROST_COORDINATION_MODE")
+	envString(&c.Coordination.LeaseName, "BIFROST_COORDINATION_LEASE_NAME")
+	envString(&c.Coordination.ListenAddr, "BIFROST_COORDINATION_LISTEN_ADDR")
+	envString(&c.Coordination.AdvertiseAddr, "BIFROST_COORDINATION_ADVERTISE_ADDR")
+	envString(&c.Coordination.PeerTokenPath, "BIFROST_COORDINATION_PEER_TOKEN_PATH")
+	envString(&c.Secrets.Vault.Address, "BIFROST_VAULT_ADDR")
+	envString(&c.Secrets.Vault.TokenPath, "BIFROST_VAULT_TOKEN_PATH")
+	envString(&c.Secrets.Vault.Namespace, "BIFROST_VAULT_NAMESPACE")
+	envString(&c.Env.EncryptionKeyPath, "BIFROST_ENV_KEY_PATH")
+	envString(&c.Permissions.AddMode, "BIFROST_FILE_MODE_ADD")
+	envString(&c.Permissions.RemoveMode, "BIFROST_FILE_MODE_REMOVE")
+	envString(&c.Log.Level, "BIFROST_LOG_LEVEL")
+	envString(&c.Log.ShipLevel, "BIFROST_LOG_SHIP_LEVEL")
+	envList(&c.Sync.ProtectedPaths, "BIFROST_PROTECTED_PATHS")
+	envList(&c.Install.Manifests, "BIFROST_INSTALL_MANIFESTS")
+	envList(&c.Webhooks.URLs, "BIFROST_WEBHOOK_URLS")
+	envList(&c.Webhooks.Events, "BIFROST_WEBHOOK_EVENTS")
+	envList(&c.Metrics.Tags, "BIFROST_METRICS_TAGS")
+	// Windows may list days with commas, so they are separated by semicolons.
+	envSplit(&c.Sync.PushWindows, "BIFROST_PUSH_WINDOWS", ";")
+	envString(&c.Sync.PushWindowTimezone, "BIFROST_PUSH_WINDOW_TIMEZONE")
+	envString(&c.Sync.RsyncPath, "BIFROST_RSYNC_PATH")
+	envString(&c.Resources.RsyncIOClass, "BIFROST_RSYNC_IO_CLASS")
+
+	return errors.Join(
+		envDuration(&c.Timeouts.Hook, "BIFROST_HOOK_TIMEOUT"),
+		envDuration(&c.Timeouts.StatusInterval, "BIFROST_STATUS_INTERVAL"),
+		envDuration(&c.Status.FileInterval, "BIFROST_STATUS_FILE_INTERVAL"),
+		envDuration(&c.Timeouts.ReconnectBackoff, "BIFROST_RECONNECT_BACKOFF"),
+		envDuration(&c.Timeouts.GCInterval, "BIFROST_GC_INTERVAL"),
+		envDuration(&c.Timeouts.Rsync, "BIFROST_RSYNC_TIMEOUT"),
+		envDuration(&c.Timeouts.RsyncStall, "BIFROST_RSYNC_STALL_TIMEOUT"),
+		envDuration(&c.Timeouts.Shutdown, "BIFROST_SHUTDOWN_TIMEOUT"),
+		envDuration(&c.Reload.Timeout, "BIFROST_RELOAD_TIMEOUT"),
+		envDuration(&c.Reload.DrainTimeout, "BIFROST_RELOAD_DRAIN_TIMEOUT"),
+		envDuration(&c.Sync.SnapshotRetention, "BIFROST_SNAPSHOT_RETENTION"),
+		envDuration(&c.Sync.PushDebounce, "BIFROST_PUSH_DEBOUNCE"),
+		envDuration(&c.Sync.SequenceGapTimeout, "BIFROST_SEQUENCE_GAP_TIMEOUT"),
+		envDuration(&c.Sync.ApprovalTimeout, "BIFROST_APPROVAL_TIMEOUT"),
+		envDuration(&c.Sync.RetryBackoff, "BIFROST_RETRY_BACKOFF"),
+		envDuration(&c.Health.Timeout, "BIFROST_HEALTH_TIMEOUT"),
+		envDuration(&c.Health.Interval, "BIFROST_HEALTH_INTERVAL"),
+		envDuration(&c.Database.MigrationTimeout, "BIFROST_MIGRATION_TIMEOUT"),
+		envDuration(&c.Install.Timeout, "BIFROST_INSTALL_TIMEOUT"),
+		envDuration(&c.Build.Timeout, "BIFROST_BUILD_TIMEOUT"),
+		envDuration(&c.Warm.Timeout, "BIFROST_WARM_TIMEOUT"),
+		envDuration(&c.Webhooks.Timeout, "BIFROST_WEBHOOK_TIMEOUT"),
+		envDuration(&c.Metrics.Interval, "BIFROST_METRICS_INTERVAL"),
+		envDuration(&c.Coordination.LeaseDuration, "BIFROST_COORDINATION_LEASE_DURATION"),
+		envDuration(&c.Coordination.ReplicaTimeout, "BIFROST_COORDINATION_REPLICA_TIMEOUT"),
+		envInt(&c.Sync.MaxSnapshots, "BIFROST_MAX_SNAPSHOTS"),
+		envInt(&c.Sync.EnvHistory, "BIFROST_ENV_HISTORY"),
+		envInt(&c.Sync.RetryAttempts, "BIFROST_RETRY_ATTEMPTS"),
+		envInt(&c.Sync.MaxDeletePercent, "BIFROST_MAX_DELETE_PERCENT"),
+		envByteSize(&c.Sync.BatchCacheSize, "BIFROST_BATCH_CACHE_SIZE"),
+		envInt(&c.Sync.SubtreeWorkers, "BIFROST_SUBTREE_WORKERS"),
+		envByteSize(&c.Quota.SoftBytes, "BIFROST_QUOTA_SOFT_BYTES"),
+		envByteSize(&c.Quota.HardBytes, "BIFROST_QUOTA_HARD_BYTES"),
+		envInt(&c.Quota.SoftInodes, "BIFROST_QUOTA_SOFT_INODES"),
+		envInt(&c.Quota.HardInodes, "BIFROST_QUOTA_HARD_INODES"),
+		envInt(&c.Resources.RsyncNice, "BIFROST_RSYNC_NICE"),
+		envByteSize(&c.Resources.MemoryLimit, "BIFROST_MEMORY_LIMIT"),
+		envByteSize(&c.Resources.BatchMemoryBuffer, "BIFROST_BATCH_MEMORY_BUFFER"),
+		envInt(&c.Permissions.UID, "BIFROST_FILE_UID"),
+		envInt(&c.Permissions.GID, "BIFROST_FILE_GID"),
+		envInt(&c.Security.SharedGID, "BIFROST_SHARED_GID"),
+		envBool(&c.Security.Hardened, "BIFROST_HARDENED"),
+		envBool(&c.Security.PreserveLabels, "BIFROST_PRESERVE_LABELS"),
+		envBool(&c.Shell.Enabled, "BIFROST_SHELL_ENABLED"),
+		envBool(&c.Log.Ship, "BIFROST_LOG_SHIP"),
+		envBool(&c.Log.Wire, "BIFROST_LOG_WIRE"),
+		envBool(&c.Status.ControlSocket, "BIFROST_CONTROL_SOCKET"),
+		envBool(&c.Status.File, "BIFROST_STATUS_FILE"),
+		envBool(&c.Readiness.UnreadyDuringPush, "BIFROST_READINESS_UNREADY_DURING_PUSH"),
+		envBool(&c.Sync.RequireApproval, "BIFROST_REQUIRE_APPROVAL"),
+		envBool(&c.Warm.ReadFiles, "BIFROST_WARM_READ_FILES"),
+	)
+}
+
+// Validate checks the configuration and reports every problem found at once.
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value, key, env string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, fmt.Sprintf("%s is required: set %s in the config file or the %s environment variable", key, key, env))
+		}
+	}
+
+	require(c.AppID, "app_id", "BIFROST_APP_ID")
+	require(c.DeploymentID, "deployment_id", "BIFROST_DEPLOYMENT_ID")
+	require(c.API.URL, "api.url", "BIFROST_API_URL")
+	require(c.Sync.FilesDir, "sync.files_dir", "BIFROST_FILES_DIR")
+
+	if c.API.URL != "" {
+		if u, err := url.Parse(c.API.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("api.url %q must be an absolute http:// or https:// URL", c.API.URL))
+		}
+	}
+
+	authMode, err := transport.ParseAuthMode(c.API.AuthMode)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("api.auth_mode: %v", err))
+	}
+	switch authMode {
+	case transport.AuthModeAPIKey:
+		require(c.API.APIKey, "api.api_key", "BIFROST_API_KEY")
+	case transport.AuthModeOIDC:
+		require(c.API.IdentityTokenPath, "api.identity_token_path", "BIFROST_IDENTITY_TOKEN_PATH")
+	}
+
+	if c.Sync.ApplyMode != ApplyModeInPlace && c.Sync.ApplyMode != ApplyModeSwap {
+		problems = append(problems, fmt.Sprintf("sync.apply_mode %q must be %q or %q", c.Sync.ApplyMode, ApplyModeInPlace, ApplyModeSwap))
+	}
+	if c.Sync.MaxSnapshots < 0 {
+		problems = append(problems, "sync.max_snapshots must not be negative (use 0 to disable snapshots)")
+	}
+	if c.Sync.EnvHistory < 0 {
+		problems = append(problems, "sync.env_history must not be negative (use 0 to disable the env history)")
+	}
+	if c.Sync.SnapshotRetention < 0 {
+		problems = append(problems, "sync.snapshot_retention must not be negative (use 0 to keep snapshots until max_snapshots is reached)")
+	}
+	if c.Sync.PushDebounce < 0 {
+		problems = append(problems, "sync.push_debounce must not be negative (use 0 to apply every push right away)")
+	}
+	if c.Sync.SequenceGapTimeout < 0 {
+		problems = append(problems, "sync.sequence_gap_timeout must not be negative (use 0 to apply pushes past a gap right away)")
+	}
+	if c.Sync.RequireApproval && c.Sync.ApplyMode != ApplyModeSwap {
+		problems = append(problems, fmt.Sprintf("sync.require_approval needs sync.apply_mode %q, to stage pushes until they are approved", ApplyModeSwap))
+	}
+	if c.Sync.RequireApproval && c.Coordination.Mode != CoordinationModeNone {
+		problems = append(problems, "sync.require_approval can't be combined with coordination.mode; approve pushes one replica at a time instead")
+	}
+	if c.Sync.ApprovalTimeout < 0 {
+		problems = append(problems, "sync.approval_timeout must not be negative (use 0 to wait for approval indefinitely)")
+	}
+	if c.Sync.RetryAttempts < 1 {
+		problems = append(problems, "sync.retry_attempts must be at least 1 (use 1 to never retry)")
+	}
+	if c.Sync.RetryBackoff < 0 {
+		problems = append(problems, "sync.retry_backoff must not be negative")
+	}
+	if c.Sync.MaxDeletePercent < 0 || c.Sync.MaxDeletePercent > 100 {
+		problems = append(problems, "sync.max_delete_percent must be between 0 and 100 (use 0 to disable the check)")
+	}
+	if c.Sync.BatchCacheSize < 0 {
+		problems = append(problems, "sync.batch_cache_size must not be negative (use 0 to disable the cache)")
+	}
+	if c.Sync.SubtreeWorkers < 0 {
+		problems = append(problems, "sync.subtree_workers must not be negative (use 0 to refuse sub-tree batches)")
+	}
+	for _, window := range c.Sync.PushWindows {
+		if _, err := parsePushWindow(window); err != nil {
+			problems = append(problems, fmt.Sprintf("sync.push_windows: %v", err))
+		}
+	}
+	if _, err := time.LoadLocation(c.Sync.PushWindowTimezone); err != nil {
+		problems = append(problems, fmt.Sprintf("sync.push_window_timezone %q is not a known time zone", c.Sync.PushWindowTimezone))
+	}
+	for _, pattern := range c.Sync.ProtectedPaths {
+		if strings.TrimSpace(pattern) == "" || strings.ContainsAny(pattern, "\n\r") {
+			problems = append(problems, fmt.Sprintf("sync.protected_paths entry %q must be a non-empty single-line pattern", pattern))
+		}
+	}
+	if c.Quota.SoftBytes < 0 || c.Quota.HardBytes < 0 || c.Quota.SoftInodes < 0 || c.Quota.HardInodes < 0 {
+		problems = append(problems, "quota limits must not be negative (use 0 to not enforce one)")
+	}
+	if c.Quota.HardBytes > 0 && c.Quota.SoftBytes > c.Quota.HardBytes {
+		problems = append(problems, "quota.soft_bytes must not be more than quota.hard_bytes")
+	}
+	if c.Quota.HardInodes > 0 && c.Quota.SoftInodes > c.Quota.HardInodes {
+		problems = append(problems, "quota.soft_inodes must not be more than quota.hard_inodes")
+	}
+	if c.Resources.RsyncNice < 0 || c.Resources.RsyncNice > 19 {
+		problems = append(problems, "resources.rsync_nice must be between 0 and 19")
+	}
+	switch c.Resources.RsyncIOClass {
+	case IOClassDefault, IOClassBestEffort, IOClassIdle:
+	default:
+		problems = append(problems, fmt.Sprintf("resources.rsync_io_class %q must be %q or %q", c.Resources.RsyncIOClass, IOClassBestEffort, IOClassIdle))
+	}
+	if c.Resources.MemoryLimit < 0 {
+		problems = append(problems, "resources.memory_limit must not be negative (use 0 for the container's limit)")
+	}
+	if c.Resources.BatchMemoryBuffer < 0 {
+		problems = append(problems, "resources.batch_memory_buffer must not be negative (use 0 for half of the memory limit)")
+	}
+	if _, err := parseReloadSignal(c.Signals.Reload); err != nil {
+		problems = append(problems, fmt.Sprintf("signals.reload: %v", err))
+	}
+	if _, err := launcher.ParseSignalTarget(c.Signals.Target); err != nil {
+		problems = append(problems, fmt.Sprintf("signals.target: %v", err))
+	}
+	if c.Signals.Shutdown != "" {
+		if _, err := launcher.ParseSignal(c.Signals.Shutdown); err != nil {
+			problems = append(problems, fmt.Sprintf("signals.shutdown: %v", err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Signals.Launchers)) {
+		signals := c.Signals.Launchers[name]
+		if !launcherNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("signals.launchers: name %q may only contain letters, digits, - and _", name))
+		}
+		if signals.Reload != "" {
+			if _, err := parseReloadSignal(signals.Reload); err != nil {
+				problems = append(problems, fmt.Sprintf("signals.launchers.%s.reload: %v", name, err))
+			}
+		}
+		if signals.Target != "" {
+			if _, err := launcher.ParseSignalTarget(signals.Target); err != nil {
+				problems = append(problems, fmt.Sprintf("signals.launchers.%s.target: %v", name, err))
+			}
+		}
+	}
+	switch c.Reload.Strategy {
+	case ReloadStrategySignal, ReloadStrategyRestart:
+	case ReloadStrategyHTTP:
+		if u, err := url.Parse(c.Reload.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("reload.url %q must be an absolute http:// or https:// URL", c.Reload.URL))
+		}
+	case ReloadStrategyCommand:
+		if strings.TrimSpace(c.Reload.Command) == "" {
+			problems = append(problems, "reload.command must be set with the command strategy")
+		}
+	case ReloadStrategyOverlap:
+		// The launcher is told about each step of the overlap with the reload signal.
+		if sig, err := parseReloadSignal(c.Signals.Reload); err == nil && sig == launcher.NoSignal {
+			problems = append(problems, fmt.Sprintf("signals.reload can't be %q with the overlap reload strategy", ReloadSignalNone))
+		}
+		if _, err := launcher.ParseSignal(c.Reload.DrainSignal); err != nil {
+			problems = append(problems, fmt.Sprintf("reload.drain_signal: %v", err))
+		}
+		if c.Reload.DrainTimeout < Duration(time.Second) {
+			problems = append(problems, "reload.drain_timeout must be at least 1s")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("reload.strategy %q must be %q, %q, %q, %q or %q", c.Reload.Strategy,
+			ReloadStrategySignal, ReloadStrategyHTTP, ReloadStrategyCommand, ReloadStrategyRestart, ReloadStrategyOverlap))
+	}
+	if c.Reload.Timeout <= 0 {
+		problems = append(problems, "reload.timeout must be greater than zero")
+	}
+	if c.Timeouts.Hook <= 0 {
+		problems = append(problems, "timeouts.hook must be greater than zero")
+	}
+	if c.Timeouts.StatusInterval < 0 {
+		problems = append(problems, "timeouts.status_interval must not be negative (use 0 to disable status reports)")
+	}
+	if c.Timeouts.ReconnectBackoff <= 0 {
+		problems = append(problems, "timeouts.reconnect_backoff must be greater than zero")
+	}
+	if c.Timeouts.Rsync <= 0 {
+		problems = append(problems, "timeouts.rsync must be greater than zero")
+	}
+	if c.Timeouts.RsyncStall < 0 {
+		problems = append(problems, "timeouts.rsync_stall must not be negative (use 0 to disable the stall watchdog)")
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		problems = append(problems, "timeouts.shutdown must be greater than zero")
+	}
+	if c.Timeouts.GCInterval < 0 {
+		problems = append(problems, "timeouts.gc_interval must not be negative (use 0 to only clean up at startup)")
+	}
+	if c.Health.URL != "" && c.Health.TCPAddress != "" {
+		problems = append(problems, "health.url and health.tcp_address can't both be set")
+	}
+	if c.Health.URL != "" {
+		if u, err := url.Parse(c.Health.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("health.url %q must be an absolute http:// or https:// URL", c.Health.URL))
+		}
+	}
+	if c.Health.TCPAddress != "" {
+		if _, port, err := net.SplitHostPort(c.Health.TCPAddress); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("health.tcp_address %q must be a host:port address", c.Health.TCPAddress))
+		}
+	}
+	if c.Health.Timeout <= 0 {
+		problems = append(problems, "health.timeout must be greater than zero")
+	}
+	if c.Health.Interval <= 0 {
+		problems = append(problems, "health.interval must be greater than zero")
+	}
+	if c.Status.ListenAddr != "" {
+		if _, port, err := net.SplitHostPort(c.Status.ListenAddr); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("status.listen_addr %q must be a host:port address", c.Status.ListenAddr))
+		}
+	}
+	if c.Status.FileInterval <= 0 {
+		problems = append(problems, "status.file_interval must be greater than zero")
+	}
+	if c.Readiness.File != "" && !filepath.IsAbs(c.Readiness.File) {
+		problems = append(problems, fmt.Sprintf("readiness.file %q must be an absolute path", c.Readiness.File))
+	}
+	if c.Readiness.UnreadyDuringPush && c.Readiness.File == "" {
+		problems = append(problems, "readiness.unready_during_push requires readiness.file")
+	}
+	switch c.Database.Provider {
+	case envfile.ProviderBifrost:
+	case envfile.ProviderAWSSecretsManager:
+		if c.Database.AWS.Region == "" {
+			problems = append(problems, "database.aws.region is required: set it in the config file, BIFROST_DATABASE_AWS_REGION or AWS_REGION")
+		}
+		if c.Database.AWS.Endpoint != "" {
+			if u, err := url.Parse(c.Database.AWS.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				problems = append(problems, fmt.Sprintf("database.aws.endpoint %q must be an absolute http:// or https:// URL", c.Database.AWS.Endpoint))
+			}
+		}
+		if len(c.Database.AWS.Secrets) == 0 {
+			problems = append(problems, "database.aws.secrets must list at least one secret (or set BIFROST_DATABASE_AWS_SECRET_ID)")
+		}
+		for i, secret := range c.Database.AWS.Secrets {
+			if err := envfile.ValidateEnvVarName(secret.EnvVar); err != nil {
+				problems = append(problems, fmt.Sprintf("database.aws.secrets[%d].env_var: %v", i, err))
+			}
+			if secret.SecretID == "" {
+				problems = append(problems, fmt.Sprintf("database.aws.secrets[%d].secret_id is required", i))
+			}
+			if err := envfile.ValidateScope(secret.Scope); err != nil {
+				problems = append(problems, fmt.Sprintf("database.aws.secrets[%d].scope: %v", i, err))
+			}
+			if secret.Port < 0 || secret.Port > 65535 {
+				problems = append(problems, fmt.Sprintf("database.aws.secrets[%d].port %d is not a valid port", i, secret.Port))
+			}
+		}
+	case envfile.ProviderKubernetesSecret:
+		require(c.Database.Kubernetes.Name, "database.kubernetes.name", "BIFROST_DATABASE_KUBERNETES_SECRET")
+		for name := range c.Database.Kubernetes.Keys {
+			if err := envfile.ValidateEnvVarName(name); err != nil {
+				problems = append(problems, fmt.Sprintf("database.kubernetes.keys: %v", err))
+			}
+		}
+		if err := envfile.ValidateScope(c.Database.Kubernetes.Scope); err != nil {
+			problems = append(problems, fmt.Sprintf("database.kubernetes.scope: %v", err))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("database.provider %q must be one of %s, %s, %s", c.Database.Provider,
+			envfile.ProviderBifrost, envfile.ProviderAWSSecretsManager, envfile.ProviderKubernetesSecret))
+	}
+	if c.Database.MigrationTimeout <= 0 {
+		problems = append(problems, "database.migration_timeout must be greater than zero")
+	}
+	for _, pattern := range c.Install.Manifests {
+		if _, err := path.Match(pattern, ""); pattern == "" || strings.Contains(pattern, "/") || err != nil {
+			problems = append(problems, fmt.Sprintf("install.manifests entry %q must be a file name or pattern without /", pattern))
+		}
+	}
+	if c.Install.Timeout <= 0 {
+		problems = append(problems, "install.timeout must be greater than zero")
+	}
+	if c.Build.Timeout <= 0 {
+		problems = append(problems, "build.timeout must be greater than zero")
+	}
+	if c.Warm.Timeout <= 0 {
+		problems = append(problems, "warm.timeout must be greater than zero")
+	}
+	for i, raw := range c.Webhooks.URLs {
+		// Not quoted, since the URL may hold the secret that authorizes posting.
+		if u, err := url.Parse(raw); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("webhooks.urls[%d] must be an absolute http:// or https:// URL", i))
+		}
+	}
+	for _, event := range c.Webhooks.Events {
+		if !slices.Contains(webhookEvents, event) {
+			problems = append(problems, fmt.Sprintf("webhooks.events entry %q must be one of %s", event, strings.Join(webhookEvents, ", ")))
+		}
+	}
+	if c.Webhooks.Timeout <= 0 {
+		problems = append(problems, "webhooks.timeout must be greater than zero")
+	}
+	switch c.Metrics.Sink {
+	case MetricsSinkNone, MetricsSinkStatsD, MetricsSinkDogStatsD:
+	default:
+		problems = append(problems, fmt.Sprintf("metrics.sink %q must be empty, %q or %q", c.Metrics.Sink, MetricsSinkStatsD, MetricsSinkDogStatsD))
+	}
+	if c.Metrics.Address != "" {
+		if _, port, err := net.SplitHostPort(c.Metrics.Address); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("metrics.address %q must be a host:port address", c.Metrics.Address))
+		}
+	}
+	for _, tag := range c.Metrics.Tags {
+		if key, _, _ := strings.Cut(tag, ":"); key == "" {
+			problems = append(problems, fmt.Sprintf("metrics.tags entry %q must be a key:value tag", tag))
+		}
+	}
+	if c.Metrics.Interval <= 0 {
+		problems = append(problems, "metrics.interval must be greater than zero")
+	}
+	switch c.Coordination.Mode {
+	case CoordinationModeNone:
+	case CoordinationModeKubernetes:
+		if !leaseNamePattern.MatchString(c.Coordination.LeaseName) {
+			problems = append(problems, fmt.Sprintf("coordination.lease_name %q must be a lowercase DNS name of at most 253 characters", c.Coordination.LeaseName))
+		}
+		if c.Coordination.LeaseDuration < Duration(3*time.Second) || time.Duration(c.Coordination.LeaseDuration)%time.Second != 0 {
+			problems = append(problems, "coordination.lease_duration must be a whole number of seconds, at least 3s")
+		}
+		if _, port, err := net.SplitHostPort(c.Coordination.ListenAddr); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("coordination.listen_addr %q must be a host:port address", c.Coordination.ListenAddr))
+		}
+		if c.Coordination.AdvertiseAddr == "" {
+			problems = append(problems, fmt.Sprintf("coordination.advertise_addr is required: set it in the config file, BIFROST_COORDINATION_ADVERTISE_ADDR, or %s from the downward API", podIPEnv))
+		} else if host, port, err := net.SplitHostPort(c.Coordination.AdvertiseAddr); err != nil || host == "" || port == "" {
+			problems = append(problems, fmt.Sprintf("coordination.advertise_addr %q must be a host:port address", c.Coordination.AdvertiseAddr))
+		}
+		if c.Coordination.ReplicaTimeout <= 0 {
+			problems = append(problems, "coordination.replica_timeout must be greater than zero")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("coordination.mode %q must be empty or %q", c.Coordination.Mode, CoordinationModeKubernetes))
+	}
+	if c.Secrets.Vault.Address != "" {
+		if u, err := url.Parse(c.Secrets.Vault.Address); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("secrets.vault.address %q must be an absolute http:// or https:// URL", c.Secrets.Vault.Address))
+		}
+		require(c.Secrets.Vault.TokenPath, "secrets.vault.token_path", "BIFROST_VAULT_TOKEN_PATH")
+	}
+	if c.Permissions.UID < -1 {
+		problems = append(problems, "permissions.uid must not be negative (use -1 to leave ownership unchanged)")
+	}
+	if c.Permissions.GID < -1 {
+		problems = append(problems, "permissions.gid must not be negative (use -1 to leave ownership unchanged)")
+	}
+	if _, err := parseModeBits(c.Permissions.AddMode); err != nil {
+		problems = append(problems, fmt.Sprintf("permissions.add_mode: %v", err))
+	}
+	if _, err := parseModeBits(c.Permissions.RemoveMode); err != nil {
+		problems = append(problems, fmt.Sprintf("permissions.remove_mode: %v", err))
+	}
+	if c.Security.SharedGID < -1 {
+		problems = append(problems, "security.shared_gid must not be negative (use -1 for the files directory's group)")
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("log.level %q must be one of debug, info, warn, error", c.Log.Level))
+	}
+	if _, err := zapcore.ParseLevel(c.Log.ShipLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("log.ship_level %q must be one of info, warn, error", c.Log.ShipLevel))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// EnvFileOptions returns how the database env file is written.
+func (c *Config) EnvFileOptions() envfile.Options {
+	return envfile.Options{
+		Secrets:           envfile.NewSecretResolver(c.Secrets),
+		EncryptionKeyPath: c.Env.EncryptionKeyPath,
+	}
+}
+
+// DatabaseEnvProvider returns the configured source of the database env vars.
+// The Kubernetes Secret is read from the pod's namespace unless one is set,
+// so Pod must be set first.
+func (c *Config) DatabaseEnvProvider(tokens *transport.TokenManager) envfile.DatabaseEnvProvider {
+	switch c.Database.Provider {
+	case envfile.ProviderAWSSecretsManager:
+		return envfile.NewAWSSecretsProvider(c.Database.AWS)
+	case envfile.ProviderKubernetesSecret:
+		secret := c.Database.Kubernetes
+		if secret.Namespace == "" {
+			secret.Namespace = Pod.GetNamespace()
+		}
+		return &envfile.KubernetesSecretProvider{
+			Secret: secret,
+			APIURL: kubernetesAPIURL(),
+			Client: kubernetesClient,
+			Token:  kubernetesToken,
+		}
+	}
+	return &envfile.APIProvider{APIURL: c.API.URL, Tokens: tokens, DeploymentID: c.DeploymentID}
+}
+
+// permissionMapping returns how changed files are chowned and chmodded.
+func (c *Config) permissionMapping() permissionMapping {
+	add, _ := parseModeBits(c.Permissions.AddMode)
+	remove, _ := parseModeBits(c.Permissions.RemoveMode)
+	return permissionMapping{uid: c.Permissions.UID, gid: c.Permissions.GID, add: add, remove: remove}
+}
+
+// ShipLevel returns the minimum level of sidecar log entries sent upstream.
+func (c *Config) ShipLevel() zapcore.Level {
+	level, err := zapcore.ParseLevel(c.Log.ShipLevel)
+	if err != nil {
+		return zapcore.InfoLevel
+	}
+	return level
+}
+
+// pushSchedule returns the parsed push windows. Windows that don't parse are
+// left out and an unknown time zone is taken as UTC; LoadConfig rejects both.
+func (c *Config) pushSchedule() pushSchedule {
+	schedule := pushSchedule{location: time.UTC}
+	if location, err := time.LoadLocation(c.Sync.PushWindowTimezone); err == nil {
+		schedule.location = location
+	}
+	for _, window := range c.Sync.PushWindows {
+		if w, err := parsePushWindow(window); err == nil {
+			schedule.windows = append(schedule.windows, w)
+		}
+	}
+	return schedule
+}
+
+// ReloadSignal returns the parsed signal the launcher is sent after a push,
+// launcher.NoSignal if none is.
+func (c *Config) ReloadSignal() syscall.Signal {
+	sig, err := parseReloadSignal(c.Signals.Reload)
+	if err != nil {
+		sig, _ = parseReloadSignal(DefaultReloadSignal)
+	}
+	return sig
+}
+
+// SignalTarget returns which processes the reload signal is sent to.
+func (c *Config) SignalTarget() launcher.SignalTarget {
+	target, err := launcher.ParseSignalTarget(c.Signals.Target)
+	if err != nil {
+		return launcher.SignalProcess
+	}
+	return target
+}
+
+// LauncherSignal returns the reload signal and target for the named launcher,
+// those of signals.launchers where set and ReloadSignal and SignalTarget
+// otherwise.
+func (c *Config) LauncherSignal(name string) (syscall.Signal, launcher.SignalTarget) {
+	sig, target := c.ReloadSignal(), c.SignalTarget()
+	signals := c.Signals.Launchers[name]
+	if parsed, err := parseReloadSignal(signals.Reload); signals.Reload != "" && err == nil {
+		sig = parsed
+	}
+	if parsed, err := launcher.ParseSignalTarget(signals.Target); signals.Target != "" && err == nil {
+		target = parsed
+	}
+	return sig, target
+}
+
+// ShutdownSignal returns the signal forwarded to the launcher when the sidecar
+// shuts down, and false if none is configured.
+func (c *Config) ShutdownSignal() (syscall.Signal, bool) {
+	if c.Signals.Shutdown == "" {
+		return 0, false
+	}
+	sig, err := launcher.ParseSignal(c.Signals.Shutdown)
+	if err != nil {
+		return 0, false
+	}
+	return sig, true
+}
+
+// parseReloadSignal parses signals.reload, which may also be "none" for
+// launcher.NoSignal.
+func parseReloadSignal(name string) (syscall.Signal, error) {
+	if strings.EqualFold(strings.TrimSpace(name), ReloadSignalNone) {
+		return launcher.NoSignal, nil
+	}
+	return launcher.ParseSignal(name)
+}
+
+func envString(target *string, name string) {
+	if value := os.Getenv(name); value != "" {
+		*target = value
+	}
+}
+
+// envList sets target from a comma-separated list, skipping empty entries.
+func envList(target *[]string, name string) {
+	envSplit(target, name, ",")
+}
+
+func envSplit(target *[]string, name, sep string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*target = list
+}
+
+func envDuration(target *Duration, name string) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: use a value like \"30s\" or \"2m\"", name, value)
+	}
+	*target = Duration(parsed)
+	return nil
+}
+
+func envInt(target *int, name string) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: use a whole number", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func envByteSize(target *ByteSize, name string) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := parseByteSize(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: use a value like \"500MB\" or \"2GiB\"", name, value)
+	}
+	*target = ByteSize(parsed)
+	return nil
+}
+
+func envBool(target *bool, name string) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: use true or false", name, value)
+	}
+	*target = parsed
+	return nil
+}
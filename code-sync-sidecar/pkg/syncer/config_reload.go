@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+const configPollInterval = 5 * time.Second
+
+// ConfigReloader re-reads the configuration when the config file changes or the
+// sidecar receives a reload signal (SIGUSR1), and hands the result to apply.
+// Only tunable settings take effect; changes to identity, API or directory
+// settings are logged and need a restart.
+type ConfigReloader struct {
+	path    string
+	current *Config
+	apply   func(*Config)
+
+	modTime time.Time
+	size    int64
+}
+
+// NewConfigReloader creates a ConfigReloader for the config file at path (which may be
+// empty when the sidecar is configured through the environment only).
+func NewConfigReloader(path string, current *Config, apply func(*Config)) *ConfigReloader {
+	cr := &ConfigReloader{
+		path:    path,
+		current: current,
+		apply:   apply,
+	}
+	cr.fileChanged() // Record the initial modification time.
+	return cr
+}
+
+// Run polls the config file and listens on signals until ctx is cancelled.
+func (cr *ConfigReloader) Run(ctx context.Context, signals <-chan os.Signal) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			cr.Reload("received " + sig.String())
+		case <-ticker.C:
+			if cr.fileChanged() {
+				cr.Reload("config file changed")
+			}
+		}
+	}
+}
+
+// Reload loads and applies the current configuration. An invalid configuration is
+// logged and ignored so a bad edit never takes the sidecar down.
+func (cr *ConfigReloader) Reload(reason string) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Error("Failed to reload configuration, keeping current settings", zap.String("reason", reason), zap.Error(err))
+		return err
+	}
+
+	for _, key := range restartRequiredChanges(cr.current, cfg) {
+		log.Warn("Configuration change requires a sidecar restart to take effect", zap.String("setting", key))
+	}
+	cr.apply(cfg)
+	cr.current = cfg
+	log.Info("Configuration reloaded", zap.String("reason", reason))
+	return nil
+}
+
+// fileChanged reports whether the config file's size or modification time changed since the last call.
+func (cr *ConfigReloader) fileChanged() bool {
+	if cr.path == "" {
+		return false
+	}
+	info, err := os.Stat(cr.path)
+	if err != nil {
+		// A missing file is reported by LoadConfig on the next reload; don't reload in a loop.
+		return false
+	}
+	if info.ModTime().Equal(cr.modTime) && info.Size() == cr.size {
+		return false
+	}
+	cr.modTime = info.ModTime()
+	cr.size = info.Size()
+	return true
+}
+
+// restartRequiredChanges lists settings that differ between prev and next but are only read at startup.
+func restartRequiredChanges(prev, next *Config) []string {
+	var changed []string
+	check := func(key string, differs bool) {
+		if differs {
+			changed = append(changed, key)
+		}
+	}
+	check("app_id", prev.AppID != next.AppID)
+	check("deployment_id", prev.DeploymentID != next.DeploymentID)
+	check("api", prev.API != next.API)
+	check("sync.files_dir", prev.Sync.FilesDir != next.Sync.FilesDir)
+	check("sync.app_log_dir", prev.Sync.AppLogDir != next.Sync.AppLogDir)
+	check("sync.apply_mode", prev.Sync.ApplyMode != next.Sync.ApplyMode)
+	check("sync.require_approval", prev.Sync.RequireApproval != next.Sync.RequireApproval)
+	check("security", prev.Security != next.Security)
+	check("status.listen_addr", prev.Status.ListenAddr != next.Status.ListenAddr)
+	check("status.control_socket", prev.Status.ControlSocket != next.Status.ControlSocket)
+	check("status.file", prev.Status.File != next.Status.File || prev.Status.FileInterval != next.Status.FileInterval)
+	check("readiness.file", prev.Readiness.File != next.Readiness.File)
+	check("coordination", prev.Coordination != next.Coordination)
+	check("metrics", !prev.Metrics.equal(next.Metrics))
+	check("log.ship", prev.Log.Ship != next.Log.Ship || prev.Log.ShipLevel != next.Log.ShipLevel)
+	return changed
+}
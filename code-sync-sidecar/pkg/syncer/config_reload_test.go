@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTestConfig = `
+app_id: app1
+deployment_id: deployment1
+api:
+  url: http://proxy:8000
+  api_key: secret
+timeouts:
+  status_interval: 30s
+`
+
+func TestConfigReloader_Reload(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, reloadTestConfig)
+	t.Setenv("BIFROST_CONFIG", path)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	rw := &FileSyncer{
+		appID:         cfg.AppID,
+		deploymentID:  cfg.DeploymentID,
+		targetSyncDir: cfg.Sync.FilesDir,
+		shells:        NewShellManager(false, t.TempDir(), nil),
+	}
+	rw.ApplyConfig(cfg)
+	reloader := NewConfigReloader(path, cfg, rw.ApplyConfig)
+	assert.False(t, reloader.fileChanged())
+
+	require.NoError(t, os.WriteFile(path, []byte(reloadTestConfig+`
+  hook: 5s
+  reconnect_backoff: 1s
+signals:
+  reload: SIGUSR2
+shell:
+  enabled: true
+`), 0644))
+	assert.True(t, reloader.fileChanged())
+	require.NoError(t, reloader.Reload("test"))
+
+	assert.Equal(t, syscall.SIGUSR2, rw.getReloadSignal())
+	assert.Equal(t, 5*time.Second, rw.getHooks().timeout)
+	assert.Equal(t, time.Second, rw.reconnectBackoff)
+	assert.True(t, rw.shells.enabled.Load())
+
+	// An invalid edit is rejected and the previous settings stay in place.
+	require.NoError(t, os.WriteFile(path, []byte("timeouts:\n  hook: -1s\n"), 0644))
+	require.Error(t, reloader.Reload("test"))
+	assert.Equal(t, syscall.SIGUSR2, rw.getReloadSignal())
+}
+
+func TestRestartRequiredChanges(t *testing.T) {
+	prev := DefaultConfig()
+	next := DefaultConfig()
+	next.Timeouts.Hook = Duration(time.Minute)
+	next.Log.Level = "debug"
+	assert.Empty(t, restartRequiredChanges(prev, next))
+
+	next.API.URL = "http://other:8000"
+	next.Sync.FilesDir = "/srv/files"
+	next.Metrics.Tags = []string{"team:web"}
+	assert.Equal(t, []string{"api", "sync.files_dir", "metrics"}, restartRequiredChanges(prev, next))
+}
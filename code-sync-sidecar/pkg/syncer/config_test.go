@@ -0,0 +1,634 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// clearConfigEnv unsets every environment variable LoadConfig reads for the duration of the test.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"BIFROST_CONFIG", "BIFROST_APP_ID", "BIFROST_DEPLOYMENT_ID", "BIFROST_API_URL",
+		"BIFROST_AUTH_MODE", "BIFROST_API_KEY", "BIFROST_IDENTITY_TOKEN_PATH", "BIFROST_FILES_DIR",
+		"BIFROST_HOOKS_DIR", "BIFROST_APP_LOG_DIR", "BIFROST_RELOAD_SIGNAL", "BIFROST_HOOK_TIMEOUT",
+		"BIFROST_STATUS_INTERVAL", "BIFROST_SHELL_ENABLED", "BIFROST_RECONNECT_BACKOFF", "BIFROST_LOG_LEVEL",
+		"BIFROST_LOG_SHIP", "BIFROST_LOG_SHIP_LEVEL", "BIFROST_LOG_WIRE", "BIFROST_APPLY_MODE", "BIFROST_RSYNC_PATH",
+		"BIFROST_MAX_SNAPSHOTS", "BIFROST_SNAPSHOT_RETENTION", "BIFROST_GC_INTERVAL",
+		"BIFROST_RSYNC_TIMEOUT", "BIFROST_RSYNC_STALL_TIMEOUT", "BIFROST_HEALTH_URL", "BIFROST_HEALTH_TCP_ADDRESS", "BIFROST_HEALTH_TIMEOUT",
+		"BIFROST_HEALTH_INTERVAL", "BIFROST_INSTALL_COMMAND", "BIFROST_INSTALL_MANIFESTS", "BIFROST_INSTALL_TIMEOUT", "BIFROST_BUILD_COMMAND", "BIFROST_BUILD_TIMEOUT", "BIFROST_WARM_READ_FILES", "BIFROST_WARM_COMMAND", "BIFROST_WARM_TIMEOUT", "BIFROST_WEBHOOK_URLS", "BIFROST_WEBHOOK_SECRET_PATH", "BIFROST_WEBHOOK_EVENTS", "BIFROST_WEBHOOK_TIMEOUT", "BIFROST_METRICS_SINK", "BIFROST_METRICS_ADDRESS", "BIFROST_METRICS_PREFIX", "BIFROST_METRICS_TAGS", "BIFROST_METRICS_INTERVAL", "BIFROST_PUSH_DEBOUNCE", "BIFROST_SEQUENCE_GAP_TIMEOUT", "BIFROST_REQUIRE_APPROVAL", "BIFROST_APPROVAL_TIMEOUT", "BIFROST_RETRY_ATTEMPTS", "BIFROST_RETRY_BACKOFF", "BIFROST_PROTECTED_PATHS", "BIFROST_MAX_DELETE_PERCENT", "BIFROST_BATCH_CACHE_SIZE", "BIFROST_SUBTREE_WORKERS",
+		"BIFROST_QUOTA_SOFT_BYTES", "BIFROST_QUOTA_HARD_BYTES", "BIFROST_QUOTA_SOFT_INODES", "BIFROST_QUOTA_HARD_INODES",
+		"BIFROST_RSYNC_NICE", "BIFROST_RSYNC_IO_CLASS", "BIFROST_MEMORY_LIMIT", "BIFROST_BATCH_MEMORY_BUFFER", "BIFROST_VAULT_ADDR", "BIFROST_VAULT_TOKEN_PATH",
+		"BIFROST_VAULT_NAMESPACE", "BIFROST_ENV_KEY_PATH", "BIFROST_FILE_UID", "BIFROST_FILE_GID",
+		"BIFROST_FILE_MODE_ADD", "BIFROST_FILE_MODE_REMOVE", "BIFROST_HARDENED", "BIFROST_SHARED_GID", "BIFROST_PRESERVE_LABELS", "BIFROST_CONTROL_SOCKET", "BIFROST_STATUS_FILE", "BIFROST_STATUS_FILE_INTERVAL",
+		"BIFROST_STATUS_ADDR", "BIFROST_SIGNAL_TARGET",
+		"BIFROST_SHUTDOWN_SIGNAL", "BIFROST_SHUTDOWN_TIMEOUT",
+		"BIFROST_RELOAD_STRATEGY", "BIFROST_RELOAD_URL", "BIFROST_RELOAD_COMMAND", "BIFROST_RELOAD_TIMEOUT",
+		"BIFROST_RELOAD_DRAIN_SIGNAL", "BIFROST_RELOAD_DRAIN_TIMEOUT",
+		"BIFROST_READINESS_FILE", "BIFROST_READINESS_UNREADY_DURING_PUSH",
+		"BIFROST_COORDINATION_MODE", "BIFROST_COORDINATION_LEASE_NAME", "BIFROST_COORDINATION_LEASE_DURATION",
+		"BIFROST_COORDINATION_LISTEN_ADDR", "BIFROST_COORDINATION_ADVERTISE_ADDR",
+		"BIFROST_COORDINATION_PEER_TOKEN_PATH", "BIFROST_COORDINATION_REPLICA_TIMEOUT", "BIFROST_POD_IP",
+		"BIFROST_MIGRATION_COMMAND", "BIFROST_MIGRATION_TIMEOUT",
+		"BIFROST_DATABASE_PROVIDER", "BIFROST_DATABASE_AWS_REGION", "BIFROST_DATABASE_AWS_SECRET_ID",
+		"BIFROST_DATABASE_KUBERNETES_SECRET", "AWS_REGION", "AWS_DEFAULT_REGION",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sidecar.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadConfig_EnvOnly(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_APP_ID", "app1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "deployment1")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+	t.Setenv("BIFROST_API_KEY", "secret")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFilesDir, cfg.Sync.FilesDir)
+	assert.Equal(t, getHooksDir(DefaultFilesDir), cfg.Sync.HooksDir)
+	assert.Equal(t, Duration(DefaultStatusInterval), cfg.Timeouts.StatusInterval)
+	assert.Equal(t, syscall.SIGHUP, cfg.ReloadSignal())
+	assert.Equal(t, launcher.SignalProcess, cfg.SignalTarget())
+	_, ok := cfg.ShutdownSignal()
+	assert.False(t, ok, "shutdown signals aren't forwarded by default")
+	assert.Equal(t, Duration(DefaultShutdownTimeout), cfg.Timeouts.Shutdown)
+	assert.Equal(t, ReloadConfig{
+		Strategy:     ReloadStrategySignal,
+		Timeout:      Duration(DefaultReloadTimeout),
+		DrainSignal:  DefaultReloadDrainSignal,
+		DrainTimeout: Duration(DefaultReloadDrainTimeout),
+	}, cfg.Reload)
+	assert.Equal(t, ApplyModeInPlace, cfg.Sync.ApplyMode)
+	assert.Equal(t, DefaultRsyncPath, cfg.Sync.RsyncPath)
+	assert.Equal(t, DefaultMaxSnapshots, cfg.Sync.MaxSnapshots)
+	assert.Equal(t, Duration(DefaultSnapshotRetention), cfg.Sync.SnapshotRetention)
+	assert.Equal(t, DefaultEnvHistory, cfg.Sync.EnvHistory)
+	assert.Equal(t, Duration(0), cfg.Sync.PushDebounce)
+	assert.Equal(t, Duration(DefaultSequenceGapTimeout), cfg.Sync.SequenceGapTimeout)
+	assert.False(t, cfg.Sync.RequireApproval)
+	assert.Equal(t, Duration(DefaultApprovalTimeout), cfg.Sync.ApprovalTimeout)
+	assert.Equal(t, DefaultRetryAttempts, cfg.Sync.RetryAttempts)
+	assert.Equal(t, Duration(DefaultRetryBackoff), cfg.Sync.RetryBackoff)
+	assert.Equal(t, DefaultMaxDeletePercent, cfg.Sync.MaxDeletePercent)
+	assert.Equal(t, ByteSize(DefaultBatchCacheSize), cfg.Sync.BatchCacheSize)
+	assert.Equal(t, DefaultSubtreeWorkers, cfg.Sync.SubtreeWorkers)
+	assert.Empty(t, cfg.Sync.ProtectedPaths)
+	assert.Equal(t, QuotaConfig{}, cfg.Quota, "no quotas by default")
+	assert.Equal(t, ResourcesConfig{}, cfg.Resources, "no throttling by default")
+	assert.Equal(t, Duration(DefaultGCInterval), cfg.Timeouts.GCInterval)
+	assert.Equal(t, Duration(DefaultRsyncTimeout), cfg.Timeouts.Rsync)
+	assert.Equal(t, Duration(DefaultRsyncStallTimeout), cfg.Timeouts.RsyncStall)
+	assert.Equal(t, HealthConfig{Timeout: Duration(DefaultHealthTimeout), Interval: Duration(DefaultHealthInterval)}, cfg.Health)
+	assert.Equal(t, InstallConfig{Manifests: DefaultInstallManifests, Timeout: Duration(DefaultInstallTimeout)}, cfg.Install, "no install command by default")
+	assert.Equal(t, BuildConfig{Timeout: Duration(DefaultBuildTimeout)}, cfg.Build, "no build step by default")
+	assert.Equal(t, WarmConfig{Timeout: Duration(DefaultWarmTimeout)}, cfg.Warm, "the cache isn't warmed by default")
+	assert.Equal(t, PermissionsConfig{UID: -1, GID: -1}, cfg.Permissions)
+	assert.Equal(t, SecurityConfig{SharedGID: -1}, cfg.Security)
+	assert.Equal(t, WebhooksConfig{Timeout: Duration(DefaultWebhookTimeout)}, cfg.Webhooks, "no webhooks by default")
+	assert.Equal(t, MetricsConfig{Prefix: DefaultMetricsPrefix, Interval: Duration(DefaultMetricsInterval)}, cfg.Metrics, "no metrics by default")
+	assert.Equal(t, DefaultStatusListenAddr, cfg.Status.ListenAddr)
+	assert.True(t, cfg.Status.ControlSocket)
+	assert.True(t, cfg.Status.File)
+	assert.Equal(t, Duration(DefaultStatusFileInterval), cfg.Status.FileInterval)
+	assert.Equal(t, ReadinessConfig{}, cfg.Readiness)
+	assert.Equal(t, CoordinationConfig{
+		LeaseDuration:  Duration(DefaultCoordinationLeaseDuration),
+		ListenAddr:     DefaultCoordinationListenAddr,
+		ReplicaTimeout: Duration(DefaultCoordinationReplicaTimeout),
+	}, cfg.Coordination)
+	assert.Equal(t, DatabaseConfig{Provider: envfile.ProviderBifrost, MigrationTimeout: Duration(DefaultMigrationTimeout)}, cfg.Database)
+}
+
+func TestLoadConfig_FileWithEnvOverrides(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, `
+app_id: app-from-file
+deployment_id: dep-from-file
+api:
+  url: https://bifrost.example.com
+  auth_mode: kubernetes
+sync:
+  files_dir: /srv/files
+  apply_mode: swap
+  rsync_path: /usr/bin/rsync
+  push_debounce: 750ms
+  retry_attempts: 5
+  max_delete_percent: 20
+  batch_cache_size: 64MiB
+  protected_paths:
+    - /uploads/
+quota:
+  soft_bytes: 1GiB
+  hard_inodes: 200000
+resources:
+  rsync_nice: 10
+  rsync_io_class: idle
+signals:
+  reload: usr2
+  shutdown: SIGTERM
+timeouts:
+  hook: 2m
+  status_interval: 0s
+  gc_interval: 10m
+  rsync: 5m
+  rsync_stall: 0s
+shell:
+  enabled: true
+health:
+  url: http://localhost:8080/healthz
+  timeout: 2m
+secrets:
+  vault:
+    address: https://vault.example.com
+    token_path: /var/run/secrets/vault/token
+permissions:
+  uid: 1000
+  add_mode: "0060"
+security:
+  shared_gid: 3000
+  preserve_labels: true
+webhooks:
+  urls:
+    - https://hooks.slack.com/services/T0/B0/secret
+  secret_path: /var/run/secrets/webhook/key
+metrics:
+  sink: statsd
+  address: statsd.monitoring:8125
+  tags: [team:web]
+readiness:
+  file: /app-files/.sidecar/ready
+coordination:
+  mode: kubernetes
+  lease_duration: 30s
+  peer_token_path: /var/run/secrets/peer/token
+database:
+  provider: aws_secrets_manager
+  aws:
+    secrets:
+      - env_var: DATABASE_URL
+        secret_id: prod/app/db
+      - env_var: ANALYTICS_URL
+        secret_id: arn:aws:secretsmanager:eu-west-1:123456789012:secret:rds!cluster-1
+        scope: worker
+        engine: aurora-postgresql
+        host: analytics.cluster-abc.eu-west-1.rds.amazonaws.com
+        port: 5432
+        database: analytics
+  migration_command: alembic upgrade head
+`))
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "dep-from-env")
+	t.Setenv("BIFROST_FILE_GID", "2000")
+	t.Setenv("BIFROST_FILE_MODE_REMOVE", "0007")
+	t.Setenv("BIFROST_HOOK_TIMEOUT", "15s")
+	t.Setenv("BIFROST_MAX_SNAPSHOTS", "2")
+	t.Setenv("BIFROST_HEALTH_INTERVAL", "500ms")
+	t.Setenv("BIFROST_VAULT_NAMESPACE", "team-a")
+	t.Setenv("BIFROST_ENV_KEY_PATH", "/var/run/secrets/env/key")
+	t.Setenv("BIFROST_HARDENED", "true")
+	t.Setenv("BIFROST_STATUS_ADDR", "127.0.0.1:9000")
+	t.Setenv("BIFROST_CONTROL_SOCKET", "false")
+	t.Setenv("BIFROST_STATUS_FILE", "false")
+	t.Setenv("BIFROST_STATUS_FILE_INTERVAL", "1s")
+	t.Setenv("BIFROST_SIGNAL_TARGET", "group")
+	t.Setenv("BIFROST_SHUTDOWN_TIMEOUT", "45s")
+	t.Setenv("BIFROST_READINESS_UNREADY_DURING_PUSH", "true")
+	t.Setenv("BIFROST_COORDINATION_REPLICA_TIMEOUT", "90s")
+	t.Setenv("BIFROST_POD_IP", "10.0.0.7")
+	t.Setenv("BIFROST_MIGRATION_TIMEOUT", "10m")
+	t.Setenv("BIFROST_LOG_WIRE", "true")
+	t.Setenv("BIFROST_RETRY_BACKOFF", "250ms")
+	t.Setenv("BIFROST_SUBTREE_WORKERS", "8")
+	t.Setenv("BIFROST_APPROVAL_TIMEOUT", "2h")
+	t.Setenv("BIFROST_SEQUENCE_GAP_TIMEOUT", "0s")
+	t.Setenv("BIFROST_BUILD_COMMAND", "npx tsc -p .")
+	t.Setenv("BIFROST_WARM_READ_FILES", "true")
+	t.Setenv("BIFROST_WARM_TIMEOUT", "30s")
+	t.Setenv("BIFROST_WEBHOOK_EVENTS", "push.failed, launcher.crashed")
+	t.Setenv("BIFROST_WEBHOOK_TIMEOUT", "5s")
+	t.Setenv("BIFROST_METRICS_SINK", "dogstatsd")
+	t.Setenv("BIFROST_METRICS_TAGS", "team:web, env:staging")
+	t.Setenv("BIFROST_METRICS_INTERVAL", "30s")
+	t.Setenv("BIFROST_INSTALL_COMMAND", "pip install -r requirements.txt")
+	t.Setenv("BIFROST_INSTALL_MANIFESTS", "requirements*.txt, pyproject.toml")
+	t.Setenv("BIFROST_PROTECTED_PATHS", "/data/, *.sqlite,")
+	t.Setenv("BIFROST_QUOTA_HARD_BYTES", "2GB")
+	t.Setenv("BIFROST_MEMORY_LIMIT", "256MiB")
+	t.Setenv("BIFROST_BATCH_MEMORY_BUFFER", "32MiB")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "app-from-file", cfg.AppID)
+	assert.Equal(t, "dep-from-env", cfg.DeploymentID)
+	assert.Equal(t, "kubernetes", cfg.API.AuthMode)
+	assert.Equal(t, "/srv/files", cfg.Sync.FilesDir)
+	assert.Equal(t, getHooksDir("/srv/files"), cfg.Sync.HooksDir)
+	assert.Equal(t, ApplyModeSwap, cfg.Sync.ApplyMode)
+	assert.Equal(t, "/usr/bin/rsync", cfg.Sync.RsyncPath)
+	assert.Equal(t, 2, cfg.Sync.MaxSnapshots)
+	assert.Equal(t, Duration(750*time.Millisecond), cfg.Sync.PushDebounce)
+	assert.Equal(t, Duration(2*time.Hour), cfg.Sync.ApprovalTimeout)
+	assert.Equal(t, Duration(0), cfg.Sync.SequenceGapTimeout)
+	assert.Equal(t, 5, cfg.Sync.RetryAttempts)
+	assert.Equal(t, Duration(250*time.Millisecond), cfg.Sync.RetryBackoff)
+	assert.Equal(t, 20, cfg.Sync.MaxDeletePercent)
+	assert.Equal(t, ByteSize(64<<20), cfg.Sync.BatchCacheSize)
+	assert.Equal(t, 8, cfg.Sync.SubtreeWorkers)
+	assert.Equal(t, []string{"/data/", "*.sqlite"}, cfg.Sync.ProtectedPaths, "the env replaces the file's list")
+	assert.Equal(t, QuotaConfig{SoftBytes: 1 << 30, HardBytes: 2_000_000_000, HardInodes: 200000}, cfg.Quota)
+	assert.Equal(t, ResourcesConfig{RsyncNice: 10, RsyncIOClass: IOClassIdle, MemoryLimit: 256 << 20, BatchMemoryBuffer: 32 << 20}, cfg.Resources)
+	assert.Equal(t, syscall.SIGUSR2, cfg.ReloadSignal())
+	assert.Equal(t, launcher.SignalGroup, cfg.SignalTarget())
+	shutdownSignal, ok := cfg.ShutdownSignal()
+	assert.True(t, ok)
+	assert.Equal(t, syscall.SIGTERM, shutdownSignal)
+	assert.Equal(t, Duration(45*time.Second), cfg.Timeouts.Shutdown)
+	assert.Equal(t, Duration(15*time.Second), cfg.Timeouts.Hook)
+	assert.Equal(t, Duration(0), cfg.Timeouts.StatusInterval)
+	assert.Equal(t, Duration(10*time.Minute), cfg.Timeouts.GCInterval)
+	assert.Equal(t, Duration(5*time.Minute), cfg.Timeouts.Rsync)
+	assert.Equal(t, Duration(0), cfg.Timeouts.RsyncStall, "0 disables the watchdog")
+	assert.True(t, cfg.Shell.Enabled)
+	assert.True(t, cfg.Log.Wire)
+	assert.Equal(t, HealthConfig{URL: "http://localhost:8080/healthz", Timeout: Duration(2 * time.Minute), Interval: Duration(500 * time.Millisecond)}, cfg.Health)
+	assert.Equal(t, BuildConfig{Command: "npx tsc -p .", Timeout: Duration(DefaultBuildTimeout)}, cfg.Build)
+	assert.Equal(t, WarmConfig{ReadFiles: true, Timeout: Duration(30 * time.Second)}, cfg.Warm)
+	assert.Equal(t, InstallConfig{Command: "pip install -r requirements.txt", Manifests: []string{"requirements*.txt", "pyproject.toml"}, Timeout: Duration(DefaultInstallTimeout)}, cfg.Install)
+	assert.Equal(t, "/var/run/secrets/env/key", cfg.Env.EncryptionKeyPath)
+	assert.Equal(t, envfile.VaultConfig{Address: "https://vault.example.com", TokenPath: "/var/run/secrets/vault/token", Namespace: "team-a"}, cfg.Secrets.Vault)
+	assert.Equal(t, permissionMapping{uid: 1000, gid: 2000, add: 0060, remove: 0007}, cfg.permissionMapping())
+	assert.Equal(t, SecurityConfig{Hardened: true, SharedGID: 3000, PreserveLabels: true}, cfg.Security)
+	assert.Equal(t, WebhooksConfig{
+		URLs:       []string{"https://hooks.slack.com/services/T0/B0/secret"},
+		SecretPath: "/var/run/secrets/webhook/key",
+		Events:     []string{WebhookPushFailed, WebhookLauncherCrashed},
+		Timeout:    Duration(5 * time.Second),
+	}, cfg.Webhooks)
+	assert.Equal(t, MetricsConfig{
+		Sink:     MetricsSinkDogStatsD,
+		Address:  "statsd.monitoring:8125",
+		Prefix:   DefaultMetricsPrefix,
+		Tags:     []string{"team:web", "env:staging"},
+		Interval: Duration(30 * time.Second),
+	}, cfg.Metrics)
+	assert.Equal(t, StatusConfig{ListenAddr: "127.0.0.1:9000", FileInterval: Duration(time.Second)}, cfg.Status)
+	assert.Equal(t, ReadinessConfig{File: "/app-files/.sidecar/ready", UnreadyDuringPush: true}, cfg.Readiness)
+	assert.Equal(t, CoordinationConfig{
+		Mode:           CoordinationModeKubernetes,
+		LeaseName:      "bifrost-sidecar-dep-from-env",
+		LeaseDuration:  Duration(30 * time.Second),
+		ListenAddr:     DefaultCoordinationListenAddr,
+		AdvertiseAddr:  "10.0.0.7:7980",
+		PeerTokenPath:  "/var/run/secrets/peer/token",
+		ReplicaTimeout: Duration(90 * time.Second),
+	}, cfg.Coordination)
+	assert.Equal(t, DatabaseConfig{
+		Provider: envfile.ProviderAWSSecretsManager,
+		AWS: envfile.AWSSecretsConfig{
+			Region: "eu-west-1",
+			Secrets: []envfile.AWSSecret{
+				{EnvVar: "DATABASE_URL", SecretID: "prod/app/db"},
+				{
+					EnvVar:   "ANALYTICS_URL",
+					SecretID: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:rds!cluster-1",
+					Scope:    "worker",
+					Engine:   "aurora-postgresql",
+					Host:     "analytics.cluster-abc.eu-west-1.rds.amazonaws.com",
+					Port:     5432,
+					Database: "analytics",
+				},
+			},
+		},
+		MigrationCommand: "alembic upgrade head",
+		MigrationTimeout: Duration(10 * time.Minute),
+	}, cfg.Database)
+	assert.IsType(t, &envfile.AWSSecretsProvider{}, cfg.DatabaseEnvProvider(nil))
+}
+
+func TestLoadConfig_ValidationErrors(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, `
+api:
+  url: proxy:8000
+signals:
+  reload: SIGKILL
+  target: session
+  shutdown: SIGSTOP
+  launchers:
+    web.1:
+      reload: SIGHUP
+    worker:
+      reload: SIGUSR3
+      target: pgroup
+reload:
+  strategy: webhook
+  timeout: 0s
+timeouts:
+  shutdown: 0s
+sync:
+  apply_mode: overwrite
+  push_debounce: -1s
+  sequence_gap_timeout: -1s
+  push_windows: ["Mon-Fri 9-17"]
+  push_window_timezone: Mars/Olympus
+  require_approval: true
+  approval_timeout: -1s
+  env_history: -1
+  retry_attempts: 0
+  max_delete_percent: 150
+  batch_cache_size: -1
+  subtree_workers: -1
+  protected_paths: [""]
+quota:
+  soft_inodes: 10
+  hard_inodes: 5
+resources:
+  rsync_nice: 20
+  rsync_io_class: realtime
+  batch_memory_buffer: -1
+log:
+  level: loud
+health:
+  url: localhost:8080
+  tcp_address: localhost
+install:
+  manifests: ["web/package.json", "[a-"]
+  timeout: 0s
+build:
+  timeout: 0s
+warm:
+  timeout: 0s
+webhooks:
+  urls: ["hooks.example.com/T0/secret"]
+  events: [push.succeeded]
+  timeout: 0s
+metrics:
+  sink: prometheus
+  address: localhost
+  tags: [":web"]
+  interval: 0s
+secrets:
+  vault:
+    address: vault:8200
+permissions:
+  uid: -2
+  add_mode: "0999"
+security:
+  shared_gid: -5
+status:
+  listen_addr: localhost
+  file_interval: 0s
+readiness:
+  file: ready
+coordination:
+  mode: kubernetes
+  lease_name: Sidecars_1
+  lease_duration: 2500ms
+  listen_addr: "7980"
+  replica_timeout: 0s
+database:
+  provider: aws_secrets_manager
+  aws:
+    endpoint: secretsmanager.internal
+    secrets:
+      - env_var: 1DB
+        port: 70000
+  migration_timeout: 0s
+`))
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	for _, problem := range []string{
+		"app_id is required: set app_id in the config file or the BIFROST_APP_ID environment variable",
+		"deployment_id is required",
+		"api.api_key is required",
+		`api.url "proxy:8000" must be an absolute`,
+		`signals.reload: unsupported signal "SIGKILL"`,
+		`signals.target: unsupported signal target "session"`,
+		`signals.shutdown: unsupported signal "SIGSTOP"`,
+		`signals.launchers: name "web.1" may only contain letters, digits, - and _`,
+		`signals.launchers.worker.reload: unsupported signal "SIGUSR3"`,
+		`signals.launchers.worker.target: unsupported signal target "pgroup"`,
+		`reload.strategy "webhook" must be "signal", "http", "command", "restart" or "overlap"`,
+		"reload.timeout must be greater than zero",
+		`readiness.file "ready" must be an absolute path`,
+		"timeouts.shutdown must be greater than zero",
+		`sync.apply_mode "overwrite" must be "in_place" or "swap"`,
+		"sync.push_debounce must not be negative",
+		"sync.sequence_gap_timeout must not be negative",
+		"sync.env_history must not be negative",
+		`sync.push_windows: push window "Mon-Fri 9-17": invalid time "9", use HH:MM`,
+		`sync.push_window_timezone "Mars/Olympus" is not a known time zone`,
+		`sync.require_approval needs sync.apply_mode "swap"`,
+		"sync.require_approval can't be combined with coordination.mode",
+		"sync.approval_timeout must not be negative",
+		"sync.retry_attempts must be at least 1",
+		"sync.max_delete_percent must be between 0 and 100",
+		"sync.batch_cache_size must not be negative",
+		"sync.subtree_workers must not be negative",
+		`sync.protected_paths entry "" must be a non-empty single-line pattern`,
+		"quota.soft_inodes must not be more than quota.hard_inodes",
+		"resources.rsync_nice must be between 0 and 19",
+		`resources.rsync_io_class "realtime" must be "best-effort" or "idle"`,
+		"resources.batch_memory_buffer must not be negative",
+		`log.level "loud" must be one of`,
+		"health.url and health.tcp_address can't both be set",
+		`health.url "localhost:8080" must be an absolute`,
+		`health.tcp_address "localhost" must be a host:port address`,
+		`install.manifests entry "web/package.json" must be a file name or pattern without /`,
+		`install.manifests entry "[a-" must be a file name or pattern without /`,
+		"install.timeout must be greater than zero",
+		"build.timeout must be greater than zero",
+		"warm.timeout must be greater than zero",
+		"webhooks.urls[0] must be an absolute http:// or https:// URL",
+		`webhooks.events entry "push.succeeded" must be one of push.applied, push.failed, drift.detected, launcher.crashed`,
+		"webhooks.timeout must be greater than zero",
+		`metrics.sink "prometheus" must be empty, "statsd" or "dogstatsd"`,
+		`metrics.address "localhost" must be a host:port address`,
+		`metrics.tags entry ":web" must be a key:value tag`,
+		"metrics.interval must be greater than zero",
+		`secrets.vault.address "vault:8200" must be an absolute`,
+		"secrets.vault.token_path is required",
+		"permissions.uid must not be negative",
+		`permissions.add_mode: "0999" must be octal mode bits`,
+		"security.shared_gid must not be negative",
+		`status.listen_addr "localhost" must be a host:port address`,
+		"status.file_interval must be greater than zero",
+		`coordination.lease_name "Sidecars_1" must be a lowercase DNS name`,
+		"coordination.lease_duration must be a whole number of seconds, at least 3s",
+		`coordination.listen_addr "7980" must be a host:port address`,
+		"coordination.advertise_addr is required",
+		"coordination.replica_timeout must be greater than zero",
+		"database.aws.region is required",
+		`database.aws.endpoint "secretsmanager.internal" must be an absolute`,
+		`database.aws.secrets[0].env_var: invalid env var name "1DB"`,
+		"database.aws.secrets[0].secret_id is required",
+		"database.aws.secrets[0].port 70000 is not a valid port",
+		"database.migration_timeout must be greater than zero",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+}
+
+func TestLoadConfig_DatabaseProvider(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_APP_ID", "app-1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "dep-1")
+	t.Setenv("BIFROST_API_KEY", "key")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+
+	t.Setenv("BIFROST_DATABASE_PROVIDER", "aws_secrets_manager")
+	t.Setenv("BIFROST_DATABASE_AWS_REGION", "us-east-2")
+	t.Setenv("BIFROST_DATABASE_AWS_SECRET_ID", "prod/db")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, envfile.AWSSecretsConfig{Region: "us-east-2", Secrets: []envfile.AWSSecret{{EnvVar: "DATABASE_URL", SecretID: "prod/db"}}}, cfg.Database.AWS)
+
+	t.Setenv("BIFROST_DATABASE_PROVIDER", "kubernetes_secret")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "database.kubernetes.name is required")
+	t.Setenv("BIFROST_DATABASE_KUBERNETES_SECRET", "db-credentials")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.IsType(t, &envfile.KubernetesSecretProvider{}, cfg.DatabaseEnvProvider(nil))
+
+	t.Setenv("BIFROST_DATABASE_PROVIDER", "neon")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `database.provider "neon" must be one of bifrost, aws_secrets_manager, kubernetes_secret`)
+}
+
+func TestLoadConfig_ReloadSignalNone(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_APP_ID", "app-1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "dep-1")
+	t.Setenv("BIFROST_API_KEY", "key")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+	t.Setenv("BIFROST_RELOAD_SIGNAL", "None")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, launcher.NoSignal, cfg.ReloadSignal())
+
+	t.Setenv("BIFROST_SHUTDOWN_SIGNAL", "none")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `signals.shutdown: unsupported signal "none"`, "an empty shutdown signal forwards nothing")
+}
+
+func TestLauncherSignal(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Signals.Reload = "SIGUSR1"
+	cfg.Signals.Target = "group"
+	cfg.Signals.Launchers = map[string]LauncherSignals{"worker": {Reload: "none"}, "cron": {Target: "tree"}}
+
+	sig, target := cfg.LauncherSignal(launcher.MainLauncher)
+	assert.Equal(t, syscall.SIGUSR1, sig)
+	assert.Equal(t, launcher.SignalGroup, target)
+	sig, target = cfg.LauncherSignal("worker")
+	assert.Equal(t, launcher.NoSignal, sig)
+	assert.Equal(t, launcher.SignalGroup, target)
+	sig, target = cfg.LauncherSignal("cron")
+	assert.Equal(t, syscall.SIGUSR1, sig)
+	assert.Equal(t, launcher.SignalTree, target)
+}
+
+func TestLoadConfig_FileErrors(t *testing.T) {
+	clearConfigEnv(t)
+
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, "api:\n  uri: http://typo\n"))
+	_, err := LoadConfig()
+	assert.ErrorContains(t, err, "field uri not found")
+
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, "timeouts:\n  hook: soon\n"))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `invalid duration "soon"`)
+
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, "quota:\n  hard_bytes: lots\n"))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `invalid size "lots"`)
+
+	t.Setenv("BIFROST_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "failed to read config file")
+
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, ""))
+	t.Setenv("BIFROST_STATUS_INTERVAL", "often")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "invalid BIFROST_STATUS_INTERVAL")
+}
+
+func TestLoadConfig_ReloadStrategy(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_CONFIG", writeConfigFile(t, `
+app_id: app-1
+deployment_id: dep-1
+api:
+  url: http://proxy:8000
+  api_key: key
+reload:
+  strategy: http
+  url: http://localhost:8080/reload
+  timeout: 10s
+`))
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, ReloadStrategyHTTP, cfg.Reload.Strategy)
+	assert.Equal(t, "http://localhost:8080/reload", cfg.Reload.URL)
+	assert.Equal(t, Duration(10*time.Second), cfg.Reload.Timeout)
+
+	t.Setenv("BIFROST_RELOAD_URL", "localhost:8080/reload")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `reload.url "localhost:8080/reload" must be an absolute http:// or https:// URL`)
+
+	t.Setenv("BIFROST_RELOAD_STRATEGY", "command")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "reload.command must be set with the command strategy")
+	t.Setenv("BIFROST_RELOAD_COMMAND", "touch tmp/restart.txt")
+	t.Setenv("BIFROST_RELOAD_TIMEOUT", "5s")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "touch tmp/restart.txt", cfg.Reload.Command)
+	assert.Equal(t, Duration(5*time.Second), cfg.Reload.Timeout)
+}
+
+func TestLoadConfig_OverlapReload(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BIFROST_APP_ID", "app-1")
+	t.Setenv("BIFROST_DEPLOYMENT_ID", "dep-1")
+	t.Setenv("BIFROST_API_KEY", "key")
+	t.Setenv("BIFROST_API_URL", "http://proxy:8000")
+	t.Setenv("BIFROST_RELOAD_STRATEGY", "overlap")
+	t.Setenv("BIFROST_RELOAD_DRAIN_SIGNAL", "SIGQUIT")
+	t.Setenv("BIFROST_RELOAD_DRAIN_TIMEOUT", "45s")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "SIGQUIT", cfg.Reload.DrainSignal)
+	assert.Equal(t, Duration(45*time.Second), cfg.Reload.DrainTimeout)
+
+	t.Setenv("BIFROST_RELOAD_SIGNAL", "none")
+	t.Setenv("BIFROST_RELOAD_DRAIN_SIGNAL", "SIGKILL")
+	t.Setenv("BIFROST_RELOAD_DRAIN_TIMEOUT", "500ms")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `signals.reload can't be "none" with the overlap reload strategy`)
+	assert.ErrorContains(t, err, `reload.drain_signal: unsupported signal "SIGKILL"`)
+	assert.ErrorContains(t, err, "reload.drain_timeout must be at least 1s")
+}
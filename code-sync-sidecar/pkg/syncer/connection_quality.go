@@ -0,0 +1,197 @@
+package syncer
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// maxPongWait bounds how far the read deadline stretches on a slow network.
+	maxPongWait = 2 * time.Minute
+
+	// reconnectWindow is how far back reconnects are counted.
+	reconnectWindow = time.Hour
+
+	// A connection is degraded once its smoothed round trip time or the
+	// reconnects in the last window reach these.
+	degradedRTT        = 500 * time.Millisecond
+	degradedReconnects = 3
+
+	// minChunkSize is the smallest chunk sent however slow the network, and
+	// chunkSendTarget how long sending one chunk should take. maxChunkSize is
+	// the chunk size reported for a network that keeps up.
+	minChunkSize    = 16 * 1024
+	maxChunkSize    = 1 << 20
+	chunkSendTarget = 2 * time.Second
+
+	// minThroughputSample is the smallest message the throughput is measured
+	// from; smaller ones fit in the socket buffers and take no time to write.
+	minThroughputSample = 64 * 1024
+)
+
+// connectionQuality measures the connection to the control plane: the round
+// trip time of pings, how often it reconnects, and how long messages take to
+// send and receive. The read deadline and the size of the chunks the sidecar
+// sends adapt to it, and it is reported in status reports so the control plane
+// can flag deployments on poor networks. The zero value is ready to use.
+type connectionQuality struct {
+	mu sync.Mutex
+	// srtt and rttvar are smoothed the way TCP does its retransmission timer
+	// (RFC 6298); rttSamples is 0 until a pong arrived.
+	srtt, rttvar time.Duration
+	rttSamples   int
+	reconnects   []time.Time
+	latency      time.Duration // Smoothed time to receive a message
+	throughput   float64       // Smoothed bytes per second of large messages
+	degraded     bool
+}
+
+// pingPayload stamps a ping with when it was sent; the pong echoes it back.
+func pingPayload(now time.Time) []byte {
+	return strconv.AppendInt(nil, now.UnixNano(), 10)
+}
+
+// recordPong measures the round trip time from a pong echoing pingPayload.
+// Pongs without a stamp, which servers may send unprompted, are ignored.
+func (q *connectionQuality) recordPong(payload string, now time.Time) {
+	sent, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return
+	}
+	rtt := now.Sub(time.Unix(0, sent))
+	if rtt < 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.rttSamples == 0 {
+		q.srtt, q.rttvar = rtt, rtt/2
+	} else {
+		q.rttvar = (3*q.rttvar + (q.srtt - rtt).Abs()) / 4
+		q.srtt = (7*q.srtt + rtt) / 8
+	}
+	q.rttSamples++
+	q.checkDegraded(now)
+}
+
+// recordReconnect counts a reconnect at now.
+func (q *connectionQuality) recordReconnect(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reconnects = append(q.recentReconnects(now), now)
+	q.checkDegraded(now)
+}
+
+// recordReceived measures a message of size bytes that took received to
+// arrive once its first frame did.
+func (q *connectionQuality) recordReceived(size int, received time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.latency = (7*q.latency + received) / 8
+	q.recordTransfer(size, received)
+}
+
+// recordSent measures a message of size bytes that took took to write.
+func (q *connectionQuality) recordSent(size int, took time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recordTransfer(size, took)
+}
+
+func (q *connectionQuality) recordTransfer(size int, took time.Duration) {
+	if size < minThroughputSample || took <= 0 {
+		return
+	}
+	rate := float64(size) / took.Seconds()
+	if q.throughput == 0 {
+		q.throughput = rate
+	} else {
+		q.throughput = (7*q.throughput + rate) / 8
+	}
+}
+
+// readDeadline returns how long to wait for a pong, or any other message,
+// before giving up on the connection: pongWait, stretched by the time the
+// network takes to answer, and doubled while it keeps reconnecting.
+func (q *connectionQuality) readDeadline() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.readDeadlineLocked(time.Now())
+}
+
+func (q *connectionQuality) readDeadlineLocked(now time.Time) time.Duration {
+	wait := pongWait
+	if q.rttSamples > 0 {
+		wait += 4 * (q.srtt + 4*q.rttvar)
+	}
+	if len(q.recentReconnects(now)) >= degradedReconnects {
+		wait *= 2
+	}
+	return min(wait, maxPongWait)
+}
+
+// chunkSize returns the size of the chunks to send data in, at most limit:
+// what the measured throughput sends in chunkSendTarget, so a chunk never
+// holds up the messages behind it for long.
+func (q *connectionQuality) chunkSize(limit int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.chunkSizeLocked(limit)
+}
+
+func (q *connectionQuality) chunkSizeLocked(limit int) int {
+	if q.throughput == 0 {
+		return limit
+	}
+	return max(min(int(q.throughput*chunkSendTarget.Seconds()), limit), min(minChunkSize, limit))
+}
+
+// recentReconnects returns the reconnects within reconnectWindow of now.
+func (q *connectionQuality) recentReconnects(now time.Time) []time.Time {
+	for len(q.reconnects) > 0 && now.Sub(q.reconnects[0]) > reconnectWindow {
+		q.reconnects = q.reconnects[1:]
+	}
+	return q.reconnects
+}
+
+// checkDegraded logs when the connection becomes degraded or recovers.
+func (q *connectionQuality) checkDegraded(now time.Time) {
+	degraded := q.srtt >= degradedRTT || len(q.recentReconnects(now)) >= degradedReconnects
+	if degraded == q.degraded {
+		return
+	}
+	q.degraded = degraded
+	fields := []zap.Field{
+		zap.Duration("rtt", q.srtt),
+		zap.Int("reconnectsLastHour", len(q.reconnects)),
+		zap.Duration("readDeadline", q.readDeadlineLocked(now)),
+	}
+	if degraded {
+		log.Warn("Connection to the control plane is degraded", fields...)
+	} else {
+		log.Info("Connection to the control plane recovered", fields...)
+	}
+}
+
+// addStats adds the connection's quality to stats.
+func (q *connectionQuality) addStats(stats *pb.ConnectionStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	// Reconnects age out of the window without anything else noticing.
+	q.checkDegraded(now)
+	stats.RttMs = q.srtt.Milliseconds()
+	stats.RttVarianceMs = q.rttvar.Milliseconds()
+	stats.ReconnectsLastHour = int32(len(q.recentReconnects(now)))
+	stats.MessageLatencyMs = q.latency.Milliseconds()
+	stats.ThroughputBytesPerSecond = int64(q.throughput)
+	stats.ReadDeadlineMs = q.readDeadlineLocked(now).Milliseconds()
+	stats.ChunkSizeBytes = int32(q.chunkSizeLocked(maxChunkSize))
+	stats.Degraded = q.degraded
+}
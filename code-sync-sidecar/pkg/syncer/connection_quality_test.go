@@ -0,0 +1,88 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestConnectionQuality_RTT(t *testing.T) {
+	var q connectionQuality
+	assert.Equal(t, pongWait, q.readDeadline(), "the deadline isn't stretched before a pong arrives")
+
+	now := time.Now()
+	q.recordPong(string(pingPayload(now.Add(-100*time.Millisecond))), now)
+	assert.Equal(t, 100*time.Millisecond, q.srtt)
+	assert.Equal(t, 50*time.Millisecond, q.rttvar)
+	assert.Equal(t, pongWait+4*(300*time.Millisecond), q.readDeadline())
+
+	q.recordPong("unprompted", now)
+	q.recordPong(string(pingPayload(now.Add(time.Second))), now)
+	assert.Equal(t, 1, q.rttSamples, "pongs without a stamp from the past are ignored")
+	assert.False(t, q.degraded)
+
+	for range 20 {
+		q.recordPong(string(pingPayload(now.Add(-30*time.Second))), now)
+	}
+	assert.True(t, q.degraded)
+	assert.Equal(t, maxPongWait, q.readDeadline())
+}
+
+func TestConnectionQuality_Reconnects(t *testing.T) {
+	var q connectionQuality
+	now := time.Now()
+	q.recordReconnect(now.Add(-2 * time.Hour))
+	q.recordReconnect(now.Add(-time.Minute))
+	q.recordReconnect(now.Add(-time.Second))
+	assert.False(t, q.degraded, "reconnects over an hour ago don't count")
+	q.recordReconnect(now)
+	assert.True(t, q.degraded)
+	assert.Equal(t, 2*pongWait, q.readDeadline())
+
+	stats := &pb.ConnectionStats{}
+	q.addStats(stats)
+	assert.Equal(t, int32(3), stats.ReconnectsLastHour)
+	assert.Equal(t, (2 * pongWait).Milliseconds(), stats.ReadDeadlineMs)
+	assert.True(t, stats.Degraded)
+}
+
+func TestConnectionQuality_ChunkSize(t *testing.T) {
+	var q connectionQuality
+	assert.Equal(t, diagnosticsChunkSize, q.chunkSize(diagnosticsChunkSize), "the full size until the throughput is known")
+
+	q.recordSent(1024, time.Second)
+	assert.Zero(t, q.throughput, "small messages don't measure the throughput")
+	q.recordReceived(minThroughputSample, 2*time.Second)
+	assert.Equal(t, float64(minThroughputSample/2), q.throughput)
+	assert.Equal(t, minThroughputSample, q.chunkSize(diagnosticsChunkSize))
+	assert.Equal(t, 4096, q.chunkSize(4096))
+
+	q.throughput = 1024
+	assert.Equal(t, minChunkSize, q.chunkSize(diagnosticsChunkSize))
+
+	stats := &pb.ConnectionStats{}
+	q.addStats(stats)
+	assert.Equal(t, int64(1024), stats.ThroughputBytesPerSecond)
+	assert.Equal(t, int32(minChunkSize), stats.ChunkSizeBytes)
+	assert.Equal(t, int64(250), stats.MessageLatencyMs)
+}
+
+func TestHandlePongs(t *testing.T) {
+	conn, _ := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{conn: conn}
+	rw.handlePongs()
+	require.NoError(t, rw.writePing())
+
+	// Control frames are handled while reading.
+	go conn.NextReader()
+	assert.Eventually(t, func() bool {
+		rw.quality.mu.Lock()
+		defer rw.quality.mu.Unlock()
+		return rw.quality.rttSamples == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
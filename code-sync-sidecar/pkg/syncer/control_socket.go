@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/control"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+// ServeControl serves the control socket in the files directory until ctx is
+// done, for the launcher and the app to talk back to the sidecar.
+func (rw *FileSyncer) ServeControl(ctx context.Context) {
+	path := control.SocketPath(rw.targetSyncDir)
+	log.Info("Serving control socket", zap.String("path", path))
+	if err := control.Serve(ctx, path, rw.handleControl); err != nil {
+		// The sidecar works without it; only integrations that use it are affected.
+		log.Warn("Control socket stopped", zap.String("path", path), zap.Error(err))
+	}
+}
+
+func (rw *FileSyncer) handleControl(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case control.MethodGetEnvVersion:
+		envFiles, err := envfile.List(rw.targetSyncDir)
+		if err != nil {
+			return nil, err
+		}
+		return control.EnvVersionResult{EnvFiles: envFiles}, nil
+
+	case control.MethodGetLastPush:
+		rw.stateMu.Lock()
+		applied := rw.applied
+		rw.stateMu.Unlock()
+		result := control.LastPushResult{PushID: applied.LastPushID, PushHash: applied.LastPushHash}
+		if !applied.AppliedAt.IsZero() {
+			result.AppliedAt = &applied.AppliedAt
+		}
+		return result, nil
+
+	case control.MethodReportReadiness:
+		var readiness control.ReadinessParams
+		if err := json.Unmarshal(params, &readiness); err != nil {
+			return nil, control.InvalidParams("%s needs {\"ready\": true|false}: %v", method, err)
+		}
+		rw.stateMu.Lock()
+		changed := rw.appReadiness == nil || rw.appReadiness.Ready != readiness.Ready
+		rw.appReadiness = &pb.AppReadiness{Ready: readiness.Ready, Message: readiness.Message, ReportedAt: timestamppb.Now()}
+		rw.stateMu.Unlock()
+		if changed {
+			log.Info("App reported its readiness", zap.Bool("ready", readiness.Ready), zap.String("message", readiness.Message))
+			rw.notifyStatusChanged()
+		}
+		return struct{}{}, nil
+
+	case control.MethodRequestResync:
+		var resync control.ResyncParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &resync); err != nil {
+				return nil, control.InvalidParams("%s params: %v", method, err)
+			}
+		}
+		detail := "requested by the app"
+		if resync.Detail != "" {
+			detail += ": " + resync.Detail
+		}
+		rw.requestResync(pb.ResyncRequest_APP_REQUESTED, detail)
+		return struct{}{}, nil
+	}
+	return nil, &control.Error{Code: control.CodeMethodNotFound, Message: "unknown method " + method}
+}
@@ -0,0 +1,62 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/control"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestServeControl(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(rw.targetSyncDir), 0755))
+	_, err := envfile.Write(rw.targetSyncDir, "", []byte("export DATABASE_URL='postgres://db/app'\n"), "")
+	require.NoError(t, err)
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rw.ServeControl(ctx)
+	var client *control.Client
+	require.Eventually(t, func() bool {
+		client, err = control.Dial(control.SocketPath(rw.targetSyncDir))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer client.Close()
+
+	var envVersion control.EnvVersionResult
+	require.NoError(t, client.Call(control.MethodGetEnvVersion, nil, &envVersion))
+	envFiles, err := envfile.List(rw.targetSyncDir)
+	require.NoError(t, err)
+	assert.Equal(t, envFiles, envVersion.EnvFiles)
+
+	var last control.LastPushResult
+	require.NoError(t, client.Call(control.MethodGetLastPush, nil, &last))
+	assert.Equal(t, "push-1", last.PushID)
+	assert.Equal(t, batchHash([]byte("batch")), last.PushHash)
+	require.NotNil(t, last.AppliedAt)
+
+	// Readiness is passed on in status reports.
+	assert.Nil(t, rw.buildStatusReport().GetStatusReport().AppReadiness)
+	require.NoError(t, client.Call(control.MethodReportReadiness, control.ReadinessParams{Ready: false, Message: "warming caches"}, nil))
+	readiness := rw.buildStatusReport().GetStatusReport().AppReadiness
+	require.NotNil(t, readiness)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, "warming caches", readiness.Message)
+	var rpcErr *control.Error
+	require.ErrorAs(t, client.Call(control.MethodReportReadiness, "ready", nil), &rpcErr)
+	assert.Equal(t, control.CodeInvalidParams, rpcErr.Code)
+
+	require.NoError(t, client.Call(control.MethodRequestResync, control.ResyncParams{Detail: "cache is corrupt"}, nil))
+	resync := waitForResyncRequest(t, mockServer)
+	assert.Equal(t, pb.ResyncRequest_APP_REQUESTED, resync.Reason)
+	assert.Equal(t, "requested by the app: cache is corrupt", resync.Detail)
+}
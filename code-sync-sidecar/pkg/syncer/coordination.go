@@ -0,0 +1,264 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/coordination"
+)
+
+// FeatureCoordination is advertised in HELLO when coordination.mode is set, so
+// the control plane expects replica results in push responses.
+const FeatureCoordination = "coordination"
+
+// leaseNamePattern matches a Kubernetes object name (a DNS subdomain).
+var leaseNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+
+var errNoLeader = errors.New("no coordination leader has been elected yet")
+
+// coordinator is this sidecar's part in coordinating the deployment's replicas.
+// Only the leader connects to the control plane; the others connect to the
+// leader's Hub and apply the pushes it forwards.
+type coordinator struct {
+	elector        *coordination.LeaseElector
+	hub            *coordination.Hub
+	identity       string
+	listenAddr     string
+	peerToken      string
+	replicaTimeout time.Duration
+}
+
+// newCoordinator sets up coordination as configured, or returns nil if it is
+// disabled. It needs Pod, which the command sets before creating the syncer.
+func newCoordinator(cfg *Config) (*coordinator, error) {
+	if cfg.Coordination.Mode == CoordinationModeNone {
+		return nil, nil
+	}
+	apiURL := kubernetesAPIURL()
+	if apiURL == "" {
+		return nil, fmt.Errorf("coordination mode %q needs the Kubernetes API, but KUBERNETES_SERVICE_HOST is not set", cfg.Coordination.Mode)
+	}
+	client, err := kubernetesClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the cluster CA: %w", err)
+	}
+	namespace := Pod.GetNamespace()
+	if namespace == "" {
+		return nil, fmt.Errorf("the pod's namespace is unknown; set %s from the downward API", podNamespaceEnv)
+	}
+	identity := Pod.GetPodName()
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to determine the pod name: %w", err)
+		}
+	}
+	var peerToken string
+	if path := cfg.Coordination.PeerTokenPath; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read peer token: %w", err)
+		}
+		if peerToken = strings.TrimSpace(string(data)); peerToken == "" {
+			return nil, fmt.Errorf("peer token file %s is empty", path)
+		}
+	}
+
+	return &coordinator{
+		elector: coordination.NewLeaseElector(coordination.LeaseConfig{
+			APIURL:    apiURL,
+			Namespace: namespace,
+			Name:      cfg.Coordination.LeaseName,
+			Identity:  identity,
+			Address:   cfg.Coordination.AdvertiseAddr,
+			Duration:  time.Duration(cfg.Coordination.LeaseDuration),
+			Client:    client,
+			Token:     kubernetesToken,
+		}),
+		hub:            coordination.NewHub(peerToken),
+		identity:       identity,
+		listenAddr:     cfg.Coordination.ListenAddr,
+		peerToken:      peerToken,
+		replicaTimeout: time.Duration(cfg.Coordination.ReplicaTimeout),
+	}, nil
+}
+
+// startCoordination serves the Hub and takes part in the election until ctx
+// is done or the syncer stops. Whenever the leader changes, the current
+// connection is closed so run reconnects to the right place.
+func (rw *FileSyncer) startCoordination(ctx context.Context) error {
+	c := rw.coordinator
+	listener, err := net.Listen("tcp", c.listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", c.listenAddr, err)
+	}
+	server := &http.Server{Handler: c.hub.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Coordination hub stopped", zap.Error(err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-rw.done:
+		}
+		cancel()
+		server.Close()
+	}()
+	go c.elector.Run(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.elector.Changed():
+				rw.writeMu.Lock()
+				if rw.conn != nil {
+					rw.conn.Close()
+				}
+				rw.writeMu.Unlock()
+			}
+		}
+	}()
+	log.Info("Coordination enabled",
+		zap.String("identity", c.identity),
+		zap.String("listenAddr", c.listenAddr))
+	return nil
+}
+
+// coordinationHub returns the Hub if this sidecar is the coordination leader.
+func (rw *FileSyncer) coordinationHub() *coordination.Hub {
+	if rw.coordinator == nil {
+		return nil
+	}
+	if _, self := rw.coordinator.elector.Leader(); !self {
+		return nil
+	}
+	return rw.coordinator.hub
+}
+
+// connectionTarget is where run connects: the control plane, or with
+// coordination the leader's Hub when this sidecar is a follower.
+type connectionTarget struct {
+	url     string
+	headers http.Header
+	peer    bool
+}
+
+func (rw *FileSyncer) connectionTarget(ctx context.Context) (connectionTarget, error) {
+	if rw.coordinator != nil {
+		leader, self := rw.coordinator.elector.Leader()
+		if !self {
+			if leader.Address == "" {
+				return connectionTarget{}, errNoLeader
+			}
+			headers := http.Header{}
+			headers.Set(coordination.ReplicaIDHeader, rw.coordinator.identity)
+			if rw.coordinator.peerToken != "" {
+				headers.Set(coordination.PeerTokenHeader, rw.coordinator.peerToken)
+			}
+			return connectionTarget{
+				url:     fmt.Sprintf("ws://%s/api/v1/push/sidecar/%s/%s", leader.Address, url.PathEscape(rw.appID), url.PathEscape(rw.deploymentID)),
+				headers: headers,
+				peer:    true,
+			}, nil
+		}
+	}
+	headers, err := rw.tokens.AuthHeaders(ctx)
+	if err != nil {
+		return connectionTarget{}, fmt.Errorf("failed to obtain credentials for WebSocket connection: %w", err)
+	}
+	return connectionTarget{url: rw.buildWebSocketURL(), headers: headers}, nil
+}
+
+// runCoordinatedPush applies a push on the leader and forwards it to the
+// followers, then sends a single final response covering every replica in
+// place of the leader's own.
+func (rw *FileSyncer) runCoordinatedPush(ctx context.Context, hub *coordination.Hub, pushMsg *pb.PushMessage) {
+	// Followers get the batch in the push, even one the leader spooled.
+	forwarded, err := rw.withBatchData(pushMsg)
+	if err != nil {
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_FAILED, fmt.Sprintf("Failed to read spooled batch to forward: %v", err)))
+		return
+	}
+	followers := hub.Forward(forwarded)
+	if len(followers) > 0 {
+		log.Info("Forwarded push to followers", zap.String("pushID", pushMsg.PushId), zap.Strings("followers", followers))
+	}
+
+	q := &rw.pushes
+	q.heldMu.Lock()
+	q.heldID, q.heldResp = pushMsg.PushId, nil
+	q.heldMu.Unlock()
+	err = rw.handlePushRequest(ctx, pushMsg)
+	q.heldMu.Lock()
+	own := q.heldResp
+	q.heldID, q.heldResp = "", nil
+	q.heldMu.Unlock()
+	if err != nil {
+		log.Error("Error handling push request", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+	}
+	if own == nil {
+		own = buildPushResponse(pushMsg.PushId, pb.PushResponse_FAILED, fmt.Sprintf("Push ended without a result: %v", err))
+	}
+
+	results := hub.Results(rw.done, pushMsg.PushId, followers, rw.coordinator.replicaTimeout)
+	rw.sendProtoMessage(aggregateReplicaResults(own, rw.coordinator.identity, results))
+}
+
+// holdFinalResponse keeps the final response of the push runCoordinatedPush is
+// running from being sent, and reports whether it did.
+func (q *pushQueue) holdFinalResponse(msg *pb.WebsocketMessage) bool {
+	resp := msg.GetPushResponse()
+	q.heldMu.Lock()
+	defer q.heldMu.Unlock()
+	if q.heldID == "" || resp.GetPushId() != q.heldID || isIntermediatePushStatus(resp.GetStatus()) {
+		return false
+	}
+	q.heldResp = msg
+	return true
+}
+
+// aggregateReplicaResults adds the leader's and the followers' results to the
+// leader's final response. A push the leader completed becomes PARTIAL if any
+// follower didn't complete it too; a follower that skipped it for a later push
+// counts as completed, since it applies that push's files instead.
+func aggregateReplicaResults(msg *pb.WebsocketMessage, leaderID string, followers []*pb.ReplicaResult) *pb.WebsocketMessage {
+	resp := msg.GetPushResponse()
+	resp.ReplicaResults = append([]*pb.ReplicaResult{{
+		ReplicaId:    leaderID,
+		Status:       resp.Status,
+		ErrorMessage: resp.ErrorMessage,
+		Leader:       true,
+	}}, followers...)
+	if resp.Status != pb.PushResponse_COMPLETED {
+		return msg
+	}
+
+	var missing []string
+	for _, result := range followers {
+		if result.Status != pb.PushResponse_COMPLETED && result.Status != pb.PushResponse_SUPERSEDED {
+			missing = append(missing, fmt.Sprintf("%s (%s)", result.ReplicaId, result.Status))
+		}
+	}
+	if len(missing) > 0 {
+		total := len(resp.ReplicaResults)
+		resp.Status = pb.PushResponse_PARTIAL
+		resp.ErrorMessage = fmt.Sprintf("Applied by %d of %d replicas; not by %s", total-len(missing), total, strings.Join(missing, ", "))
+	}
+	return msg
+}
@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/coordination"
+)
+
+func TestAggregateReplicaResults(t *testing.T) {
+	msg := aggregateReplicaResults(buildPushResponse("push-1", pb.PushResponse_COMPLETED, ""), "pod-a", []*pb.ReplicaResult{
+		{ReplicaId: "pod-b", Status: pb.PushResponse_COMPLETED},
+		{ReplicaId: "pod-c", Status: pb.PushResponse_SUPERSEDED},
+	})
+	resp := msg.GetPushResponse()
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status)
+	require.Len(t, resp.ReplicaResults, 3)
+	assert.Equal(t, "pod-a", resp.ReplicaResults[0].ReplicaId)
+	assert.True(t, resp.ReplicaResults[0].Leader)
+	assert.False(t, resp.ReplicaResults[1].Leader)
+
+	msg = aggregateReplicaResults(buildPushResponse("push-2", pb.PushResponse_COMPLETED, ""), "pod-a", []*pb.ReplicaResult{
+		{ReplicaId: "pod-b", Status: pb.PushResponse_FAILED, ErrorMessage: "rsync failed"},
+		{ReplicaId: "pod-c", Status: pb.PushResponse_COMPLETED},
+		{ReplicaId: "pod-d", Status: pb.PushResponse_UNKNOWN},
+	})
+	resp = msg.GetPushResponse()
+	assert.Equal(t, pb.PushResponse_PARTIAL, resp.Status)
+	assert.Equal(t, "Applied by 2 of 4 replicas; not by pod-b (FAILED), pod-d (UNKNOWN)", resp.ErrorMessage)
+
+	// A push the leader itself failed keeps its own status and message.
+	msg = aggregateReplicaResults(buildPushResponse("push-3", pb.PushResponse_FAILED, "hook failed"), "pod-a", []*pb.ReplicaResult{
+		{ReplicaId: "pod-b", Status: pb.PushResponse_COMPLETED},
+	})
+	resp = msg.GetPushResponse()
+	assert.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	assert.Equal(t, "hook failed", resp.ErrorMessage)
+	assert.Equal(t, "hook failed", resp.ReplicaResults[0].ErrorMessage)
+}
+
+func TestHoldFinalResponse(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	var sent []*pb.WebsocketMessage
+	rw.sendOffline = func(msg proto.Message) { sent = append(sent, msg.(*pb.WebsocketMessage)) }
+
+	rw.pushes.heldMu.Lock()
+	rw.pushes.heldID = "push-1"
+	rw.pushes.heldMu.Unlock()
+	require.NoError(t, rw.trySendProtoMessage(buildPushResponse("push-1", pb.PushResponse_APPLYING, "")))
+	require.NoError(t, rw.trySendProtoMessage(buildPushResponse("push-0", pb.PushResponse_COMPLETED, "")))
+	require.NoError(t, rw.trySendProtoMessage(buildPushResponse("push-1", pb.PushResponse_COMPLETED, "")))
+
+	require.Len(t, sent, 2, "only the held push's final response is kept back")
+	assert.Equal(t, pb.PushResponse_APPLYING, sent[0].GetPushResponse().Status)
+	assert.Equal(t, "push-0", sent[1].GetPushResponse().PushId)
+	assert.Equal(t, pb.PushResponse_COMPLETED, rw.pushes.heldResp.GetPushResponse().Status)
+}
+
+func TestConnectionTarget_FollowerWithoutLeader(t *testing.T) {
+	rw := &FileSyncer{}
+	rw.coordinator = &coordinator{
+		elector:  coordination.NewLeaseElector(coordination.LeaseConfig{Duration: 15 * time.Second}),
+		hub:      coordination.NewHub(""),
+		identity: "pod-b",
+	}
+	_, err := rw.connectionTarget(context.Background())
+	assert.ErrorIs(t, err, errNoLeader)
+	assert.Nil(t, rw.coordinationHub())
+	assert.Contains(t, rw.enabledFeatures(), FeatureCoordination)
+}
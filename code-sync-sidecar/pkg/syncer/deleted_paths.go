@@ -0,0 +1,175 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// validateDeletedPaths checks every path a push deletes before anything
+// changes, returning a failed result for each invalid one.
+func validateDeletedPaths(paths []string) []*pb.DeletedPathResult {
+	var failed []*pb.DeletedPathResult
+	for _, path := range paths {
+		if _, err := validateSyncPath(path); err != nil {
+			failed = append(failed, &pb.DeletedPathResult{Path: path, Status: pb.DeletedPathResult_FAILED, ErrorMessage: err.Error()})
+		}
+	}
+	return failed
+}
+
+// previewDeletions reports which paths exist under root, without removing anything.
+func previewDeletions(root string, paths []string) []*pb.DeletedPathResult {
+	results := make([]*pb.DeletedPathResult, 0, len(paths))
+	for _, rel := range paths {
+		result := &pb.DeletedPathResult{Path: rel}
+		results = append(results, result)
+		path, err := resolveDeletedPath(root, rel)
+		if err == nil {
+			_, err = os.Lstat(path)
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			result.Status = pb.DeletedPathResult_NOT_FOUND
+		case err != nil:
+			result.Status, result.ErrorMessage = pb.DeletedPathResult_FAILED, err.Error()
+		default:
+			result.Status = pb.DeletedPathResult_WOULD_REMOVE
+		}
+	}
+	return results
+}
+
+// applyDeletions removes each path under backup.targetDir, moving files into the
+// backup so restoring it brings them back. Removed entries are added to
+// backup.changes as rsync would itemize them. It stops at the first failure.
+func (b *syncBackup) applyDeletions(paths []string) ([]*pb.DeletedPathResult, error) {
+	results := make([]*pb.DeletedPathResult, 0, len(paths))
+	var firstErr error
+	for _, rel := range paths {
+		result := &pb.DeletedPathResult{Path: rel}
+		results = append(results, result)
+		if firstErr != nil {
+			result.Status, result.ErrorMessage = pb.DeletedPathResult_FAILED, "not removed: an earlier path failed"
+			continue
+		}
+		removed, err := b.delete(rel)
+		switch {
+		case err != nil:
+			result.Status, result.ErrorMessage = pb.DeletedPathResult_FAILED, err.Error()
+			firstErr = fmt.Errorf("failed to delete %s: %w", rel, err)
+		case removed:
+			result.Status = pb.DeletedPathResult_REMOVED
+		default:
+			result.Status = pb.DeletedPathResult_NOT_FOUND
+		}
+	}
+	return results, firstErr
+}
+
+func (b *syncBackup) delete(rel string) (bool, error) {
+	rel, err := validateSyncPath(rel)
+	if err != nil {
+		return false, err
+	}
+	path, err := resolveInRoot(b.targetDir, rel)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return true, b.deleteEntry(rel)
+	}
+
+	// Move the files out one by one so each is itemized and can be restored.
+	var entries []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			entryRel, err := filepath.Rel(b.targetDir, p)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entryRel)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if err := b.deleteEntry(entry); err != nil {
+			return false, err
+		}
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	b.changes = append(b.changes, itemizedChange{flags: deletingFlags, path: rel})
+	return true, nil
+}
+
+// deleteEntry removes a file or symlink. Without a backup dir (swap mode, where
+// the whole release is discarded on rollback), or when the backup already holds
+// the original or the batch created the file, it's removed outright.
+func (b *syncBackup) deleteEntry(rel string) error {
+	path := filepath.Join(b.targetDir, rel)
+	if b.dir == "" || b.createdByBatch(rel) {
+		return b.removeEntry(rel, path)
+	}
+	saved := filepath.Join(b.dir, rel)
+	if _, err := os.Lstat(saved); err == nil {
+		return b.removeEntry(rel, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(saved), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory for %s: %w", rel, err)
+	}
+	if err := os.Rename(path, saved); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	b.changes = append(b.changes, itemizedChange{flags: deletingFlags, path: rel})
+	return nil
+}
+
+func (b *syncBackup) removeEntry(rel, path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	b.changes = append(b.changes, itemizedChange{flags: deletingFlags, path: rel})
+	return nil
+}
+
+func (b *syncBackup) createdByBatch(rel string) bool {
+	for _, change := range b.changes {
+		if change.path == rel && !change.isDeleted() && change.isCreated() {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveDeletedPath(root, rel string) (string, error) {
+	clean, err := validateSyncPath(rel)
+	if err != nil {
+		return "", err
+	}
+	return resolveInRoot(root, clean)
+}
+
+// withDeletedPaths attaches per-path deletion results to a push response.
+func withDeletedPaths(msg *pb.WebsocketMessage, results []*pb.DeletedPathResult) *pb.WebsocketMessage {
+	msg.GetPushResponse().DeletedPaths = results
+	return msg
+}
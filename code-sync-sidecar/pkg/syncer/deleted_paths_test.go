@@ -0,0 +1,92 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestApplyDeletions_Restore(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "old/pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "old/pkg/a.go"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "old/b.go"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stale.txt"), []byte("stale"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(root), 0755))
+	backup, err := newSyncBackup(root, launcher.SidecarDir(root))
+	require.NoError(t, err)
+	defer backup.discard()
+
+	results, err := backup.applyDeletions([]string{"old", "stale.txt", "missing.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, []pb.DeletedPathResult_Status{
+		pb.DeletedPathResult_REMOVED, pb.DeletedPathResult_REMOVED, pb.DeletedPathResult_NOT_FOUND,
+	}, []pb.DeletedPathResult_Status{results[0].Status, results[1].Status, results[2].Status})
+	assert.NoDirExists(t, filepath.Join(root, "old"))
+	assert.NoFileExists(t, filepath.Join(root, "stale.txt"))
+	assert.ElementsMatch(t, []string{"old/b.go", "old/pkg/a.go", "old", "stale.txt"}, buildFileChangeReport(backup.changes).deleted)
+
+	require.NoError(t, backup.restore())
+	data, err := os.ReadFile(filepath.Join(root, "old/pkg/a.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+	assert.FileExists(t, filepath.Join(root, "old/b.go"))
+	assert.FileExists(t, filepath.Join(root, "stale.txt"))
+}
+
+func TestApplyDeletions_SymlinkEscape(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "keep.txt"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linked")))
+	backup := &syncBackup{targetDir: root}
+
+	results, err := backup.applyDeletions([]string{"linked/keep.txt"})
+	assert.ErrorContains(t, err, "through a symlink")
+	assert.Equal(t, pb.DeletedPathResult_FAILED, results[0].Status)
+	assert.FileExists(t, filepath.Join(outside, "keep.txt"))
+
+	// Deleting the symlink itself removes the link, not its target.
+	results, err = backup.applyDeletions([]string{"linked"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.DeletedPathResult_REMOVED, results[0].Status)
+	assert.FileExists(t, filepath.Join(outside, "keep.txt"))
+}
+
+func TestHandlePushRequest_DeletedPaths(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	stale := filepath.Join(rw.targetSyncDir, "stale.txt")
+	require.NoError(t, os.WriteFile(stale, []byte("stale"), 0644))
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", DeletedPaths: []string{"stale.txt"}, DeletedPathsDryRun: true,
+	}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.Len(t, resp.GetDeletedPaths(), 1)
+	assert.Equal(t, pb.DeletedPathResult_WOULD_REMOVE, resp.GetDeletedPaths()[0].GetStatus())
+	assert.FileExists(t, stale)
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", DeletedPaths: []string{"stale.txt"},
+	}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, pb.DeletedPathResult_REMOVED, resp.GetDeletedPaths()[0].GetStatus())
+	assert.Equal(t, []string{"stale.txt"}, resp.GetDeletedFiles())
+	assert.NoFileExists(t, stale)
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-3", DeletedPaths: []string{"../outside", ".sidecar"},
+	})
+	assert.Error(t, err)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Len(t, resp.GetDeletedPaths(), 2)
+}
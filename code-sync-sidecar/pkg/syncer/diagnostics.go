@@ -0,0 +1,364 @@
+package syncer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	// diagnosticsLogLines is how many recent sidecar log lines a bundle holds.
+	diagnosticsLogLines = 2000
+	// diagnosticsChunkSize bounds the data in each DIAGNOSTICS_CHUNK; chunks are
+	// smaller on a slow connection.
+	diagnosticsChunkSize = 256 * 1024
+	// diagnosticsMaxFileSize bounds each launcher file copied into a bundle.
+	diagnosticsMaxFileSize = 64 * 1024
+	// diagnosticsListDepth is how deep the internal directories are listed.
+	diagnosticsListDepth = 2
+
+	redacted = "REDACTED"
+)
+
+// DiagnosticLogs keeps the sidecar's recent log lines for diagnostics bundles.
+// The command tees the global logger into it.
+var DiagnosticLogs = newRecentLogs(diagnosticsLogLines)
+
+// recentLogs is a zap core that keeps the last lines logged at info or above.
+type recentLogs struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	ring    *logRing
+}
+
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+}
+
+func newRecentLogs(size int) *recentLogs {
+	return &recentLogs{
+		LevelEnabler: zapcore.InfoLevel,
+		encoder:      zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		ring:         &logRing{lines: make([]string, 0, size)},
+	}
+}
+
+func (r *recentLogs) With(fields []zapcore.Field) zapcore.Core {
+	clone := *r
+	clone.encoder = r.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.encoder)
+	}
+	return &clone
+}
+
+func (r *recentLogs) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(entry.Level) {
+		return checked.AddCore(entry, r)
+	}
+	return checked
+}
+
+func (r *recentLogs) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := r.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+
+	ring := r.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	if len(ring.lines) < cap(ring.lines) {
+		ring.lines = append(ring.lines, line)
+	} else {
+		ring.lines[ring.next] = line
+		ring.next = (ring.next + 1) % len(ring.lines)
+	}
+	return nil
+}
+
+func (r *recentLogs) Sync() error {
+	return nil
+}
+
+// Lines returns the kept lines, oldest first.
+func (r *recentLogs) Lines() []string {
+	ring := r.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	return append(append([]string(nil), ring.lines[ring.next:]...), ring.lines[:ring.next]...)
+}
+
+// handleDiagnosticsRequest builds a diagnostics bundle off the read loop and
+// sends it back in DIAGNOSTICS_CHUNK messages.
+func (rw *FileSyncer) handleDiagnosticsRequest(req *pb.DiagnosticsRequest) error {
+	if req == nil {
+		return fmt.Errorf("received DIAGNOSTICS_REQUEST but diagnostics_request field is nil")
+	}
+	go func() {
+		var bundle bytes.Buffer
+		if err := rw.writeDiagnosticsBundle(&bundle); err != nil {
+			log.Error("Failed to build diagnostics bundle", zap.String("requestID", req.RequestId), zap.Error(err))
+			rw.sendProtoMessage(buildDiagnosticsChunk(&pb.DiagnosticsChunk{RequestId: req.RequestId, Last: true, ErrorMessage: err.Error()}))
+			return
+		}
+		log.Info("Sending diagnostics bundle", zap.String("requestID", req.RequestId), zap.Int("sizeBytes", bundle.Len()))
+		for index := int32(0); ; index++ {
+			size := rw.quality.chunkSize(diagnosticsChunkSize)
+			chunk := &pb.DiagnosticsChunk{RequestId: req.RequestId, Index: index, Data: bundle.Next(size)}
+			chunk.Last = bundle.Len() == 0
+			if err := rw.trySendProtoMessage(buildDiagnosticsChunk(chunk)); err != nil {
+				// A bundle with a gap is useless; the control plane can ask again.
+				log.Warn("Failed to send diagnostics bundle", zap.String("requestID", req.RequestId), zap.Error(err))
+				return
+			}
+			if chunk.Last {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func buildDiagnosticsChunk(chunk *pb.DiagnosticsChunk) *pb.WebsocketMessage {
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_DIAGNOSTICS_CHUNK,
+		Message:     &pb.WebsocketMessage_DiagnosticsChunk{DiagnosticsChunk: chunk},
+	}
+}
+
+// writeDiagnosticsBundle writes a gzipped tarball for support cases to w. It never
+// includes secrets: the config is redacted and only the names of env vars are kept.
+func (rw *FileSyncer) writeDiagnosticsBundle(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to add %s to the diagnostics bundle: %w", name, err)
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	files := []struct {
+		name  string
+		build func() ([]byte, error)
+	}{
+		{"version.txt", func() ([]byte, error) {
+			return fmt.Appendf(nil, "code-sync-sidecar %s (protocol %d)\n", Version, ProtocolVersion), nil
+		}},
+		{"status.json", func() ([]byte, error) {
+			return StatusJSON.Marshal(rw.buildStatusReport().GetStatusReport())
+		}},
+		{"config.yaml", rw.redactedConfig},
+		{"state.json", rw.redactedState},
+		{"env-keys.txt", func() ([]byte, error) { return envFileKeys(rw.targetSyncDir) }},
+		{"env-provenance.json", func() ([]byte, error) {
+			provenance, err := envfile.ReadProvenance(rw.targetSyncDir)
+			if err != nil {
+				return nil, err
+			}
+			return json.MarshalIndent(provenance, "", "  ")
+		}},
+		{"rsync-output.txt", func() ([]byte, error) {
+			rw.stateMu.Lock()
+			defer rw.stateMu.Unlock()
+			return rw.lastRsyncOutput, nil
+		}},
+		{"directories.txt", func() ([]byte, error) { return directoryStats(rw.targetSyncDir) }},
+		{"logs/sidecar.log", func() ([]byte, error) {
+			return []byte(strings.Join(DiagnosticLogs.Lines(), "\n") + "\n"), nil
+		}},
+	}
+	for _, file := range files {
+		data, err := file.build()
+		if err != nil {
+			// Keep going: a partial bundle is more useful than none.
+			data = fmt.Appendf(nil, "failed to collect %s: %v\n", file.name, err)
+		}
+		if err := add(file.name, data); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{launcher.ExitReasonFile, launcher.AppStatusFile, launcher.HandshakeFileName} {
+		data, err := readFileLimited(filepath.Join(launcher.Dir(rw.targetSyncDir), name), diagnosticsMaxFileSize)
+		if err != nil {
+			continue // Not written yet
+		}
+		if err := add("launcher/"+name, data); err != nil {
+			return err
+		}
+	}
+
+	for rotation := maxAuditRotations; rotation >= 0; rotation-- {
+		path := auditLogPath(rw.targetSyncDir, rotation)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // Not rotated that many times yet
+		}
+		if err := add("audit/"+filepath.Base(path), data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish the diagnostics bundle: %w", err)
+	}
+	return gz.Close()
+}
+
+// redactedConfig returns the current config as YAML with credentials removed.
+func (rw *FileSyncer) redactedConfig() ([]byte, error) {
+	rw.settingsMu.RLock()
+	cfg := rw.config
+	rw.settingsMu.RUnlock()
+	if cfg == nil {
+		return nil, fmt.Errorf("no configuration loaded")
+	}
+	clean := *cfg
+	if clean.API.APIKey != "" {
+		clean.API.APIKey = redacted
+	}
+	clean.API.URL = redactURL(clean.API.URL)
+	clean.Health.URL = redactURL(clean.Health.URL)
+	clean.Secrets.Vault.Address = redactURL(clean.Secrets.Vault.Address)
+	clean.Webhooks.URLs = make([]string, len(cfg.Webhooks.URLs))
+	for i, u := range cfg.Webhooks.URLs {
+		clean.Webhooks.URLs[i] = redactWebhookURL(u)
+	}
+	return yaml.Marshal(&clean)
+}
+
+// redactURL removes any credentials and query string from a URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	return u.String()
+}
+
+// redactedState returns the persisted sidecar state without the resume token,
+// which would let anyone resume the sidecar's session.
+func (rw *FileSyncer) redactedState() ([]byte, error) {
+	state, err := loadSidecarState(rw.targetSyncDir)
+	if err != nil {
+		return nil, err
+	}
+	if state.ResumeToken != "" {
+		state.ResumeToken = redacted
+	}
+	return json.MarshalIndent(state, "", "  ")
+}
+
+// envFileKeys lists the variable names in each env file, never their values.
+func envFileKeys(filesDir string) ([]byte, error) {
+	envFiles, err := envfile.List(filesDir)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	for _, envFile := range envFiles {
+		fmt.Fprintf(&out, "%s (version %s)\n", envFile.Path, envFile.Version)
+		if strings.HasSuffix(envFile.Path, ".enc") {
+			out.WriteString("  (encrypted)\n")
+			continue
+		}
+		data, err := os.ReadFile(envFile.Path)
+		if err != nil {
+			fmt.Fprintf(&out, "  failed to read: %v\n", err)
+			continue
+		}
+		for _, name := range envfile.Names(data) {
+			fmt.Fprintf(&out, "  %s\n", name)
+		}
+	}
+	if len(envFiles) == 0 {
+		out.WriteString("no env files\n")
+	}
+	return out.Bytes(), nil
+}
+
+// directoryStats lists the sidecar and launcher directories, and the size of the
+// synced files and free space on their filesystem.
+func directoryStats(filesDir string) ([]byte, error) {
+	var out bytes.Buffer
+	if usage, err := syncDirUsage(filesDir); err == nil {
+		fmt.Fprintf(&out, "synced files: %d bytes\n", usage)
+	} else {
+		fmt.Fprintf(&out, "synced files: %v\n", err)
+	}
+	if free, err := filesystemFreeBytes(filesDir); err == nil {
+		fmt.Fprintf(&out, "free space: %d bytes\n", free)
+	} else {
+		fmt.Fprintf(&out, "free space: %v\n", err)
+	}
+
+	for _, root := range []string{launcher.SidecarDir(filesDir), launcher.Dir(filesDir)} {
+		fmt.Fprintf(&out, "\n%s\n", root)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				fmt.Fprintf(&out, "  %s: %v\n", path, err)
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			if rel == "." {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil // Removed while walking
+			}
+			fmt.Fprintf(&out, "  %s %10d %s %s\n", info.Mode(), info.Size(), info.ModTime().UTC().Format(time.RFC3339), rel)
+			if d.IsDir() && strings.Count(rel, string(filepath.Separator))+1 >= diagnosticsListDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// readFileLimited reads up to limit bytes of a file.
+func readFileLimited(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
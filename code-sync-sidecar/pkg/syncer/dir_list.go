@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// maxDirListEntries bounds how many entries a DIR_LIST_RESPONSE carries.
+const maxDirListEntries = 1000
+
+// handleDirListRequest lists the requested directory off the read loop and
+// sends it in a DIR_LIST_RESPONSE.
+func (rw *FileSyncer) handleDirListRequest(req *pb.DirListRequest) error {
+	if req == nil {
+		return fmt.Errorf("received DIR_LIST_REQUEST but dir_list_request field is nil")
+	}
+	go func() {
+		resp := &pb.DirListResponse{RequestId: req.RequestId, Path: req.Path}
+		if err := rw.listSyncedDir(req.Path, resp); err != nil {
+			log.Warn("Failed to list directory for the control plane", zap.String("path", req.Path), zap.Error(err))
+			resp.Entries = nil
+			resp.ErrorMessage = err.Error()
+		}
+		rw.sendProtoMessage(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_DIR_LIST_RESPONSE,
+			Message:     &pb.WebsocketMessage_DirListResponse{DirListResponse: resp},
+		})
+	}()
+	return nil
+}
+
+// listSyncedDir fills resp with the entries of rel, sorted by name. It holds
+// workspaceMu so the listing never shows a push half applied.
+func (rw *FileSyncer) listSyncedDir(rel string, resp *pb.DirListResponse) error {
+	if rel == "" {
+		rel = "."
+	}
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	root, err := rw.contentDir()
+	if err != nil {
+		return err
+	}
+	path, err := resolveReadPath(root, rel)
+	if err != nil {
+		return err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", rel, err)
+	}
+
+	for _, entry := range entries {
+		if path == realRoot && isInternalEntry(entry.Name()) {
+			continue
+		}
+		if len(resp.Entries) == maxDirListEntries {
+			resp.Truncated = true
+			break
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read.
+			continue
+		}
+		resp.Entries = append(resp.Entries, &pb.DirEntry{
+			Name:       entry.Name(),
+			Type:       dirEntryType(info.Mode()),
+			SizeBytes:  info.Size(),
+			Mode:       uint32(info.Mode().Perm()),
+			ModifiedAt: timestamppb.New(info.ModTime()),
+		})
+	}
+	return nil
+}
+
+func dirEntryType(mode fs.FileMode) pb.DirEntry_Type {
+	switch {
+	case mode.IsRegular():
+		return pb.DirEntry_FILE
+	case mode.IsDir():
+		return pb.DirEntry_DIRECTORY
+	case mode&fs.ModeSymlink != 0:
+		return pb.DirEntry_SYMLINK
+	default:
+		return pb.DirEntry_OTHER
+	}
+}
@@ -0,0 +1,74 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHandleDirListRequest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("app"), 0640))
+	require.NoError(t, os.Symlink("app.py", filepath.Join(dir, "main.py")))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{targetSyncDir: dir, conn: conn}
+
+	require.NoError(t, rw.handleDirListRequest(&pb.DirListRequest{RequestId: "req-1"}))
+
+	select {
+	case message := <-mockServer.messages:
+		var wsMessage pb.WebsocketMessage
+		require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		require.Equal(t, pb.WebsocketMessage_DIR_LIST_RESPONSE, wsMessage.MessageType)
+		resp := wsMessage.GetDirListResponse()
+		assert.Equal(t, "req-1", resp.RequestId)
+		assert.Empty(t, resp.ErrorMessage)
+		assert.False(t, resp.Truncated)
+		require.Len(t, resp.Entries, 3, "internal directories are left out")
+		assert.Equal(t, "app.py", resp.Entries[0].Name)
+		assert.Equal(t, pb.DirEntry_FILE, resp.Entries[0].Type)
+		assert.Equal(t, int64(3), resp.Entries[0].SizeBytes)
+		assert.Equal(t, uint32(0640), resp.Entries[0].Mode)
+		assert.WithinDuration(t, time.Now(), resp.Entries[0].ModifiedAt.AsTime(), time.Minute)
+		assert.Equal(t, "main.py", resp.Entries[1].Name)
+		assert.Equal(t, pb.DirEntry_SYMLINK, resp.Entries[1].Type)
+		assert.Equal(t, "pkg", resp.Entries[2].Name)
+		assert.Equal(t, pb.DirEntry_DIRECTORY, resp.Entries[2].Type)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for directory listing")
+	}
+}
+
+func TestListSyncedDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "many"), 0755))
+	for i := range maxDirListEntries + 1 {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "many", fmt.Sprintf("%04d.txt", i)), nil, 0644))
+	}
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.Symlink(launcher.SidecarDir(dir), filepath.Join(dir, "sidecar-link")))
+	rw := &FileSyncer{targetSyncDir: dir}
+
+	resp := &pb.DirListResponse{}
+	require.NoError(t, rw.listSyncedDir("many", resp))
+	assert.Len(t, resp.Entries, maxDirListEntries)
+	assert.True(t, resp.Truncated)
+
+	assert.ErrorContains(t, rw.listSyncedDir(".sidecar", &pb.DirListResponse{}), "reserved for the sidecar")
+	assert.ErrorContains(t, rw.listSyncedDir("sidecar-link", &pb.DirListResponse{}), "reserved for the sidecar")
+	assert.ErrorContains(t, rw.listSyncedDir("..", &pb.DirListResponse{}), "outside the files directory")
+	assert.ErrorContains(t, rw.listSyncedDir("missing", &pb.DirListResponse{}), "missing does not exist")
+}
@@ -0,0 +1,35 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// diskSafetyMargin is kept free on top of what a push needs, so applying one
+// never fills the filesystem the app runs from.
+const diskSafetyMargin = 64 << 20
+
+var errInsufficientDisk = errors.New("insufficient disk space")
+
+// diskFreeBytes allows mocking the free space lookup in tests
+var diskFreeBytes = filesystemFreeBytes
+
+// requiredDiskBytes estimates the space needed to apply a batch: the batch is
+// written to a temporary file, and rsync writes roughly as much again while it
+// builds the new files next to the ones they replace.
+func requiredDiskBytes(batchSize int64) int64 {
+	return 2*batchSize + diskSafetyMargin
+}
+
+// checkDiskSpace fails with errInsufficientDisk when the filesystem holding dir
+// doesn't have room to apply a batch of batchSize bytes.
+func checkDiskSpace(dir string, batchSize int64) error {
+	free, err := diskFreeBytes(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check free space on %s: %w", dir, err)
+	}
+	if required := requiredDiskBytes(batchSize); free < required {
+		return fmt.Errorf("%w: applying the push needs %d bytes but only %d are free on %s", errInsufficientDisk, required, free, dir)
+	}
+	return nil
+}
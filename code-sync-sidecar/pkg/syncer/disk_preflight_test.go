@@ -0,0 +1,50 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func mockDiskFreeBytes(t *testing.T, free int64, err error) {
+	t.Helper()
+	original := diskFreeBytes
+	diskFreeBytes = func(string) (int64, error) { return free, err }
+	t.Cleanup(func() { diskFreeBytes = original })
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	mockDiskFreeBytes(t, diskSafetyMargin+2000, nil)
+	assert.NoError(t, checkDiskSpace(t.TempDir(), 1000))
+	err := checkDiskSpace(t.TempDir(), 1001)
+	assert.ErrorIs(t, err, errInsufficientDisk)
+
+	mockDiskFreeBytes(t, 0, errors.New("statfs failed"))
+	err = checkDiskSpace(t.TempDir(), 1)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errInsufficientDisk)
+}
+
+func TestHandlePushRequest_InsufficientDisk(t *testing.T) {
+	mockDiskFreeBytes(t, 1024, nil)
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: t.TempDir(),
+		conn:          conn,
+	}
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.ErrorIs(t, err, errInsufficientDisk)
+
+	resp := waitForPushResponse(t, mockServer)
+	require.NotNil(t, resp)
+	assert.Equal(t, pb.PushResponse_INSUFFICIENT_DISK, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "only 1024 are free")
+	assertOnlyAuditLog(t, rw.targetSyncDir, "nothing is written")
+}
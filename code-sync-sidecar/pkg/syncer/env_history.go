@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// RollbackEnv restores a version of the env files kept in the history, the
+// one before the current version when version is empty, and reloads the app,
+// the same way an ENV_ROLLBACK request would. It's for recovering from a bad
+// env refresh from inside the pod, and needs no connection to the API.
+func RollbackEnv(ctx context.Context, cfg *Config, version string) (*pb.EnvRollbackResponse, error) {
+	rw := newFileSyncer(cfg, nil)
+	restored, err := rw.rollbackEnv(ctx, version)
+	return rw.buildEnvRollbackResponse("", restored, err), err
+}
+
+// handleEnvRollbackRequest rolls the env files back off the read loop and
+// sends an ENV_ROLLBACK_RESPONSE when done.
+func (rw *FileSyncer) handleEnvRollbackRequest(req *pb.EnvRollbackRequest) error {
+	if req == nil {
+		return fmt.Errorf("received ENV_ROLLBACK but env_rollback_request field is nil")
+	}
+	go func() {
+		restored, err := rw.rollbackEnv(context.Background(), req.Version)
+		if err != nil {
+			log.Error("Env rollback failed", zap.String("requestID", req.RequestId), zap.String("version", req.Version), zap.Error(err))
+		}
+		rw.sendProtoMessage(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_ENV_ROLLBACK_RESPONSE,
+			Message:     &pb.WebsocketMessage_EnvRollbackResponse{EnvRollbackResponse: rw.buildEnvRollbackResponse(req.RequestId, restored, err)},
+		})
+	}()
+	return nil
+}
+
+// rollbackEnv restores the env files of version and signals the launcher to
+// reload, so the app picks up the restored values.
+func (rw *FileSyncer) rollbackEnv(ctx context.Context, version string) (*envfile.Version, error) {
+	if rw.getEnvHistory() <= 0 {
+		return nil, fmt.Errorf("the env history is disabled (sync.env_history is 0)")
+	}
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	restored, err := envfile.RestoreVersion(rw.targetSyncDir, version)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Rolled back env files", zap.String("version", restored.ID), zap.String("pushID", restored.PushID))
+
+	hs, err := rw.writeReloadHandshake(restored.PushID, launcher.ReloadReasonEnvRollback, nil, "")
+	if err != nil {
+		return &restored, fmt.Errorf("env version %s restored but the launcher handshake could not be written: %w", restored.ID, err)
+	}
+	if err := rw.getReloader().Reload(ctx, hs); err != nil {
+		return &restored, fmt.Errorf("env version %s restored but the app could not be reloaded: %w", restored.ID, err)
+	}
+	return &restored, nil
+}
+
+func (rw *FileSyncer) buildEnvRollbackResponse(requestID string, restored *envfile.Version, err error) *pb.EnvRollbackResponse {
+	resp := &pb.EnvRollbackResponse{
+		RequestId: requestID,
+		Status:    pb.EnvRollbackResponse_COMPLETED,
+	}
+	if restored != nil {
+		resp.Version = envVersionInfo(*restored)
+	}
+	if err != nil {
+		resp.Status = pb.EnvRollbackResponse_FAILED
+		resp.ErrorMessage = err.Error()
+	}
+	versions, current, listErr := envfile.ListVersions(rw.targetSyncDir)
+	if listErr != nil {
+		log.Warn("Failed to list env versions", zap.Error(listErr))
+	}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, envVersionInfo(version))
+	}
+	resp.CurrentVersion = current
+	return resp
+}
+
+func envVersionInfo(version envfile.Version) *pb.EnvVersion {
+	return &pb.EnvVersion{
+		Id:        version.ID,
+		PushId:    version.PushID,
+		CreatedAt: timestamppb.New(version.CreatedAt),
+		Files:     version.Files,
+	}
+}
@@ -0,0 +1,71 @@
+package syncer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHandleEnvRollbackRequest(t *testing.T) {
+	dir := t.TempDir()
+	rw, finder := newSnapshotTestSyncer(t, dir, ApplyModeInPlace)
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw.conn = conn
+
+	receive := func() *pb.EnvRollbackResponse {
+		t.Helper()
+		select {
+		case message := <-mockServer.messages:
+			var wsMessage pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+			require.Equal(t, pb.WebsocketMessage_ENV_ROLLBACK_RESPONSE, wsMessage.MessageType)
+			return wsMessage.GetEnvRollbackResponse()
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for env rollback response")
+			return nil
+		}
+	}
+
+	require.NoError(t, rw.handleEnvRollbackRequest(&pb.EnvRollbackRequest{RequestId: "req-1"}))
+	resp := receive()
+	assert.Equal(t, pb.EnvRollbackResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "sync.env_history is 0")
+
+	rw.envHistory = 5
+	for i, content := range []string{"export DATABASE_URL='postgres://db/main'\n", "export DATABASE_URL='postgres://db/bad'\n"} {
+		_, err := envfile.Write(dir, "", []byte(content), "")
+		require.NoError(t, err)
+		_, err = envfile.SaveVersion(dir, []string{"", "push-2"}[i], rw.envHistory, time.Now())
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, rw.handleEnvRollbackRequest(&pb.EnvRollbackRequest{RequestId: "req-2"}))
+	resp = receive()
+	assert.Equal(t, "req-2", resp.RequestId)
+	assert.Equal(t, pb.EnvRollbackResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Equal(t, "000001", resp.Version.GetId())
+	assert.Equal(t, "000001", resp.CurrentVersion)
+	require.Len(t, resp.Versions, 2)
+	assert.Equal(t, "push-2", resp.Versions[1].PushId)
+	assert.Equal(t, "export DATABASE_URL='postgres://db/main'\n", readFile(t, envfile.Path(dir, "")))
+	assert.NotEmpty(t, finder.processes[12345].signalCalls, "launcher is signalled")
+
+	var hs launcher.Handshake
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, launcher.HandshakePath(dir))), &hs))
+	assert.Equal(t, launcher.ReloadReasonEnvRollback, hs.Reason)
+
+	require.NoError(t, rw.handleEnvRollbackRequest(&pb.EnvRollbackRequest{RequestId: "req-3", Version: "000007"}))
+	resp = receive()
+	assert.Equal(t, pb.EnvRollbackResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, `env version "000007" does not exist`)
+	assert.Equal(t, "000001", resp.CurrentVersion)
+}
@@ -0,0 +1,123 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// maxFileGetSize bounds the files a FILE_GET_REQUEST returns, so a single
+// response stays well within a websocket message.
+const maxFileGetSize = 4 << 20
+
+// handleFileGetRequest reads the requested file off the read loop and sends it
+// in a FILE_GET_RESPONSE.
+func (rw *FileSyncer) handleFileGetRequest(req *pb.FileGetRequest) error {
+	if req == nil {
+		return fmt.Errorf("received FILE_GET_REQUEST but file_get_request field is nil")
+	}
+	go func() {
+		resp := &pb.FileGetResponse{RequestId: req.RequestId, Path: req.Path}
+		if err := rw.readSyncedFile(req, resp); err != nil {
+			log.Warn("Failed to read file for the control plane", zap.String("path", req.Path), zap.Error(err))
+			resp.Content = nil
+			resp.ErrorMessage = err.Error()
+		}
+		rw.sendProtoMessage(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_FILE_GET_RESPONSE,
+			Message:     &pb.WebsocketMessage_FileGetResponse{FileGetResponse: resp},
+		})
+	}()
+	return nil
+}
+
+// readSyncedFile fills resp with the file req asks for. It holds workspaceMu so
+// the file is never read halfway through a push.
+func (rw *FileSyncer) readSyncedFile(req *pb.FileGetRequest, resp *pb.FileGetResponse) error {
+	limit := int64(maxFileGetSize)
+	if req.MaxBytes > 0 && req.MaxBytes < limit {
+		limit = req.MaxBytes
+	}
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	root, err := rw.contentDir()
+	if err != nil {
+		return err
+	}
+	path, err := resolveReadPath(root, req.Path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", req.Path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", req.Path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", req.Path)
+	}
+	resp.SizeBytes = info.Size()
+	resp.Mode = uint32(info.Mode().Perm())
+	resp.ModifiedAt = timestamppb.New(info.ModTime())
+	if info.Size() > limit {
+		return fmt.Errorf("%s is %d bytes, over the %d-byte limit", req.Path, info.Size(), limit)
+	}
+
+	// Read one byte past the limit in case the file grew since the stat.
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", req.Path, err)
+	}
+	if int64(len(content)) > limit {
+		return fmt.Errorf("%s is over the %d-byte limit", req.Path, limit)
+	}
+	resp.Content = content
+	return nil
+}
+
+// resolveReadPath resolves rel, a path the control plane asks to read, to a
+// path under root. Unlike resolveInRoot it follows the final symlink too, since
+// reading through it would leave root.
+func resolveReadPath(root, rel string) (string, error) {
+	rel, err := validateSyncPath(rel)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s does not exist", rel)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rel, err)
+	}
+	if path != realRoot && !strings.HasPrefix(path, realRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q leads outside the files directory through a symlink", rel)
+	}
+	// A symlink into .sidecar mustn't expose the env files either.
+	if target, err := filepath.Rel(realRoot, path); err == nil {
+		if top, _, _ := strings.Cut(target, string(filepath.Separator)); isInternalEntry(top) {
+			return "", fmt.Errorf("path %q leads to a path reserved for the sidecar", rel)
+		}
+	}
+	return path, nil
+}
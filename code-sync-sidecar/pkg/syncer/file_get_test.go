@@ -0,0 +1,84 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHandleFileGetRequest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "app.yaml"), []byte("port: 8080\n"), 0640))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{targetSyncDir: dir, conn: conn}
+
+	require.NoError(t, rw.handleFileGetRequest(&pb.FileGetRequest{RequestId: "req-1", Path: "config/app.yaml"}))
+
+	select {
+	case message := <-mockServer.messages:
+		var wsMessage pb.WebsocketMessage
+		require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		require.Equal(t, pb.WebsocketMessage_FILE_GET_RESPONSE, wsMessage.MessageType)
+		resp := wsMessage.GetFileGetResponse()
+		assert.Equal(t, "req-1", resp.RequestId)
+		assert.Equal(t, "config/app.yaml", resp.Path)
+		assert.Empty(t, resp.ErrorMessage)
+		assert.Equal(t, "port: 8080\n", string(resp.Content))
+		assert.Equal(t, int64(11), resp.SizeBytes)
+		assert.Equal(t, uint32(0640), resp.Mode)
+		assert.WithinDuration(t, time.Now(), resp.ModifiedAt.AsTime(), time.Minute)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for file response")
+	}
+}
+
+func TestReadSyncedFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"), make([]byte, 100), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(dir), "env.sh"), []byte("export A=1\n"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(".sidecar", "env.sh"), filepath.Join(dir, "env-link")))
+	require.NoError(t, os.Symlink("big.bin", filepath.Join(dir, "big-link")))
+	rw := &FileSyncer{targetSyncDir: dir}
+
+	tests := []struct {
+		path     string
+		maxBytes int64
+		wantErr  string
+	}{
+		{path: "../secret", wantErr: "outside the files directory"},
+		{path: "/etc/passwd", wantErr: "outside the files directory"},
+		{path: ".sidecar/env.sh", wantErr: "reserved for the sidecar"},
+		{path: "escape", wantErr: "leads outside the files directory through a symlink"},
+		{path: "env-link", wantErr: "leads to a path reserved for the sidecar"},
+		{path: "missing.txt", wantErr: "missing.txt does not exist"},
+		{path: "pkg", wantErr: "pkg is not a regular file"},
+		{path: "big.bin", maxBytes: 10, wantErr: "big.bin is 100 bytes, over the 10-byte limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp := &pb.FileGetResponse{}
+			err := rw.readSyncedFile(&pb.FileGetRequest{Path: tt.path, MaxBytes: tt.maxBytes}, resp)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, resp.Content)
+		})
+	}
+
+	resp := &pb.FileGetResponse{}
+	require.NoError(t, rw.readSyncedFile(&pb.FileGetRequest{Path: "big-link"}, resp), "symlinks within the files directory are followed")
+	assert.Len(t, resp.Content, 100)
+}
@@ -0,0 +1,1992 @@
+// Package syncer is the core of the sidecar. A FileSyncer connects to the
+// Bifrost control plane, applies the rsync batches it pushes to the shared
+// volume and reloads the app through the launcher, and answers the control
+// plane's other requests. Config holds its settings, read from an optional
+// YAML file and the environment.
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/metrics"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+// execCommand allows mocking exec.CommandContext in tests
+var execCommand = exec.CommandContext
+
+const (
+	pingPeriod = 10 * time.Second
+	pongWait   = 40 * time.Second
+
+	// rsyncStopGracePeriod is how long rsync has to exit after SIGTERM before it is killed.
+	rsyncStopGracePeriod = 5 * time.Second
+
+	// maxPathsInMessage limits how many file names are listed in an error message.
+	maxPathsInMessage = 10
+)
+
+// FileSyncer handles syncing files via rsync triggered by WebSocket messages.
+type FileSyncer struct {
+	apiURL        string
+	tokens        *transport.TokenManager
+	appID         string
+	deploymentID  string
+	targetSyncDir string
+	applyMode     string
+	rsyncPath     string
+	// requireApproval holds staged pushes until an ACTIVATE approves them.
+	requireApproval bool
+	conn            *websocket.Conn
+	done            chan struct{}
+	processFinder   launcher.ProcessFinder
+	readiness       *ReadinessMarker
+	coordinator     *coordinator
+
+	startedAt time.Time
+	shells    *ShellManager
+	tails     *LogTailManager
+	// batches holds pushes whose batches are still arriving in chunks.
+	batches batchStreams
+	// audit records every push in the audit log once it is finished.
+	audit auditLog
+	// batchCacheMu serializes changes to the batch cache in .sidecar/cache.
+	batchCacheMu sync.Mutex
+	// resyncMu serializes sending RESYNC_REQUESTs.
+	resyncMu sync.Mutex
+
+	// settingsMu guards the settings below, which can be changed at runtime by ApplyConfig.
+	settingsMu         sync.RWMutex
+	config             *Config
+	statusInterval     time.Duration
+	reloadSignal       syscall.Signal
+	signalTarget       launcher.SignalTarget
+	reloader           Reloader
+	reconnectBackoff   time.Duration
+	maxSnapshots       int
+	snapshotRetention  time.Duration
+	envHistory         int
+	gcInterval         time.Duration
+	rsyncTimeout       time.Duration
+	rsyncStallTimeout  time.Duration
+	pushDebounce       time.Duration
+	sequenceGapTimeout time.Duration
+	pushSchedule       pushSchedule
+	approvalTimeout    time.Duration
+	retry              retryPolicy
+	deletionRails      deletionRails
+	batchCacheSize     int64
+	subtreeWorkers     int
+	quota              quotaLimits
+	resources          resourceLimits
+	unreadyDuringPush  bool
+	preserveLabels     bool
+	wireLog            bool
+	hooks              *HookRunner
+	build              *BuildRunner
+	warm               *CacheWarmer
+	webhooks           *WebhookEmitter
+	install            *InstallRunner
+	migrations         *MigrationRunner
+	health             *HealthProber
+	envOptions         envfile.Options
+	databaseEnv        envfile.DatabaseEnvProvider
+	permissions        permissionMapping
+
+	// metrics is set once when the syncer starts, since the sink can't change
+	// without a restart.
+	metrics metrics.Sink
+
+	pushes pushQueue
+	// outbox keeps messages the server must get while the connection is down.
+	outbox outbox
+	// workspaceMu serializes changes to the synced files: pushes, snapshots and env rollbacks.
+	workspaceMu sync.Mutex
+
+	stopOnce sync.Once
+
+	// writeMu serializes writes to conn; gorilla/websocket supports one concurrent writer.
+	writeMu sync.Mutex
+	// poller replaces conn while websocket upgrades are blocked. Guarded by writeMu.
+	poller *transport.LongPoller
+	// sendOffline replaces conn for a syncer that never connects, such as the
+	// apply command's.
+	sendOffline func(msg proto.Message)
+
+	// stateSaveMu serializes writes of applied to the state file; see saveState.
+	stateSaveMu      sync.Mutex
+	stateMu          sync.Mutex
+	applied          SidecarState
+	serverHello      *pb.HelloAck
+	connectedSince   time.Time
+	reconnectCount   int32
+	messagesSent     atomic.Int64
+	messagesReceived atomic.Int64
+	quality          connectionQuality
+	// lastRsyncOutput is what rsync printed applying the last batch, for diagnostics.
+	lastRsyncOutput []byte
+	// workspaceUsage was measured after the last push that changed files.
+	workspaceUsage *pb.WorkspaceUsage
+	// pendingResync is a problem to report in a RESYNC_REQUEST until a mirror
+	// push is applied, and lastResync identifies the last request sent.
+	pendingResync *pb.ResyncRequest
+	lastResync    string
+	// lastDriftWebhook is the workspace hash drift.detected was last posted for.
+	lastDriftWebhook string
+	// appReadiness is what the app last reported over the control socket.
+	appReadiness *pb.AppReadiness
+	// statusChanged has the status file rewritten right away.
+	statusChanged chan struct{}
+}
+
+// NewFileSyncer creates and starts a new FileSyncer.
+func NewFileSyncer(ctx context.Context, cfg *Config, tokens *transport.TokenManager) (*FileSyncer, error) {
+	rw := newFileSyncer(cfg, tokens)
+	coord, err := newCoordinator(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up coordination: %w", err)
+	}
+	if coord != nil {
+		rw.coordinator = coord
+		if err := rw.startCoordination(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start coordination: %w", err)
+		}
+	}
+
+	sink, err := NewMetricsSink(cfg.Metrics, cfg.AppID, cfg.DeploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up metrics: %w", err)
+	}
+	rw.metrics = sink
+
+	go rw.run(ctx)
+	go rw.runGarbageCollector(ctx)
+	go rw.runLauncherWatcher(ctx)
+	if cfg.Metrics.Sink != MetricsSinkNone {
+		go rw.runMetricsReporter(ctx, time.Duration(cfg.Metrics.Interval))
+	}
+
+	// Logging about start is done by the command
+	return rw, nil
+}
+
+// newFileSyncer creates a FileSyncer without connecting or starting any background work.
+func newFileSyncer(cfg *Config, tokens *transport.TokenManager) *FileSyncer {
+	rw := &FileSyncer{
+		apiURL:          cfg.API.URL,
+		tokens:          tokens,
+		appID:           cfg.AppID,
+		deploymentID:    cfg.DeploymentID,
+		targetSyncDir:   cfg.Sync.FilesDir,
+		applyMode:       cfg.Sync.ApplyMode,
+		requireApproval: cfg.Sync.RequireApproval,
+		rsyncPath:       cfg.Sync.RsyncPath,
+		done:            make(chan struct{}),
+		statusChanged:   make(chan struct{}, 1),
+		processFinder:   &launcher.DefaultProcessFinder{},
+		readiness:       NewReadinessMarker(cfg.Readiness.File),
+
+		startedAt: time.Now(),
+	}
+	state, err := loadSidecarState(cfg.Sync.FilesDir)
+	if err != nil {
+		// Don't fail startup; checkSidecarState asks the control plane for a full resync instead.
+		log.Warn("Failed to load sidecar state", zap.Error(err))
+	}
+	rw.applied = state
+	rw.checkSidecarState(err)
+	rw.shells = NewShellManager(cfg.Shell.Enabled, cfg.Sync.FilesDir, func(msg *pb.WebsocketMessage) error {
+		return rw.trySendProtoMessage(msg)
+	})
+	rw.tails = NewLogTailManager(cfg.Sync.AppLogDir, func(msg *pb.WebsocketMessage) error {
+		return rw.trySendProtoMessage(msg)
+	})
+	rw.ApplyConfig(cfg)
+	return rw
+}
+
+// rsync returns the path of the rsync binary that applies batches.
+func (rw *FileSyncer) rsync() string {
+	if rw.rsyncPath == "" {
+		return DefaultRsyncPath
+	}
+	return rw.rsyncPath
+}
+
+// MarkReady writes the readiness file once the shared volume has been
+// provisioned; see ReadinessMarker.
+func (rw *FileSyncer) MarkReady() {
+	rw.readiness.Ready()
+}
+
+// Stop gracefully shuts down the FileSyncer. It is safe to call more than once.
+func (rw *FileSyncer) Stop() {
+	rw.stopOnce.Do(rw.stop)
+}
+
+func (rw *FileSyncer) stop() {
+	log.Info("Stopping file syncer...")
+	close(rw.done)
+	rw.readiness.Close()
+	rw.writeMu.Lock()
+	defer rw.writeMu.Unlock()
+	if rw.conn != nil {
+		// Cleanly close the WebSocket connection
+		err := rw.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		if err != nil {
+			log.Warn("Error sending WebSocket close message", zap.Error(err))
+		}
+		rw.conn.Close()
+	}
+	rw.getMetrics().Close()
+	log.Info("File syncer stopped.")
+}
+
+// run is the main loop for the FileSyncer.
+func (rw *FileSyncer) run(ctx context.Context) {
+	blockedDials := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Context cancelled, shutting down.")
+			rw.Stop() // Ensure Stop is called on context cancellation
+			return
+		case <-rw.done:
+			log.Info("Stop signal received, shutting down.")
+			return
+		default:
+			target, err := rw.connectionTarget(ctx)
+			if err != nil {
+				log.Warn("Not connecting yet", zap.Error(err))
+				rw.waitReconnectBackoff("Retrying WebSocket connection")
+				continue
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(target.url, target.headers)
+			if err != nil {
+				var respStatusCode int
+				if resp != nil {
+					respStatusCode = resp.StatusCode
+				}
+				if respStatusCode == http.StatusUnauthorized && !target.peer {
+					// The token may have been revoked or expired early; exchange a new one before retrying.
+					rw.tokens.Invalidate()
+				}
+				log.Warn("Failed to connect to WebSocket",
+					zap.String("url", target.url),
+					zap.Error(err),
+					zap.Int("httpStatus", respStatusCode),
+				)
+				// The leader's Hub has no long-polling endpoint to fall back to.
+				if target.peer || !transport.IsUpgradeBlocked(resp, err) {
+					blockedDials = 0
+				} else if blockedDials++; blockedDials >= pollFallbackAfter {
+					log.Warn("WebSocket upgrades appear to be blocked, falling back to HTTP long-polling",
+						zap.Int("blockedDials", blockedDials))
+					if err := rw.runLongPoll(ctx); err != nil {
+						log.Warn("HTTP long-polling ended", zap.Error(err))
+					}
+					// Go straight back to polling if the next dial is blocked too.
+					blockedDials = pollFallbackAfter - 1
+				}
+				rw.waitReconnectBackoff("Retrying WebSocket connection")
+				continue // Retry connection
+			}
+			blockedDials = 0
+			// Close the response body explicitly if it's not nil
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+
+			rw.writeMu.Lock()
+			rw.conn = conn
+			rw.writeMu.Unlock()
+			rw.recordConnected()
+			if target.peer {
+				log.Info("Connected to coordination leader", zap.String("url", target.url))
+			} else {
+				log.Info("Connected to Code Sync proxy", zap.String("url", target.url))
+			}
+
+			rw.sendProtoMessage(rw.buildHello())
+			rw.flushOutbox()
+			go rw.sendResyncRequest()
+
+			// Connection successful, start message loop
+			err = rw.messageLoop(ctx)
+			if err != nil {
+				log.Warn("WebSocket message loop ended", zap.Error(err))
+			}
+			// Close connection before retry or shutdown
+			rw.writeMu.Lock()
+			rw.conn.Close()
+			rw.conn = nil
+			rw.writeMu.Unlock()
+			rw.notifyStatusChanged()
+
+			// Check if we should exit or retry
+			select {
+			case <-ctx.Done():
+				log.Info("Context cancelled after connection loss.")
+				rw.Stop()
+				return
+			case <-rw.done:
+				log.Info("Stop signal received after connection loss.")
+				return
+			default:
+				rw.waitReconnectBackoff("Connection lost, retrying")
+			}
+		}
+	}
+}
+
+// ApplyConfig updates the settings that can change while the sidecar is running.
+// It never touches the websocket connection, and a push that is already being
+// applied keeps the hook runner it started with.
+func (rw *FileSyncer) ApplyConfig(cfg *Config) {
+	hooks := NewHookRunner(cfg.Sync.HooksDir, time.Duration(cfg.Timeouts.Hook), rw.appID, rw.deploymentID, rw.targetSyncDir)
+
+	rw.settingsMu.Lock()
+	rw.config = cfg
+	rw.statusInterval = time.Duration(cfg.Timeouts.StatusInterval)
+	rw.reloadSignal = cfg.ReloadSignal()
+	rw.signalTarget = cfg.SignalTarget()
+	rw.reloader = newReloader(cfg, rw.appID, rw.deploymentID, rw.targetSyncDir, rw.processFinder)
+	rw.reconnectBackoff = time.Duration(cfg.Timeouts.ReconnectBackoff)
+	rw.maxSnapshots = cfg.Sync.MaxSnapshots
+	rw.snapshotRetention = time.Duration(cfg.Sync.SnapshotRetention)
+	rw.envHistory = cfg.Sync.EnvHistory
+	rw.gcInterval = time.Duration(cfg.Timeouts.GCInterval)
+	rw.rsyncTimeout = time.Duration(cfg.Timeouts.Rsync)
+	rw.rsyncStallTimeout = time.Duration(cfg.Timeouts.RsyncStall)
+	rw.pushDebounce = time.Duration(cfg.Sync.PushDebounce)
+	rw.sequenceGapTimeout = time.Duration(cfg.Sync.SequenceGapTimeout)
+	rw.pushSchedule = cfg.pushSchedule()
+	rw.approvalTimeout = time.Duration(cfg.Sync.ApprovalTimeout)
+	rw.retry = retryPolicy{attempts: cfg.Sync.RetryAttempts, backoff: time.Duration(cfg.Sync.RetryBackoff)}
+	rw.deletionRails = deletionRails{protected: cfg.Sync.ProtectedPaths, maxPercent: cfg.Sync.MaxDeletePercent}
+	rw.batchCacheSize = int64(cfg.Sync.BatchCacheSize)
+	rw.subtreeWorkers = cfg.Sync.SubtreeWorkers
+	rw.quota = cfg.quotaLimits()
+	rw.resources = cfg.resourceLimits()
+	rw.unreadyDuringPush = cfg.Readiness.UnreadyDuringPush
+	rw.preserveLabels = cfg.Security.PreserveLabels
+	rw.wireLog = cfg.Log.Wire
+	rw.hooks = hooks
+	rw.install = NewInstallRunner(cfg.Install.Command, cfg.Install.Manifests, time.Duration(cfg.Install.Timeout), rw.targetSyncDir)
+	rw.build = NewBuildRunner(cfg.Build.Command, time.Duration(cfg.Build.Timeout), rw.targetSyncDir)
+	rw.warm = NewCacheWarmer(cfg.Warm, rw.targetSyncDir)
+	rw.webhooks = NewWebhookEmitter(cfg.Webhooks, rw.appID, rw.deploymentID)
+	rw.migrations = NewMigrationRunner(cfg.Database.MigrationCommand, time.Duration(cfg.Database.MigrationTimeout), rw.targetSyncDir)
+	rw.databaseEnv = cfg.DatabaseEnvProvider(rw.tokens)
+	rw.health = NewHealthProber(cfg.Health)
+	rw.envOptions = cfg.EnvFileOptions()
+	rw.permissions = cfg.permissionMapping()
+	resources := rw.resources
+	rw.settingsMu.Unlock()
+
+	resources.applyMemoryLimit()
+	if rw.shells != nil {
+		rw.shells.SetEnabled(cfg.Shell.Enabled)
+	}
+}
+
+// getReloadSignal returns the signal that tells the launcher to pick up new files.
+func (rw *FileSyncer) getReloadSignal() syscall.Signal {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	if rw.reloadSignal == 0 {
+		return syscall.SIGHUP
+	}
+	return rw.reloadSignal
+}
+
+// getSignalTarget returns which of the launcher's processes the reload signal reaches.
+func (rw *FileSyncer) getSignalTarget() launcher.SignalTarget {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.signalTarget
+}
+
+// getReloader returns how the app is told to pick up a push. A FileSyncer
+// without a configuration signals the launcher.
+func (rw *FileSyncer) getReloader() Reloader {
+	rw.settingsMu.RLock()
+	reloader := rw.reloader
+	rw.settingsMu.RUnlock()
+	if reloader != nil {
+		return reloader
+	}
+	return &signalReloader{filesDir: rw.targetSyncDir, finder: rw.processFinder, sig: rw.getReloadSignal(), target: rw.getSignalTarget()}
+}
+
+func (rw *FileSyncer) getStatusInterval() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.statusInterval
+}
+
+func (rw *FileSyncer) getMaxSnapshots() int {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.maxSnapshots
+}
+
+func (rw *FileSyncer) getEnvHistory() int {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.envHistory
+}
+
+func (rw *FileSyncer) getSnapshotRetention() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.snapshotRetention
+}
+
+func (rw *FileSyncer) getGCInterval() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.gcInterval
+}
+
+// getRsyncTimeout returns the maximum run time of one rsync command.
+func (rw *FileSyncer) getRsyncTimeout() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	if rw.rsyncTimeout <= 0 {
+		return DefaultRsyncTimeout
+	}
+	return rw.rsyncTimeout
+}
+
+// getRsyncStallTimeout returns how long rsync may make no progress before the
+// watchdog kills it, or 0 if the watchdog is disabled.
+func (rw *FileSyncer) getRsyncStallTimeout() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.rsyncStallTimeout
+}
+
+func (rw *FileSyncer) getDeletionRails() deletionRails {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.deletionRails
+}
+
+// getBatchCacheSize returns how many bytes of batches are cached, or 0 if the
+// cache is disabled, as it is for a FileSyncer without a configuration.
+func (rw *FileSyncer) getBatchCacheSize() int64 {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.batchCacheSize
+}
+
+func (rw *FileSyncer) getSubtreeWorkers() int {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.subtreeWorkers
+}
+
+func (rw *FileSyncer) getQuota() quotaLimits {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.quota
+}
+
+func (rw *FileSyncer) getResourceLimits() resourceLimits {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.resources
+}
+
+func (rw *FileSyncer) getPushSchedule() pushSchedule {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.pushSchedule
+}
+
+func (rw *FileSyncer) getApprovalTimeout() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.approvalTimeout
+}
+
+func (rw *FileSyncer) getPushDebounce() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.pushDebounce
+}
+
+func (rw *FileSyncer) getSequenceGapTimeout() time.Duration {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.sequenceGapTimeout
+}
+
+// getRetryPolicy returns how transient rsync and signal failures are retried.
+func (rw *FileSyncer) getRetryPolicy() retryPolicy {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.retry
+}
+
+func (rw *FileSyncer) getUnreadyDuringPush() bool {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.unreadyDuringPush
+}
+
+// getPreserveLabels reports whether files a push replaces keep their security labels.
+func (rw *FileSyncer) getPreserveLabels() bool {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.preserveLabels
+}
+
+// getWireLog reports whether every websocket message is logged.
+func (rw *FileSyncer) getWireLog() bool {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.wireLog
+}
+
+func (rw *FileSyncer) getHooks() *HookRunner {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.hooks
+}
+
+// getHealthProber returns the post-reload health probe, or nil if none is configured.
+func (rw *FileSyncer) getHealthProber() *HealthProber {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.health
+}
+
+// getEnvFileOptions returns how the database env file is written.
+func (rw *FileSyncer) getEnvFileOptions() envfile.Options {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.envOptions
+}
+
+// getDatabaseEnvProvider returns the source of the database env vars.
+func (rw *FileSyncer) getDatabaseEnvProvider() envfile.DatabaseEnvProvider {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.databaseEnv
+}
+
+// getPermissionMapping returns the ownership and mode applied to changed files.
+func (rw *FileSyncer) getPermissionMapping() permissionMapping {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.permissions
+}
+
+// waitReconnectBackoff sleeps for the configured reconnect backoff before the next dial attempt.
+func (rw *FileSyncer) waitReconnectBackoff(reason string) {
+	rw.settingsMu.RLock()
+	backoff := rw.reconnectBackoff
+	rw.settingsMu.RUnlock()
+	if backoff <= 0 {
+		backoff = DefaultReconnectBackoff
+	}
+	log.Info(reason, zap.Duration("backoff", backoff))
+	time.Sleep(backoff)
+}
+
+// hasApplied reports whether the push was already applied, so a resent push can be acknowledged without re-applying it.
+func (rw *FileSyncer) hasApplied(pushID string) bool {
+	rw.stateMu.Lock()
+	defer rw.stateMu.Unlock()
+	return rw.applied.hasApplied(pushID)
+}
+
+// recordApplied remembers a successfully applied push, in memory and on disk.
+// Database-only pushes keep the content hash of the last applied batch.
+func (rw *FileSyncer) recordApplied(pushID, hash string) {
+	rw.stateMu.Lock()
+	rw.applied.LastPushID = pushID
+	rw.applied.AppliedAt = time.Now()
+	rw.applied.RecentPushIDs = append(rw.applied.RecentPushIDs, pushID)
+	if extra := len(rw.applied.RecentPushIDs) - maxRecentPushIDs; extra > 0 {
+		rw.applied.RecentPushIDs = slices.Clone(rw.applied.RecentPushIDs[extra:])
+	}
+	if hash != "" {
+		rw.applied.LastPushHash = hash
+	}
+	rw.stateMu.Unlock()
+
+	if err := rw.saveState(); err != nil {
+		log.Warn("Failed to persist sidecar state", zap.String("pushID", pushID), zap.Error(err))
+	}
+}
+
+// isNoOpPush reports whether pushMsg only carries the batch applied last, so
+// applying it again would leave the files as they are. Forced pushes are always
+// applied, to overwrite local modifications and reload the app, and so are
+// mirror pushes, as their deletions aren't part of the batch.
+func (rw *FileSyncer) isNoOpPush(pushMsg *pb.PushMessage) bool {
+	if pushMsg.Force || pushMsg.Mirror || (!carriesBatch(pushMsg) && len(pushMsg.SubtreeBatches) == 0) || len(pushMsg.Files) > 0 ||
+		len(pushMsg.DeletedPaths) > 0 || len(pushMsg.DatabaseBranchUpdates) > 0 {
+		return false
+	}
+	hash := rw.batchOf(pushMsg).hash
+	if len(pushMsg.SubtreeBatches) > 0 {
+		hash = subtreeBatchesHash(pushMsg)
+	}
+	rw.stateMu.Lock()
+	lastHash := rw.applied.LastPushHash
+	rw.stateMu.Unlock()
+	return lastHash != "" && lastHash == hash
+}
+
+// forgetLastPushHash drops the content hash of the last applied batch once the
+// files no longer match it, so the next push of that batch is applied again.
+func (rw *FileSyncer) forgetLastPushHash() {
+	rw.stateMu.Lock()
+	rw.applied.LastPushHash = ""
+	rw.stateMu.Unlock()
+
+	if err := rw.saveState(); err != nil {
+		log.Warn("Failed to persist sidecar state", zap.Error(err))
+	}
+}
+
+// recordConnected updates the connection stats reported in status reports.
+func (rw *FileSyncer) recordConnected() {
+	rw.stateMu.Lock()
+	defer rw.stateMu.Unlock()
+	now := time.Now()
+	if !rw.connectedSince.IsZero() {
+		rw.reconnectCount++
+		rw.quality.recordReconnect(now)
+		rw.getMetrics().Count(metricReconnects, 1)
+	}
+	rw.connectedSince = now
+	rw.notifyStatusChanged()
+}
+
+func (rw *FileSyncer) sendPeriodicPings(ctx context.Context) chan error {
+	pingDone := make(chan error, 1)
+	pingTicker := time.NewTicker(pingPeriod)
+
+	go func() {
+		defer close(pingDone)
+		defer pingTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-rw.done:
+				return
+			case <-pingTicker.C:
+				// Send ping to server
+				log.Debug("Sending ping to server")
+				if err := rw.writePing(); err != nil {
+					select {
+					case pingDone <- fmt.Errorf("failed to send ping: %w", err):
+					default:
+					}
+					return
+				}
+			}
+		}
+	}()
+	return pingDone
+}
+
+// writePing sends a websocket ping on the current connection, if any.
+func (rw *FileSyncer) writePing() error {
+	rw.writeMu.Lock()
+	defer rw.writeMu.Unlock()
+	if rw.conn == nil {
+		return nil
+	}
+	now := time.Now()
+	rw.conn.SetWriteDeadline(now.Add(10 * time.Second))
+	return rw.conn.WriteMessage(websocket.PingMessage, pingPayload(now))
+}
+
+// handlePongs sets the connection's read deadline, and extends it whenever a
+// pong arrives, measuring the round trip time of the ping it answers. The
+// deadline adapts to the connection's quality.
+func (rw *FileSyncer) handlePongs() {
+	rw.conn.SetReadDeadline(time.Now().Add(rw.quality.readDeadline()))
+	rw.conn.SetPongHandler(func(payload string) error {
+		now := time.Now()
+		rw.quality.recordPong(payload, now)
+		deadline := rw.quality.readDeadline()
+		log.Debug("Received Pong, resetting read deadline", zap.Duration("deadline", deadline))
+		rw.conn.SetReadDeadline(now.Add(deadline))
+		return nil
+	})
+}
+
+// messageLoop reads messages from the WebSocket connection.
+func (rw *FileSyncer) messageLoop(ctx context.Context) error {
+	// Scope the connection's background writers to this loop so they stop when the connection ends.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if rw.shells != nil {
+		// Shell output cannot be replayed to a new connection, so hang up open sessions.
+		defer rw.shells.CloseAll()
+	}
+	if rw.tails != nil {
+		defer rw.tails.StopAll()
+	}
+	defer rw.dropBatchStreams()
+
+	rw.handlePongs()
+	pingDone := rw.sendPeriodicPings(ctx)
+	rw.sendPeriodicStatusReports(ctx)
+
+	// Main message reading loop
+	readDone := make(chan error, 1)
+	go func() {
+		defer close(readDone)
+		for {
+			messageType, message, received, err := rw.readMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					select {
+					case readDone <- fmt.Errorf("unexpected WebSocket close error: %w", err):
+					default:
+					}
+					return
+				}
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					log.Warn("WebSocket read timeout", zap.Duration("timeout", rw.quality.readDeadline()))
+					select {
+					case readDone <- fmt.Errorf("read timeout: %w", err):
+					default:
+					}
+					return
+				}
+				select {
+				case readDone <- fmt.Errorf("WebSocket read error: %w", err):
+				default:
+				}
+				return
+			}
+
+			rw.messagesReceived.Add(1)
+			rw.quality.recordReceived(len(message), received)
+			if err := rw.handleMessage(messageType, message, received); err != nil {
+				log.Error("Error handling message", zap.Error(err))
+				// Continue processing other messages even if one fails
+			}
+		}
+	}()
+
+	// Wait for either context cancellation, stop signal, or connection error
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("context cancelled during message loop")
+	case <-rw.done:
+		return fmt.Errorf("stop signal received during message loop")
+	case err := <-readDone:
+		return err
+	case err := <-pingDone:
+		return err
+	}
+}
+
+// readMessage reads the next message from the websocket. It also returns how
+// long the message took to receive once its first frame arrived, which for a
+// push is mostly the transfer of its batch.
+func (rw *FileSyncer) readMessage() (int, []byte, time.Duration, error) {
+	messageType, r, err := rw.conn.NextReader()
+	if err != nil {
+		return messageType, nil, 0, err
+	}
+	start := time.Now()
+	message, err := io.ReadAll(r)
+	return messageType, message, time.Since(start), err
+}
+
+// handleMessage handles a websocket message that took received to receive.
+func (rw *FileSyncer) handleMessage(messageType int, message []byte, received time.Duration) error {
+	switch messageType {
+	case websocket.BinaryMessage:
+		log.Debug("Received binary message", zap.Int("sizeBytes", len(message)))
+
+		// Unmarshal the message using protobuf
+		var incomingMsg pb.WebsocketMessage
+		err := proto.Unmarshal(message, &incomingMsg)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal websocket message: %w", err)
+		}
+
+		return rw.handleProtoMessage(&incomingMsg, received)
+	case websocket.CloseMessage:
+		log.Info("Received close message from server.")
+		return fmt.Errorf("server initiated close")
+	default:
+		log.Warn("Received unhandled message type", zap.Int("type", messageType))
+	}
+	return nil
+}
+
+// handleProtoMessage dispatches a message from the server, whichever transport it
+// arrived on. received is how long the message took to receive, or 0 if unknown.
+func (rw *FileSyncer) handleProtoMessage(incomingMsg *pb.WebsocketMessage, received time.Duration) error {
+	msgTypeStr := incomingMsg.MessageType.String()
+	log.Info("Received message", zap.String("type", msgTypeStr))
+	rw.logWireMessage(wireInbound, incomingMsg)
+	switch incomingMsg.MessageType {
+	case pb.WebsocketMessage_PUSH_REQUEST:
+		if pushMsg := incomingMsg.GetPushMessage(); pushMsg.GetStreamedBatchSize() > 0 {
+			return rw.startBatchStream(pushMsg)
+		}
+		return rw.enqueuePush(incomingMsg.GetPushMessage(), received)
+	case pb.WebsocketMessage_BATCH_CHUNK:
+		return rw.handleBatchChunk(incomingMsg.GetBatchChunk())
+	case pb.WebsocketMessage_PUSH_CANCEL:
+		return rw.cancelPush(incomingMsg.GetPushCancel())
+	case pb.WebsocketMessage_ACTIVATE:
+		return rw.activatePush(incomingMsg.GetActivateRequest())
+	case pb.WebsocketMessage_SNAPSHOT_CREATE, pb.WebsocketMessage_SNAPSHOT_RESTORE:
+		return rw.handleSnapshotRequest(incomingMsg.MessageType, incomingMsg.GetSnapshotRequest())
+	case pb.WebsocketMessage_ENV_ROLLBACK:
+		return rw.handleEnvRollbackRequest(incomingMsg.GetEnvRollbackRequest())
+	case pb.WebsocketMessage_MANIFEST_REQUEST:
+		return rw.handleManifestRequest(incomingMsg.GetManifestRequest())
+	case pb.WebsocketMessage_SYNC_STATUS_REQUEST:
+		return rw.handleSyncStatusRequest(incomingMsg.GetSyncStatusRequest())
+	case pb.WebsocketMessage_FILE_GET_REQUEST:
+		return rw.handleFileGetRequest(incomingMsg.GetFileGetRequest())
+	case pb.WebsocketMessage_DIR_LIST_REQUEST:
+		return rw.handleDirListRequest(incomingMsg.GetDirListRequest())
+	case pb.WebsocketMessage_HELLO_ACK:
+		return rw.handleHelloAck(incomingMsg.GetHelloAck())
+	case pb.WebsocketMessage_DIAGNOSTICS_REQUEST:
+		return rw.handleDiagnosticsRequest(incomingMsg.GetDiagnosticsRequest())
+	case pb.WebsocketMessage_SHELL_OPEN, pb.WebsocketMessage_SHELL_STDIN,
+		pb.WebsocketMessage_SHELL_RESIZE, pb.WebsocketMessage_SHELL_CLOSE:
+		return rw.handleShellMessage(incomingMsg)
+	case pb.WebsocketMessage_LOG_TAIL_REQUEST, pb.WebsocketMessage_LOG_TAIL_STOP:
+		return rw.handleLogTailMessage(incomingMsg)
+	default:
+		return fmt.Errorf("received unexpected message type: %s", msgTypeStr)
+	}
+}
+
+func (rw *FileSyncer) handleShellMessage(msg *pb.WebsocketMessage) error {
+	if rw.shells == nil {
+		return fmt.Errorf("remote shell is not available")
+	}
+	switch msg.MessageType {
+	case pb.WebsocketMessage_SHELL_OPEN:
+		return rw.shells.Open(msg.GetShellOpen())
+	case pb.WebsocketMessage_SHELL_STDIN:
+		return rw.shells.Write(msg.GetShellData())
+	case pb.WebsocketMessage_SHELL_RESIZE:
+		return rw.shells.Resize(msg.GetShellResize())
+	case pb.WebsocketMessage_SHELL_CLOSE:
+		return rw.shells.Close(msg.GetShellClose())
+	}
+	return fmt.Errorf("received unexpected shell message type: %s", msg.MessageType)
+}
+
+func (rw *FileSyncer) handleLogTailMessage(msg *pb.WebsocketMessage) error {
+	if rw.tails == nil {
+		return fmt.Errorf("log tailing is not available")
+	}
+	if msg.MessageType == pb.WebsocketMessage_LOG_TAIL_REQUEST {
+		return rw.tails.Start(msg.GetLogTailRequest())
+	}
+	return rw.tails.Stop(msg.GetLogTailStop())
+}
+
+// handlePushRequest applies a push. Cancelling ctx aborts it and rolls back any
+// files already changed, up until the launcher has been signalled.
+func (rw *FileSyncer) handlePushRequest(ctx context.Context, pushMsg *pb.PushMessage) error {
+	if pushMsg == nil {
+		return fmt.Errorf("received PUSH_REQUEST but push_message field is nil")
+	}
+	pushID := pushMsg.PushId
+	batch := rw.batchOf(pushMsg)
+	timer := rw.pushes.startTimer(pushID)
+	defer rw.pushes.stopTimer(timer)
+
+	// Reject bad injected files before anything, including the database env file, changes.
+	if invalid := validateInjectedFiles(pushMsg.Files); len(invalid) > 0 {
+		rw.sendProtoMessage(withInjectedFiles(buildPushResponse(pushID, pb.PushResponse_FAILED,
+			fmt.Sprintf("Push rejected: invalid injected file %s: %s", invalid[0].Path, invalid[0].ErrorMessage)), invalid))
+		return fmt.Errorf("push has %d invalid injected files", len(invalid))
+	}
+	if invalid := validateDeletedPaths(pushMsg.DeletedPaths); len(invalid) > 0 {
+		rw.sendProtoMessage(withDeletedPaths(buildPushResponse(pushID, pb.PushResponse_FAILED,
+			fmt.Sprintf("Push rejected: invalid deleted path %s: %s", invalid[0].Path, invalid[0].ErrorMessage)), invalid))
+		return fmt.Errorf("push has %d invalid deleted paths", len(invalid))
+	}
+	if err := validateRsyncFlags(pushMsg.RsyncFlags); err != nil {
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return err
+	}
+	// The batches to apply: the push's own, or those of its sub-trees.
+	var batches []*pushBatch
+	hash := batch.hash
+	workers := rw.getSubtreeWorkers()
+	switch {
+	case len(pushMsg.SubtreeBatches) > 0:
+		err := validateSubtreeBatches(pushMsg.SubtreeBatches)
+		if err == nil && carriesBatch(pushMsg) {
+			err = fmt.Errorf("push has both a batch and sub-tree batches")
+		}
+		if err == nil && workers <= 0 {
+			err = fmt.Errorf("sub-tree batches are disabled (sync.subtree_workers is 0)")
+		}
+		if err != nil {
+			rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+			return err
+		}
+		batches = subtreeBatchesOf(pushMsg)
+		hash = subtreeBatchesHash(pushMsg)
+	case !batch.empty():
+		batches = []*pushBatch{batch}
+	}
+	var batchesSize int64
+	for _, b := range batches {
+		batchesSize += b.size
+	}
+	if err := validateRequiredEnv(pushMsg.RequiredEnv); err != nil {
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return err
+	}
+	// The activation plan is checked against the reloader and health probe the
+	// push goes on to use, so a config reload mid-push can't swap them out.
+	health := rw.getHealthProber()
+	reloader := rw.getReloader()
+	if err := validateActivationPlan(pushMsg.ActivationPlan, reloader, health); err != nil {
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return err
+	}
+
+	// A retried push of the batch that's already live changes nothing, so don't
+	// restart the app for it.
+	if rw.isNoOpPush(pushMsg) {
+		log.Info("Push matches the last applied batch, skipping it", zap.String("pushID", pushID))
+		rw.recordApplied(pushID, hash)
+		resp := buildPushResponse(pushID, pb.PushResponse_COMPLETED, "")
+		resp.GetPushResponse().NoOp = true
+		rw.sendProtoMessage(resp)
+		return nil
+	}
+
+	// A push that creates a database branch and also changes files runs its
+	// migrations once the files are applied, so they include the push's own
+	// migration scripts, and the app is reloaded once, after them.
+	changesFiles := len(batches) > 0 || len(pushMsg.Files) > 0 || (len(pushMsg.DeletedPaths) > 0 && !pushMsg.DeletedPathsDryRun)
+	migrateWithFiles := changesFiles && createsBranch(pushMsg.DatabaseBranchUpdates) && rw.getMigrations() != nil
+	var hookResults []*pb.HookResult
+	var databaseEnvVars []envfile.DatabaseEnvVar
+	var missingEnv *missingEnvError
+	requiredEnvChecked := false
+
+	// Log database branch updates if present
+	if len(pushMsg.DatabaseBranchUpdates) > 0 {
+		log.Info("Received database branch updates",
+			zap.String("pushID", pushID),
+			zap.Int("updateCount", len(pushMsg.DatabaseBranchUpdates)))
+
+		for i, update := range pushMsg.DatabaseBranchUpdates {
+			log.Info("Database branch update",
+				zap.Int("index", i),
+				zap.String("databaseName", update.DatabaseName),
+				zap.String("previousBranchId", update.PreviousBranchId),
+				zap.String("newBranchId", update.NewBranchId),
+				zap.Bool("branchCreated", update.BranchCreated),
+				zap.String("parentBranchId", update.ParentBranchId))
+		}
+
+		// Process database branch updates
+		envVars, migrations, err := rw.processDatabaseBranchUpdates(ctx, pushID, pushMsg.DatabaseBranchUpdates, pushMsg.RequiredEnv, !migrateWithFiles, timer)
+		hookResults = append(hookResults, migrations...)
+		databaseEnvVars = envVars
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, nil, hookResults)
+		}
+		if errors.As(err, &missingEnv) {
+			// The app isn't signalled, so it keeps running with its current env.
+			log.Error("Push is missing required env vars", zap.String("pushID", pushID), zap.Strings("missing", missingEnv.names))
+			rw.sendProtoMessage(withMissingEnv(buildPushResponse(pushID, pb.PushResponse_MISSING_ENV, fmt.Sprintf("Push rejected: %v", err)), missingEnv))
+			return err
+		}
+		if errors.Is(err, errMigrationFailed) {
+			// The app isn't signalled, so it keeps using the previous branch.
+			log.Error("Database migration failed", zap.String("pushID", pushID), zap.Error(err))
+			rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)), hookResults))
+			return err
+		}
+		if err != nil {
+			log.Error("Failed to process database branch updates", zap.Error(err))
+			// Don't fail the entire push for database updates, just log the error
+			// This ensures backward compatibility
+			migrateWithFiles = false
+		}
+		requiredEnvChecked = err == nil
+	} else {
+		log.Info("No database branch updates in push message", zap.String("pushID", pushID))
+	}
+
+	// Check the env the app will be reloaded with before any file changes.
+	if !requiredEnvChecked {
+		err := rw.verifyRequiredEnv(ctx, pushMsg.RequiredEnv)
+		if errors.As(err, &missingEnv) {
+			log.Error("Push is missing required env vars", zap.String("pushID", pushID), zap.Strings("missing", missingEnv.names))
+			rw.sendProtoMessage(withHookResults(withMissingEnv(buildPushResponse(pushID, pb.PushResponse_MISSING_ENV, fmt.Sprintf("Push rejected: %v", err)), missingEnv), hookResults))
+			return err
+		}
+		if err != nil {
+			log.Error("Failed to check required env vars", zap.String("pushID", pushID), zap.Error(err))
+			rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)), hookResults))
+			return err
+		}
+	}
+
+	// Handle code changes if present. Settings are read once so a config reload
+	// mid-push doesn't change hooks or signals halfway through.
+	hooks := rw.getHooks()
+	retry := rw.getRetryPolicy()
+	permissions := rw.getPermissionMapping()
+	preserveLabels := rw.getPreserveLabels()
+	quota := rw.getQuota()
+	deletion := rw.getDeletionRails()
+	opts := withMirrorFlags(rsyncOptions{timeout: rw.getRsyncTimeout(), stallTimeout: rw.getRsyncStallTimeout(), extraFlags: pushMsg.RsyncFlags, limits: rw.getResourceLimits(), timer: timer}, pushMsg.Mirror, deletion)
+	var fileChanges fileChangeReport
+	var usage *pb.WorkspaceUsage
+	var injectedFiles []*pb.InjectedFileResult
+	files, failedTemplates, err := rw.renderInjectedFiles(ctx, pushMsg)
+	if err != nil {
+		log.Error("Failed to render injected files", zap.String("pushID", pushID), zap.Error(err))
+		rw.sendProtoMessage(withInjectedFiles(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)), failedTemplates))
+		return err
+	}
+	var deletions []*pb.DeletedPathResult
+	deletePaths := len(pushMsg.DeletedPaths) > 0 && !pushMsg.DeletedPathsDryRun
+	if len(pushMsg.DeletedPaths) > 0 && pushMsg.DeletedPathsDryRun {
+		contentDir, err := rw.contentDir()
+		if err != nil {
+			rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+			return err
+		}
+		deletions = previewDeletions(contentDir, pushMsg.DeletedPaths)
+	}
+	var subtreeResults []*pb.SubtreeResult
+	if len(batches) > 0 || len(files) > 0 || deletePaths {
+		progress := rw.newPushProgress(pushID)
+		progress.report(pb.PushProgress_DOWNLOADING, 100, batchesSize, batchesSize)
+
+		if err := checkDiskSpace(rw.targetSyncDir, batchesSize); errors.Is(err, errInsufficientDisk) {
+			log.Error("Not enough disk space to apply push", zap.String("pushID", pushID), zap.Error(err))
+			rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_INSUFFICIENT_DISK, fmt.Sprintf("Push rejected: %v", err)))
+			return err
+		} else if err != nil {
+			// Don't block pushes when free space can't be determined.
+			log.Warn("Skipping disk space check", zap.Error(err))
+		}
+
+		if len(pushMsg.Paths) > 0 && len(batches) > 0 {
+			// Fail closed, and even with force: the check guards against a batch
+			// built wrongly on the server, which force can't vouch for.
+			var outOfScope *outOfScopeError
+			if err := rw.checkPathScope(ctx, batches, opts, pushMsg.Paths); errors.As(err, &outOfScope) {
+				log.Error("Push changes paths outside its scope", zap.String("pushID", pushID), zap.Strings("paths", outOfScope.paths))
+				rw.sendProtoMessage(withOutOfScopePaths(buildPushResponse(pushID, pb.PushResponse_OUT_OF_SCOPE, fmt.Sprintf("Push rejected: %v", err)), outOfScope))
+				return err
+			} else if err != nil {
+				log.Error("Failed to check the paths the push changes", zap.String("pushID", pushID), zap.Error(err))
+				rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: failed to check the paths the push changes: %v", err)))
+				return err
+			}
+		}
+
+		if !pushMsg.Force && len(batches) > 0 {
+			conflicts, err := rw.detectConflicts(ctx, batches, opts)
+			if err != nil {
+				// Don't block pushes on a failed check; apply the batch as before.
+				log.Warn("Failed to check for local modifications", zap.String("pushID", pushID), zap.Error(err))
+			} else if len(conflicts) > 0 {
+				log.Warn("Push conflicts with local modifications", zap.String("pushID", pushID), zap.Strings("files", conflicts))
+				resp := buildPushResponse(pushID, pb.PushResponse_CONFLICT, fmt.Sprintf(
+					"Push rejected: files were modified in the deployment since the last push: %s. Push with force to overwrite them.",
+					summarizePaths(conflicts, maxPathsInMessage)))
+				resp.GetPushResponse().ConflictingFiles = conflicts
+				rw.sendProtoMessage(resp)
+				return fmt.Errorf("push conflicts with local modifications to %d files", len(conflicts))
+			}
+		}
+
+		if !pushMsg.Force && len(batches) > 0 && opts.deletes() && deletion.maxPercent > 0 {
+			// Unlike the conflict check, fail closed: a mirror push from the wrong
+			// directory could otherwise wipe the deployment.
+			var tooMany *tooManyDeletionsError
+			if err := rw.checkDeletions(ctx, batches, opts, deletion.maxPercent); errors.As(err, &tooMany) {
+				log.Warn("Push deletes too many files", zap.String("pushID", pushID), zap.Int("deleted", len(tooMany.paths)), zap.Int("total", tooMany.total))
+				rw.sendProtoMessage(withMirrorDeletions(buildPushResponse(pushID, pb.PushResponse_TOO_MANY_DELETIONS,
+					fmt.Sprintf("Push rejected: %v. Push with force to delete them anyway.", err)), tooMany))
+				return err
+			} else if err != nil {
+				log.Error("Failed to check how many files the push deletes", zap.String("pushID", pushID), zap.Error(err))
+				rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: failed to check how many files the push deletes: %v", err)))
+				return err
+			}
+		}
+
+		hookResult, err := hooks.Run(ctx, PreSyncHook, pushMsg)
+		if hookResult != nil {
+			hookResults = append(hookResults, hookResult)
+		}
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, nil, hookResults)
+		}
+		if err != nil {
+			log.Error("Pre-sync hook failed", zap.Error(err))
+			rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)), hookResults))
+			return fmt.Errorf("pre-sync hook failed: %w", err)
+		}
+
+		// Apply the rsync batch
+		progress.status(pb.PushResponse_APPLYING)
+		if rw.getUnreadyDuringPush() {
+			// Back to ready once the push has finished, whatever its outcome: either
+			// the new code is live or the files were restored.
+			rw.readiness.Unready("applying push " + pushID)
+			defer rw.readiness.Ready()
+		}
+		var backup *syncBackup
+		if len(batches) > 0 {
+			attempts, rsyncErr := retry.run(ctx, retryStepRsync, isTransientRsyncError, func() error {
+				if backup != nil {
+					// Undo the failed attempt so the next one starts from the same files.
+					if restoreErr := backup.restore(); restoreErr != nil {
+						return fmt.Errorf("failed to restore files before retrying rsync: %w", restoreErr)
+					}
+					backup.discard()
+				}
+				onProgress := func(bytesDone int64, percent int32) {
+					progress.report(pb.PushProgress_APPLYING, percent, bytesDone, 0)
+				}
+				var applyErr error
+				if len(pushMsg.SubtreeBatches) > 0 {
+					backup, subtreeResults, applyErr = rw.applySubtreeBatches(ctx, batches, opts, workers, onProgress)
+				} else {
+					backup, applyErr = rw.applyRsyncBatch(ctx, batch, opts, onProgress)
+				}
+				return applyErr
+			})
+			timer.attempts(retryStepRsync, attempts)
+			err = rsyncErr
+		} else {
+			backup, err = rw.newInjectionBackup()
+		}
+		if backup != nil {
+			defer backup.discard()
+		}
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, backup, hookResults)
+		}
+		if errors.Is(err, errRsyncStalled) {
+			// rsync was killed partway through, so put back what it had changed.
+			log.Error("rsync stalled applying the batch", zap.String("pushID", pushID), zap.Error(err))
+			rw.rollBack(pushID, backup)
+			rw.sendProtoMessage(withSubtreeResults(withHookResults(buildPushResponse(pushID, pb.PushResponse_STALLED, fmt.Sprintf("Push application failed: %v", err)), hookResults), subtreeResults))
+			return fmt.Errorf("push application failed: %w", err)
+		}
+		if err != nil {
+			log.Error("Failed to apply rsync batch", zap.Error(err))
+			// Send PushResponse with FAILED status
+			rw.sendProtoMessage(withSecurityDenials(withSubtreeResults(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), subtreeResults), err))
+			return fmt.Errorf("push application failed: %w", err)
+		}
+
+		progress.finish(pb.PushProgress_APPLYING)
+		log.Info("Rsync batch applied successfully.")
+
+		if deletePaths {
+			deletions, err = backup.applyDeletions(pushMsg.DeletedPaths)
+			if err != nil {
+				log.Error("Failed to delete paths", zap.Error(err))
+				rw.rollBack(pushID, backup)
+				rw.sendProtoMessage(withDeletedPaths(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), deletions))
+				return err
+			}
+			log.Info("Deleted paths", zap.Int("count", len(deletions)))
+		}
+
+		if len(files) > 0 {
+			injectedFiles, err = writeInjectedFiles(backup.targetDir, files, preserveLabels)
+			if err != nil {
+				log.Error("Failed to write injected files", zap.Error(err))
+				rw.rollBack(pushID, backup)
+				rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions))
+				return err
+			}
+			log.Info("Wrote injected files", zap.Int("count", len(injectedFiles)))
+		}
+
+		if err := permissions.apply(backup.targetDir, writtenPaths(backup.changes, sortedInjectedPaths(files))); err != nil {
+			log.Error("Failed to map file ownership and permissions", zap.Error(err))
+			rw.rollBack(pushID, backup)
+			rw.sendProtoMessage(withSecurityDenials(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions), err))
+			return fmt.Errorf("failed to map file ownership and permissions: %w", err)
+		}
+		if preserveLabels {
+			if err := preserveSecurityLabels(backup, writtenPaths(backup.changes, nil)); err != nil {
+				log.Error("Failed to keep the security labels of replaced files", zap.Error(err))
+				rw.rollBack(pushID, backup)
+				rw.sendProtoMessage(withSecurityDenials(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions), err))
+				return err
+			}
+		}
+
+		usage, err = quota.check(backup.targetDir)
+		if errors.Is(err, errQuotaExceeded) {
+			log.Error("Push takes the workspace over its quota", zap.String("pushID", pushID), zap.Error(err))
+			rw.rollBack(pushID, backup)
+			rw.sendProtoMessage(withWorkspaceUsage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_QUOTA_EXCEEDED, fmt.Sprintf("Push rejected: %v", err)), hookResults), injectedFiles), deletions), usage))
+			return err
+		} else if err != nil {
+			// Don't block pushes when the workspace can't be measured.
+			log.Warn("Skipping workspace quota check", zap.Error(err))
+		} else if usage.Warning != "" {
+			log.Warn("Workspace is over its soft quota", zap.String("pushID", pushID), zap.String("warning", usage.Warning))
+		}
+
+		hookResult, err = hooks.Run(ctx, PostSyncHook, pushMsg)
+		if hookResult != nil {
+			hookResults = append(hookResults, hookResult)
+		}
+		// Last chance to cancel: once the launcher is signalled the new files are live.
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, backup, hookResults)
+		}
+		if err != nil {
+			// The files are already applied, but don't restart the app into a state the hook rejected.
+			log.Error("Post-sync hook failed", zap.Error(err))
+			rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push applied but not activated: %v", err)), hookResults), injectedFiles), deletions))
+			return fmt.Errorf("post-sync hook failed: %w", err)
+		}
+
+		installResult, err := rw.runInstall(ctx, backup.targetDir, pushID, append(transferredFiles(backup.changes), sortedInjectedPaths(files)...), progress, timer)
+		if installResult != nil {
+			hookResults = append(hookResults, installResult)
+		}
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, backup, hookResults)
+		}
+		if err != nil {
+			// The app would fail on the dependencies it's missing.
+			log.Error("Dependency install failed", zap.String("pushID", pushID), zap.Error(err))
+			rw.rollBack(pushID, backup)
+			rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions))
+			return err
+		}
+
+		buildResult, err := rw.runBuild(ctx, backup.targetDir, pushID, progress, timer)
+		if buildResult != nil {
+			hookResults = append(hookResults, buildResult)
+		}
+		if ctx.Err() != nil {
+			return rw.pushCancelled(pushID, backup, hookResults)
+		}
+		if err != nil {
+			// Don't reload the app into files that didn't build.
+			log.Error("Build failed", zap.String("pushID", pushID), zap.Error(err))
+			rw.rollBack(pushID, backup)
+			rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions))
+			return err
+		}
+
+		if migrateWithFiles {
+			migrations, err := rw.runMigrations(ctx, backup.targetDir, pushID, pushMsg.DatabaseBranchUpdates, databaseEnvVars)
+			hookResults = append(hookResults, migrations...)
+			if ctx.Err() != nil {
+				return rw.pushCancelled(pushID, backup, hookResults)
+			}
+			if err != nil {
+				// Don't reload the app into code that needs the migration.
+				log.Error("Database migration failed", zap.String("pushID", pushID), zap.Error(err))
+				rw.rollBack(pushID, backup)
+				rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults), injectedFiles), deletions))
+				return err
+			}
+		}
+
+		if backup.release != "" && rw.requireApproval {
+			approvedBy, err := rw.awaitApproval(ctx, pushID)
+			if ctx.Err() != nil {
+				return rw.pushCancelled(pushID, backup, hookResults)
+			}
+			if err != nil {
+				log.Error("Staged push was not approved", zap.String("pushID", pushID), zap.Error(err))
+				rw.rollBack(pushID, backup)
+				rw.sendProtoMessage(withDeletedPaths(withInjectedFiles(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push staged but not activated: %v", err)), hookResults), injectedFiles), deletions))
+				return err
+			}
+			log.Info("Staged push approved", zap.String("pushID", pushID), zap.String("approvedBy", approvedBy))
+		}
+
+		if backup.release != "" {
+			if err := activateRelease(rw.targetSyncDir, backup.release); err != nil {
+				log.Error("Failed to activate release", zap.Error(err))
+				if restoreErr := backup.restore(); restoreErr != nil {
+					log.Error("Failed to remove staged release", zap.Error(restoreErr))
+				}
+				rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, pb.PushResponse_FAILED, fmt.Sprintf("Push application failed: %v", err)), hookResults))
+				return fmt.Errorf("failed to activate release: %w", err)
+			}
+			log.Info("Activated release", zap.String("release", backup.release))
+		}
+
+		// Warm the cache last, so an approval that took a while can't leave it cold again.
+		if warmResult := rw.warmCache(ctx, backup.targetDir, pushID, append(transferredFiles(backup.changes), sortedInjectedPaths(files)...), timer); warmResult != nil {
+			hookResults = append(hookResults, warmResult)
+		}
+
+		// Tell the launcher script which push it's reloading for.
+		hs, err := rw.writeReloadHandshake(pushID, launcher.ReloadReasonPush, pushMsg.DatabaseBranchUpdates, "")
+		if err != nil {
+			return err
+		}
+		log.Info("Successfully wrote launcher handshake", zap.String("path", launcher.HandshakePath(rw.targetSyncDir)), zap.String("pushID", pushID))
+		if err := writeChangeEvent(rw.targetSyncDir, newChangeEvent(pushID, backup.changes, injectedFiles, deletions)); err != nil {
+			// The app still reloads; a hot-reloader watching for events can rescan.
+			log.Warn("Failed to write change event", zap.String("pushID", pushID), zap.Error(err))
+		}
+
+		progress.status(pb.PushResponse_RELOADING)
+		progress.report(pb.PushProgress_RELOADING, 0, 0, 0)
+		signalledAt := time.Now()
+		var unhealthy *stageUnhealthyError
+		if len(pushMsg.ActivationPlan) > 0 {
+			err = rw.reloadInStages(ctx, retry, reloader.(*signalReloader), pushMsg.ActivationPlan, health, timer)
+		} else {
+			err = rw.reloadApp(ctx, retry, reloader, hs, timer)
+		}
+		if errors.As(err, &unhealthy) {
+			log.Error("Activation stage is not healthy, rolling back", zap.String("pushID", pushID), zap.Int("stage", unhealthy.stage), zap.Error(err), zap.String("output", unhealthy.output))
+			status, message := pb.PushResponse_ROLLED_BACK, fmt.Sprintf("Activation stage %d is not healthy, rolled back to the previous files: %v. Last probe: %s", unhealthy.stage, unhealthy.err, unhealthy.output)
+			if rollbackErr := rw.rollBackStages(pushID, backup, reloader.(*signalReloader), unhealthy); rollbackErr != nil {
+				log.Error("Failed to roll back staged activation", zap.String("pushID", pushID), zap.Error(rollbackErr))
+				status, message = pb.PushResponse_RELOAD_FAILED, fmt.Sprintf("Activation stage %d is not healthy: %v. Last probe: %s. Rolling back failed: %v", unhealthy.stage, unhealthy.err, unhealthy.output, rollbackErr)
+			}
+			resp := withHookResults(buildPushResponse(pushID, status, message), hookResults)
+			resp.GetPushResponse().FailedActivationStage = int32(unhealthy.stage)
+			rw.sendProtoMessage(resp)
+			return err
+		}
+		if err != nil {
+			log.Error("Failed to reload the app", zap.String("strategy", reloader.Strategy()), zap.Error(err))
+			status, message := pb.PushResponse_FAILED, fmt.Sprintf("Failed to reload the app (%s): %v", reloader.Strategy(), err)
+			if errors.Is(err, launcher.ErrNotRunning) {
+				status = pb.PushResponse_LAUNCHER_NOT_RUNNING
+			}
+			if backup.release != "" {
+				// Keep the current release in line with the code the app is still running.
+				if previous, rollbackErr := rollbackRelease(rw.targetSyncDir); rollbackErr != nil {
+					log.Warn("Failed to roll back release", zap.Error(rollbackErr))
+				} else {
+					log.Info("Rolled back to previous release", zap.String("release", previous))
+					status, message = pb.PushResponse_ROLLED_BACK, fmt.Sprintf("Failed to reload the app (%s), rolled back to the previous release: %v", reloader.Strategy(), err)
+				}
+			}
+			rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, status, message), hookResults))
+			return fmt.Errorf("failed to reload the app: %w", err)
+		}
+
+		log.Info("Asked the app to reload", zap.String("strategy", reloader.Strategy()))
+
+		rw.updateManifest(backup)
+		fileChanges = buildFileChangeReport(backup.changes)
+		if usage != nil {
+			rw.stateMu.Lock()
+			rw.workspaceUsage = usage
+			rw.stateMu.Unlock()
+		}
+		rw.audit.filesChanged(pushID, fileChanges.total)
+		if backup.release != "" {
+			if err := pruneReleases(rw.targetSyncDir, maxReleases); err != nil {
+				log.Warn("Failed to remove old releases", zap.Error(err))
+			}
+		}
+
+		// The new files are live, so cancelling the push no longer stops the probe.
+		output, err := health.Wait(context.WithoutCancel(ctx))
+		timer.since(stageSignalToHealthy, signalledAt)
+		if err != nil {
+			log.Error("App is not healthy after reload", zap.String("pushID", pushID), zap.Error(err), zap.String("output", output))
+			rw.recordApplied(pushID, hash)
+			rw.sendProtoMessage(withWorkspaceUsage(withDeletedPaths(withInjectedFiles(withFileChanges(withHookResults(buildPushResponse(pushID, pb.PushResponse_RELOAD_FAILED,
+				fmt.Sprintf("Push applied but the app is not healthy: %v. Last probe: %s", err, output)), hookResults), fileChanges), injectedFiles), deletions), usage))
+			return fmt.Errorf("app not healthy after reload: %w", err)
+		}
+		progress.finish(pb.PushProgress_RELOADING)
+		if health != nil {
+			progress.status(pb.PushResponse_HEALTHY)
+		}
+		log.Info("Push activated. Sending ACK to proxy.")
+	} else {
+		log.Info("No code changes to apply, database updates only.")
+	}
+
+	rw.recordApplied(pushID, hash)
+	// A mirror push split into sub-trees leaves the rest of the tree as it was.
+	if pushMsg.Mirror && !batch.empty() {
+		rw.clearResync()
+	}
+
+	// Always send a success response, regardless of whether there were code changes
+	rw.sendProtoMessage(withSubtreeResults(withWorkspaceUsage(withDeletedPaths(withInjectedFiles(withFileChanges(withHookResults(buildPushResponse(pushID, pb.PushResponse_COMPLETED, ""), hookResults), fileChanges), injectedFiles), deletions), usage), subtreeResults))
+
+	return nil
+}
+
+// processDatabaseBranchUpdates handles database branch updates by refreshing the
+// env file. With reload set it also runs the migrations of any branch created
+// and signals the app; otherwise the caller does both once the push's files are
+// applied. Either way the refreshed env must have every var in requiredEnv, or
+// a *missingEnvError is returned and nothing more is done. It returns the
+// refreshed env vars and the migrations' results.
+func (rw *FileSyncer) processDatabaseBranchUpdates(ctx context.Context, pushID string, updates []*pb.DatabaseBranchUpdate, requiredEnv []string, reload bool, timer *pushTimer) ([]envfile.DatabaseEnvVar, []*pb.HookResult, error) {
+	if len(updates) == 0 {
+		return nil, nil, nil
+	}
+
+	log.Info("Processing database branch updates",
+		zap.Int("count", len(updates)),
+		zap.String("deploymentID", rw.deploymentID))
+
+	// Call the API to get the latest database environment variables
+	// This will include the updated branch connections
+	envStart := time.Now()
+	provider := rw.getDatabaseEnvProvider()
+	envVars, err := envfile.WriteDatabaseEnvFile(ctx, provider, rw.targetSyncDir, rw.getEnvFileOptions())
+	timer.since(stageEnvWrite, envStart)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to refresh database env file: %w", err)
+	}
+	rw.audit.envWritten(pushID, envVars)
+	if len(envVars) > 0 {
+		if _, err := envfile.RecordProvenance(rw.targetSyncDir, pushID, provider.Name(), envVars, time.Now()); err != nil {
+			log.Warn("Failed to record env provenance", zap.Error(err))
+		}
+	}
+	if keep := rw.getEnvHistory(); keep > 0 {
+		if _, err := envfile.SaveVersion(rw.targetSyncDir, pushID, keep, time.Now()); err != nil {
+			log.Warn("Failed to save env file version", zap.Error(err))
+		}
+	}
+
+	log.Info("Successfully refreshed database environment variables after branch update")
+	if err := rw.checkRequiredEnv(requiredEnv, envVars); err != nil {
+		return envVars, nil, err
+	}
+	if !reload {
+		log.Info("Migrating and reloading once the push's files are applied", zap.String("pushID", pushID))
+		return envVars, nil, nil
+	}
+
+	var migrations []*pb.HookResult
+	if createsBranch(updates) && rw.getMigrations() != nil {
+		dir, err := rw.contentDir()
+		if err != nil {
+			return envVars, nil, fmt.Errorf("%w: %w", errMigrationFailed, err)
+		}
+		if migrations, err = rw.runMigrations(ctx, dir, pushID, updates, envVars); err != nil {
+			return envVars, migrations, err
+		}
+	}
+
+	hs, err := rw.writeReloadHandshake(pushID, launcher.ReloadReasonDatabaseUpdate, updates, "")
+	if err != nil {
+		return envVars, migrations, err
+	}
+
+	// Reload the app so it picks up the database connection changes
+	if err := rw.reloadApp(ctx, rw.getRetryPolicy(), rw.getReloader(), hs, timer); err != nil {
+		log.Error("Failed to reload the app after database update", zap.Error(err))
+		return envVars, migrations, fmt.Errorf("failed to reload the app after database update: %w", err)
+	}
+
+	log.Info("Reloaded the app after database branch update")
+
+	return envVars, migrations, nil
+}
+
+// reloadApp asks the app to reload for hs, retrying with retry when a signalled
+// process exited in the meantime or the app refused the connection. With the
+// signal strategy, how each launcher was signalled goes on the push's final
+// response. The files
+// are already in place by then, so cancelling the push doesn't stop the retries.
+func (rw *FileSyncer) reloadApp(ctx context.Context, retry retryPolicy, reloader Reloader, hs launcher.Handshake, timer *pushTimer) error {
+	ctx = context.WithoutCancel(ctx)
+	var launchers []*pb.LauncherSignalResult
+	attempts, err := retry.run(ctx, retryStepSignal, isTransientReloadError, func() error {
+		if signaller, ok := reloader.(*signalReloader); ok {
+			return signaller.signalLaunchers(&launchers, nil)
+		}
+		return reloader.Reload(ctx, hs)
+	})
+	timer.attempts(retryStepSignal, attempts)
+	timer.launcherResults(launchers)
+	return err
+}
+
+// applyRsyncBatch applies the received rsync batch data. Files that rsync replaces
+// are saved to the returned backup so a cancelled push can be rolled back; the
+// caller must discard it once the push is finished. onProgress, if set, is called
+// as rsync reports its overall progress.
+func (rw *FileSyncer) applyRsyncBatch(ctx context.Context, batch *pushBatch, opts rsyncOptions, onProgress func(bytesDone int64, percent int32)) (*syncBackup, error) {
+	if batch.empty() {
+		log.Info("Received empty batch data. Nothing to apply.")
+		return nil, nil // Not an error, just nothing to do
+	}
+	backup, err := rw.newApplyBackup()
+	if err != nil {
+		return nil, err
+	}
+	backup.changes, err = rw.runRsyncBatch(ctx, batch, backup, opts, onProgress)
+	return backup, err
+}
+
+// newApplyBackup returns where the next push's files are applied: a new release
+// in swap mode, otherwise the files directory with an empty backup directory.
+func (rw *FileSyncer) newApplyBackup() (*syncBackup, error) {
+	sidecarDir := launcher.SidecarDir(rw.targetSyncDir)
+	if err := os.MkdirAll(sidecarDir, launcher.Volume.InternalDir); err != nil {
+		return nil, fmt.Errorf("failed to create sidecar directory %s: %w", sidecarDir, err)
+	}
+	if err := os.MkdirAll(rw.targetSyncDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create target sync directory %s: %w", rw.targetSyncDir, err)
+	}
+	if rw.applyMode == ApplyModeSwap {
+		return newReleaseBackup(rw.targetSyncDir)
+	}
+	return newSyncBackup(rw.targetSyncDir, sidecarDir)
+}
+
+// runRsyncBatch runs rsync to apply batch to backup's target directory, or to
+// the batch's sub-tree of it, backing up the files it replaces. It returns the
+// changes rsync made, relative to the target directory, even if it failed
+// partway through.
+func (rw *FileSyncer) runRsyncBatch(ctx context.Context, batch *pushBatch, backup *syncBackup, opts rsyncOptions, onProgress func(bytesDone int64, percent int32)) ([]itemizedChange, error) {
+	sidecarDir := launcher.SidecarDir(rw.targetSyncDir)
+	writeStart := time.Now()
+	tempBatchPath, err := batch.writeTemp(sidecarDir)
+	opts.timer.since(stageBatchWrite, writeStart)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempBatchPath)
+
+	destDir, backupDir := backup.targetDir, backup.dir
+	if batch.subtree != "" {
+		if destDir, err = subtreeDir(destDir, batch.subtree); err != nil {
+			return nil, err
+		}
+		if backupDir != "" {
+			backupDir = filepath.Join(backupDir, filepath.FromSlash(batch.subtree))
+		}
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create sub-tree directory %s: %w", destDir, err)
+		}
+	}
+
+	args := append([]string{"--archive"}, opts.flags()...)
+	if backupDir != "" {
+		args = append(args, "--backup", fmt.Sprintf("--backup-dir=%s", backupDir))
+	}
+	args = append(args,
+		// Itemize changes so files created by the batch can be removed on rollback.
+		"--out-format=%i %n",
+		"--info=progress2",
+		fmt.Sprintf("--read-batch=%s", tempBatchPath),
+		fmt.Sprintf("%s/", destDir),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	rsyncCmd := execCommand(ctx, rw.rsync(), args...)
+	// Run rsync in its own process group so the watchdog can kill the processes
+	// it forks along with it.
+	rsyncCmd.SysProcAttr = newProcessGroupAttr()
+	rsyncCmd.Cancel = func() error {
+		return stopRsync(rsyncCmd.Process)
+	}
+	rsyncCmd.WaitDelay = rsyncStopGracePeriod
+
+	log.Info("Running rsync command", zap.String("command", rsyncCmd.String()))
+	startTime := time.Now()
+	outputWriter := &progressWriter{onProgress: onProgress}
+	rsyncCmd.Stdout = outputWriter
+	rsyncCmd.Stderr = outputWriter
+	stalled := false
+	if err = rsyncCmd.Start(); err == nil {
+		opts.limits.throttleRsync(rsyncCmd.Process.Pid)
+		var watchdog *rsyncWatchdog
+		if opts.stallTimeout > 0 {
+			watchdog = startRsyncWatchdog(rsyncCmd.Process.Pid, opts.stallTimeout)
+		}
+		err = rsyncCmd.Wait()
+		if watchdog != nil {
+			stalled = watchdog.Stop()
+		}
+	}
+	duration := time.Since(startTime)
+	opts.timer.since(stageRsync, startTime)
+	rw.recordRsyncMetrics(duration, err, stalled, ctx.Err())
+	output := outputWriter.Bytes()
+	changes := inSubtree(parseItemizedChanges(output), batch.subtree)
+	rw.stateMu.Lock()
+	rw.lastRsyncOutput = output
+	rw.stateMu.Unlock()
+
+	logFields := []zap.Field{
+		zap.Duration("duration", duration),
+		zap.String("output", string(output)),
+	}
+
+	if err != nil && stalled {
+		log.Error("Rsync command stalled", append(logFields, zap.Error(err))...)
+		return changes, fmt.Errorf("%w: no CPU or I/O for %v, killed after %v (raise timeouts.rsync_stall if the volume is just slow): %w",
+			errRsyncStalled, opts.stallTimeout, duration, err)
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			log.Error("Rsync command timed out", append(logFields, zap.Error(err))...)
+			return changes, fmt.Errorf("rsync command timed out after %v (raise timeouts.rsync to allow longer syncs): %w", duration, err)
+		case context.Canceled:
+			log.Warn("Rsync command cancelled", append(logFields, zap.Error(err))...)
+			return changes, fmt.Errorf("rsync command cancelled after %v: %w", duration, err)
+		}
+		log.Error("Rsync apply failed", append(logFields, zap.Error(err))...)
+		if module := currentSecurityModule(); module.enforcing() {
+			if denials := rsyncSecurityDenials(output, backup.targetDir, module); len(denials) > 0 {
+				return changes, fmt.Errorf("rsync command failed: %w: %w. Output: %s", err, &securityDenialError{module: module, denials: denials}, string(output))
+			}
+		}
+		return changes, fmt.Errorf("rsync command failed: %w. Output: %s", err, string(output))
+	}
+
+	if len(output) > 0 {
+		log.Info("Rsync completed successfully", logFields...)
+	} else {
+		log.Info("Rsync completed successfully (no output)", zap.Duration("duration", duration))
+	}
+
+	return changes, nil
+}
+
+// detectConflicts lists the files the batches would overwrite that were modified
+// in the deployment since a previous push wrote them, using rsync dry runs.
+func (rw *FileSyncer) detectConflicts(ctx context.Context, batches []*pushBatch, opts rsyncOptions) ([]string, error) {
+	manifest, err := loadManifest(rw.targetSyncDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest) == 0 {
+		return nil, nil // Nothing synced yet, so nothing can conflict
+	}
+
+	changes, err := rw.dryRunBatches(ctx, batches, opts)
+	if err != nil {
+		return nil, err
+	}
+	contentDir, err := rw.contentDir()
+	if err != nil {
+		return nil, err
+	}
+	return manifest.findConflicts(contentDir, transferredFiles(changes))
+}
+
+// dryRunBatches lists the changes applying each of batches with opts would make
+// to the synced code, without making them.
+func (rw *FileSyncer) dryRunBatches(ctx context.Context, batches []*pushBatch, opts rsyncOptions) ([]itemizedChange, error) {
+	var changes []itemizedChange
+	for _, batch := range batches {
+		batchChanges, err := rw.dryRunBatch(ctx, batch, opts)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, batchChanges...)
+	}
+	return changes, nil
+}
+
+// dryRunBatch lists the changes applying the batch with opts would make to the
+// synced code, without making them.
+func (rw *FileSyncer) dryRunBatch(ctx context.Context, batch *pushBatch, opts rsyncOptions) ([]itemizedChange, error) {
+	contentDir, err := rw.contentDir()
+	if err != nil {
+		return nil, err
+	}
+	sidecarDir := launcher.SidecarDir(rw.targetSyncDir)
+	if err := os.MkdirAll(sidecarDir, launcher.Volume.InternalDir); err != nil {
+		return nil, fmt.Errorf("failed to create sidecar directory %s: %w", sidecarDir, err)
+	}
+	writeStart := time.Now()
+	tempBatchPath, err := batch.writeTemp(sidecarDir)
+	opts.timer.since(stageBatchWrite, writeStart)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempBatchPath)
+
+	destDir := contentDir
+	if batch.subtree != "" {
+		if destDir, err = subtreeDir(contentDir, batch.subtree); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(destDir); errors.Is(err, fs.ErrNotExist) {
+			// rsync can't make the sub-tree's parents; a new sub-tree is all created
+			// files whatever it's compared with.
+			emptyDir, err := os.MkdirTemp(sidecarDir, "dry_run_*")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create directory for dry run: %w", err)
+			}
+			defer os.RemoveAll(emptyDir)
+			destDir = emptyDir
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	args := append([]string{"--archive"}, opts.flags()...)
+	args = append(args,
+		"--dry-run",
+		"--out-format=%i %n",
+		fmt.Sprintf("--read-batch=%s", tempBatchPath),
+		fmt.Sprintf("%s/", destDir),
+	)
+	rsyncStart := time.Now()
+	output, err := execCommand(ctx, rw.rsync(), args...).CombinedOutput()
+	opts.timer.since(stageRsync, rsyncStart)
+	if err != nil {
+		return nil, fmt.Errorf("rsync dry run failed: %w. Output: %s", err, string(output))
+	}
+	return inSubtree(parseItemizedChanges(output), batch.subtree), nil
+}
+
+// contentDir returns the directory holding the synced code: the current release
+// in swap mode, otherwise the files directory itself.
+func (rw *FileSyncer) contentDir() (string, error) {
+	if rw.applyMode != ApplyModeSwap {
+		return rw.targetSyncDir, nil
+	}
+	release, err := currentRelease(rw.targetSyncDir)
+	if err != nil || release != "" {
+		return release, err
+	}
+	return rw.targetSyncDir, nil // The first release is seeded from the files directory
+}
+
+// updateManifest records the files written by an applied batch.
+func (rw *FileSyncer) updateManifest(backup *syncBackup) {
+	if backup == nil {
+		return
+	}
+	manifest, err := loadManifest(rw.targetSyncDir)
+	if err != nil {
+		log.Warn("Failed to load manifest, starting a new one", zap.Error(err))
+		manifest = fileManifest{}
+	}
+	// Deleted paths are dropped from the manifest.
+	if err := manifest.record(backup.targetDir, append(transferredFiles(backup.changes), deletedPaths(backup.changes)...)); err != nil {
+		log.Warn("Failed to update manifest", zap.Error(err))
+		return
+	}
+	if err := saveManifest(rw.targetSyncDir, manifest); err != nil {
+		log.Warn("Failed to save manifest", zap.Error(err))
+	}
+}
+
+// summarizePaths joins up to limit paths for a human-readable message.
+func summarizePaths(paths []string, limit int) string {
+	if len(paths) <= limit {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:limit], ", "), len(paths)-limit)
+}
+
+// writeBatchFile saves batch data to a temporary file inside the .sidecar
+// directory. The caller must remove the file.
+func writeBatchFile(sidecarDir string, batchData []byte) (string, error) {
+	tempBatchFile, err := os.CreateTemp(sidecarDir, "sync_batch_*.bin")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary batch file in %s: %w", sidecarDir, err)
+	}
+
+	bytesWritten, err := tempBatchFile.Write(batchData)
+	if err != nil {
+		tempBatchFile.Close()
+		os.Remove(tempBatchFile.Name())
+		return "", fmt.Errorf("failed to write to temporary batch file %s: %w", tempBatchFile.Name(), err)
+	}
+	tempBatchPath := tempBatchFile.Name()
+	if err := tempBatchFile.Close(); err != nil {
+		os.Remove(tempBatchPath)
+		return "", fmt.Errorf("failed to close temporary batch file %s: %w", tempBatchPath, err)
+	}
+
+	log.Info("Saved received batch data",
+		zap.String("path", tempBatchPath),
+		zap.Int("sizeBytes", bytesWritten),
+	)
+	return tempBatchPath, nil
+}
+
+// buildWebSocketURL constructs the WebSocket URL for the rsync sidecar.
+func (rw *FileSyncer) buildWebSocketURL() string {
+	wsURL, err := transport.WebSocketURL(rw.apiURL, rw.appID, rw.deploymentID)
+	if err != nil {
+		log.Fatal("Invalid BIFROST_API_URL provided",
+			zap.String("apiURL", rw.apiURL),
+			zap.Error(err),
+		)
+	}
+	return wsURL
+}
+
+// sendProtoMessage marshals and sends a protobuf message over the WebSocket,
+// logging rather than returning any failure. A message the server must get is
+// kept in the outbox instead, and sent after reconnecting.
+func (rw *FileSyncer) sendProtoMessage(msg proto.Message) {
+	if err := rw.trySendProtoMessage(msg); err != nil {
+		if wsMsg, ok := msg.(*pb.WebsocketMessage); ok && isDurableMessage(wsMsg) {
+			qerr := rw.queueOutbound(wsMsg)
+			if qerr == nil {
+				log.Warn("Failed to send proto message, queued it to send after reconnecting",
+					zap.String("messageType", wsMsg.MessageType.String()),
+					zap.Error(err),
+				)
+				return
+			}
+			log.Warn("Failed to queue proto message", zap.Error(qerr))
+		}
+		log.Warn("Failed to send proto message",
+			zap.String("messageType", fmt.Sprintf("%T", msg)),
+			zap.Error(err),
+		)
+	}
+}
+
+// Send sends msg to the control plane over whichever connection is open. It
+// returns an error if the sidecar is disconnected or the write fails, so
+// callers that must not lose the message can retry.
+func (rw *FileSyncer) Send(msg *pb.WebsocketMessage) error {
+	return rw.trySendProtoMessage(msg)
+}
+
+// trySendProtoMessage marshals and sends a protobuf message over the WebSocket.
+// It returns an error if there is no active connection or the write fails, so
+// callers that must not lose the message can retry.
+func (rw *FileSyncer) trySendProtoMessage(msg proto.Message) error {
+	if wsMsg, ok := msg.(*pb.WebsocketMessage); ok && wsMsg.MessageType == pb.WebsocketMessage_PUSH_RESPONSE {
+		if resp := wsMsg.GetPushResponse(); resp != nil && resp.Pod == nil {
+			resp.Pod = Pod
+		}
+		rw.pushes.addTiming(wsMsg.GetPushResponse())
+		rw.pushes.addSequenceGap(wsMsg.GetPushResponse())
+		rw.audit.addApprovedBy(wsMsg.GetPushResponse())
+		if rw.pushes.holdFinalResponse(wsMsg) {
+			return nil
+		}
+		// Recorded even if the send fails, so it can be replayed after reconnecting.
+		rw.recordPushResponse(wsMsg.GetPushResponse())
+		rw.recordAudit(wsMsg.GetPushResponse())
+		rw.emitPushWebhook(wsMsg.GetPushResponse())
+		rw.recordPushMetrics(wsMsg.GetPushResponse())
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proto message: %w", err)
+	}
+	return rw.writeProtoData(msg, data)
+}
+
+// writeProtoData sends msg, already marshalled to data, over whichever
+// connection is open.
+func (rw *FileSyncer) writeProtoData(msg proto.Message, data []byte) error {
+	rw.writeMu.Lock()
+	defer rw.writeMu.Unlock()
+	switch {
+	case rw.conn != nil:
+		start := time.Now()
+		if err := rw.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			return fmt.Errorf("failed to write %d bytes to websocket: %w", len(data), err)
+		}
+		rw.quality.recordSent(len(data), time.Since(start))
+	case rw.poller != nil:
+		if err := rw.poller.Post(data); err != nil {
+			return fmt.Errorf("failed to post %d bytes: %w", len(data), err)
+		}
+	case rw.sendOffline != nil:
+		rw.sendOffline(msg)
+	default:
+		return fmt.Errorf("no active websocket connection")
+	}
+	rw.messagesSent.Add(1)
+	rw.logWireMessage(wireOutbound, msg)
+	log.Debug("Successfully sent proto message",
+		zap.String("messageType", fmt.Sprintf("%T", msg)),
+		zap.Int("sizeBytes", len(data)),
+	)
+	return nil
+}
+
+func buildPushResponse(pushID string, status pb.PushResponse_PushStatus, errorMessage string) *pb.WebsocketMessage {
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_RESPONSE,
+		Message: &pb.WebsocketMessage_PushResponse{
+			PushResponse: &pb.PushResponse{
+				Status:       status,
+				ErrorMessage: errorMessage,
+				PushId:       pushID,
+			},
+		},
+	}
+}
+
+// withHookResults attaches hook results to a push response message.
+func withHookResults(msg *pb.WebsocketMessage, results []*pb.HookResult) *pb.WebsocketMessage {
+	msg.GetPushResponse().HookResults = results
+	return msg
+}
+
+// withFileChanges attaches the paths a push changed to a push response message.
+func withFileChanges(msg *pb.WebsocketMessage, report fileChangeReport) *pb.WebsocketMessage {
+	resp := msg.GetPushResponse()
+	resp.CreatedFiles = report.created
+	resp.ModifiedFiles = report.modified
+	resp.DeletedFiles = report.deleted
+	resp.FileChangesTruncated = report.truncated
+	return msg
+}
@@ -1,4 +1,4 @@
-package main
+package syncer
 
 import (
 	"context"
@@ -20,9 +20,11 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
 )
 
-// mockProcess implements ProcessSignaler for testing
+// mockProcess implements launcher.ProcessSignaler for testing
 type mockProcess struct {
 	signalCalls []syscall.Signal
 	signalErr   error
@@ -32,17 +34,20 @@ func (m *mockProcess) Signal(sig syscall.Signal) error {
 	if m.signalErr != nil {
 		return m.signalErr
 	}
+	if sig == 0 {
+		return nil // Liveness probes aren't recorded
+	}
 	m.signalCalls = append(m.signalCalls, sig)
 	return nil
 }
 
-// mockProcessFinder implements ProcessFinder for testing
+// mockProcessFinder implements launcher.ProcessFinder for testing
 type mockProcessFinder struct {
 	processes map[int]*mockProcess
 	findErr   error
 }
 
-func (m *mockProcessFinder) FindProcess(pid int) (ProcessSignaler, error) {
+func (m *mockProcessFinder) FindProcess(pid int) (launcher.ProcessSignaler, error) {
 	if m.findErr != nil {
 		return nil, m.findErr
 	}
@@ -132,7 +137,40 @@ func TestHelperProcess(t *testing.T) {
 
 	// Simulate rsync behavior
 	if cmdBase == "rsync" {
+		if len(args) == 1 && args[0] == "--version" {
+			fmt.Fprintf(os.Stdout, "rsync  version 3.4.1  protocol version 32\n")
+			os.Exit(0)
+		}
 		// Check for a specific argument or environment variable to trigger failure
+		if sleep, err := time.ParseDuration(os.Getenv("HELPER_RSYNC_SLEEP")); err == nil {
+			time.Sleep(sleep) // Simulate a long-running transfer
+		}
+		if argsFile := os.Getenv("HELPER_RSYNC_ARGS_FILE"); argsFile != "" {
+			f, _ := os.OpenFile(argsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			fmt.Fprintln(f, strings.Join(args, " "))
+			f.Close()
+		}
+		// Exit as if files vanished, the first time only: the marker file records
+		// that it happened.
+		if marker := os.Getenv("HELPER_RSYNC_VANISH_ONCE"); marker != "" {
+			if _, err := os.Stat(marker); err != nil {
+				os.WriteFile(marker, nil, 0644)
+				fmt.Fprintf(os.Stderr, "file has vanished: \"/src/app.log\"\n")
+				os.Exit(24)
+			}
+		}
+		// Fail only the rsync writing to a destination that contains this, e.g. one sub-tree.
+		if dest := os.Getenv("HELPER_RSYNC_FAIL_DEST"); dest != "" && strings.Contains(args[len(args)-1], dest) {
+			fmt.Fprintf(os.Stderr, "rsync simulation error output\n")
+			os.Exit(1)
+		}
+		// Fail with these lines of output, separated by ';', as for a partial transfer.
+		if output := os.Getenv("HELPER_RSYNC_FAIL_OUTPUT"); output != "" {
+			for _, line := range strings.Split(output, ";") {
+				fmt.Fprintln(os.Stderr, line)
+			}
+			os.Exit(23)
+		}
 		if os.Getenv("HELPER_RSYNC_FAIL") == "1" {
 			fmt.Fprintf(os.Stderr, "rsync simulation error output\n")
 			os.Exit(1) // Simulate rsync error exit code
@@ -157,6 +195,14 @@ func TestHelperProcess(t *testing.T) {
 			os.Exit(3)
 		}
 
+		// Itemized changes, separated by ';', e.g. ">f+++++++++ app.py;>f.st...... lib.py"
+		for _, line := range strings.Split(os.Getenv("HELPER_RSYNC_ITEMIZE"), ";") {
+			if line != "" {
+				fmt.Fprintln(os.Stdout, line)
+			}
+		}
+		fmt.Fprintf(os.Stdout, "\r          1,024  50%%    1.00MB/s    0:00:01 (xfr#1, to-chk=1/2)")
+		fmt.Fprintf(os.Stdout, "\r          2,048 100%%    1.00MB/s    0:00:02 (xfr#2, to-chk=0/2)\n")
 		fmt.Fprintf(os.Stdout, "rsync simulation success output\n")
 		os.Exit(0) // Simulate rsync success
 	} else {
@@ -173,12 +219,22 @@ func TestNewFileSyncer(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // Ensure context is cancelled eventually
 
-	rw, err := NewFileSyncer(ctx, "http://localhost:8080", "test-key", "app1", "deployment1", tmpDir)
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, "http://localhost:8080", "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	cfg.API.URL = "http://localhost:8080"
+	cfg.AppID = "app1"
+	cfg.DeploymentID = "deployment1"
+	cfg.Sync.FilesDir = tmpDir
+	cfg.Timeouts.StatusInterval = 0
+
+	rw, err := NewFileSyncer(ctx, cfg, tokens)
 	require.NoError(t, err)
 	require.NotNil(t, rw)
 
 	assert.Equal(t, "http://localhost:8080", rw.apiURL)
-	assert.Equal(t, "test-key", rw.apiKey)
+	assert.Equal(t, tokens, rw.tokens)
 	assert.Equal(t, "app1", rw.appID)
 	assert.Equal(t, "deployment1", rw.deploymentID)
 	assert.Equal(t, tmpDir, rw.targetSyncDir)
@@ -228,7 +284,6 @@ func TestFileSyncer_Stop(t *testing.T) {
 
 	rw := &FileSyncer{
 		apiURL:        "http://localhost:8080", // Not used directly in Stop, but needed for New
-		apiKey:        "test-key",
 		appID:         "app1",
 		deploymentID:  "deployment1",
 		targetSyncDir: tmpDir,
@@ -237,7 +292,7 @@ func TestFileSyncer_Stop(t *testing.T) {
 	}
 
 	// Manually connect for this test
-	headers := http.Header{"X-Api-Key": []string{rw.apiKey}}
+	headers := http.Header{"X-Api-Key": []string{"test-key"}}
 	conn, _, err := websocket.DefaultDialer.Dial(wsURL, headers)
 	require.NoError(t, err)
 	rw.conn = conn // Assign the connection
@@ -284,8 +339,13 @@ func TestBuildWebSocketURL(t *testing.T) {
 		},
 		{
 			name:     "http url with path",
-			apiURL:   "http://localhost:8080/basepath", // Base path should be ignored
-			expected: "ws://localhost:8080/api/v1/push/sidecar/app1/deployment1",
+			apiURL:   "http://localhost:8080/basepath", // Base path is kept as a prefix
+			expected: "ws://localhost:8080/basepath/api/v1/push/sidecar/app1/deployment1",
+		},
+		{
+			name:     "https url with path and trailing slash",
+			apiURL:   "https://example.com/bifrost/",
+			expected: "wss://example.com/bifrost/api/v1/push/sidecar/app1/deployment1",
 		},
 		{
 			name:     "https url with port",
@@ -314,8 +374,8 @@ func TestApplyRsyncBatch(t *testing.T) {
 	require.NoError(t, err)
 	defer os.RemoveAll(tmpDir)
 
-	// Need a valid PID file for sendSignalToLauncher to potentially work
-	sidecarDir := getSidecarDir(tmpDir)
+	// Need a valid PID file for launcher.Signal to potentially work
+	sidecarDir := launcher.SidecarDir(tmpDir)
 	require.NoError(t, os.MkdirAll(sidecarDir, 0777))
 	pidFilePath := filepath.Join(sidecarDir, "launcher.pid")
 	err = os.WriteFile(pidFilePath, []byte("12345"), 0644)
@@ -387,7 +447,7 @@ func TestApplyRsyncBatch(t *testing.T) {
 				mockFinder.processes[12345] = &mockProcess{}
 			}
 
-			launcherDir := getLauncherDir(testSpecificDir)
+			launcherDir := launcher.Dir(testSpecificDir)
 			require.NoError(t, os.MkdirAll(launcherDir, 0777))
 			err = os.WriteFile(filepath.Join(launcherDir, "launcher.pid"), []byte("12345"), 0644)
 			require.NoError(t, err)
@@ -420,7 +480,7 @@ func TestApplyRsyncBatch(t *testing.T) {
 			defer func() { execCommand = originalExecCommand }()
 
 			// Run the function under test
-			err := rw.handlePushRequest(&pb.PushMessage{PushId: pushID, BatchFile: tt.batchData})
+			err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: pushID, BatchFile: tt.batchData})
 
 			// Check error
 			if tt.expectedErr != "" {
@@ -455,6 +515,9 @@ func TestApplyRsyncBatch(t *testing.T) {
 					case message := <-mockServer.messages:
 						err2 := proto.Unmarshal(message, &wsMessage)
 						require.NoError(t, err2, "Failed to unmarshal websocket message")
+						if wsMessage.MessageType == pb.WebsocketMessage_PUSH_PROGRESS || isIntermediatePushStatus(wsMessage.GetPushResponse().GetStatus()) {
+							continue // Progress updates and intermediate states precede the final response
+						}
 						break waitLoop
 					case <-time.After(1 * time.Second):
 						t.Fatal("Timed out waiting for websocket message")
@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+// templateData is what injected file templates are rendered against.
+type templateData struct {
+	// Env holds the deployment's shared database env vars, with secret
+	// references resolved; ScopedEnv holds the scoped ones by scope.
+	Env          map[string]string
+	ScopedEnv    map[string]map[string]string
+	AppID        string
+	DeploymentID string
+	Push         templatePush
+}
+
+type templatePush struct {
+	ID                string
+	ChangeDescription string
+	FilesChanged      int32
+	Additions         int32
+	Deletions         int32
+}
+
+// parseFileTemplate parses an injected file's content. A reference to a
+// missing env var is an error rather than an empty string.
+func parseFileTemplate(path string, content []byte) (*template.Template, error) {
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func hasTemplates(files map[string]*pb.InjectedFile) bool {
+	for _, file := range files {
+		if file.GetTemplate() {
+			return true
+		}
+	}
+	return false
+}
+
+// renderInjectedFiles returns files with every template replaced by its
+// rendered content. Env vars are fetched when the push is applied, so a
+// template picks up database branch changes made by the same push.
+func (rw *FileSyncer) renderInjectedFiles(ctx context.Context, pushMsg *pb.PushMessage) (map[string]*pb.InjectedFile, []*pb.InjectedFileResult, error) {
+	if !hasTemplates(pushMsg.Files) {
+		return pushMsg.Files, nil, nil
+	}
+	envVars, err := envfile.FetchDatabaseEnvVars(ctx, rw.getDatabaseEnvProvider(), rw.getEnvFileOptions().Secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch env vars for templates: %w", err)
+	}
+	data := templateData{
+		Env:          make(map[string]string),
+		ScopedEnv:    make(map[string]map[string]string),
+		AppID:        rw.appID,
+		DeploymentID: rw.deploymentID,
+		Push: templatePush{
+			ID:                pushMsg.PushId,
+			ChangeDescription: pushMsg.ChangeDescription,
+			FilesChanged:      pushMsg.FilesChanged,
+			Additions:         pushMsg.Additions,
+			Deletions:         pushMsg.Deletions,
+		},
+	}
+	for _, envVar := range envVars {
+		if envVar.Scope == "" {
+			data.Env[envVar.EnvVarName] = envVar.ConnectionURI
+			continue
+		}
+		if data.ScopedEnv[envVar.Scope] == nil {
+			data.ScopedEnv[envVar.Scope] = make(map[string]string)
+		}
+		data.ScopedEnv[envVar.Scope][envVar.EnvVarName] = envVar.ConnectionURI
+	}
+
+	rendered := make(map[string]*pb.InjectedFile, len(pushMsg.Files))
+	var failed []*pb.InjectedFileResult
+	for _, path := range sortedInjectedPaths(pushMsg.Files) {
+		file := pushMsg.Files[path]
+		if !file.GetTemplate() {
+			rendered[path] = file
+			continue
+		}
+		content, err := renderFileTemplate(path, file.GetContent(), data)
+		if err != nil {
+			failed = append(failed, &pb.InjectedFileResult{Path: path, ErrorMessage: err.Error()})
+			continue
+		}
+		rendered[path] = &pb.InjectedFile{Content: content, Mode: file.GetMode()}
+	}
+	if len(failed) > 0 {
+		return nil, failed, fmt.Errorf("failed to render %d injected file templates", len(failed))
+	}
+	return rendered, nil, nil
+}
+
+func renderFileTemplate(path string, content []byte, data templateData) ([]byte, error) {
+	tmpl, err := parseFileTemplate(path, content)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if out.Len() > maxInjectedFileSize {
+		return nil, fmt.Errorf("rendered file is %d bytes, over the %d byte limit", out.Len(), maxInjectedFileSize)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+func newTemplateTestSyncer(t *testing.T) (*FileSyncer, *mockWebsocketServer) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]envfile.DatabaseEnvVar{
+			{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/app"},
+			{EnvVarName: "QUEUE_URL", ConnectionURI: "redis://queue", Scope: "worker"},
+		})
+	}))
+	t.Cleanup(server.Close)
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, server.URL, "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.apiURL = server.URL
+	rw.tokens = tokens
+	rw.appID = "app1"
+	rw.deploymentID = "deployment1"
+	rw.databaseEnv = &envfile.APIProvider{APIURL: server.URL, Tokens: tokens, DeploymentID: "deployment1"}
+	return rw, mockServer
+}
+
+func TestRenderInjectedFiles(t *testing.T) {
+	rw, _ := newTemplateTestSyncer(t)
+
+	files, failed, err := rw.renderInjectedFiles(context.Background(), &pb.PushMessage{
+		PushId: "push-1",
+		Files: map[string]*pb.InjectedFile{
+			"app.conf": {Template: true, Mode: 0600, Content: []byte(
+				`db={{ .Env.DATABASE_URL }} queue={{ index .ScopedEnv "worker" "QUEUE_URL" }} push={{ .Push.ID }} dep={{ .DeploymentID }}`)},
+			"raw.txt": {Content: []byte("{{ not rendered }}")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	assert.Equal(t, "db=postgres://db/app queue=redis://queue push=push-1 dep=deployment1", string(files["app.conf"].GetContent()))
+	assert.Equal(t, uint32(0600), files["app.conf"].GetMode())
+	assert.Equal(t, "{{ not rendered }}", string(files["raw.txt"].GetContent()))
+
+	_, failed, err = rw.renderInjectedFiles(context.Background(), &pb.PushMessage{
+		Files: map[string]*pb.InjectedFile{"app.conf": {Template: true, Content: []byte("{{ .Env.MISSING }}")}},
+	})
+	assert.Error(t, err)
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed[0].GetErrorMessage(), "MISSING")
+}
+
+func TestHandlePushRequest_TemplatedFile(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1",
+		Files:  map[string]*pb.InjectedFile{"config/db.env": {Template: true, Content: []byte("DATABASE_URL={{ .Env.DATABASE_URL }}\n")}},
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	data, err := os.ReadFile(filepath.Join(rw.targetSyncDir, "config/db.env"))
+	require.NoError(t, err)
+	assert.Equal(t, "DATABASE_URL=postgres://db/app\n", string(data))
+
+	// A template that doesn't parse is rejected with the other validation errors.
+	err = rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2",
+		Files:  map[string]*pb.InjectedFile{"bad.conf": {Template: true, Content: []byte("{{ .Env.DATABASE_URL ")}},
+	})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "invalid template")
+}
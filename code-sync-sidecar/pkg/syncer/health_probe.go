@@ -0,0 +1,122 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+const (
+	DefaultHealthTimeout  = 60 * time.Second
+	DefaultHealthInterval = 2 * time.Second
+
+	// healthAttemptTimeout bounds a single probe so a hung app doesn't use up the whole deadline.
+	healthAttemptTimeout = 5 * time.Second
+	maxProbeOutputLength = 2 * 1024
+)
+
+// HealthProber checks that the app is serving again after it was reloaded, by
+// requesting an HTTP URL or connecting to a TCP address.
+type HealthProber struct {
+	url      string
+	tcpAddr  string
+	timeout  time.Duration
+	interval time.Duration
+	client   *http.Client
+}
+
+// NewHealthProber creates a HealthProber, or returns nil when cfg configures no probe.
+func NewHealthProber(cfg HealthConfig) *HealthProber {
+	if cfg.URL == "" && cfg.TCPAddress == "" {
+		return nil
+	}
+	hp := &HealthProber{
+		url:      cfg.URL,
+		tcpAddr:  cfg.TCPAddress,
+		timeout:  time.Duration(cfg.Timeout),
+		interval: time.Duration(cfg.Interval),
+		client: &http.Client{
+			Timeout: healthAttemptTimeout,
+			// A redirect means the app is up; don't follow it to somewhere else.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+	if hp.timeout <= 0 {
+		hp.timeout = DefaultHealthTimeout
+	}
+	if hp.interval <= 0 {
+		hp.interval = DefaultHealthInterval
+	}
+	return hp
+}
+
+// Wait probes the app until it is healthy or the probe deadline passes. It
+// returns the output of the last attempt and, if the app never became healthy,
+// an error. A nil HealthProber reports healthy immediately.
+func (hp *HealthProber) Wait(ctx context.Context) (string, error) {
+	if hp == nil {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, hp.timeout)
+	defer cancel()
+
+	var lastOutput string
+	for attempt := 1; ; attempt++ {
+		output, err := hp.probe(ctx)
+		if err == nil {
+			log.Info("App is healthy after reload", zap.Int("attempts", attempt))
+			return output, nil
+		}
+		log.Debug("Health probe failed", zap.Int("attempt", attempt), zap.Error(err))
+		// An attempt cut short by the deadline says less than the one before it.
+		if ctx.Err() == nil || lastOutput == "" {
+			lastOutput = output
+		}
+
+		select {
+		case <-ctx.Done():
+			return lastOutput, fmt.Errorf("app not healthy after %v (%d attempts): %w", hp.timeout, attempt, err)
+		case <-time.After(hp.interval):
+		}
+	}
+}
+
+// probe makes a single attempt. It returns an error unless the app is healthy.
+func (hp *HealthProber) probe(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthAttemptTimeout)
+	defer cancel()
+
+	if hp.tcpAddr != "" {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", hp.tcpAddr)
+		if err != nil {
+			return err.Error(), err
+		}
+		conn.Close()
+		return fmt.Sprintf("connected to %s", hp.tcpAddr), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hp.url, nil)
+	if err != nil {
+		return err.Error(), err
+	}
+	resp, err := hp.client.Do(req)
+	if err != nil {
+		return err.Error(), err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeOutputLength))
+	output := strings.TrimSpace(fmt.Sprintf("%s %s", resp.Status, body))
+	if resp.StatusCode >= http.StatusBadRequest {
+		return output, fmt.Errorf("GET %s returned %s", hp.url, resp.Status)
+	}
+	return output, nil
+}
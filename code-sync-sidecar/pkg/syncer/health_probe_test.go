@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHealthProber_HTTP(t *testing.T) {
+	// The app fails its first two checks while it restarts.
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	hp := NewHealthProber(HealthConfig{URL: server.URL, Timeout: Duration(5 * time.Second), Interval: Duration(10 * time.Millisecond)})
+	output, err := hp.Wait(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "200 OK ok", output)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestHealthProber_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database unavailable", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hp := NewHealthProber(HealthConfig{URL: server.URL, Timeout: Duration(100 * time.Millisecond), Interval: Duration(10 * time.Millisecond)})
+	output, err := hp.Wait(context.Background())
+	assert.ErrorContains(t, err, "app not healthy after 100ms")
+	assert.Equal(t, "500 Internal Server Error database unavailable", output)
+}
+
+func TestHealthProber_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+
+	hp := NewHealthProber(HealthConfig{TCPAddress: addr, Timeout: Duration(time.Second), Interval: Duration(10 * time.Millisecond)})
+	_, err = hp.Wait(context.Background())
+	assert.NoError(t, err)
+
+	listener.Close()
+	hp.timeout = 50 * time.Millisecond
+	_, err = hp.Wait(context.Background())
+	assert.ErrorContains(t, err, "app not healthy")
+}
+
+func TestNewHealthProber_Disabled(t *testing.T) {
+	hp := NewHealthProber(HealthConfig{})
+	assert.Nil(t, hp)
+	_, err := hp.Wait(context.Background())
+	assert.NoError(t, err, "no probe means the app counts as healthy")
+}
+
+func TestHandlePushRequest_ReloadFailed(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boot failed", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+		health:        NewHealthProber(HealthConfig{URL: server.URL, Timeout: Duration(100 * time.Millisecond), Interval: Duration(10 * time.Millisecond)}),
+	}
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.ErrorContains(t, err, "app not healthy after reload")
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_RELOAD_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "502 Bad Gateway boot failed")
+	assert.True(t, rw.hasApplied("push-1"), "the files were applied even though the app is unhealthy")
+}
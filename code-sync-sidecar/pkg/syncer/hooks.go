@@ -0,0 +1,133 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	PreSyncHook  = "pre-sync"
+	PostSyncHook = "post-sync"
+
+	DefaultHookTimeout  = 60 * time.Second
+	maxHookOutputLength = 8 * 1024
+	hookWaitDelay       = 2 * time.Second
+)
+
+// getHooksDir returns the default location of push hook scripts. Hooks live in
+// the synced tree so they are versioned and pushed with the app's code.
+func getHooksDir(filesDir string) string {
+	return filepath.Join(filesDir, ".bifrost", "hooks")
+}
+
+// HookRunner executes the optional pre-sync.sh and post-sync.sh scripts around
+// rsync application.
+type HookRunner struct {
+	dir          string
+	timeout      time.Duration
+	appID        string
+	deploymentID string
+	filesDir     string
+}
+
+// NewHookRunner creates a HookRunner for scripts in dir.
+func NewHookRunner(dir string, timeout time.Duration, appID, deploymentID, filesDir string) *HookRunner {
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	return &HookRunner{
+		dir:          dir,
+		timeout:      timeout,
+		appID:        appID,
+		deploymentID: deploymentID,
+		filesDir:     filesDir,
+	}
+}
+
+// Run executes the named hook if its script exists. It returns a nil result
+// when there is no script, and an error when the hook exits non-zero, times out
+// or ctx is cancelled.
+func (hr *HookRunner) Run(ctx context.Context, name string, pushMsg *pb.PushMessage) (*pb.HookResult, error) {
+	if hr == nil {
+		return nil, nil
+	}
+	scriptPath := filepath.Join(hr.dir, name+".sh")
+	info, err := os.Stat(scriptPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s hook %s: %w", name, scriptPath, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hr.timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if info.Mode()&0111 != 0 {
+		cmd = execCommand(ctx, scriptPath)
+	} else {
+		cmd = execCommand(ctx, "/bin/sh", scriptPath)
+	}
+	cmd.Dir = hr.filesDir
+	// Don't wait indefinitely on children of a killed hook that still hold the output pipe.
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = append(os.Environ(),
+		"BIFROST_HOOK="+name,
+		"BIFROST_PUSH_ID="+pushMsg.GetPushId(),
+		"BIFROST_APP_ID="+hr.appID,
+		"BIFROST_DEPLOYMENT_ID="+hr.deploymentID,
+		"BIFROST_FILES_DIR="+hr.filesDir,
+		"BIFROST_CHANGE_DESCRIPTION="+pushMsg.GetChangeDescription(),
+		"BIFROST_FILES_CHANGED="+strconv.Itoa(int(pushMsg.GetFilesChanged())),
+		"BIFROST_ADDITIONS="+strconv.Itoa(int(pushMsg.GetAdditions())),
+		"BIFROST_DELETIONS="+strconv.Itoa(int(pushMsg.GetDeletions())),
+	)
+
+	log.Info("Running push hook", zap.String("hook", name), zap.String("command", cmd.String()))
+	startTime := time.Now()
+	output, err := cmd.CombinedOutput()
+	duration := time.Since(startTime)
+
+	result := &pb.HookResult{
+		Name:       name,
+		ExitCode:   int32(cmd.ProcessState.ExitCode()),
+		Output:     truncateOutput(output, maxHookOutputLength),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return result, fmt.Errorf("%s hook timed out after %v", name, hr.timeout)
+		case context.Canceled:
+			return result, fmt.Errorf("%s hook cancelled: %w", name, ctx.Err())
+		}
+		return result, fmt.Errorf("%s hook failed: %w. Output: %s", name, err, result.Output)
+	}
+
+	log.Info("Push hook succeeded",
+		zap.String("hook", name),
+		zap.Duration("duration", duration),
+		zap.String("output", result.Output),
+	)
+	return result, nil
+}
+
+// truncateOutput keeps the tail of command output, which usually holds the error.
+func truncateOutput(output []byte, limit int) string {
+	if len(output) <= limit {
+		return string(output)
+	}
+	return "...(truncated)\n" + string(output[len(output)-limit:])
+}
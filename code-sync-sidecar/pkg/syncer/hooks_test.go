@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func writeHook(t *testing.T, dir, name, script string, mode os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".sh"), []byte(script), mode))
+}
+
+func TestHookRunner_Run(t *testing.T) {
+	filesDir := t.TempDir()
+	hooksDir := getHooksDir(filesDir)
+	runner := NewHookRunner(hooksDir, time.Second, "app1", "deployment1", filesDir)
+	pushMsg := &pb.PushMessage{PushId: "push-1", FilesChanged: 3}
+
+	// Missing hooks are skipped.
+	result, err := runner.Run(context.Background(), PreSyncHook, pushMsg)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	writeHook(t, hooksDir, PreSyncHook, "#!/bin/sh\necho \"$BIFROST_HOOK $BIFROST_PUSH_ID $BIFROST_FILES_CHANGED\"\n", 0755)
+	result, err = runner.Run(context.Background(), PreSyncHook, pushMsg)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, PreSyncHook, result.GetName())
+	assert.Equal(t, int32(0), result.GetExitCode())
+	assert.Equal(t, "pre-sync push-1 3\n", result.GetOutput())
+
+	// Non-executable scripts are run with sh.
+	writeHook(t, hooksDir, PostSyncHook, "echo migration failed >&2\nexit 4\n", 0644)
+	result, err = runner.Run(context.Background(), PostSyncHook, pushMsg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration failed")
+	assert.Equal(t, int32(4), result.GetExitCode())
+
+	writeHook(t, hooksDir, PostSyncHook, "sleep 5\n", 0644)
+	_, err = runner.Run(context.Background(), PostSyncHook, pushMsg)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestHandlePushRequest_PreSyncHookFails(t *testing.T) {
+	filesDir := t.TempDir()
+	hooksDir := getHooksDir(filesDir)
+	writeHook(t, hooksDir, PreSyncHook, "echo not allowed right now\nexit 1\n", 0644)
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: filesDir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+		hooks:         NewHookRunner(hooksDir, time.Second, "app1", "deployment1", filesDir),
+	}
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	require.Error(t, err)
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	require.Len(t, resp.GetHookResults(), 1)
+	assert.Equal(t, PreSyncHook, resp.GetHookResults()[0].GetName())
+	assert.Contains(t, resp.GetHookResults()[0].GetOutput(), "not allowed right now")
+}
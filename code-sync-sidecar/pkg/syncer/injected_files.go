@@ -0,0 +1,182 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	defaultInjectedFileMode = 0644
+	// maxInjectedFileSize keeps injected files to config-sized content; anything
+	// larger belongs in the rsync batch.
+	maxInjectedFileSize = 1 << 20
+)
+
+// validateSyncPath checks that rel names a path inside the files directory,
+// outside the sidecar's internal entries, and returns it cleaned.
+func validateSyncPath(rel string) (string, error) {
+	if rel == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	if filepath.IsAbs(rel) || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q is outside the files directory", rel)
+	}
+	clean := filepath.Clean(rel)
+	if top, _, _ := strings.Cut(clean, string(filepath.Separator)); isInternalEntry(top) {
+		return "", fmt.Errorf("path %q is reserved for the sidecar", rel)
+	}
+	return clean, nil
+}
+
+// resolveInRoot joins rel to root, checking that no symlink among its existing
+// parent directories leads outside root.
+func resolveInRoot(root, rel string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	path := filepath.Join(root, rel)
+	dir := filepath.Dir(path)
+	for {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if realDir != realRoot && !strings.HasPrefix(realDir, realRoot+string(filepath.Separator)) {
+				return "", fmt.Errorf("path %q leads outside the files directory through a symlink", rel)
+			}
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+		// Directories that don't exist yet will be created inside the nearest one that does.
+		dir = filepath.Dir(dir)
+	}
+}
+
+// validateInjectedFiles checks every injected file before anything is written,
+// returning a failed result for each invalid one.
+func validateInjectedFiles(files map[string]*pb.InjectedFile) []*pb.InjectedFileResult {
+	var failed []*pb.InjectedFileResult
+	for _, path := range sortedInjectedPaths(files) {
+		file := files[path]
+		var err error
+		if _, err = validateSyncPath(path); err == nil {
+			switch {
+			case file.GetMode()&^0777 != 0:
+				err = fmt.Errorf("mode %o has bits other than permissions", file.GetMode())
+			case len(file.GetContent()) > maxInjectedFileSize:
+				err = fmt.Errorf("file is %d bytes, over the %d byte limit", len(file.GetContent()), maxInjectedFileSize)
+			case file.GetTemplate():
+				_, err = parseFileTemplate(path, file.GetContent())
+			}
+		}
+		if err != nil {
+			failed = append(failed, &pb.InjectedFileResult{Path: path, ErrorMessage: err.Error()})
+		}
+	}
+	return failed
+}
+
+// writeInjectedFiles atomically writes each injected file under root, returning
+// a result per file. It stops at the first failure; files after it are reported
+// as not written. With preserveLabels a file that replaces another keeps its
+// security labels.
+func writeInjectedFiles(root string, files map[string]*pb.InjectedFile, preserveLabels bool) ([]*pb.InjectedFileResult, error) {
+	var results []*pb.InjectedFileResult
+	var firstErr error
+	for _, path := range sortedInjectedPaths(files) {
+		result := &pb.InjectedFileResult{Path: path}
+		results = append(results, result)
+		if firstErr != nil {
+			result.ErrorMessage = "not written: an earlier file failed"
+			continue
+		}
+		if err := writeInjectedFile(root, path, files[path], preserveLabels); err != nil {
+			result.ErrorMessage = err.Error()
+			firstErr = fmt.Errorf("failed to write injected file %s: %w", path, err)
+			continue
+		}
+		result.Success = true
+	}
+	return results, firstErr
+}
+
+func writeInjectedFile(root, rel string, file *pb.InjectedFile, preserveLabels bool) error {
+	rel, err := validateSyncPath(rel)
+	if err != nil {
+		return err
+	}
+	path, err := resolveInRoot(root, rel)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	mode := os.FileMode(file.GetMode())
+	if mode == 0 {
+		mode = defaultInjectedFileMode
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(file.GetContent()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	// Chmod rather than relying on the umask, so the mode is exactly what was asked for.
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set mode: %w", err)
+	}
+	if preserveLabels {
+		if err := copySecurityLabels(path, tmp.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to keep security label: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+// newInjectionBackup prepares a push that injects or deletes files without a
+// batch. In swap mode the changes go to a new release, as a batch would.
+func (rw *FileSyncer) newInjectionBackup() (*syncBackup, error) {
+	if rw.applyMode == ApplyModeSwap {
+		return newReleaseBackup(rw.targetSyncDir)
+	}
+	sidecarDir := launcher.SidecarDir(rw.targetSyncDir)
+	if err := os.MkdirAll(sidecarDir, launcher.Volume.InternalDir); err != nil {
+		return nil, fmt.Errorf("failed to create sidecar directory %s: %w", sidecarDir, err)
+	}
+	return newSyncBackup(rw.targetSyncDir, sidecarDir)
+}
+
+func sortedInjectedPaths(files map[string]*pb.InjectedFile) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// withInjectedFiles attaches per-file results to a push response.
+func withInjectedFiles(msg *pb.WebsocketMessage, results []*pb.InjectedFileResult) *pb.WebsocketMessage {
+	msg.GetPushResponse().InjectedFiles = results
+	return msg
+}
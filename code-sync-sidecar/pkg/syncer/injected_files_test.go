@@ -0,0 +1,100 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestValidateSyncPath(t *testing.T) {
+	for _, path := range []string{"config/nginx.conf", "flags.json", "a/../b.txt"} {
+		_, err := validateSyncPath(path)
+		assert.NoError(t, err, path)
+	}
+	for _, path := range []string{"", "/etc/passwd", "../outside", "a/../../outside", ".sidecar/env.sh", ".launcher/handshake.json", "current/app.py"} {
+		_, err := validateSyncPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestWriteInjectedFiles(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "flags.json"), []byte("{}"), 0644))
+
+	results, err := writeInjectedFiles(root, map[string]*pb.InjectedFile{
+		"flags.json":       {Content: []byte(`{"beta": true}`)},
+		"nginx/nginx.conf": {Content: []byte("worker_processes 1;\n"), Mode: 0600},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*pb.InjectedFileResult{
+		{Path: "flags.json", Success: true},
+		{Path: "nginx/nginx.conf", Success: true},
+	}, results)
+
+	data, err := os.ReadFile(filepath.Join(root, "flags.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"beta": true}`, string(data))
+	info, err := os.Stat(filepath.Join(root, "nginx/nginx.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(root, "flags.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestWriteInjectedFiles_SymlinkEscape(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linked")))
+
+	results, err := writeInjectedFiles(root, map[string]*pb.InjectedFile{
+		"linked/evil.conf": {Content: []byte("x")},
+		"z.conf":           {Content: []byte("y")},
+	}, false)
+	assert.ErrorContains(t, err, "through a symlink")
+	require.Len(t, results, 2)
+	assert.False(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.NoFileExists(t, filepath.Join(outside, "evil.conf"))
+	assert.NoFileExists(t, filepath.Join(root, "z.conf"))
+}
+
+func TestHandlePushRequest_InjectedFiles(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+
+	// A push with only injected files still reloads the app.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1",
+		Files:  map[string]*pb.InjectedFile{"config/flags.json": {Content: []byte(`{"beta": true}`)}},
+	}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.Len(t, resp.GetInjectedFiles(), 1)
+	assert.True(t, resp.GetInjectedFiles()[0].GetSuccess())
+	data, err := os.ReadFile(filepath.Join(rw.targetSyncDir, "config/flags.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"beta": true}`, string(data))
+	assert.FileExists(t, launcher.HandshakePath(rw.targetSyncDir))
+
+	// Invalid paths reject the push before anything is written.
+	err = rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId:    "push-2",
+		BatchFile: []byte("batch"),
+		Files: map[string]*pb.InjectedFile{
+			"ok.conf":     {Content: []byte("x")},
+			"../etc/evil": {Content: []byte("x")},
+		},
+	})
+	assert.Error(t, err)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	require.Len(t, resp.GetInjectedFiles(), 1)
+	assert.Equal(t, "../etc/evil", resp.GetInjectedFiles()[0].GetPath())
+	assert.NoFileExists(t, filepath.Join(rw.targetSyncDir, "ok.conf"))
+}
@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// InstallHook names the HookResult of the dependency install command.
+	InstallHook = "install"
+
+	DefaultInstallTimeout = 10 * time.Minute
+)
+
+// DefaultInstallManifests are the dependency manifests and lock files whose
+// change runs the install command, unless install.manifests lists others.
+var DefaultInstallManifests = []string{
+	"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+	"requirements.txt", "pyproject.toml", "poetry.lock", "Pipfile", "Pipfile.lock",
+	"go.mod", "go.sum",
+	"Gemfile", "Gemfile.lock",
+	"Cargo.toml", "Cargo.lock",
+	"composer.json", "composer.lock",
+}
+
+var errInstallFailed = errors.New("dependency install failed")
+
+// InstallRunner runs the configured install command, such as npm install,
+// when a push changes a dependency manifest, before the app is reloaded.
+type InstallRunner struct {
+	command   string
+	timeout   time.Duration
+	manifests []string // Patterns matched against the name of each changed file
+	filesDir  string
+}
+
+// NewInstallRunner creates an InstallRunner, or returns nil if command is empty.
+func NewInstallRunner(command string, manifests []string, timeout time.Duration, filesDir string) *InstallRunner {
+	if command == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultInstallTimeout
+	}
+	if len(manifests) == 0 {
+		manifests = DefaultInstallManifests
+	}
+	return &InstallRunner{command: command, timeout: timeout, manifests: manifests, filesDir: filesDir}
+}
+
+// changedManifests returns the paths, relative to the files directory, whose
+// name matches one of the manifest patterns.
+func (ir *InstallRunner) changedManifests(paths []string) []string {
+	var changed []string
+	for _, p := range paths {
+		name := path.Base(p)
+		for _, pattern := range ir.manifests {
+			if ok, _ := path.Match(pattern, name); ok {
+				changed = append(changed, p)
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// Run runs the command with sh in dir, where the push's files were applied,
+// with the changed manifests in BIFROST_CHANGED_MANIFESTS, one per line. It
+// returns an error when the command exits non-zero, times out or ctx is
+// cancelled.
+func (ir *InstallRunner) Run(ctx context.Context, dir, pushID string, manifests []string) (*pb.HookResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.timeout)
+	defer cancel()
+
+	cmd := execCommand(ctx, "/bin/sh", "-c", ir.command)
+	cmd.Dir = dir
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = append(os.Environ(),
+		"BIFROST_PUSH_ID="+pushID,
+		"BIFROST_FILES_DIR="+ir.filesDir,
+		"BIFROST_CHANGED_MANIFESTS="+strings.Join(manifests, "\n"),
+	)
+
+	log.Info("Installing dependencies",
+		zap.String("pushID", pushID),
+		zap.Strings("manifests", manifests),
+		zap.String("command", ir.command))
+	startTime := time.Now()
+	output, err := cmd.CombinedOutput()
+	duration := time.Since(startTime)
+
+	result := &pb.HookResult{
+		Name:       InstallHook,
+		ExitCode:   int32(cmd.ProcessState.ExitCode()),
+		Output:     truncateOutput(output, maxHookOutputLength),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return result, fmt.Errorf("%w: timed out after %v", errInstallFailed, ir.timeout)
+		case context.Canceled:
+			return result, fmt.Errorf("dependency install cancelled: %w", ctx.Err())
+		}
+		return result, fmt.Errorf("%w: %w. Output: %s", errInstallFailed, err, result.Output)
+	}
+
+	log.Info("Dependencies installed", zap.String("pushID", pushID), zap.Duration("duration", duration))
+	return result, nil
+}
+
+// runInstall runs the install command in dir if any of the written paths is a
+// dependency manifest. It returns a nil result if no command is configured or
+// no manifest changed; the error wraps errInstallFailed unless the push was
+// cancelled.
+func (rw *FileSyncer) runInstall(ctx context.Context, dir, pushID string, written []string, progress *pushProgress, timer *pushTimer) (*pb.HookResult, error) {
+	install := rw.getInstall()
+	if install == nil {
+		return nil, nil
+	}
+	manifests := install.changedManifests(written)
+	if len(manifests) == 0 {
+		return nil, nil
+	}
+	progress.report(pb.PushProgress_INSTALLING, 0, 0, 0)
+	start := time.Now()
+	result, err := install.Run(ctx, dir, pushID, manifests)
+	timer.since(stageInstall, start)
+	return result, err
+}
+
+// getInstall returns the runner for the install command, or nil if none is configured.
+func (rw *FileSyncer) getInstall() *InstallRunner {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.install
+}
@@ -0,0 +1,82 @@
+package syncer
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestInstallRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+
+	runner := NewInstallRunner(`echo "$BIFROST_PUSH_ID $BIFROST_CHANGED_MANIFESTS"`, nil, time.Second, dir)
+	result, err := runner.Run(context.Background(), dir, "push-1", []string{"package.json", "web/requirements.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, InstallHook, result.Name)
+	assert.Equal(t, "push-1 package.json\nweb/requirements.txt\n", result.Output)
+
+	runner = NewInstallRunner("echo 'npm ERR! 404 Not Found' >&2; exit 1", nil, time.Second, dir)
+	result, err = runner.Run(context.Background(), dir, "push-2", []string{"package.json"})
+	assert.ErrorIs(t, err, errInstallFailed)
+	assert.ErrorContains(t, err, "npm ERR! 404")
+	assert.Equal(t, int32(1), result.ExitCode)
+
+	runner = NewInstallRunner("sleep 5", nil, 50*time.Millisecond, dir)
+	_, err = runner.Run(context.Background(), dir, "push-3", []string{"package.json"})
+	assert.ErrorContains(t, err, "dependency install failed: timed out after 50ms")
+
+	assert.Nil(t, NewInstallRunner("", nil, time.Second, dir))
+}
+
+func TestInstallRunner_ChangedManifests(t *testing.T) {
+	runner := NewInstallRunner("npm install", nil, time.Second, "")
+	assert.Equal(t, []string{"package.json", "services/api/go.sum"},
+		runner.changedManifests([]string{"package.json", "src/app.ts", "services/api/go.sum", "package.json.bak"}))
+
+	runner = NewInstallRunner("pip install -r requirements.txt", []string{"requirements*.txt"}, time.Second, "")
+	assert.Equal(t, []string{"requirements-dev.txt"}, runner.changedManifests([]string{"requirements-dev.txt", "package.json"}))
+	assert.Empty(t, runner.changedManifests(nil))
+}
+
+func TestHandlePushRequest_Install(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+	// The install runs with the real shell.
+	execCommand = exec.CommandContext
+	finder := rw.processFinder.(*mockProcessFinder)
+	rw.install = NewInstallRunner(`echo "$BIFROST_CHANGED_MANIFESTS" >> installs.log`, nil, 5*time.Second, rw.targetSyncDir)
+
+	// A push that changes no manifest doesn't install anything.
+	pushMsg := &pb.PushMessage{PushId: "push-1", Files: map[string]*pb.InjectedFile{"src/app.ts": {Content: []byte("let a = 1;\n")}}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	assert.Empty(t, resp.HookResults)
+	assert.NoFileExists(t, filepath.Join(rw.targetSyncDir, "installs.log"))
+
+	pushMsg = &pb.PushMessage{PushId: "push-2", Files: map[string]*pb.InjectedFile{"package.json": {Content: []byte(`{"dependencies": {}}`)}}}
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status, resp.ErrorMessage)
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, InstallHook, resp.HookResults[0].Name)
+	assert.Equal(t, "package.json\n", readFile(t, filepath.Join(rw.targetSyncDir, "installs.log")))
+	assert.Len(t, finder.processes[12345].signalCalls, 2)
+
+	// A failed install stops the push before the app is reloaded.
+	rw.install = NewInstallRunner("echo 'npm ERR! 404 Not Found' >&2; exit 1", nil, 5*time.Second, rw.targetSyncDir)
+	pushMsg = &pb.PushMessage{PushId: "push-3", Files: map[string]*pb.InjectedFile{"package.json": {Content: []byte(`{"dependencies": {"missing": "1.0.0"}}`)}}}
+	assert.ErrorIs(t, rw.handlePushRequest(context.Background(), pushMsg), errInstallFailed)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "Push application failed: dependency install failed: exit status 1. Output: npm ERR! 404")
+	require.Len(t, resp.HookResults, 1)
+	assert.Equal(t, int32(1), resp.HookResults[0].ExitCode)
+	assert.Len(t, finder.processes[12345].signalCalls, 2)
+}
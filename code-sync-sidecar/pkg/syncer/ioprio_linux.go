@@ -0,0 +1,13 @@
+package syncer
+
+import "syscall"
+
+const ioprioWhoPgrp = 2
+
+// setGroupIOPriority sets the I/O priority of the process group pgid with ioprio_set(2).
+func setGroupIOPriority(pgid, ioprio int) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pgid), uintptr(ioprio)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package syncer
+
+import "errors"
+
+// setGroupIOPriority fails: I/O scheduling classes are Linux-only.
+func setGroupIOPriority(int, int) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// launcherWatchInterval is how often the launcher PID is probed.
+const launcherWatchInterval = 2 * time.Second
+
+// writeReloadHandshake writes the handshake for a reload of the app in the
+// sync dir, listing the env files currently in place, to be followed by the
+// reload, and returns it.
+func (rw *FileSyncer) writeReloadHandshake(pushID string, reason launcher.ReloadReason, updates []*pb.DatabaseBranchUpdate, snapshot string) (launcher.Handshake, error) {
+	envFiles, err := envfile.List(rw.targetSyncDir)
+	if err != nil {
+		return launcher.Handshake{}, err
+	}
+	hs := launcher.NewHandshake(pushID, reason, envFiles, updates)
+	hs.Snapshot = snapshot
+	return hs, launcher.WriteHandshake(rw.targetSyncDir, hs)
+}
+
+// StopLauncher forwards signals.shutdown to the launcher and waits up to
+// timeouts.shutdown for it to exit, so the app shuts down gracefully before the
+// sidecar does. It does nothing unless signals.shutdown is set.
+func (rw *FileSyncer) StopLauncher() {
+	rw.settingsMu.RLock()
+	cfg := rw.config
+	rw.settingsMu.RUnlock()
+	if cfg == nil {
+		return
+	}
+	sig, ok := cfg.ShutdownSignal()
+	if !ok {
+		return
+	}
+
+	timeout := time.Duration(cfg.Timeouts.Shutdown)
+	log.Info("Forwarding shutdown signal to the launcher",
+		zap.String("signal", sig.String()),
+		zap.Duration("timeout", timeout))
+	if err := launcher.Stop(rw.targetSyncDir, rw.processFinder, sig, cfg.SignalTarget(), timeout); err != nil {
+		log.Warn("Launcher did not shut down cleanly", zap.Error(err))
+	}
+}
+
+// runLauncherWatcher reports the launcher exiting with an unsolicited
+// LAUNCHER_EXITED message, instead of leaving it to be discovered when the next
+// push fails to signal it. An event that can't be sent while disconnected is
+// retried on every check until it is.
+func (rw *FileSyncer) runLauncherWatcher(ctx context.Context) {
+	watcher := launcher.NewWatcher(rw.targetSyncDir, rw.processFinder)
+	var pending *pb.WebsocketMessage
+
+	ticker := time.NewTicker(launcherWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rw.done:
+			return
+		case now := <-ticker.C:
+			if event := watcher.Check(now); event != nil {
+				rw.stateMu.Lock()
+				event.LastPushId = rw.applied.LastPushID
+				rw.stateMu.Unlock()
+				log.Error("Launcher exited",
+					zap.Int32("pid", event.Pid),
+					zap.String("reason", event.Reason),
+					zap.String("appStatus", event.AppStatus))
+				rw.emitLauncherWebhook(event)
+				pending = &pb.WebsocketMessage{
+					MessageType: pb.WebsocketMessage_LAUNCHER_EXITED,
+					Message:     &pb.WebsocketMessage_LauncherExited{LauncherExited: event},
+				}
+			}
+			if pending != nil && rw.trySendProtoMessage(pending) == nil {
+				pending = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package syncer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	logPollInterval   = 500 * time.Millisecond
+	logFlushInterval  = time.Second
+	logSendRetryDelay = time.Second
+	logBatchSize      = 100
+	logBufferSize     = 1000
+	logMaxLineLength  = 16 * 1024
+	logMaxReadPerFile = 1024 * 1024
+	logFIFOSourceName = "fifo"
+)
+
+// LogForwarder tails application log files (or a FIFO the launcher writes to)
+// and streams their lines upstream as batched LOG_ENTRY messages.
+//
+// Lines flow through a bounded buffer. When the websocket is down or slow the
+// buffer fills and the tailers stop reading, so file data stays on disk and FIFO
+// writers block instead of lines being dropped.
+type LogForwarder struct {
+	path string
+	send func(*pb.WebsocketMessage) error
+
+	entries chan *pb.LogEntry
+
+	// offsets tracks how far each file has been forwarded, keyed by path.
+	offsets map[string]int64
+	files   map[string]os.FileInfo
+}
+
+// NewLogForwarder creates a forwarder for a log directory or FIFO. send is used
+// to deliver batches and should return an error when the batch was not sent.
+func NewLogForwarder(path string, send func(*pb.WebsocketMessage) error) *LogForwarder {
+	return &LogForwarder{
+		path:    path,
+		send:    send,
+		entries: make(chan *pb.LogEntry, logBufferSize),
+		offsets: make(map[string]int64),
+		files:   make(map[string]os.FileInfo),
+	}
+}
+
+// Run forwards log lines until ctx is cancelled.
+func (lf *LogForwarder) Run(ctx context.Context) {
+	log.Info("Starting application log forwarder", zap.String("path", lf.path))
+	go lf.sendLoop(ctx)
+
+	info, err := os.Stat(lf.path)
+	if err == nil && info.Mode()&fs.ModeNamedPipe != 0 {
+		lf.readFIFO(ctx)
+		return
+	}
+	lf.tailDir(ctx)
+}
+
+// tailDir polls the log directory for new data. Files that exist at startup are
+// forwarded from their current end; files created later are forwarded from the start.
+func (lf *LogForwarder) tailDir(ctx context.Context) {
+	lf.scan(ctx, true)
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lf.scan(ctx, false)
+		}
+	}
+}
+
+func (lf *LogForwarder) scan(ctx context.Context, initial bool) {
+	err := filepath.WalkDir(lf.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Files can disappear mid-walk during rotation
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		prev, seen := lf.files[path]
+		lf.files[path] = info
+		switch {
+		case !seen && initial:
+			lf.offsets[path] = info.Size()
+			return nil
+		case !seen, !os.SameFile(prev, info), info.Size() < lf.offsets[path]:
+			// New, replaced or truncated file: start from the beginning.
+			lf.offsets[path] = 0
+		}
+
+		if info.Size() > lf.offsets[path] {
+			lf.readFile(ctx, path)
+		}
+		return ctx.Err()
+	})
+	if err != nil && ctx.Err() == nil && !os.IsNotExist(err) {
+		log.Warn("Failed to scan application log dir", zap.String("path", lf.path), zap.Error(err))
+	}
+}
+
+// readFile forwards complete lines appended to path since the last read. A
+// trailing partial line is left for the next poll.
+func (lf *LogForwarder) readFile(ctx context.Context, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	offset := lf.offsets[path]
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	source, err := filepath.Rel(lf.path, path)
+	if err != nil {
+		source = path
+	}
+
+	reader := bufio.NewReader(io.LimitReader(f, logMaxReadPerFile))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// Incomplete line (or end of this read window); pick it up next time unless it is already too long.
+			if len(line) >= logMaxLineLength {
+				if !lf.enqueue(ctx, source, line) {
+					return
+				}
+				offset += int64(len(line))
+			}
+			break
+		}
+		offset += int64(len(line))
+		if !lf.enqueue(ctx, source, line[:len(line)-1]) {
+			break
+		}
+	}
+	lf.offsets[path] = offset
+}
+
+// readFIFO forwards lines from a named pipe. The pipe is opened read-write so
+// that writers closing their end does not produce EOF.
+func (lf *LogForwarder) readFIFO(ctx context.Context) {
+	f, err := os.OpenFile(lf.path, os.O_RDWR, 0)
+	if err != nil {
+		log.Error("Failed to open application log FIFO", zap.String("path", lf.path), zap.Error(err))
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	reader := bufio.NewReaderSize(f, logMaxLineLength)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] == '\n' {
+				line = line[:len(line)-1]
+			}
+			if !lf.enqueue(ctx, logFIFOSourceName, append([]byte(nil), line...)) {
+				return
+			}
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			if ctx.Err() == nil {
+				log.Warn("Application log FIFO read failed", zap.String("path", lf.path), zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// enqueue adds a line to the send buffer, blocking while it is full. It
+// returns false if ctx was cancelled first.
+func (lf *LogForwarder) enqueue(ctx context.Context, source string, line []byte) bool {
+	truncated := false
+	if len(line) > logMaxLineLength {
+		line = line[:logMaxLineLength]
+		truncated = true
+	}
+	entry := &pb.LogEntry{
+		Timestamp: timestamppb.Now(),
+		Source:    source,
+		Line:      string(line),
+		Truncated: truncated,
+	}
+	select {
+	case lf.entries <- entry:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendLoop groups buffered entries into batches and sends them, retrying a
+// batch until it is delivered.
+func (lf *LogForwarder) sendLoop(ctx context.Context) {
+	sendLogBatches(ctx, lf.entries, nil, func(entries []*pb.LogEntry) error {
+		return lf.send(buildLogBatchMessage(pb.WebsocketMessage_LOG_ENTRY, entries))
+	})
+}
+
+// sendLogBatches reads entries into batches of up to logBatchSize, flushed at
+// least every logFlushInterval, and retries each batch until send succeeds.
+// pending, if set, is called before each flush and may append entries.
+func sendLogBatches(ctx context.Context, entries <-chan *pb.LogEntry, pending func([]*pb.LogEntry) []*pb.LogEntry, send func([]*pb.LogEntry) error) {
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*pb.LogEntry
+	flush := func() {
+		if pending != nil {
+			batch = pending(batch)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		for {
+			if err := send(batch); err == nil {
+				batch = nil
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(logSendRetryDelay):
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			batch = append(batch, entry)
+			if len(batch) >= logBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func buildLogBatchMessage(messageType pb.WebsocketMessage_MessageType, entries []*pb.LogEntry) *pb.WebsocketMessage {
+	return &pb.WebsocketMessage{
+		MessageType: messageType,
+		Message: &pb.WebsocketMessage_LogBatch{
+			LogBatch: &pb.LogBatch{Entries: entries},
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func collectLogLines(t *testing.T, batches <-chan *pb.LogBatch, want int) []*pb.LogEntry {
+	t.Helper()
+	var entries []*pb.LogEntry
+	deadline := time.After(5 * time.Second)
+	for len(entries) < want {
+		select {
+		case batch := <-batches:
+			entries = append(entries, batch.GetEntries()...)
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d log lines, got %d", want, len(entries))
+		}
+	}
+	return entries
+}
+
+func TestLogForwarder_TailsDirectory(t *testing.T) {
+	logDir := t.TempDir()
+	existing := filepath.Join(logDir, "app.log")
+	require.NoError(t, os.WriteFile(existing, []byte("old line\n"), 0644))
+
+	// Fail the first send to exercise the retry path.
+	var sends atomic.Int32
+	batches := make(chan *pb.LogBatch, 10)
+	lf := NewLogForwarder(logDir, func(msg *pb.WebsocketMessage) error {
+		if sends.Add(1) == 1 {
+			return errors.New("not connected")
+		}
+		assert.Equal(t, pb.WebsocketMessage_LOG_ENTRY, msg.GetMessageType())
+		batches <- msg.GetLogBatch()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go lf.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	f, err := os.OpenFile(existing, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("first\nsecond\npartial")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(logDir, "worker"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(logDir, "worker", "out.log"), []byte("worker started\n"), 0644))
+
+	entries := collectLogLines(t, batches, 3)
+	lines := map[string]string{}
+	for _, entry := range entries {
+		lines[entry.GetLine()] = entry.GetSource()
+	}
+	assert.Equal(t, map[string]string{
+		"first":          "app.log",
+		"second":         "app.log",
+		"worker started": filepath.Join("worker", "out.log"),
+	}, lines)
+	assert.GreaterOrEqual(t, sends.Load(), int32(2))
+}
+
+func TestLogForwarder_TruncatedFileRestarts(t *testing.T) {
+	logDir := t.TempDir()
+	logFile := filepath.Join(logDir, "app.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("a long line that was already there\n"), 0644))
+
+	batches := make(chan *pb.LogBatch, 10)
+	lf := NewLogForwarder(logDir, func(msg *pb.WebsocketMessage) error {
+		batches <- msg.GetLogBatch()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go lf.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(logFile, []byte("rotated\n"), 0644))
+
+	entries := collectLogLines(t, batches, 1)
+	assert.Equal(t, "rotated", entries[0].GetLine())
+}
@@ -0,0 +1,356 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	maxLogTails            = 4
+	defaultLogTailLines    = 100
+	maxLogTailLines        = 1000
+	defaultLogTailDuration = 10 * time.Minute
+	maxLogTailDuration     = time.Hour
+	// logTailLinesPerSecond is how many lines a tail sends per second; lines
+	// beyond it are dropped and counted, so a noisy app can't flood the connection.
+	logTailLinesPerSecond = 200
+)
+
+var (
+	errLogTailStopped      = errors.New("log tail stopped")
+	errLogTailExpired      = errors.New("log tail expired")
+	errLogTailDisconnected = errors.New("connection closed")
+)
+
+// LogTailManager runs the log tails the control plane opens with
+// LOG_TAIL_REQUEST, each following one file in the app log directory.
+type LogTailManager struct {
+	dir  string
+	send func(*pb.WebsocketMessage) error
+
+	mu    sync.Mutex
+	tails map[string]context.CancelCauseFunc
+}
+
+// NewLogTailManager creates a LogTailManager for files under dir. An empty dir
+// rejects every tail.
+func NewLogTailManager(dir string, send func(*pb.WebsocketMessage) error) *LogTailManager {
+	return &LogTailManager{
+		dir:   dir,
+		send:  send,
+		tails: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Start opens the requested file and follows it until the tail is stopped or expires.
+func (tm *LogTailManager) Start(req *pb.LogTailRequest) error {
+	if req == nil || req.TailId == "" {
+		return fmt.Errorf("received LOG_TAIL_REQUEST without a tail id")
+	}
+	path, err := tm.resolve(req.Path)
+	if err != nil {
+		tm.sendEnd(req.TailId, pb.LogTailEnd_FAILED, err.Error())
+		return fmt.Errorf("rejecting log tail %s: %w", req.TailId, err)
+	}
+
+	duration := defaultLogTailDuration
+	if req.DurationSeconds > 0 {
+		duration = min(time.Duration(req.DurationSeconds)*time.Second, maxLogTailDuration)
+	}
+	lines := defaultLogTailLines
+	if req.Lines > 0 {
+		lines = min(int(req.Lines), maxLogTailLines)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if _, exists := tm.tails[req.TailId]; exists {
+		return fmt.Errorf("log tail %s is already running", req.TailId)
+	}
+	if len(tm.tails) >= maxLogTails {
+		tm.sendEnd(req.TailId, pb.LogTailEnd_FAILED, fmt.Sprintf("too many log tails (max %d)", maxLogTails))
+		return fmt.Errorf("rejecting log tail %s: too many tails", req.TailId)
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	tm.tails[req.TailId] = cancel
+
+	log.Info("Starting log tail", zap.String("tailID", req.TailId), zap.String("path", path), zap.Duration("duration", duration))
+	go func() {
+		ctx, cancelTimeout := context.WithTimeoutCause(ctx, duration, errLogTailExpired)
+		defer cancelTimeout()
+		tm.run(ctx, req.TailId, path, lines)
+	}()
+	return nil
+}
+
+// Stop ends a tail; its LOG_TAIL_END is sent once it has stopped.
+func (tm *LogTailManager) Stop(req *pb.LogTailStop) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	cancel, ok := tm.tails[req.GetTailId()]
+	if !ok {
+		return fmt.Errorf("unknown log tail %q", req.GetTailId())
+	}
+	cancel(errLogTailStopped)
+	return nil
+}
+
+// StopAll ends every tail without reporting it, e.g. when the websocket
+// connection drops and nobody is left to read them.
+func (tm *LogTailManager) StopAll() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for _, cancel := range tm.tails {
+		cancel(errLogTailDisconnected)
+	}
+}
+
+// resolve checks rel names a file in the log directory, following symlinks.
+func (tm *LogTailManager) resolve(rel string) (string, error) {
+	if tm.dir == "" {
+		return "", fmt.Errorf("no app log directory is configured (sync.app_log_dir)")
+	}
+	if rel == "" || filepath.IsAbs(rel) || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q is outside the app log directory", rel)
+	}
+	realDir, err := filepath.EvalSymlinks(tm.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", tm.dir, err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(tm.dir, rel))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s does not exist", rel)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rel, err)
+	}
+	if !strings.HasPrefix(path, realDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q leads outside the app log directory through a symlink", rel)
+	}
+	return path, nil
+}
+
+func (tm *LogTailManager) run(ctx context.Context, tailID, path string, lines int) {
+	t := &logTail{
+		id:      tailID,
+		path:    path,
+		send:    tm.send,
+		limiter: lineRateLimiter{perSecond: logTailLinesPerSecond},
+	}
+	err := t.follow(ctx, lines)
+	t.close()
+
+	tm.mu.Lock()
+	delete(tm.tails, tailID)
+	tm.mu.Unlock()
+
+	cause := context.Cause(ctx)
+	switch {
+	case err != nil:
+		log.Warn("Log tail failed", zap.String("tailID", tailID), zap.Error(err))
+		tm.sendEnd(tailID, pb.LogTailEnd_FAILED, err.Error())
+	case errors.Is(cause, errLogTailDisconnected):
+		log.Info("Log tail stopped, connection closed", zap.String("tailID", tailID))
+	case errors.Is(cause, errLogTailExpired):
+		log.Info("Log tail expired", zap.String("tailID", tailID))
+		tm.sendEnd(tailID, pb.LogTailEnd_EXPIRED, "")
+	default:
+		log.Info("Log tail stopped", zap.String("tailID", tailID))
+		tm.sendEnd(tailID, pb.LogTailEnd_STOPPED, "")
+	}
+}
+
+func (tm *LogTailManager) sendEnd(tailID string, reason pb.LogTailEnd_Reason, errorMessage string) {
+	msg := &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_LOG_TAIL_END,
+		Message: &pb.WebsocketMessage_LogTailEnd{
+			LogTailEnd: &pb.LogTailEnd{TailId: tailID, Reason: reason, ErrorMessage: errorMessage},
+		},
+	}
+	if err := tm.send(msg); err != nil {
+		log.Warn("Failed to send log tail end", zap.String("tailID", tailID), zap.Error(err))
+	}
+}
+
+// logTail follows one file, reopening it when it is rotated or truncated.
+type logTail struct {
+	id      string
+	path    string
+	send    func(*pb.WebsocketMessage) error
+	limiter lineRateLimiter
+
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	dropped int64
+}
+
+// follow sends the last lines of the file, then whatever is appended to it,
+// until ctx ends.
+func (t *logTail) follow(ctx context.Context, lines int) error {
+	if err := t.open(); err != nil {
+		return err
+	}
+	last, err := lastLines(t.file, t.info.Size(), lines)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	t.offset = t.info.Size()
+	t.sendLines(last, time.Now())
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := t.poll(now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *logTail) open() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", t.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", t.path, err)
+	}
+	if !info.Mode().IsRegular() {
+		f.Close()
+		return fmt.Errorf("%s is not a regular file", t.path)
+	}
+	t.close()
+	t.file, t.info, t.offset, t.partial = f, info, 0, nil
+	return nil
+}
+
+func (t *logTail) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// poll sends the complete lines appended since the last poll. A file replaced
+// by rotation or truncated is read again from the start.
+func (t *logTail) poll(now time.Time) error {
+	if info, err := os.Stat(t.path); err == nil && !os.SameFile(info, t.info) {
+		// Finish the rotated file first: the app may have written to it since the last poll.
+		if err := t.readAppended(now); err != nil {
+			return err
+		}
+		if err := t.open(); err != nil {
+			return err
+		}
+	} else if info, err := t.file.Stat(); err == nil && info.Size() < t.offset {
+		t.offset, t.partial = 0, nil
+	}
+	return t.readAppended(now)
+}
+
+func (t *logTail) readAppended(now time.Time) error {
+	data, err := io.ReadAll(io.NewSectionReader(t.file, t.offset, logMaxReadPerFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	t.offset += int64(len(data))
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 && len(data) < logMaxLineLength {
+		t.partial = data
+		return nil
+	}
+	t.partial = nil
+	if end < 0 {
+		end = len(data) - 1 // Send an overlong line without waiting for its end
+	} else if end+1 < len(data) {
+		t.partial = append([]byte(nil), data[end+1:]...)
+	}
+	t.sendLines(strings.Split(strings.TrimSuffix(string(data[:end+1]), "\n"), "\n"), now)
+	return nil
+}
+
+func (t *logTail) sendLines(lines []string, now time.Time) {
+	allowed := t.limiter.allow(len(lines), now)
+	t.dropped += int64(len(lines) - allowed)
+	if allowed == 0 {
+		return
+	}
+	// Keep the newest lines; the dropped ones are only counted.
+	lines = lines[len(lines)-allowed:]
+	msg := &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_LOG_TAIL_DATA,
+		Message: &pb.WebsocketMessage_LogTailData{
+			LogTailData: &pb.LogTailData{TailId: t.id, Lines: lines, DroppedLines: t.dropped},
+		},
+	}
+	if err := t.send(msg); err != nil {
+		log.Warn("Failed to send log tail lines", zap.String("tailID", t.id), zap.Error(err))
+		return
+	}
+	t.dropped = 0
+}
+
+// lastLines returns up to n complete lines from the end of f, reading at most
+// logMaxReadPerFile bytes.
+func lastLines(f *os.File, size int64, n int) ([]string, error) {
+	start := max(size-logMaxReadPerFile, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, start, size-start))
+	if err != nil {
+		return nil, err
+	}
+	if start > 0 {
+		// Skip the line cut off by where the read started.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	return lines[max(len(lines)-n, 0):], nil
+}
+
+// lineRateLimiter allows up to perSecond lines in each one-second window.
+type lineRateLimiter struct {
+	perSecond   int
+	windowStart time.Time
+	sent        int
+}
+
+// allow returns how many of n lines can be sent at now.
+func (l *lineRateLimiter) allow(n int, now time.Time) int {
+	if now.Sub(l.windowStart) >= time.Second {
+		l.windowStart, l.sent = now, 0
+	}
+	allowed := min(n, l.perSecond-l.sent)
+	l.sent += allowed
+	return allowed
+}
@@ -0,0 +1,127 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func newTestLogTailManager(t *testing.T, dir string) (*LogTailManager, chan *pb.WebsocketMessage) {
+	t.Helper()
+	sent := make(chan *pb.WebsocketMessage, 100)
+	tm := NewLogTailManager(dir, func(msg *pb.WebsocketMessage) error {
+		sent <- msg
+		return nil
+	})
+	t.Cleanup(tm.StopAll)
+	return tm, sent
+}
+
+func nextLogTailMessage(t *testing.T, sent chan *pb.WebsocketMessage) *pb.WebsocketMessage {
+	t.Helper()
+	select {
+	case msg := <-sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for log tail message")
+		return nil
+	}
+}
+
+func TestLogTailManager_TailAndFollow(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644))
+	tm, sent := newTestLogTailManager(t, dir)
+
+	require.NoError(t, tm.Start(&pb.LogTailRequest{TailId: "tail-1", Path: "app.log", Lines: 2}))
+	assert.Equal(t, []string{"two", "three"}, nextLogTailMessage(t, sent).GetLogTailData().GetLines())
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("four\nfi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"four"}, nextLogTailMessage(t, sent).GetLogTailData().GetLines(), "partial lines wait for their end")
+	_, err = f.WriteString("ve\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{"five"}, nextLogTailMessage(t, sent).GetLogTailData().GetLines())
+
+	// A rotated file is followed from its start.
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("six\n"), 0644))
+	assert.Equal(t, []string{"six"}, nextLogTailMessage(t, sent).GetLogTailData().GetLines())
+
+	require.NoError(t, tm.Stop(&pb.LogTailStop{TailId: "tail-1"}))
+	end := nextLogTailMessage(t, sent).GetLogTailEnd()
+	assert.Equal(t, "tail-1", end.GetTailId())
+	assert.Equal(t, pb.LogTailEnd_STOPPED, end.GetReason())
+	assert.Eventually(t, func() bool {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		return len(tm.tails) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogTailManager_Expires(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), nil, 0644))
+	tm, sent := newTestLogTailManager(t, dir)
+
+	require.NoError(t, tm.Start(&pb.LogTailRequest{TailId: "tail-1", Path: "app.log", DurationSeconds: 1}))
+	end := nextLogTailMessage(t, sent).GetLogTailEnd()
+	assert.Equal(t, pb.LogTailEnd_EXPIRED, end.GetReason(), "an empty file sends no lines")
+}
+
+func TestLogTailManager_Rejects(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.log"), nil, 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.log"), filepath.Join(dir, "escape.log")))
+	tm, sent := newTestLogTailManager(t, dir)
+
+	for path, wantErr := range map[string]string{
+		"../secret.log": "outside the app log directory",
+		"escape.log":    "leads outside the app log directory through a symlink",
+		"missing.log":   "missing.log does not exist",
+	} {
+		assert.ErrorContains(t, tm.Start(&pb.LogTailRequest{TailId: path, Path: path}), wantErr)
+		end := nextLogTailMessage(t, sent).GetLogTailEnd()
+		assert.Equal(t, pb.LogTailEnd_FAILED, end.GetReason())
+		assert.Contains(t, end.GetErrorMessage(), wantErr)
+	}
+
+	tm, sent = newTestLogTailManager(t, "")
+	assert.Error(t, tm.Start(&pb.LogTailRequest{TailId: "tail-1", Path: "app.log"}))
+	assert.Contains(t, nextLogTailMessage(t, sent).GetLogTailEnd().GetErrorMessage(), "no app log directory is configured")
+}
+
+func TestLastLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", logMaxReadPerFile)+"\nlast\n"), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	lines, err := lastLines(f, info.Size(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"last"}, lines, "the line cut off by the read limit is skipped")
+}
+
+func TestLineRateLimiter(t *testing.T) {
+	limiter := lineRateLimiter{perSecond: 10}
+	now := time.Now()
+	assert.Equal(t, 6, limiter.allow(6, now))
+	assert.Equal(t, 4, limiter.allow(6, now.Add(500*time.Millisecond)))
+	assert.Equal(t, 0, limiter.allow(1, now.Add(900*time.Millisecond)))
+	assert.Equal(t, 3, limiter.allow(3, now.Add(time.Second)), "a new window starts every second")
+}
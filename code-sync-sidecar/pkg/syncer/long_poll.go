@@ -0,0 +1,93 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+// Some networks block websocket upgrades outright. After pollFallbackAfter
+// consecutive dials fail that way, the sidecar long-polls the proxy over plain
+// HTTP instead, and tries the websocket again every pollRetryWebSocket.
+const (
+	pollFallbackAfter  = 3
+	pollRetryWebSocket = 5 * time.Minute
+)
+
+// buildPendingURL constructs the long-poll URL for this sidecar.
+func (rw *FileSyncer) buildPendingURL() string {
+	pendingURL, err := transport.PendingURL(rw.apiURL, rw.appID, rw.deploymentID)
+	if err != nil {
+		log.Fatal("Invalid BIFROST_API_URL provided",
+			zap.String("apiURL", rw.apiURL),
+			zap.Error(err),
+		)
+	}
+	return pendingURL
+}
+
+// runLongPoll exchanges messages over HTTP until pollRetryWebSocket has passed,
+// a poll fails, or the sidecar stops. Messages the sidecar sends meanwhile are
+// posted by trySendProtoMessage.
+func (rw *FileSyncer) runLongPoll(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-rw.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	poller := transport.NewLongPoller(rw.buildPendingURL(), rw.tokens)
+	rw.writeMu.Lock()
+	rw.poller = poller
+	rw.writeMu.Unlock()
+	defer func() {
+		rw.writeMu.Lock()
+		rw.poller = nil
+		rw.writeMu.Unlock()
+		rw.notifyStatusChanged()
+	}()
+	if rw.shells != nil {
+		defer rw.shells.CloseAll()
+	}
+	if rw.tails != nil {
+		defer rw.tails.StopAll()
+	}
+	defer rw.dropBatchStreams()
+
+	// The proxy registers the sidecar on its first request, so HELLO also opens the session.
+	if err := rw.trySendProtoMessage(rw.buildHello()); err != nil {
+		return err
+	}
+	rw.recordConnected()
+	log.Info("Connected to Code Sync proxy by HTTP long-polling", zap.String("url", poller.URL()))
+	rw.flushOutbox()
+	go rw.sendResyncRequest()
+	rw.sendPeriodicStatusReports(ctx)
+
+	deadline := time.Now().Add(pollRetryWebSocket)
+	for time.Now().Before(deadline) {
+		messages, err := poller.Poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("long-polling stopped: %w", ctx.Err())
+			}
+			return err
+		}
+		for _, msg := range messages {
+			rw.messagesReceived.Add(1)
+			if err := rw.handleProtoMessage(msg, 0); err != nil {
+				log.Error("Error handling message", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/transport"
+)
+
+func TestFileSyncer_FallsBackToLongPolling(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		posted   []pb.WebsocketMessage_MessageType
+		polls    atomic.Int32
+		wsDials  atomic.Int32
+		authSeen atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v1/push/sidecar/"):
+			wsDials.Add(1)
+			http.Error(w, "websockets are not allowed", http.StatusForbidden)
+		case r.URL.Path == "/api/v1/push/pending/app1/deployment1" && r.Method == http.MethodPost:
+			authSeen.Store(r.Header.Get("X-Api-Key") == "test-key")
+			data, _ := io.ReadAll(r.Body)
+			var msg pb.WebsocketMessage
+			assert.NoError(t, proto.Unmarshal(data, &msg))
+			mu.Lock()
+			posted = append(posted, msg.MessageType)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/api/v1/push/pending/app1/deployment1":
+			assert.Equal(t, "25", r.URL.Query().Get("wait"))
+			batch := &pb.MessageBatch{}
+			if polls.Add(1) == 1 {
+				batch.Messages = append(batch.Messages, &pb.WebsocketMessage{
+					MessageType: pb.WebsocketMessage_HELLO_ACK,
+					Message:     &pb.WebsocketMessage_HelloAck{HelloAck: &pb.HelloAck{ProtocolVersion: 1}},
+				})
+			} else {
+				time.Sleep(20 * time.Millisecond)
+			}
+			data, _ := proto.Marshal(batch)
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tokens, err := transport.NewTokenManager(transport.AuthModeAPIKey, server.URL, "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+	cfg := DefaultConfig()
+	cfg.API.URL = server.URL
+	cfg.AppID = "app1"
+	cfg.DeploymentID = "deployment1"
+	cfg.Sync.FilesDir = t.TempDir()
+	cfg.Timeouts.StatusInterval = 0
+	cfg.Timeouts.ReconnectBackoff = Duration(time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rw, err := NewFileSyncer(ctx, cfg, tokens)
+	require.NoError(t, err)
+	defer rw.Stop()
+
+	require.Eventually(t, func() bool {
+		rw.stateMu.Lock()
+		defer rw.stateMu.Unlock()
+		return rw.serverHello != nil
+	}, 5*time.Second, 10*time.Millisecond, "HELLO_ACK should arrive by long-poll")
+
+	assert.Equal(t, int32(pollFallbackAfter), wsDials.Load())
+	mu.Lock()
+	assert.Equal(t, pb.WebsocketMessage_HELLO, posted[0])
+	mu.Unlock()
+	assert.True(t, authSeen.Load())
+	require.NoError(t, rw.trySendProtoMessage(buildPushResponse("push-1", pb.PushResponse_COMPLETED, "")))
+}
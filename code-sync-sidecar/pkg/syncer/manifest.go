@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const manifestFileName = "manifest.json"
+
+// fileManifest records the state of every file written by a push, keyed by
+// path relative to the files directory, so later pushes can tell whether a file
+// was modified in the deployment since it was synced.
+type fileManifest map[string]manifestEntry
+
+type manifestEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime_ns"`
+	SHA256  string `json:"sha256"`
+}
+
+func getManifestPath(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), manifestFileName)
+}
+
+// loadManifest reads the manifest. A missing file returns an empty manifest.
+func loadManifest(filesDir string) (fileManifest, error) {
+	manifest := fileManifest{}
+	path := getManifestPath(filesDir)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// saveManifest writes the manifest atomically.
+func saveManifest(filesDir string, manifest fileManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	path := getManifestPath(filesDir)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// record updates the entries for paths from their current state in targetDir.
+// Paths that no longer exist are dropped.
+func (m fileManifest) record(targetDir string, paths []string) error {
+	for _, rel := range paths {
+		entry, err := newManifestEntry(filepath.Join(targetDir, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			delete(m, rel)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m[rel] = entry
+	}
+	return nil
+}
+
+// findConflicts returns the paths, sorted, whose content differs from the
+// manifest. Paths the manifest doesn't know about never conflict.
+func (m fileManifest) findConflicts(targetDir string, paths []string) ([]string, error) {
+	var conflicts []string
+	for _, rel := range paths {
+		recorded, ok := m[rel]
+		if !ok {
+			continue
+		}
+		path := filepath.Join(targetDir, rel)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			conflicts = append(conflicts, rel) // Deleted in the deployment
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		// Unchanged size and mtime means unchanged content; only hash when they differ.
+		if info.Size() == recorded.Size && info.ModTime().UnixNano() == recorded.ModTime {
+			continue
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if hash != recorded.SHA256 {
+			conflicts = append(conflicts, rel)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+func newManifestEntry(path string) (manifestEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	return manifestEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		SHA256:  hash,
+	}, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,99 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestManifest_FindConflicts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.py"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.py"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.py"), []byte("c"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+
+	manifest := fileManifest{}
+	require.NoError(t, manifest.record(dir, []string{"a.py", "b.py", "c.py", "missing.py"}))
+	assert.Len(t, manifest, 3)
+	require.NoError(t, saveManifest(dir, manifest))
+
+	loaded, err := loadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, loaded)
+
+	// Touching a file without changing its content is not a conflict.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.py"), later, later))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.py"), []byte("edited"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "c.py")))
+
+	conflicts, err := loaded.findConflicts(dir, []string{"c.py", "b.py", "a.py", "new.py"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.py", "c.py"}, conflicts)
+}
+
+func TestLoadManifest_Missing(t *testing.T) {
+	manifest, err := loadManifest(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, manifest)
+}
+
+func TestHandlePushRequest_Conflict(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f.st...... app.py;>f+++++++++ new.py")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("synced"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	manifest := fileManifest{}
+	require.NoError(t, manifest.record(dir, []string{"app.py"}))
+	require.NoError(t, saveManifest(dir, manifest))
+
+	// Edit the file in the deployment after it was synced.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("hotfix"), 0644))
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	require.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_CONFLICT, resp.GetStatus())
+	assert.Equal(t, []string{"app.py"}, resp.GetConflictingFiles())
+	assert.Contains(t, resp.GetErrorMessage(), "app.py")
+
+	// A forced push overwrites the local change and records the new content.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch"), Force: true}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+
+	loaded, err := loadManifest(dir)
+	require.NoError(t, err)
+	hotfix, err := hashFile(filepath.Join(dir, "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, hotfix, loaded["app.py"].SHA256, "mock rsync leaves the file as is")
+	assert.NotContains(t, loaded, "new.py", "files that don't exist are not recorded")
+}
+
+func TestSummarizePaths(t *testing.T) {
+	assert.Equal(t, "a, b", summarizePaths([]string{"a", "b"}, 2))
+	assert.Equal(t, "a, b and 2 more", summarizePaths([]string{"a", "b", "c", "d"}, 2))
+}
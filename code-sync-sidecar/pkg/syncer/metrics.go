@@ -0,0 +1,209 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/metrics"
+)
+
+// Sinks for metrics.sink.
+const (
+	// MetricsSinkNone sends no metrics.
+	MetricsSinkNone = ""
+	// MetricsSinkStatsD sends metrics to a StatsD server, without tags.
+	MetricsSinkStatsD = "statsd"
+	// MetricsSinkDogStatsD sends metrics with tags to the Datadog agent.
+	MetricsSinkDogStatsD = "dogstatsd"
+)
+
+const (
+	DefaultMetricsPrefix   = "bifrost.sidecar."
+	DefaultMetricsInterval = 10 * time.Second
+)
+
+// The metrics the sidecar sends, under the configured prefix.
+const (
+	// metricPushes counts pushes by their final status.
+	metricPushes = "push.count"
+	// metricPushDuration times pushes from starting to apply them until their
+	// final response, and metricPushStageDuration each stage, tagged stage.
+	metricPushDuration      = "push.duration"
+	metricPushStageDuration = "push.stage.duration"
+	// metricPushFiles counts the files pushes changed, tagged change.
+	metricPushFiles = "push.files"
+
+	// metricRsyncRuns counts rsync runs by result, which metricRsyncDuration
+	// times; metricRsyncRetries counts the runs retried after transient failures.
+	metricRsyncRuns     = "rsync.runs"
+	metricRsyncDuration = "rsync.duration"
+	metricRsyncRetries  = "rsync.retries"
+
+	metricConnected          = "connection.connected"
+	metricReconnects         = "connection.reconnects"
+	metricReconnectsLastHour = "connection.reconnects_last_hour"
+	metricRTT                = "connection.rtt_ms"
+	metricRTTVariance        = "connection.rtt_variance_ms"
+	metricMessageLatency     = "connection.message_latency_ms"
+	metricThroughput         = "connection.throughput_bytes_per_second"
+	metricDegraded           = "connection.degraded"
+	metricMessagesSent       = "connection.messages_sent"
+	metricMessagesReceived   = "connection.messages_received"
+)
+
+// NewMetricsSink creates the sink cfg configures, tagging its metrics with
+// the deployment and pod, or metrics.Nop if none is.
+func NewMetricsSink(cfg MetricsConfig, appID, deploymentID string) (metrics.Sink, error) {
+	if cfg.Sink == MetricsSinkNone {
+		return metrics.Nop{}, nil
+	}
+	tags := []string{metrics.Tag("app_id", appID), metrics.Tag("deployment_id", deploymentID)}
+	if pod := Pod.GetPodName(); pod != "" {
+		tags = append(tags, metrics.Tag("pod", pod))
+	}
+	return metrics.NewStatsD(metrics.StatsDOptions{
+		Address:   cfg.Address,
+		Prefix:    cfg.Prefix,
+		Tags:      append(tags, cfg.Tags...),
+		DogStatsD: cfg.Sink == MetricsSinkDogStatsD,
+	})
+}
+
+// equal reports whether c and other configure the same sink.
+func (c MetricsConfig) equal(other MetricsConfig) bool {
+	return c.Sink == other.Sink && c.Address == other.Address && c.Prefix == other.Prefix &&
+		slices.Equal(c.Tags, other.Tags) && c.Interval == other.Interval
+}
+
+// getMetrics returns the metrics sink, which discards metrics if none is
+// configured.
+func (rw *FileSyncer) getMetrics() metrics.Sink {
+	if rw.metrics == nil {
+		return metrics.Nop{}
+	}
+	return rw.metrics
+}
+
+// recordPushMetrics records a push's final response. Like webhooks, replayed
+// responses and duplicates of pushes already applied record nothing.
+func (rw *FileSyncer) recordPushMetrics(resp *pb.PushResponse) {
+	if resp == nil || resp.PushId == "" || resp.Replayed || resp.AlreadyApplied || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	m := rw.getMetrics()
+	status := metrics.Tag("status", strings.ToLower(resp.Status.String()))
+	m.Count(metricPushes, 1, status)
+	if t := resp.Timing; t != nil {
+		m.Timing(metricPushDuration, time.Duration(t.TotalMs)*time.Millisecond, status)
+		for _, stage := range []struct {
+			name string
+			ms   int64
+		}{
+			{"download", t.DownloadMs},
+			{"batch_write", t.BatchWriteMs},
+			{"rsync", t.RsyncMs},
+			{"env_write", t.EnvWriteMs},
+			{"build", t.BuildMs},
+			{"install", t.InstallMs},
+			{"warm", t.WarmMs},
+			{"signal_to_healthy", t.SignalToHealthyMs},
+		} {
+			if stage.ms > 0 {
+				m.Timing(metricPushStageDuration, time.Duration(stage.ms)*time.Millisecond, metrics.Tag("stage", stage.name))
+			}
+		}
+	}
+	if resp.RsyncAttempts > 1 {
+		m.Count(metricRsyncRetries, int64(resp.RsyncAttempts-1))
+	}
+	for _, files := range []struct {
+		change string
+		paths  []string
+	}{
+		{"created", resp.CreatedFiles},
+		{"modified", resp.ModifiedFiles},
+		{"deleted", resp.DeletedFiles},
+	} {
+		if len(files.paths) > 0 {
+			m.Count(metricPushFiles, int64(len(files.paths)), metrics.Tag("change", files.change))
+		}
+	}
+}
+
+// recordRsyncMetrics records an rsync run that took took and ended with err.
+func (rw *FileSyncer) recordRsyncMetrics(took time.Duration, err error, stalled bool, ctxErr error) {
+	result := "ok"
+	switch {
+	case err == nil:
+	case stalled:
+		result = "stalled"
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		result = "timeout"
+	case errors.Is(ctxErr, context.Canceled):
+		result = "cancelled"
+	default:
+		result = "failed"
+	}
+	m := rw.getMetrics()
+	tag := metrics.Tag("result", result)
+	m.Count(metricRsyncRuns, 1, tag)
+	m.Timing(metricRsyncDuration, took, tag)
+}
+
+// runMetricsReporter sends the connection's metrics every interval until ctx
+// ends or the syncer stops.
+func (rw *FileSyncer) runMetricsReporter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var sent, received int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rw.done:
+			return
+		case <-ticker.C:
+			sent, received = rw.reportConnectionMetrics(sent, received)
+		}
+	}
+}
+
+// reportConnectionMetrics sends the connection stats status reports carry,
+// counting the messages since the previous report's totals, and returns the
+// new totals.
+func (rw *FileSyncer) reportConnectionMetrics(prevSent, prevReceived int64) (int64, int64) {
+	stats := &pb.ConnectionStats{
+		MessagesSent:     rw.messagesSent.Load(),
+		MessagesReceived: rw.messagesReceived.Load(),
+	}
+	rw.quality.addStats(stats)
+
+	m := rw.getMetrics()
+	m.Gauge(metricConnected, gaugeBool(rw.isConnected()))
+	m.Gauge(metricReconnectsLastHour, float64(stats.ReconnectsLastHour))
+	m.Gauge(metricRTT, float64(stats.RttMs))
+	m.Gauge(metricRTTVariance, float64(stats.RttVarianceMs))
+	m.Gauge(metricMessageLatency, float64(stats.MessageLatencyMs))
+	m.Gauge(metricThroughput, float64(stats.ThroughputBytesPerSecond))
+	m.Gauge(metricDegraded, gaugeBool(stats.Degraded))
+	m.Count(metricMessagesSent, stats.MessagesSent-prevSent)
+	m.Count(metricMessagesReceived, stats.MessagesReceived-prevReceived)
+	return stats.MessagesSent, stats.MessagesReceived
+}
+
+// isConnected reports whether the sidecar can reach the control plane, over
+// a websocket or by long polling.
+func (rw *FileSyncer) isConnected() bool {
+	return rw.connectionState() != ConnectionDisconnected
+}
+
+func gaugeBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
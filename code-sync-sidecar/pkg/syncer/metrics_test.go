@@ -0,0 +1,154 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/metrics"
+)
+
+// recordingSink records metrics as "name:value|type|#tags" lines.
+type recordingSink struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (s *recordingSink) Count(name string, value int64, tags ...string) {
+	s.record(name, fmt.Sprint(value), "c", tags)
+}
+
+func (s *recordingSink) Gauge(name string, value float64, tags ...string) {
+	s.record(name, fmt.Sprint(value), "g", tags)
+}
+
+func (s *recordingSink) Timing(name string, value time.Duration, tags ...string) {
+	s.record(name, fmt.Sprint(value.Milliseconds()), "ms", tags)
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func (s *recordingSink) record(name, value, kind string, tags []string) {
+	line := name + ":" + value + "|" + kind
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, line)
+}
+
+func (s *recordingSink) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := s.lines
+	s.lines = nil
+	return lines
+}
+
+func TestRecordPushMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	rw := &FileSyncer{metrics: sink}
+
+	rw.recordPushMetrics(&pb.PushResponse{PushId: "push-1", Status: pb.PushResponse_APPLYING})
+	rw.recordPushMetrics(&pb.PushResponse{PushId: "push-1", Status: pb.PushResponse_COMPLETED, Replayed: true})
+	rw.recordPushMetrics(&pb.PushResponse{PushId: "push-1", Status: pb.PushResponse_COMPLETED, AlreadyApplied: true})
+	assert.Empty(t, sink.take(), "only the first final response of a push is recorded")
+
+	rw.recordPushMetrics(&pb.PushResponse{
+		PushId:        "push-2",
+		Status:        pb.PushResponse_COMPLETED,
+		Timing:        &pb.PushTiming{DownloadMs: 20, RsyncMs: 300, WarmMs: 40, TotalMs: 500},
+		RsyncAttempts: 3,
+		CreatedFiles:  []string{"new.py"},
+		ModifiedFiles: []string{"app.py", "lib.py"},
+	})
+	assert.Equal(t, []string{
+		"push.count:1|c|#status:completed",
+		"push.duration:500|ms|#status:completed",
+		"push.stage.duration:20|ms|#stage:download",
+		"push.stage.duration:300|ms|#stage:rsync",
+		"push.stage.duration:40|ms|#stage:warm",
+		"rsync.retries:2|c",
+		"push.files:1|c|#change:created",
+		"push.files:2|c|#change:modified",
+	}, sink.take())
+
+	rw.recordPushMetrics(&pb.PushResponse{PushId: "push-3", Status: pb.PushResponse_RELOAD_FAILED})
+	assert.Equal(t, []string{"push.count:1|c|#status:reload_failed"}, sink.take())
+}
+
+func TestRecordRsyncMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	rw := &FileSyncer{metrics: sink}
+	failed := errors.New("exit status 23")
+
+	rw.recordRsyncMetrics(time.Second, nil, false, nil)
+	rw.recordRsyncMetrics(time.Second, failed, true, nil)
+	rw.recordRsyncMetrics(time.Second, failed, false, context.DeadlineExceeded)
+	rw.recordRsyncMetrics(time.Second, failed, false, nil)
+	assert.Equal(t, []string{
+		"rsync.runs:1|c|#result:ok",
+		"rsync.duration:1000|ms|#result:ok",
+		"rsync.runs:1|c|#result:stalled",
+		"rsync.duration:1000|ms|#result:stalled",
+		"rsync.runs:1|c|#result:timeout",
+		"rsync.duration:1000|ms|#result:timeout",
+		"rsync.runs:1|c|#result:failed",
+		"rsync.duration:1000|ms|#result:failed",
+	}, sink.take())
+}
+
+func TestReportConnectionMetrics(t *testing.T) {
+	conn, _ := newMockWebsocket(t)
+	defer conn.Close()
+	sink := &recordingSink{}
+	rw := &FileSyncer{metrics: sink, conn: conn}
+	rw.messagesSent.Store(5)
+	rw.messagesReceived.Store(3)
+	now := time.Now()
+	rw.quality.recordPong(string(pingPayload(now.Add(-100*time.Millisecond))), now)
+
+	sent, received := rw.reportConnectionMetrics(0, 0)
+	assert.Equal(t, int64(5), sent)
+	assert.Equal(t, int64(3), received)
+	assert.Equal(t, []string{
+		"connection.connected:1|g",
+		"connection.reconnects_last_hour:0|g",
+		"connection.rtt_ms:100|g",
+		"connection.rtt_variance_ms:50|g",
+		"connection.message_latency_ms:0|g",
+		"connection.throughput_bytes_per_second:0|g",
+		"connection.degraded:0|g",
+		"connection.messages_sent:5|c",
+		"connection.messages_received:3|c",
+	}, sink.take())
+
+	rw.messagesSent.Store(7)
+	rw.conn = nil
+	rw.reportConnectionMetrics(sent, received)
+	lines := sink.take()
+	assert.Contains(t, lines, "connection.connected:0|g")
+	assert.Contains(t, lines, "connection.messages_sent:2|c", "messages are counted since the last report")
+	assert.Contains(t, lines, "connection.messages_received:0|c")
+}
+
+func TestNewMetricsSink(t *testing.T) {
+	sink, err := NewMetricsSink(MetricsConfig{}, "app1", "deployment1")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Nop{}, sink)
+	assert.Equal(t, metrics.Nop{}, (&FileSyncer{}).getMetrics())
+
+	sink, err = NewMetricsSink(MetricsConfig{Sink: MetricsSinkDogStatsD, Address: "127.0.0.1:8125"}, "app1", "deployment1")
+	require.NoError(t, err)
+	assert.IsType(t, &metrics.StatsD{}, sink)
+	require.NoError(t, sink.Close())
+}
@@ -0,0 +1,140 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+)
+
+const (
+	// MigrationHook names the HookResult of a migration, followed by ":" and
+	// the database name.
+	MigrationHook = "migration"
+
+	DefaultMigrationTimeout = 5 * time.Minute
+)
+
+var errMigrationFailed = errors.New("database migration failed")
+
+// MigrationRunner runs the configured migration command against a database
+// branch a push created, before the app is signalled to use it.
+type MigrationRunner struct {
+	command  string
+	timeout  time.Duration
+	filesDir string
+}
+
+// NewMigrationRunner creates a MigrationRunner, or returns nil if command is empty.
+func NewMigrationRunner(command string, timeout time.Duration, filesDir string) *MigrationRunner {
+	if command == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultMigrationTimeout
+	}
+	return &MigrationRunner{command: command, timeout: timeout, filesDir: filesDir}
+}
+
+// Run runs the command with sh in dir for the database of update. The
+// database env vars, which already point at the new branch, are set in its
+// environment along with the branch details. It returns an error when the
+// command exits non-zero, times out or ctx is cancelled.
+func (mr *MigrationRunner) Run(ctx context.Context, dir, pushID string, update *pb.DatabaseBranchUpdate, envVars []envfile.DatabaseEnvVar) (*pb.HookResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, mr.timeout)
+	defer cancel()
+
+	cmd := execCommand(ctx, "/bin/sh", "-c", mr.command)
+	cmd.Dir = dir
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = os.Environ()
+	for _, envVar := range envVars {
+		// Scoped variables belong to particular app processes, not to migrations.
+		if envVar.Scope == "" {
+			cmd.Env = append(cmd.Env, envVar.EnvVarName+"="+envVar.ConnectionURI)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		"BIFROST_PUSH_ID="+pushID,
+		"BIFROST_FILES_DIR="+mr.filesDir,
+		"BIFROST_DATABASE_NAME="+update.DatabaseName,
+		"BIFROST_BRANCH_ID="+update.NewBranchId,
+		"BIFROST_PARENT_BRANCH_ID="+update.ParentBranchId,
+	)
+
+	log.Info("Running database migration",
+		zap.String("database", update.DatabaseName),
+		zap.String("branchID", update.NewBranchId),
+		zap.String("command", mr.command))
+	startTime := time.Now()
+	output, err := cmd.CombinedOutput()
+	duration := time.Since(startTime)
+
+	result := &pb.HookResult{
+		Name:       MigrationHook + ":" + update.DatabaseName,
+		ExitCode:   int32(cmd.ProcessState.ExitCode()),
+		Output:     truncateOutput(output, maxHookOutputLength),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return result, fmt.Errorf("migration of database %s timed out after %v", update.DatabaseName, mr.timeout)
+		case context.Canceled:
+			return result, fmt.Errorf("migration of database %s cancelled: %w", update.DatabaseName, ctx.Err())
+		}
+		return result, fmt.Errorf("migration of database %s failed: %w. Output: %s", update.DatabaseName, err, result.Output)
+	}
+
+	log.Info("Database migration succeeded",
+		zap.String("database", update.DatabaseName),
+		zap.Duration("duration", duration),
+		zap.String("output", result.Output))
+	return result, nil
+}
+
+// createsBranch reports whether any of updates created a new database branch.
+func createsBranch(updates []*pb.DatabaseBranchUpdate) bool {
+	for _, update := range updates {
+		if update.BranchCreated {
+			return true
+		}
+	}
+	return false
+}
+
+// runMigrations runs the migration command in dir for every database branch
+// the push created, stopping at the first failure. The error wraps
+// errMigrationFailed.
+func (rw *FileSyncer) runMigrations(ctx context.Context, dir, pushID string, updates []*pb.DatabaseBranchUpdate, envVars []envfile.DatabaseEnvVar) ([]*pb.HookResult, error) {
+	migrations := rw.getMigrations()
+	if migrations == nil {
+		return nil, nil
+	}
+	var results []*pb.HookResult
+	for _, update := range updates {
+		if !update.BranchCreated {
+			continue
+		}
+		result, err := migrations.Run(ctx, dir, pushID, update, envVars)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("%w: %w", errMigrationFailed, err)
+		}
+	}
+	return results, nil
+}
+
+// getMigrations returns the runner for the migration command, or nil if none is configured.
+func (rw *FileSyncer) getMigrations() *MigrationRunner {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.migrations
+}
@@ -0,0 +1,141 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestMigrationRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	update := &pb.DatabaseBranchUpdate{DatabaseName: "main", NewBranchId: "br-2", BranchCreated: true, ParentBranchId: "br-1"}
+	envVars := []envfile.DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://br-2/app"},
+		{EnvVarName: "QUEUE_URL", ConnectionURI: "redis://queue", Scope: "worker"},
+	}
+
+	runner := NewMigrationRunner(`echo "$DATABASE_URL $BIFROST_DATABASE_NAME $BIFROST_BRANCH_ID $BIFROST_PARENT_BRANCH_ID $BIFROST_PUSH_ID ${QUEUE_URL:-unset}"; pwd`, time.Second, dir)
+	result, err := runner.Run(context.Background(), dir, "push-1", update, envVars)
+	require.NoError(t, err)
+	assert.Equal(t, "migration:main", result.GetName())
+	assert.Equal(t, int32(0), result.GetExitCode())
+	resolved, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://br-2/app main br-2 br-1 push-1 unset\n"+resolved+"\n", result.GetOutput())
+
+	runner = NewMigrationRunner("echo relation already exists >&2; exit 3", time.Second, dir)
+	result, err = runner.Run(context.Background(), dir, "push-1", update, envVars)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration of database main failed")
+	assert.Contains(t, err.Error(), "relation already exists")
+	assert.Equal(t, int32(3), result.GetExitCode())
+
+	runner = NewMigrationRunner("sleep 5", 50*time.Millisecond, dir)
+	_, err = runner.Run(context.Background(), dir, "push-1", update, envVars)
+	assert.ErrorContains(t, err, "timed out after 50ms")
+
+	assert.Nil(t, NewMigrationRunner("", time.Second, dir))
+}
+
+// newMigrationTestSyncer returns a syncer whose API serves DATABASE_URL and
+// which runs command as its migration.
+func newMigrationTestSyncer(t *testing.T, command string) (*FileSyncer, *mockWebsocketServer, *mockProcessFinder) {
+	t.Helper()
+	rw, mockServer := newTemplateTestSyncer(t)
+	// Migrations run with the real shell.
+	execCommand = exec.CommandContext
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(rw.targetSyncDir), 0755))
+	rw.migrations = NewMigrationRunner(command, 5*time.Second, rw.targetSyncDir)
+	return rw, mockServer, rw.processFinder.(*mockProcessFinder)
+}
+
+func TestHandlePushRequest_MigratesNewBranch(t *testing.T) {
+	rw, mockServer, finder := newMigrationTestSyncer(t, `echo "$DATABASE_URL" > migrated; echo migrated "$BIFROST_DATABASE_NAME"`)
+	rw.envHistory = 3
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1",
+		DatabaseBranchUpdates: []*pb.DatabaseBranchUpdate{
+			{DatabaseName: "main", PreviousBranchId: "br-1", NewBranchId: "br-2", BranchCreated: true, ParentBranchId: "br-1"},
+			{DatabaseName: "analytics", PreviousBranchId: "br-3", NewBranchId: "br-4"},
+		},
+	}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.Len(t, resp.GetHookResults(), 1, "only created branches are migrated")
+	assert.Equal(t, "migration:main", resp.GetHookResults()[0].GetName())
+	assert.Equal(t, "migrated main\n", resp.GetHookResults()[0].GetOutput())
+
+	data, err := os.ReadFile(filepath.Join(rw.targetSyncDir, "migrated"))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/app\n", string(data))
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+
+	provenance, err := envfile.ReadProvenance(rw.targetSyncDir)
+	require.NoError(t, err)
+	require.Len(t, provenance, 2)
+	assert.Equal(t, "DATABASE_URL", provenance[0].Name)
+	assert.Equal(t, envfile.SourceDatabase, provenance[0].Source)
+	assert.Equal(t, envfile.ProviderBifrost, provenance[0].Provider)
+	assert.Equal(t, "push-1", provenance[0].PushID)
+	assert.Equal(t, "worker", provenance[1].Scope)
+
+	versions, current, err := envfile.ListVersions(rw.targetSyncDir)
+	require.NoError(t, err)
+	require.Len(t, versions, 1, "the refreshed env files are kept for rollback")
+	assert.Equal(t, "push-1", versions[0].PushID)
+	assert.Equal(t, versions[0].ID, current)
+}
+
+func TestHandlePushRequest_MigrationFailureSkipsReload(t *testing.T) {
+	rw, mockServer, finder := newMigrationTestSyncer(t, "echo duplicate column >&2; exit 1")
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId:                "push-1",
+		DatabaseBranchUpdates: []*pb.DatabaseBranchUpdate{{DatabaseName: "main", NewBranchId: "br-2", BranchCreated: true}},
+	})
+	assert.ErrorIs(t, err, errMigrationFailed)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "database migration failed")
+	assert.Contains(t, resp.GetErrorMessage(), "duplicate column")
+	require.Len(t, resp.GetHookResults(), 1)
+	assert.Equal(t, int32(1), resp.GetHookResults()[0].GetExitCode())
+	assert.Empty(t, finder.processes, "the app isn't reloaded onto an unmigrated branch")
+}
+
+func TestHandlePushRequest_MigratesAfterApplyingFiles(t *testing.T) {
+	rw, mockServer, finder := newMigrationTestSyncer(t, "cat migrations/0002.sql")
+	pushMsg := &pb.PushMessage{
+		PushId:                "push-1",
+		DatabaseBranchUpdates: []*pb.DatabaseBranchUpdate{{DatabaseName: "main", NewBranchId: "br-2", BranchCreated: true}},
+		Files:                 map[string]*pb.InjectedFile{"migrations/0002.sql": {Content: []byte("ALTER TABLE users ADD email text;\n")}},
+	}
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.Len(t, resp.GetHookResults(), 1)
+	assert.Equal(t, "ALTER TABLE users ADD email text;\n", resp.GetHookResults()[0].GetOutput(), "the migration sees the push's files")
+	assert.Len(t, finder.processes[12345].signalCalls, 1, "the app is reloaded once, after the migration")
+
+	// A failed migration stops the push before the app is reloaded.
+	rw.migrations = NewMigrationRunner("exit 2", 5*time.Second, rw.targetSyncDir)
+	pushMsg.PushId = "push-2"
+	pushMsg.Files = map[string]*pb.InjectedFile{"migrations/0003.sql": {Content: []byte("DROP TABLE users;\n")}}
+	assert.ErrorIs(t, rw.handlePushRequest(context.Background(), pushMsg), errMigrationFailed)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "Push application failed: database migration failed")
+	assert.Len(t, finder.processes[12345].signalCalls, 1)
+}
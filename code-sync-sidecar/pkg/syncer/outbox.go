@@ -0,0 +1,165 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	// maxOutboxMessages bounds the outbox; the oldest messages are dropped
+	// beyond it.
+	maxOutboxMessages = 1000
+
+	outboxFileSuffix = ".msg"
+)
+
+// outbox keeps the messages that couldn't be sent because the connection was
+// down, in .sidecar/outbox, and sends them once the sidecar reconnects.
+type outbox struct {
+	mu   sync.Mutex
+	last int64 // The name of the last message queued, to keep names in order
+	// flushed lists the pushes whose final responses the last flush sent, so
+	// replaying the session's responses doesn't send them twice.
+	flushed map[string]bool
+}
+
+func getOutboxDir(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), "outbox")
+}
+
+// isDurableMessage reports whether msg must reach the server even if the
+// connection is down when it is sent: final push responses and the results
+// of requests, which the server has no other way to learn.
+func isDurableMessage(msg *pb.WebsocketMessage) bool {
+	switch msg.MessageType {
+	case pb.WebsocketMessage_PUSH_RESPONSE:
+		return !isIntermediatePushStatus(msg.GetPushResponse().GetStatus())
+	case pb.WebsocketMessage_LAUNCHER_EXITED, pb.WebsocketMessage_SNAPSHOT_RESPONSE,
+		pb.WebsocketMessage_ENV_ROLLBACK_RESPONSE:
+		return true
+	}
+	return false
+}
+
+// queueOutbound keeps msg in the outbox to send after reconnecting.
+func (rw *FileSyncer) queueOutbound(msg *pb.WebsocketMessage) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	o := &rw.outbox
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	dir := getOutboxDir(rw.targetSyncDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	names, err := listOutbox(dir)
+	if err != nil {
+		return err
+	}
+	if extra := len(names) - maxOutboxMessages + 1; extra > 0 {
+		log.Warn("Outbox is full, dropping the oldest messages", zap.Int("dropped", extra))
+		for _, name := range names[:extra] {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+	// Named by when they were queued, so they are sent in order.
+	o.last = max(o.last+1, time.Now().UnixNano())
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", o.last, outboxFileSuffix))
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	return nil
+}
+
+// flushOutbox sends the queued messages in the order they were queued,
+// stopping at the first that can't be sent.
+func (rw *FileSyncer) flushOutbox() {
+	o := &rw.outbox
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushed = nil
+
+	dir := getOutboxDir(rw.targetSyncDir)
+	names, err := listOutbox(dir)
+	if err != nil {
+		log.Warn("Failed to read outbox", zap.Error(err))
+		return
+	}
+	if len(names) == 0 {
+		return
+	}
+	log.Info("Sending messages queued while disconnected", zap.Int("messages", len(names)))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn("Failed to read outbox message", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		var msg pb.WebsocketMessage
+		if err := proto.Unmarshal(data, &msg); err != nil {
+			log.Warn("Dropping outbox message that can't be decoded", zap.String("path", path), zap.Error(err))
+			os.Remove(path)
+			continue
+		}
+		if err := rw.writeProtoData(&msg, data); err != nil {
+			log.Warn("Failed to send outbox message, keeping it for the next connection", zap.Error(err))
+			return
+		}
+		os.Remove(path)
+		if resp := msg.GetPushResponse(); resp != nil {
+			if o.flushed == nil {
+				o.flushed = make(map[string]bool)
+			}
+			o.flushed[resp.PushId] = true
+		}
+	}
+}
+
+// wasFlushed reports whether the last flush sent the final response to pushID.
+func (o *outbox) wasFlushed(pushID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.flushed[pushID]
+}
+
+// listOutbox returns the names of the queued messages, oldest first.
+func listOutbox(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), outboxFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
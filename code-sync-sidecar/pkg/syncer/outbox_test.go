@@ -0,0 +1,69 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestOutbox_QueuesWhileDisconnected(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+
+	rw.sendProtoMessage(buildPushResponse("push-1", pb.PushResponse_RECEIVED, ""))
+	rw.sendProtoMessage(buildPushResponse("push-1", pb.PushResponse_COMPLETED, ""))
+	rw.sendProtoMessage(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_LAUNCHER_EXITED,
+		Message:     &pb.WebsocketMessage_LauncherExited{LauncherExited: &pb.LauncherExited{Pid: 42}},
+	})
+	rw.sendProtoMessage(rw.buildStatusReport())
+	names, err := listOutbox(getOutboxDir(rw.targetSyncDir))
+	require.NoError(t, err)
+	assert.Len(t, names, 2, "only the final push response and the launcher exit are kept")
+
+	// Flushed in order once connected, and not replayed again for the resumed session.
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw.conn = conn
+	rw.flushOutbox()
+	var types []pb.WebsocketMessage_MessageType
+	for range 2 {
+		select {
+		case data := <-mockServer.messages:
+			var msg pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(data, &msg))
+			types = append(types, msg.MessageType)
+			if resp := msg.GetPushResponse(); resp != nil {
+				assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status)
+				assert.False(t, resp.Replayed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for outbox messages")
+		}
+	}
+	assert.Equal(t, []pb.WebsocketMessage_MessageType{pb.WebsocketMessage_PUSH_RESPONSE, pb.WebsocketMessage_LAUNCHER_EXITED}, types)
+	names, err = listOutbox(getOutboxDir(rw.targetSyncDir))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	rw.replayPushResponses([]string{"push-1"})
+	select {
+	case <-mockServer.messages:
+		t.Fatal("A response sent from the outbox was replayed")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestOutbox_DropsOldestWhenFull(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	for range maxOutboxMessages + 5 {
+		require.NoError(t, rw.queueOutbound(buildPushResponse("push-1", pb.PushResponse_FAILED, "")))
+	}
+	names, err := listOutbox(getOutboxDir(rw.targetSyncDir))
+	require.NoError(t, err)
+	assert.Len(t, names, maxOutboxMessages)
+}
@@ -0,0 +1,124 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// pathScope is the set of paths a push says its batch is limited to, as glob
+// patterns relative to the files directory. "*", "?" and "[...]" match within a
+// path segment and a "**" segment matches any number of segments, so
+// "src/api/**" covers src/api and everything below it.
+type pathScope [][]string
+
+// parsePathScope parses a push's path patterns. No patterns means no scope.
+func parsePathScope(patterns []string) (pathScope, error) {
+	scope := make(pathScope, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" || path.IsAbs(pattern) {
+			return nil, fmt.Errorf("invalid path pattern %q: must be relative to the files directory", pattern)
+		}
+		segments := strings.Split(path.Clean(pattern), "/")
+		for _, segment := range segments {
+			if segment == ".." {
+				return nil, fmt.Errorf("invalid path pattern %q: must not leave the files directory", pattern)
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+			}
+		}
+		scope = append(scope, segments)
+	}
+	return scope, nil
+}
+
+// allows reports whether the batch may make change. Besides paths a pattern
+// matches, a directory holding them may be created or have its attributes
+// updated, since rsync does that on the way to files below it.
+func (s pathScope) allows(change itemizedChange) bool {
+	name := strings.Split(change.path, "/")
+	ancestor := !change.isDeleted() && change.flags[1] == 'd'
+	for _, pattern := range s {
+		if matchSegments(pattern, name, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// outOfScope returns the paths of the changes the scope doesn't allow.
+func (s pathScope) outOfScope(changes []itemizedChange) []string {
+	var paths []string
+	for _, change := range changes {
+		if !s.allows(change) {
+			paths = append(paths, change.path)
+		}
+	}
+	return paths
+}
+
+// matchSegments reports whether the path segments in name match pattern or,
+// with ancestor set, whether name is a directory that paths pattern matches
+// could be below.
+func matchSegments(pattern, name []string, ancestor bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:], ancestor) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return ancestor
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// checkPathScope runs the batches as rsync dry runs and returns an
+// *outOfScopeError if they would change paths patterns don't allow.
+func (rw *FileSyncer) checkPathScope(ctx context.Context, batches []*pushBatch, opts rsyncOptions, patterns []string) error {
+	scope, err := parsePathScope(patterns)
+	if err != nil {
+		return err
+	}
+	changes, err := rw.dryRunBatches(ctx, batches, opts)
+	if err != nil {
+		return err
+	}
+	if paths := scope.outOfScope(changes); len(paths) > 0 {
+		return &outOfScopeError{paths: paths, patterns: patterns}
+	}
+	return nil
+}
+
+// outOfScopeError is returned when a push's batch changes paths outside the
+// paths the push named.
+type outOfScopeError struct {
+	paths    []string
+	patterns []string
+}
+
+func (e *outOfScopeError) Error() string {
+	return fmt.Sprintf("batch changes %d paths outside %s: %s",
+		len(e.paths), strings.Join(e.patterns, ", "), summarizePaths(e.paths, maxPathsInMessage))
+}
+
+func withOutOfScopePaths(msg *pb.WebsocketMessage, err *outOfScopeError) *pb.WebsocketMessage {
+	paths := err.paths
+	if len(paths) > maxReportedChanges {
+		paths = paths[:maxReportedChanges]
+	}
+	msg.GetPushResponse().OutOfScopePaths = paths
+	return msg
+}
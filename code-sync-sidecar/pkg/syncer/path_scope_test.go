@@ -0,0 +1,84 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestParsePathScope(t *testing.T) {
+	scope, err := parsePathScope([]string{"src/api/**", "./README.md"})
+	require.NoError(t, err)
+	assert.Equal(t, pathScope{{"src", "api", "**"}, {"README.md"}}, scope)
+
+	_, err = parsePathScope([]string{"/etc/**"})
+	assert.ErrorContains(t, err, `invalid path pattern "/etc/**": must be relative to the files directory`)
+	_, err = parsePathScope([]string{"src/../../etc"})
+	assert.ErrorContains(t, err, "must not leave the files directory")
+	_, err = parsePathScope([]string{"src/[a-"})
+	assert.ErrorContains(t, err, "syntax error in pattern")
+}
+
+func TestPathScope_OutOfScope(t *testing.T) {
+	scope, err := parsePathScope([]string{"src/api/**", "docs/*.md"})
+	require.NoError(t, err)
+	changes := parseItemizedChanges([]byte(strings.Join([]string{
+		".d..t...... src/",                 // Parent directory of the scope
+		"cd+++++++++ src/api/",             // The scope itself
+		"cd+++++++++ src/api/v2/",          // Below it
+		">f+++++++++ src/api/v2/routes.go", // Below it
+		">f.st...... docs/index.md",
+		">f.st...... docs/guides/setup.md", // "*" doesn't cross segments
+		">f.st...... src/main.go",
+		"*deleting   src/", // Removing a parent isn't in scope
+		"cd+++++++++ lib/",
+	}, "\n")))
+	assert.Equal(t, []string{"docs/guides/setup.md", "src/main.go", "src", "lib"}, scope.outOfScope(changes))
+
+	everything, err := parsePathScope([]string{"**"})
+	require.NoError(t, err)
+	assert.Empty(t, everything.outOfScope(changes))
+}
+
+func TestHandlePushRequest_PathScope(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f.st...... src/api/handler.go;>f.st...... config/prod.yaml")
+
+	// Force doesn't skip the check.
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), Paths: []string{"src/api/**"}, Force: true,
+	})
+	var outOfScope *outOfScopeError
+	require.ErrorAs(t, err, &outOfScope)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_OUT_OF_SCOPE, resp.GetStatus())
+	assert.Equal(t, "Push rejected: batch changes 1 paths outside src/api/**: config/prod.yaml", resp.GetErrorMessage())
+	assert.Equal(t, []string{"config/prod.yaml"}, resp.GetOutOfScopePaths())
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "only the dry run ran")
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch"), Paths: []string{"src/api/**", "config/*.yaml"},
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+
+	// The check fails closed.
+	t.Setenv("HELPER_RSYNC_FAIL", "1")
+	err = rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-3", BatchFile: []byte("batch-3"), Paths: []string{"src/api/**"},
+	})
+	assert.Error(t, err)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "failed to check the paths the push changes")
+}
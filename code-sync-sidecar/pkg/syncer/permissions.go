@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// modeBitsMask covers the permission, setuid, setgid and sticky bits.
+const modeBitsMask = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// permissionMapping is the ownership and mode applied to files a push changes.
+type permissionMapping struct {
+	uid, gid    int
+	add, remove os.FileMode
+}
+
+func (m permissionMapping) enabled() bool {
+	return m.uid >= 0 || m.gid >= 0 || m.add != 0 || m.remove != 0
+}
+
+// parseModeBits parses octal mode bits such as "0640" or "2770" into an
+// os.FileMode. An empty string is no bits.
+func parseModeBits(value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	bits, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, fmt.Errorf("%q must be octal mode bits between 0000 and 7777", value)
+	}
+	mode := os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// apply chowns and chmods each path under root. Symlinks are chowned but never
+// chmodded, since that would change their target. Paths that no longer exist
+// are skipped. Changes the security module refuses don't stop the others, and
+// are returned together in a securityDenialError.
+func (m permissionMapping) apply(root string, paths []string) error {
+	if !m.enabled() {
+		return nil
+	}
+	module := currentSecurityModule()
+	var denials []*pb.SecurityDenial
+	for _, rel := range paths {
+		path := filepath.Join(root, rel)
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if m.uid >= 0 || m.gid >= 0 {
+			if err := os.Lchown(path, m.uid, m.gid); module.denied(err) {
+				denials = append(denials, &pb.SecurityDenial{Path: rel, Operation: deniedChown, ErrorMessage: err.Error()})
+				continue
+			} else if err != nil {
+				return fmt.Errorf("failed to set ownership of %s: %w", rel, err)
+			}
+		}
+		if info.Mode()&os.ModeSymlink != 0 || (m.add == 0 && m.remove == 0) {
+			continue
+		}
+		mode := (info.Mode()&modeBitsMask)&^m.remove | m.add
+		if err := os.Chmod(path, mode); module.denied(err) {
+			denials = append(denials, &pb.SecurityDenial{Path: rel, Operation: deniedChmod, ErrorMessage: err.Error()})
+		} else if err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", rel, err)
+		}
+	}
+	if len(denials) > 0 {
+		return &securityDenialError{module: module, denials: denials}
+	}
+	return nil
+}
+
+// writtenPaths returns the paths a push created or changed: everything the
+// batch itemized except deletions, then the injected files.
+func writtenPaths(changes []itemizedChange, injected []string) []string {
+	var paths []string
+	for _, change := range changes {
+		if !change.isDeleted() {
+			paths = append(paths, change.path)
+		}
+	}
+	return append(paths, injected...)
+}
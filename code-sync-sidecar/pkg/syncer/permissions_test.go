@@ -0,0 +1,53 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModeBits(t *testing.T) {
+	mode, err := parseModeBits("")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), mode)
+	mode, err = parseModeBits("0640")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), mode)
+	mode, err = parseModeBits("2770")
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSetgid|0770, mode)
+
+	for _, value := range []string{"rw-r--r--", "0999", "17777"} {
+		_, err := parseModeBits(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestPermissionMapping_Apply(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src/main.go"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "run.sh"), []byte("x"), 0755))
+	require.NoError(t, os.Symlink("run.sh", filepath.Join(root, "link")))
+
+	m := permissionMapping{uid: os.Getuid(), gid: os.Getgid(), add: 0060, remove: 0007}
+	require.NoError(t, m.apply(root, []string{"src", "src/main.go", "run.sh", "link", "missing.txt"}))
+
+	for path, want := range map[string]os.FileMode{"src": 0770, "src/main.go": 0660, "run.sh": 0770} {
+		info, err := os.Stat(filepath.Join(root, path))
+		require.NoError(t, err)
+		assert.Equal(t, want, info.Mode().Perm(), path)
+		assert.Equal(t, uint32(os.Getgid()), info.Sys().(*syscall.Stat_t).Gid, path)
+	}
+}
+
+func TestPermissionMapping_Disabled(t *testing.T) {
+	m := (&Config{Permissions: PermissionsConfig{UID: -1, GID: -1}}).permissionMapping()
+	assert.False(t, m.enabled())
+	// Nothing is touched, so missing roots aren't an error.
+	assert.NoError(t, m.apply("/nonexistent", []string{"a"}))
+}
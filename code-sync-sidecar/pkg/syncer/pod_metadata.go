@@ -0,0 +1,166 @@
+package syncer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// Pod is where the sidecar runs in Kubernetes, included in status reports and
+// push responses. The command sets it from DetectPodMetadata at startup; it is
+// nil outside Kubernetes.
+var Pod *pb.PodMetadata
+
+// Environment variables the pod spec sets from the downward API, e.g.
+// valueFrom.fieldRef.fieldPath: spec.nodeName.
+const (
+	podNameEnv      = "BIFROST_POD_NAME"
+	podNamespaceEnv = "BIFROST_POD_NAMESPACE"
+	nodeNameEnv     = "BIFROST_NODE_NAME"
+	podIPEnv        = "BIFROST_POD_IP"
+)
+
+// podAPITimeout bounds the Kubernetes API request for the pod's node.
+const podAPITimeout = 5 * time.Second
+
+// These are replaced in tests.
+var (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesAPIURL  = func() string {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return ""
+		}
+		return "https://" + net.JoinHostPort(host, port)
+	}
+	kubernetesClient = func() (*http.Client, error) {
+		ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", filepath.Join(serviceAccountDir, "ca.crt"))
+		}
+		return &http.Client{
+			Timeout:   podAPITimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}, nil
+	}
+)
+
+// kubernetesToken reads the service account token for the Kubernetes API. It is
+// re-read on every use because the kubelet rotates it.
+func kubernetesToken() (string, error) {
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// DetectPodMetadata finds the pod the sidecar runs in. The downward API
+// variables BIFROST_POD_NAME, BIFROST_POD_NAMESPACE and BIFROST_NODE_NAME take
+// precedence; otherwise the namespace is read from the service account, the pod
+// name is the hostname, and the node is looked up in the Kubernetes API, which
+// needs permission to get the pod. It returns nil outside Kubernetes, and what it
+// found along with an error if the node lookup failed.
+func DetectPodMetadata(ctx context.Context) (*pb.PodMetadata, error) {
+	pod := &pb.PodMetadata{
+		PodName:   os.Getenv(podNameEnv),
+		Namespace: os.Getenv(podNamespaceEnv),
+		NodeName:  os.Getenv(nodeNameEnv),
+	}
+	apiURL := kubernetesAPIURL()
+	if apiURL != "" {
+		if pod.PodName == "" {
+			pod.PodName, _ = os.Hostname()
+		}
+		if pod.Namespace == "" {
+			if data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+				pod.Namespace = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if pod.PodName == "" && pod.Namespace == "" && pod.NodeName == "" {
+		return nil, nil
+	}
+	if pod.NodeName != "" || apiURL == "" || pod.PodName == "" || pod.Namespace == "" {
+		return pod, nil
+	}
+
+	node, err := lookupPodNode(ctx, apiURL, pod.Namespace, pod.PodName)
+	if err != nil {
+		return pod, fmt.Errorf("failed to look up the node of pod %s/%s (set %s from the downward API instead): %w", pod.Namespace, pod.PodName, nodeNameEnv, err)
+	}
+	pod.NodeName = node
+	return pod, nil
+}
+
+// lookupPodNode reads spec.nodeName of a pod from the Kubernetes API with the
+// service account's token.
+func lookupPodNode(ctx context.Context, apiURL, namespace, name string) (string, error) {
+	token, err := kubernetesToken()
+	if err != nil {
+		return "", err
+	}
+	client, err := kubernetesClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to load the cluster CA: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, podAPITimeout)
+	defer cancel()
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s", apiURL, url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var podSpec struct {
+		Spec struct {
+			NodeName string `json:"nodeName"`
+		} `json:"spec"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&podSpec); err != nil {
+		return "", fmt.Errorf("failed to decode pod: %w", err)
+	}
+	return podSpec.Spec.NodeName, nil
+}
+
+// PodLogFields returns the pod's non-empty fields for the logger's initial fields.
+func PodLogFields(pod *pb.PodMetadata) map[string]string {
+	fields := make(map[string]string)
+	if pod.GetPodName() != "" {
+		fields["podName"] = pod.GetPodName()
+	}
+	if pod.GetNamespace() != "" {
+		fields["namespace"] = pod.GetNamespace()
+	}
+	if pod.GetNodeName() != "" {
+		fields["nodeName"] = pod.GetNodeName()
+	}
+	return fields
+}
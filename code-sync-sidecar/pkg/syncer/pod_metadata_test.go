@@ -0,0 +1,104 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// fakeCluster points the pod metadata lookup at a service account directory
+// and, if handler is set, an API server running it.
+func fakeCluster(t *testing.T, namespace string, handler http.HandlerFunc) {
+	t.Helper()
+	originalDir, originalURL, originalClient := serviceAccountDir, kubernetesAPIURL, kubernetesClient
+	t.Cleanup(func() {
+		serviceAccountDir, kubernetesAPIURL, kubernetesClient = originalDir, originalURL, originalClient
+	})
+
+	serviceAccountDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(serviceAccountDir, "namespace"), []byte(namespace+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(serviceAccountDir, "token"), []byte("sa-token\n"), 0644))
+	if handler == nil {
+		kubernetesAPIURL = func() string { return "" }
+		return
+	}
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	kubernetesAPIURL = func() string { return server.URL }
+	kubernetesClient = func() (*http.Client, error) { return server.Client(), nil }
+}
+
+func TestDetectPodMetadata_DownwardAPI(t *testing.T) {
+	fakeCluster(t, "ignored", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API request %s", r.URL.Path)
+	})
+	t.Setenv("BIFROST_POD_NAME", "web-7d9f-abcde")
+	t.Setenv("BIFROST_POD_NAMESPACE", "team-a")
+	t.Setenv("BIFROST_NODE_NAME", "node-3")
+
+	pod, err := DetectPodMetadata(context.Background())
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&pb.PodMetadata{PodName: "web-7d9f-abcde", Namespace: "team-a", NodeName: "node-3"}, pod))
+	assert.Equal(t, map[string]string{"podName": "web-7d9f-abcde", "namespace": "team-a", "nodeName": "node-3"}, PodLogFields(pod))
+}
+
+func TestDetectPodMetadata_KubernetesAPI(t *testing.T) {
+	fakeCluster(t, "team-b", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sa-token", r.Header.Get("Authorization"))
+		if r.URL.Path != "/api/v1/namespaces/team-b/pods/web-1" {
+			http.Error(w, `pods "other" is forbidden`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"metadata":{"name":"web-1"},"spec":{"nodeName":"node-9"}}`))
+	})
+	t.Setenv("BIFROST_POD_NAME", "web-1")
+	t.Setenv("BIFROST_POD_NAMESPACE", "")
+	t.Setenv("BIFROST_NODE_NAME", "")
+
+	pod, err := DetectPodMetadata(context.Background())
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&pb.PodMetadata{PodName: "web-1", Namespace: "team-b", NodeName: "node-9"}, pod))
+
+	// Without RBAC to get the pod, what was found is still returned.
+	t.Setenv("BIFROST_POD_NAME", "other")
+	pod, err = DetectPodMetadata(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 403")
+	assert.True(t, proto.Equal(&pb.PodMetadata{PodName: "other", Namespace: "team-b"}, pod))
+}
+
+func TestDetectPodMetadata_OutsideKubernetes(t *testing.T) {
+	fakeCluster(t, "team-a", nil)
+	t.Setenv("BIFROST_POD_NAME", "")
+	t.Setenv("BIFROST_POD_NAMESPACE", "")
+	t.Setenv("BIFROST_NODE_NAME", "")
+
+	pod, err := DetectPodMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, pod)
+	assert.Empty(t, PodLogFields(pod))
+}
+
+func TestPushResponse_IncludesPod(t *testing.T) {
+	original := Pod
+	Pod = &pb.PodMetadata{PodName: "web-1", Namespace: "team-a", NodeName: "node-3"}
+	t.Cleanup(func() { Pod = original })
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{conn: conn, targetSyncDir: t.TempDir()}
+	rw.sendProtoMessage(buildPushResponse("push-1", pb.PushResponse_COMPLETED, ""))
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.True(t, proto.Equal(Pod, resp.GetPod()))
+	assert.True(t, proto.Equal(Pod, rw.buildStatusReport().GetStatusReport().GetPod()))
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package syncer
+
+import (
+	"os"
+	"syscall"
+)
+
+// DefaultReloadSignal is sent to the launcher once a push is applied.
+const DefaultReloadSignal = "SIGHUP"
+
+// newProcessGroupAttr runs rsync in its own process group so the watchdog can
+// kill the processes it forks along with it.
+func newProcessGroupAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopRsync stops rsync with SIGTERM so it removes its partially written temp files.
+func stopRsync(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+func killProcessGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+func setGroupNice(pgid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PGRP, pgid, nice)
+}
+
+// processCPUMillis returns the CPU time the sidecar has used.
+func processCPUMillis() (int64, error) {
+	var rusage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &rusage); err != nil {
+		return 0, err
+	}
+	return (rusage.Utime.Nano() + rusage.Stime.Nano()) / 1e6, nil
+}
+
+// filesystemFreeBytes returns the space available to unprivileged users on the filesystem holding path.
+func filesystemFreeBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,57 @@
+package syncer
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Windows builds are for running the sidecar in local development against the
+// devserver. Signals can't be sent there, so the launcher isn't signalled
+// unless signals.reload says otherwise, and rsync can't be throttled.
+
+// DefaultReloadSignal is sent to the launcher once a push is applied.
+const DefaultReloadSignal = ReloadSignalNone
+
+// newProcessGroupAttr returns nil: Windows has no process groups, so the
+// watchdog kills rsync alone.
+func newProcessGroupAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// stopRsync kills rsync, as Windows can't ask it to stop.
+func stopRsync(p *os.Process) error {
+	return p.Kill()
+}
+
+func killProcessGroup(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+func setGroupNice(int, int) error {
+	return errors.ErrUnsupported
+}
+
+func processCPUMillis() (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// filesystemFreeBytes returns the space available to the sidecar's user on the volume holding path.
+func filesystemFreeBytes(path string) (int64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if r, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0); r == 0 {
+		return 0, err
+	}
+	return int64(available), nil
+}
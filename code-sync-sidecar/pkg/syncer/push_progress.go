@@ -0,0 +1,159 @@
+package syncer
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// minProgressInterval throttles PUSH_PROGRESS messages within a stage.
+const minProgressInterval = 500 * time.Millisecond
+
+// pushProgress sends PUSH_PROGRESS updates for one push. Stage changes and
+// completion are always sent; intermediate updates at most every minProgressInterval.
+type pushProgress struct {
+	pushID string
+	send   func(*pb.WebsocketMessage)
+
+	stage     pb.PushProgress_Stage
+	percent   int32
+	bytesDone int64
+	lastSent  time.Time
+}
+
+func (rw *FileSyncer) newPushProgress(pushID string) *pushProgress {
+	return &pushProgress{
+		pushID: pushID,
+		send: func(msg *pb.WebsocketMessage) {
+			rw.sendProtoMessage(msg)
+		},
+	}
+}
+
+func (p *pushProgress) report(stage pb.PushProgress_Stage, percent int32, bytesDone, bytesTotal int64) {
+	now := time.Now()
+	force := stage != p.stage || percent == 100
+	if !force && (percent == p.percent || now.Sub(p.lastSent) < minProgressInterval) {
+		return
+	}
+	p.stage = stage
+	p.percent = percent
+	p.bytesDone = bytesDone
+	p.lastSent = now
+	p.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_PROGRESS,
+		Message: &pb.WebsocketMessage_PushProgress{
+			PushProgress: &pb.PushProgress{
+				PushId:     p.pushID,
+				Stage:      stage,
+				Percent:    percent,
+				BytesDone:  bytesDone,
+				BytesTotal: bytesTotal,
+			},
+		},
+	})
+}
+
+// output sends lines a command wrote during stage. Unlike report it isn't
+// throttled, so no output is dropped; the caller batches the lines instead.
+func (p *pushProgress) output(stage pb.PushProgress_Stage, lines []string) {
+	p.send(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_PROGRESS,
+		Message: &pb.WebsocketMessage_PushProgress{
+			PushProgress: &pb.PushProgress{
+				PushId:      p.pushID,
+				Stage:       stage,
+				OutputLines: lines,
+			},
+		},
+	})
+}
+
+// status sends an intermediate PushResponse. The push's final response follows later.
+func (p *pushProgress) status(status pb.PushResponse_PushStatus) {
+	p.send(buildPushResponse(p.pushID, status, ""))
+}
+
+// isIntermediatePushStatus reports whether a PushResponse is followed by the push's final response.
+func isIntermediatePushStatus(status pb.PushResponse_PushStatus) bool {
+	switch status {
+	case pb.PushResponse_PENDING, pb.PushResponse_RECEIVED, pb.PushResponse_APPLYING, pb.PushResponse_AWAITING_APPROVAL,
+		pb.PushResponse_RELOADING, pb.PushResponse_HEALTHY:
+		return true
+	}
+	return false
+}
+
+// finish reports the current stage as complete, unless rsync already reported 100%.
+func (p *pushProgress) finish(stage pb.PushProgress_Stage) {
+	if p.stage == stage && p.percent == 100 {
+		return
+	}
+	var bytesDone int64
+	if p.stage == stage {
+		bytesDone = p.bytesDone
+	}
+	p.report(stage, 100, bytesDone, 0)
+}
+
+// progress2Pattern matches rsync --info=progress2 lines such as
+// "      1,238,099  45%   10.00MB/s    0:00:01 (xfr#3, to-chk=5/10)".
+var progress2Pattern = regexp.MustCompile(`^\s*([\d,]+)\s+(\d{1,3})%`)
+
+// parseProgress2 extracts the transferred byte count and overall percentage from an rsync progress line.
+func parseProgress2(line string) (int64, int32, bool) {
+	match := progress2Pattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, 0, false
+	}
+	bytesDone, err := strconv.ParseInt(strings.ReplaceAll(match[1], ",", ""), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	percent, err := strconv.Atoi(match[2])
+	if err != nil || percent > 100 {
+		return 0, 0, false
+	}
+	return bytesDone, int32(percent), true
+}
+
+// progressWriter collects rsync's combined output and calls onProgress for every
+// progress line. rsync redraws progress with carriage returns, so both '\r' and
+// '\n' end a line.
+type progressWriter struct {
+	onProgress func(bytesDone int64, percent int32)
+
+	mu      sync.Mutex
+	output  bytes.Buffer
+	pending []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.output.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexAny(w.pending, "\r\n")
+		if i < 0 {
+			break
+		}
+		if bytesDone, percent, ok := parseProgress2(string(w.pending[:i])); ok && w.onProgress != nil {
+			w.onProgress(bytesDone, percent)
+		}
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Bytes returns everything written so far.
+func (w *progressWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.output.Bytes()
+}
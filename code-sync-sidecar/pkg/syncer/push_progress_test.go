@@ -0,0 +1,83 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestParseProgress2(t *testing.T) {
+	bytesDone, percent, ok := parseProgress2("      1,238,099  45%   10.00MB/s    0:00:01 (xfr#3, to-chk=5/10)")
+	require.True(t, ok)
+	assert.Equal(t, int64(1238099), bytesDone)
+	assert.Equal(t, int32(45), percent)
+
+	_, _, ok = parseProgress2(">f+++++++++ src/new.go")
+	assert.False(t, ok)
+}
+
+func TestHandlePushRequest_ReportsProgress(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	filesDir := t.TempDir()
+	launcherDir := launcher.Dir(filesDir)
+	require.NoError(t, os.MkdirAll(launcherDir, 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(launcherDir, "launcher.pid"), []byte("12345"), 0644))
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: filesDir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}
+	batch := []byte("fake-rsync-batch-data")
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: batch}))
+
+	type update struct {
+		stage     pb.PushProgress_Stage
+		percent   int32
+		bytesDone int64
+	}
+	var updates []update
+	var statuses []pb.PushResponse_PushStatus
+	for {
+		var wsMessage pb.WebsocketMessage
+		select {
+		case message := <-mockServer.messages:
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for push response")
+		}
+		if wsMessage.MessageType == pb.WebsocketMessage_PUSH_RESPONSE {
+			statuses = append(statuses, wsMessage.GetPushResponse().GetStatus())
+			if isIntermediatePushStatus(wsMessage.GetPushResponse().GetStatus()) {
+				continue
+			}
+			break
+		}
+		progress := wsMessage.GetPushProgress()
+		assert.Equal(t, "push-1", progress.GetPushId())
+		updates = append(updates, update{progress.GetStage(), progress.GetPercent(), progress.GetBytesDone()})
+	}
+
+	assert.Equal(t, []update{
+		{pb.PushProgress_DOWNLOADING, 100, int64(len(batch))},
+		{pb.PushProgress_APPLYING, 50, 1024},
+		{pb.PushProgress_APPLYING, 100, 2048},
+		{pb.PushProgress_RELOADING, 0, 0},
+		{pb.PushProgress_RELOADING, 100, 0},
+	}, updates)
+	assert.Equal(t, []pb.PushResponse_PushStatus{pb.PushResponse_APPLYING, pb.PushResponse_RELOADING, pb.PushResponse_COMPLETED}, statuses)
+}
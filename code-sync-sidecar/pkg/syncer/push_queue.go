@@ -0,0 +1,320 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const maxQueuedPushes = 16
+
+var errPushCancelled = errors.New("push cancelled")
+
+// pushQueue applies pushes one at a time, in the order they arrived, off the
+// websocket read loop so a PUSH_CANCEL can be received while a push is running.
+type pushQueue struct {
+	startOnce sync.Once
+	pending   chan *pb.PushMessage
+
+	mu        sync.Mutex
+	queued    map[string]bool          // Push ID -> cancelled before it started
+	downloads map[string]time.Duration // Push ID -> how long its message took to receive
+	activeID  string
+	cancel    context.CancelFunc
+	// heldWake wakes the push held for a push window when a queued push is
+	// cancelled, so a cancelled held push is answered right away.
+	heldWake chan struct{}
+	// approve receives who approved the running push while it waits for an
+	// ACTIVATE; nil otherwise.
+	approve chan string
+	seq     pushSequencer
+
+	// gapMu guards the sequence gaps to report on pushes' final responses; it
+	// is separate from mu, which is held while responses are sent.
+	gapMu sync.Mutex
+	gaps  map[string]*pb.SequenceGap // Push ID -> sequences given up on before it
+
+	// timerMu guards the timer of the push being applied, which is read while
+	// responses are sent.
+	timerMu sync.Mutex
+	timer   *pushTimer
+
+	// heldMu guards the final response runCoordinatedPush holds back; it is
+	// separate from mu, which is held while responses are sent.
+	heldMu   sync.Mutex
+	heldID   string
+	heldResp *pb.WebsocketMessage
+}
+
+// enqueuePush schedules a push to be applied after any pushes already queued;
+// a sequenced push is applied in sequence order instead. download is how long
+// its message took to receive, or 0 if unknown.
+func (rw *FileSyncer) enqueuePush(pushMsg *pb.PushMessage, download time.Duration) error {
+	if pushMsg == nil {
+		return fmt.Errorf("received PUSH_REQUEST but push_message field is nil")
+	}
+	rw.spoolOversizedBatch(pushMsg)
+	kept, err := rw.admitPush(pushMsg, download)
+	rw.persistSequence()
+	if !kept {
+		rw.releaseBatch(pushMsg)
+	}
+	return err
+}
+
+// admitPush queues or defers pushMsg, or answers it right away, reporting
+// whether the push was kept to be applied.
+func (rw *FileSyncer) admitPush(pushMsg *pb.PushMessage, download time.Duration) (bool, error) {
+	rw.audit.begin(pushMsg)
+	q := &rw.pushes
+	q.startOnce.Do(func() {
+		q.pending = make(chan *pb.PushMessage, maxQueuedPushes)
+		q.queued = make(map[string]bool)
+		q.downloads = make(map[string]time.Duration)
+		q.heldWake = make(chan struct{}, 1)
+		rw.stateMu.Lock()
+		q.seq.last, q.seq.lastID = rw.applied.LastSequence, rw.applied.LastSequencePushID
+		rw.stateMu.Unlock()
+		go rw.runPushWorker()
+	})
+
+	if rw.hasApplied(pushMsg.PushId) {
+		// The server resends a push when our response was lost; don't rsync it twice.
+		log.Info("Push was already applied, acknowledging without re-applying", zap.String("pushID", pushMsg.PushId))
+		resp := buildPushResponse(pushMsg.PushId, pb.PushResponse_COMPLETED, "")
+		resp.GetPushResponse().AlreadyApplied = true
+		rw.sendProtoMessage(resp)
+		return false, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, queued := q.queued[pushMsg.PushId]; queued || q.activeID == pushMsg.PushId {
+		log.Info("Push is already queued or running, ignoring duplicate", zap.String("pushID", pushMsg.PushId))
+		return false, nil
+	}
+	if pushMsg.Sequence > 0 {
+		return rw.enqueueSequencedPushLocked(pushMsg, download)
+	}
+	if err := rw.queuePushLocked(pushMsg, download); err != nil {
+		return false, err
+	}
+	rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_RECEIVED, ""))
+	return true, nil
+}
+
+// queuePushLocked hands a push to the worker, answering it with FAILED if the
+// queue is full. Called with q.mu held.
+func (rw *FileSyncer) queuePushLocked(pushMsg *pb.PushMessage, download time.Duration) error {
+	q := &rw.pushes
+	select {
+	case q.pending <- pushMsg:
+		q.queued[pushMsg.PushId] = false
+		if download > 0 {
+			q.downloads[pushMsg.PushId] = download
+		}
+		return nil
+	default:
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_FAILED, "Push rejected: too many pushes are queued"))
+		return fmt.Errorf("push queue is full, rejected push %s", pushMsg.PushId)
+	}
+}
+
+// cancelPush aborts the running push, marks a queued push so it is skipped, or
+// drops a push waiting for a sequence gap.
+func (rw *FileSyncer) cancelPush(req *pb.PushCancel) error {
+	if req == nil || req.PushId == "" {
+		return fmt.Errorf("received PUSH_CANCEL without a push id")
+	}
+	if rw.cancelBatchStream(req.PushId) {
+		return nil
+	}
+	if hub := rw.coordinationHub(); hub != nil {
+		// Followers may have the push queued or running even if the leader is done with it.
+		hub.Cancel(req.PushId)
+	}
+	q := &rw.pushes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.activeID == req.PushId && q.cancel != nil {
+		log.Info("Cancelling running push", zap.String("pushID", req.PushId))
+		q.cancel()
+		return nil
+	}
+	if _, ok := q.queued[req.PushId]; ok {
+		log.Info("Cancelling queued push", zap.String("pushID", req.PushId))
+		q.queued[req.PushId] = true
+		select {
+		case q.heldWake <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	if rw.cancelDeferredPushLocked(req.PushId) {
+		return nil
+	}
+	return fmt.Errorf("no running or queued push %s to cancel", req.PushId)
+}
+
+func (rw *FileSyncer) runPushWorker() {
+	for {
+		select {
+		case <-rw.done:
+			return
+		case pushMsg := <-rw.pushes.pending:
+			if !rw.holdPush(pushMsg) {
+				return
+			}
+			burst := rw.collectPushBurst(pushMsg)
+			for i, pushMsg := range burst {
+				if by := supersededBy(burst[i+1:]); by != "" && canBeSuperseded(pushMsg) {
+					rw.supersedePush(pushMsg, by)
+					continue
+				}
+				rw.runQueuedPush(pushMsg)
+			}
+		}
+	}
+}
+
+// collectPushBurst waits until no push has arrived for the debounce window and
+// returns every push received meanwhile, in order. Without a debounce window
+// the push is returned on its own.
+func (rw *FileSyncer) collectPushBurst(first *pb.PushMessage) []*pb.PushMessage {
+	burst := []*pb.PushMessage{first}
+	debounce := rw.getPushDebounce()
+	if debounce <= 0 {
+		return burst
+	}
+	timer := time.NewTimer(debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-rw.done:
+			return burst
+		case <-timer.C:
+			if len(burst) > 1 {
+				log.Info("Coalescing pushes received within the debounce window",
+					zap.Int("pushes", len(burst)), zap.Duration("debounce", debounce))
+			}
+			return burst
+		case pushMsg := <-rw.pushes.pending:
+			burst = append(burst, pushMsg)
+			timer.Reset(debounce)
+		}
+	}
+}
+
+// canBeSuperseded reports whether a later push makes applying this one
+// unnecessary. Each batch holds the full tree, but database branch updates,
+// injected files and deleted paths are only carried by the push that made them.
+func canBeSuperseded(pushMsg *pb.PushMessage) bool {
+	return len(pushMsg.DatabaseBranchUpdates) == 0 && len(pushMsg.Files) == 0 && len(pushMsg.DeletedPaths) == 0
+}
+
+// supersededBy returns the ID of the last push among later that carries a
+// batch of the full tree, or "" if none does. A batch limited to paths or
+// split into sub-trees only covers part of the tree, so it replaces nothing.
+func supersededBy(later []*pb.PushMessage) string {
+	for i := len(later) - 1; i >= 0; i-- {
+		if carriesBatch(later[i]) && len(later[i].Paths) == 0 && len(later[i].SubtreeBatches) == 0 {
+			return later[i].PushId
+		}
+	}
+	return ""
+}
+
+// supersedePush answers a queued push that won't be applied because a later one replaces it.
+func (rw *FileSyncer) supersedePush(pushMsg *pb.PushMessage, by string) {
+	q := &rw.pushes
+	q.mu.Lock()
+	cancelled := q.queued[pushMsg.PushId]
+	delete(q.queued, pushMsg.PushId)
+	delete(q.downloads, pushMsg.PushId)
+	q.mu.Unlock()
+
+	rw.releaseBatch(pushMsg)
+	if cancelled {
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_CANCELLED, "Push cancelled before it started"))
+		return
+	}
+	log.Info("Skipping push superseded by a later push", zap.String("pushID", pushMsg.PushId), zap.String("supersededBy", by))
+	resp := buildPushResponse(pushMsg.PushId, pb.PushResponse_SUPERSEDED, "")
+	resp.GetPushResponse().SupersededBy = by
+	rw.sendProtoMessage(resp)
+}
+
+func (rw *FileSyncer) runQueuedPush(pushMsg *pb.PushMessage) {
+	q := &rw.pushes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer rw.releaseBatch(pushMsg)
+
+	q.mu.Lock()
+	cancelled := q.queued[pushMsg.PushId]
+	delete(q.queued, pushMsg.PushId)
+	if !cancelled {
+		q.activeID = pushMsg.PushId
+		q.cancel = cancel
+	} else {
+		delete(q.downloads, pushMsg.PushId)
+	}
+	q.mu.Unlock()
+
+	if cancelled {
+		log.Info("Skipping push cancelled before it started", zap.String("pushID", pushMsg.PushId))
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_CANCELLED, "Push cancelled before it started"))
+		return
+	}
+	defer func() {
+		q.mu.Lock()
+		q.activeID = ""
+		q.cancel = nil
+		q.mu.Unlock()
+	}()
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+	// Resolved before coordinating, so followers get the batch itself.
+	if err := rw.resolveBatch(pushMsg); errors.Is(err, errBatchNotCached) {
+		log.Warn("Push refers to a batch that isn't cached", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_BATCH_NOT_CACHED, fmt.Sprintf("Push rejected: %v; send it again with the batch", err)))
+		return
+	} else if err != nil {
+		log.Error("Failed to resolve the push's batch", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_FAILED, fmt.Sprintf("Push rejected: %v", err)))
+		return
+	}
+	if hub := rw.coordinationHub(); hub != nil {
+		rw.runCoordinatedPush(ctx, hub, pushMsg)
+		return
+	}
+	if err := rw.handlePushRequest(ctx, pushMsg); err != nil {
+		log.Error("Error handling push request", zap.String("pushID", pushMsg.PushId), zap.Error(err))
+	}
+}
+
+// pushCancelled rolls back any files the push already changed and reports CANCELLED.
+func (rw *FileSyncer) pushCancelled(pushID string, backup *syncBackup, hookResults []*pb.HookResult) error {
+	errorMessage := "Push cancelled"
+	if backup != nil {
+		if err := backup.restore(); err != nil {
+			log.Error("Failed to restore files after cancelled push", zap.String("pushID", pushID), zap.Error(err))
+			rw.requestResync(pb.ResyncRequest_CORRUPTION, fmt.Sprintf("failed to roll back cancelled push %s: %v", pushID, err))
+			errorMessage = fmt.Sprintf("Push cancelled, but restoring the previous files failed: %v", err)
+		} else {
+			errorMessage = "Push cancelled, previous files restored"
+		}
+	}
+	log.Info("Push cancelled", zap.String("pushID", pushID))
+	rw.sendProtoMessage(withHookResults(buildPushResponse(pushID, pb.PushResponse_CANCELLED, errorMessage), hookResults))
+	return errPushCancelled
+}
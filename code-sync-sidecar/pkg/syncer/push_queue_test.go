@@ -0,0 +1,214 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// waitForPushResponse returns the next final push response, skipping progress updates and intermediate states.
+func waitForPushResponse(t *testing.T, mockServer *mockWebsocketServer) *pb.PushResponse {
+	t.Helper()
+	for {
+		select {
+		case message := <-mockServer.messages:
+			var wsMessage pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+			if wsMessage.MessageType == pb.WebsocketMessage_PUSH_PROGRESS || isIntermediatePushStatus(wsMessage.GetPushResponse().GetStatus()) {
+				continue
+			}
+			return wsMessage.GetPushResponse()
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for push response")
+			return nil
+		}
+	}
+}
+
+func TestPushQueue_Cancel(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+	t.Setenv("HELPER_RSYNC_SLEEP", "30s")
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: t.TempDir(),
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+		done:          make(chan struct{}),
+	}
+	defer close(rw.done)
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}, 0))
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch")}, 0))
+	assert.Error(t, rw.cancelPush(&pb.PushCancel{PushId: "unknown"}))
+
+	// Cancel the queued push first, then the running one once rsync has started.
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-2"}))
+	require.Eventually(t, func() bool {
+		rw.pushes.mu.Lock()
+		defer rw.pushes.mu.Unlock()
+		return rw.pushes.activeID == "push-1"
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-1"}))
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-1", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.GetStatus())
+	assert.Less(t, time.Since(start), 5*time.Second, "rsync should be stopped promptly")
+
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-2", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.GetStatus())
+
+	entries, err := filepath.Glob(filepath.Join(launcher.SidecarDir(rw.targetSyncDir), "sync_*"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "temporary batch and backup files are removed")
+}
+
+func TestPushQueue_DuplicatePush(t *testing.T) {
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: t.TempDir(),
+		conn:          conn,
+		done:          make(chan struct{}),
+	}
+	defer close(rw.done)
+	rw.applied.RecentPushIDs = []string{"push-1"}
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}, 0))
+
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-1", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.True(t, resp.GetAlreadyApplied())
+	assertOnlyAuditLog(t, rw.targetSyncDir, "the batch is not applied again")
+}
+
+func TestPushQueue_DebounceCoalescesPushes(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.pushDebounce = 200 * time.Millisecond
+
+	for _, pushID := range []string{"push-1", "push-2", "push-3"} {
+		require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: pushID, BatchFile: []byte("batch")}, 0))
+	}
+
+	for _, pushID := range []string{"push-1", "push-2"} {
+		resp := waitForPushResponse(t, mockServer)
+		assert.Equal(t, pushID, resp.GetPushId())
+		assert.Equal(t, pb.PushResponse_SUPERSEDED, resp.GetStatus())
+		assert.Equal(t, "push-3", resp.GetSupersededBy())
+	}
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-3", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+}
+
+func TestPushQueue_DebounceKeepsInjectedFiles(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.pushDebounce = 200 * time.Millisecond
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{
+		PushId:    "push-1",
+		BatchFile: []byte("batch"),
+		Files:     map[string]*pb.InjectedFile{"config/app.env": {Content: []byte("A=1\n")}},
+	}, 0))
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch")}, 0))
+
+	for _, pushID := range []string{"push-1", "push-2"} {
+		resp := waitForPushResponse(t, mockServer)
+		assert.Equal(t, pushID, resp.GetPushId())
+		assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus(), resp.GetErrorMessage())
+	}
+	data, err := os.ReadFile(filepath.Join(rw.targetSyncDir, "config", "app.env"))
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(data))
+}
+
+func TestPushQueue_DebounceKeepsDeletedPaths(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.pushDebounce = 200 * time.Millisecond
+	oldFile := filepath.Join(rw.targetSyncDir, "src", "old.ts")
+	require.NoError(t, os.MkdirAll(filepath.Dir(oldFile), 0755))
+	require.NoError(t, os.WriteFile(oldFile, []byte("old"), 0644))
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{
+		PushId:       "push-1",
+		BatchFile:    []byte("batch"),
+		DeletedPaths: []string{"src/old.ts"},
+	}, 0))
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch")}, 0))
+
+	for _, pushID := range []string{"push-1", "push-2"} {
+		resp := waitForPushResponse(t, mockServer)
+		assert.Equal(t, pushID, resp.GetPushId())
+		assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus(), resp.GetErrorMessage())
+	}
+	assert.NoFileExists(t, oldFile)
+}
+
+func TestPushQueue_DebounceKeepsFullBatchBeforeScopedPush(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.pushDebounce = 200 * time.Millisecond
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f.st...... src/api/handler.go")
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch-1")}, 0))
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch-2"), Paths: []string{"src/api/**"},
+	}, 0))
+
+	for _, pushID := range []string{"push-1", "push-2"} {
+		resp := waitForPushResponse(t, mockServer)
+		assert.Equal(t, pushID, resp.GetPushId())
+		assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus(), resp.GetErrorMessage())
+	}
+}
+
+func TestSupersededBy(t *testing.T) {
+	files := &pb.PushMessage{PushId: "files", BatchFile: []byte("batch")}
+	databaseOnly := &pb.PushMessage{
+		PushId:                "database",
+		DatabaseBranchUpdates: []*pb.DatabaseBranchUpdate{{DatabaseName: "main", NewBranchId: "br-2"}},
+	}
+
+	assert.Equal(t, "files", supersededBy([]*pb.PushMessage{files, databaseOnly}))
+	assert.Empty(t, supersededBy([]*pb.PushMessage{databaseOnly}), "a push without files doesn't replace earlier files")
+	scoped := &pb.PushMessage{PushId: "scoped", BatchFile: []byte("batch"), Paths: []string{"src/api/**"}}
+	assert.Empty(t, supersededBy([]*pb.PushMessage{scoped}), "a scoped push doesn't replace the rest of the tree")
+	assert.Equal(t, "files", supersededBy([]*pb.PushMessage{files, scoped}))
+	subtrees := &pb.PushMessage{PushId: "subtrees", SubtreeBatches: []*pb.SubtreeBatch{{Path: "src", BatchFile: []byte("batch")}}}
+	assert.Empty(t, supersededBy([]*pb.PushMessage{subtrees}), "sub-tree batches don't replace the rest of the tree")
+	assert.True(t, canBeSuperseded(files))
+	assert.False(t, canBeSuperseded(databaseOnly), "database branch updates must still be applied")
+	assert.False(t, canBeSuperseded(&pb.PushMessage{
+		PushId:    "injected",
+		BatchFile: []byte("batch"),
+		Files:     map[string]*pb.InjectedFile{"config/app.env": {Content: []byte("A=1")}},
+	}), "injected files are not in later batches")
+	assert.False(t, canBeSuperseded(&pb.PushMessage{
+		PushId:       "deletions",
+		BatchFile:    []byte("batch"),
+		DeletedPaths: []string{"src/old.ts"},
+	}), "deleted paths are not in later batches")
+}
@@ -0,0 +1,236 @@
+package syncer
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// pushSequencer keeps sequenced pushes in order: a push older than one already
+// received is rejected, and one that arrives ahead of a gap is deferred until
+// the pushes before it arrive or the gap timeout passes. It is guarded by
+// pushQueue.mu.
+type pushSequencer struct {
+	last   int64 // The latest sequence queued
+	lastID string
+	// deferred holds the pushes waiting behind a gap, by sequence.
+	deferred map[int64]deferredPush
+	// gapTimer fires when the sidecar stops waiting for the missing pushes;
+	// gapGen tells a stale firing from the current one.
+	gapTimer    *time.Timer
+	gapGen      int
+	gapDeadline time.Time
+	// unsaved is set when the latest sequence isn't on disk yet; see
+	// persistSequence.
+	unsaved bool
+}
+
+type deferredPush struct {
+	pushMsg  *pb.PushMessage
+	download time.Duration
+}
+
+// enqueueSequencedPushLocked queues, defers or rejects a push by its sequence,
+// reporting whether it was kept to be applied. Called with q.mu held.
+func (rw *FileSyncer) enqueueSequencedPushLocked(pushMsg *pb.PushMessage, download time.Duration) (bool, error) {
+	s := &rw.pushes.seq
+	seq := pushMsg.Sequence
+	if s.last == 0 || seq == s.last+1 || (seq == s.last && pushMsg.PushId == s.lastID) {
+		if err := rw.queuePushLocked(pushMsg, download); err != nil {
+			return false, err
+		}
+		rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_RECEIVED, ""))
+		rw.advanceSequenceLocked(pushMsg)
+		rw.releaseDeferredPushesLocked()
+		return true, nil
+	}
+	if seq <= s.last {
+		rw.rejectOutOfOrderPush(pushMsg, fmt.Sprintf("Push rejected: sequence %d is older than sequence %d, which was already received", seq, s.last))
+		return false, nil
+	}
+	if held, ok := s.deferred[seq]; ok {
+		if held.pushMsg.PushId == pushMsg.PushId {
+			log.Info("Push is already waiting for a sequence gap, ignoring duplicate", zap.String("pushID", pushMsg.PushId))
+			return false, nil
+		}
+		rw.rejectOutOfOrderPush(pushMsg, fmt.Sprintf("Push rejected: push %s with sequence %d is already waiting", held.pushMsg.PushId, seq))
+		return false, nil
+	}
+
+	if s.deferred == nil {
+		s.deferred = make(map[int64]deferredPush)
+	}
+	s.deferred[seq] = deferredPush{pushMsg: pushMsg, download: download}
+	rw.sendProtoMessage(buildPushResponse(pushMsg.PushId, pb.PushResponse_RECEIVED, ""))
+	timeout := rw.getSequenceGapTimeout()
+	if timeout <= 0 {
+		rw.closeSequenceGapLocked()
+		return true, nil
+	}
+	if s.gapTimer == nil {
+		rw.startSequenceGapTimerLocked(timeout)
+	}
+	log.Info("Holding push until the pushes before it arrive",
+		zap.String("pushID", pushMsg.PushId), zap.Int64("sequence", seq),
+		zap.Int64("lastSequence", s.last), zap.Time("until", s.gapDeadline))
+	resp := buildPushResponse(pushMsg.PushId, pb.PushResponse_PENDING, "")
+	resp.GetPushResponse().HeldUntil = timestamppb.New(s.gapDeadline)
+	rw.sendProtoMessage(resp)
+	return true, nil
+}
+
+func (rw *FileSyncer) rejectOutOfOrderPush(pushMsg *pb.PushMessage, message string) {
+	last := rw.pushes.seq.last
+	log.Warn("Rejecting out-of-order push", zap.String("pushID", pushMsg.PushId),
+		zap.Int64("sequence", pushMsg.Sequence), zap.Int64("lastSequence", last))
+	resp := buildPushResponse(pushMsg.PushId, pb.PushResponse_OUT_OF_ORDER, message)
+	resp.GetPushResponse().LastSequence = last
+	rw.sendProtoMessage(resp)
+}
+
+// advanceSequenceLocked makes pushMsg's sequence the latest received. It is
+// saved by persistSequence once q.mu is released.
+func (rw *FileSyncer) advanceSequenceLocked(pushMsg *pb.PushMessage) {
+	s := &rw.pushes.seq
+	s.last, s.lastID = pushMsg.Sequence, pushMsg.PushId
+	rw.stateMu.Lock()
+	rw.applied.LastSequence = pushMsg.Sequence
+	rw.applied.LastSequencePushID = pushMsg.PushId
+	rw.stateMu.Unlock()
+	s.unsaved = true
+}
+
+// releaseDeferredPushesLocked queues the deferred pushes that follow on from
+// the latest sequence, and stops waiting once none are left.
+func (rw *FileSyncer) releaseDeferredPushesLocked() {
+	s := &rw.pushes.seq
+	for {
+		next, ok := s.deferred[s.last+1]
+		if !ok {
+			break
+		}
+		delete(s.deferred, s.last+1)
+		log.Info("Applying push whose sequence gap closed", zap.String("pushID", next.pushMsg.PushId), zap.Int64("sequence", next.pushMsg.Sequence))
+		// A full queue answers the push with FAILED; its sequence is used up all the same.
+		if err := rw.queuePushLocked(next.pushMsg, next.download); err != nil {
+			rw.releaseBatch(next.pushMsg)
+		}
+		rw.advanceSequenceLocked(next.pushMsg)
+	}
+	if len(s.deferred) == 0 && s.gapTimer != nil {
+		s.gapTimer.Stop()
+		s.gapTimer = nil
+		s.gapGen++
+	}
+}
+
+func (rw *FileSyncer) startSequenceGapTimerLocked(timeout time.Duration) {
+	s := &rw.pushes.seq
+	s.gapGen++
+	gen := s.gapGen
+	s.gapDeadline = time.Now().Add(timeout)
+	s.gapTimer = time.AfterFunc(timeout, func() {
+		q := &rw.pushes
+		q.mu.Lock()
+		if q.seq.gapGen == gen {
+			q.seq.gapTimer = nil
+			rw.closeSequenceGapLocked()
+		}
+		q.mu.Unlock()
+		rw.persistSequence()
+	})
+}
+
+// closeSequenceGapLocked gives up on the missing pushes before the lowest
+// deferred one and applies it, reporting the gap on its final response.
+func (rw *FileSyncer) closeSequenceGapLocked() {
+	q := &rw.pushes
+	s := &q.seq
+	if len(s.deferred) == 0 {
+		return
+	}
+	lowest := int64(0)
+	for seq := range s.deferred {
+		if lowest == 0 || seq < lowest {
+			lowest = seq
+		}
+	}
+	gap := &pb.SequenceGap{First: s.last + 1, Last: lowest - 1}
+	pushID := s.deferred[lowest].pushMsg.PushId
+	log.Warn("Gave up waiting for missing pushes",
+		zap.String("pushID", pushID), zap.Int64("firstMissing", gap.First), zap.Int64("lastMissing", gap.Last))
+	q.gapMu.Lock()
+	if q.gaps == nil {
+		q.gaps = make(map[string]*pb.SequenceGap)
+	}
+	q.gaps[pushID] = gap
+	q.gapMu.Unlock()
+
+	s.last = lowest - 1
+	rw.releaseDeferredPushesLocked()
+	if len(s.deferred) > 0 {
+		if timeout := rw.getSequenceGapTimeout(); timeout > 0 {
+			rw.startSequenceGapTimerLocked(timeout)
+		} else {
+			rw.closeSequenceGapLocked()
+		}
+	}
+}
+
+// cancelDeferredPushLocked answers a deferred push as cancelled, reporting
+// whether pushID was one.
+func (rw *FileSyncer) cancelDeferredPushLocked(pushID string) bool {
+	s := &rw.pushes.seq
+	for seq, held := range s.deferred {
+		if held.pushMsg.PushId != pushID {
+			continue
+		}
+		log.Info("Cancelling push waiting for a sequence gap", zap.String("pushID", pushID))
+		delete(s.deferred, seq)
+		rw.releaseBatch(held.pushMsg)
+		if len(s.deferred) == 0 && s.gapTimer != nil {
+			s.gapTimer.Stop()
+			s.gapTimer = nil
+			s.gapGen++
+		}
+		rw.sendProtoMessage(buildPushResponse(pushID, pb.PushResponse_CANCELLED, "Push cancelled before it started"))
+		return true
+	}
+	return false
+}
+
+// addSequenceGap sets the sequences given up on before the push on its final
+// response.
+func (q *pushQueue) addSequenceGap(resp *pb.PushResponse) {
+	if isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	q.gapMu.Lock()
+	defer q.gapMu.Unlock()
+	if gap, ok := q.gaps[resp.PushId]; ok {
+		resp.SequenceGap = gap
+		delete(q.gaps, resp.PushId)
+	}
+}
+
+// persistSequence saves the latest sequence if it changed, so sequences
+// continue across restarts. It is called without q.mu held, so the read loop
+// and the push worker don't wait on the disk.
+func (rw *FileSyncer) persistSequence() {
+	q := &rw.pushes
+	q.mu.Lock()
+	unsaved := q.seq.unsaved
+	q.seq.unsaved = false
+	q.mu.Unlock()
+	if !unsaved {
+		return
+	}
+	if err := rw.saveState(); err != nil {
+		log.Warn("Failed to persist sidecar state", zap.Error(err))
+	}
+}
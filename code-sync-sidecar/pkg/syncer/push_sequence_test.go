@@ -0,0 +1,124 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func newSequencedPush(pushID string, seq int64) *pb.PushMessage {
+	return &pb.PushMessage{PushId: pushID, BatchFile: []byte("batch " + pushID), Sequence: seq}
+}
+
+func TestPushQueue_SequencedPushesApplyInOrder(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.sequenceGapTimeout = time.Minute
+
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-1", 1), 0))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+
+	// push-3 waits for push-2, which arrived late.
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-3", 3), 0))
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-3", 3), 0), "a resent deferred push is ignored")
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-2", 2), 0))
+	for _, pushID := range []string{"push-2", "push-3"} {
+		resp := waitForPushResponse(t, mockServer)
+		assert.Equal(t, pushID, resp.GetPushId())
+		assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+		assert.Nil(t, resp.GetSequenceGap())
+	}
+
+	// A push delayed past a newer one is rejected.
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-old", 2), 0))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-old", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_OUT_OF_ORDER, resp.GetStatus())
+	assert.Equal(t, int64(3), resp.GetLastSequence())
+
+	assert.Equal(t, int64(3), rw.buildHello().GetHello().GetLastSequence())
+	state, err := loadSidecarState(rw.targetSyncDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), state.LastSequence)
+	assert.Equal(t, "push-3", state.LastSequencePushID)
+}
+
+func TestPushQueue_SequenceGapTimeout(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.sequenceGapTimeout = 100 * time.Millisecond
+	// Sequences continue from the ones received before a restart.
+	rw.applied.LastSequence, rw.applied.LastSequencePushID = 1, "push-1"
+
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-4", 4), 0))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-4", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.NotNil(t, resp.GetSequenceGap())
+	assert.Equal(t, int64(2), resp.GetSequenceGap().GetFirst())
+	assert.Equal(t, int64(3), resp.GetSequenceGap().GetLast())
+	state, err := loadSidecarState(rw.targetSyncDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), state.LastSequence, "a sequence reached by closing a gap is saved")
+
+	// The pushes given up on are out of order once they arrive.
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-2", 2), 0))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_OUT_OF_ORDER, resp.GetStatus())
+	assert.Equal(t, int64(4), resp.GetLastSequence())
+}
+
+func TestPersistSequence_Concurrent(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+	rw := &FileSyncer{targetSyncDir: filesDir}
+
+	// Other state saves racing the sequence's must not move it backwards.
+	var wg sync.WaitGroup
+	for i := range int64(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rw.recordResumeToken(fmt.Sprintf("token-%d", i))
+		}()
+		rw.pushes.mu.Lock()
+		rw.advanceSequenceLocked(newSequencedPush(fmt.Sprintf("push-%d", i+1), i+1))
+		rw.pushes.mu.Unlock()
+		rw.persistSequence()
+	}
+	wg.Wait()
+
+	state, err := loadSidecarState(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), state.LastSequence)
+	assert.Equal(t, "push-50", state.LastSequencePushID)
+}
+
+func TestPushQueue_CancelDeferredPush(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.sequenceGapTimeout = time.Minute
+	rw.applied.LastSequence = 1
+
+	require.NoError(t, rw.enqueuePush(newSequencedPush("push-3", 3), 0))
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-3"}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-3", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.GetStatus())
+
+	rw.pushes.mu.Lock()
+	defer rw.pushes.mu.Unlock()
+	assert.Empty(t, rw.pushes.seq.deferred)
+	assert.Nil(t, rw.pushes.seq.gapTimer, "nothing is left to wait for")
+}
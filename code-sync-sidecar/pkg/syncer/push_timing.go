@@ -0,0 +1,145 @@
+package syncer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// timedStage is a stage of a push whose duration is reported in PushTiming.
+type timedStage int
+
+const (
+	stageBatchWrite timedStage = iota
+	stageRsync
+	stageEnvWrite
+	stageSignalToHealthy
+	stageBuild
+	stageInstall
+	stageWarm
+)
+
+// pushTimer adds up how long each stage of a push takes. A nil timer records
+// nothing, for callers applying a batch outside of a push.
+type pushTimer struct {
+	pushID  string
+	started time.Time
+
+	mu     sync.Mutex
+	timing *pb.PushTiming
+	// rsyncAttempts and signalAttempts count the tries of the steps that are
+	// retried when they fail transiently.
+	rsyncAttempts  int32
+	signalAttempts int32
+	// launchers is how signalling each launcher went.
+	launchers []*pb.LauncherSignalResult
+}
+
+// since adds the time elapsed since start to stage.
+func (t *pushTimer) since(stage timedStage, start time.Time) {
+	if t == nil {
+		return
+	}
+	ms := time.Since(start).Milliseconds()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch stage {
+	case stageBatchWrite:
+		t.timing.BatchWriteMs += ms
+	case stageRsync:
+		t.timing.RsyncMs += ms
+	case stageEnvWrite:
+		t.timing.EnvWriteMs += ms
+	case stageSignalToHealthy:
+		t.timing.SignalToHealthyMs += ms
+	case stageBuild:
+		t.timing.BuildMs += ms
+	case stageInstall:
+		t.timing.InstallMs += ms
+	case stageWarm:
+		t.timing.WarmMs += ms
+	}
+}
+
+// attempts records how many times step was tried.
+func (t *pushTimer) attempts(step string, n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch step {
+	case retryStepRsync:
+		t.rsyncAttempts += int32(n)
+	case retryStepSignal:
+		t.signalAttempts += int32(n)
+	}
+}
+
+// launcherResults records how signalling each launcher went.
+func (t *pushTimer) launcherResults(results []*pb.LauncherSignalResult) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.launchers = results
+}
+
+// snapshot returns the timing so far, with the total up to now.
+func (t *pushTimer) snapshot() *pb.PushTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &pb.PushTiming{
+		DownloadMs:        t.timing.DownloadMs,
+		BatchWriteMs:      t.timing.BatchWriteMs,
+		RsyncMs:           t.timing.RsyncMs,
+		EnvWriteMs:        t.timing.EnvWriteMs,
+		SignalToHealthyMs: t.timing.SignalToHealthyMs,
+		BuildMs:           t.timing.BuildMs,
+		InstallMs:         t.timing.InstallMs,
+		WarmMs:            t.timing.WarmMs,
+		TotalMs:           time.Since(t.started).Milliseconds(),
+	}
+}
+
+// startTimer starts timing the push about to be applied, which its final
+// response reports. The download time is the one recorded when it was queued.
+func (q *pushQueue) startTimer(pushID string) *pushTimer {
+	q.mu.Lock()
+	download := q.downloads[pushID]
+	delete(q.downloads, pushID)
+	q.mu.Unlock()
+
+	t := &pushTimer{pushID: pushID, started: time.Now(), timing: &pb.PushTiming{DownloadMs: download.Milliseconds()}}
+	q.timerMu.Lock()
+	q.timer = t
+	q.timerMu.Unlock()
+	return t
+}
+
+// stopTimer stops reporting t once its push is finished.
+func (q *pushQueue) stopTimer(t *pushTimer) {
+	q.timerMu.Lock()
+	defer q.timerMu.Unlock()
+	if q.timer == t {
+		q.timer = nil
+	}
+}
+
+// addTiming sets the timing, attempt counts and launcher results of the push
+// being applied on its final response.
+func (q *pushQueue) addTiming(resp *pb.PushResponse) {
+	q.timerMu.Lock()
+	t := q.timer
+	q.timerMu.Unlock()
+	if t == nil || resp.Timing != nil || resp.PushId != t.pushID || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	resp.Timing = t.snapshot()
+	t.mu.Lock()
+	resp.RsyncAttempts, resp.SignalAttempts = t.rsyncAttempts, t.signalAttempts
+	resp.LauncherResults = t.launchers
+	t.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestPushTiming(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	t.Setenv("HELPER_RSYNC_SLEEP", "100ms")
+
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch"), Force: true}, 1500*time.Millisecond))
+
+	var final *pb.PushResponse
+	for final == nil {
+		select {
+		case message := <-mockServer.messages:
+			var wsMessage pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+			resp := wsMessage.GetPushResponse()
+			if resp == nil {
+				continue
+			}
+			if isIntermediatePushStatus(resp.Status) {
+				assert.Nil(t, resp.Timing, "only the final response has the timing")
+				continue
+			}
+			final = resp
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for push response")
+		}
+	}
+
+	assert.Equal(t, pb.PushResponse_COMPLETED, final.Status)
+	timing := final.GetTiming()
+	require.NotNil(t, timing)
+	assert.Equal(t, int64(1500), timing.DownloadMs)
+	assert.GreaterOrEqual(t, timing.RsyncMs, int64(100))
+	assert.Zero(t, timing.EnvWriteMs, "the push has no database updates")
+	assert.GreaterOrEqual(t, timing.TotalMs, timing.RsyncMs+timing.BatchWriteMs+timing.SignalToHealthyMs)
+
+	assert.Eventually(t, func() bool {
+		rw.pushes.timerMu.Lock()
+		defer rw.pushes.timerMu.Unlock()
+		return rw.pushes.timer == nil
+	}, time.Second, 5*time.Millisecond, "the timer stops with the push")
+	rw.pushes.mu.Lock()
+	defer rw.pushes.mu.Unlock()
+	assert.Empty(t, rw.pushes.downloads)
+}
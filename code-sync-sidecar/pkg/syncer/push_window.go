@@ -0,0 +1,195 @@
+package syncer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// The sidecar image has no zoneinfo, so push window time zones are compiled in.
+	_ "time/tzdata"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// pushWindowRecheck bounds how long a held push waits before its window is
+// worked out again, so a config reload that changes the windows applies to it.
+const pushWindowRecheck = time.Minute
+
+var weekdaysByName = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// pushWindow is a daily span of time in which pushes may be applied, on some
+// days of the week. A window ending before it starts runs past midnight into
+// the next day.
+type pushWindow struct {
+	days       [7]bool // Indexed by time.Weekday; the day the window starts
+	start, end int     // Minutes since midnight
+}
+
+// parsePushWindow parses "[DAYS ]HH:MM-HH:MM", where DAYS is a comma-separated
+// list of days or ranges of days, e.g. "Mon-Fri" or "Sat,Sun". Without DAYS the
+// window is open every day.
+func parsePushWindow(s string) (pushWindow, error) {
+	var w pushWindow
+	fields := strings.Fields(s)
+	var span string
+	switch len(fields) {
+	case 1:
+		span = fields[0]
+		for day := range w.days {
+			w.days[day] = true
+		}
+	case 2:
+		span = fields[1]
+		for _, part := range strings.Split(fields[0], ",") {
+			from, to, isRange := strings.Cut(part, "-")
+			first, ok := weekdaysByName[strings.ToLower(from)]
+			if !ok {
+				return w, fmt.Errorf("push window %q: unknown day %q", s, from)
+			}
+			last := first
+			if isRange {
+				if last, ok = weekdaysByName[strings.ToLower(to)]; !ok {
+					return w, fmt.Errorf("push window %q: unknown day %q", s, to)
+				}
+			}
+			for day := first; ; day = (day + 1) % 7 {
+				w.days[day] = true
+				if day == last {
+					break
+				}
+			}
+		}
+	default:
+		return w, fmt.Errorf("push window %q must look like \"Mon-Fri 09:00-17:00\"", s)
+	}
+
+	from, to, ok := strings.Cut(span, "-")
+	if !ok {
+		return w, fmt.Errorf("push window %q must look like \"Mon-Fri 09:00-17:00\"", s)
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return w, fmt.Errorf("push window %q: %w", s, err)
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return w, fmt.Errorf("push window %q: %w", s, err)
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("push window %q is empty", s)
+	}
+	return w, nil
+}
+
+// parseClock parses "HH:MM" as minutes since midnight; "24:00" is the end of the day.
+func parseClock(s string) (int, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// pushSchedule is when pushes may be applied. With no windows, pushes are
+// applied whenever they arrive.
+type pushSchedule struct {
+	windows  []pushWindow
+	location *time.Location
+}
+
+// nextOpen returns t if it falls in a window, or else when the next window
+// opens.
+func (s pushSchedule) nextOpen(t time.Time) time.Time {
+	if len(s.windows) == 0 {
+		return t
+	}
+	local := t.In(s.location)
+	var next time.Time
+	// Start a day early for windows that run past midnight.
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), 0, w.start, 0, 0, s.location)
+			length := w.end - w.start
+			if length < 0 {
+				length += 24 * 60
+			}
+			end := start.Add(time.Duration(length) * time.Minute)
+			if !t.Before(start) && t.Before(end) {
+				return t
+			}
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
+
+// heldUntil returns when pushMsg may be applied, after its not_before time and
+// within the push windows, or the zero time if it may be applied now.
+func (rw *FileSyncer) heldUntil(pushMsg *pb.PushMessage, now time.Time) time.Time {
+	at := now
+	if notBefore := pushMsg.GetNotBefore(); notBefore != nil && notBefore.AsTime().After(at) {
+		at = notBefore.AsTime()
+	}
+	if !pushMsg.GetBypassPushWindows() {
+		at = rw.getPushSchedule().nextOpen(at)
+	}
+	if !at.After(now) {
+		return time.Time{}
+	}
+	return at
+}
+
+// holdPush waits until pushMsg may be applied, reporting PENDING with the time
+// it will be applied while it is held. It returns early when the push is
+// cancelled, leaving runQueuedPush to report that, and returns false when the
+// sidecar stops.
+func (rw *FileSyncer) holdPush(pushMsg *pb.PushMessage) bool {
+	q := &rw.pushes
+	var reported time.Time
+	for {
+		until := rw.heldUntil(pushMsg, time.Now())
+		if until.IsZero() {
+			if !reported.IsZero() {
+				log.Info("Push window open, applying held push", zap.String("pushID", pushMsg.PushId))
+			}
+			return true
+		}
+		q.mu.Lock()
+		cancelled := q.queued[pushMsg.PushId]
+		q.mu.Unlock()
+		if cancelled {
+			return true
+		}
+		if !until.Equal(reported) {
+			log.Info("Holding push until it may be applied", zap.String("pushID", pushMsg.PushId), zap.Time("until", until))
+			resp := buildPushResponse(pushMsg.PushId, pb.PushResponse_PENDING, "")
+			resp.GetPushResponse().HeldUntil = timestamppb.New(until)
+			rw.sendProtoMessage(resp)
+			reported = until
+		}
+
+		timer := time.NewTimer(min(time.Until(until), pushWindowRecheck))
+		select {
+		case <-rw.done:
+			timer.Stop()
+			return false
+		case <-q.heldWake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
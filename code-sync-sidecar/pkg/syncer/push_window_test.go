@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestParsePushWindow(t *testing.T) {
+	w, err := parsePushWindow("Mon-Fri 09:00-17:30")
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{false, true, true, true, true, true, false}, w.days)
+	assert.Equal(t, 9*60, w.start)
+	assert.Equal(t, 17*60+30, w.end)
+
+	w, err = parsePushWindow("fri-mon,wed 22:00-02:00")
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{true, true, false, true, false, true, true}, w.days, "day ranges wrap around the week")
+
+	w, err = parsePushWindow("00:00-24:00")
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{true, true, true, true, true, true, true}, w.days)
+
+	for _, bad := range []string{"", "Mon-Fri", "Funday 09:00-17:00", "Mon 9:00-17:00", "Mon 09:00-25:00", "Mon 09:00-09:00", "Mon Tue 09:00-10:00"} {
+		_, err := parsePushWindow(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPushSchedule_NextOpen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	schedule := (&Config{Sync: SyncConfig{
+		PushWindows:        []string{"Mon-Fri 09:00-17:00", "Sat 22:00-02:00"},
+		PushWindowTimezone: "Europe/Berlin",
+	}}).pushSchedule()
+	require.Len(t, schedule.windows, 2)
+
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 3, day, hour, minute, 0, 0, berlin) }
+	// 2026-03-02 is a Monday.
+	assert.Equal(t, at(2, 10, 0), schedule.nextOpen(at(2, 10, 0)), "inside a window")
+	assert.Equal(t, at(3, 9, 0), schedule.nextOpen(at(2, 17, 0)), "the end of a window is outside it")
+	assert.Equal(t, at(7, 22, 0), schedule.nextOpen(at(6, 18, 0)), "Friday evening waits for Saturday night")
+	assert.Equal(t, at(8, 1, 0), schedule.nextOpen(at(8, 1, 0)), "a window runs past midnight")
+	assert.Equal(t, at(9, 9, 0), schedule.nextOpen(at(8, 2, 0)))
+	assert.Equal(t, at(2, 9, 0).UTC(), schedule.nextOpen(at(2, 7, 30).UTC()).UTC(), "any time zone is compared in the windows'")
+
+	always := pushSchedule{}
+	now := time.Now()
+	assert.Equal(t, now, always.nextOpen(now))
+}
+
+func TestPushQueue_HoldsPushOutsideWindow(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.done = make(chan struct{})
+	defer close(rw.done)
+	rw.pushSchedule = (&Config{Sync: SyncConfig{PushWindows: []string{"00:00-00:01"}}}).pushSchedule()
+
+	nextStatus := func() *pb.PushResponse {
+		t.Helper()
+		for {
+			select {
+			case message := <-mockServer.messages:
+				var wsMessage pb.WebsocketMessage
+				require.NoError(t, proto.Unmarshal(message, &wsMessage))
+				if resp := wsMessage.GetPushResponse(); resp != nil && resp.Status != pb.PushResponse_RECEIVED {
+					return resp
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for push response")
+				return nil
+			}
+		}
+	}
+
+	// Held for the window, which is only open for the first minute of the day.
+	notBefore := time.Now().Add(time.Hour)
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch"), NotBefore: timestamppb.New(notBefore)}, 0))
+	resp := nextStatus()
+	assert.Equal(t, pb.PushResponse_PENDING, resp.Status)
+	held := resp.HeldUntil.AsTime()
+	assert.False(t, held.Before(notBefore), "held past not_before")
+	assert.Equal(t, 0, held.Hour()*60+held.Minute(), "held until the window opens")
+
+	// Cancelling a held push answers it right away.
+	require.NoError(t, rw.cancelPush(&pb.PushCancel{PushId: "push-1"}))
+	resp = nextStatus()
+	assert.Equal(t, "push-1", resp.PushId)
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.Status)
+
+	// Bypassing the windows still honours not_before.
+	require.NoError(t, rw.enqueuePush(&pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch"),
+		NotBefore: timestamppb.New(time.Now().Add(200 * time.Millisecond)), BypassPushWindows: true}, 0))
+	resp = nextStatus()
+	assert.Equal(t, pb.PushResponse_PENDING, resp.Status)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-2", resp.PushId)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.Status)
+}
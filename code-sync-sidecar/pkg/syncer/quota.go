@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// errQuotaExceeded is returned when applying a push took the workspace over a hard quota.
+var errQuotaExceeded = errors.New("workspace quota exceeded")
+
+// quotaLimits are the workspace quotas from QuotaConfig; 0 isn't enforced.
+type quotaLimits struct {
+	softBytes, hardBytes   int64
+	softInodes, hardInodes int64
+}
+
+func (c *Config) quotaLimits() quotaLimits {
+	return quotaLimits{
+		softBytes:  int64(c.Quota.SoftBytes),
+		hardBytes:  int64(c.Quota.HardBytes),
+		softInodes: int64(c.Quota.SoftInodes),
+		hardInodes: int64(c.Quota.HardInodes),
+	}
+}
+
+// check measures the workspace in dir against the limits. It returns an error
+// wrapping errQuotaExceeded, along with the usage, when a hard limit is exceeded.
+func (q quotaLimits) check(dir string) (*pb.WorkspaceUsage, error) {
+	bytes, inodes, err := workspaceUsage(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure workspace %s: %w", dir, err)
+	}
+	usage := &pb.WorkspaceUsage{
+		Bytes:      bytes,
+		Inodes:     inodes,
+		SoftBytes:  q.softBytes,
+		HardBytes:  q.hardBytes,
+		SoftInodes: q.softInodes,
+		HardInodes: q.hardInodes,
+	}
+
+	var hard, soft []string
+	over := func(list *[]string, value, limit int64, what string) {
+		if limit > 0 && value > limit {
+			*list = append(*list, fmt.Sprintf("%d %s, over the limit of %d", value, what, limit))
+		}
+	}
+	over(&hard, bytes, q.hardBytes, "bytes")
+	over(&hard, inodes, q.hardInodes, "inodes")
+	if len(hard) > 0 {
+		usage.Warning = "workspace is over its hard quota: " + strings.Join(hard, "; ")
+		return usage, fmt.Errorf("%w: %s", errQuotaExceeded, strings.Join(hard, "; "))
+	}
+	over(&soft, bytes, q.softBytes, "bytes")
+	over(&soft, inodes, q.softInodes, "inodes")
+	if len(soft) > 0 {
+		usage.Warning = "workspace is over its soft quota: " + strings.Join(soft, "; ")
+	}
+	return usage, nil
+}
+
+// workspaceUsage sums the size of regular files under dir and counts its files,
+// directories and symlinks, skipping the sidecar and launcher internals.
+func workspaceUsage(dir string) (bytes, inodes int64, err error) {
+	sidecarDir := launcher.SidecarDir(dir)
+	launcherDir := launcher.Dir(dir)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (path == sidecarDir || path == launcherDir) {
+			return filepath.SkipDir
+		}
+		if path != dir {
+			inodes++
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		bytes += info.Size()
+		return nil
+	})
+	return bytes, inodes, err
+}
+
+// withWorkspaceUsage attaches the workspace's size to a push response.
+func withWorkspaceUsage(msg *pb.WebsocketMessage, usage *pb.WorkspaceUsage) *pb.WebsocketMessage {
+	msg.GetPushResponse().WorkspaceUsage = usage
+	return msg
+}
@@ -0,0 +1,92 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for value, want := range map[string]int64{
+		"0":       0,
+		"1024":    1024,
+		"512B":    512,
+		"2K":      2048,
+		"1KiB":    1024,
+		"500MB":   500_000_000,
+		"2GiB":    2 << 30,
+		" 1 TB ":  1_000_000_000_000,
+		"100MiB":  100 << 20,
+		"3G":      3 << 30,
+		"10KB":    10_000,
+		"7 MiB":   7 << 20,
+		"1048576": 1 << 20,
+	} {
+		got, err := parseByteSize(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "MB", "1.5GB", "ten", "9999999999TiB"} {
+		_, err := parseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// newQuotaTestDir returns a workspace holding 2 files of 100 bytes in 1
+// directory, along with sidecar internals that don't count.
+func newQuotaTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), make([]byte, 100), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "lib.py"), make([]byte, 100), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sidecar"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sidecar", "state.json"), make([]byte, 1000), 0644))
+	return dir
+}
+
+func TestQuotaLimitsCheck(t *testing.T) {
+	dir := newQuotaTestDir(t)
+
+	usage, err := quotaLimits{}.check(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), usage.GetBytes())
+	assert.Equal(t, int64(3), usage.GetInodes())
+	assert.Empty(t, usage.GetWarning())
+
+	usage, err = quotaLimits{softBytes: 150, hardBytes: 1000, softInodes: 10}.check(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "workspace is over its soft quota: 200 bytes, over the limit of 150", usage.GetWarning())
+
+	usage, err = quotaLimits{softBytes: 150, hardBytes: 1000, hardInodes: 2}.check(dir)
+	assert.ErrorIs(t, err, errQuotaExceeded)
+	assert.Equal(t, "workspace is over its hard quota: 3 inodes, over the limit of 2", usage.GetWarning())
+	assert.Equal(t, int64(2), usage.GetHardInodes())
+}
+
+func TestHandlePushRequest_Quota(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	require.NoError(t, os.WriteFile(filepath.Join(rw.targetSyncDir, "app.py"), make([]byte, 100), 0644))
+
+	rw.quota = quotaLimits{softBytes: 50, hardBytes: 1000}
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, int64(100), resp.GetWorkspaceUsage().GetBytes())
+	assert.Contains(t, resp.GetWorkspaceUsage().GetWarning(), "soft quota")
+	assert.Equal(t, int64(100), rw.buildStatusReport().GetStatusReport().GetWorkspaceUsage().GetBytes())
+
+	rw.quota = quotaLimits{hardBytes: 50}
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch2")})
+	assert.ErrorIs(t, err, errQuotaExceeded)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_QUOTA_EXCEEDED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "100 bytes, over the limit of 50")
+	assert.Equal(t, int64(50), resp.GetWorkspaceUsage().GetHardBytes())
+}
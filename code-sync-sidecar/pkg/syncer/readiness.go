@@ -0,0 +1,97 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// ReadinessMarker is a file whose presence tells the app container's readiness
+// probe that the sidecar has provisioned the shared volume and that no push is
+// being applied, so traffic isn't routed to a pod whose code is mid-change. A
+// nil or path-less marker does nothing.
+type ReadinessMarker struct {
+	path string
+
+	mu      sync.Mutex
+	ready   bool
+	stopped bool
+}
+
+// NewReadinessMarker returns a marker for the file at path, or a no-op marker
+// if path is empty.
+func NewReadinessMarker(path string) *ReadinessMarker {
+	return &ReadinessMarker{path: path}
+}
+
+// Path returns the marker file's path.
+func (m *ReadinessMarker) Path() string {
+	if m == nil {
+		return ""
+	}
+	return m.path
+}
+
+// Ready creates the marker file. It does nothing after Close.
+func (m *ReadinessMarker) Ready() {
+	if m == nil || m.path == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		log.Warn("Failed to create readiness file directory", zap.String("path", m.path), zap.Error(err))
+		return
+	}
+	content := fmt.Sprintf("%s\n", time.Now().UTC().Format(time.RFC3339))
+	if err := os.WriteFile(m.path, []byte(content), 0644); err != nil {
+		log.Warn("Failed to write readiness file", zap.String("path", m.path), zap.Error(err))
+		return
+	}
+	if !m.ready {
+		log.Info("Marked ready", zap.String("path", m.path))
+	}
+	m.ready = true
+}
+
+// Unready removes the marker file, logging why.
+func (m *ReadinessMarker) Unready(reason string) {
+	if m == nil || m.path == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(reason)
+}
+
+// Close removes the marker file for good, so a push finishing during shutdown
+// can't mark the pod ready again.
+func (m *ReadinessMarker) Close() {
+	if m == nil || m.path == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	m.remove("sidecar stopping")
+}
+
+func (m *ReadinessMarker) remove(reason string) {
+	if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to remove readiness file", zap.String("path", m.path), zap.Error(err))
+		return
+	}
+	if m.ready {
+		log.Info("Marked not ready", zap.String("path", m.path), zap.String("reason", reason))
+	}
+	m.ready = false
+}
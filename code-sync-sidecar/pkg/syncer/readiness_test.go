@@ -0,0 +1,87 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestReadinessMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes", "ready")
+	marker := NewReadinessMarker(path)
+
+	marker.Ready()
+	assert.FileExists(t, path)
+	marker.Unready("applying push")
+	assert.NoFileExists(t, path)
+	marker.Unready("again")
+	marker.Ready()
+	assert.FileExists(t, path)
+
+	// Once closed, the marker stays removed.
+	marker.Close()
+	assert.NoFileExists(t, path)
+	marker.Ready()
+	assert.NoFileExists(t, path)
+
+	// Disabled and nil markers do nothing.
+	NewReadinessMarker("").Ready()
+	var nilMarker *ReadinessMarker
+	nilMarker.Ready()
+	nilMarker.Close()
+}
+
+// readinessRecorder is a launcher that records whether the readiness file
+// existed when it was signalled to reload.
+type readinessRecorder struct {
+	path string
+	seen []bool
+}
+
+func (r *readinessRecorder) FindProcess(int) (launcher.ProcessSignaler, error) {
+	return r, nil
+}
+
+func (r *readinessRecorder) Signal(sig syscall.Signal) error {
+	if sig == 0 {
+		return nil // Liveness probe
+	}
+	_, err := os.Stat(r.path)
+	r.seen = append(r.seen, err == nil)
+	return nil
+}
+
+func TestHandlePushRequest_UnreadyDuringPush(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	recorder := &readinessRecorder{path: readyFile}
+	rw.processFinder = recorder
+	rw.readiness = NewReadinessMarker(readyFile)
+	rw.MarkReady()
+
+	rw.unreadyDuringPush = true
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.Equal(t, []bool{false}, recorder.seen, "not ready while the app reloads")
+	assert.FileExists(t, readyFile)
+
+	// A failed push restores the marker too.
+	t.Setenv("HELPER_RSYNC_FAIL", "1")
+	require.Error(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}))
+	assert.Equal(t, pb.PushResponse_FAILED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.FileExists(t, readyFile)
+	t.Setenv("HELPER_RSYNC_FAIL", "")
+
+	rw.unreadyDuringPush = false
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-3", BatchFile: []byte("batch-3")}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.Equal(t, []bool{false, true}, recorder.seen)
+}
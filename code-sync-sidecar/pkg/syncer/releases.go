@@ -0,0 +1,237 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// In swap mode each push is applied to a new release directory under
+// .releases, and the "current" symlink in the files directory is switched to it
+// once the batch has been applied. The app never sees a partially applied
+// tree, and the previous releases are kept so a rollback is a symlink flip.
+const (
+	releasesDirName = ".releases"
+	currentLinkName = "current"
+
+	// maxReleases is how many releases are kept, including the current one.
+	maxReleases = 3
+)
+
+func getReleasesDir(filesDir string) string {
+	return filepath.Join(filesDir, releasesDirName)
+}
+
+func getCurrentLink(filesDir string) string {
+	return filepath.Join(filesDir, currentLinkName)
+}
+
+// currentRelease returns the release directory the current symlink points to,
+// or "" if no release has been activated yet.
+func currentRelease(filesDir string) (string, error) {
+	target, err := os.Readlink(getCurrentLink(filesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read current release link: %w", err)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filesDir, target)
+	}
+	return target, nil
+}
+
+// stageRelease creates a new release directory holding a hard-linked copy of the
+// current release. rsync replaces files by renaming a temp file over them, so
+// writing to the new release never changes the files of the current one.
+// Before the first release, the files already in filesDir are copied instead.
+func stageRelease(filesDir string) (string, error) {
+	release, err := newRelease(filesDir)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := currentRelease(filesDir)
+	if err != nil {
+		os.RemoveAll(release)
+		return "", err
+	}
+	source, skip := current, func(string) bool { return false }
+	if current == "" {
+		source, skip = filesDir, isInternalEntry
+	}
+	if err := cloneTree(source, release, skip); err != nil {
+		os.RemoveAll(release)
+		return "", fmt.Errorf("failed to copy %s to new release: %w", source, err)
+	}
+	return release, nil
+}
+
+// newRelease creates an empty release directory.
+func newRelease(filesDir string) (string, error) {
+	releasesDir := getReleasesDir(filesDir)
+	if err := os.MkdirAll(releasesDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create releases directory %s: %w", releasesDir, err)
+	}
+	// Release names start with the time so they sort oldest first.
+	release, err := os.MkdirTemp(releasesDir, time.Now().UTC().Format("20060102T150405.000000000")+"_*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create release directory in %s: %w", releasesDir, err)
+	}
+	if err := os.Chmod(release, 0755); err != nil {
+		os.RemoveAll(release)
+		return "", fmt.Errorf("failed to set permissions on %s: %w", release, err)
+	}
+	return release, nil
+}
+
+// isInternalEntry reports whether a top-level entry of the files directory
+// belongs to the sidecar or launcher rather than the synced code.
+func isInternalEntry(name string) bool {
+	switch name {
+	case ".sidecar", ".launcher", releasesDirName, currentLinkName:
+		return true
+	}
+	return false
+}
+
+// isTopLevel reports whether a relative path names an entry directly inside its root.
+func isTopLevel(rel string) bool {
+	return !strings.Contains(rel, string(filepath.Separator))
+}
+
+// cloneTree recreates the tree under src in dst, hard linking regular files and
+// copying symlinks. Top-level entries for which skip returns true are left out.
+func cloneTree(src, dst string, skip func(name string) bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if isTopLevel(rel) && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return os.Link(path, target)
+		}
+		return nil // Sockets, pipes and devices aren't synced
+	})
+}
+
+// activateRelease atomically points the current symlink at release.
+func activateRelease(filesDir, release string) error {
+	rel, err := filepath.Rel(filesDir, release)
+	if err != nil {
+		return fmt.Errorf("failed to resolve release path %s: %w", release, err)
+	}
+	link := getCurrentLink(filesDir)
+	tmpLink := link + ".tmp"
+	os.Remove(tmpLink)
+	if err := os.Symlink(rel, tmpLink); err != nil {
+		return fmt.Errorf("failed to create release link: %w", err)
+	}
+	// rename(2) replaces the old link in one step, so readers always see a complete release.
+	if err := os.Rename(tmpLink, link); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("failed to switch current release to %s: %w", release, err)
+	}
+	return nil
+}
+
+// listReleases returns the release directories, oldest first.
+func listReleases(filesDir string) ([]string, error) {
+	entries, err := os.ReadDir(getReleasesDir(filesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+	var releases []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			releases = append(releases, filepath.Join(getReleasesDir(filesDir), entry.Name()))
+		}
+	}
+	sort.Strings(releases)
+	return releases, nil
+}
+
+// rollbackRelease points the current symlink back at the release before it and
+// returns that release.
+func rollbackRelease(filesDir string) (string, error) {
+	current, err := currentRelease(filesDir)
+	if err != nil {
+		return "", err
+	}
+	releases, err := listReleases(filesDir)
+	if err != nil {
+		return "", err
+	}
+	i := sort.SearchStrings(releases, current)
+	if i == len(releases) || releases[i] != current || i == 0 {
+		return "", fmt.Errorf("no release before %s to roll back to", current)
+	}
+	previous := releases[i-1]
+	if err := activateRelease(filesDir, previous); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
+// pruneReleases removes the oldest releases so at most keep remain. The current
+// release is never removed. It must not run while a release is being staged.
+func pruneReleases(filesDir string, keep int) error {
+	current, err := currentRelease(filesDir)
+	if err != nil {
+		return err
+	}
+	releases, err := listReleases(filesDir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	excess := len(releases) - keep
+	for _, release := range releases {
+		if excess <= 0 {
+			break
+		}
+		if release == current {
+			continue
+		}
+		if err := os.RemoveAll(release); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove old release %s: %w", release, err))
+		}
+		excess--
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,171 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func inode(t *testing.T, path string) uint64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Sys().(*syscall.Stat_t).Ino
+}
+
+func TestStageRelease_SeedsFromFilesDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "app.py"), []byte("app"), 0644))
+	require.NoError(t, os.Symlink("pkg/app.py", filepath.Join(dir, "main.py")))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(dir), "state.json"), []byte("{}"), 0644))
+
+	release, err := stageRelease(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, inode(t, filepath.Join(dir, "pkg", "app.py")), inode(t, filepath.Join(release, "pkg", "app.py")), "files are hard linked")
+	link, err := os.Readlink(filepath.Join(release, "main.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg/app.py", link)
+	assert.NoDirExists(t, filepath.Join(release, ".sidecar"))
+	assert.NoDirExists(t, filepath.Join(release, releasesDirName))
+}
+
+func TestActivateAndRollbackRelease(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := stageRelease(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(first, "app.py"), []byte("v1"), 0644))
+	require.NoError(t, activateRelease(dir, first))
+
+	second, err := stageRelease(dir)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(second, "app.py"), "new release starts from the current one")
+	// Replace the file the way rsync does, by renaming a new file over it.
+	tmp := filepath.Join(second, ".app.py.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte("v2"), 0644))
+	require.NoError(t, os.Rename(tmp, filepath.Join(second, "app.py")))
+	require.NoError(t, activateRelease(dir, second))
+
+	data, err := os.ReadFile(filepath.Join(getCurrentLink(dir), "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+	data, err = os.ReadFile(filepath.Join(first, "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data), "previous release is unchanged")
+
+	previous, err := rollbackRelease(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first, previous)
+	data, err = os.ReadFile(filepath.Join(getCurrentLink(dir), "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	_, err = rollbackRelease(dir)
+	assert.Error(t, err, "no release before the first one")
+}
+
+func TestPruneReleases(t *testing.T) {
+	dir := t.TempDir()
+	var releases []string
+	for range 5 {
+		release, err := stageRelease(dir)
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+	// The current release is kept even when it is one of the oldest.
+	require.NoError(t, activateRelease(dir, releases[0]))
+
+	require.NoError(t, pruneReleases(dir, 3))
+
+	remaining, err := listReleases(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{releases[0], releases[3], releases[4]}, remaining)
+}
+
+func TestHandlePushRequest_SwapMode(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("v1"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		applyMode:     ApplyModeSwap,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+
+	release, err := currentRelease(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, release)
+	assert.FileExists(t, filepath.Join(release, "app.py"))
+	assert.NoDirExists(t, filepath.Join(release, ".launcher"))
+
+	// A push cancelled while rsync runs removes its staged release and leaves current alone.
+	t.Setenv("HELPER_RSYNC_SLEEP", "30s")
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, rw.handlePushRequest(ctx, &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}), errPushCancelled)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_CANCELLED, resp.GetStatus())
+
+	releases, err := listReleases(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{release}, releases)
+}
+
+func TestHandlePushRequest_SwapModeRollsBackWhenSignalFails(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		applyMode:     ApplyModeSwap,
+		processFinder: finder,
+		conn:          conn,
+	}
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	first, err := currentRelease(dir)
+	require.NoError(t, err)
+
+	finder.processes[12345] = &mockProcess{signalErr: assert.AnError}
+	assert.Error(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_ROLLED_BACK, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "rolled back to the previous release")
+
+	current, err := currentRelease(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first, current)
+}
@@ -0,0 +1,335 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// Reloader tells the app to pick up a push, database update or snapshot
+// restore once the launcher handshake describing it is written.
+type Reloader interface {
+	// Reload asks the app to reload for hs and returns once it has been asked.
+	Reload(ctx context.Context, hs launcher.Handshake) error
+	// Strategy is the reload.strategy the Reloader implements.
+	Strategy() string
+}
+
+// newReloader creates the Reloader cfg selects for the app in filesDir.
+func newReloader(cfg *Config, appID, deploymentID, filesDir string, finder launcher.ProcessFinder) Reloader {
+	timeout := time.Duration(cfg.Reload.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultReloadTimeout
+	}
+	switch cfg.Reload.Strategy {
+	case ReloadStrategyHTTP:
+		return &httpReloader{
+			url: cfg.Reload.URL,
+			client: &http.Client{
+				Timeout:       timeout,
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			},
+		}
+	case ReloadStrategyCommand:
+		return &commandReloader{
+			command:      cfg.Reload.Command,
+			timeout:      timeout,
+			appID:        appID,
+			deploymentID: deploymentID,
+			filesDir:     filesDir,
+		}
+	case ReloadStrategyRestart:
+		sig, ok := cfg.ShutdownSignal()
+		if !ok {
+			sig = syscall.SIGTERM
+		}
+		return &restartReloader{filesDir: filesDir, finder: finder, sig: sig, target: cfg.SignalTarget(), timeout: timeout}
+	case ReloadStrategyOverlap:
+		return &overlapReloader{
+			filesDir:     filesDir,
+			finder:       finder,
+			sig:          cfg.ReloadSignal(),
+			target:       cfg.SignalTarget(),
+			timeout:      timeout,
+			drainSignal:  strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(cfg.Reload.DrainSignal)), "SIG"),
+			drainTimeout: time.Duration(cfg.Reload.DrainTimeout),
+		}
+	default:
+		r := &signalReloader{filesDir: filesDir, finder: finder, sig: cfg.ReloadSignal(), target: cfg.SignalTarget()}
+		for name := range cfg.Signals.Launchers {
+			if r.launchers == nil {
+				r.launchers = make(map[string]launcherSignal)
+			}
+			sig, target := cfg.LauncherSignal(name)
+			r.launchers[name] = launcherSignal{sig: sig, target: target}
+		}
+		return r
+	}
+}
+
+// signalReloader sends the reload signal to each launcher, which restarts its
+// app with the files and env in place. Most deployments have only the main
+// launcher; those that run several from one files dir, such as a web server
+// and a worker, get each of them signalled.
+type signalReloader struct {
+	filesDir  string
+	finder    launcher.ProcessFinder
+	sig       syscall.Signal
+	target    launcher.SignalTarget
+	launchers map[string]launcherSignal // From signals.launchers, keyed by launcher name
+}
+
+// launcherSignal is the reload signal and target of one named launcher.
+type launcherSignal struct {
+	sig    syscall.Signal
+	target launcher.SignalTarget
+}
+
+func (r *signalReloader) Strategy() string { return ReloadStrategySignal }
+
+func (r *signalReloader) Reload(ctx context.Context, hs launcher.Handshake) error {
+	var results []*pb.LauncherSignalResult
+	return r.signalLaunchers(&results, nil)
+}
+
+// signalLaunchers signals the launchers in names, or when names is nil each
+// launcher that has written a PID file, or the main launcher when none has,
+// and records how it went in results. Launchers results already has as
+// signalled are skipped, so a retry only signals those that failed. The error
+// joins those of the launchers that failed, each named when there are several.
+func (r *signalReloader) signalLaunchers(results *[]*pb.LauncherSignalResult, names []string) error {
+	if names == nil {
+		var err error
+		if names, err = launcher.Launchers(r.filesDir); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			names = []string{launcher.MainLauncher}
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		i := slices.IndexFunc(*results, func(result *pb.LauncherSignalResult) bool { return result.Name == name })
+		if i < 0 {
+			*results = append(*results, &pb.LauncherSignalResult{Name: name})
+			i = len(*results) - 1
+		}
+		result := (*results)[i]
+		if result.Signalled {
+			continue
+		}
+
+		sig, target := r.sig, r.target
+		if override, ok := r.launchers[name]; ok {
+			sig, target = override.sig, override.target
+		}
+		result.Signal = ReloadSignalNone
+		if sig != launcher.NoSignal {
+			result.Signal = launcher.SignalName(sig)
+		}
+		result.Attempts++
+		pid, err := launcher.SignalNamed(r.filesDir, name, r.finder, sig, target)
+		result.Pid = int32(pid)
+		result.Signalled = err == nil
+		result.NotRunning = errors.Is(err, launcher.ErrNotRunning)
+		result.ErrorMessage = ""
+		if err != nil {
+			result.ErrorMessage = err.Error()
+			if len(names) > 1 {
+				err = fmt.Errorf("launcher %s: %w", name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// httpReloader POSTs the handshake as JSON to an endpoint of the app, which
+// reloads itself. Any 2xx response means the app accepted the reload.
+type httpReloader struct {
+	url    string
+	client *http.Client
+}
+
+func (r *httpReloader) Strategy() string { return ReloadStrategyHTTP }
+
+func (r *httpReloader) Reload(ctx context.Context, hs launcher.Handshake) error {
+	body, err := json.Marshal(hs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal launcher handshake: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create reload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bifrost-Push-Id", hs.PushID)
+	req.Header.Set("X-Bifrost-Reload-Reason", string(hs.Reason))
+
+	log.Info("Calling app reload endpoint", zap.String("url", r.url), zap.String("pushID", hs.PushID))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	output, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeOutputLength))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("reload endpoint returned %s: %s", resp.Status, output)
+	}
+	return nil
+}
+
+// commandReloader runs a shell command in the sidecar container, with the
+// handshake's path and contents in its environment.
+type commandReloader struct {
+	command      string
+	timeout      time.Duration
+	appID        string
+	deploymentID string
+	filesDir     string
+}
+
+func (r *commandReloader) Strategy() string { return ReloadStrategyCommand }
+
+func (r *commandReloader) Reload(ctx context.Context, hs launcher.Handshake) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := execCommand(ctx, "/bin/sh", "-c", r.command)
+	cmd.Dir = r.filesDir
+	// Don't wait indefinitely on children of a killed command that still hold the output pipe.
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = append(os.Environ(),
+		"BIFROST_PUSH_ID="+hs.PushID,
+		"BIFROST_RELOAD_REASON="+string(hs.Reason),
+		"BIFROST_LAUNCHER_HANDSHAKE="+launcher.HandshakePath(r.filesDir),
+		"BIFROST_APP_ID="+r.appID,
+		"BIFROST_DEPLOYMENT_ID="+r.deploymentID,
+		"BIFROST_FILES_DIR="+r.filesDir,
+	)
+
+	log.Info("Running reload command", zap.String("command", r.command))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("reload command timed out after %v", r.timeout)
+		}
+		return fmt.Errorf("reload command failed: %w. Output: %s", err, truncateOutput(output, maxHookOutputLength))
+	}
+	log.Info("Reload command succeeded", zap.String("output", truncateOutput(output, maxHookOutputLength)))
+	return nil
+}
+
+// restartReloader stops the launcher and waits for the app container's restart
+// policy to start a new one, for apps that can only pick up changes from a cold
+// start. The app container must restart on exit, as a pod's containers do.
+type restartReloader struct {
+	filesDir string
+	finder   launcher.ProcessFinder
+	sig      syscall.Signal
+	target   launcher.SignalTarget
+	timeout  time.Duration
+}
+
+func (r *restartReloader) Strategy() string { return ReloadStrategyRestart }
+
+func (r *restartReloader) Reload(ctx context.Context, hs launcher.Handshake) error {
+	_, err := launcher.Restart(r.filesDir, r.finder, r.sig, r.target, r.timeout)
+	return err
+}
+
+// overlapPollInterval is how often an overlapping reload checks whether the new
+// app is ready. It's replaced in tests.
+var overlapPollInterval = 200 * time.Millisecond
+
+// overlapReloader has the launcher start the new app while the old one keeps
+// serving, waits for the new app to create the ready file, and then has the
+// launcher drain the old one. Each step is a handshake with its OverlapPhase
+// followed by the reload signal. The app must be able to share or hand over its
+// listening socket, e.g. with SO_REUSEPORT.
+type overlapReloader struct {
+	filesDir     string
+	finder       launcher.ProcessFinder
+	sig          syscall.Signal
+	target       launcher.SignalTarget
+	timeout      time.Duration
+	drainSignal  string // Without the SIG prefix, as the launcher's kill takes it
+	drainTimeout time.Duration
+}
+
+func (r *overlapReloader) Strategy() string { return ReloadStrategyOverlap }
+
+func (r *overlapReloader) Reload(ctx context.Context, hs launcher.Handshake) error {
+	readyFile := filepath.Join(launcher.Dir(r.filesDir), launcher.ReadyFileName)
+	if err := os.Remove(readyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove ready file: %w", err)
+	}
+	hs.ReadyFile = readyFile
+	hs.DrainSignal = r.drainSignal
+	hs.DrainTimeoutSeconds = int((r.drainTimeout + time.Second - 1) / time.Second)
+
+	if err := r.advance(hs, launcher.OverlapStart); err != nil {
+		return err
+	}
+	if err := waitForFile(ctx, readyFile, r.timeout); err != nil {
+		// The launcher can't be reached or is slow: keep the old app serving.
+		if abortErr := r.advance(hs, launcher.OverlapAbort); abortErr != nil {
+			log.Warn("Failed to abort overlapping reload", zap.Error(abortErr))
+		}
+		return fmt.Errorf("new app process never became ready: %w", err)
+	}
+	log.Info("New app process is ready, draining the old one", zap.String("drainSignal", r.drainSignal))
+	if err := r.advance(hs, launcher.OverlapDrain); err != nil {
+		// Not wrapped: retrying would start yet another app process.
+		return fmt.Errorf("new app process is ready but the old one could not be drained: %v", err)
+	}
+	return nil
+}
+
+// advance tells the launcher to take the next step of the overlap.
+func (r *overlapReloader) advance(hs launcher.Handshake, phase launcher.OverlapPhase) error {
+	hs.OverlapPhase = phase
+	hs.WrittenAt = time.Now().UTC()
+	if err := launcher.WriteHandshake(r.filesDir, hs); err != nil {
+		return err
+	}
+	return launcher.Signal(r.filesDir, r.finder, r.sig, r.target)
+}
+
+// waitForFile polls until path exists, ctx is done or timeout passes.
+func waitForFile(ctx context.Context, path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(overlapPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("%s not created within %v", path, timeout)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
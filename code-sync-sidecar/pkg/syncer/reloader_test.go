@@ -0,0 +1,223 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestNewReloader(t *testing.T) {
+	cfg := DefaultConfig()
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	assert.Equal(t, &signalReloader{filesDir: "/files", finder: finder, sig: syscall.SIGHUP, target: launcher.SignalProcess},
+		newReloader(cfg, "app-1", "dep-1", "/files", finder))
+
+	cfg.Signals.Launchers = map[string]LauncherSignals{"worker": {Target: "tree"}}
+	assert.Equal(t, map[string]launcherSignal{"worker": {sig: syscall.SIGHUP, target: launcher.SignalTree}},
+		newReloader(cfg, "app-1", "dep-1", "/files", finder).(*signalReloader).launchers)
+
+	cfg.Reload.Strategy = ReloadStrategyRestart
+	cfg.Signals.Shutdown = "SIGINT"
+	assert.Equal(t, &restartReloader{filesDir: "/files", finder: finder, sig: syscall.SIGINT, target: launcher.SignalProcess, timeout: DefaultReloadTimeout},
+		newReloader(cfg, "app-1", "dep-1", "/files", finder))
+
+	cfg.Reload.Strategy = ReloadStrategyCommand
+	cfg.Reload.Command = "touch restart.txt"
+	assert.Equal(t, ReloadStrategyCommand, newReloader(cfg, "app-1", "dep-1", "/files", finder).Strategy())
+	cfg.Reload.Strategy = ReloadStrategyHTTP
+	assert.Equal(t, ReloadStrategyHTTP, newReloader(cfg, "app-1", "dep-1", "/files", finder).Strategy())
+}
+
+func TestHTTPReloader(t *testing.T) {
+	var received launcher.Handshake
+	var pushID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		pushID = r.Header.Get("X-Bifrost-Push-Id")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.PushID == "push-2" {
+			http.Error(w, "reload already in progress", http.StatusConflict)
+		}
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.Reload.Strategy = ReloadStrategyHTTP
+	cfg.Reload.URL = server.URL + "/reload"
+	reloader := newReloader(cfg, "app-1", "dep-1", t.TempDir(), nil)
+
+	hs := launcher.NewHandshake("push-1", launcher.ReloadReasonPush, nil, nil)
+	require.NoError(t, reloader.Reload(context.Background(), hs))
+	assert.Equal(t, "push-1", pushID)
+	assert.Equal(t, launcher.ReloadReasonPush, received.Reason)
+
+	err := reloader.Reload(context.Background(), launcher.NewHandshake("push-2", launcher.ReloadReasonPush, nil, nil))
+	assert.ErrorContains(t, err, "reload endpoint returned 409 Conflict: reload already in progress")
+
+	// An app that isn't listening is retried.
+	server.Close()
+	err = reloader.Reload(context.Background(), hs)
+	require.Error(t, err)
+	assert.True(t, isTransientReloadError(err))
+}
+
+func TestCommandReloader(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Reload.Strategy = ReloadStrategyCommand
+	cfg.Reload.Command = `echo "$BIFROST_PUSH_ID $BIFROST_RELOAD_REASON $BIFROST_LAUNCHER_HANDSHAKE" > reloaded`
+	reloader := newReloader(cfg, "app-1", "dep-1", dir, nil)
+
+	require.NoError(t, reloader.Reload(context.Background(), launcher.NewHandshake("push-1", launcher.ReloadReasonDatabaseUpdate, nil, nil)))
+	data, err := os.ReadFile(filepath.Join(dir, "reloaded"))
+	require.NoError(t, err)
+	assert.Equal(t, "push-1 database_update "+launcher.HandshakePath(dir)+"\n", string(data))
+
+	cfg.Reload.Command = "echo 'no server to reload' >&2; exit 3"
+	err = newReloader(cfg, "app-1", "dep-1", dir, nil).Reload(context.Background(), launcher.Handshake{})
+	assert.ErrorContains(t, err, "reload command failed: exit status 3. Output: no server to reload")
+
+	cfg.Reload.Command = "sleep 5"
+	cfg.Reload.Timeout = Duration(50 * time.Millisecond)
+	err = newReloader(cfg, "app-1", "dep-1", dir, nil).Reload(context.Background(), launcher.Handshake{})
+	assert.ErrorContains(t, err, "reload command timed out after 50ms")
+}
+
+func TestHandlePushRequest_ReloadStrategy(t *testing.T) {
+	var reloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reloads = append(reloads, r.Header.Get("X-Bifrost-Push-Id"))
+		if len(reloads) > 1 {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	cfg := DefaultConfig()
+	cfg.Reload.Strategy = ReloadStrategyHTTP
+	cfg.Reload.URL = server.URL
+	rw.reloader = newReloader(cfg, "app-1", "dep-1", rw.targetSyncDir, rw.processFinder)
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.Equal(t, []string{"push-1"}, reloads)
+	assert.Empty(t, rw.processFinder.(*mockProcessFinder).processes, "the launcher isn't signalled")
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")})
+	assert.ErrorContains(t, err, "failed to reload the app")
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.True(t, strings.HasPrefix(resp.GetErrorMessage(), "Failed to reload the app (http): reload endpoint returned 500"), resp.GetErrorMessage())
+}
+
+func TestHandlePushRequest_MultipleLaunchers(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.retry = retryPolicy{attempts: 2, backoff: time.Millisecond}
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(rw.targetSyncDir), "worker.pid"), []byte("200\n"), 0644))
+	finder := rw.processFinder.(*mockProcessFinder)
+	finder.processes[200] = &mockProcess{signalErr: syscall.ESRCH}
+	cfg := DefaultConfig()
+	cfg.Signals.Launchers = map[string]LauncherSignals{"worker": {Reload: "SIGQUIT"}}
+	rw.reloader = newReloader(cfg, "app-1", "dep-1", rw.targetSyncDir, rw.processFinder)
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.ErrorIs(t, err, launcher.ErrNotRunning)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_LAUNCHER_NOT_RUNNING, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "launcher worker: launcher is not running")
+	require.Len(t, resp.GetLauncherResults(), 2)
+	assert.Equal(t, &pb.LauncherSignalResult{Name: launcher.MainLauncher, Pid: 12345, Signal: "SIGHUP", Signalled: true, Attempts: 1},
+		resp.GetLauncherResults()[0], "the main launcher isn't signalled again by the retry")
+	worker := resp.GetLauncherResults()[1]
+	assert.Equal(t, "worker", worker.GetName())
+	assert.Equal(t, int32(200), worker.GetPid())
+	assert.Equal(t, "SIGQUIT", worker.GetSignal())
+	assert.True(t, worker.GetNotRunning())
+	assert.Equal(t, int32(2), worker.GetAttempts())
+	assert.Equal(t, []syscall.Signal{syscall.SIGHUP}, finder.processes[12345].signalCalls)
+
+	finder.processes[200] = &mockProcess{}
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	require.Len(t, resp.GetLauncherResults(), 2)
+	assert.True(t, resp.GetLauncherResults()[1].GetSignalled())
+	assert.Equal(t, []syscall.Signal{syscall.SIGQUIT}, finder.processes[200].signalCalls)
+}
+
+// overlapLauncher plays the launcher script's side of an overlapping reload:
+// it records the phase of each handshake it's signalled with and, if ready,
+// has the new app create the ready file once started.
+type overlapLauncher struct {
+	t        *testing.T
+	filesDir string
+	ready    bool
+	phases   []launcher.OverlapPhase
+}
+
+func (l *overlapLauncher) FindProcess(int) (launcher.ProcessSignaler, error) { return l, nil }
+
+func (l *overlapLauncher) Signal(sig syscall.Signal) error {
+	if sig == 0 {
+		return nil // Liveness probe
+	}
+	data, err := os.ReadFile(launcher.HandshakePath(l.filesDir))
+	require.NoError(l.t, err)
+	var hs launcher.Handshake
+	require.NoError(l.t, json.Unmarshal(data, &hs))
+	l.phases = append(l.phases, hs.OverlapPhase)
+	if hs.OverlapPhase == launcher.OverlapStart && l.ready {
+		require.NoError(l.t, os.WriteFile(hs.ReadyFile, nil, 0644))
+	}
+	return nil
+}
+
+func TestOverlapReloader(t *testing.T) {
+	originalInterval := overlapPollInterval
+	overlapPollInterval = time.Millisecond
+	t.Cleanup(func() { overlapPollInterval = originalInterval })
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	// A ready file left from the last reload doesn't count.
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), launcher.ReadyFileName), nil, 0644))
+
+	cfg := DefaultConfig()
+	cfg.Reload.Strategy = ReloadStrategyOverlap
+	cfg.Reload.Timeout = Duration(50 * time.Millisecond)
+	cfg.Reload.DrainSignal = "sigquit"
+	cfg.Reload.DrainTimeout = Duration(1500 * time.Millisecond)
+	ready := &overlapLauncher{t: t, filesDir: dir, ready: true}
+	require.NoError(t, newReloader(cfg, "app-1", "dep-1", dir, ready).Reload(context.Background(), launcher.NewHandshake("push-1", launcher.ReloadReasonPush, nil, nil)))
+	assert.Equal(t, []launcher.OverlapPhase{launcher.OverlapStart, launcher.OverlapDrain}, ready.phases)
+
+	data, err := os.ReadFile(launcher.HandshakePath(dir))
+	require.NoError(t, err)
+	var hs launcher.Handshake
+	require.NoError(t, json.Unmarshal(data, &hs))
+	assert.Equal(t, "push-1", hs.PushID)
+	assert.Equal(t, "QUIT", hs.DrainSignal)
+	assert.Equal(t, 2, hs.DrainTimeoutSeconds, "rounded up to whole seconds for the launcher")
+
+	// A new app that never becomes ready is stopped and the old one kept.
+	require.NoError(t, os.Remove(filepath.Join(launcher.Dir(dir), launcher.ReadyFileName)))
+	stuck := &overlapLauncher{t: t, filesDir: dir}
+	err = newReloader(cfg, "app-1", "dep-1", dir, stuck).Reload(context.Background(), launcher.NewHandshake("push-2", launcher.ReloadReasonPush, nil, nil))
+	assert.ErrorContains(t, err, "new app process never became ready")
+	assert.Equal(t, []launcher.OverlapPhase{launcher.OverlapStart, launcher.OverlapAbort}, stuck.phases)
+}
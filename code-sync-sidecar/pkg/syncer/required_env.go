@@ -0,0 +1,98 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// missingEnvError lists the env vars a push requires that the app's env
+// wouldn't have, or would have empty.
+type missingEnvError struct {
+	names []string
+}
+
+func (e *missingEnvError) Error() string {
+	return fmt.Sprintf("required env vars missing or empty: %s", strings.Join(e.names, ", "))
+}
+
+// validateRequiredEnv checks the names in a push's required_env are env var names.
+func validateRequiredEnv(names []string) error {
+	for _, name := range names {
+		if err := envfile.ValidateEnvVarName(name); err != nil {
+			return fmt.Errorf("invalid required env var: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkRequiredEnv checks each required var is set and non-empty in the env the
+// app is started with: the container's env, which the launcher records the
+// names of, with the unscoped database env vars sourced on top of it. Vars
+// that aren't database env vars can't be checked if the launcher hasn't
+// recorded the container's env; they're logged and let through.
+func (rw *FileSyncer) checkRequiredEnv(required []string, envVars []envfile.DatabaseEnvVar) error {
+	if len(required) == 0 {
+		return nil
+	}
+	database := make(map[string]string, len(envVars))
+	for _, envVar := range envVars {
+		if envVar.Scope == "" {
+			database[envVar.EnvVarName] = envVar.ConnectionURI
+		}
+	}
+	container, err := launcher.ContainerEnvNames(rw.targetSyncDir)
+	if err != nil {
+		log.Warn("Can't read the container env names the launcher recorded", zap.Error(err))
+	}
+
+	var missing, unverified []string
+	for _, name := range required {
+		if value, ok := database[name]; ok {
+			if value == "" {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		switch {
+		case container == nil:
+			unverified = append(unverified, name)
+		case !container[name]:
+			missing = append(missing, name)
+		}
+	}
+	if len(unverified) > 0 {
+		log.Warn("Skipping required env vars that can't be checked without the container env names",
+			zap.Strings("names", unverified))
+	}
+	if len(missing) > 0 {
+		return &missingEnvError{names: missing}
+	}
+	return nil
+}
+
+// verifyRequiredEnv fetches the current database env vars and checks required
+// against them, for pushes that don't refresh the env file themselves.
+func (rw *FileSyncer) verifyRequiredEnv(ctx context.Context, required []string) error {
+	if len(required) == 0 {
+		return nil
+	}
+	envVars, err := envfile.FetchDatabaseEnvVars(ctx, rw.getDatabaseEnvProvider(), rw.getEnvFileOptions().Secrets)
+	if err != nil {
+		return fmt.Errorf("failed to fetch env vars to check required env: %w", err)
+	}
+	return rw.checkRequiredEnv(required, envVars)
+}
+
+// withMissingEnv attaches the missing required env vars to a push response message.
+func withMissingEnv(msg *pb.WebsocketMessage, err *missingEnvError) *pb.WebsocketMessage {
+	msg.GetPushResponse().MissingEnv = err.names
+	return msg
+}
@@ -0,0 +1,61 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHandlePushRequest_MissingEnv(t *testing.T) {
+	rw, mockServer := newTemplateTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	envNames := filepath.Join(launcher.Dir(rw.targetSyncDir), "env_names")
+	require.NoError(t, os.WriteFile(envNames, []byte("PATH\nSECRET_KEY\n"), 0644))
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), RequiredEnv: []string{"DATABASE_URL", "SECRET_KEY", "API_TOKEN", "QUEUE_URL"},
+	})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_MISSING_ENV, resp.GetStatus())
+	assert.Equal(t, []string{"API_TOKEN", "QUEUE_URL"}, resp.GetMissingEnv(), "scoped database env vars don't count")
+	assert.NoFileExists(t, argsFile, "the batch isn't applied")
+
+	require.NoError(t, os.WriteFile(envNames, []byte("PATH\nSECRET_KEY\nAPI_TOKEN\nQUEUE_URL\n"), 0644))
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch"), RequiredEnv: []string{"DATABASE_URL", "SECRET_KEY", "API_TOKEN", "QUEUE_URL"},
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+
+	err = rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-3", BatchFile: []byte("batch-3"), RequiredEnv: []string{"NOT-A-NAME"},
+	})
+	assert.Error(t, err)
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "invalid required env var")
+}
+
+func TestCheckRequiredEnv(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	envVars := []envfile.DatabaseEnvVar{
+		{EnvVarName: "DATABASE_URL", ConnectionURI: "postgres://db/app"},
+		{EnvVarName: "CACHE_URL", ConnectionURI: ""},
+	}
+
+	// Without the launcher's record, only the database env vars are checked.
+	assert.NoError(t, rw.checkRequiredEnv([]string{"DATABASE_URL", "SECRET_KEY"}, envVars))
+	err := rw.checkRequiredEnv([]string{"DATABASE_URL", "CACHE_URL"}, envVars)
+	var missing *missingEnvError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, []string{"CACHE_URL"}, missing.names, "an empty database env var overrides the container's")
+}
@@ -0,0 +1,184 @@
+package syncer
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// I/O scheduling classes for resources.rsync_io_class.
+const (
+	// IOClassDefault leaves rsync's I/O priority unchanged.
+	IOClassDefault = ""
+	// IOClassBestEffort runs rsync at the lowest best-effort I/O priority.
+	IOClassBestEffort = "best-effort"
+	// IOClassIdle lets rsync use the disk only when no other process does.
+	IOClassIdle = "idle"
+)
+
+// ioprio_set(2) constants; the syscall package doesn't define them.
+const (
+	ioprioClassShift    = 13
+	ioprioClassBE       = 2
+	ioprioClassIdle     = 3
+	ioprioLowestBELevel = 7
+)
+
+// cgroupRoot is where the sidecar's cgroup is mounted; replaced in tests.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupMemoryLimitShare is the part of the cgroup's memory limit used as the
+// sidecar's soft limit when resources.memory_limit isn't set, leaving the
+// garbage collector headroom before the kernel's OOM killer steps in.
+const cgroupMemoryLimitShare = 0.9
+
+// resourceLimits are the limits from ResourcesConfig, resolved against the
+// sidecar's cgroup.
+type resourceLimits struct {
+	rsyncNice    int
+	rsyncIOClass string
+	// memoryLimit is the sidecar's soft memory limit; 0 if none.
+	memoryLimit int64
+	// batchBudget bounds the batches held in memory at once; bigger ones are
+	// spooled to disk.
+	batchBudget int64
+}
+
+func (c *Config) resourceLimits() resourceLimits {
+	limits := resourceLimits{
+		rsyncNice:    c.Resources.RsyncNice,
+		rsyncIOClass: c.Resources.RsyncIOClass,
+		memoryLimit:  int64(c.Resources.MemoryLimit),
+		batchBudget:  maxBatchStreams * maxStreamedBatchSize,
+	}
+	if limits.memoryLimit == 0 && os.Getenv("GOMEMLIMIT") == "" {
+		if _, cgroupLimit, ok := cgroupMemory(); ok && cgroupLimit > 0 {
+			limits.memoryLimit = int64(float64(cgroupLimit) * cgroupMemoryLimitShare)
+		}
+	}
+	switch {
+	case c.Resources.BatchMemoryBuffer > 0:
+		limits.batchBudget = int64(c.Resources.BatchMemoryBuffer)
+	case limits.memoryLimit > 0:
+		limits.batchBudget = min(limits.batchBudget, limits.memoryLimit/2)
+	}
+	return limits
+}
+
+// applyMemoryLimit sets the Go runtime's soft memory limit. Without one, a
+// limit set through GOMEMLIMIT is left alone.
+func (l resourceLimits) applyMemoryLimit() {
+	if l.memoryLimit > 0 {
+		debug.SetMemoryLimit(l.memoryLimit)
+	} else if os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(math.MaxInt64)
+	}
+}
+
+// throttleRsync lowers the CPU and I/O priority of the process group pgid,
+// which rsync leads. Failing to is logged; rsync then runs at full priority.
+func (l resourceLimits) throttleRsync(pgid int) {
+	if l.rsyncNice > 0 {
+		if err := setGroupNice(pgid, l.rsyncNice); err != nil {
+			log.Warn("Failed to lower rsync's CPU priority", zap.Int("nice", l.rsyncNice), zap.Error(err))
+		}
+	}
+	var ioprio int
+	switch l.rsyncIOClass {
+	case IOClassBestEffort:
+		ioprio = ioprioClassBE<<ioprioClassShift | ioprioLowestBELevel
+	case IOClassIdle:
+		ioprio = ioprioClassIdle << ioprioClassShift
+	default:
+		return
+	}
+	if err := setGroupIOPriority(pgid, ioprio); err != nil {
+		log.Warn("Failed to lower rsync's I/O priority", zap.String("class", l.rsyncIOClass), zap.Error(err))
+	}
+}
+
+// cgroupMemory returns the memory used by the sidecar's cgroup and its limit,
+// 0 if unlimited, from cgroup v2 or else v1. ok is false without either.
+func cgroupMemory() (usage, limit int64, ok bool) {
+	if usage, ok := readCgroupInt(filepath.Join(cgroupRoot, "memory.current")); ok {
+		limit, _ := readCgroupInt(filepath.Join(cgroupRoot, "memory.max")) // "max" when unlimited
+		return usage, limit, true
+	}
+	v1 := filepath.Join(cgroupRoot, "memory")
+	if usage, ok := readCgroupInt(filepath.Join(v1, "memory.usage_in_bytes")); ok {
+		limit, _ := readCgroupInt(filepath.Join(v1, "memory.limit_in_bytes"))
+		if limit >= math.MaxInt64/2 {
+			limit = 0 // v1 reports no limit as a page-rounded MaxInt64
+		}
+		return usage, limit, true
+	}
+	return 0, 0, false
+}
+
+func readCgroupInt(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n, err == nil
+}
+
+// readResourceUsage reports what the sidecar process uses.
+func (rw *FileSyncer) readResourceUsage() *pb.ResourceUsage {
+	limits := rw.getResourceLimits()
+	usage := &pb.ResourceUsage{
+		Goroutines:              int32(runtime.NumGoroutine()),
+		BufferedBatchBytes:      rw.batches.bufferedBytes(),
+		BufferedBatchLimitBytes: limits.batchBudget,
+		MemoryLimitBytes:        limits.memoryLimit,
+	}
+	if rss, err := processRSS(); err == nil {
+		usage.RssBytes = rss
+	} else {
+		log.Debug("Failed to read the sidecar's memory use", zap.Error(err))
+	}
+	if cpuMillis, err := processCPUMillis(); err == nil {
+		usage.CpuMillis = cpuMillis
+	}
+	if cgroupUsage, cgroupLimit, ok := cgroupMemory(); ok {
+		usage.CgroupMemoryBytes, usage.CgroupMemoryLimitBytes = cgroupUsage, cgroupLimit
+	}
+	return usage
+}
+
+// processRSS reads the sidecar's resident memory from /proc/self/status.
+func processRSS() (int64, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid VmRSS %q: %w", value, err)
+		}
+		return kb << 10, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no VmRSS in /proc/self/status")
+}
@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setCgroupRoot(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	original := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = original })
+}
+
+func TestCgroupMemory(t *testing.T) {
+	setCgroupRoot(t, map[string]string{"memory.current": "1000\n", "memory.max": "max\n"})
+	usage, limit, ok := cgroupMemory()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1000), usage)
+	assert.Equal(t, int64(0), limit, "unlimited")
+
+	setCgroupRoot(t, map[string]string{"memory/memory.usage_in_bytes": "2000\n", "memory/memory.limit_in_bytes": "9223372036854771712\n"})
+	usage, limit, ok = cgroupMemory()
+	assert.True(t, ok)
+	assert.Equal(t, int64(2000), usage)
+	assert.Equal(t, int64(0), limit, "v1's unlimited")
+
+	setCgroupRoot(t, nil)
+	_, _, ok = cgroupMemory()
+	assert.False(t, ok)
+}
+
+func TestConfigResourceLimits(t *testing.T) {
+	t.Setenv("GOMEMLIMIT", "")
+	setCgroupRoot(t, map[string]string{"memory.current": "1000", "memory.max": "1000000000"})
+	cfg := DefaultConfig()
+
+	limits := cfg.resourceLimits()
+	assert.Equal(t, int64(900_000_000), limits.memoryLimit, "90% of the cgroup's limit")
+	assert.Equal(t, int64(450_000_000), limits.batchBudget)
+
+	cfg.Resources.MemoryLimit = 100 << 20
+	limits = cfg.resourceLimits()
+	assert.Equal(t, int64(100<<20), limits.memoryLimit)
+	assert.Equal(t, int64(50<<20), limits.batchBudget)
+
+	cfg.Resources.BatchMemoryBuffer = 16 << 20
+	limits = cfg.resourceLimits()
+	assert.Equal(t, int64(16<<20), limits.batchBudget)
+	cfg.Resources.BatchMemoryBuffer = 0
+
+	setCgroupRoot(t, nil)
+	cfg.Resources.MemoryLimit = 0
+	limits = cfg.resourceLimits()
+	assert.Equal(t, int64(0), limits.memoryLimit)
+	assert.Equal(t, int64(maxBatchStreams*maxStreamedBatchSize), limits.batchBudget)
+}
+
+func TestThrottleRsync(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	resourceLimits{rsyncNice: 10, rsyncIOClass: IOClassIdle}.throttleRsync(cmd.Process.Pid)
+
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(cmd.Process.Pid), "stat"))
+	require.NoError(t, err)
+	fields := strings.Fields(string(stat)[strings.LastIndexByte(string(stat), ')')+1:])
+	assert.Equal(t, "10", fields[16], "nice")
+}
+
+func TestReadResourceUsage(t *testing.T) {
+	setCgroupRoot(t, map[string]string{"memory.current": "5000", "memory.max": "max"})
+	rw, _ := newRsyncOptionsTestSyncer(t)
+	rw.resources = resourceLimits{memoryLimit: 1 << 30, batchBudget: 1 << 29}
+
+	usage := rw.buildStatusReport().GetStatusReport().GetResources()
+	assert.Positive(t, usage.GetRssBytes())
+	assert.Positive(t, usage.GetGoroutines())
+	assert.Equal(t, int64(1<<30), usage.GetMemoryLimitBytes())
+	assert.Equal(t, int64(1<<29), usage.GetBufferedBatchLimitBytes())
+	assert.Equal(t, int64(5000), usage.GetCgroupMemoryBytes())
+	assert.Equal(t, int64(0), usage.GetCgroupMemoryLimitBytes())
+}
@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// recordPushResponse keeps a push's final response, so it can be replayed if
+// the connection dropped before the server received it.
+func (rw *FileSyncer) recordPushResponse(resp *pb.PushResponse) {
+	if resp == nil || resp.PushId == "" || resp.Replayed || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		log.Warn("Failed to record push response", zap.String("pushID", resp.PushId), zap.Error(err))
+		return
+	}
+
+	rw.stateMu.Lock()
+	responses := slices.DeleteFunc(rw.applied.RecentResponses, func(r RecordedResponse) bool {
+		return r.PushID == resp.PushId
+	})
+	responses = append(responses, RecordedResponse{PushID: resp.PushId, Response: data})
+	if extra := len(responses) - maxRecentPushIDs; extra > 0 {
+		responses = slices.Clone(responses[extra:])
+	}
+	rw.applied.RecentResponses = responses
+	rw.stateMu.Unlock()
+
+	if err := rw.saveState(); err != nil {
+		log.Warn("Failed to persist sidecar state", zap.String("pushID", resp.PushId), zap.Error(err))
+	}
+	rw.notifyStatusChanged()
+}
+
+// recordResumeToken remembers the token the server issued for this session.
+func (rw *FileSyncer) recordResumeToken(token string) {
+	rw.stateMu.Lock()
+	if token == "" || token == rw.applied.ResumeToken {
+		rw.stateMu.Unlock()
+		return
+	}
+	rw.applied.ResumeToken = token
+	rw.stateMu.Unlock()
+
+	if err := rw.saveState(); err != nil {
+		log.Warn("Failed to persist sidecar state", zap.Error(err))
+	}
+}
+
+// replayPushResponses resends the final responses of pushes the server says it
+// never got an answer for.
+func (rw *FileSyncer) replayPushResponses(pushIDs []string) {
+	for _, pushID := range pushIDs {
+		if rw.outbox.wasFlushed(pushID) {
+			// Sent from the outbox after reconnecting, just before the server's answer to HELLO.
+			continue
+		}
+		msg := rw.replayedPushResponse(pushID)
+		if msg == nil {
+			continue
+		}
+		log.Info("Replaying push response",
+			zap.String("pushID", pushID),
+			zap.String("status", msg.GetPushResponse().GetStatus().String()))
+		rw.sendProtoMessage(msg)
+	}
+}
+
+// replayedPushResponse returns the response to replay for a push, or nil if the
+// push is still queued or running and will answer on its own.
+func (rw *FileSyncer) replayedPushResponse(pushID string) *pb.WebsocketMessage {
+	rw.stateMu.Lock()
+	var recorded []byte
+	for _, r := range rw.applied.RecentResponses {
+		if r.PushID == pushID {
+			recorded = r.Response
+		}
+	}
+	applied := rw.applied.hasApplied(pushID)
+	rw.stateMu.Unlock()
+
+	if recorded != nil {
+		resp := &pb.PushResponse{}
+		err := proto.Unmarshal(recorded, resp)
+		if err == nil {
+			resp.Replayed = true
+			return &pb.WebsocketMessage{
+				MessageType: pb.WebsocketMessage_PUSH_RESPONSE,
+				Message:     &pb.WebsocketMessage_PushResponse{PushResponse: resp},
+			}
+		}
+		log.Warn("Failed to decode recorded push response", zap.String("pushID", pushID), zap.Error(err))
+	}
+
+	q := &rw.pushes
+	q.mu.Lock()
+	_, queued := q.queued[pushID]
+	inProgress := queued || q.activeID == pushID
+	q.mu.Unlock()
+	if inProgress {
+		return nil
+	}
+
+	var msg *pb.WebsocketMessage
+	if applied {
+		msg = buildPushResponse(pushID, pb.PushResponse_COMPLETED, "")
+		msg.GetPushResponse().AlreadyApplied = true
+	} else {
+		msg = buildPushResponse(pushID, pb.PushResponse_FAILED, "Push was never received by the sidecar")
+	}
+	msg.GetPushResponse().Replayed = true
+	return msg
+}
@@ -0,0 +1,56 @@
+package syncer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestReplayedPushResponse(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+	rw := &FileSyncer{targetSyncDir: filesDir}
+
+	// Final responses are recorded as they are sent, even with no connection.
+	assert.Error(t, rw.trySendProtoMessage(buildPushResponse("push-1", pb.PushResponse_RECEIVED, "")))
+	assert.Error(t, rw.trySendProtoMessage(buildPushResponse("push-1", pb.PushResponse_CONFLICT, "files changed")))
+	rw.recordApplied("push-2", "")
+	rw.pushes.activeID = "push-3"
+
+	state, err := loadSidecarState(filesDir)
+	require.NoError(t, err)
+	require.Len(t, state.RecentResponses, 1, "intermediate statuses aren't recorded")
+
+	replayed := rw.replayedPushResponse("push-1").GetPushResponse()
+	assert.Equal(t, pb.PushResponse_CONFLICT, replayed.GetStatus())
+	assert.Equal(t, "files changed", replayed.GetErrorMessage())
+	assert.True(t, replayed.GetReplayed())
+
+	replayed = rw.replayedPushResponse("push-2").GetPushResponse()
+	assert.Equal(t, pb.PushResponse_COMPLETED, replayed.GetStatus())
+	assert.True(t, replayed.GetAlreadyApplied())
+
+	assert.Nil(t, rw.replayedPushResponse("push-3"), "a running push answers on its own")
+
+	replayed = rw.replayedPushResponse("push-4").GetPushResponse()
+	assert.Equal(t, pb.PushResponse_FAILED, replayed.GetStatus())
+	assert.True(t, replayed.GetReplayed())
+}
+
+func TestHandleHelloAck_StoresResumeToken(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+	rw := &FileSyncer{targetSyncDir: filesDir}
+
+	require.NoError(t, rw.handleHelloAck(&pb.HelloAck{ProtocolVersion: 1, ResumeToken: "token-1"}))
+	assert.Equal(t, "token-1", rw.buildHello().GetHello().GetResumeToken())
+
+	state, err := loadSidecarState(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", state.ResumeToken, "the token survives a sidecar restart")
+}
@@ -0,0 +1,168 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// maxDriftedFiles bounds how many paths a RESYNC_REQUEST lists.
+const maxDriftedFiles = 1000
+
+// checkSidecarState flags a resync at startup if the sidecar's state couldn't
+// be read, or is missing although the files directory has files in it, as
+// after the .sidecar directory was deleted. loadErr is from loadSidecarState.
+func (rw *FileSyncer) checkSidecarState(loadErr error) {
+	if loadErr != nil {
+		rw.flagResync(pb.ResyncRequest_CORRUPTION, loadErr.Error())
+		return
+	}
+	if _, err := os.Stat(getStatePath(rw.targetSyncDir)); !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	dir, err := rw.contentDir()
+	if err != nil {
+		return
+	}
+	files, err := listRegularFiles(dir)
+	if err != nil || len(files) == 0 {
+		return // Nothing synced yet
+	}
+	rw.flagResync(pb.ResyncRequest_MISSING_STATE,
+		fmt.Sprintf("%s has %d files but no record of the pushes that wrote them", dir, len(files)))
+}
+
+// flagResync records a problem to report in the next RESYNC_REQUEST.
+func (rw *FileSyncer) flagResync(reason pb.ResyncRequest_Reason, detail string) {
+	log.Warn("Synced files need a full resync", zap.Stringer("reason", reason), zap.String("detail", detail))
+	rw.stateMu.Lock()
+	rw.pendingResync = &pb.ResyncRequest{Reason: reason, Detail: detail}
+	rw.stateMu.Unlock()
+}
+
+// requestResync asks the control plane for a full resync because of a problem
+// found while the sidecar is running. The request is sent once any push being
+// applied has finished, or on the next connection if the sidecar is
+// disconnected, and stands until a mirror push is applied. Until then a push of
+// the last batch is applied again rather than skipped.
+func (rw *FileSyncer) requestResync(reason pb.ResyncRequest_Reason, detail string) {
+	rw.flagResync(reason, detail)
+	rw.forgetLastPushHash()
+	go rw.sendResyncRequest()
+}
+
+// clearResync drops a flagged problem once a mirror push has replaced the
+// synced files.
+func (rw *FileSyncer) clearResync() {
+	rw.stateMu.Lock()
+	rw.pendingResync = nil
+	rw.lastResync = ""
+	rw.stateMu.Unlock()
+}
+
+// rollBack restores the files a failed push changed. If that fails too, the
+// files are a mix of both pushes and only a full resync fixes them.
+func (rw *FileSyncer) rollBack(pushID string, backup *syncBackup) {
+	if err := backup.restore(); err != nil {
+		log.Error("Failed to roll back the batch", zap.String("pushID", pushID), zap.Error(err))
+		rw.requestResync(pb.ResyncRequest_CORRUPTION, fmt.Sprintf("failed to roll back push %s: %v", pushID, err))
+	}
+}
+
+// sendResyncRequest sends a RESYNC_REQUEST if a problem was flagged or the
+// synced files have drifted from the manifest. It runs on every connection;
+// a request identical to the last one sent isn't repeated.
+func (rw *FileSyncer) sendResyncRequest() {
+	rw.resyncMu.Lock()
+	defer rw.resyncMu.Unlock()
+
+	req, err := rw.buildResyncRequest()
+	if err != nil {
+		log.Warn("Failed to check whether the synced files need a resync", zap.Error(err))
+		return
+	}
+	if req == nil {
+		return
+	}
+	if req.Reason == pb.ResyncRequest_DRIFT {
+		rw.emitDriftWebhook(req)
+	}
+	key := req.Reason.String() + " " + req.WorkspaceHash
+	rw.stateMu.Lock()
+	sent := rw.lastResync == key
+	rw.stateMu.Unlock()
+	if sent {
+		return
+	}
+	if err := rw.trySendProtoMessage(&pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_RESYNC_REQUEST,
+		Message:     &pb.WebsocketMessage_ResyncRequest{ResyncRequest: req},
+	}); err != nil {
+		log.Info("Resync request will be sent on reconnect", zap.Error(err))
+		return
+	}
+	log.Info("Requested a full resync", zap.Stringer("reason", req.Reason), zap.String("workspaceHash", req.WorkspaceHash))
+	rw.stateMu.Lock()
+	rw.lastResync = key
+	rw.stateMu.Unlock()
+}
+
+// buildResyncRequest returns the RESYNC_REQUEST to send, or nil if the synced
+// files can still be trusted. It holds workspaceMu so a push being applied
+// isn't mistaken for drift.
+func (rw *FileSyncer) buildResyncRequest() (*pb.ResyncRequest, error) {
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	rw.stateMu.Lock()
+	var req *pb.ResyncRequest
+	if rw.pendingResync != nil {
+		req = proto.Clone(rw.pendingResync).(*pb.ResyncRequest)
+	}
+	lastPushID := rw.applied.LastPushID
+	rw.stateMu.Unlock()
+
+	dir, err := rw.contentDir()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := loadManifest(rw.targetSyncDir)
+	if err != nil {
+		if req == nil {
+			req = &pb.ResyncRequest{Reason: pb.ResyncRequest_CORRUPTION, Detail: err.Error()}
+		}
+		manifest = fileManifest{}
+	} else if req == nil {
+		drifted, err := manifest.findConflicts(dir, slices.Collect(maps.Keys(manifest)))
+		if err != nil {
+			return nil, err
+		}
+		if len(drifted) > 0 {
+			req = &pb.ResyncRequest{
+				Reason: pb.ResyncRequest_DRIFT,
+				Detail: fmt.Sprintf("%d synced files were changed or removed in the deployment: %s",
+					len(drifted), summarizePaths(drifted, maxPathsInMessage)),
+				DriftedFiles: drifted[:min(len(drifted), maxDriftedFiles)],
+			}
+		}
+	}
+	if req == nil {
+		return nil, nil
+	}
+	files, err := inventoryFiles(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	req.WorkspaceHash = workspaceHash(files)
+	req.LastPushId = lastPushID
+	return req, nil
+}
@@ -0,0 +1,117 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func waitForResyncRequest(t *testing.T, mockServer *mockWebsocketServer) *pb.ResyncRequest {
+	t.Helper()
+	for {
+		select {
+		case message := <-mockServer.messages:
+			var wsMessage pb.WebsocketMessage
+			require.NoError(t, proto.Unmarshal(message, &wsMessage))
+			if wsMessage.MessageType == pb.WebsocketMessage_RESYNC_REQUEST {
+				return wsMessage.GetResyncRequest()
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for resync request")
+			return nil
+		}
+	}
+}
+
+func TestCheckSidecarState(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(rw.targetSyncDir), 0755))
+	rw.checkSidecarState(nil)
+	assert.Nil(t, rw.pendingResync, "nothing synced yet")
+
+	require.NoError(t, os.WriteFile(filepath.Join(rw.targetSyncDir, "main.go"), []byte("package main"), 0644))
+	rw.checkSidecarState(nil)
+	assert.Equal(t, pb.ResyncRequest_MISSING_STATE, rw.pendingResync.GetReason())
+	assert.Contains(t, rw.pendingResync.GetDetail(), "has 1 files but no record of the pushes that wrote them")
+
+	rw.pendingResync = nil
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+	rw.checkSidecarState(nil)
+	assert.Nil(t, rw.pendingResync)
+
+	require.NoError(t, os.WriteFile(getStatePath(rw.targetSyncDir), []byte("{"), 0644))
+	_, err := loadSidecarState(rw.targetSyncDir)
+	rw.checkSidecarState(err)
+	assert.Equal(t, pb.ResyncRequest_CORRUPTION, rw.pendingResync.GetReason())
+	assert.Contains(t, rw.pendingResync.GetDetail(), "failed to parse state file")
+}
+
+func TestSendResyncRequest_Drift(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(rw.targetSyncDir), 0755))
+	for _, name := range []string{"main.go", "util.go", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(rw.targetSyncDir, name), []byte(name), 0644))
+	}
+	manifest := fileManifest{}
+	require.NoError(t, manifest.record(rw.targetSyncDir, []string{"main.go", "util.go", "README.md"}))
+	require.NoError(t, saveManifest(rw.targetSyncDir, manifest))
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+
+	// No drift, nothing to ask for.
+	rw.sendResyncRequest()
+	assert.Empty(t, rw.lastResync)
+
+	require.NoError(t, os.WriteFile(filepath.Join(rw.targetSyncDir, "main.go"), []byte("edited in the pod"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(rw.targetSyncDir, "util.go")))
+	rw.sendResyncRequest()
+	req := waitForResyncRequest(t, mockServer)
+	assert.Equal(t, pb.ResyncRequest_DRIFT, req.GetReason())
+	assert.Equal(t, []string{"main.go", "util.go"}, req.GetDriftedFiles())
+	assert.Equal(t, "push-1", req.GetLastPushId())
+	files, err := rw.workspaceInventory()
+	require.NoError(t, err)
+	assert.Equal(t, workspaceHash(files), req.GetWorkspaceHash())
+
+	// Reconnecting with the same drift doesn't ask again.
+	rw.sendResyncRequest()
+	select {
+	case <-mockServer.messages:
+		t.Fatal("resync request was repeated")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// An unreadable manifest can't be checked for drift.
+	require.NoError(t, os.WriteFile(getManifestPath(rw.targetSyncDir), []byte("{"), 0644))
+	rw.sendResyncRequest()
+	req = waitForResyncRequest(t, mockServer)
+	assert.Equal(t, pb.ResyncRequest_CORRUPTION, req.GetReason())
+	assert.Contains(t, req.GetDetail(), "failed to parse manifest")
+}
+
+func TestRollBack_RequestsResync(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+
+	rw.rollBack("push-2", &syncBackup{targetDir: rw.targetSyncDir, dir: filepath.Join(rw.targetSyncDir, "missing")})
+	req := waitForResyncRequest(t, mockServer)
+	assert.Equal(t, pb.ResyncRequest_CORRUPTION, req.GetReason())
+	assert.Contains(t, req.GetDetail(), "failed to roll back push push-2")
+	assert.False(t, rw.isNoOpPush(&pb.PushMessage{PushId: "push-3", BatchFile: []byte("batch")}), "the last batch is applied again")
+
+	// Until a mirror push replaces the files, the request stands.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-3", BatchFile: []byte("batch-3")}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.NotNil(t, rw.pendingResync)
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-4", BatchFile: []byte("batch-4"), Mirror: true}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	assert.Nil(t, rw.pendingResync)
+}
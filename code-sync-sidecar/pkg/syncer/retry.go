@@ -0,0 +1,80 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// Steps of a push that are retried, as named in logs and attempt counts.
+const (
+	retryStepRsync  = "rsync"
+	retryStepSignal = "signal"
+)
+
+// rsyncExitVanished is rsync's exit code for a transfer that finished partially
+// because source files vanished while it ran.
+const rsyncExitVanished = 24
+
+// retryPolicy says how often a push step that fails transiently is tried, and
+// how long to wait between attempts. The wait doubles after each retry.
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// run calls fn until it succeeds, fails with an error transient doesn't accept,
+// or the attempts are used up, and returns how many attempts were made along
+// with fn's last error. A policy with no attempts set still tries once.
+func (p retryPolicy) run(ctx context.Context, step string, transient func(error) bool, fn func() error) (int, error) {
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.attempts || !transient(err) || ctx.Err() != nil {
+			return attempt, err
+		}
+		log.Warn("Push step failed transiently, retrying",
+			zap.String("step", step),
+			zap.Int("attempt", attempt),
+			zap.Int("maxAttempts", p.attempts),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isTransientRsyncError reports whether rsync failed only because files vanished
+// while it ran, which another run is expected to get past.
+func isTransientRsyncError(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == rsyncExitVanished
+}
+
+// isTransientSignalError reports whether signalling the launcher failed because
+// a process exited between being found and being signalled, e.g. while the
+// launcher was restarting the app, or the launcher itself isn't running, as
+// while its container restarts.
+func isTransientSignalError(err error) bool {
+	return errors.Is(err, syscall.ESRCH) || errors.Is(err, os.ErrProcessDone) || errors.Is(err, launcher.ErrNotRunning)
+}
+
+// isTransientReloadError reports whether reloading the app failed transiently:
+// a signalled process exited, or the app's reload endpoint refused the
+// connection while the app was restarting.
+func isTransientReloadError(err error) bool {
+	return isTransientSignalError(err) || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,112 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// exitedProcessFinder returns processes that have already exited for the first
+// failures lookups, then ones that take signals.
+type exitedProcessFinder struct {
+	failures int
+	mockProcessFinder
+}
+
+func (f *exitedProcessFinder) FindProcess(pid int) (launcher.ProcessSignaler, error) {
+	if f.failures > 0 {
+		f.failures--
+		return &mockProcess{signalErr: fmt.Errorf("failed to send signal: %w", syscall.ESRCH)}, nil
+	}
+	return f.mockProcessFinder.FindProcess(pid)
+}
+
+func TestRetryPolicy_Run(t *testing.T) {
+	errTransient := errors.New("transient")
+	transient := func(err error) bool { return errors.Is(err, errTransient) }
+	policy := retryPolicy{attempts: 3, backoff: time.Millisecond}
+
+	calls := 0
+	attempts, err := policy.run(context.Background(), "test", transient, func() error {
+		if calls++; calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts, err = policy.run(context.Background(), "test", transient, func() error { return errTransient })
+	assert.ErrorIs(t, err, errTransient, "the last error is returned once the attempts are used up")
+	assert.Equal(t, 3, attempts)
+
+	attempts, err = policy.run(context.Background(), "test", transient, func() error { return errors.New("permanent") })
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts, "other errors aren't retried")
+
+	attempts, _ = retryPolicy{}.run(context.Background(), "test", transient, func() error { return errTransient })
+	assert.Equal(t, 1, attempts, "a zero policy tries once")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts, _ = policy.run(ctx, "test", transient, func() error { return errTransient })
+	assert.Equal(t, 1, attempts, "a cancelled push isn't retried")
+}
+
+func TestIsTransientSignalError(t *testing.T) {
+	assert.True(t, isTransientSignalError(fmt.Errorf("failed to send signal: %w", syscall.ESRCH)))
+	assert.True(t, isTransientSignalError(fmt.Errorf("failed to send signal: %w", os.ErrProcessDone)))
+	assert.False(t, isTransientSignalError(fmt.Errorf("failed to send signal: %w", syscall.EPERM)))
+}
+
+func TestIsTransientReloadError(t *testing.T) {
+	assert.True(t, isTransientReloadError(fmt.Errorf("failed to send signal: %w", syscall.ESRCH)))
+	assert.True(t, isTransientReloadError(fmt.Errorf("reload request failed: %w", syscall.ECONNREFUSED)))
+	assert.False(t, isTransientReloadError(fmt.Errorf("reload request failed: %w", syscall.ETIMEDOUT)))
+}
+
+func TestHandlePushRequest_RetriesTransientFailures(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.retry = retryPolicy{attempts: 3, backoff: time.Millisecond}
+	rw.processFinder = &exitedProcessFinder{failures: 1, mockProcessFinder: mockProcessFinder{processes: make(map[int]*mockProcess)}}
+	t.Setenv("HELPER_RSYNC_VANISH_ONCE", filepath.Join(t.TempDir(), "vanished"))
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, int32(2), resp.GetRsyncAttempts())
+	assert.Equal(t, int32(2), resp.GetSignalAttempts())
+
+	rw.retry = retryPolicy{attempts: 1}
+	t.Setenv("HELPER_RSYNC_VANISH_ONCE", filepath.Join(t.TempDir(), "vanished"))
+	assert.Error(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch-2")}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Equal(t, int32(1), resp.GetRsyncAttempts())
+	assert.Zero(t, resp.GetSignalAttempts())
+}
+
+func TestHandlePushRequest_LauncherNotRunning(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.retry = retryPolicy{attempts: 2, backoff: time.Millisecond}
+	finder := rw.processFinder.(*mockProcessFinder)
+	finder.processes[12345] = &mockProcess{signalErr: os.ErrProcessDone}
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.ErrorIs(t, err, launcher.ErrNotRunning)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_LAUNCHER_NOT_RUNNING, resp.GetStatus())
+	assert.Equal(t, "Failed to reload the app (signal): launcher is not running: signal 0 to pid 12345 failed: os: process already finished", resp.GetErrorMessage())
+	assert.Equal(t, int32(2), resp.GetSignalAttempts(), "the launcher may be restarting")
+}
@@ -0,0 +1,122 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// alwaysProtectedPaths are rsync filter patterns for the sidecar's and the
+// launcher's own state in the files directory, which rsync never deletes.
+var alwaysProtectedPaths = []string{"/.sidecar/", "/.launcher/"}
+
+// deletionRails are the limits on pushes that delete files with rsync.
+type deletionRails struct {
+	// protected are rsync filter patterns, on top of alwaysProtectedPaths.
+	protected []string
+	// maxPercent is the largest share of the deployment's entries a push may
+	// delete without force; 0 disables the check.
+	maxPercent int
+}
+
+// tooManyDeletionsError is returned when a push would delete more of the
+// deployment than deletionRails.maxPercent allows.
+type tooManyDeletionsError struct {
+	paths      []string
+	total      int
+	maxPercent int
+}
+
+func (e *tooManyDeletionsError) Error() string {
+	return fmt.Sprintf("push would delete %d of %d files and directories (%d%%), more than sync.max_delete_percent (%d%%) allows: %s",
+		len(e.paths), e.total, len(e.paths)*100/e.total, e.maxPercent, summarizePaths(e.paths, maxPathsInMessage))
+}
+
+// withMirrorFlags returns opts for a push, adding --delete to a mirror push and
+// the protect rules to any push that deletes with rsync.
+func withMirrorFlags(opts rsyncOptions, mirror bool, rails deletionRails) rsyncOptions {
+	if mirror && !slices.Contains(opts.extraFlags, "--delete") {
+		opts.extraFlags = append(slices.Clone(opts.extraFlags), "--delete")
+	}
+	if opts.deletes() {
+		opts.protectedPaths = append(slices.Clone(alwaysProtectedPaths), rails.protected...)
+	}
+	return opts
+}
+
+// deletes reports whether rsync removes files the batch's source doesn't have.
+func (opts rsyncOptions) deletes() bool {
+	return slices.Contains(opts.extraFlags, "--delete")
+}
+
+// flags returns the push's extra rsync flags followed by a protect filter rule
+// for each protected path.
+func (opts rsyncOptions) flags() []string {
+	flags := slices.Clone(opts.extraFlags)
+	for _, pattern := range opts.protectedPaths {
+		flags = append(flags, "--filter=P "+pattern)
+	}
+	return flags
+}
+
+// checkDeletions runs the batches as rsync dry runs and returns a
+// *tooManyDeletionsError if they would delete more than maxPercent of the
+// entries in the deployment.
+func (rw *FileSyncer) checkDeletions(ctx context.Context, batches []*pushBatch, opts rsyncOptions, maxPercent int) error {
+	contentDir, err := rw.contentDir()
+	if err != nil {
+		return err
+	}
+	total, err := countEntries(contentDir)
+	if err != nil {
+		return fmt.Errorf("failed to count files in %s: %w", contentDir, err)
+	}
+	if total == 0 {
+		return nil // Nothing to delete
+	}
+
+	changes, err := rw.dryRunBatches(ctx, batches, opts)
+	if err != nil {
+		return err
+	}
+	deleted := deletedPaths(changes)
+	if len(deleted)*100 > maxPercent*total {
+		return &tooManyDeletionsError{paths: deleted, total: total, maxPercent: maxPercent}
+	}
+	return nil
+}
+
+// countEntries counts the files and directories below dir, leaving out the
+// sidecar's and the launcher's directories.
+func countEntries(dir string) (int, error) {
+	internal := map[string]bool{launcher.SidecarDir(dir): true, launcher.Dir(dir): true}
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if internal[path] {
+			return filepath.SkipDir
+		}
+		if path != dir {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// withMirrorDeletions attaches the paths a rejected push would have deleted.
+func withMirrorDeletions(msg *pb.WebsocketMessage, err *tooManyDeletionsError) *pb.WebsocketMessage {
+	paths := err.paths
+	if len(paths) > maxReportedChanges {
+		paths = paths[:maxReportedChanges]
+	}
+	msg.GetPushResponse().MirrorDeletions = paths
+	return msg
+}
@@ -0,0 +1,132 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestWithMirrorFlags(t *testing.T) {
+	rails := deletionRails{protected: []string{"/data/"}, maxPercent: 50}
+
+	opts := withMirrorFlags(rsyncOptions{extraFlags: []string{"--checksum"}}, false, rails)
+	assert.Equal(t, []string{"--checksum"}, opts.flags(), "nothing is protected without --delete")
+
+	opts = withMirrorFlags(rsyncOptions{extraFlags: []string{"--checksum"}}, true, rails)
+	assert.Equal(t, []string{"--checksum", "--delete", "--filter=P /.sidecar/", "--filter=P /.launcher/", "--filter=P /data/"}, opts.flags())
+
+	opts = withMirrorFlags(rsyncOptions{extraFlags: []string{"--delete"}}, true, deletionRails{})
+	assert.Equal(t, []string{"--delete", "--filter=P /.sidecar/", "--filter=P /.launcher/"}, opts.flags())
+}
+
+func TestCountEntries(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "a.go"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sidecar", "audit"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".launcher"), 0755))
+
+	count, err := countEntries(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+// newMirrorTestSyncer returns a syncer whose files directory holds 4 files.
+func newMirrorTestSyncer(t *testing.T) (*FileSyncer, *mockWebsocketServer) {
+	t.Helper()
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.deletionRails = deletionRails{protected: []string{"/data/"}, maxPercent: 50}
+	for _, name := range []string{"a.py", "b.py", "c.py", "d.py"} {
+		require.NoError(t, os.WriteFile(filepath.Join(rw.targetSyncDir, name), nil, 0644))
+	}
+	return rw, mockServer
+}
+
+func TestHandlePushRequest_MirrorProtectsPaths(t *testing.T) {
+	rw, mockServer := newMirrorTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	t.Setenv("HELPER_RSYNC_ITEMIZE", "*deleting   a.py")
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), Mirror: true,
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	runs := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, runs, 2, "the dry run and the apply")
+	assert.Contains(t, runs[0], "--dry-run")
+	for _, run := range runs {
+		assert.Contains(t, run, "--delete --filter=P /.sidecar/ --filter=P /.launcher/ --filter=P /data/")
+	}
+}
+
+func TestHandlePushRequest_MirrorOfLastBatchIsApplied(t *testing.T) {
+	rw, mockServer := newMirrorTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+
+	// The same batch, but with the files it doesn't have removed.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch"), Mirror: true,
+	}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.False(t, resp.GetNoOp())
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "--delete")
+}
+
+func TestHandlePushRequest_MirrorTooManyDeletions(t *testing.T) {
+	rw, mockServer := newMirrorTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	t.Setenv("HELPER_RSYNC_ITEMIZE", "*deleting   a.py;*deleting   b.py;*deleting   c.py")
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), Mirror: true,
+	})
+	var tooMany *tooManyDeletionsError
+	require.ErrorAs(t, err, &tooMany)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_TOO_MANY_DELETIONS, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "would delete 3 of 4 files and directories (75%)")
+	assert.Equal(t, []string{"a.py", "b.py", "c.py"}, resp.GetMirrorDeletions())
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "only the dry run ran")
+
+	// Force skips the threshold, but not the protected paths.
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch"), Mirror: true, Force: true,
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	data, err = os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "--read-batch")
+	assert.Equal(t, 2, strings.Count(string(data), "--filter=P /data/"))
+}
+
+func TestHandlePushRequest_MirrorCheckFailsClosed(t *testing.T) {
+	rw, mockServer := newMirrorTestSyncer(t)
+	t.Setenv("HELPER_RSYNC_FAIL", "1")
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), Mirror: true,
+	})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "failed to check how many files the push deletes")
+}
@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"strings"
+)
+
+// itemizedChange is one line of rsync's "--out-format=%i %n" output, e.g.
+// ">f.st...... src/main.go", "cd+++++++++ src/pkg/" or "*deleting   old.go".
+//
+// When replaying a batch, rsync reports the changes recorded when the batch was
+// written, so a file the batch creates may already exist in the destination.
+type itemizedChange struct {
+	flags string
+	path  string
+}
+
+// parseItemizedChanges extracts itemized changes from rsync output, skipping
+// progress and other lines. Paths are relative to the destination without a trailing slash.
+func parseItemizedChanges(output []byte) []itemizedChange {
+	var changes []itemizedChange
+	lines := strings.FieldsFunc(string(output), func(r rune) bool { return r == '\r' || r == '\n' })
+	for _, line := range lines {
+		if name, ok := strings.CutPrefix(line, deletingFlags+" "); ok {
+			name = strings.TrimSuffix(strings.TrimLeft(name, " "), "/")
+			if name != "" && name != "." {
+				changes = append(changes, itemizedChange{flags: deletingFlags, path: name})
+			}
+			continue
+		}
+		flags, name, ok := strings.Cut(line, " ")
+		if !ok || len(flags) != 11 || name == "" || !strings.ContainsRune("<>ch.*", rune(flags[0])) {
+			continue
+		}
+		name = strings.TrimSuffix(name, "/")
+		if name == "." || name == "" {
+			continue
+		}
+		changes = append(changes, itemizedChange{flags: flags, path: name})
+	}
+	return changes
+}
+
+// deletingFlags is how rsync itemizes a removed item.
+const deletingFlags = "*deleting"
+
+// isDeleted reports whether the item was removed.
+func (c itemizedChange) isDeleted() bool {
+	return c.flags == deletingFlags
+}
+
+// isFile reports whether the change is to a regular file.
+func (c itemizedChange) isFile() bool {
+	return c.flags[1] == 'f'
+}
+
+// isCreated reports whether the item was new when the batch was written.
+func (c itemizedChange) isCreated() bool {
+	return strings.Trim(c.flags[2:], "+") == ""
+}
+
+// transferredFiles returns the paths of regular files the changes write.
+func transferredFiles(changes []itemizedChange) []string {
+	var paths []string
+	for _, change := range changes {
+		if change.isFile() && (change.flags[0] == '>' || change.flags[0] == 'c') {
+			paths = append(paths, change.path)
+		}
+	}
+	return paths
+}
+
+// maxReportedChanges caps each list of changed paths in a PushResponse.
+const maxReportedChanges = 1000
+
+// fileChangeReport lists the paths an applied batch created, modified and
+// deleted. Created and modified cover files and symlinks whose content was
+// written; attribute-only changes and directories aren't listed. total counts
+// every path, including those cut off the lists.
+type fileChangeReport struct {
+	created, modified, deleted []string
+	truncated                  bool
+	total                      int
+}
+
+func buildFileChangeReport(changes []itemizedChange) fileChangeReport {
+	var report fileChangeReport
+	add := func(list *[]string, path string) {
+		report.total++
+		if len(*list) == maxReportedChanges {
+			report.truncated = true
+			return
+		}
+		*list = append(*list, path)
+	}
+	for _, change := range changes {
+		switch change.kind() {
+		case changeCreated:
+			add(&report.created, change.path)
+		case changeModified:
+			add(&report.modified, change.path)
+		case changeDeleted:
+			add(&report.deleted, change.path)
+		}
+	}
+	return report
+}
+
+// changeKind is how a change is reported: as a created, modified or deleted
+// path, or not at all.
+type changeKind int
+
+const (
+	changeUnreported changeKind = iota
+	changeCreated
+	changeModified
+	changeDeleted
+)
+
+// kind reports how the change is listed. Only files and symlinks whose
+// content was written count as created or modified.
+func (c itemizedChange) kind() changeKind {
+	switch {
+	case c.isDeleted():
+		return changeDeleted
+	case c.flags[0] != '>' && c.flags[0] != 'c':
+		// Attribute-only change, or a hard link to an unchanged file
+		return changeUnreported
+	case c.flags[1] != 'f' && c.flags[1] != 'L':
+		// Directories and special files
+		return changeUnreported
+	case c.isCreated():
+		return changeCreated
+	default:
+		return changeModified
+	}
+}
+
+// deletedPaths returns the paths the changes removed.
+func deletedPaths(changes []itemizedChange) []string {
+	var paths []string
+	for _, change := range changes {
+		if change.isDeleted() {
+			paths = append(paths, change.path)
+		}
+	}
+	return paths
+}
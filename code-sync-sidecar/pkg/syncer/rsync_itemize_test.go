@@ -0,0 +1,78 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestParseItemizedChanges(t *testing.T) {
+	output := []byte("cd+++++++++ ./\ncd+++++++++ pkg/\n>f+++++++++ pkg/new.go\r      1,024 100%    1.00MB/s    0:00:01 (xfr#1, to-chk=0/2)\n>f.st...... main.go\n.d..t...... docs/\n*deleting   old/\nrsync simulation success output\n")
+	changes := parseItemizedChanges(output)
+
+	assert.Equal(t, []itemizedChange{
+		{flags: "cd+++++++++", path: "pkg"},
+		{flags: ">f+++++++++", path: "pkg/new.go"},
+		{flags: ">f.st......", path: "main.go"},
+		{flags: ".d..t......", path: "docs"},
+		{flags: "*deleting", path: "old"},
+	}, changes)
+	assert.True(t, changes[0].isCreated())
+	assert.False(t, changes[2].isCreated())
+	assert.Equal(t, []string{"pkg/new.go", "main.go"}, transferredFiles(changes))
+	assert.False(t, changes[4].isCreated())
+	assert.Equal(t, []string{"old"}, deletedPaths(changes))
+}
+
+func TestBuildFileChangeReport(t *testing.T) {
+	changes := parseItemizedChanges([]byte(
+		"cd+++++++++ pkg/\n>f+++++++++ pkg/new.go\ncL+++++++++ link\n>f.st...... main.go\n" +
+			".f...p..... run.sh\n*deleting   old.go\n"))
+
+	report := buildFileChangeReport(changes)
+	assert.Equal(t, []string{"pkg/new.go", "link"}, report.created)
+	assert.Equal(t, []string{"main.go"}, report.modified)
+	assert.Equal(t, []string{"old.go"}, report.deleted)
+	assert.False(t, report.truncated)
+
+	var many []itemizedChange
+	for i := range maxReportedChanges + 1 {
+		many = append(many, itemizedChange{flags: deletingFlags, path: fmt.Sprintf("f%d", i)})
+	}
+	report = buildFileChangeReport(many)
+	assert.Len(t, report.deleted, maxReportedChanges)
+	assert.True(t, report.truncated)
+}
+
+func TestHandlePushRequest_ReportsFileChanges(t *testing.T) {
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	defer func() { execCommand = originalExecCommand }()
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f+++++++++ app.py;>f.st...... lib.py;*deleting   old.py")
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.Equal(t, []string{"app.py"}, resp.GetCreatedFiles())
+	assert.Equal(t, []string{"lib.py"}, resp.GetModifiedFiles())
+	assert.Equal(t, []string{"old.py"}, resp.GetDeletedFiles())
+}
@@ -0,0 +1,50 @@
+package syncer
+
+import (
+	"fmt"
+	"time"
+)
+
+const DefaultRsyncTimeout = 60 * time.Second
+
+// allowedRsyncFlags are the flags a push may add to the rsync command. Anything
+// that changes where rsync reads or writes (--backup-dir, --rsh, --log-file, ...)
+// or how files are replaced (--inplace breaks rollback and hard-linked releases)
+// is left out.
+var allowedRsyncFlags = map[string]bool{
+	"--delete":         true,
+	"--checksum":       true,
+	"--ignore-times":   true,
+	"--size-only":      true,
+	"--no-perms":       true,
+	"--no-owner":       true,
+	"--no-group":       true,
+	"--no-times":       true,
+	"--omit-dir-times": true,
+}
+
+// rsyncOptions are the settings used to apply one push.
+type rsyncOptions struct {
+	timeout time.Duration
+	// stallTimeout is how long rsync may use no CPU and do no I/O before it is
+	// killed; 0 disables the watchdog.
+	stallTimeout time.Duration
+	extraFlags   []string
+	// protectedPaths are rsync filter patterns for paths --delete must not
+	// remove; set when extraFlags includes --delete.
+	protectedPaths []string
+	// limits sets the CPU and I/O priority rsync runs at.
+	limits resourceLimits
+	// timer, if set, records how long writing the batch and running rsync take.
+	timer *pushTimer
+}
+
+// validateRsyncFlags returns an error naming the first flag that isn't allowed.
+func validateRsyncFlags(flags []string) error {
+	for _, flag := range flags {
+		if !allowedRsyncFlags[flag] {
+			return fmt.Errorf("rsync flag %q is not allowed", flag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestValidateRsyncFlags(t *testing.T) {
+	assert.NoError(t, validateRsyncFlags(nil))
+	assert.NoError(t, validateRsyncFlags([]string{"--delete", "--checksum"}))
+	assert.ErrorContains(t, validateRsyncFlags([]string{"--checksum", "--rsh=sh -c evil"}), `"--rsh=sh -c evil" is not allowed`)
+	assert.Error(t, validateRsyncFlags([]string{"--inplace"}))
+}
+
+func newRsyncOptionsTestSyncer(t *testing.T) (*FileSyncer, *mockWebsocketServer) {
+	t.Helper()
+	originalExecCommand := execCommand
+	execCommand = helperCommandContext
+	t.Cleanup(func() { execCommand = originalExecCommand })
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	t.Cleanup(func() { conn.Close() })
+	return &FileSyncer{
+		targetSyncDir: dir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}, mockServer
+}
+
+func TestHandlePushRequest_RsyncFlags(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-1", BatchFile: []byte("batch"), RsyncFlags: []string{"--checksum"},
+	}))
+	assert.Equal(t, pb.PushResponse_COMPLETED, waitForPushResponse(t, mockServer).GetStatus())
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Fields(string(data)), "--checksum")
+
+	err = rw.handlePushRequest(context.Background(), &pb.PushMessage{
+		PushId: "push-2", BatchFile: []byte("batch"), RsyncFlags: []string{"--backup-dir=/etc"},
+	})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "is not allowed")
+}
+
+func TestHandlePushRequest_RsyncTimeout(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	t.Setenv("HELPER_RSYNC_SLEEP", "30s")
+	rw.rsyncTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "timed out")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestHandlePushRequest_RsyncStalled(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	// The helper sleeps without using CPU or doing I/O, like rsync blocked on a dead mount.
+	t.Setenv("HELPER_RSYNC_SLEEP", "30s")
+	rw.rsyncStallTimeout = 300 * time.Millisecond
+
+	start := time.Now()
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.ErrorIs(t, err, errRsyncStalled)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_STALLED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "no CPU or I/O")
+	assert.Less(t, time.Since(start), 10*time.Second, "killed well before timeouts.rsync")
+}
+
+func TestHandlePushRequest_NoOpForSameBatch(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	launcherProcess := &mockProcess{}
+	rw.processFinder.(*mockProcessFinder).processes[12345] = launcherProcess
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")}))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.False(t, resp.GetNoOp())
+	require.FileExists(t, argsFile)
+	require.NoError(t, os.Remove(argsFile))
+	require.Len(t, launcherProcess.signalCalls, 1)
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-2", BatchFile: []byte("batch")}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Equal(t, "push-2", resp.GetPushId())
+	assert.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus())
+	assert.True(t, resp.GetNoOp())
+	assert.NoFileExists(t, argsFile, "rsync doesn't run")
+	assert.Len(t, launcherProcess.signalCalls, 1, "the app isn't reloaded")
+	assert.True(t, rw.hasApplied("push-2"))
+
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-3", BatchFile: []byte("batch"), Force: true}))
+	resp = waitForPushResponse(t, mockServer)
+	assert.False(t, resp.GetNoOp(), "forced pushes are always applied")
+	assert.FileExists(t, argsFile)
+	assert.Len(t, launcherProcess.signalCalls, 2)
+
+	rw.forgetLastPushHash()
+	require.NoError(t, rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-4", BatchFile: []byte("batch")}))
+	assert.False(t, waitForPushResponse(t, mockServer).GetNoOp(), "the files no longer match the batch")
+	assert.Len(t, launcherProcess.signalCalls, 3)
+}
@@ -0,0 +1,170 @@
+package syncer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// DefaultRsyncStallTimeout is how long rsync may go without using CPU or doing
+// I/O before the watchdog kills it.
+const DefaultRsyncStallTimeout = 2 * time.Minute
+
+// maxWatchdogInterval caps how long the watchdog waits between samples.
+const maxWatchdogInterval = 5 * time.Second
+
+// errRsyncStalled is returned when the watchdog killed rsync.
+var errRsyncStalled = errors.New("rsync stalled")
+
+// These are replaced in tests.
+var (
+	rsyncProcDir   = "/proc"
+	killRsyncGroup = killProcessGroup
+)
+
+// rsyncWatchdog kills an rsync process group that has used no CPU time and done
+// no I/O for stallTimeout, which the context timeout alone only catches after
+// timeouts.rsync: a transfer blocked on a dead network mount sits in
+// uninterruptible sleep and never finishes by itself.
+type rsyncWatchdog struct {
+	pgid         int
+	stallTimeout time.Duration
+	stalled      atomic.Bool
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// startRsyncWatchdog starts watching the process group pgid, which rsync leads.
+func startRsyncWatchdog(pgid int, stallTimeout time.Duration) *rsyncWatchdog {
+	w := &rsyncWatchdog{
+		pgid:         pgid,
+		stallTimeout: stallTimeout,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Stop stops watching and reports whether the watchdog killed rsync.
+func (w *rsyncWatchdog) Stop() bool {
+	close(w.stop)
+	<-w.done
+	return w.stalled.Load()
+}
+
+func (w *rsyncWatchdog) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(max(min(w.stallTimeout/4, maxWatchdogInterval), time.Millisecond))
+	defer ticker.Stop()
+
+	last, _ := groupActivity(w.pgid)
+	lastChange := time.Now()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		activity, ok := groupActivity(w.pgid)
+		if !ok {
+			return // The group has exited
+		}
+		if activity != last {
+			last, lastChange = activity, time.Now()
+			continue
+		}
+		if idle := time.Since(lastChange); idle >= w.stallTimeout {
+			log.Error("rsync has used no CPU and done no I/O, killing it",
+				zap.Int("pgid", w.pgid), zap.Duration("idle", idle))
+			w.stalled.Store(true)
+			if err := killRsyncGroup(w.pgid); err != nil {
+				log.Warn("Failed to kill stalled rsync", zap.Int("pgid", w.pgid), zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// groupActivity sums the CPU ticks and the bytes read and written by every
+// process in the process group pgid. It returns false when the group has no
+// processes left.
+func groupActivity(pgid int) (uint64, bool) {
+	entries, err := os.ReadDir(rsyncProcDir)
+	if err != nil {
+		return 0, false
+	}
+	var total uint64
+	found := false
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join(rsyncProcDir, entry.Name())
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue // The process exited while listing
+		}
+		group, ticks, ok := parseProcStat(string(stat))
+		if !ok || group != pgid {
+			continue
+		}
+		found = true
+		total += ticks
+		if io, err := os.ReadFile(filepath.Join(dir, "io")); err == nil {
+			total += parseProcIO(string(io))
+		}
+	}
+	return total, found
+}
+
+// parseProcStat reads the process group and the CPU ticks used by a process
+// and its reaped children from the contents of /proc/<pid>/stat. As in
+// launcher's parseParentPID, fields are read after comm's last parenthesis.
+func parseProcStat(stat string) (pgid int, ticks uint64, ok bool) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, 0, false
+	}
+	// state ppid pgrp session tty_nr tpgid flags minflt cminflt majflt cmajflt utime stime cutime cstime
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 15 {
+		return 0, 0, false
+	}
+	pgid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, field := range fields[11:15] {
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		ticks += n
+	}
+	return pgid, ticks, true
+}
+
+// parseProcIO sums the rchar and wchar counters of /proc/<pid>/io, which count
+// every byte read and written, including from pipes and cached files.
+func parseProcIO(io string) uint64 {
+	var total uint64
+	for _, line := range strings.Split(io, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || (name != "rchar" && name != "wchar") {
+			continue
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			total += n
+		}
+	}
+	return total
+}
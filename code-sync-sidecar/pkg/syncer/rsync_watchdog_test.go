@@ -0,0 +1,78 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcStat(t *testing.T) {
+	pgid, ticks, ok := parseProcStat("4242 (rsync (x)) S 1 4242 4242 0 -1 4194560 120 0 0 0 7 3 2 1 20 0 1 0 100 0 0")
+	require.True(t, ok)
+	assert.Equal(t, 4242, pgid)
+	assert.Equal(t, uint64(13), ticks, "utime, stime, cutime and cstime")
+
+	_, _, ok = parseProcStat("4242 (rsync) S 1")
+	assert.False(t, ok)
+}
+
+func TestParseProcIO(t *testing.T) {
+	assert.Equal(t, uint64(300), parseProcIO("rchar: 100\nwchar: 200\nsyscr: 5\nread_bytes: 4096\n"))
+	assert.Equal(t, uint64(0), parseProcIO(""))
+}
+
+// writeProcStat writes a fake /proc/<pid>/stat for a process in group pgid that has used ticks.
+func writeProcStat(t *testing.T, procDir, pid string, pgid string, ticks string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(procDir, pid), 0755))
+	stat := pid + " (rsync) S 1 " + pgid + " " + pgid + " 0 -1 0 0 0 0 0 " + ticks + " 0 0 0 20 0 1 0"
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, pid, "stat"), []byte(stat), 0644))
+}
+
+func TestRsyncWatchdog(t *testing.T) {
+	procDir := t.TempDir()
+	originalProcDir, originalKill := rsyncProcDir, killRsyncGroup
+	rsyncProcDir = procDir
+	killed := make(chan int, 1)
+	killRsyncGroup = func(pgid int) error {
+		killed <- pgid
+		return nil
+	}
+	t.Cleanup(func() { rsyncProcDir, killRsyncGroup = originalProcDir, originalKill })
+
+	writeProcStat(t, procDir, "100", "100", "5")
+	writeProcStat(t, procDir, "101", "100", "1")
+	writeProcStat(t, procDir, "200", "200", "1")
+
+	t.Run("progressing", func(t *testing.T) {
+		w := startRsyncWatchdog(100, 200*time.Millisecond)
+		for i := 0; i < 8; i++ {
+			time.Sleep(50 * time.Millisecond)
+			writeProcStat(t, procDir, "101", "100", string(rune('2'+i)))
+		}
+		assert.False(t, w.Stop())
+		assert.Empty(t, killed)
+	})
+
+	t.Run("stalled", func(t *testing.T) {
+		w := startRsyncWatchdog(100, 200*time.Millisecond)
+		select {
+		case pgid := <-killed:
+			assert.Equal(t, 100, pgid)
+		case <-time.After(5 * time.Second):
+			t.Fatal("stalled rsync wasn't killed")
+		}
+		assert.True(t, w.Stop())
+	})
+
+	t.Run("exited", func(t *testing.T) {
+		w := startRsyncWatchdog(300, 200*time.Millisecond)
+		time.Sleep(300 * time.Millisecond)
+		assert.False(t, w.Stop(), "a group with no processes isn't stalled")
+		assert.Empty(t, killed)
+	})
+}
@@ -0,0 +1,57 @@
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"syscall"
+)
+
+// securityLabelAttrs are the extended attributes holding a file's security
+// labels: its SELinux context, or its Smack label.
+var securityLabelAttrs = []string{"security.selinux", "security.SMACK64"}
+
+// copySecurityLabels sets the security labels src has on dst. Symlinks are
+// left alone, since setting an attribute would follow them.
+func copySecurityLabels(src, dst string) error {
+	for _, path := range []string{src, dst} {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+	}
+	for _, attr := range securityLabelAttrs {
+		label, err := getxattr(src, attr)
+		if errors.Is(err, syscall.ENODATA) || errors.Is(err, syscall.ENOTSUP) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if current, err := getxattr(dst, attr); err == nil && bytes.Equal(current, label) {
+			continue
+		}
+		if err := syscall.Setxattr(dst, attr, label, 0); err != nil {
+			return &os.PathError{Op: "setxattr " + attr, Path: dst, Err: err}
+		}
+	}
+	return nil
+}
+
+func getxattr(path, attr string) ([]byte, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := syscall.Getxattr(path, attr, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			buf = make([]byte, 4*len(buf))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
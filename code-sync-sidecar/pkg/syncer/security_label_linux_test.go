@@ -0,0 +1,36 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreserveSecurityLabels(t *testing.T) {
+	// A user attribute stands in for the SELinux context, which can only be set
+	// with SELinux enabled.
+	const attr = "user.bifrost_test_label"
+	original := securityLabelAttrs
+	securityLabelAttrs = []string{attr}
+	t.Cleanup(func() { securityLabelAttrs = original })
+
+	target, backupDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, "index.html"), []byte("old"), 0644))
+	if err := syscall.Setxattr(filepath.Join(backupDir, "index.html"), attr, []byte("httpd_sys_content_t"), 0); err != nil {
+		t.Skipf("Extended attributes aren't supported here: %v", err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(target, "index.html"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(target, "new.html"), []byte("new"), 0644))
+
+	backup := &syncBackup{targetDir: target, dir: backupDir}
+	require.NoError(t, preserveSecurityLabels(backup, []string{"index.html", "new.html", "gone.html"}))
+	label, err := getxattr(filepath.Join(target, "index.html"), attr)
+	require.NoError(t, err)
+	assert.Equal(t, "httpd_sys_content_t", string(label))
+	_, err = getxattr(filepath.Join(target, "new.html"), attr)
+	assert.ErrorIs(t, err, syscall.ENODATA, "a new file keeps the default label")
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package syncer
+
+// copySecurityLabels does nothing: security labels are Linux-only.
+func copySecurityLabels(src, dst string) error {
+	return nil
+}
@@ -0,0 +1,186 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// The operations on a file a security module can refuse, as reported in a
+// SecurityDenial.
+const (
+	deniedWrite   = "write"
+	deniedChown   = "chown"
+	deniedChmod   = "chmod"
+	deniedRelabel = "relabel"
+)
+
+// securityModule is the Linux security module confining the sidecar. SELinux
+// labels every file with a context that a file replaced by rename loses;
+// AppArmor confines by path and has no labels.
+type securityModule struct {
+	name  string // "selinux" or "apparmor"; empty when there is none
+	mode  string
+	label string // The sidecar's own context or profile
+}
+
+// enforcing reports whether the module refuses what its policy doesn't allow,
+// rather than only logging it.
+func (m securityModule) enforcing() bool {
+	return m.mode == "enforcing" || m.mode == "enforce"
+}
+
+// denied reports whether err, from changing a file, looks like the module's
+// doing rather than the file's ownership: EACCES, which ownership doesn't give
+// the files' owner, or EPERM to a sidecar running as root.
+func (m securityModule) denied(err error) bool {
+	if !m.enforcing() {
+		return false
+	}
+	return errors.Is(err, syscall.EACCES) || (errors.Is(err, syscall.EPERM) && os.Geteuid() == 0)
+}
+
+func (m securityModule) proto() *pb.SecurityModule {
+	if m.name == "" {
+		return nil
+	}
+	return &pb.SecurityModule{Name: m.name, Mode: m.mode, Label: m.label}
+}
+
+// currentSecurityModule is the module confining the sidecar, detected once;
+// replaced in tests.
+var currentSecurityModule = sync.OnceValue(func() securityModule {
+	return detectSecurityModule("/")
+})
+
+// detectSecurityModule finds the security module enabled under root, which is
+// / outside of tests, from the files the kernel exposes in /sys and /proc.
+func detectSecurityModule(root string) securityModule {
+	read := func(path string) string {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(strings.TrimRight(string(data), "\x00\n"))
+	}
+	if enforce := read("sys/fs/selinux/enforce"); enforce != "" {
+		m := securityModule{name: "selinux", mode: "permissive", label: read("proc/self/attr/current")}
+		if enforce == "1" {
+			m.mode = "enforcing"
+		}
+		return m
+	}
+	if read("sys/module/apparmor/parameters/enabled") == "Y" {
+		profile := read("proc/self/attr/apparmor/current")
+		if profile == "" {
+			profile = read("proc/self/attr/current")
+		}
+		// e.g. "cri-containerd.apparmor.d (enforce)", or "unconfined".
+		m := securityModule{name: "apparmor", mode: "unconfined", label: profile}
+		if name, mode, ok := strings.Cut(profile, " ("); ok {
+			m.label, m.mode = name, strings.TrimSuffix(mode, ")")
+		}
+		return m
+	}
+	return securityModule{}
+}
+
+// securityDenialError is returned when the security module refused changes
+// to files; the push's response lists them.
+type securityDenialError struct {
+	module  securityModule
+	denials []*pb.SecurityDenial
+}
+
+func (e *securityDenialError) Error() string {
+	changes := make([]string, len(e.denials))
+	for i, denial := range e.denials {
+		changes[i] = denial.Operation + " " + denial.Path
+	}
+	return fmt.Sprintf("%s (%s) refused %s", e.module.name, e.module.mode, summarizePaths(changes, maxPathsInMessage))
+}
+
+// withSecurityDenials attaches the changes the security module refused, if
+// err is a securityDenialError, to a push response.
+func withSecurityDenials(msg *pb.WebsocketMessage, err error) *pb.WebsocketMessage {
+	var denied *securityDenialError
+	if errors.As(err, &denied) {
+		msg.GetPushResponse().SecurityDenials = denied.denials[:min(len(denied.denials), maxReportedChanges)]
+	}
+	return msg
+}
+
+// rsyncErrorPattern matches the lines rsync writes when it fails to change a
+// file, such as
+//
+//	rsync: [receiver] mkstemp "/app/.main.py.Xy12" failed: Permission denied (13)
+//	rsync: [generator] chown "/app/run.sh" failed: Operation not permitted (1)
+//	rsync: [receiver] failed to set permissions on "/app/run.sh": Permission denied (13)
+var rsyncErrorPattern = regexp.MustCompile(`^rsync: (?:\[\w+\] )?([^"]*)"([^"]+)".*: .* \((\d+)\)$`)
+
+// rsyncSecurityDenials returns the changes rsync reported failing in output
+// that module refused, with paths relative to destDir.
+func rsyncSecurityDenials(output []byte, destDir string, module securityModule) []*pb.SecurityDenial {
+	var denials []*pb.SecurityDenial
+	for _, line := range strings.Split(string(output), "\n") {
+		match := rsyncErrorPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		errno, _ := strconv.Atoi(match[3])
+		if !module.denied(syscall.Errno(errno)) {
+			continue
+		}
+		operation := deniedWrite
+		switch {
+		case strings.Contains(match[1], "chown"), strings.Contains(match[1], "chgrp"):
+			operation = deniedChown
+		case strings.Contains(match[1], "permissions"):
+			operation = deniedChmod
+		case strings.Contains(match[1], "xattr"):
+			operation = deniedRelabel
+		}
+		path := strings.TrimPrefix(filepath.ToSlash(match[2]), filepath.ToSlash(destDir)+"/")
+		denials = append(denials, &pb.SecurityDenial{Path: path, Operation: operation, ErrorMessage: strings.TrimSpace(line)})
+	}
+	return denials
+}
+
+// preserveSecurityLabels gives each of paths that replaced a file the
+// security labels, such as its SELinux context, of the file it replaced, which
+// rsync's rename over it lost. Paths that replaced nothing keep the label the
+// policy gave them.
+func preserveSecurityLabels(backup *syncBackup, paths []string) error {
+	module := currentSecurityModule()
+	var denials []*pb.SecurityDenial
+	for _, rel := range paths {
+		replaced := backup.replacedPath(rel)
+		if replaced == "" {
+			continue
+		}
+		err := copySecurityLabels(replaced, filepath.Join(backup.targetDir, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if module.denied(err) {
+			denials = append(denials, &pb.SecurityDenial{Path: rel, Operation: deniedRelabel, ErrorMessage: err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to keep the security label of %s: %w", rel, err)
+		}
+	}
+	if len(denials) > 0 {
+		return &securityDenialError{module: module, denials: denials}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestDetectSecurityModule(t *testing.T) {
+	write := func(root, path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	}
+
+	root := t.TempDir()
+	write(root, "sys/fs/selinux/enforce", "1")
+	write(root, "proc/self/attr/current", "system_u:system_r:container_t:s0:c12,c34\x00")
+	assert.Equal(t, securityModule{name: "selinux", mode: "enforcing", label: "system_u:system_r:container_t:s0:c12,c34"}, detectSecurityModule(root))
+	write(root, "sys/fs/selinux/enforce", "0")
+	assert.False(t, detectSecurityModule(root).enforcing())
+
+	root = t.TempDir()
+	write(root, "sys/module/apparmor/parameters/enabled", "Y\n")
+	write(root, "proc/self/attr/apparmor/current", "cri-containerd.apparmor.d (enforce)\n")
+	module := detectSecurityModule(root)
+	assert.Equal(t, securityModule{name: "apparmor", mode: "enforce", label: "cri-containerd.apparmor.d"}, module)
+	assert.True(t, module.enforcing())
+	assert.Equal(t, &pb.SecurityModule{Name: "apparmor", Mode: "enforce", Label: "cri-containerd.apparmor.d"}, module.proto())
+	write(root, "proc/self/attr/apparmor/current", "unconfined\n")
+	assert.Equal(t, securityModule{name: "apparmor", mode: "unconfined", label: "unconfined"}, detectSecurityModule(root))
+
+	assert.Equal(t, securityModule{}, detectSecurityModule(t.TempDir()))
+	assert.Nil(t, securityModule{}.proto())
+}
+
+func TestRsyncSecurityDenials(t *testing.T) {
+	output := []byte(`>f+++++++++ app.py
+rsync: [receiver] mkstemp "/app/src/.main.py.Xy12" failed: Permission denied (13)
+rsync: [receiver] failed to set permissions on "/app/run.sh": Permission denied (13)
+rsync: [generator] chown "/app/lib" failed: Permission denied (13)
+rsync: [receiver] write failed on "/app/big.bin": No space left on device (28)
+rsync error: some files/attrs were not transferred (see previous errors) (code 23) at main.c(1338) [generator=3.2.7]
+`)
+	module := securityModule{name: "selinux", mode: "enforcing"}
+	denials := rsyncSecurityDenials(output, "/app", module)
+	require.Len(t, denials, 3)
+	assert.Equal(t, "src/.main.py.Xy12", denials[0].Path)
+	assert.Equal(t, deniedWrite, denials[0].Operation)
+	assert.Equal(t, "run.sh", denials[1].Path)
+	assert.Equal(t, deniedChmod, denials[1].Operation)
+	assert.Equal(t, "lib", denials[2].Path)
+	assert.Equal(t, deniedChown, denials[2].Operation)
+	assert.Contains(t, denials[2].ErrorMessage, `chown "/app/lib" failed`)
+
+	assert.Empty(t, rsyncSecurityDenials(output, "/app", securityModule{name: "selinux", mode: "permissive"}), "a permissive module refuses nothing")
+}
+
+func TestHandlePushRequest_SecurityDenials(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	original := currentSecurityModule
+	currentSecurityModule = func() securityModule { return securityModule{name: "selinux", mode: "enforcing"} }
+	t.Cleanup(func() { currentSecurityModule = original })
+	t.Setenv("HELPER_RSYNC_FAIL_OUTPUT", `rsync: [receiver] failed to set permissions on "`+rw.targetSyncDir+`/run.sh": Permission denied (13)`)
+
+	err := rw.handlePushRequest(context.Background(), &pb.PushMessage{PushId: "push-1", BatchFile: []byte("batch")})
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "selinux (enforcing) refused chmod run.sh")
+	require.Len(t, resp.GetSecurityDenials(), 1)
+	assert.Equal(t, "run.sh", resp.GetSecurityDenials()[0].GetPath())
+	assert.Equal(t, deniedChmod, resp.GetSecurityDenials()[0].GetOperation())
+
+	assert.Equal(t, "selinux", rw.buildHello().GetHello().GetSecurityModule().GetName())
+}
@@ -0,0 +1,251 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/creack/pty"
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	defaultShellCommand  = "/bin/sh"
+	maxShellSessions     = 4
+	shellReadBufferBytes = 32 * 1024
+)
+
+// ShellManager runs PTY-backed shell sessions opened by the control plane, so
+// developers can get a terminal into the deployment to debug synced code. It is
+// disabled unless the deployment's shell capability flag is set.
+type ShellManager struct {
+	enabled atomic.Bool
+	workDir string
+	send    func(*pb.WebsocketMessage) error
+
+	mu       sync.Mutex
+	sessions map[string]*shellSession
+}
+
+type shellSession struct {
+	id  string
+	cmd *exec.Cmd
+	pty *os.File
+}
+
+// NewShellManager creates a ShellManager whose sessions start in workDir.
+func NewShellManager(enabled bool, workDir string, send func(*pb.WebsocketMessage) error) *ShellManager {
+	sm := &ShellManager{
+		workDir:  workDir,
+		send:     send,
+		sessions: make(map[string]*shellSession),
+	}
+	sm.enabled.Store(enabled)
+	return sm
+}
+
+// Enabled reports whether new shell sessions are allowed.
+func (sm *ShellManager) Enabled() bool {
+	return sm.enabled.Load()
+}
+
+// SetEnabled allows or rejects new sessions. Sessions that are already open keep running.
+func (sm *ShellManager) SetEnabled(enabled bool) {
+	sm.enabled.Store(enabled)
+}
+
+// Open starts a new shell session.
+func (sm *ShellManager) Open(req *pb.ShellOpen) error {
+	if req == nil || req.SessionId == "" {
+		return fmt.Errorf("received SHELL_OPEN without a session id")
+	}
+	if !sm.enabled.Load() {
+		sm.sendExit(req.SessionId, -1, "remote shell is not enabled for this deployment")
+		return fmt.Errorf("remote shell is disabled, rejecting session %s", req.SessionId)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, exists := sm.sessions[req.SessionId]; exists {
+		return fmt.Errorf("shell session %s is already open", req.SessionId)
+	}
+	if len(sm.sessions) >= maxShellSessions {
+		sm.sendExit(req.SessionId, -1, fmt.Sprintf("too many open shell sessions (max %d)", maxShellSessions))
+		return fmt.Errorf("rejecting shell session %s: too many open sessions", req.SessionId)
+	}
+
+	command := req.Command
+	if command == "" {
+		command = defaultShellCommand
+	}
+	cmd := exec.Command(command)
+	cmd.Dir = sm.workDir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	ptmx, err := pty.StartWithSize(cmd, shellWinsize(req.Rows, req.Cols))
+	if err != nil {
+		sm.sendExit(req.SessionId, -1, fmt.Sprintf("failed to start shell: %v", err))
+		return fmt.Errorf("failed to start shell session %s: %w", req.SessionId, err)
+	}
+
+	session := &shellSession{id: req.SessionId, cmd: cmd, pty: ptmx}
+	sm.sessions[req.SessionId] = session
+	log.Info("Opened remote shell session",
+		zap.String("sessionID", req.SessionId),
+		zap.String("command", command),
+		zap.Int("pid", cmd.Process.Pid),
+	)
+
+	go sm.pump(session)
+	return nil
+}
+
+// Write forwards terminal input to a session.
+func (sm *ShellManager) Write(req *pb.ShellData) error {
+	session, err := sm.session(req.GetSessionId())
+	if err != nil {
+		return err
+	}
+	if _, err := session.pty.Write(req.Data); err != nil {
+		return fmt.Errorf("failed to write to shell session %s: %w", session.id, err)
+	}
+	return nil
+}
+
+// Resize updates a session's terminal dimensions.
+func (sm *ShellManager) Resize(req *pb.ShellResize) error {
+	session, err := sm.session(req.GetSessionId())
+	if err != nil {
+		return err
+	}
+	if err := pty.Setsize(session.pty, shellWinsize(req.Rows, req.Cols)); err != nil {
+		return fmt.Errorf("failed to resize shell session %s: %w", session.id, err)
+	}
+	return nil
+}
+
+// Close terminates a session. The SHELL_EXIT message is sent once the process exits.
+func (sm *ShellManager) Close(req *pb.ShellClose) error {
+	session, err := sm.session(req.GetSessionId())
+	if err != nil {
+		return err
+	}
+	session.terminate()
+	return nil
+}
+
+// CloseAll terminates every open session, e.g. when the websocket connection drops.
+func (sm *ShellManager) CloseAll() {
+	sm.mu.Lock()
+	sessions := make([]*shellSession, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		sessions = append(sessions, session)
+	}
+	sm.mu.Unlock()
+
+	for _, session := range sessions {
+		session.terminate()
+	}
+}
+
+func (sm *ShellManager) session(id string) (*shellSession, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown shell session %q", id)
+	}
+	return session, nil
+}
+
+// pump streams PTY output upstream until the shell exits, then reports the exit code.
+func (sm *ShellManager) pump(session *shellSession) {
+	buf := make([]byte, shellReadBufferBytes)
+	for {
+		n, err := session.pty.Read(buf)
+		if n > 0 {
+			msg := &pb.WebsocketMessage{
+				MessageType: pb.WebsocketMessage_SHELL_STDOUT,
+				Message: &pb.WebsocketMessage_ShellData{
+					ShellData: &pb.ShellData{
+						SessionId: session.id,
+						Data:      append([]byte(nil), buf[:n]...),
+					},
+				},
+			}
+			if sendErr := sm.send(msg); sendErr != nil {
+				log.Warn("Failed to send shell output", zap.String("sessionID", session.id), zap.Error(sendErr))
+			}
+		}
+		if err != nil {
+			// The PTY returns EIO once the shell and its children have exited.
+			if !errors.Is(err, io.EOF) && !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+				log.Warn("Shell session read failed", zap.String("sessionID", session.id), zap.Error(err))
+			}
+			break
+		}
+	}
+
+	exitCode := 0
+	errorMessage := ""
+	if err := session.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			exitCode = -1
+			errorMessage = err.Error()
+		}
+	}
+	session.pty.Close()
+
+	sm.mu.Lock()
+	delete(sm.sessions, session.id)
+	sm.mu.Unlock()
+
+	log.Info("Remote shell session exited", zap.String("sessionID", session.id), zap.Int("exitCode", exitCode))
+	sm.sendExit(session.id, exitCode, errorMessage)
+}
+
+func (sm *ShellManager) sendExit(sessionID string, exitCode int, errorMessage string) {
+	msg := &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_SHELL_EXIT,
+		Message: &pb.WebsocketMessage_ShellExit{
+			ShellExit: &pb.ShellExit{
+				SessionId:    sessionID,
+				ExitCode:     int32(exitCode),
+				ErrorMessage: errorMessage,
+			},
+		},
+	}
+	if err := sm.send(msg); err != nil {
+		log.Warn("Failed to send shell exit", zap.String("sessionID", sessionID), zap.Error(err))
+	}
+}
+
+// terminate hangs up the shell, as a terminal closing would.
+func (s *shellSession) terminate() {
+	if s.cmd.Process != nil {
+		if err := s.cmd.Process.Signal(syscall.SIGHUP); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			log.Warn("Failed to signal shell session", zap.String("sessionID", s.id), zap.Error(err))
+		}
+	}
+}
+
+func shellWinsize(rows, cols uint32) *pty.Winsize {
+	if rows == 0 {
+		rows = 24
+	}
+	if cols == 0 {
+		cols = 80
+	}
+	return &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)}
+}
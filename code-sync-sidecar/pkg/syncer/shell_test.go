@@ -0,0 +1,62 @@
+package syncer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestShellManager_Disabled(t *testing.T) {
+	sent := make(chan *pb.WebsocketMessage, 1)
+	sm := NewShellManager(false, t.TempDir(), func(msg *pb.WebsocketMessage) error {
+		sent <- msg
+		return nil
+	})
+
+	err := sm.Open(&pb.ShellOpen{SessionId: "s1"})
+	require.Error(t, err)
+
+	msg := <-sent
+	assert.Equal(t, pb.WebsocketMessage_SHELL_EXIT, msg.GetMessageType())
+	assert.Equal(t, "s1", msg.GetShellExit().GetSessionId())
+	assert.Contains(t, msg.GetShellExit().GetErrorMessage(), "not enabled")
+}
+
+func TestShellManager_Session(t *testing.T) {
+	sent := make(chan *pb.WebsocketMessage, 100)
+	sm := NewShellManager(true, t.TempDir(), func(msg *pb.WebsocketMessage) error {
+		sent <- msg
+		return nil
+	})
+
+	require.NoError(t, sm.Open(&pb.ShellOpen{SessionId: "s1", Rows: 30, Cols: 100}))
+	require.Error(t, sm.Open(&pb.ShellOpen{SessionId: "s1"}), "duplicate session ids are rejected")
+	require.NoError(t, sm.Resize(&pb.ShellResize{SessionId: "s1", Rows: 40, Cols: 120}))
+	require.NoError(t, sm.Write(&pb.ShellData{SessionId: "s1", Data: []byte("echo hello-$((40+2)); exit 3\n")}))
+
+	var output strings.Builder
+	var exit *pb.ShellExit
+	timeout := time.After(5 * time.Second)
+	for exit == nil {
+		select {
+		case msg := <-sent:
+			switch msg.GetMessageType() {
+			case pb.WebsocketMessage_SHELL_STDOUT:
+				output.Write(msg.GetShellData().GetData())
+			case pb.WebsocketMessage_SHELL_EXIT:
+				exit = msg.GetShellExit()
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for shell exit, output so far: %q", output.String())
+		}
+	}
+
+	assert.Contains(t, output.String(), "hello-42")
+	assert.Equal(t, int32(3), exit.GetExitCode())
+	assert.ErrorContains(t, sm.Write(&pb.ShellData{SessionId: "s1", Data: []byte("ls\n")}), "unknown shell session")
+}
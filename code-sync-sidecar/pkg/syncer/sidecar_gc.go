@@ -0,0 +1,122 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	DefaultGCInterval        = time.Hour
+	DefaultSnapshotRetention = 7 * 24 * time.Hour
+
+	// gcDisabledRecheck is how often a disabled collector checks whether it was re-enabled.
+	gcDisabledRecheck = time.Minute
+)
+
+// orphanPatterns match the temporary files and directories created in .sidecar
+// while a push or snapshot is applied. Outside of those operations, any that
+// exist were left behind by a crash or a failed cleanup.
+var orphanPatterns = []string{"sync_batch_*.bin", "sync_backup_*", "snapshot_restore_*", "*.tmp"}
+
+// runGarbageCollector cleans up .sidecar once at startup and then on every GC
+// interval until the sidecar stops. A zero interval disables the periodic pass.
+func (rw *FileSyncer) runGarbageCollector(ctx context.Context) {
+	rw.collectGarbage()
+	for {
+		interval := rw.getGCInterval()
+		if interval <= 0 {
+			interval = gcDisabledRecheck
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-rw.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if rw.getGCInterval() > 0 {
+			rw.collectGarbage()
+		}
+	}
+}
+
+// collectGarbage removes orphaned temporary files and expired snapshots. It
+// holds workspaceMu so nothing it removes can belong to a running push.
+func (rw *FileSyncer) collectGarbage() {
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	removed, err := collectGarbage(rw.targetSyncDir, rw.getSnapshotRetention(), time.Now())
+	if err != nil {
+		log.Warn("Garbage collection failed for some files", zap.Error(err))
+	}
+	if len(removed) > 0 {
+		log.Info("Removed orphaned sidecar files", zap.Strings("paths", removed))
+	}
+}
+
+// collectGarbage removes leftover temporary files from filesDir and its .sidecar
+// directory, and snapshots older than snapshotRetention (0 keeps them). It
+// returns the paths removed.
+func collectGarbage(filesDir string, snapshotRetention time.Duration, now time.Time) ([]string, error) {
+	var removed []string
+	var errs []error
+	remove := func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+			return
+		}
+		removed = append(removed, path)
+	}
+
+	sidecarDir := launcher.SidecarDir(filesDir)
+	for _, pattern := range orphanPatterns {
+		matches, err := filepath.Glob(filepath.Join(sidecarDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			remove(path)
+		}
+	}
+	for _, dir := range []string{getSnapshotsDir(filesDir), batchCacheDir(filesDir)} {
+		partial, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range partial {
+			remove(path)
+		}
+	}
+	if _, err := os.Lstat(getCurrentLink(filesDir) + ".tmp"); err == nil {
+		remove(getCurrentLink(filesDir) + ".tmp")
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, err)
+	}
+
+	if snapshotRetention > 0 {
+		snapshots, err := listSnapshots(filesDir)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, snapshot := range snapshots {
+			if now.Sub(snapshot.CreatedAt.AsTime()) > snapshotRetention {
+				remove(getSnapshotPath(filesDir, snapshot.Name))
+			}
+		}
+	}
+	return removed, errors.Join(errs...)
+}
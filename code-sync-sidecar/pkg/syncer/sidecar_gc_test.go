@@ -0,0 +1,59 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	dir := t.TempDir()
+	sidecarDir := launcher.SidecarDir(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(sidecarDir, "sync_backup_123", "pkg"), 0755))
+	require.NoError(t, os.MkdirAll(getSnapshotsDir(dir), 0755))
+	for _, name := range []string{"sync_batch_1.bin", "state.json.tmp", "manifest.json.tmp", "state.json", "env.sh", "rsync"} {
+		require.NoError(t, os.WriteFile(filepath.Join(sidecarDir, name), []byte("x"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(getSnapshotsDir(dir), "snapshot_1.tmp"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink(".releases/gone", getCurrentLink(dir)+".tmp"))
+
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"old": 10 * 24 * time.Hour, "recent": time.Hour} {
+		path := getSnapshotPath(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+		require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+
+	removed, err := collectGarbage(dir, DefaultSnapshotRetention, now)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(sidecarDir, "sync_backup_123"),
+		filepath.Join(sidecarDir, "sync_batch_1.bin"),
+		filepath.Join(sidecarDir, "state.json.tmp"),
+		filepath.Join(sidecarDir, "manifest.json.tmp"),
+		filepath.Join(getSnapshotsDir(dir), "snapshot_1.tmp"),
+		getCurrentLink(dir) + ".tmp",
+		getSnapshotPath(dir, "old"),
+	}, removed)
+	for _, name := range []string{"state.json", "env.sh", "rsync"} {
+		assert.FileExists(t, filepath.Join(sidecarDir, name))
+	}
+	assert.FileExists(t, getSnapshotPath(dir, "recent"))
+
+	// A zero retention keeps snapshots regardless of age.
+	removed, err = collectGarbage(dir, 0, now.Add(365*24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestCollectGarbage_MissingSidecarDir(t *testing.T) {
+	removed, err := collectGarbage(t.TempDir(), DefaultSnapshotRetention, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
@@ -0,0 +1,115 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const sidecarLogSource = "sidecar"
+
+// SidecarLogShipper is a zap core that copies the sidecar's own log entries
+// upstream as SIDECAR_LOG messages, so sync failures show up in the dashboard
+// without kubectl access.
+//
+// Unlike application logs, logging must never block the sidecar: when the
+// buffer is full (e.g. while the websocket is down) new entries are dropped and
+// counted, and the count is reported with the next batch.
+type SidecarLogShipper struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	entries chan *pb.LogEntry
+	dropped *atomic.Int64
+}
+
+// NewSidecarLogShipper creates a shipper for entries at minLevel or above. Debug
+// entries are never shipped, since sending a batch itself logs at debug level.
+// Entries are buffered until Run is started.
+func NewSidecarLogShipper(minLevel zapcore.Level) *SidecarLogShipper {
+	if minLevel < zapcore.InfoLevel {
+		minLevel = zapcore.InfoLevel
+	}
+	return &SidecarLogShipper{
+		LevelEnabler: minLevel,
+		encoder:      zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		entries:      make(chan *pb.LogEntry, logBufferSize),
+		dropped:      &atomic.Int64{},
+	}
+}
+
+func (s *SidecarLogShipper) With(fields []zapcore.Field) zapcore.Core {
+	clone := *s
+	clone.encoder = s.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.encoder)
+	}
+	return &clone
+}
+
+func (s *SidecarLogShipper) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.Enabled(entry.Level) {
+		return checked.AddCore(entry, s)
+	}
+	return checked
+}
+
+func (s *SidecarLogShipper) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := s.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+
+	truncated := false
+	if len(line) > logMaxLineLength {
+		line = line[:logMaxLineLength]
+		truncated = true
+	}
+	logEntry := &pb.LogEntry{
+		Timestamp: timestamppb.New(entry.Time),
+		Source:    sidecarLogSource,
+		Line:      line,
+		Truncated: truncated,
+		Level:     entry.Level.String(),
+	}
+	select {
+	case s.entries <- logEntry:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (s *SidecarLogShipper) Sync() error {
+	return nil
+}
+
+// Run delivers buffered entries with send until ctx is cancelled. send should
+// return an error when the batch was not sent so it is retried.
+func (s *SidecarLogShipper) Run(ctx context.Context, send func(*pb.WebsocketMessage) error) {
+	sendLogBatches(ctx, s.entries, s.appendDropped, func(entries []*pb.LogEntry) error {
+		return send(buildLogBatchMessage(pb.WebsocketMessage_SIDECAR_LOG, entries))
+	})
+}
+
+// appendDropped adds a notice about entries dropped since the last batch.
+func (s *SidecarLogShipper) appendDropped(batch []*pb.LogEntry) []*pb.LogEntry {
+	dropped := s.dropped.Swap(0)
+	if dropped == 0 {
+		return batch
+	}
+	return append(batch, &pb.LogEntry{
+		Timestamp: timestamppb.Now(),
+		Source:    sidecarLogSource,
+		Line:      fmt.Sprintf("dropped %d sidecar log entries while the log buffer was full", dropped),
+		Level:     zapcore.WarnLevel.String(),
+	})
+}
@@ -0,0 +1,56 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestSidecarLogShipper(t *testing.T) {
+	shipper := NewSidecarLogShipper(zapcore.DebugLevel)
+	logger := zap.New(shipper).With(zap.String("pushID", "push-1"))
+	logger.Debug("not shipped")
+	logger.Error("Rsync apply failed", zap.Int("exitCode", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sent := make(chan *pb.WebsocketMessage, 1)
+	go shipper.Run(ctx, func(msg *pb.WebsocketMessage) error {
+		sent <- msg
+		return nil
+	})
+
+	select {
+	case msg := <-sent:
+		assert.Equal(t, pb.WebsocketMessage_SIDECAR_LOG, msg.GetMessageType())
+		entries := msg.GetLogBatch().GetEntries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, sidecarLogSource, entries[0].GetSource())
+		assert.Equal(t, "error", entries[0].GetLevel())
+		assert.Contains(t, entries[0].GetLine(), `"msg":"Rsync apply failed"`)
+		assert.Contains(t, entries[0].GetLine(), `"pushID":"push-1"`)
+		assert.Contains(t, entries[0].GetLine(), `"exitCode":1`)
+	case <-time.After(2 * logFlushInterval):
+		t.Fatal("Timed out waiting for sidecar log batch")
+	}
+}
+
+func TestSidecarLogShipper_DropsWhenFull(t *testing.T) {
+	shipper := NewSidecarLogShipper(zapcore.InfoLevel)
+	logger := zap.New(shipper)
+	for i := 0; i < logBufferSize+3; i++ {
+		logger.Info("line")
+	}
+
+	batch := shipper.appendDropped(nil)
+	require.Len(t, batch, 1)
+	assert.Contains(t, batch[0].GetLine(), "dropped 3 sidecar log entries")
+	assert.Empty(t, shipper.appendDropped(nil), "the dropped count is reported once")
+}
@@ -0,0 +1,144 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	sidecarStateFileName = "state.json"
+
+	// maxRecentPushIDs bounds how many applied push IDs are kept for duplicate detection.
+	maxRecentPushIDs = 100
+)
+
+// errNoFilesDir is returned instead of writing the sidecar's own files when no
+// files directory is set, which would put them under the working directory.
+var errNoFilesDir = errors.New("no files directory is set")
+
+// SidecarState is what the sidecar remembers about applied pushes across
+// restarts. It is stored as JSON in the .sidecar directory.
+type SidecarState struct {
+	LastPushID   string    `json:"last_push_id"`
+	LastPushHash string    `json:"last_push_hash,omitempty"`
+	AppliedAt    time.Time `json:"applied_at"`
+	// RecentPushIDs lists the most recently applied push IDs, oldest first.
+	RecentPushIDs []string `json:"recent_push_ids,omitempty"`
+	// ResumeToken is from the last HELLO_ACK and is sent back in the next HELLO.
+	ResumeToken string `json:"resume_token,omitempty"`
+	// RecentResponses holds the final responses of the most recent pushes,
+	// oldest first, to replay if the server never received them.
+	RecentResponses []RecordedResponse `json:"recent_responses,omitempty"`
+	// LastSequence is the latest push sequence received, and LastSequencePushID
+	// the push that had it; a push older than it is rejected as out of order.
+	LastSequence       int64  `json:"last_sequence,omitempty"`
+	LastSequencePushID string `json:"last_sequence_push_id,omitempty"`
+}
+
+// RecordedResponse is a push's final PushResponse, marshalled as protobuf.
+type RecordedResponse struct {
+	PushID   string `json:"push_id"`
+	Response []byte `json:"response"`
+}
+
+// hasApplied reports whether pushID is one of the recently applied pushes.
+func (s SidecarState) hasApplied(pushID string) bool {
+	return pushID != "" && slices.Contains(s.RecentPushIDs, pushID)
+}
+
+func getStatePath(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), sidecarStateFileName)
+}
+
+// loadSidecarState reads the persisted state. A missing file means nothing has
+// been applied yet and returns the zero state.
+func loadSidecarState(filesDir string) (SidecarState, error) {
+	var state SidecarState
+	path := getStatePath(filesDir)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return SidecarState{}, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// saveSidecarState writes the state atomically so a crash never leaves a partial file.
+func saveSidecarState(filesDir string, state SidecarState) error {
+	if filesDir == "" {
+		return errNoFilesDir
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar state: %w", err)
+	}
+	path := getStatePath(filesDir)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace state file %s: %w", path, err)
+	}
+	return nil
+}
+
+// saveState writes rw.applied to the state file. stateSaveMu is held from the
+// copy through the rename, so concurrent saves can't share the temp file and
+// the last one written always has the latest state.
+func (rw *FileSyncer) saveState() error {
+	rw.stateSaveMu.Lock()
+	defer rw.stateSaveMu.Unlock()
+	rw.stateMu.Lock()
+	state := rw.applied.clone()
+	rw.stateMu.Unlock()
+	return saveSidecarState(rw.targetSyncDir, state)
+}
+
+// clone returns a copy of s that doesn't share its slices, as they are changed
+// in place under stateMu.
+func (s SidecarState) clone() SidecarState {
+	s.RecentPushIDs = slices.Clone(s.RecentPushIDs)
+	s.RecentResponses = slices.Clone(s.RecentResponses)
+	return s
+}
+
+// batchHash identifies the content of a push by the SHA-256 of its rsync batch.
+func batchHash(batchData []byte) string {
+	sum := sha256.Sum256(batchData)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func buildHelloMessage(state SidecarState) *pb.WebsocketMessage {
+	hello := &pb.Hello{
+		LastPushId:   state.LastPushID,
+		LastPushHash: state.LastPushHash,
+		ResumeToken:  state.ResumeToken,
+		LastSequence: state.LastSequence,
+	}
+	if !state.AppliedAt.IsZero() {
+		hello.LastAppliedAt = timestamppb.New(state.AppliedAt)
+	}
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_HELLO,
+		Message:     &pb.WebsocketMessage_Hello{Hello: hello},
+	}
+}
@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestSidecarState_RoundTrip(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+
+	state, err := loadSidecarState(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, SidecarState{}, state, "a missing state file means nothing was applied")
+
+	rw := &FileSyncer{targetSyncDir: filesDir}
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+	rw.recordApplied("push-2", "") // Database-only push keeps the last batch hash
+
+	state, err = loadSidecarState(filesDir)
+	require.NoError(t, err)
+	assert.Equal(t, "push-2", state.LastPushID)
+	assert.Equal(t, batchHash([]byte("batch")), state.LastPushHash)
+	assert.False(t, state.AppliedAt.IsZero())
+
+	hello := buildHelloMessage(state)
+	assert.Equal(t, pb.WebsocketMessage_HELLO, hello.GetMessageType())
+	assert.Equal(t, "push-2", hello.GetHello().GetLastPushId())
+	assert.Equal(t, state.LastPushHash, hello.GetHello().GetLastPushHash())
+	assert.Equal(t, state.AppliedAt.UnixNano(), hello.GetHello().GetLastAppliedAt().AsTime().UnixNano())
+}
+
+func TestLoadSidecarState_Corrupt(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+	require.NoError(t, os.WriteFile(getStatePath(filesDir), []byte("{not json"), 0644))
+
+	_, err := loadSidecarState(filesDir)
+	assert.ErrorContains(t, err, "failed to parse state file")
+}
+
+func TestSidecarFiles_NeedFilesDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rw := &FileSyncer{}
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+	rw.recordAudit(&pb.PushResponse{PushId: "push-1", Status: pb.PushResponse_COMPLETED})
+
+	assert.ErrorIs(t, saveSidecarState("", SidecarState{}), errNoFilesDir)
+	assert.ErrorIs(t, appendAuditRecord("", auditRecord{PushID: "push-1"}), errNoFilesDir)
+	assert.NoDirExists(t, launcher.SidecarDir(""), "nothing is written under the working directory")
+}
+
+func TestSaveState_Concurrent(t *testing.T) {
+	filesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(filesDir), 0777))
+	rw := &FileSyncer{targetSyncDir: filesDir}
+
+	// The push worker, the send path and the read loop all save the state.
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			rw.recordApplied(fmt.Sprintf("push-%d", i), "")
+		}()
+		go func() {
+			defer wg.Done()
+			rw.recordPushResponse(&pb.PushResponse{PushId: fmt.Sprintf("push-%d", i), Status: pb.PushResponse_COMPLETED})
+		}()
+		go func() {
+			defer wg.Done()
+			rw.recordResumeToken(fmt.Sprintf("token-%d", i))
+		}()
+	}
+	wg.Wait()
+
+	state, err := loadSidecarState(filesDir)
+	require.NoError(t, err)
+	rw.stateMu.Lock()
+	defer rw.stateMu.Unlock()
+	assert.Equal(t, rw.applied.RecentPushIDs, state.RecentPushIDs, "the last save has the latest state")
+	assert.Len(t, state.RecentResponses, len(rw.applied.RecentResponses))
+	assert.Equal(t, rw.applied.ResumeToken, state.ResumeToken)
+	assert.NoFileExists(t, getStatePath(filesDir)+".tmp")
+}
+
+func TestRecordApplied_KeepsRecentPushIDs(t *testing.T) {
+	rw := &FileSyncer{targetSyncDir: t.TempDir()}
+	for i := 0; i < maxRecentPushIDs+5; i++ {
+		rw.recordApplied(fmt.Sprintf("push-%d", i), "")
+	}
+
+	assert.Len(t, rw.applied.RecentPushIDs, maxRecentPushIDs)
+	assert.False(t, rw.hasApplied("push-4"))
+	assert.True(t, rw.hasApplied("push-5"))
+	assert.True(t, rw.hasApplied(fmt.Sprintf("push-%d", maxRecentPushIDs+4)))
+}
@@ -0,0 +1,488 @@
+package syncer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// Snapshots are gzipped tarballs of the synced code under .sidecar/snapshots.
+// Tarballs rather than hard links are used so edits made in the deployment
+// after a snapshot was taken can't change it.
+const (
+	snapshotsDirName   = "snapshots"
+	snapshotFileSuffix = ".tar.gz"
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+func getSnapshotsDir(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), snapshotsDirName)
+}
+
+func getSnapshotPath(filesDir, name string) string {
+	return filepath.Join(getSnapshotsDir(filesDir), name+snapshotFileSuffix)
+}
+
+func validateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use up to 64 letters, digits, '.', '_' or '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// handleSnapshotRequest creates or restores a snapshot off the read loop and
+// sends a SNAPSHOT_RESPONSE when done.
+func (rw *FileSyncer) handleSnapshotRequest(messageType pb.WebsocketMessage_MessageType, req *pb.SnapshotRequest) error {
+	if req == nil {
+		return fmt.Errorf("received %s but snapshot_request field is nil", messageType)
+	}
+	go func() {
+		var info *pb.SnapshotInfo
+		var err error
+		name := req.Name
+		if messageType == pb.WebsocketMessage_SNAPSHOT_CREATE {
+			if name == "" {
+				name = time.Now().UTC().Format("20060102T150405Z")
+			}
+			info, err = rw.createSnapshot(name)
+		} else {
+			info, err = rw.restoreSnapshot(name)
+		}
+		if err != nil {
+			log.Error("Snapshot request failed", zap.String("type", messageType.String()), zap.String("name", name), zap.Error(err))
+		}
+		rw.sendProtoMessage(rw.buildSnapshotResponse(name, info, err))
+	}()
+	return nil
+}
+
+// createSnapshot archives the synced code and then drops the oldest snapshots
+// beyond the configured limit.
+func (rw *FileSyncer) createSnapshot(name string) (*pb.SnapshotInfo, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	maxSnapshots := rw.getMaxSnapshots()
+	if maxSnapshots <= 0 {
+		return nil, fmt.Errorf("snapshots are disabled (sync.max_snapshots is 0)")
+	}
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	contentDir, err := rw.contentDir()
+	if err != nil {
+		return nil, err
+	}
+	path := getSnapshotPath(rw.targetSyncDir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+	if err := writeSnapshot(contentDir, path); err != nil {
+		return nil, err
+	}
+	log.Info("Created snapshot", zap.String("name", name), zap.String("path", path))
+
+	if err := pruneSnapshots(rw.targetSyncDir, maxSnapshots); err != nil {
+		log.Warn("Failed to remove old snapshots", zap.Error(err))
+	}
+	return snapshotInfo(path)
+}
+
+// restoreSnapshot replaces the synced code with the content of a snapshot and
+// signals the launcher to reload.
+func (rw *FileSyncer) restoreSnapshot(name string) (*pb.SnapshotInfo, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	path := getSnapshotPath(rw.targetSyncDir, name)
+	info, err := snapshotInfo(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %q does not exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	var contentDir string
+	if rw.applyMode == ApplyModeSwap {
+		release, err := newRelease(rw.targetSyncDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := extractSnapshot(path, release); err != nil {
+			os.RemoveAll(release)
+			return nil, err
+		}
+		if err := activateRelease(rw.targetSyncDir, release); err != nil {
+			os.RemoveAll(release)
+			return nil, err
+		}
+		if err := pruneReleases(rw.targetSyncDir, maxReleases); err != nil {
+			log.Warn("Failed to remove old releases", zap.Error(err))
+		}
+		contentDir = release
+	} else {
+		// Extract next to the files directory first so a corrupt archive changes nothing.
+		staging, err := os.MkdirTemp(launcher.SidecarDir(rw.targetSyncDir), "snapshot_restore_*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create restore directory: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		if err := extractSnapshot(path, staging); err != nil {
+			return nil, err
+		}
+		if err := mirrorTree(staging, rw.targetSyncDir, isInternalEntry); err != nil {
+			return nil, fmt.Errorf("failed to restore snapshot %q: %w", name, err)
+		}
+		contentDir = rw.targetSyncDir
+	}
+	log.Info("Restored snapshot", zap.String("name", name))
+	rw.forgetLastPushHash()
+
+	// Restored files are the new baseline for conflict detection.
+	manifest := fileManifest{}
+	files, err := listRegularFiles(contentDir)
+	if err == nil {
+		err = manifest.record(contentDir, files)
+	}
+	if err != nil {
+		log.Warn("Failed to update manifest after restore", zap.Error(err))
+	} else if err := saveManifest(rw.targetSyncDir, manifest); err != nil {
+		log.Warn("Failed to save manifest after restore", zap.Error(err))
+	}
+
+	hs, err := rw.writeReloadHandshake("", launcher.ReloadReasonSnapshotRestore, nil, name)
+	if err != nil {
+		return info, fmt.Errorf("snapshot restored but the launcher handshake could not be written: %w", err)
+	}
+	if err := rw.getReloader().Reload(context.Background(), hs); err != nil {
+		return info, fmt.Errorf("snapshot restored but the app could not be reloaded: %w", err)
+	}
+	return info, nil
+}
+
+// listRegularFiles returns the regular files under dir, relative to it, without
+// the sidecar's internal entries.
+func listRegularFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if isTopLevel(rel) && isInternalEntry(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (rw *FileSyncer) buildSnapshotResponse(name string, info *pb.SnapshotInfo, err error) *pb.WebsocketMessage {
+	resp := &pb.SnapshotResponse{
+		Name:     name,
+		Status:   pb.SnapshotResponse_COMPLETED,
+		Snapshot: info,
+	}
+	if err != nil {
+		resp.Status = pb.SnapshotResponse_FAILED
+		resp.ErrorMessage = err.Error()
+	}
+	snapshots, listErr := listSnapshots(rw.targetSyncDir)
+	if listErr != nil {
+		log.Warn("Failed to list snapshots", zap.Error(listErr))
+	}
+	resp.Snapshots = snapshots
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_SNAPSHOT_RESPONSE,
+		Message:     &pb.WebsocketMessage_SnapshotResponse{SnapshotResponse: resp},
+	}
+}
+
+func snapshotInfo(path string) (*pb.SnapshotInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SnapshotInfo{
+		Name:      strings.TrimSuffix(filepath.Base(path), snapshotFileSuffix),
+		SizeBytes: info.Size(),
+		CreatedAt: timestamppb.New(info.ModTime()),
+	}, nil
+}
+
+// listSnapshots returns every snapshot, oldest first.
+func listSnapshots(filesDir string) ([]*pb.SnapshotInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(getSnapshotsDir(filesDir), "*"+snapshotFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*pb.SnapshotInfo
+	for _, path := range paths {
+		info, err := snapshotInfo(path)
+		if err != nil {
+			continue // Removed since it was listed
+		}
+		snapshots = append(snapshots, info)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.AsTime().Before(snapshots[j].CreatedAt.AsTime())
+	})
+	return snapshots, nil
+}
+
+// pruneSnapshots removes the oldest snapshots so at most keep remain.
+func pruneSnapshots(filesDir string, keep int) error {
+	snapshots, err := listSnapshots(filesDir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := 0; i < len(snapshots)-keep; i++ {
+		if err := os.Remove(getSnapshotPath(filesDir, snapshots[i].Name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove snapshot %s: %w", snapshots[i].Name, err))
+		} else {
+			log.Info("Removed old snapshot", zap.String("name", snapshots[i].Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeSnapshot archives srcDir, without the sidecar's internal entries, to path.
+// The archive is written to a temporary file first so a failure leaves no partial snapshot.
+func writeSnapshot(srcDir, path string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "snapshot_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if isTopLevel(rel) && isInternalEntry(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case !d.IsDir() && !d.Type().IsRegular():
+			return nil // Sockets, pipes and devices aren't synced
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", srcDir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// extractSnapshot unpacks the archive at path into dstDir, which must exist.
+func extractSnapshot(path, dstDir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot %s: %w", path, err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		name := filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("snapshot %s contains an unsafe path %q", path, hdr.Name)
+		}
+		target := filepath.Join(dstDir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		mode := fs.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mirrorTree makes dst match src by removing entries src doesn't have and moving
+// src's files into place. src must be on the same filesystem as dst and is
+// consumed. Top-level entries of dst for which skip returns true are left alone.
+func mirrorTree(src, dst string, skip func(name string) bool) error {
+	err := filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if isTopLevel(rel) && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		srcInfo, err := os.Lstat(filepath.Join(src, rel))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil && srcInfo.IsDir() == d.IsDir() {
+			return nil // Kept; files are replaced below
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		}
+		return os.Rename(path, target)
+	})
+}
@@ -0,0 +1,149 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func newSnapshotTestSyncer(t *testing.T, dir string, applyMode string) (*FileSyncer, *mockProcessFinder) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	return &FileSyncer{
+		targetSyncDir: dir,
+		applyMode:     applyMode,
+		processFinder: finder,
+		maxSnapshots:  2,
+	}, finder
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSnapshot_CreateAndRestoreInPlace(t *testing.T) {
+	dir := t.TempDir()
+	rw, finder := newSnapshotTestSyncer(t, dir, ApplyModeInPlace)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "app.py"), []byte("v1"), 0644))
+	require.NoError(t, os.Symlink("pkg/app.py", filepath.Join(dir, "main.py")))
+
+	info, err := rw.createSnapshot("before-change")
+	require.NoError(t, err)
+	assert.Equal(t, "before-change", info.Name)
+	assert.Positive(t, info.SizeBytes)
+	_, err = rw.createSnapshot("before-change")
+	assert.ErrorContains(t, err, "already exists")
+
+	// Change, add and remove files after the snapshot.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "app.py"), []byte("v2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.py"), []byte("new"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "main.py")))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(dir), "state.json"), []byte("{}"), 0644))
+
+	_, err = rw.restoreSnapshot("before-change")
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", readFile(t, filepath.Join(dir, "pkg", "app.py")))
+	assert.NoFileExists(t, filepath.Join(dir, "new.py"))
+	link, err := os.Readlink(filepath.Join(dir, "main.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg/app.py", link)
+	assert.FileExists(t, filepath.Join(launcher.SidecarDir(dir), "state.json"), "internal files are left alone")
+	assert.FileExists(t, getSnapshotPath(dir, "before-change"), "restoring keeps the snapshot")
+	assert.NotEmpty(t, finder.processes[12345].signalCalls, "launcher is signalled")
+
+	manifest, err := loadManifest(dir)
+	require.NoError(t, err)
+	assert.Contains(t, manifest, filepath.Join("pkg", "app.py"))
+}
+
+func TestSnapshot_RestoreSwapMode(t *testing.T) {
+	dir := t.TempDir()
+	rw, _ := newSnapshotTestSyncer(t, dir, ApplyModeSwap)
+	first, err := stageRelease(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(first, "app.py"), []byte("v1"), 0644))
+	require.NoError(t, activateRelease(dir, first))
+
+	_, err = rw.createSnapshot("v1")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(first, "app.py"), []byte("v2"), 0644))
+
+	_, err = rw.restoreSnapshot("v1")
+	require.NoError(t, err)
+
+	current, err := currentRelease(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, current, "restored into a new release")
+	assert.Equal(t, "v1", readFile(t, filepath.Join(getCurrentLink(dir), "app.py")))
+}
+
+func TestSnapshot_RetentionAndErrors(t *testing.T) {
+	dir := t.TempDir()
+	rw, _ := newSnapshotTestSyncer(t, dir, ApplyModeInPlace)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("v1"), 0644))
+
+	for i, name := range []string{"one", "two", "three"} {
+		_, err := rw.createSnapshot(name)
+		require.NoError(t, err)
+		// Snapshots are ordered by modification time.
+		at := time.Now().Add(time.Duration(i-3) * time.Minute)
+		require.NoError(t, os.Chtimes(getSnapshotPath(dir, name), at, at))
+	}
+	snapshots, err := listSnapshots(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"two", "three"}, names, "oldest snapshot beyond the limit is removed")
+
+	_, err = rw.createSnapshot("../escape")
+	assert.ErrorContains(t, err, "invalid snapshot name")
+	_, err = rw.restoreSnapshot("missing")
+	assert.ErrorContains(t, err, "does not exist")
+
+	rw.maxSnapshots = 0
+	_, err = rw.createSnapshot("disabled")
+	assert.ErrorContains(t, err, "disabled")
+}
+
+func TestHandleSnapshotRequest(t *testing.T) {
+	dir := t.TempDir()
+	rw, _ := newSnapshotTestSyncer(t, dir, ApplyModeInPlace)
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw.conn = conn
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("v1"), 0644))
+
+	require.NoError(t, rw.handleSnapshotRequest(pb.WebsocketMessage_SNAPSHOT_CREATE, &pb.SnapshotRequest{}))
+
+	select {
+	case message := <-mockServer.messages:
+		var wsMessage pb.WebsocketMessage
+		require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		require.Equal(t, pb.WebsocketMessage_SNAPSHOT_RESPONSE, wsMessage.MessageType)
+		resp := wsMessage.GetSnapshotResponse()
+		assert.Equal(t, pb.SnapshotResponse_COMPLETED, resp.Status)
+		assert.NotEmpty(t, resp.Name, "a name is generated")
+		assert.Equal(t, resp.Name, resp.Snapshot.GetName())
+		assert.Len(t, resp.Snapshots, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for snapshot response")
+	}
+}
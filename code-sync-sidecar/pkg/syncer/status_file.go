@@ -0,0 +1,232 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/control"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// statusFileName is kept up to date in the .sidecar directory.
+const statusFileName = "status.json"
+
+// DefaultStatusFileInterval is how often the status file is rewritten when
+// nothing in it changed, to keep its updated_at fresh.
+const DefaultStatusFileInterval = 5 * time.Second
+
+// Connection states in the status file.
+const (
+	ConnectionConnected    = "connected"
+	ConnectionPolling      = "polling"
+	ConnectionDisconnected = "disconnected"
+)
+
+// SidecarStatus is what the status file holds, for tools in other containers
+// of the pod, such as debuggers and exporters, that can read the shared volume
+// but not reach the sidecar's network endpoints.
+type SidecarStatus struct {
+	// UpdatedAt is refreshed at least every status.file_interval while the
+	// sidecar runs, so a reader can tell a stale file from a live one.
+	UpdatedAt    time.Time        `json:"updated_at"`
+	StartedAt    time.Time        `json:"started_at"`
+	AppID        string           `json:"app_id"`
+	DeploymentID string           `json:"deployment_id"`
+	Pod          string           `json:"pod,omitempty"`
+	Connection   StatusConnection `json:"connection"`
+	// LastAppliedPush is the last push applied to the files, as the control
+	// socket's get_last_push returns it.
+	LastAppliedPush control.LastPushResult `json:"last_applied_push"`
+	// LastPushResult is the final response of the last push, which may have
+	// failed; nil until a push finished.
+	LastPushResult *StatusPushResult `json:"last_push_result,omitempty"`
+	ActivePushID   string            `json:"active_push_id,omitempty"`
+	QueuedPushes   int32             `json:"queued_pushes"`
+	// EnvFiles are the env files with a version that changes with their
+	// contents, as in the launcher handshake, relative to the files directory.
+	EnvFiles []launcher.EnvFile `json:"env_files"`
+	Health   StatusHealth       `json:"health"`
+}
+
+// StatusConnection is the state of the connection to the control plane.
+type StatusConnection struct {
+	// State is ConnectionConnected, ConnectionPolling (the HTTP long-polling
+	// fallback) or ConnectionDisconnected.
+	State string `json:"state"`
+	// ConnectedSince is when the sidecar last connected.
+	ConnectedSince     *time.Time `json:"connected_since,omitempty"`
+	Reconnects         int32      `json:"reconnects"`
+	ReconnectsLastHour int32      `json:"reconnects_last_hour"`
+	RTTMs              int64      `json:"rtt_ms"`
+	Degraded           bool       `json:"degraded"`
+}
+
+// StatusPushResult is a push's final status.
+type StatusPushResult struct {
+	PushID       string `json:"push_id"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// StatusHealth is the health of the launcher and the app.
+type StatusHealth struct {
+	// LauncherState is a StatusReport.LauncherState, e.g. "RUNNING".
+	LauncherState string `json:"launcher_state"`
+	LauncherPID   int    `json:"launcher_pid,omitempty"`
+	// AppReady and AppMessage are what the app last reported over the control
+	// socket; AppReady is nil if it never did.
+	AppReady   *bool  `json:"app_ready,omitempty"`
+	AppMessage string `json:"app_message,omitempty"`
+}
+
+// StatusFilePath returns the path of the status file in filesDir.
+func StatusFilePath(filesDir string) string {
+	return filepath.Join(launcher.SidecarDir(filesDir), statusFileName)
+}
+
+// MaintainStatusFile keeps the status file up to date until ctx is done or
+// the syncer stops: it is rewritten every interval, and as soon as the
+// connection, the app's readiness or the last push changes.
+func (rw *FileSyncer) MaintainStatusFile(ctx context.Context, interval time.Duration) {
+	path := StatusFilePath(rw.targetSyncDir)
+	log.Info("Maintaining status file", zap.String("path", path))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr string
+	for {
+		// Failures are logged once, not on every write.
+		if err := writeStatusFile(path, rw.buildSidecarStatus()); err != nil {
+			if err.Error() != lastErr {
+				log.Warn("Failed to write status file", zap.String("path", path), zap.Error(err))
+			}
+			lastErr = err.Error()
+		} else if lastErr != "" {
+			log.Info("Status file written again", zap.String("path", path))
+			lastErr = ""
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-rw.done:
+			return
+		case <-ticker.C:
+		case <-rw.statusChanged:
+		}
+	}
+}
+
+// notifyStatusChanged has the status file rewritten without waiting for the
+// interval.
+func (rw *FileSyncer) notifyStatusChanged() {
+	select {
+	case rw.statusChanged <- struct{}{}:
+	default:
+	}
+}
+
+// buildSidecarStatus snapshots the sidecar for the status file.
+func (rw *FileSyncer) buildSidecarStatus() SidecarStatus {
+	status := SidecarStatus{
+		UpdatedAt:    time.Now().UTC(),
+		StartedAt:    rw.startedAt.UTC(),
+		AppID:        rw.appID,
+		DeploymentID: rw.deploymentID,
+		Pod:          Pod.GetPodName(),
+		EnvFiles:     []launcher.EnvFile{},
+	}
+
+	rw.stateMu.Lock()
+	applied := rw.applied
+	connectedSince := rw.connectedSince
+	status.Connection.Reconnects = rw.reconnectCount
+	appReadiness := rw.appReadiness
+	rw.stateMu.Unlock()
+
+	status.Connection.State = rw.connectionState()
+	if !connectedSince.IsZero() {
+		since := connectedSince.UTC()
+		status.Connection.ConnectedSince = &since
+	}
+	stats := &pb.ConnectionStats{}
+	rw.quality.addStats(stats)
+	status.Connection.ReconnectsLastHour = stats.ReconnectsLastHour
+	status.Connection.RTTMs = stats.RttMs
+	status.Connection.Degraded = stats.Degraded
+
+	status.LastAppliedPush = control.LastPushResult{PushID: applied.LastPushID, PushHash: applied.LastPushHash}
+	if !applied.AppliedAt.IsZero() {
+		appliedAt := applied.AppliedAt.UTC()
+		status.LastAppliedPush.AppliedAt = &appliedAt
+	}
+	if n := len(applied.RecentResponses); n > 0 {
+		var resp pb.PushResponse
+		if err := proto.Unmarshal(applied.RecentResponses[n-1].Response, &resp); err == nil {
+			status.LastPushResult = &StatusPushResult{PushID: resp.PushId, Status: resp.Status.String(), ErrorMessage: firstLine(resp.ErrorMessage)}
+		}
+	}
+	status.ActivePushID, status.QueuedPushes = rw.pushes.status()
+
+	envFiles, err := envfile.List(rw.targetSyncDir)
+	if err != nil {
+		log.Debug("Failed to list env files for the status file", zap.Error(err))
+	}
+	for _, envFile := range envFiles {
+		if path, err := filepath.Rel(rw.targetSyncDir, envFile.Path); err == nil {
+			envFile.Path = path
+		}
+		status.EnvFiles = append(status.EnvFiles, envFile)
+	}
+
+	launcherState, launcherPID := launcher.State(rw.targetSyncDir, rw.processFinder)
+	status.Health.LauncherState = launcherState.String()
+	status.Health.LauncherPID = launcherPID
+	if appReadiness != nil {
+		status.Health.AppReady = &appReadiness.Ready
+		status.Health.AppMessage = appReadiness.Message
+	}
+	return status
+}
+
+// connectionState returns how the sidecar is connected to the control plane.
+func (rw *FileSyncer) connectionState() string {
+	rw.writeMu.Lock()
+	defer rw.writeMu.Unlock()
+	switch {
+	case rw.conn != nil:
+		return ConnectionConnected
+	case rw.poller != nil:
+		return ConnectionPolling
+	}
+	return ConnectionDisconnected
+}
+
+// writeStatusFile atomically replaces the status file at path with status,
+// so a reader never sees it half written.
+func writeStatusFile(path string, status SidecarStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), launcher.Volume.InternalDir); err != nil {
+		return fmt.Errorf("failed to create sidecar directory %s: %w", filepath.Dir(path), err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write status file %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace status file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/control"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// readStatusFile returns the status file once check accepts it.
+func readStatusFile(t *testing.T, filesDir string, check func(SidecarStatus) bool) SidecarStatus {
+	t.Helper()
+	var status SidecarStatus
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(StatusFilePath(filesDir))
+		if err != nil {
+			return false
+		}
+		status = SidecarStatus{}
+		require.NoError(t, json.Unmarshal(data, &status))
+		return check(status)
+	}, 5*time.Second, 10*time.Millisecond)
+	return status
+}
+
+func TestMaintainStatusFile(t *testing.T) {
+	rw, _ := newRsyncOptionsTestSyncer(t)
+	rw.appID, rw.deploymentID = "app1", "deployment1"
+	rw.statusChanged = make(chan struct{}, 1)
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(rw.targetSyncDir), 0755))
+	_, err := envfile.Write(rw.targetSyncDir, "", []byte("export DATABASE_URL='postgres://db/app'\n"), "")
+	require.NoError(t, err)
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+	rw.recordPushResponse(&pb.PushResponse{PushId: "push-2", Status: pb.PushResponse_FAILED, ErrorMessage: "rsync command failed\nexit status 23"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The interval is never reached: changes are written right away.
+	go rw.MaintainStatusFile(ctx, time.Hour)
+
+	status := readStatusFile(t, rw.targetSyncDir, func(SidecarStatus) bool { return true })
+	assert.Equal(t, "app1", status.AppID)
+	assert.Equal(t, "deployment1", status.DeploymentID)
+	assert.WithinDuration(t, time.Now(), status.UpdatedAt, time.Minute)
+	assert.Equal(t, ConnectionConnected, status.Connection.State)
+	assert.Equal(t, "push-1", status.LastAppliedPush.PushID)
+	assert.Equal(t, batchHash([]byte("batch")), status.LastAppliedPush.PushHash)
+	require.NotNil(t, status.LastAppliedPush.AppliedAt)
+	assert.Equal(t, &StatusPushResult{PushID: "push-2", Status: "FAILED", ErrorMessage: "rsync command failed"}, status.LastPushResult)
+	require.Len(t, status.EnvFiles, 1)
+	assert.Equal(t, filepath.Join(".sidecar", "env.sh"), status.EnvFiles[0].Path, "paths are relative to the files directory")
+	assert.NotEmpty(t, status.EnvFiles[0].Version)
+	assert.NotEmpty(t, status.Health.LauncherState)
+	assert.Nil(t, status.Health.AppReady)
+
+	_, err = rw.handleControl(control.MethodReportReadiness, json.RawMessage(`{"ready": true}`))
+	require.NoError(t, err)
+	status = readStatusFile(t, rw.targetSyncDir, func(s SidecarStatus) bool { return s.Health.AppReady != nil })
+	assert.True(t, *status.Health.AppReady)
+
+	rw.writeMu.Lock()
+	rw.conn = nil
+	rw.writeMu.Unlock()
+	rw.notifyStatusChanged()
+	readStatusFile(t, rw.targetSyncDir, func(s SidecarStatus) bool { return s.Connection.State == ConnectionDisconnected })
+
+	_, err = os.Stat(StatusFilePath(rw.targetSyncDir) + ".tmp")
+	assert.ErrorIs(t, err, os.ErrNotExist, "the file is replaced atomically")
+}
@@ -0,0 +1,106 @@
+package syncer
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+const (
+	DefaultStatusInterval = 30 * time.Second
+
+	// statusDisabledRecheck is how often a disabled status loop checks whether reports were re-enabled.
+	statusDisabledRecheck = 5 * time.Second
+)
+
+// sendPeriodicStatusReports emits a STATUS_REPORT right away and then on every
+// status interval until the connection's context ends. A zero interval disables
+// reports; the interval is re-read after every tick so config reloads apply.
+func (rw *FileSyncer) sendPeriodicStatusReports(ctx context.Context) {
+	go func() {
+		for {
+			interval := rw.getStatusInterval()
+			if interval > 0 {
+				rw.sendProtoMessage(rw.buildStatusReport())
+			} else {
+				interval = statusDisabledRecheck
+			}
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-rw.done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// buildStatusReport snapshots the sidecar's health for the control plane.
+func (rw *FileSyncer) buildStatusReport() *pb.WebsocketMessage {
+	launcherState, launcherPID := launcher.State(rw.targetSyncDir, rw.processFinder)
+
+	syncDirBytes, err := syncDirUsage(rw.targetSyncDir)
+	if err != nil {
+		log.Warn("Failed to compute sync dir usage", zap.String("path", rw.targetSyncDir), zap.Error(err))
+	}
+	freeBytes, err := filesystemFreeBytes(rw.targetSyncDir)
+	if err != nil {
+		log.Warn("Failed to stat sync dir filesystem", zap.String("path", rw.targetSyncDir), zap.Error(err))
+	}
+
+	rw.stateMu.Lock()
+	lastPushID := rw.applied.LastPushID
+	connectedSince := rw.connectedSince
+	reconnectCount := rw.reconnectCount
+	workspaceUsage := rw.workspaceUsage
+	appReadiness := rw.appReadiness
+	rw.stateMu.Unlock()
+
+	stats := &pb.ConnectionStats{
+		ReconnectCount:   reconnectCount,
+		MessagesSent:     rw.messagesSent.Load(),
+		MessagesReceived: rw.messagesReceived.Load(),
+	}
+	if !connectedSince.IsZero() {
+		stats.ConnectedSince = timestamppb.New(connectedSince)
+	}
+	rw.quality.addStats(stats)
+
+	return &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_STATUS_REPORT,
+		Message: &pb.WebsocketMessage_StatusReport{
+			StatusReport: &pb.StatusReport{
+				Timestamp:        timestamppb.Now(),
+				UptimeSeconds:    int64(time.Since(rw.startedAt).Seconds()),
+				LastPushId:       lastPushID,
+				LauncherState:    launcherState,
+				LauncherPid:      int32(launcherPID),
+				SyncDirBytes:     syncDirBytes,
+				SyncDirFreeBytes: freeBytes,
+				ConnectionStats:  stats,
+				Pod:              Pod,
+				WorkspaceUsage:   workspaceUsage,
+				Resources:        rw.readResourceUsage(),
+				AppReadiness:     appReadiness,
+			},
+		},
+	}
+}
+
+// syncDirUsage sums the size of regular files under the sync dir, skipping the
+// sidecar and launcher internals.
+func syncDirUsage(syncDir string) (int64, error) {
+	bytes, _, err := workspaceUsage(syncDir)
+	return bytes, err
+}
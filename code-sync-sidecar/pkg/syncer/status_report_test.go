@@ -0,0 +1,69 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestSyncDirUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "app.py"), make([]byte, 100), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pkg", "mod.py"), make([]byte, 50), 0644))
+
+	// Sidecar and launcher internals are not part of the synced workspace.
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(tmpDir), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(tmpDir), "rsync"), make([]byte, 1000), 0777))
+	require.NoError(t, os.MkdirAll(launcher.Dir(tmpDir), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(tmpDir), "launcher.pid"), []byte("1"), 0644))
+
+	size, err := syncDirUsage(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), size)
+}
+
+func TestBuildStatusReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(launcher.Dir(tmpDir), 0777))
+
+	finder := &mockProcessFinder{processes: make(map[int]*mockProcess)}
+	rw := &FileSyncer{
+		targetSyncDir: tmpDir,
+		processFinder: finder,
+		startedAt:     time.Now().Add(-time.Minute),
+	}
+
+	report := rw.buildStatusReport().GetStatusReport()
+	require.NotNil(t, report)
+	assert.Equal(t, pb.StatusReport_NO_PID_FILE, report.GetLauncherState())
+	assert.GreaterOrEqual(t, report.GetUptimeSeconds(), int64(60))
+	assert.Nil(t, report.GetConnectionStats().GetConnectedSince())
+
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(tmpDir), "launcher.pid"), []byte("4242\n"), 0644))
+	rw.recordConnected()
+	rw.recordConnected()
+	rw.applied.LastPushID = "push-1"
+	rw.messagesSent.Add(3)
+
+	report = rw.buildStatusReport().GetStatusReport()
+	assert.Equal(t, pb.StatusReport_RUNNING, report.GetLauncherState())
+	assert.Equal(t, int32(4242), report.GetLauncherPid())
+	assert.Equal(t, "push-1", report.GetLastPushId())
+	assert.Equal(t, int32(1), report.GetConnectionStats().GetReconnectCount())
+	assert.Equal(t, int32(1), report.GetConnectionStats().GetReconnectsLastHour())
+	assert.Equal(t, pongWait.Milliseconds(), report.GetConnectionStats().GetReadDeadlineMs())
+	assert.Equal(t, int64(3), report.GetConnectionStats().GetMessagesSent())
+	assert.NotNil(t, report.GetConnectionStats().GetConnectedSince())
+
+	finder.processes[4242] = &mockProcess{signalErr: os.ErrProcessDone}
+	report = rw.buildStatusReport().GetStatusReport()
+	assert.Equal(t, pb.StatusReport_NOT_RUNNING, report.GetLauncherState())
+}
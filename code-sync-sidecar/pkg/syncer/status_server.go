@@ -0,0 +1,64 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+const (
+	// StatusPath is where the local status endpoint serves the sidecar's STATUS_REPORT as JSON.
+	StatusPath = "/status"
+	// DiagnosticsPath serves a diagnostics bundle, as sent for DIAGNOSTICS_REQUEST.
+	DiagnosticsPath = "/diagnostics"
+)
+
+// StatusJSON renders status reports for the local endpoint and the status command.
+var StatusJSON = protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}
+
+// serveStatus serves the local status endpoint on addr until ctx is done. It is
+// meant for `code-sync-sidecar status` and probes inside the pod, so addr should
+// be a loopback address.
+func (rw *FileSyncer) ServeStatus(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+StatusPath, rw.handleStatus)
+	mux.HandleFunc("GET "+DiagnosticsPath, rw.handleDiagnostics)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Info("Serving local status endpoint", zap.String("addr", addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		// The sidecar works without it; only the status command is affected.
+		log.Warn("Local status endpoint stopped", zap.String("addr", addr), zap.Error(err))
+	}
+}
+
+func (rw *FileSyncer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	data, err := StatusJSON.Marshal(rw.buildStatusReport().GetStatusReport())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (rw *FileSyncer) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	var bundle bytes.Buffer
+	if err := rw.writeDiagnosticsBundle(&bundle); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Write(bundle.Bytes())
+}
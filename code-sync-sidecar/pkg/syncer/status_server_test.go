@@ -0,0 +1,25 @@
+package syncer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStatus(t *testing.T) {
+	rw, _ := newRsyncOptionsTestSyncer(t)
+	rw.applied.LastPushID = "push-7"
+
+	recorder := httptest.NewRecorder()
+	rw.handleStatus(recorder, httptest.NewRequest(http.MethodGet, StatusPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var report map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, "push-7", report["lastPushId"])
+	assert.Contains(t, report, "launcherState")
+}
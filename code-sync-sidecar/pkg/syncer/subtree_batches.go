@@ -0,0 +1,211 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// validateSubtreeBatches returns an error for the first sub-tree batch that
+// has no batch, isn't a directory below the files directory outside the
+// sidecar's internal entries, or overlaps another, since overlapping sub-trees
+// would have two rsyncs writing the same files at once.
+func validateSubtreeBatches(batches []*pb.SubtreeBatch) error {
+	paths := make([]string, 0, len(batches))
+	for _, batch := range batches {
+		clean := path.Clean(batch.Path)
+		if batch.Path == "" || strings.Contains(batch.Path, `\`) || clean == "." {
+			return fmt.Errorf("sub-tree %q must be a directory below the files directory", batch.Path)
+		}
+		if _, err := validateSyncPath(filepath.FromSlash(batch.Path)); err != nil {
+			return fmt.Errorf("sub-tree %q: %w", batch.Path, err)
+		}
+		if len(batch.BatchFile) == 0 {
+			return fmt.Errorf("sub-tree %q has no batch", batch.Path)
+		}
+		for _, other := range paths {
+			if clean == other || strings.HasPrefix(clean, other+"/") || strings.HasPrefix(other, clean+"/") {
+				return fmt.Errorf("sub-trees %q and %q overlap", other, clean)
+			}
+		}
+		paths = append(paths, clean)
+	}
+	return nil
+}
+
+// subtreeDir returns the directory of subtree under root. Like an injected
+// file's, its existing parents mustn't lead out of root through a symlink, and
+// neither may the sub-tree itself, since MkdirAll and rsync would follow it.
+func subtreeDir(root, subtree string) (string, error) {
+	rel, err := validateSyncPath(filepath.FromSlash(subtree))
+	if err != nil {
+		return "", err
+	}
+	dir, err := resolveInRoot(root, rel)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Lstat(dir); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", fmt.Errorf("sub-tree %q is a symlink", subtree)
+	}
+	return dir, nil
+}
+
+// subtreeBatchesOf returns pushMsg's sub-tree batches.
+func subtreeBatchesOf(pushMsg *pb.PushMessage) []*pushBatch {
+	batches := make([]*pushBatch, len(pushMsg.SubtreeBatches))
+	for i, batch := range pushMsg.SubtreeBatches {
+		batches[i] = &pushBatch{data: batch.BatchFile, size: int64(len(batch.BatchFile)), subtree: path.Clean(batch.Path)}
+	}
+	return batches
+}
+
+// subtreeBatchesHash is the content hash of a push's sub-tree batches as a
+// whole, recorded like a batch's hash so the same push sent again is a no-op.
+func subtreeBatchesHash(pushMsg *pb.PushMessage) string {
+	h := sha256.New()
+	for _, batch := range pushMsg.SubtreeBatches {
+		fmt.Fprintf(h, "%s\x00%s\n", path.Clean(batch.Path), batchHash(batch.BatchFile))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// inSubtree makes the changes rsync itemized applying a batch to subtree
+// relative to the files directory.
+func inSubtree(changes []itemizedChange, subtree string) []itemizedChange {
+	if subtree == "" {
+		return changes
+	}
+	for i := range changes {
+		changes[i].path = subtree + "/" + changes[i].path
+	}
+	return changes
+}
+
+// applySubtreeBatches applies a push's sub-tree batches with up to workers
+// rsyncs at once, all into one backup so the push is rolled back as a whole.
+// Once one fails, the running rsyncs are stopped and the batches not started
+// are skipped. It returns a result for each batch, in order; on failure the
+// error is the first sub-tree's to fail.
+func (rw *FileSyncer) applySubtreeBatches(ctx context.Context, batches []*pushBatch, opts rsyncOptions, workers int, onProgress func(bytesDone int64, percent int32)) (*syncBackup, []*pb.SubtreeResult, error) {
+	backup, err := rw.newApplyBackup()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The rsyncs overlap, so they are timed together rather than each adding its own time.
+	timer := opts.timer
+	opts.timer = nil
+	start := time.Now()
+	defer timer.since(stageRsync, start)
+
+	log.Info("Applying sub-tree batches", zap.Int("subtrees", len(batches)), zap.Int("workers", workers))
+	applyCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	results := make([]*pb.SubtreeResult, len(batches))
+	changes := make([][]itemizedChange, len(batches))
+	errs := make([]error, len(batches))
+	progress := &subtreeProgress{bytesDone: make([]int64, len(batches)), percent: make([]int32, len(batches)), onProgress: onProgress}
+	var failMu sync.Mutex
+	failed := -1
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(batches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = &pb.SubtreeResult{Path: batches[i].subtree}
+				if applyCtx.Err() != nil {
+					continue // Skipped
+				}
+				started := time.Now()
+				changes[i], errs[i] = rw.runRsyncBatch(applyCtx, batches[i], backup, opts, progress.forSubtree(i))
+				results[i].DurationMs = time.Since(started).Milliseconds()
+				results[i].FilesChanged = int32(len(transferredFiles(changes[i])) + len(deletedPaths(changes[i])))
+				if errs[i] == nil {
+					results[i].Success = true
+					continue
+				}
+				failMu.Lock()
+				if failed < 0 && ctx.Err() == nil {
+					failed = i
+				}
+				failMu.Unlock()
+				stop()
+			}
+		}()
+	}
+	for i := range batches {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, c := range changes {
+		backup.changes = append(backup.changes, c...)
+	}
+	for i, result := range results {
+		switch {
+		case result.Success:
+		case i == failed:
+			result.ErrorMessage = errs[i].Error()
+		case failed >= 0:
+			result.ErrorMessage = fmt.Sprintf("stopped because sub-tree %s failed", batches[failed].subtree)
+		default:
+			result.ErrorMessage = "stopped because the push was cancelled"
+		}
+	}
+	if failed >= 0 {
+		return backup, results, fmt.Errorf("sub-tree %s: %w", batches[failed].subtree, errs[failed])
+	}
+	if err := ctx.Err(); err != nil {
+		return backup, results, fmt.Errorf("applying sub-tree batches cancelled: %w", err)
+	}
+	return backup, results, nil
+}
+
+// subtreeProgress combines the progress of the rsyncs applying sub-tree
+// batches into one for the push: the bytes they have written and their mean
+// percentage.
+type subtreeProgress struct {
+	mu         sync.Mutex
+	bytesDone  []int64
+	percent    []int32
+	onProgress func(bytesDone int64, percent int32)
+}
+
+func (p *subtreeProgress) forSubtree(i int) func(bytesDone int64, percent int32) {
+	if p.onProgress == nil {
+		return nil
+	}
+	return func(bytesDone int64, percent int32) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.bytesDone[i], p.percent[i] = bytesDone, percent
+		var totalBytes, totalPercent int64
+		for j := range p.bytesDone {
+			totalBytes += p.bytesDone[j]
+			totalPercent += int64(p.percent[j])
+		}
+		p.onProgress(totalBytes, int32(totalPercent/int64(len(p.percent))))
+	}
+}
+
+func withSubtreeResults(msg *pb.WebsocketMessage, results []*pb.SubtreeResult) *pb.WebsocketMessage {
+	msg.GetPushResponse().SubtreeResults = results
+	return msg
+}
@@ -0,0 +1,142 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestValidateSubtreeBatches(t *testing.T) {
+	batch := []byte("batch")
+	assert.NoError(t, validateSubtreeBatches([]*pb.SubtreeBatch{
+		{Path: "packages/api", BatchFile: batch}, {Path: "packages/api-client", BatchFile: batch}, {Path: "web/", BatchFile: batch},
+	}))
+
+	for _, tc := range []struct {
+		batches []*pb.SubtreeBatch
+		want    string
+	}{
+		{[]*pb.SubtreeBatch{{Path: "", BatchFile: batch}}, "must be a directory below the files directory"},
+		{[]*pb.SubtreeBatch{{Path: ".", BatchFile: batch}}, "must be a directory below the files directory"},
+		{[]*pb.SubtreeBatch{{Path: "/srv/api", BatchFile: batch}}, "outside the files directory"},
+		{[]*pb.SubtreeBatch{{Path: "api/../../etc", BatchFile: batch}}, "outside the files directory"},
+		{[]*pb.SubtreeBatch{{Path: ".sidecar/cache", BatchFile: batch}}, "reserved for the sidecar"},
+		{[]*pb.SubtreeBatch{{Path: ".releases/20260101T000000.000000000_1", BatchFile: batch}}, "reserved for the sidecar"},
+		{[]*pb.SubtreeBatch{{Path: "current", BatchFile: batch}}, "reserved for the sidecar"},
+		{[]*pb.SubtreeBatch{{Path: "api"}}, "has no batch"},
+		{[]*pb.SubtreeBatch{{Path: "packages", BatchFile: batch}, {Path: "packages/api", BatchFile: batch}}, `sub-trees "packages" and "packages/api" overlap`},
+		{[]*pb.SubtreeBatch{{Path: "api", BatchFile: batch}, {Path: "./api", BatchFile: batch}}, "overlap"},
+	} {
+		assert.ErrorContains(t, validateSubtreeBatches(tc.batches), tc.want)
+	}
+}
+
+func TestSubtreeDir(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linked")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "packages"), 0755))
+
+	dir, err := subtreeDir(root, "packages/api")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "packages", "api"), dir)
+
+	_, err = subtreeDir(root, "linked/api")
+	assert.ErrorContains(t, err, "leads outside the files directory through a symlink")
+	_, err = subtreeDir(root, "linked")
+	assert.ErrorContains(t, err, "is a symlink")
+	_, err = subtreeDir(root, "current/api")
+	assert.ErrorContains(t, err, "reserved for the sidecar")
+}
+
+func newSubtreePush(pushID string, paths ...string) *pb.PushMessage {
+	pushMsg := &pb.PushMessage{PushId: pushID}
+	for _, path := range paths {
+		pushMsg.SubtreeBatches = append(pushMsg.SubtreeBatches, &pb.SubtreeBatch{Path: path, BatchFile: []byte("batch " + path)})
+	}
+	return pushMsg
+}
+
+func TestHandlePushRequest_SubtreeBatches(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.subtreeWorkers = 2
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("HELPER_RSYNC_ARGS_FILE", argsFile)
+	t.Setenv("HELPER_RSYNC_ITEMIZE", ">f+++++++++ main.go")
+
+	pushMsg := newSubtreePush("push-1", "packages/api", "packages/web", "tools")
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp := waitForPushResponse(t, mockServer)
+	require.Equal(t, pb.PushResponse_COMPLETED, resp.GetStatus(), resp.GetErrorMessage())
+	require.Len(t, resp.GetSubtreeResults(), 3)
+	for i, path := range []string{"packages/api", "packages/web", "tools"} {
+		result := resp.GetSubtreeResults()[i]
+		assert.Equal(t, path, result.GetPath())
+		assert.True(t, result.GetSuccess())
+		assert.Equal(t, int32(1), result.GetFilesChanged())
+	}
+	assert.ElementsMatch(t, []string{"packages/api/main.go", "packages/web/main.go", "tools/main.go"}, resp.GetCreatedFiles())
+
+	// Each sub-tree has its own rsync, writing to and backing up only its directory.
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	for _, path := range []string{"packages/api", "packages/web", "tools"} {
+		found := false
+		for _, line := range lines {
+			args := strings.Fields(line)
+			if args[len(args)-1] == filepath.Join(rw.targetSyncDir, path)+"/" {
+				found = true
+				assert.Contains(t, line, filepath.FromSlash(path)+" --out-format", "the backup directory is the sub-tree's")
+			}
+		}
+		assert.True(t, found, "no rsync for %s", path)
+	}
+
+	// Sent again, the push changes nothing.
+	pushMsg.PushId = "push-2"
+	require.NoError(t, rw.handlePushRequest(context.Background(), pushMsg))
+	assert.True(t, waitForPushResponse(t, mockServer).GetNoOp())
+}
+
+func TestHandlePushRequest_SubtreeBatchFails(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+	rw.subtreeWorkers = 1
+	t.Setenv("HELPER_RSYNC_FAIL_DEST", "packages/web")
+
+	err := rw.handlePushRequest(context.Background(), newSubtreePush("push-1", "packages/api", "packages/web", "tools"))
+	assert.Error(t, err)
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "sub-tree packages/web")
+	results := resp.GetSubtreeResults()
+	require.Len(t, results, 3)
+	assert.True(t, results[0].GetSuccess())
+	assert.False(t, results[1].GetSuccess())
+	assert.Contains(t, results[1].GetErrorMessage(), "rsync command failed")
+	assert.False(t, results[2].GetSuccess())
+	assert.Equal(t, "stopped because sub-tree packages/web failed", results[2].GetErrorMessage())
+}
+
+func TestHandlePushRequest_SubtreeBatchesRejected(t *testing.T) {
+	rw, mockServer := newRsyncOptionsTestSyncer(t)
+
+	assert.Error(t, rw.handlePushRequest(context.Background(), newSubtreePush("push-1", "api")))
+	resp := waitForPushResponse(t, mockServer)
+	assert.Equal(t, pb.PushResponse_FAILED, resp.GetStatus())
+	assert.Contains(t, resp.GetErrorMessage(), "sub-tree batches are disabled")
+
+	rw.subtreeWorkers = 2
+	pushMsg := newSubtreePush("push-2", "api")
+	pushMsg.BatchFile = []byte("batch")
+	assert.Error(t, rw.handlePushRequest(context.Background(), pushMsg))
+	resp = waitForPushResponse(t, mockServer)
+	assert.Contains(t, resp.GetErrorMessage(), "both a batch and sub-tree batches")
+}
@@ -0,0 +1,132 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// syncBackup records what an rsync batch changed so it can be undone. rsync
+// moves every file it replaces into dir (--backup-dir), and changes lists the
+// itemized changes it reported, relative to targetDir.
+//
+// In swap mode the batch is applied to a new release instead; release is set to
+// targetDir and dir is empty, and undoing the batch removes the release.
+// previous is the tree the release was copied from.
+type syncBackup struct {
+	targetDir string
+	dir       string
+	release   string
+	previous  string
+	changes   []itemizedChange
+}
+
+// newReleaseBackup stages a new release to apply a batch to in swap mode.
+func newReleaseBackup(filesDir string) (*syncBackup, error) {
+	release, err := stageRelease(filesDir)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := currentRelease(filesDir)
+	if err != nil {
+		os.RemoveAll(release)
+		return nil, err
+	}
+	if previous == "" {
+		previous = filesDir
+	}
+	return &syncBackup{targetDir: release, release: release, previous: previous}, nil
+}
+
+// newSyncBackup creates an empty backup directory under sidecarDir. It lives on
+// the same filesystem as targetDir so files can be moved back with a rename.
+func newSyncBackup(targetDir, sidecarDir string) (*syncBackup, error) {
+	dir, err := os.MkdirTemp(sidecarDir, "sync_backup_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup directory in %s: %w", sidecarDir, err)
+	}
+	return &syncBackup{targetDir: targetDir, dir: dir}, nil
+}
+
+// restore removes the files the batch created and moves the replaced files back.
+func (b *syncBackup) restore() error {
+	if b.release != "" {
+		// The release was never activated, so nothing outside it changed.
+		if err := os.RemoveAll(b.release); err != nil {
+			return fmt.Errorf("failed to remove staged release %s: %w", b.release, err)
+		}
+		return nil
+	}
+
+	var errs []error
+
+	// Remove created paths in reverse, so files go before their parent directories.
+	// Files that existed before the batch are put back from the backup below, and
+	// directories that still hold other files are left alone.
+	for i := len(b.changes) - 1; i >= 0; i-- {
+		change := b.changes[i]
+		if !change.isCreated() || change.flags[0] == '.' {
+			continue
+		}
+		path := filepath.Join(b.targetDir, change.path)
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) && !(errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST)) {
+			errs = append(errs, fmt.Errorf("failed to remove created path %s: %w", path, err))
+		}
+	}
+
+	err := filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(b.targetDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to recreate directory for %s: %w", dst, err)
+		}
+		if err := os.Rename(path, dst); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", dst, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	b.discard()
+	return errors.Join(errs...)
+}
+
+// replacedPath returns where the file that rel, relative to targetDir,
+// replaced is now: in the backup directory, or in the previous release in swap
+// mode. It returns "" if rel replaced nothing.
+func (b *syncBackup) replacedPath(rel string) string {
+	dir := b.dir
+	if b.release != "" {
+		dir = b.previous
+	}
+	if dir == "" {
+		return ""
+	}
+	path := filepath.Join(dir, rel)
+	if _, err := os.Lstat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// discard deletes the backup directory. It is safe to call more than once.
+func (b *syncBackup) discard() {
+	if b.dir != "" {
+		os.RemoveAll(b.dir)
+	}
+}
@@ -0,0 +1,38 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestSyncBackup_Restore(t *testing.T) {
+	targetDir := t.TempDir()
+	sidecarDir := launcher.SidecarDir(targetDir)
+	require.NoError(t, os.MkdirAll(sidecarDir, 0755))
+
+	backup, err := newSyncBackup(targetDir, sidecarDir)
+	require.NoError(t, err)
+
+	// Simulate rsync replacing main.go (old copy moved to the backup dir) and creating pkg/new.go.
+	// Replayed batches can report existing files and directories as created.
+	require.NoError(t, os.WriteFile(filepath.Join(backup.dir, "main.go"), []byte("old"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "main.go"), []byte("new"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(targetDir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "pkg", "new.go"), []byte("new"), 0644))
+	backup.changes = parseItemizedChanges([]byte("cd+++++++++ ./\n>f+++++++++ main.go\ncd+++++++++ pkg/\n>f+++++++++ pkg/new.go\n"))
+
+	require.NoError(t, backup.restore())
+
+	content, err := os.ReadFile(filepath.Join(targetDir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+	assert.NoDirExists(t, filepath.Join(targetDir, "pkg"))
+	assert.NoDirExists(t, backup.dir)
+	assert.DirExists(t, targetDir)
+}
@@ -0,0 +1,142 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+// handleSyncStatusRequest builds the sync status off the read loop and sends
+// it in a SYNC_STATUS_RESPONSE.
+func (rw *FileSyncer) handleSyncStatusRequest(req *pb.SyncStatusRequest) error {
+	if req == nil {
+		return fmt.Errorf("received SYNC_STATUS_REQUEST but sync_status_request field is nil")
+	}
+	go func() {
+		rw.sendProtoMessage(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_SYNC_STATUS_RESPONSE,
+			Message:     &pb.WebsocketMessage_SyncStatusResponse{SyncStatusResponse: rw.buildSyncStatus(req)},
+		})
+	}()
+	return nil
+}
+
+// buildSyncStatus snapshots what the sidecar has applied, with the latest
+// entries of the audit log if req asks for them. Parts that can't be read are
+// left out and reported in the error message.
+func (rw *FileSyncer) buildSyncStatus(req *pb.SyncStatusRequest) *pb.SyncStatusResponse {
+	requestID := req.RequestId
+	resp := &pb.SyncStatusResponse{RequestId: requestID}
+	var errs []error
+
+	rw.stateMu.Lock()
+	resp.LastPushId = rw.applied.LastPushID
+	resp.LastPushHash = rw.applied.LastPushHash
+	if !rw.applied.AppliedAt.IsZero() {
+		resp.LastAppliedAt = timestamppb.New(rw.applied.AppliedAt)
+	}
+	rw.stateMu.Unlock()
+
+	resp.ActivePushId, resp.QueuedPushes = rw.pushes.status()
+	launcherState, launcherPID := launcher.State(rw.targetSyncDir, rw.processFinder)
+	resp.LauncherState = launcherState
+	resp.LauncherPid = int32(launcherPID)
+
+	envFiles, err := envfile.List(rw.targetSyncDir)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, envFile := range envFiles {
+		path, err := filepath.Rel(rw.targetSyncDir, envFile.Path)
+		if err != nil {
+			path = envFile.Path
+		}
+		resp.EnvFiles = append(resp.EnvFiles, &pb.EnvFileVersion{Path: path, Version: envFile.Version})
+	}
+	provenance, err := envfile.ReadProvenance(rw.targetSyncDir)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	resp.EnvProvenance = envProvenance(provenance)
+
+	files, err := rw.workspaceInventory()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to hash workspace: %w", err))
+	} else {
+		resp.WorkspaceHash = workspaceHash(files)
+	}
+
+	if req.AuditEntries > 0 {
+		records, err := readAuditLog(rw.targetSyncDir, int(req.AuditEntries))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		resp.AuditLog = auditEntries(records)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		log.Warn("Sync status is incomplete", zap.String("requestID", requestID), zap.Error(err))
+		resp.ErrorMessage = err.Error()
+	}
+	return resp
+}
+
+// envProvenance converts the recorded provenance of the env vars.
+func envProvenance(records []envfile.Provenance) []*pb.EnvVarProvenance {
+	var provenance []*pb.EnvVarProvenance
+	for _, record := range records {
+		source := pb.EnvVarProvenance_UNKNOWN
+		switch record.Source {
+		case envfile.SourceDatabase:
+			source = pb.EnvVarProvenance_DATABASE
+		case envfile.SourcePush:
+			source = pb.EnvVarProvenance_PUSH
+		case envfile.SourceSecretProvider:
+			source = pb.EnvVarProvenance_SECRET_PROVIDER
+		}
+		provenance = append(provenance, &pb.EnvVarProvenance{
+			Name:      record.Name,
+			Scope:     record.Scope,
+			Source:    source,
+			Provider:  record.Provider,
+			SecretRef: record.SecretRef,
+			PushId:    record.PushID,
+			Version:   record.Version,
+			UpdatedAt: timestamppb.New(record.UpdatedAt),
+		})
+	}
+	return provenance
+}
+
+// workspaceHash identifies the synced code by hashing the path and content
+// hash of every file in the inventory, which is sorted by path.
+func workspaceHash(files []*pb.FileEntry) string {
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00%s\n", file.Path, file.Sha256)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// status returns the push being applied, if any, and how many pushes are
+// waiting to be applied.
+func (q *pushQueue) status() (activeID string, queued int32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, cancelled := range q.queued {
+		if !cancelled {
+			queued++
+		}
+	}
+	return q.activeID, queued
+}
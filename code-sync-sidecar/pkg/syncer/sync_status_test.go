@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/envfile"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestHandleSyncStatusRequest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("app"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(dir), "env.sh"), []byte("export A=1\n"), 0600))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("12345"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{
+		targetSyncDir: dir,
+		processFinder: &mockProcessFinder{processes: make(map[int]*mockProcess)},
+		conn:          conn,
+	}
+	rw.recordApplied("push-1", batchHash([]byte("batch")))
+	_, err := envfile.RecordProvenance(dir, "push-1", envfile.ProviderBifrost, []envfile.DatabaseEnvVar{{EnvVarName: "A", ConnectionURI: "1"}}, time.Now())
+	require.NoError(t, err)
+	rw.pushes.activeID = "push-2"
+	rw.pushes.queued = map[string]bool{"push-3": false, "push-4": true}
+
+	require.NoError(t, rw.handleSyncStatusRequest(&pb.SyncStatusRequest{RequestId: "req-1"}))
+
+	var resp *pb.SyncStatusResponse
+	select {
+	case message := <-mockServer.messages:
+		var wsMessage pb.WebsocketMessage
+		require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		require.Equal(t, pb.WebsocketMessage_SYNC_STATUS_RESPONSE, wsMessage.MessageType)
+		resp = wsMessage.GetSyncStatusResponse()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for sync status response")
+	}
+
+	assert.Equal(t, "req-1", resp.RequestId)
+	assert.Empty(t, resp.ErrorMessage)
+	assert.Equal(t, "push-1", resp.LastPushId)
+	assert.Equal(t, batchHash([]byte("batch")), resp.LastPushHash)
+	assert.NotNil(t, resp.LastAppliedAt)
+	assert.Equal(t, "push-2", resp.ActivePushId)
+	assert.Equal(t, int32(1), resp.QueuedPushes, "cancelled pushes aren't counted")
+	assert.Equal(t, pb.StatusReport_RUNNING, resp.LauncherState)
+	assert.Equal(t, int32(12345), resp.LauncherPid)
+	require.Len(t, resp.EnvFiles, 1)
+	assert.Equal(t, filepath.Join(".sidecar", "env.sh"), resp.EnvFiles[0].Path)
+	assert.NotEmpty(t, resp.EnvFiles[0].Version)
+	require.Len(t, resp.EnvProvenance, 1)
+	assert.Equal(t, "A", resp.EnvProvenance[0].Name)
+	assert.Equal(t, pb.EnvVarProvenance_DATABASE, resp.EnvProvenance[0].Source)
+	assert.Equal(t, "push-1", resp.EnvProvenance[0].PushId)
+
+	files, err := rw.workspaceInventory()
+	require.NoError(t, err)
+	assert.Equal(t, workspaceHash(files), resp.WorkspaceHash)
+
+	// The workspace hash follows the content of the synced files.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("changed"), 0644))
+	assert.NotEqual(t, resp.WorkspaceHash, rw.buildSyncStatus(&pb.SyncStatusRequest{RequestId: "req-2"}).WorkspaceHash)
+}
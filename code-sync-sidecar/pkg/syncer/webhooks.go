@@ -0,0 +1,281 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// The events posted to webhooks.
+const (
+	WebhookPushApplied     = "push.applied"
+	WebhookPushFailed      = "push.failed"
+	WebhookDriftDetected   = "drift.detected"
+	WebhookLauncherCrashed = "launcher.crashed"
+)
+
+var webhookEvents = []string{WebhookPushApplied, WebhookPushFailed, WebhookDriftDetected, WebhookLauncherCrashed}
+
+const (
+	DefaultWebhookTimeout = 10 * time.Second
+
+	// webhookAttempts is how many times an event is posted to a URL that fails
+	// with a network error or a 5xx or 429 response, webhookRetryBackoff apart
+	// and more each time.
+	webhookAttempts     = 3
+	webhookRetryBackoff = 2 * time.Second
+
+	// maxWebhookFiles bounds the drifted files listed in an event.
+	maxWebhookFiles = 100
+	// maxWebhookErrorLength bounds the error in an event, which can include
+	// rsync's output.
+	maxWebhookErrorLength = 4096
+)
+
+// WebhookEvent is the JSON body posted to webhooks. Text sums it up in a line,
+// which chat tools such as Slack show as the message.
+type WebhookEvent struct {
+	Event        string    `json:"event"`
+	Text         string    `json:"text"`
+	AppID        string    `json:"app_id"`
+	DeploymentID string    `json:"deployment_id"`
+	Pod          string    `json:"pod,omitempty"`
+	Time         time.Time `json:"time"`
+	PushID       string    `json:"push_id,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DriftedFiles []string  `json:"drifted_files,omitempty"`
+	// The launcher's PID and why it exited, for launcher.crashed.
+	PID       int32  `json:"pid,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	AppStatus string `json:"app_status,omitempty"`
+}
+
+// WebhookEmitter posts the sidecar's events to outbound webhooks, so alerts
+// can be wired to chat or paging tools straight from the deployment. When a
+// secret is configured each request is signed with an HMAC-SHA256 of its
+// timestamp and body. Delivery is best effort: an event that still fails
+// after the retries is logged and dropped.
+type WebhookEmitter struct {
+	urls         []string
+	events       map[string]bool // Every event when nil
+	secretPath   string
+	appID        string
+	deploymentID string
+	client       *http.Client
+	backoff      time.Duration
+}
+
+// NewWebhookEmitter creates a WebhookEmitter, or returns nil if no URLs are
+// configured.
+func NewWebhookEmitter(cfg WebhooksConfig, appID, deploymentID string) *WebhookEmitter {
+	if len(cfg.URLs) == 0 {
+		return nil
+	}
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	e := &WebhookEmitter{
+		urls:         cfg.URLs,
+		secretPath:   cfg.SecretPath,
+		appID:        appID,
+		deploymentID: deploymentID,
+		client:       &http.Client{Timeout: timeout},
+		backoff:      webhookRetryBackoff,
+	}
+	if len(cfg.Events) > 0 {
+		e.events = make(map[string]bool, len(cfg.Events))
+		for _, event := range cfg.Events {
+			e.events[event] = true
+		}
+	}
+	return e
+}
+
+// Emit posts event to every URL in the background, unless the emitter is nil
+// or the event isn't one it sends.
+func (e *WebhookEmitter) Emit(event WebhookEvent) {
+	if e == nil || (e.events != nil && !e.events[event.Event]) {
+		return
+	}
+	event.AppID = e.appID
+	event.DeploymentID = e.deploymentID
+	event.Pod = Pod.GetPodName()
+	event.Time = time.Now().UTC()
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Warn("Failed to encode webhook event", zap.String("event", event.Event), zap.Error(err))
+		return
+	}
+	for _, target := range e.urls {
+		go func() {
+			if err := e.deliver(context.Background(), target, event.Event, body); err != nil {
+				log.Warn("Failed to deliver webhook", zap.String("event", event.Event), zap.String("url", redactWebhookURL(target)), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// deliver posts body to target, retrying failures that may be temporary.
+func (e *WebhookEmitter) deliver(ctx context.Context, target, event string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		if retry, err = e.post(ctx, target, event, body); err == nil || !retry {
+			return err
+		}
+		if attempt < webhookAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * e.backoff):
+			}
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", webhookAttempts, err)
+}
+
+// post makes one attempt at delivering body, and reports whether a failure
+// is worth retrying.
+func (e *WebhookEmitter) post(ctx context.Context, target, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bifrost-Event", event)
+	req.Header.Set("X-Bifrost-Timestamp", timestamp)
+	if e.secretPath != "" {
+		// Read on every delivery, so a rotated secret is picked up.
+		secret, err := os.ReadFile(e.secretPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read webhook secret: %w", err)
+		}
+		req.Header.Set("X-Bifrost-Signature", signWebhook(bytes.TrimSpace(secret), timestamp, body))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// signWebhook returns the signature of a webhook request: "sha256=" and the
+// hex HMAC-SHA256, keyed with secret, of its timestamp, a dot and its body.
+// Signing the timestamp lets a receiver reject replayed requests.
+func signWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// emitPushWebhook posts push.applied or push.failed for a push's final
+// response. Cancelled and superseded pushes, duplicates and replayed responses
+// post nothing.
+func (rw *FileSyncer) emitPushWebhook(resp *pb.PushResponse) {
+	if resp == nil || resp.PushId == "" || resp.Replayed || resp.AlreadyApplied || isIntermediatePushStatus(resp.Status) {
+		return
+	}
+	event := WebhookEvent{PushID: resp.PushId, Status: resp.Status.String()}
+	switch resp.Status {
+	case pb.PushResponse_COMPLETED:
+		event.Event = WebhookPushApplied
+		event.Text = fmt.Sprintf("Push %s was applied to %s", resp.PushId, rw.deploymentID)
+	case pb.PushResponse_CANCELLED, pb.PushResponse_SUPERSEDED:
+		return
+	default:
+		event.Event = WebhookPushFailed
+		event.Error = truncateOutput([]byte(resp.ErrorMessage), maxWebhookErrorLength)
+		event.Text = fmt.Sprintf("Push %s to %s failed (%s): %s", resp.PushId, rw.deploymentID, resp.Status, firstLine(resp.ErrorMessage))
+	}
+	rw.getWebhooks().Emit(event)
+}
+
+// emitDriftWebhook posts drift.detected for drifted files, unless it was
+// already posted for the same files.
+func (rw *FileSyncer) emitDriftWebhook(req *pb.ResyncRequest) {
+	rw.stateMu.Lock()
+	if rw.lastDriftWebhook == req.WorkspaceHash {
+		rw.stateMu.Unlock()
+		return
+	}
+	rw.lastDriftWebhook = req.WorkspaceHash
+	rw.stateMu.Unlock()
+
+	rw.getWebhooks().Emit(WebhookEvent{
+		Event:        WebhookDriftDetected,
+		Text:         fmt.Sprintf("Files in %s drifted from the last push: %s", rw.deploymentID, req.Detail),
+		PushID:       req.LastPushId,
+		DriftedFiles: req.DriftedFiles[:min(len(req.DriftedFiles), maxWebhookFiles)],
+	})
+}
+
+// emitLauncherWebhook posts launcher.crashed for the launcher exiting.
+func (rw *FileSyncer) emitLauncherWebhook(exited *pb.LauncherExited) {
+	reason := exited.Reason
+	if reason == "" {
+		reason = "killed"
+	}
+	rw.getWebhooks().Emit(WebhookEvent{
+		Event:     WebhookLauncherCrashed,
+		Text:      fmt.Sprintf("The launcher in %s exited: %s", rw.deploymentID, reason),
+		PushID:    exited.LastPushId,
+		PID:       exited.Pid,
+		Reason:    exited.Reason,
+		AppStatus: exited.AppStatus,
+	})
+}
+
+// getWebhooks returns the webhook emitter, or nil if no webhooks are configured.
+func (rw *FileSyncer) getWebhooks() *WebhookEmitter {
+	rw.settingsMu.RLock()
+	defer rw.settingsMu.RUnlock()
+	return rw.webhooks
+}
+
+// redactWebhookURL removes the path and query string of a webhook URL as well
+// as any credentials, since services such as Slack put the secret that
+// authorizes posting in the path.
+func redactWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	redactedURL := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	if u.Path != "" && u.Path != "/" {
+		redactedURL.Path = "/" + redacted
+	}
+	return redactedURL.String()
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,107 @@
+package syncer
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// Directions of a logged wire message.
+const (
+	wireInbound  = "in"
+	wireOutbound = "out"
+)
+
+// wireSecretFields are string fields whose whole value is a credential.
+var wireSecretFields = map[protoreflect.Name]bool{
+	"session_token": true,
+	"resume_token":  true,
+}
+
+var (
+	// wireURLCredentials matches the credentials in a URL, e.g. a database
+	// connection URI, up to the @ before its host.
+	wireURLCredentials = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://)[^/\s@]+@`)
+	// wireEnvAssignment matches an env var assignment, e.g. in hook output.
+	wireEnvAssignment = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)=("[^"]*"|'[^']*'|\S+)`)
+)
+
+// logWireMessage logs msg decoded, with redactWireMessage applied, if wire
+// logging is on.
+func (rw *FileSyncer) logWireMessage(direction string, msg proto.Message) {
+	if !rw.getWireLog() {
+		return
+	}
+	log.Info("Wire message",
+		zap.String("direction", direction),
+		zap.String("type", string(msg.ProtoReflect().Descriptor().Name())),
+		zap.Any("message", redactWireMessage(msg.ProtoReflect())),
+	)
+}
+
+// redactWireMessage returns the set fields of msg as a map for logging, without
+// anything that could be a secret: bytes fields, such as batches and file
+// contents, are replaced by their size, map values such as headers by
+// REDACTED, and credentials in URLs and the values of env var assignments are
+// removed from strings.
+func redactWireMessage(msg protoreflect.Message) map[string]any {
+	out := make(map[string]any)
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			entries := make(map[string]any, v.Map().Len())
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				if fd.MapValue().Message() != nil {
+					entries[k.String()] = redactWireMessage(mv.Message())
+				} else {
+					entries[k.String()] = redacted
+				}
+				return true
+			})
+			out[string(fd.Name())] = entries
+		case fd.IsList():
+			list := v.List()
+			values := make([]any, list.Len())
+			for i := range values {
+				values[i] = redactWireValue(fd, list.Get(i))
+			}
+			out[string(fd.Name())] = values
+		case wireSecretFields[fd.Name()]:
+			out[string(fd.Name())] = redacted
+		default:
+			out[string(fd.Name())] = redactWireValue(fd, v)
+		}
+		return true
+	})
+	return out
+}
+
+// redactWireValue returns one value of fd for logging.
+func redactWireValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return redactWireMessage(v.Message())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("[%d bytes]", len(v.Bytes()))
+	case protoreflect.StringKind:
+		return redactWireString(v.String())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	default:
+		return v.Interface()
+	}
+}
+
+// redactWireString removes URL credentials and env var values from s.
+func redactWireString(s string) string {
+	s = wireURLCredentials.ReplaceAllString(s, "${1}"+redacted+"@")
+	return wireEnvAssignment.ReplaceAllString(s, "${1}="+redacted)
+}
@@ -0,0 +1,47 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestRedactWireMessage(t *testing.T) {
+	msg := &pb.WebsocketMessage{
+		MessageType: pb.WebsocketMessage_PUSH_REQUEST,
+		Message: &pb.WebsocketMessage_PushMessage{PushMessage: &pb.PushMessage{
+			PushId:      "push-1",
+			BatchFile:   []byte("batch-data"),
+			Files:       map[string]*pb.InjectedFile{"config/app.env": {Content: []byte("SECRET=1"), Mode: 0600}},
+			RequiredEnv: []string{"DATABASE_URL"},
+		}},
+	}
+
+	assert.Equal(t, map[string]any{
+		"message_type": "PUSH_REQUEST",
+		"push_message": map[string]any{
+			"push_id":    "push-1",
+			"batch_file": "[10 bytes]",
+			"files": map[string]any{
+				"config/app.env": map[string]any{"content": "[8 bytes]", "mode": uint32(0600)},
+			},
+			"required_env": []any{"DATABASE_URL"},
+		},
+	}, redactWireMessage(msg.ProtoReflect()))
+
+	auth := &pb.AuthMessage{SessionToken: "token"}
+	assert.Equal(t, map[string]any{"session_token": redacted}, redactWireMessage(auth.ProtoReflect()))
+}
+
+func TestRedactWireString(t *testing.T) {
+	for input, want := range map[string]string{
+		"connecting to postgres://app:hunter2@db:5432/app": "connecting to postgres://REDACTED@db:5432/app",
+		`export DATABASE_URL="postgres://db/app" && run`:   "export DATABASE_URL=REDACTED && run",
+		"GET https://api.example.com/health?token=abc":     "GET https://api.example.com/health?token=REDACTED",
+		"rsync exited with status 23":                      "rsync exited with status 23",
+	} {
+		assert.Equal(t, want, redactWireString(input))
+	}
+}
@@ -0,0 +1,88 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+// handleManifestRequest builds an inventory of the synced files off the read
+// loop and sends it in a MANIFEST_RESPONSE.
+func (rw *FileSyncer) handleManifestRequest(req *pb.ManifestRequest) error {
+	if req == nil {
+		return fmt.Errorf("received MANIFEST_REQUEST but manifest_request field is nil")
+	}
+	go func() {
+		resp := &pb.ManifestResponse{RequestId: req.RequestId}
+		files, err := rw.workspaceInventory()
+		if err != nil {
+			log.Error("Failed to build workspace inventory", zap.Error(err))
+			resp.ErrorMessage = err.Error()
+		} else {
+			resp.Files = files
+		}
+		rw.sendProtoMessage(&pb.WebsocketMessage{
+			MessageType: pb.WebsocketMessage_MANIFEST_RESPONSE,
+			Message:     &pb.WebsocketMessage_ManifestResponse{ManifestResponse: resp},
+		})
+	}()
+	return nil
+}
+
+// workspaceInventory lists the synced files. It holds workspaceMu so the
+// inventory never shows a push half applied.
+func (rw *FileSyncer) workspaceInventory() ([]*pb.FileEntry, error) {
+	rw.workspaceMu.Lock()
+	defer rw.workspaceMu.Unlock()
+
+	dir, err := rw.contentDir()
+	if err != nil {
+		return nil, err
+	}
+	known, err := loadManifest(rw.targetSyncDir)
+	if err != nil {
+		log.Warn("Failed to load manifest, hashing every file", zap.Error(err))
+		known = fileManifest{}
+	}
+	return inventoryFiles(dir, known)
+}
+
+// inventoryFiles describes every regular file under dir, sorted by path. Hashes
+// recorded in known are reused for files whose size and modification time
+// haven't changed since.
+func inventoryFiles(dir string, known fileManifest) ([]*pb.FileEntry, error) {
+	paths, err := listRegularFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	files := make([]*pb.FileEntry, 0, len(paths))
+	for _, rel := range paths {
+		path := filepath.Join(dir, rel)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		sum := ""
+		if entry, ok := known[rel]; ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+			sum = entry.SHA256
+		} else if sum, err = hashFile(path); err != nil {
+			return nil, err
+		}
+		files = append(files, &pb.FileEntry{
+			Path:       filepath.ToSlash(rel),
+			SizeBytes:  info.Size(),
+			ModifiedAt: timestamppb.New(info.ModTime()),
+			Sha256:     sum,
+		})
+	}
+	return files, nil
+}
@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+	"github.com/bifrostinc/code-sync-sidecar/pkg/launcher"
+)
+
+func TestInventoryFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "mod.py"), []byte("mod"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("app"), 0644))
+	require.NoError(t, os.Symlink("app.py", filepath.Join(dir, "main.py")))
+	require.NoError(t, os.MkdirAll(launcher.SidecarDir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.SidecarDir(dir), "state.json"), []byte("{}"), 0644))
+	require.NoError(t, os.MkdirAll(launcher.Dir(dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(launcher.Dir(dir), "launcher.pid"), []byte("1"), 0644))
+
+	// A recorded hash is reused while the file's size and mtime match.
+	known := fileManifest{}
+	require.NoError(t, known.record(dir, []string{"app.py"}))
+	entry := known["app.py"]
+	entry.SHA256 = "recorded"
+	known["app.py"] = entry
+
+	files, err := inventoryFiles(dir, known)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "app.py", files[0].Path)
+	assert.Equal(t, "recorded", files[0].Sha256)
+	assert.Equal(t, "pkg/mod.py", files[1].Path)
+	assert.Equal(t, int64(3), files[1].SizeBytes)
+	modHash, err := hashFile(filepath.Join(dir, "pkg", "mod.py"))
+	require.NoError(t, err)
+	assert.Equal(t, modHash, files[1].Sha256)
+	assert.WithinDuration(t, time.Now(), files[1].ModifiedAt.AsTime(), time.Minute)
+
+	// A file changed since it was recorded is hashed again.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("changed"), 0644))
+	files, err = inventoryFiles(dir, known)
+	require.NoError(t, err)
+	assert.NotEqual(t, "recorded", files[0].Sha256)
+}
+
+func TestHandleManifestRequest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("app"), 0644))
+	conn, mockServer := newMockWebsocket(t)
+	defer conn.Close()
+	rw := &FileSyncer{targetSyncDir: dir, conn: conn}
+
+	require.NoError(t, rw.handleManifestRequest(&pb.ManifestRequest{RequestId: "req-1"}))
+
+	select {
+	case message := <-mockServer.messages:
+		var wsMessage pb.WebsocketMessage
+		require.NoError(t, proto.Unmarshal(message, &wsMessage))
+		require.Equal(t, pb.WebsocketMessage_MANIFEST_RESPONSE, wsMessage.MessageType)
+		resp := wsMessage.GetManifestResponse()
+		assert.Equal(t, "req-1", resp.RequestId)
+		assert.Empty(t, resp.ErrorMessage)
+		require.Len(t, resp.Files, 1)
+		assert.Equal(t, "app.py", resp.Files[0].Path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for manifest response")
+	}
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+const (
+	// PollWait is how long the proxy holds a poll open when no messages are queued.
+	PollWait = 25 * time.Second
+	// pollRequestSlack is added to PollWait for the HTTP client timeout, so a
+	// poll the proxy holds for the full wait isn't cut short.
+	pollRequestSlack = 10 * time.Second
+)
+
+// IsUpgradeBlocked reports whether a failed websocket dial looks like
+// something between the sidecar and the proxy refusing the upgrade, rather than
+// the proxy being down or rejecting the credentials.
+func IsUpgradeBlocked(resp *http.Response, err error) bool {
+	if resp == nil || !errors.Is(err, websocket.ErrBadHandshake) {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusUpgradeRequired:
+		return true
+	}
+	// A plain 2xx means a proxy answered the request itself and dropped the upgrade.
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// LongPoller exchanges messages with the proxy over HTTP: a GET that the proxy
+// holds until messages are queued for the sidecar, and a POST per message sent.
+// It stands in for the websocket on networks that block upgrades.
+type LongPoller struct {
+	pendingURL string
+	tokens     *TokenManager
+	client     *http.Client
+}
+
+// NewLongPoller creates a LongPoller for the pending URL of a sidecar (see PendingURL).
+func NewLongPoller(pendingURL string, tokens *TokenManager) *LongPoller {
+	return &LongPoller{
+		pendingURL: pendingURL,
+		tokens:     tokens,
+		client:     &http.Client{Timeout: PollWait + pollRequestSlack},
+	}
+}
+
+// URL returns the pending URL the poller exchanges messages with.
+func (lp *LongPoller) URL() string {
+	return lp.pendingURL
+}
+
+// Poll waits up to PollWait for messages queued for the sidecar.
+func (lp *LongPoller) Poll(ctx context.Context) ([]*pb.WebsocketMessage, error) {
+	u, err := url.Parse(lp.pendingURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll URL %q: %w", lp.pendingURL, err)
+	}
+	q := u.Query()
+	q.Set("wait", strconv.Itoa(int(PollWait.Seconds())))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poll request: %w", err)
+	}
+	body, err := lp.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("poll failed: %w", err)
+	}
+	var batch pb.MessageBatch
+	if err := proto.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal poll response: %w", err)
+	}
+	return batch.Messages, nil
+}
+
+// Post sends one marshalled WebsocketMessage to the proxy.
+func (lp *LongPoller) Post(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pollRequestSlack)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, lp.pendingURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create post request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if _, err := lp.do(req); err != nil {
+		return fmt.Errorf("post failed: %w", err)
+	}
+	return nil
+}
+
+func (lp *LongPoller) do(req *http.Request) ([]byte, error) {
+	if err := lp.tokens.SetAuthHeader(req); err != nil {
+		return nil, fmt.Errorf("failed to obtain credentials: %w", err)
+	}
+	resp, err := lp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		lp.tokens.Invalidate()
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bifrostinc/code-sync-sidecar/pb"
+)
+
+func TestIsUpgradeBlocked(t *testing.T) {
+	response := func(status int) *http.Response { return &http.Response{StatusCode: status} }
+
+	assert.True(t, IsUpgradeBlocked(response(http.StatusForbidden), websocket.ErrBadHandshake))
+	assert.True(t, IsUpgradeBlocked(response(http.StatusUpgradeRequired), websocket.ErrBadHandshake))
+	assert.True(t, IsUpgradeBlocked(response(http.StatusOK), websocket.ErrBadHandshake))
+	assert.False(t, IsUpgradeBlocked(response(http.StatusUnauthorized), websocket.ErrBadHandshake))
+	assert.False(t, IsUpgradeBlocked(response(http.StatusBadGateway), websocket.ErrBadHandshake))
+	assert.False(t, IsUpgradeBlocked(nil, errors.New("connection refused")))
+}
+
+func TestLongPoller(t *testing.T) {
+	var posted []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("X-Api-Key"))
+		if r.Method == http.MethodPost {
+			posted, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		assert.Equal(t, "25", r.URL.Query().Get("wait"))
+		data, _ := proto.Marshal(&pb.MessageBatch{Messages: []*pb.WebsocketMessage{{MessageType: pb.WebsocketMessage_HELLO_ACK}}})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	tokens, err := NewTokenManager(AuthModeAPIKey, server.URL, "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+	pendingURL, err := PendingURL(server.URL, "app1", "deployment1")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/v1/push/pending/app1/deployment1", pendingURL)
+	poller := NewLongPoller(pendingURL, tokens)
+
+	messages, err := poller.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, pb.WebsocketMessage_HELLO_ACK, messages[0].MessageType)
+
+	require.NoError(t, poller.Post([]byte("message")))
+	assert.Equal(t, "message", string(posted))
+}
@@ -0,0 +1,245 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bifrostinc/code-sync-sidecar/log"
+)
+
+// AuthMode selects how the sidecar authenticates against the Bifrost API.
+type AuthMode string
+
+const (
+	// AuthModeAPIKey sends the static BIFROST_API_KEY in the X-Api-Key header.
+	AuthModeAPIKey AuthMode = "api_key"
+	// AuthModeKubernetes exchanges the pod's service-account token for a short-lived Bifrost token.
+	AuthModeKubernetes AuthMode = "kubernetes"
+	// AuthModeOIDC exchanges an OIDC identity token read from disk for a short-lived Bifrost token.
+	AuthModeOIDC AuthMode = "oidc"
+)
+
+const (
+	DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	tokenExchangePath     = "/api/v1/auth/token"
+	tokenExchangeGrant    = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT          = "urn:ietf:params:oauth:token-type:jwt"
+	tokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	tokenRefreshMargin    = 60 * time.Second
+	defaultTokenExpiresIn = 5 * time.Minute
+)
+
+// ParseAuthMode converts a BIFROST_AUTH_MODE value into an AuthMode, defaulting to API key auth.
+func ParseAuthMode(value string) (AuthMode, error) {
+	switch AuthMode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", AuthModeAPIKey:
+		return AuthModeAPIKey, nil
+	case AuthModeKubernetes:
+		return AuthModeKubernetes, nil
+	case AuthModeOIDC:
+		return AuthModeOIDC, nil
+	default:
+		return "", fmt.Errorf("unsupported auth mode %q (expected %s, %s or %s)", value, AuthModeAPIKey, AuthModeKubernetes, AuthModeOIDC)
+	}
+}
+
+// tokenExchangeRequest is the body sent to the token exchange endpoint.
+type tokenExchangeRequest struct {
+	GrantType        string `json:"grant_type"`
+	SubjectToken     string `json:"subject_token"`
+	SubjectTokenType string `json:"subject_token_type"`
+	AppID            string `json:"app_id"`
+	DeploymentID     string `json:"deployment_id"`
+}
+
+// tokenExchangeResponse is the body returned by the token exchange endpoint.
+type tokenExchangeResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// TokenManager provides credentials for requests to the Bifrost API. In API key
+// mode it returns the static key; otherwise it exchanges an identity token for a
+// short-lived Bifrost token and refreshes it shortly before it expires. It is
+// shared by the websocket and HTTP clients.
+type TokenManager struct {
+	mode              AuthMode
+	apiURL            string
+	apiKey            string
+	identityTokenPath string
+	appID             string
+	deploymentID      string
+	httpClient        *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewTokenManager creates a TokenManager for the given auth mode.
+func NewTokenManager(mode AuthMode, apiURL, apiKey, identityTokenPath, appID, deploymentID string) (*TokenManager, error) {
+	switch mode {
+	case AuthModeAPIKey:
+		if apiKey == "" {
+			return nil, fmt.Errorf("an API key is required in %s auth mode", mode)
+		}
+	case AuthModeKubernetes:
+		if identityTokenPath == "" {
+			identityTokenPath = DefaultServiceAccountTokenPath
+		}
+	case AuthModeOIDC:
+		if identityTokenPath == "" {
+			return nil, fmt.Errorf("an identity token path is required in %s auth mode", mode)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth mode %q", mode)
+	}
+
+	return &TokenManager{
+		mode:              mode,
+		apiURL:            apiURL,
+		apiKey:            apiKey,
+		identityTokenPath: identityTokenPath,
+		appID:             appID,
+		deploymentID:      deploymentID,
+		httpClient:        &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Mode returns the auth mode the manager was configured with.
+func (tm *TokenManager) Mode() AuthMode {
+	return tm.mode
+}
+
+// Token returns a valid credential, exchanging a new token if the cached one is
+// missing or about to expire.
+func (tm *TokenManager) Token(ctx context.Context) (string, error) {
+	if tm.mode == AuthModeAPIKey {
+		return tm.apiKey, nil
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.token != "" && time.Until(tm.expiresAt) > tokenRefreshMargin {
+		return tm.token, nil
+	}
+
+	token, expiresAt, err := tm.exchange(ctx)
+	if err != nil {
+		return "", err
+	}
+	tm.token = token
+	tm.expiresAt = expiresAt
+	log.Info("Obtained Bifrost access token",
+		zap.String("authMode", string(tm.mode)),
+		zap.Time("expiresAt", expiresAt),
+	)
+	return tm.token, nil
+}
+
+// Invalidate drops the cached token so the next call to Token performs a fresh
+// exchange. Callers use it after the API rejects a token as unauthorized.
+func (tm *TokenManager) Invalidate() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.token = ""
+	tm.expiresAt = time.Time{}
+}
+
+// AuthHeaders returns the headers that authenticate a request to the Bifrost API.
+func (tm *TokenManager) AuthHeaders(ctx context.Context) (http.Header, error) {
+	token, err := tm.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tm.mode == AuthModeAPIKey {
+		return http.Header{"X-Api-Key": []string{token}}, nil
+	}
+	return http.Header{"Authorization": []string{"Bearer " + token}}, nil
+}
+
+// SetAuthHeader adds authentication headers to an outgoing HTTP request.
+func (tm *TokenManager) SetAuthHeader(req *http.Request) error {
+	headers, err := tm.AuthHeaders(req.Context())
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Set(key, value)
+		}
+	}
+	return nil
+}
+
+// exchange trades the identity token on disk for a short-lived Bifrost token.
+func (tm *TokenManager) exchange(ctx context.Context) (string, time.Time, error) {
+	// Re-read on every exchange: projected service-account tokens are rotated by the kubelet.
+	identityToken, err := os.ReadFile(tm.identityTokenPath)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to read identity token %s: %w", tm.identityTokenPath, err)
+	}
+
+	subjectTokenType := tokenTypeJWT
+	if tm.mode == AuthModeOIDC {
+		subjectTokenType = tokenTypeIDToken
+	}
+	body, err := json.Marshal(tokenExchangeRequest{
+		GrantType:        tokenExchangeGrant,
+		SubjectToken:     strings.TrimSpace(string(identityToken)),
+		SubjectTokenType: subjectTokenType,
+		AppID:            tm.appID,
+		DeploymentID:     tm.deploymentID,
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode token exchange request: %w", err)
+	}
+
+	exchangeURL, err := EndpointURL(tm.apiURL, tokenExchangePath)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exchangeURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create token exchange request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := tm.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("token exchange request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var exchanged tokenExchangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&exchanged); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode token exchange response: %w", err)
+	}
+	if exchanged.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("token exchange response did not include an access token")
+	}
+
+	expiresIn := time.Duration(exchanged.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = defaultTokenExpiresIn
+	}
+	return exchanged.AccessToken, time.Now().Add(expiresIn), nil
+}
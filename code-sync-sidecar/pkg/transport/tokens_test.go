@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuthMode(t *testing.T) {
+	mode, err := ParseAuthMode("")
+	require.NoError(t, err)
+	assert.Equal(t, AuthModeAPIKey, mode)
+
+	mode, err = ParseAuthMode(" Kubernetes ")
+	require.NoError(t, err)
+	assert.Equal(t, AuthModeKubernetes, mode)
+
+	_, err = ParseAuthMode("basic")
+	assert.Error(t, err)
+}
+
+func TestTokenManager_APIKey(t *testing.T) {
+	tm, err := NewTokenManager(AuthModeAPIKey, "http://localhost:8080", "test-key", "", "app1", "deployment1")
+	require.NoError(t, err)
+
+	headers, err := tm.AuthHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "test-key", headers.Get("X-Api-Key"))
+	assert.Empty(t, headers.Get("Authorization"))
+
+	_, err = NewTokenManager(AuthModeAPIKey, "http://localhost:8080", "", "", "app1", "deployment1")
+	assert.Error(t, err)
+}
+
+func TestTokenManager_Exchange(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenPath := filepath.Join(tmpDir, "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("sa-token\n"), 0600))
+
+	var exchanges atomic.Int32
+	var expiresIn atomic.Int64
+	expiresIn.Store(3600)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, tokenExchangePath, r.URL.Path)
+		var req tokenExchangeRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "sa-token", req.SubjectToken)
+		assert.Equal(t, tokenTypeJWT, req.SubjectTokenType)
+		assert.Equal(t, "app1", req.AppID)
+		assert.Equal(t, "deployment1", req.DeploymentID)
+
+		exchanges.Add(1)
+		json.NewEncoder(w).Encode(tokenExchangeResponse{
+			AccessToken: "bifrost-token",
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn.Load(),
+		})
+	}))
+	defer server.Close()
+
+	tm, err := NewTokenManager(AuthModeKubernetes, server.URL, "", tokenPath, "app1", "deployment1")
+	require.NoError(t, err)
+
+	headers, err := tm.AuthHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer bifrost-token", headers.Get("Authorization"))
+	assert.Empty(t, headers.Get("X-Api-Key"))
+
+	// A cached token that is not close to expiry is reused.
+	_, err = tm.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), exchanges.Load())
+
+	// Tokens inside the refresh margin are exchanged again.
+	tm.Invalidate()
+	expiresIn.Store(10)
+	_, err = tm.Token(context.Background())
+	require.NoError(t, err)
+	_, err = tm.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), exchanges.Load())
+}
+
+func TestTokenManager_ExchangeUnderPathPrefix(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("sa-token"), 0600))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bifrost"+tokenExchangePath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tokenExchangeResponse{AccessToken: "bifrost-token", TokenType: "Bearer", ExpiresIn: 3600})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tm, err := NewTokenManager(AuthModeKubernetes, server.URL+"/bifrost/", "", tokenPath, "app1", "deployment1")
+	require.NoError(t, err)
+	token, err := tm.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "bifrost-token", token)
+}
+
+func TestTokenManager_ExchangeFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenPath := filepath.Join(tmpDir, "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("id-token"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid subject token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tm, err := NewTokenManager(AuthModeOIDC, server.URL, "", tokenPath, "app1", "deployment1")
+	require.NoError(t, err)
+
+	_, err = tm.Token(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+
+	missing, err := NewTokenManager(AuthModeOIDC, server.URL, "", filepath.Join(tmpDir, "missing"), "app1", "deployment1")
+	require.NoError(t, err)
+	_, err = missing.Token(context.Background())
+	assert.ErrorContains(t, err, "failed to read identity token")
+}
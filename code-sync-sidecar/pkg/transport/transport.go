@@ -0,0 +1,46 @@
+// Package transport connects the sidecar to the Bifrost API: it authenticates
+// requests with a TokenManager, builds the sidecar's endpoint URLs, and
+// provides the HTTP long-polling fallback for networks that block websockets.
+package transport
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// EndpointURL returns the URL of the API endpoint at path. Any path apiURL has
+// is kept as a prefix, for a control plane mounted below the root of its host:
+// with https://example.com/bifrost/ pushes come from
+// https://example.com/bifrost/api/v1/push/...
+func EndpointURL(apiURL, path string) (*url.URL, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", apiURL, err)
+	}
+	return u.JoinPath(strings.TrimPrefix(path, "/")), nil
+}
+
+// WebSocketURL returns the websocket URL a sidecar connects to for pushes.
+func WebSocketURL(apiURL, appID, deploymentID string) (string, error) {
+	u, err := EndpointURL(apiURL, fmt.Sprintf("/api/v1/push/sidecar/%s/%s", appID, deploymentID))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return u.String(), nil
+}
+
+// PendingURL returns the URL a sidecar long-polls for messages, and posts its
+// own to, when it can't open a websocket.
+func PendingURL(apiURL, appID, deploymentID string) (string, error) {
+	u, err := EndpointURL(apiURL, fmt.Sprintf("/api/v1/push/pending/%s/%s", appID, deploymentID))
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiURL   string
+		expected string
+	}{
+		{name: "root", apiURL: "https://example.com", expected: "https://example.com/api/v1/auth/token"},
+		{name: "root with trailing slash", apiURL: "https://example.com/", expected: "https://example.com/api/v1/auth/token"},
+		{name: "prefix", apiURL: "https://example.com/bifrost", expected: "https://example.com/bifrost/api/v1/auth/token"},
+		{name: "prefix with trailing slash", apiURL: "https://example.com/bifrost/", expected: "https://example.com/bifrost/api/v1/auth/token"},
+		{name: "nested prefix with port", apiURL: "http://gateway:8080/internal/bifrost", expected: "http://gateway:8080/internal/bifrost/api/v1/auth/token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := EndpointURL(tt.apiURL, tokenExchangePath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, u.String())
+		})
+	}
+
+	_, err := EndpointURL("http://bad host", tokenExchangePath)
+	assert.Error(t, err)
+}
+
+func TestPrefixedPushURLs(t *testing.T) {
+	wsURL, err := WebSocketURL("https://example.com/bifrost/", "app1", "deployment1")
+	require.NoError(t, err)
+	assert.Equal(t, "wss://example.com/bifrost/api/v1/push/sidecar/app1/deployment1", wsURL)
+
+	pendingURL, err := PendingURL("https://example.com/bifrost", "app1", "deployment1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/bifrost/api/v1/push/pending/app1/deployment1", pendingURL)
+}
@@ -0,0 +1,11 @@
+//go:build embed_rsync
+
+package main
+
+import _ "embed"
+
+// embeddedRsync is the rsync binary provisioned for the launcher, compiled in
+// with the embed_rsync build tag so the image doesn't have to ship it.
+//
+//go:embed binaries/rsync-*-linux-amd64
+var embeddedRsync []byte
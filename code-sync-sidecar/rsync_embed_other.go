@@ -0,0 +1,7 @@
+//go:build !embed_rsync || !(amd64 || arm64)
+
+package main
+
+// embeddedRsync is nil without the embed_rsync build tag, and the rsync
+// binary is copied from binariesSourceDir instead.
+var embeddedRsync []byte
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// configReloadSignals make the sidecar reload its config file.
+var configReloadSignals = []os.Signal{syscall.SIGUSR1}
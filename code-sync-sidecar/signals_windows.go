@@ -0,0 +1,7 @@
+package main
+
+import "os"
+
+// configReloadSignals is empty: Windows has no SIGUSR1, so the config file is
+// only reloaded when it changes.
+var configReloadSignals []os.Signal
@@ -1,11 +1,11 @@
 #!/bin/bash
 set -e
 
-echo "Waiting for sidecar launcher script..."
-while [ ! -f /app-files/.sidecar/rsync-launcher.sh ]; do 
-    echo "Waiting for launcher script..."
+echo "Waiting for sidecar launcher..."
+while [ ! -x /app-files/.sidecar/code-sync-sidecar ]; do 
+    echo "Waiting for launcher..."
     sleep 1
 done
 
 echo "Starting application with sidecar..."
-exec /app-files/.sidecar/rsync-launcher.sh 'fastapi run app.py --host 0.0.0.0 --port 8000'
\ No newline at end of file
+exec /app-files/.sidecar/code-sync-sidecar launch -- fastapi run app.py --host 0.0.0.0 --port 8000